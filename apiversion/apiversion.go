@@ -0,0 +1,63 @@
+// Package apiversion holds the prefix of the versioned API and marks the
+// unversioned routes it replaces as deprecated.
+package apiversion
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// V1 prefixes the routes of the first version of the API.
+const V1 = "/api/v1"
+
+var (
+	// LegacyDeprecated is when the unversioned routes were deprecated, with
+	// the introduction of V1.
+	LegacyDeprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	// LegacySunset is when the unversioned routes are removed.
+	LegacySunset = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// legacyCalls counts the requests to deprecated routes by route, to tell
+// whether clients still use them.
+var legacyCalls = expvar.NewMap("legacy_route_calls")
+
+// Deprecated returns a middleware announcing that a route is deprecated in
+// favour of successor, a route pattern such as
+// "/api/v1/customers/:customer_number" whose parameters are filled in from
+// the request. The Deprecation (RFC 9745) and Sunset (RFC 8594) headers are
+// always set; the Link to the successor only when the request has every
+// parameter of it.
+func Deprecated(successor string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		legacyCalls.Add(ctx.Request.Method+" "+ctx.FullPath(), 1)
+		ctx.Header("Deprecation", fmt.Sprintf("@%d", LegacyDeprecated.Unix()))
+		ctx.Header("Sunset", LegacySunset.Format(http.TimeFormat))
+		if path, ok := fill(successor, ctx.Params); ok {
+			ctx.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, path))
+		}
+		ctx.Next()
+	}
+}
+
+// fill replaces the :name segments of pattern by the parameters of the same
+// name.
+func fill(pattern string, params gin.Params) (string, bool) {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		value, ok := params.Get(segment[1:])
+		if !ok {
+			return "", false
+		}
+		segments[i] = value
+	}
+	return strings.Join(segments, "/"), true
+}
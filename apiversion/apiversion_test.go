@@ -0,0 +1,37 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	r.GET("/customer/:customer_number", Deprecated(V1+"/customers/:customer_number"), ok)
+	r.PUT("/customer", Deprecated(V1+"/customers/:customer_number"), ok)
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/customer/7", nil))
+	header := recorder.Header()
+	if header.Get("Deprecation") != "@1792368000" || header.Get("Sunset") != "Fri, 30 Apr 2027 00:00:00 GMT" {
+		t.Errorf("Deprecation = %q, Sunset = %q", header.Get("Deprecation"), header.Get("Sunset"))
+	}
+	if link := header.Get("Link"); link != `</api/v1/customers/7>; rel="successor-version"` {
+		t.Errorf("Link = %q", link)
+	}
+
+	// The successor of PUT /customer depends on the body.
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/customer", nil))
+	if recorder.Header().Get("Deprecation") == "" || recorder.Header().Get("Link") != "" {
+		t.Errorf("PUT /customer headers = %v", recorder.Header())
+	}
+	if calls := legacyCalls.Get("GET /customer/:customer_number"); calls == nil || calls.String() != "1" {
+		t.Errorf("legacy_route_calls = %v", legacyCalls)
+	}
+}
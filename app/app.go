@@ -2,72 +2,245 @@ package app
 
 import (
 	"context"
+	"customer-playground/blob"
+	"customer-playground/cache"
+	"customer-playground/certificate"
+	"customer-playground/config"
 	"customer-playground/database"
 	"customer-playground/domain"
+	"customer-playground/encryption"
+	"customer-playground/migrations"
+	"customer-playground/notify"
+	"customer-playground/pii"
+	"customer-playground/ratelimit"
+	"customer-playground/scheduler"
+	"customer-playground/tenant"
+	"customer-playground/verification"
 	"database/sql"
 	"errors"
+	"expvar"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
 	delivery_customer "customer-playground/services/customer/delivery"
 	repository_customer "customer-playground/services/customer/repository"
 	usecase_customer "customer-playground/services/customer/usecase"
+	delivery_customeraddress "customer-playground/services/customeraddress/delivery"
+	repository_customeraddress "customer-playground/services/customeraddress/repository"
+	usecase_customeraddress "customer-playground/services/customeraddress/usecase"
+	delivery_customercontact "customer-playground/services/customercontact/delivery"
+	repository_customercontact "customer-playground/services/customercontact/repository"
+	usecase_customercontact "customer-playground/services/customercontact/usecase"
+	delivery_customermerge "customer-playground/services/customermerge/delivery"
+	repository_customermerge "customer-playground/services/customermerge/repository"
+	usecase_customermerge "customer-playground/services/customermerge/usecase"
 	delivery_customernote "customer-playground/services/customernote/delivery"
 	repository_customernote "customer-playground/services/customernote/repository"
 	usecase_customernote "customer-playground/services/customernote/usecase"
+	delivery_customertag "customer-playground/services/customertag/delivery"
+	repository_customertag "customer-playground/services/customertag/repository"
+	usecase_customertag "customer-playground/services/customertag/usecase"
+	delivery_customfield "customer-playground/services/customfield/delivery"
+	repository_customfield "customer-playground/services/customfield/repository"
+	usecase_customfield "customer-playground/services/customfield/usecase"
+	delivery_followup "customer-playground/services/followup/delivery"
+	repository_followup "customer-playground/services/followup/repository"
+	usecase_followup "customer-playground/services/followup/usecase"
+	delivery_noteattachment "customer-playground/services/noteattachment/delivery"
+	repository_noteattachment "customer-playground/services/noteattachment/repository"
+	usecase_noteattachment "customer-playground/services/noteattachment/usecase"
+	delivery_privacy "customer-playground/services/privacy/delivery"
+	repository_privacy "customer-playground/services/privacy/repository"
+	usecase_privacy "customer-playground/services/privacy/usecase"
+	delivery_segment "customer-playground/services/segment/delivery"
+	repository_segment "customer-playground/services/segment/repository"
+	usecase_segment "customer-playground/services/segment/usecase"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func Run() {
-	initConfig()
-	logger := initLogger()
-	dbPool, err := initDatabase()
+// Run serves the API until SIGINT or SIGTERM, together with the background
+// jobs.
+func Run(cfg config.Config) {
+	logger := initLogger(cfg.Log)
+	services, err := openServices(cfg, logger)
 	if err != nil {
-		logger.Fatalf("%s: %v", "Error on connect to database", err)
+		logger.Fatalf("%s: %v", "Error on start", err)
 	}
-	customerNoteUseCase, customerUseCase := initService(dbPool, logger)
-	initHandler(customerNoteUseCase, customerUseCase, logger)
+	defer services.Close()
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go services.db.MonitorReplicas(backgroundCtx, cfg.Database.ReplicaHealthInterval)
+
+	useCases, tenants := services.useCases, services.tenants
+	// Background jobs see one tenant at a time, like requests do.
+	jobs := []scheduler.Job{
+		{Name: "attachment-cleanup", Interval: cfg.Attachment.CleanupInterval, Run: tenant.ForEach(tenants, useCases.noteAttachment.Cleanup)},
+		{Name: "follow-up-reminders", Interval: cfg.FollowUp.Interval, Run: tenant.ForEach(tenants, useCases.followUp.DispatchReminders)},
+		{Name: "mentions", Interval: cfg.FollowUp.Interval, Run: tenant.ForEach(tenants, useCases.followUp.DispatchMentions)},
+		{Name: "re-encryption", Interval: cfg.Encryption.Interval, Run: tenant.ForEach(tenants, encryption.ReEncrypt(services.cipher, useCases.reEncrypters...))},
+	}
+	if cfg.Encryption.RotateAfter > 0 {
+		jobs = append(jobs, scheduler.Job{Name: "key-rotation", Interval: cfg.Encryption.Interval, Run: rotateKeys(services.keyfile, cfg.Encryption.RotateAfter, logger)})
+	}
+	scheduler.Start(backgroundCtx, logger, jobs...)
+
+	limiter, err := initRateLimiter(backgroundCtx, cfg.RateLimit, services.db, logger)
+	if err != nil {
+		logger.Fatalf("%s: %v", "Error on initialize rate limiter", err)
+	}
+	watchConfig(cfg, limiter, logger)
+
+	initHandler(cfg, useCases, tenants, limiter, logger)
+}
+
+// services are the database, the tenants and the use cases on top of them,
+// shared by the server and the commands.
+type services struct {
+	db       *database.DB
+	tenants  *tenant.Registry
+	cipher   *encryption.Cipher
+	keyfile  *encryption.Keyfile
+	useCases useCases
+	// closers are closed in reverse order, before the database.
+	closers []io.Closer
+	logger  *logrus.Logger
+}
+
+// openServices refuses a database whose schema is missing migrations; the
+// migrate command applies them.
+func openServices(cfg config.Config, logger *logrus.Logger) (*services, error) {
+	db, err := initDatabase(cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	if err := migrations.Check(context.Background(), db.Primary()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s := &services{db: db, logger: logger}
+	if err := s.init(cfg); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
 }
-func initConfig() {
-	viper.SetConfigType("toml")
 
-	viper.AddConfigPath(".")
-	viper.SetConfigName(".config")
+func (s *services) init(cfg config.Config) error {
+	logger := s.logger
+	cacheBackend, err := initCache(cfg.Cache)
+	if err != nil {
+		return fmt.Errorf("initialize cache: %w", err)
+	}
+	if closer, ok := cacheBackend.(io.Closer); ok {
+		s.closers = append(s.closers, closer)
+	}
+
+	sender, err := initVerificationSender(cfg.Verification, logger)
+	if err != nil {
+		return fmt.Errorf("initialize verification sender: %w", err)
+	}
+
+	blobStore, err := initBlobStore(cfg.Attachment)
+	if err != nil {
+		return fmt.Errorf("initialize attachment storage: %w", err)
+	}
+
+	notifier, err := initNotifier(cfg.FollowUp, logger)
+	if err != nil {
+		return fmt.Errorf("initialize notifier: %w", err)
+	}
+
+	s.tenants, err = initTenants(cfg, s.db)
+	if err != nil {
+		return fmt.Errorf("initialize tenants: %w", err)
+	}
+
+	signer, err := initCertificateSigner(cfg.Privacy, logger)
+	if err != nil {
+		return fmt.Errorf("initialize certificate signer: %w", err)
+	}
+
+	s.cipher, s.keyfile, err = initEncryption(cfg.Encryption, logger)
+	if err != nil {
+		return fmt.Errorf("initialize encryption: %w", err)
+	}
 
-	viper.AutomaticEnv()
+	useCases, repositories := initService(cfg, s.db, initQueryRetry(cfg.Database.QueryRetry), cacheBackend, sender, blobStore, notifier, signer, s.cipher, logger)
+	s.useCases = useCases
+	s.closers = append(s.closers, repositories...)
+	return nil
+}
 
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file: ", viper.ConfigFileUsed())
-	} else {
-		log.Fatal(err)
+func (s *services) Close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i].Close(); err != nil {
+			s.logger.Errorf("%s: %v", "Error on close", err)
+		}
 	}
+	s.db.Close()
+}
+
+// watchConfig applies the log level and rate limits whenever the config file
+// changes. Other settings keep their value until the next start.
+func watchConfig(started config.Config, limiter *ratelimit.Limiter, logger *logrus.Logger) {
+	config.Watch(started, func(next config.Config) {
+		level, _ := logrus.ParseLevel(next.Log.Level)
+		logger.SetLevel(level)
+		limiter.SetLimits(rateLimits(next.RateLimit))
+		logger.Infof("config reloaded from %s", next.File)
+		if started.NeedsRestart(next) {
+			logger.Warnf("%s changed settings that only apply after a restart", next.File)
+		}
+	}, func(err error) {
+		logger.Errorf("%s: %v", "Error on reload config, keeping the current one", err)
+	})
 }
 
-func initLogger() *logrus.Logger {
+func initLogger(cfg config.LogConfig) *logrus.Logger {
 	logger := logrus.New()
 	logger.Formatter = &logrus.JSONFormatter{}
+	// Checked by config.Load.
+	level, _ := logrus.ParseLevel(cfg.Level)
+	logger.SetLevel(level)
+	// Errors from the database can quote the rows they failed on.
+	logger.AddHook(pii.NewHook())
 
 	return logger
 }
 
-func initDatabase() (*sql.DB, error) {
+func initDatabase(cfg config.DatabaseConfig) (*database.DB, error) {
 	dbConn := database.DatabaseConnector{
-		Host:     viper.GetString("database.host"),
-		Port:     viper.GetInt("database.port"),
-		Username: viper.GetString("database.username"),
-		Password: viper.GetString("database.password"),
-		DBName:   viper.GetString("database.name"),
-		SSLMode:  viper.GetString("database.sslmode"),
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DBName:   cfg.Name,
+		SSLMode:  cfg.SSLMode,
+
+		ConnectTimeout:  cfg.ConnectTimeout,
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		StartupRetry: database.RetryPolicy{
+			MaxAttempts:     cfg.StartupRetry.MaxAttempts,
+			InitialInterval: cfg.StartupRetry.InitialInterval,
+			MaxInterval:     cfg.StartupRetry.MaxInterval,
+			Multiplier:      2,
+		},
 	}
 
 	dbPool, err := dbConn.Connect()
@@ -75,32 +248,428 @@ func initDatabase() (*sql.DB, error) {
 		return nil, err
 	}
 
-	return dbPool, nil
+	var replicaPools []*sql.DB
+	for _, replica := range cfg.Replicas {
+		replicaConn := dbConn
+		replicaConn.Host = replica.Host
+		if replica.Port != 0 {
+			replicaConn.Port = replica.Port
+		}
+		if replica.Username != "" {
+			replicaConn.Username = replica.Username
+			replicaConn.Password = replica.Password
+		}
+		if replica.Name != "" {
+			replicaConn.DBName = replica.Name
+		}
+		if replica.SSLMode != "" {
+			replicaConn.SSLMode = replica.SSLMode
+		}
+
+		replicaPool, err := replicaConn.Open()
+		if err != nil {
+			dbPool.Close()
+			return nil, err
+		}
+		replicaPools = append(replicaPools, replicaPool)
+	}
+
+	db := database.NewDB(dbPool, replicaPools...)
+	db.CheckReplicas(context.Background(), time.Duration(dbConn.ConnectTimeout)*time.Second)
+
+	return db, nil
+}
+
+func initQueryRetry(cfg config.RetryConfig) database.RetryPolicy {
+	retry := database.DefaultRetryPolicy
+	if cfg.MaxAttempts > 0 {
+		retry.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.InitialInterval > 0 {
+		retry.InitialInterval = cfg.InitialInterval
+	}
+	if cfg.MaxInterval > 0 {
+		retry.MaxInterval = cfg.MaxInterval
+	}
+	return retry
+}
+
+// initCache returns the backend configured in [cache], or nil when caching is
+// disabled.
+func initCache(cfg config.CacheConfig) (cache.Backend, error) {
+	switch backend := cfg.Backend; backend {
+	case "", "none":
+		return nil, nil
+	case "lru":
+		return cache.NewLRU(cfg.Size), nil
+	case "redis":
+		redis := cache.NewRedis(
+			cfg.Redis.Addr,
+			cfg.Redis.Password,
+			cfg.Redis.DB,
+		)
+		if err := redis.Ping(context.Background()); err != nil {
+			return nil, err
+		}
+		return redis, nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", backend)
+	}
 }
 
-func initService(dbPool *sql.DB, logger *logrus.Logger) (domain.CustomerNoteUseCase, domain.CustomerUseCase) {
-	customerNoteRepository := repository_customernote.NewCustomerNoteRepository(dbPool, logger)
+// initVerificationSender returns the sender configured in [verification].
+func initVerificationSender(cfg config.VerificationConfig, logger *logrus.Logger) (domain.VerificationSender, error) {
+	switch sender := cfg.Sender; sender {
+	case "", "log":
+		return verification.NewLogSender(logger), nil
+	case "file":
+		return verification.NewFileSender(cfg.FilePath), nil
+	default:
+		return nil, fmt.Errorf("unknown verification sender %q", sender)
+	}
+}
+
+// initBlobStore returns the attachment storage configured in [attachment].
+func initBlobStore(cfg config.AttachmentConfig) (blob.Store, error) {
+	switch backend := cfg.Backend; backend {
+	case "", "local":
+		return blob.NewLocal(cfg.Dir), nil
+	case "s3":
+		return blob.NewS3(blob.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+		}, &http.Client{Timeout: time.Minute}), nil
+	default:
+		return nil, fmt.Errorf("unknown attachment backend %q", backend)
+	}
+}
+
+// initNotifier returns the notifier configured in [followup].
+func initNotifier(cfg config.FollowUpConfig, logger *logrus.Logger) (domain.Notifier, error) {
+	switch notifier := cfg.Notifier; notifier {
+	case "", "log":
+		return notify.NewLogNotifier(logger), nil
+	case "smtp":
+		return notify.NewSMTPNotifier(notify.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+			Domain:   cfg.SMTP.Domain,
+		}), nil
+	case "webhook":
+		return notify.NewWebhookNotifier(
+			cfg.Webhook.URL,
+			cfg.Webhook.Secret,
+			&http.Client{Timeout: cfg.Webhook.Timeout},
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", notifier)
+	}
+}
+
+// initCertificateSigner returns the signer of erasure certificates for
+// privacy.signing_key. Without a key it makes up one, which is gone with the
+// process.
+func initCertificateSigner(cfg config.PrivacyConfig, logger *logrus.Logger) (domain.CertificateSigner, error) {
+	seed := cfg.SigningKey
+	if seed == "" {
+		logger.Warn("privacy.signing_key is not set, erasure certificates are signed with a temporary key")
+		return certificate.GenerateSigner()
+	}
+	return certificate.NewSigner(seed)
+}
+
+// initEncryption returns the cipher for [encryption] and the keyfile holding
+// its keys. A missing keyfile is created, which only suits development: data
+// encrypted with it is lost with the file.
+func initEncryption(cfg config.EncryptionConfig, logger *logrus.Logger) (*encryption.Cipher, *encryption.Keyfile, error) {
+	switch provider := cfg.Provider; provider {
+	case "", "keyfile":
+		path := cfg.Keyfile
+		keyfile, err := encryption.OpenKeyfile(path, false)
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Warnf("encryption keyfile %s does not exist, it is created with new keys", path)
+			keyfile, err = encryption.OpenKeyfile(path, true)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		cipher, err := encryption.NewCipher(context.Background(), keyfile, cfg.Fields)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cipher, keyfile, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown encryption provider %q", provider)
+	}
+}
+
+// rotateKeys returns a job replacing the current key once it is older than
+// maxAge. The re-encryption job then moves the data over to the new key.
+func rotateKeys(keyfile *encryption.Keyfile, maxAge time.Duration, logger *logrus.Logger) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		keyID, err := keyfile.RotateIfOlder(maxAge)
+		if err != nil || keyID == "" {
+			return 0, err
+		}
+		logger.Infof("encryption key rotated to %s", keyID)
+		return 1, nil
+	}
+}
+
+// initTenants creates the tenants configured in [tenants] that the database
+// does not know yet and returns them with their IDs.
+func initTenants(cfg config.Config, db *database.DB) (*tenant.Registry, error) {
+	tenants := make([]domain.Tenant, 0, len(cfg.Tenants))
+	for slug, config := range cfg.Tenants {
+		tenants = append(tenants, domain.Tenant{Slug: slug, Name: config.Name, Overrides: tenant.Flatten(config.Overrides)})
+	}
+	// Map iteration order is random; keep the IDs of new tenants stable.
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].Slug < tenants[j].Slug })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tenants, err := tenant.Sync(ctx, db.Primary(), tenants)
+	if err != nil {
+		return nil, err
+	}
+	return tenant.NewRegistry(tenants), nil
+}
+
+type useCases struct {
+	customerNote         domain.CustomerNoteUseCase
+	customer             domain.CustomerUseCase
+	customerAddress      domain.CustomerAddressUseCase
+	customerContactPoint domain.CustomerContactPointUseCase
+	tag                  domain.TagUseCase
+	segment              domain.SegmentUseCase
+	customField          domain.CustomFieldUseCase
+	customerMerge        domain.CustomerMergeUseCase
+	noteAttachment       domain.NoteAttachmentUseCase
+	followUp             domain.FollowUpUseCase
+	privacy              domain.PrivacyUseCase
+	// reEncrypters hold encrypted fields, see encryption.ReEncrypt.
+	reEncrypters []domain.ReEncrypter
+}
+
+func initService(cfg config.Config, db *database.DB, retry database.RetryPolicy, cacheBackend cache.Backend, sender domain.VerificationSender, blobStore blob.Store, notifier domain.Notifier, signer domain.CertificateSigner, cipher *encryption.Cipher, logger *logrus.Logger) (useCases, []io.Closer) {
+	customerNoteRepository := repository_customernote.NewCustomerNoteRepository(db, retry, logger)
 	customerNoteUseCase := usecase_customernote.NewCustomerNoteUseCase(customerNoteRepository, logger)
-	customerRepository := repository_customer.NewCustomerRepository(dbPool, logger)
-	customerUseCase := usecase_customer.NewCustomerUseCase(customerRepository, logger)
-	return customerNoteUseCase, customerUseCase
+	noteAttachmentRepository := repository_noteattachment.NewNoteAttachmentRepository(db, retry, blobStore, logger)
+	noteAttachmentUseCase := usecase_noteattachment.NewNoteAttachmentUseCase(noteAttachmentRepository, customerNoteUseCase, cfg.Attachment.MaxSize, logger)
+	followUpRepository := repository_followup.NewFollowUpRepository(db, retry, logger)
+	followUpUseCase := usecase_followup.NewFollowUpUseCase(followUpRepository, notifier, cfg.FollowUp.BatchSize, logger)
+	customerRepository := repository_customer.NewCustomerRepository(db, retry, cipher, logger)
+	if cacheBackend != nil {
+		customerRepository = repository_customer.NewCachedCustomerRepository(
+			customerRepository,
+			cacheBackend,
+			cfg.Cache.TTL,
+			cipher,
+			cache.NewMetrics("cache_customer"),
+			logger,
+		)
+	}
+	customFieldRepository := repository_customfield.NewCustomFieldRepository(db, retry, logger)
+	customFieldUseCase := usecase_customfield.NewCustomFieldUseCase(customFieldRepository, logger)
+	customerUseCase := usecase_customer.NewCustomerUseCase(customerRepository, customFieldRepository, logger)
+	customerAddressRepository := repository_customeraddress.NewCustomerAddressRepository(db, retry, logger)
+	customerAddressUseCase := usecase_customeraddress.NewCustomerAddressUseCase(customerAddressRepository, logger)
+	// Contact point changes rewrite customer.email and customer.phone, so the
+	// cached customer has to be dropped.
+	customerInvalidator, _ := customerRepository.(domain.CustomerInvalidator)
+	customerContactPointRepository := repository_customercontact.NewCustomerContactPointRepository(db, retry, cipher, logger)
+	customerContactPointUseCase := usecase_customercontact.NewCustomerContactPointUseCase(customerContactPointRepository, sender, customerInvalidator, logger)
+	tagRepository := repository_customertag.NewTagRepository(db, retry, logger)
+	tagUseCase := usecase_customertag.NewTagUseCase(tagRepository, logger)
+	segmentRepository := repository_segment.NewSegmentRepository(db, retry, cipher, logger)
+	segmentUseCase := usecase_segment.NewSegmentUseCase(segmentRepository, cipher, logger)
+	customerMergeRepository := repository_customermerge.NewCustomerMergeRepository(db, retry, cipher, logger)
+	customerMergeUseCase := usecase_customermerge.NewCustomerMergeUseCase(customerMergeRepository, customerRepository, customerInvalidator, cfg.Dedupe.Threshold, logger)
+	privacyRepository := repository_privacy.NewPrivacyRepository(db, retry, cipher, logger)
+	privacyUseCase := usecase_privacy.NewPrivacyUseCase(privacyRepository, usecase_privacy.Sources{
+		Customers:     customerRepository,
+		Addresses:     customerAddressRepository,
+		ContactPoints: customerContactPointRepository,
+		Tags:          tagRepository,
+		Notes:         customerNoteRepository,
+		Attachments:   noteAttachmentRepository,
+		Merges:        customerMergeRepository,
+	}, signer, customerInvalidator, logger)
+
+	var reEncrypters []domain.ReEncrypter
+	for _, repository := range []interface{}{customerRepository, customerContactPointRepository, customerMergeRepository} {
+		if reEncrypter, ok := repository.(domain.ReEncrypter); ok {
+			reEncrypters = append(reEncrypters, reEncrypter)
+		}
+	}
+
+	var repositories []io.Closer
+	for _, repository := range []interface{}{customerNoteRepository, customerRepository, customerAddressRepository, customerContactPointRepository, tagRepository, segmentRepository, customFieldRepository, customerMergeRepository, noteAttachmentRepository, followUpRepository, privacyRepository} {
+		if closer, ok := repository.(io.Closer); ok {
+			repositories = append(repositories, closer)
+		}
+	}
+	return useCases{
+		customerNote:         customerNoteUseCase,
+		customer:             customerUseCase,
+		customerAddress:      customerAddressUseCase,
+		customerContactPoint: customerContactPointUseCase,
+		tag:                  tagUseCase,
+		segment:              segmentUseCase,
+		customField:          customFieldUseCase,
+		customerMerge:        customerMergeUseCase,
+		noteAttachment:       noteAttachmentUseCase,
+		followUp:             followUpUseCase,
+		privacy:              privacyUseCase,
+		reEncrypters:         reEncrypters,
+	}, repositories
+}
+
+// initRateLimiter builds the limiter from [ratelimit]. The postgres backend
+// shares buckets between every instance using the primary database.
+func initRateLimiter(ctx context.Context, cfg config.RateLimitConfig, db *database.DB, logger *logrus.Logger) (*ratelimit.Limiter, error) {
+	var store ratelimit.Store
+	switch backend := cfg.Backend; backend {
+	case "", "memory":
+		store = ratelimit.NewMemory()
+	case "postgres":
+		postgres := ratelimit.NewPostgres(db.Primary())
+		go postgres.RunCleanup(ctx, time.Minute, time.Hour, func(err error) {
+			logger.Errorf("%s: %v", "Error on clean up rate limit buckets", err)
+		})
+		store = postgres
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", backend)
+	}
+
+	groups, fallback := rateLimits(cfg)
+	return ratelimit.NewLimiter(store, groups, fallback, logger), nil
+}
+
+// rateLimits returns the groups and the default limit of [ratelimit].
+func rateLimits(cfg config.RateLimitConfig) ([]ratelimit.Group, ratelimit.Limit) {
+	var groups []ratelimit.Group
+	for name, group := range cfg.Groups {
+		groups = append(groups, ratelimit.Group{
+			Name:   name,
+			Routes: group.Routes,
+			Limit:  ratelimit.Limit{Rate: group.Rate, Burst: group.Burst},
+		})
+	}
+	// Map iteration order is random; keep route matching deterministic.
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	fallback := ratelimit.Limit{
+		Rate:  cfg.Default.Rate,
+		Burst: cfg.Default.Burst,
+	}
+	return groups, fallback
 }
 
-func initHandler(customerNoteUseCase domain.CustomerNoteUseCase, customerUseCase domain.CustomerUseCase, logger *logrus.Logger) {
+func attachmentMaxSize(cfg config.AttachmentConfig) int64 {
+	if maxSize := cfg.MaxSize; maxSize > 0 {
+		return maxSize
+	}
+	return usecase_noteattachment.DefaultMaxSize
+}
+
+// databaseSession scopes read-your-writes routing to a single request.
+func databaseSession(ctx *gin.Context) {
+	ctx.Request = ctx.Request.WithContext(database.WithSession(ctx.Request.Context()))
+	ctx.Next()
+}
+
+// requestUser makes the agent named in the X-User header available to the
+// use cases.
+func requestUser(ctx *gin.Context) {
+	if user := ctx.GetHeader(domain.UserHeader); user != "" {
+		ctx.Request = ctx.Request.WithContext(domain.WithUser(ctx.Request.Context(), user))
+	}
+	ctx.Next()
+}
+
+// requirePermission refuses requests whose agent does not hold permission,
+// for routes outside the use cases, which check permissions themselves.
+func requirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !domain.HasPermission(ctx.Request.Context(), permission) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, domain.ErrorResponse{Message: "this route needs the " + permission + " permission"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// registerRoutes adds the routes of every handler to r.
+func registerRoutes(r *gin.Engine, cfg config.Config, useCases useCases, logger *logrus.Logger) {
+	delivery_customernote.NewCustomerNoteHandler(r, useCases.customerNote, logger)
+	delivery_customer.NewCustomerHandler(r, useCases.customer, logger)
+	delivery_customeraddress.NewCustomerAddressHandler(r, useCases.customerAddress, logger)
+	delivery_customercontact.NewCustomerContactPointHandler(r, useCases.customerContactPoint, logger)
+	delivery_customertag.NewTagHandler(r, useCases.tag, logger)
+	delivery_segment.NewSegmentHandler(r, useCases.segment, logger)
+	delivery_customfield.NewCustomFieldHandler(r, useCases.customField, logger)
+	delivery_customermerge.NewCustomerMergeHandler(r, useCases.customerMerge, logger)
+	delivery_noteattachment.NewNoteAttachmentHandler(r, useCases.noteAttachment, attachmentMaxSize(cfg.Attachment), logger)
+	delivery_followup.NewFollowUpHandler(r, useCases.followUp, logger)
+	delivery_privacy.NewPrivacyHandler(r, useCases.privacy, logger)
+}
+
+func initHandler(cfg config.Config, useCases useCases, tenants *tenant.Registry, limiter *ratelimit.Limiter, logger *logrus.Logger) {
 	ctx := context.Background()
 
 	gin.SetMode(gin.DebugMode)
 	r := gin.Default()
+	r.ContextWithFallback = true
+	// ClientIP, which the rate limits key on, only reads X-Forwarded-For
+	// from these.
+	if err := r.SetTrustedProxies(cfg.App.TrustedProxies); err != nil {
+		logger.Fatalf("%s: %v", "Error on set trusted proxies", err)
+	}
+	r.Use(databaseSession)
+	r.Use(requestUser)
+	// After requestUser, so the user named by a token replaces X-User; the
+	// resolver refuses requests sending both.
+	r.Use(tenant.NewResolver(
+		tenants,
+		cfg.Tenant.TokenSecret,
+		cfg.Tenant.TrustHeader,
+		cfg.App.TrustedProxies,
+		cfg.Tenant.Default,
+		[]string{"/swagger-ui/"},
+		logger,
+	).Handler)
+	r.Use(limiter.Handler)
 
 	http.Handle("/", r)
 
-	// Swagger endpoint
-	r.GET("/swagger-ui/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	delivery_customernote.NewCustomerNoteHandler(r, customerNoteUseCase, logger)
-	delivery_customer.NewCustomerHandler(r, customerUseCase, logger)
+	// Swagger endpoints, /swagger-ui/v1/ for /api/v1 and /swagger-ui/ for the
+	// unversioned routes, with the custom fields of the default tenant added
+	// to the customer schema
+	defaultTenant, _ := tenants.Lookup(cfg.Tenant.Default)
+	registerCustomFieldDoc(useCases.customField, defaultTenant, logger)
+	legacyDocs := ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.InstanceName(customFieldDocName))
+	v1Docs := ginSwagger.WrapHandler(swaggerFiles.NewHandler(), ginSwagger.InstanceName(customFieldDocV1Name))
+	r.GET("/swagger-ui/*any", func(ctx *gin.Context) {
+		if strings.HasPrefix(ctx.Param("any"), "/v1/") {
+			v1Docs(ctx)
+			return
+		}
+		legacyDocs(ctx)
+	})
+	// Runtime metrics such as cache hits and misses, which include the
+	// command line and memory statistics, only for tokens allowed to see them
+	r.GET("/debug/vars", requirePermission(domain.PermissionReadMetrics), gin.WrapH(expvar.Handler()))
+	registerRoutes(r, cfg, useCases, logger)
 
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(`:%d`, viper.GetInt("app.port")),
+		Addr:         fmt.Sprintf(`:%d`, cfg.App.Port),
 		WriteTimeout: time.Second * 15,
 		ReadTimeout:  time.Second * 15,
 		IdleTimeout:  time.Second * 60,
@@ -113,7 +682,7 @@ func initHandler(customerNoteUseCase domain.CustomerNoteUseCase, customerUseCase
 		}
 	}()
 
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"customer-playground/config"
+	"customer-playground/domain"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRegisterRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// Conflicting routes panic.
+	registerRoutes(r, config.Default(), useCases{}, logrus.New())
+
+	routes := map[string]bool{}
+	for _, route := range r.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+	for _, route := range []string{"GET /api/v1/customers/:number", "POST /api/v1/customers/:number/notes", "GET /api/v1/notes/:id/revisions", "PUT /customer", "GET /customer-note/get-by-id/:id"} {
+		if !routes[route] {
+			t.Errorf("%s is not registered", route)
+		}
+	}
+}
+
+func TestNoteRoutesShareRateLimit(t *testing.T) {
+	cfg, err := config.Load(filepath.Join("..", ".config.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := cfg.RateLimit.Groups["customer-note"].Routes
+	for _, route := range []string{"/customer-note/:id", "/api/v1/customers/:number/notes", "/api/v1/notes", "/api/v1/notes/:id", "/api/v1/notes/:id/revisions"} {
+		if !slices.Contains(routes, route) {
+			t.Errorf("%s is not in the customer-note rate limit group", route)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name        string
+		permissions []string
+		wantStatus  int
+	}{
+		{name: "no permissions", wantStatus: http.StatusForbidden},
+		{name: "other permission", permissions: []string{domain.PermissionReadPII}, wantStatus: http.StatusForbidden},
+		{name: "permission", permissions: []string{domain.PermissionReadMetrics}, wantStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		r := gin.New()
+		r.Use(func(ctx *gin.Context) {
+			ctx.Request = ctx.Request.WithContext(domain.WithPermissions(ctx.Request.Context(), test.permissions))
+		})
+		r.GET("/debug/vars", requirePermission(domain.PermissionReadMetrics), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+		if w.Code != test.wantStatus {
+			t.Errorf("%s: status = %d, want %d", test.name, w.Code, test.wantStatus)
+		}
+	}
+}
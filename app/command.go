@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"customer-playground/config"
+	"customer-playground/domain"
+	"customer-playground/tenant"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// options are the flags every command shares.
+type options struct {
+	configFile string
+	output     string
+	tenant     string
+	user       string
+}
+
+// NewCommand returns the command line of the service. Without a subcommand it
+// prints its help; "serve" runs the API.
+func NewCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:          "customer-playground",
+		Short:        "Customers and their notes",
+		SilenceUsage: true,
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.configFile, "config", "", "config file, "+config.DefaultFile+" in the working directory when it exists")
+	flags.StringVarP(&opts.output, "output", "o", outputTable, "output format, "+outputTable+" or "+outputJSON)
+	flags.StringVar(&opts.tenant, "tenant", "", "slug of the tenant to work on, tenant.default when empty")
+	flags.StringVar(&opts.user, "user", os.Getenv("USER"), "agent the changes are made as, e.g. the author of new notes")
+	root.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{outputTable, outputJSON}, cobra.ShellCompDirectiveNoFileComp))
+	root.RegisterFlagCompletionFunc("config", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"toml", "yaml", "yml", "json"}, cobra.ShellCompDirectiveFilterFileExt
+	})
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		_, err := newPrinter(opts.output, cmd.OutOrStdout())
+		return err
+	}
+
+	root.AddCommand(
+		newServeCommand(opts),
+		newMigrateCommand(opts),
+		newSeedCommand(opts),
+		newLoadTestCommand(opts),
+		newCustomerCommand(opts),
+		newNoteCommand(opts),
+		newExportCommand(opts),
+		newConfigCommand(opts),
+	)
+	return root
+}
+
+func newServeCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the API and run the background jobs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(opts.configFile)
+			if err != nil {
+				return err
+			}
+			if cfg.File != "" {
+				fmt.Fprintln(cmd.OutOrStdout(), "Using config file: ", cfg.File)
+			}
+			Run(cfg)
+			return nil
+		},
+	}
+}
+
+func newConfigCommand(opts *options) *cobra.Command {
+	configCommand := &cobra.Command{
+		Use:   "config",
+		Short: "Check the configuration",
+	}
+	configCommand.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Report every invalid or unknown setting",
+		Long:  "Loads the config file and the APP_ environment variables like serve does, without connecting to anything.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(opts.configFile)
+			if err != nil {
+				return err
+			}
+			file := cfg.File
+			if file == "" {
+				file = "the defaults"
+			}
+			return opts.printer(cmd).message(domain.Response{Message: "config is valid: " + file, StatusCode: 200})
+		},
+	})
+	return configCommand
+}
+
+// open loads the config and connects the use cases, for commands working on
+// the data of one tenant. The context carries the tenant and the user.
+func (opts *options) open(cmd *cobra.Command) (*services, context.Context, error) {
+	cfg, err := config.Load(opts.configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger := initLogger(cfg.Log)
+	// Only problems are logged, the output is the result of the command.
+	if logger.IsLevelEnabled(logrus.InfoLevel) {
+		logger.SetLevel(logrus.WarnLevel)
+	}
+	services, err := openServices(cfg, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	slug := opts.tenant
+	if slug == "" {
+		slug = cfg.Tenant.Default
+	}
+	current, ok := services.tenants.Lookup(slug)
+	if !ok {
+		services.Close()
+		return nil, nil, fmt.Errorf("unknown tenant %q", slug)
+	}
+	ctx := tenant.WithTenant(cmd.Context(), current)
+	// The command line runs with the database credentials, so its user is
+	// trusted like the subject of a token and sees their private notes.
+	if opts.user != "" {
+		ctx = domain.WithSubject(domain.WithUser(ctx, opts.user), opts.user)
+	}
+	return services, ctx, nil
+}
+
+func (opts *options) printer(cmd *cobra.Command) *printer {
+	// Checked by the root command.
+	printer, _ := newPrinter(opts.output, cmd.OutOrStdout())
+	return printer
+}
+
+// responseError turns a use case response other than 200 into an error.
+func responseError(message domain.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if message.StatusCode != 200 {
+		return errors.New(message.Message)
+	}
+	return nil
+}
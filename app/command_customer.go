@@ -0,0 +1,346 @@
+package app
+
+import (
+	"context"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newCustomerCommand(opts *options) *cobra.Command {
+	customerCommand := &cobra.Command{
+		Use:   "customer",
+		Short: "Look up and change customers",
+	}
+
+	customerCommand.AddCommand(&cobra.Command{
+		Use:               "get CUSTOMER_NUMBER",
+		Short:             "Show a customer",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: opts.completeCustomerNumbers,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			customerNumber, err := parseNumber(args[0])
+			if err != nil {
+				return err
+			}
+			services, ctx, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			defer services.Close()
+
+			customer, err := services.useCases.customer.GetByCustomerNumber(customerNumber, ctx)
+			var moved *domain.CustomerMovedError
+			if errors.As(err, &moved) {
+				customer, err = services.useCases.customer.GetByCustomerNumber(moved.MovedTo, ctx)
+			}
+			if err != nil {
+				return err
+			}
+			return opts.printer(cmd).customers([]domain.Customer{customer})
+		},
+	})
+
+	var email string
+	var fields []string
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "List customers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter := domain.CustomerFilter{Email: email}
+			for _, field := range fields {
+				name, value, ok := strings.Cut(field, "=")
+				if !ok {
+					return fmt.Errorf("--field %q is not name=value", field)
+				}
+				if filter.CustomFields == nil {
+					filter.CustomFields = types.JSONMap{}
+				}
+				// Parsed like the cf[name] query parameters of the API.
+				filter.CustomFields[name] = value
+			}
+			services, ctx, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			defer services.Close()
+
+			customers, err := services.useCases.customer.GetAll(filter, ctx)
+			if err != nil {
+				return err
+			}
+			return opts.printer(cmd).customers(customers)
+		},
+	}
+	listCommand.Flags().StringVar(&email, "email", "", "only the customer with this email, regardless of case")
+	listCommand.Flags().StringArrayVar(&fields, "field", nil, "only customers whose custom field has this value, as name=value")
+	customerCommand.AddCommand(listCommand)
+
+	var customer domain.Customer
+	var birthDate string
+	var values []string
+	createCommand := &cobra.Command{
+		Use:   "create",
+		Short: "Add a customer",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if birthDate != "" {
+				date, err := time.Parse("2006-01-02", birthDate)
+				if err != nil {
+					return fmt.Errorf("--birth-date: %w", err)
+				}
+				customer.BirthDate = types.NullTime{Time: date, Valid: true}
+			}
+			customFields, err := parseCustomFields(values)
+			if err != nil {
+				return err
+			}
+			customer.CustomFields = customFields
+			services, ctx, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			defer services.Close()
+
+			if err := responseError(services.useCases.customer.Insert(&customer, ctx)); err != nil {
+				return err
+			}
+			created, err := services.useCases.customer.GetByCustomerNumber(customer.CustomerNumber, ctx)
+			if err != nil {
+				return err
+			}
+			return opts.printer(cmd).customers([]domain.Customer{created})
+		},
+	}
+	createCommand.Flags().StringVar(&customer.Name, "name", "", "name of the customer")
+	createCommand.Flags().StringVar(&customer.Email, "email", "", "email of the customer")
+	createCommand.Flags().StringVar(&customer.Phone, "phone", "", "phone number of the customer")
+	createCommand.Flags().StringVar(&birthDate, "birth-date", "", "birth date of the customer, as YYYY-MM-DD")
+	createCommand.Flags().StringArrayVar(&values, "field", nil, "custom field value as name=value, where value is JSON or else a string")
+	createCommand.MarkFlagRequired("name")
+	createCommand.MarkFlagRequired("email")
+	customerCommand.AddCommand(createCommand)
+
+	customerCommand.AddCommand(&cobra.Command{
+		Use:               "delete CUSTOMER_NUMBER...",
+		Short:             "Delete customers",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: opts.completeCustomerNumbers,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			customerNumbers := make([]int, 0, len(args))
+			for _, arg := range args {
+				customerNumber, err := parseNumber(arg)
+				if err != nil {
+					return err
+				}
+				customerNumbers = append(customerNumbers, customerNumber)
+			}
+			services, ctx, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			defer services.Close()
+
+			for _, customerNumber := range customerNumbers {
+				message, err := services.useCases.customer.DeleteByCustomerNumber(customerNumber, ctx)
+				if err := responseError(message, err); err != nil {
+					return fmt.Errorf("customer %d: %w", customerNumber, err)
+				}
+				message.Message = fmt.Sprintf("customer %d deleted", customerNumber)
+				if err := opts.printer(cmd).message(message); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	return customerCommand
+}
+
+func newNoteCommand(opts *options) *cobra.Command {
+	noteCommand := &cobra.Command{
+		Use:   "note",
+		Short: "Write notes on customers",
+	}
+
+	var note domain.CustomerNote
+	var pinned bool
+	var dueAt string
+	addCommand := &cobra.Command{
+		Use:   "add CUSTOMER_NUMBER TEXT",
+		Short: "Add a note to a customer, written by --user",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return opts.completeCustomerNumbers(cmd, args, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			customerNumber, err := parseNumber(args[0])
+			if err != nil {
+				return err
+			}
+			note.CustomerNumber = customerNumber
+			note.Note = args[1]
+			if cmd.Flags().Changed("pinned") {
+				note.Pinned = &pinned
+			}
+			if dueAt != "" {
+				due, err := parseTime(dueAt)
+				if err != nil {
+					return fmt.Errorf("--due-at: %w", err)
+				}
+				note.DueAt = types.NullTime{Time: due, Valid: true}
+			}
+			services, ctx, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			defer services.Close()
+
+			if err := responseError(services.useCases.customerNote.Insert(&note, ctx)); err != nil {
+				return err
+			}
+			created, err := services.useCases.customerNote.GetById(note.ID, ctx)
+			if err != nil {
+				return err
+			}
+			return opts.printer(cmd).notes([]domain.CustomerNote{created})
+		},
+	}
+	addCommand.Flags().StringVar(&note.Category, "category", "", "call, email, complaint, meeting or other (default other)")
+	addCommand.Flags().StringVar(&note.Visibility, "visibility", "", "internal or private (default internal)")
+	addCommand.Flags().BoolVar(&pinned, "pinned", false, "pin the note")
+	addCommand.Flags().StringVar(&dueAt, "due-at", "", "due date of the follow-up, as YYYY-MM-DD or RFC 3339")
+	addCommand.Flags().StringVar(&note.Assignee, "assignee", "", "user the follow-up is assigned to")
+	addCommand.RegisterFlagCompletionFunc("category", cobra.FixedCompletions([]string{
+		domain.NoteCategoryCall, domain.NoteCategoryEmail, domain.NoteCategoryComplaint, domain.NoteCategoryMeeting, domain.NoteCategoryOther,
+	}, cobra.ShellCompDirectiveNoFileComp))
+	addCommand.RegisterFlagCompletionFunc("visibility", cobra.FixedCompletions([]string{
+		domain.NoteVisibilityInternal, domain.NoteVisibilityPrivate,
+	}, cobra.ShellCompDirectiveNoFileComp))
+	noteCommand.AddCommand(addCommand)
+	return noteCommand
+}
+
+func newExportCommand(opts *options) *cobra.Command {
+	var bundle string
+	exportCommand := &cobra.Command{
+		Use:               "export CUSTOMER_NUMBER",
+		Short:             "Export everything held about a customer",
+		Long:              "Exports a customer with addresses, contact points, tags, notes, revisions, merges and erasure requests, like GET /customer/{number}/data-export. Private notes are included.",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: opts.completeCustomerNumbers,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			customerNumber, err := parseNumber(args[0])
+			if err != nil {
+				return err
+			}
+			services, ctx, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			defer services.Close()
+
+			export, err := services.useCases.privacy.Export(customerNumber, ctx)
+			if err != nil {
+				return err
+			}
+			if bundle != "" {
+				return writeBundle(ctx, services.useCases.privacy, export, bundle)
+			}
+			return opts.printer(cmd).print(export, []string{"SECTION", "COUNT"}, [][]string{
+				{"addresses", strconv.Itoa(len(export.Addresses))},
+				{"contact points", strconv.Itoa(len(export.ContactPoints))},
+				{"tags", strconv.Itoa(len(export.Tags))},
+				{"notes", strconv.Itoa(len(export.Notes))},
+				{"merges", strconv.Itoa(len(export.Merges))},
+				{"erasure requests", strconv.Itoa(len(export.ErasureRequests))},
+			})
+		},
+	}
+	exportCommand.Flags().StringVar(&bundle, "bundle", "", "write a ZIP file with the export and the attached files to this path instead")
+	return exportCommand
+}
+
+func writeBundle(ctx context.Context, privacy domain.PrivacyUseCase, export domain.DataSubjectExport, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := privacy.WriteBundle(export, file, ctx); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+// completeCustomerNumbers offers the customers of the tenant, described by
+// their names.
+func (opts *options) completeCustomerNumbers(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	services, ctx, err := opts.open(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer services.Close()
+
+	customers, err := services.useCases.customer.GetAll(domain.CustomerFilter{}, ctx)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var completions []string
+	for _, customer := range customers {
+		if number := strconv.Itoa(customer.CustomerNumber); strings.HasPrefix(number, toComplete) {
+			completions = append(completions, number+"\t"+customer.Name)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func parseNumber(s string) (int, error) {
+	number, err := strconv.Atoi(s)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("%q is not a customer number", s)
+	}
+	return number, nil
+}
+
+// parseCustomFields reads name=value pairs. Values are JSON when they parse
+// as such, e.g. 3 or true, and strings otherwise.
+func parseCustomFields(pairs []string) (types.JSONMap, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	fields := types.JSONMap{}
+	for _, pair := range pairs {
+		name, raw, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("--field %q is not name=value", pair)
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		fields[name] = value
+	}
+	return fields, nil
+}
+
+// parseTime reads a date, taken as midnight UTC, or an RFC 3339 time.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"customer-playground/config"
+	"customer-playground/datagen"
+	"customer-playground/migrations"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Bring the schema up to date and create the configured tenants",
+		Long: "Applies the schema migrations the database does not have yet, as recorded in its schema_migrations table, and then creates the tenants in [tenants] that do not exist yet. " +
+			"Migrations create tables and the customer_app role, so connect as a user allowed to, e.g. with APP_DATABASE_USERNAME=postgres. " +
+			"The role can log in once database.app_password is set, e.g. with APP_DATABASE_APP_PASSWORD_FILE.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(opts.configFile)
+			if err != nil {
+				return err
+			}
+			db, err := initDatabase(cfg.Database)
+			if err != nil {
+				return fmt.Errorf("connect to database: %w", err)
+			}
+			defer db.Close()
+
+			applied, err := migrations.Up(cmd.Context(), db.Primary())
+			for _, migration := range applied {
+				fmt.Fprintf(cmd.ErrOrStderr(), "applied %s\n", migration)
+			}
+			if err != nil {
+				return err
+			}
+			if cfg.Database.AppPassword != "" {
+				if err := migrations.SetAppPassword(cmd.Context(), db.Primary(), cfg.Database.AppPassword); err != nil {
+					return fmt.Errorf("set the password of %s: %w", migrations.AppRole, err)
+				}
+			}
+			tenants, err := initTenants(cfg, db)
+			if err != nil {
+				return fmt.Errorf("initialize tenants: %w", err)
+			}
+
+			all := tenants.All()
+			rows := make([][]string, 0, len(all))
+			for _, tenant := range all {
+				rows = append(rows, []string{strconv.Itoa(tenant.ID), tenant.Slug, tenant.Name})
+			}
+			return opts.printer(cmd).print(all, []string{"ID", "SLUG", "NAME"}, rows)
+		},
+	}
+}
+
+// seedResult is what the seed command reports.
+type seedResult struct {
+	Customers int `json:"customers"`
+	Notes     int `json:"notes"`
+}
+
+func newSeedCommand(opts *options) *cobra.Command {
+	var customers, batchSize int
+	var notes float64
+	var seed int64
+	var locales []string
+	var until string
+	var span time.Duration
+	seedCommand := &cobra.Command{
+		Use:   "seed",
+		Short: "Add generated customers and notes",
+		Long: "Adds customers with made up names, emails, phones and birth dates, each with notes by a few agents. " +
+			"The rows are written with COPY, in one transaction per batch, and encrypted like the API does. " +
+			"The same flags generate the same data, whose emails then already exist; use another --seed to add more.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			localeList, err := datagen.LookupLocales(locales)
+			if err != nil {
+				return err
+			}
+			cfg := datagen.Config{Seed: seed, Locales: localeList, Span: span, NotesPerCustomer: notes}
+			if notes == 0 {
+				// Zero picks the default of datagen.
+				cfg.NotesPerCustomer = -1
+			}
+			if until != "" {
+				if cfg.Now, err = time.Parse("2006-01-02", until); err != nil {
+					return fmt.Errorf("--until: %w", err)
+				}
+			}
+			if batchSize <= 0 {
+				return fmt.Errorf("--batch-size must be positive")
+			}
+			services, ctx, err := opts.open(cmd)
+			if err != nil {
+				return err
+			}
+			defer services.Close()
+
+			definitions, err := services.useCases.customField.GetAll(ctx)
+			if err != nil {
+				return err
+			}
+			for _, definition := range definitions {
+				if definition.Required {
+					return fmt.Errorf("custom field %s is required, seed does not fill in custom fields", definition.Name)
+				}
+			}
+
+			generator := datagen.New(cfg)
+			writer := datagen.NewWriter(services.db.Primary(), services.cipher)
+			var result seedResult
+			for result.Customers < customers {
+				records := make([]datagen.Record, min(batchSize, customers-result.Customers))
+				for i := range records {
+					records[i] = generator.Next()
+				}
+				if err := writer.Write(ctx, records); err != nil {
+					var pqErr *pq.Error
+					if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+						return fmt.Errorf("%w; the tenant already has some of the generated emails, use another --seed", err)
+					}
+					return err
+				}
+				result.Customers += len(records)
+				for _, record := range records {
+					result.Notes += len(record.Notes)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "%d of %d customers\n", result.Customers, customers)
+			}
+			return opts.printer(cmd).print(result, []string{"CUSTOMERS", "NOTES"}, [][]string{
+				{strconv.Itoa(result.Customers), strconv.Itoa(result.Notes)},
+			})
+		},
+	}
+	seedCommand.Flags().IntVar(&customers, "customers", 100, "number of customers")
+	seedCommand.Flags().Float64Var(&notes, "notes", datagen.DefaultNotesPerCustomer, "mean number of notes per customer")
+	seedCommand.Flags().Int64Var(&seed, "seed", 1, "seed of the generated data")
+	seedCommand.Flags().StringSliceVar(&locales, "locale", []string{datagen.DefaultLocale}, "locales of the names and phones, one of "+strings.Join(datagen.LocaleCodes(), ", "))
+	seedCommand.Flags().StringVar(&until, "until", "", "date the newest customers are created on, as YYYY-MM-DD (default today)")
+	seedCommand.Flags().DurationVar(&span, "span", datagen.DefaultSpan, "how far before --until customers are created")
+	seedCommand.Flags().IntVar(&batchSize, "batch-size", 1000, "customers per transaction")
+	seedCommand.RegisterFlagCompletionFunc("locale", cobra.FixedCompletions(datagen.LocaleCodes(), cobra.ShellCompDirectiveNoFileComp))
+	return seedCommand
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"customer-playground/domain"
+	"customer-playground/loadtest"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newLoadTestCommand(opts *options) *cobra.Command {
+	var cfg loadtest.Config
+	var mix, token string
+	loadTestCommand := &cobra.Command{
+		Use:   "loadtest",
+		Short: "Drive a running API and report its latencies",
+		Long: "Sends a weighted mix of read and write requests to the API at --url until --duration is over or --requests were sent, " +
+			"then prints the throughput and latency percentiles of each scenario. Interrupting the run prints what it measured so far. " +
+			"The rate limits of the API apply, so expect 429s from the writes unless they are raised. " +
+			"Scenarios: " + fmt.Sprint(loadtest.Scenarios()) + ".",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if cfg.Mix, err = loadtest.ParseMix(mix); err != nil {
+				return err
+			}
+			cfg.Header = http.Header{}
+			// A token names the user; the API refuses X-User next to it.
+			if token != "" {
+				cfg.Header.Set("Authorization", "Bearer "+token)
+			} else {
+				if opts.tenant != "" {
+					cfg.Header.Set(domain.TenantHeader, opts.tenant)
+				}
+				if opts.user != "" {
+					cfg.Header.Set(domain.UserHeader, opts.user)
+				}
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			report, err := loadtest.Run(ctx, cfg)
+			if err != nil {
+				return err
+			}
+
+			rows := make([][]string, 0, len(report.Scenarios)+1)
+			for _, stats := range append(report.Scenarios, report.Total) {
+				rows = append(rows, []string{
+					stats.Name,
+					strconv.Itoa(stats.Requests),
+					strconv.Itoa(stats.Errors),
+					formatFloat(stats.Throughput),
+					formatFloat(stats.P50),
+					formatFloat(stats.P90),
+					formatFloat(stats.P95),
+					formatFloat(stats.P99),
+					formatFloat(stats.Max),
+				})
+			}
+			return opts.printer(cmd).print(report, []string{"SCENARIO", "REQUESTS", "ERRORS", "RPS", "P50 MS", "P90 MS", "P95 MS", "P99 MS", "MAX MS"}, rows)
+		},
+	}
+	flags := loadTestCommand.Flags()
+	flags.StringVar(&cfg.BaseURL, "url", "http://localhost:8080", "base URL of the API")
+	flags.IntVar(&cfg.Concurrency, "concurrency", 10, "requests in flight at a time")
+	flags.DurationVar(&cfg.Duration, "duration", 30*time.Second, "how long to run, 0 to stop after --requests only")
+	flags.IntVar(&cfg.Requests, "requests", 0, "number of requests to send, 0 for no limit")
+	flags.Float64Var(&cfg.Rate, "rate", 0, "requests per second of all workers together, 0 for as fast as the API answers")
+	flags.StringVar(&mix, "mix", loadtest.DefaultMix, "weights of the scenarios as scenario=weight,...")
+	flags.Int64Var(&cfg.Seed, "seed", 1, "seed of the scenario choices and the generated customers")
+	flags.StringVar(&token, "token", "", "bearer token to send instead of the "+domain.TenantHeader+" and "+domain.UserHeader+" headers")
+	return loadTestCommand
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 1, 64)
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"bytes"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func execute(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	command := NewCommand()
+	command.SetArgs(args)
+	command.SetOut(&out)
+	command.SetErr(&out)
+	err := command.Execute()
+	return out.String(), err
+}
+
+func TestConfigValidate(t *testing.T) {
+	out, err := execute(t, "config", "validate", "--config", filepath.Join("..", ".config.toml"))
+	if err != nil || !strings.HasPrefix(out, "config is valid: ") {
+		t.Errorf("config validate = %q, %v", out, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[app]\nport = 0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = execute(t, "config", "validate", "--config", path)
+	if err == nil || !strings.Contains(err.Error(), "app.port") {
+		t.Errorf("config validate of an invalid file = %v", err)
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	out, err := execute(t, "config", "validate", "--config", filepath.Join("..", ".config.toml"), "-o", "json")
+	var message domain.Response
+	if err != nil || json.Unmarshal([]byte(out), &message) != nil || message.StatusCode != 200 {
+		t.Errorf("config validate -o json = %q, %v", out, err)
+	}
+
+	if _, err := execute(t, "config", "validate", "-o", "xml"); err == nil {
+		t.Error("-o xml succeeded")
+	}
+}
+
+func TestPrinterTable(t *testing.T) {
+	var out bytes.Buffer
+	printer, _ := newPrinter(outputTable, &out)
+	err := printer.notes([]domain.CustomerNote{
+		{ID: 1, CustomerNumber: 7, Category: "call", Visibility: "internal", Author: "jane.agent", Note: "First line\nsecond line"},
+		{ID: 12, CustomerNumber: 7, Category: "email", Visibility: "private", Author: "bob.agent", Note: strings.Repeat("a", 100)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"ID  CUSTOMER  CATEGORY  VISIBILITY  AUTHOR      NOTE",
+		"1   7         call      internal    jane.agent  First line…",
+		"12  7         email     private     bob.agent   " + strings.Repeat("a", maxCellLength-1) + "…",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("table =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseCustomFields(t *testing.T) {
+	fields, err := parseCustomFields([]string{"tier=gold", "visits=3", "newsletter=true", "note=\"3\""})
+	want := types.JSONMap{"tier": "gold", "visits": float64(3), "newsletter": true, "note": "3"}
+	if err != nil || !reflect.DeepEqual(fields, want) {
+		t.Errorf("parseCustomFields() = %#v, %v, want %#v", fields, err, want)
+	}
+	if _, err := parseCustomFields([]string{"tier"}); err == nil {
+		t.Error("parseCustomFields() without = succeeded")
+	}
+}
+
+func TestSeedFlags(t *testing.T) {
+	// The flags are checked before the database is connected.
+	for _, args := range [][]string{
+		{"--locale", "xx_XX"},
+		{"--until", "yesterday"},
+		{"--batch-size", "0"},
+	} {
+		_, err := execute(t, append([]string{"seed", "--config", filepath.Join("..", ".config.toml")}, args...)...)
+		if err == nil || strings.Contains(err.Error(), "database") {
+			t.Errorf("seed %v = %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"customer-playground/apiversion"
+	"customer-playground/customfield"
+	"customer-playground/docs"
+	docs_v1 "customer-playground/docs/v1"
+	"customer-playground/domain"
+	"customer-playground/tenant"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/swaggo/swag"
+)
+
+// The API descriptions with custom fields, of the unversioned routes and of
+// /api/v1.
+const (
+	customFieldDocName   = "customer-playground"
+	customFieldDocV1Name = "customer-playground-v1"
+)
+
+// customFieldDoc serves the generated API description with the custom field
+// definitions described in the customer schema and as filters of the customer
+// listing. It is rebuilt on every read, so new definitions show up without a
+// restart.
+type customFieldDoc struct {
+	base swag.Swagger
+	// listing is the path of the customer listing, which takes the filters.
+	listing      string
+	customFields domain.CustomFieldUseCase
+	tenant       domain.Tenant
+	logger       *logrus.Logger
+}
+
+func registerCustomFieldDoc(customFields domain.CustomFieldUseCase, tenant domain.Tenant, logger *logrus.Logger) {
+	swag.Register(customFieldDocName, customFieldDoc{base: docs.SwaggerInfo, listing: "/customer", customFields: customFields, tenant: tenant, logger: logger})
+	swag.Register(customFieldDocV1Name, customFieldDoc{base: docs_v1.SwaggerInfov1, listing: apiversion.V1 + "/customers", customFields: customFields, tenant: tenant, logger: logger})
+}
+
+func (d customFieldDoc) ReadDoc() string {
+	base := d.base.ReadDoc()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	definitions, err := d.customFields.GetAll(tenant.WithTenant(ctx, d.tenant))
+	if err != nil {
+		d.logger.Errorf("%s: %v", "Error on load custom fields for the API description", err)
+		return base
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(base), &doc); err != nil {
+		d.logger.Errorf("%s: %v", "Error on parse the API description", err)
+		return base
+	}
+	schemas, _ := doc["definitions"].(map[string]interface{})
+	customer, _ := schemas["domain.Customer"].(map[string]interface{})
+	properties, _ := customer["properties"].(map[string]interface{})
+	if properties == nil {
+		return base
+	}
+	properties["custom_fields"] = customfield.Schema(definitions)
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	listing, _ := paths[d.listing].(map[string]interface{})
+	if get, ok := listing["get"].(map[string]interface{}); ok {
+		get["parameters"] = customFieldFilters(definitions)
+	}
+
+	patched, err := json.Marshal(doc)
+	if err != nil {
+		d.logger.Errorf("%s: %v", "Error on encode the API description", err)
+		return base
+	}
+	return string(patched)
+}
+
+// customFieldFilters describes one cf[name] query parameter per definition.
+func customFieldFilters(definitions []domain.CustomFieldDefinition) []interface{} {
+	schema := customfield.Schema(definitions)["properties"].(map[string]interface{})
+	parameters := []interface{}{}
+	for _, definition := range definitions {
+		parameter := map[string]interface{}{
+			"name":        "cf[" + definition.Name + "]",
+			"in":          "query",
+			"required":    false,
+			"description": "Only customers with this " + definition.Name,
+		}
+		for key, value := range schema[definition.Name].(map[string]interface{}) {
+			switch key {
+			case "type", "format", "enum", "pattern":
+				parameter[key] = value
+			}
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
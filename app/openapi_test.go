@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"customer-playground/domain"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/swaggo/swag"
+)
+
+type stubCustomFieldUseCase struct {
+	domain.CustomFieldUseCase
+}
+
+func (stubCustomFieldUseCase) GetAll(ctx context.Context) ([]domain.CustomFieldDefinition, error) {
+	return []domain.CustomFieldDefinition{{Name: "tier", Type: domain.CustomFieldTypeEnum, EnumValues: []string{"gold", "silver"}}}, nil
+}
+
+func TestCustomFieldDoc(t *testing.T) {
+	registerCustomFieldDoc(stubCustomFieldUseCase{}, domain.Tenant{ID: 1, Slug: "default"}, logrus.New())
+
+	for name, listing := range map[string]string{customFieldDocName: "/customer", customFieldDocV1Name: "/api/v1/customers"} {
+		content, err := swag.ReadDoc(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc struct {
+			Paths map[string]map[string]struct {
+				Deprecated bool `json:"deprecated"`
+				Parameters []struct {
+					Name string `json:"name"`
+				} `json:"parameters"`
+			} `json:"paths"`
+		}
+		if err := json.Unmarshal([]byte(content), &doc); err != nil {
+			t.Fatal(err)
+		}
+		get, ok := doc.Paths[listing]["get"]
+		if !ok || len(get.Parameters) != 1 || get.Parameters[0].Name != "cf[tier]" {
+			t.Errorf("%s: GET %s = %+v", name, listing, get)
+		}
+		// Every version only describes its own routes.
+		if get.Deprecated != (name == customFieldDocName) || len(doc.Paths) == 0 {
+			t.Errorf("%s: GET %s deprecated = %v", name, listing, get.Deprecated)
+		}
+		for path := range doc.Paths {
+			if (name == customFieldDocV1Name) != strings.HasPrefix(path, "/api/v1/") {
+				t.Errorf("%s describes %s", name, path)
+			}
+		}
+	}
+}
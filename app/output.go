@@ -0,0 +1,115 @@
+package app
+
+import (
+	"customer-playground/domain"
+	"customer-playground/types"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// maxCellLength shortens long texts such as notes in tables.
+const maxCellLength = 60
+
+// printer writes the results of commands as JSON or as a table.
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+func newPrinter(format string, w io.Writer) (*printer, error) {
+	switch format {
+	case outputTable, outputJSON:
+		return &printer{format: format, w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, want %s or %s", format, outputTable, outputJSON)
+	}
+}
+
+// print writes value as indented JSON, or rows under header as a table.
+func (p *printer) print(value interface{}, header []string, rows [][]string) error {
+	if p.format == outputJSON {
+		encoder := json.NewEncoder(p.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	w := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func (p *printer) message(message domain.Response) error {
+	if p.format == outputJSON {
+		return p.print(message, nil, nil)
+	}
+	_, err := fmt.Fprintln(p.w, message.Message)
+	return err
+}
+
+func (p *printer) customers(customers []domain.Customer) error {
+	rows := make([][]string, 0, len(customers))
+	for _, customer := range customers {
+		rows = append(rows, []string{
+			strconv.Itoa(customer.CustomerNumber),
+			customer.Name,
+			customer.Email,
+			customer.Phone,
+			formatDate(customer.BirthDate),
+			formatTime(customer.CreatedAt),
+		})
+	}
+	return p.print(customers, []string{"NUMBER", "NAME", "EMAIL", "PHONE", "BIRTH DATE", "CREATED AT"}, rows)
+}
+
+func (p *printer) notes(notes []domain.CustomerNote) error {
+	rows := make([][]string, 0, len(notes))
+	for _, note := range notes {
+		rows = append(rows, []string{
+			strconv.Itoa(note.ID),
+			strconv.Itoa(note.CustomerNumber),
+			note.Category,
+			note.Visibility,
+			note.Author,
+			shorten(note.Note),
+		})
+	}
+	return p.print(notes, []string{"ID", "CUSTOMER", "CATEGORY", "VISIBILITY", "AUTHOR", "NOTE"}, rows)
+}
+
+func formatDate(t types.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format("2006-01-02")
+}
+
+func formatTime(t types.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format("2006-01-02 15:04")
+}
+
+// shorten keeps the first line of s, cut to maxCellLength runes.
+func shorten(s string) string {
+	line, _, cut := strings.Cut(s, "\n")
+	if runes := []rune(line); len(runes) > maxCellLength {
+		line, cut = string(runes[:maxCellLength-1]), true
+	}
+	if cut {
+		line += "…"
+	}
+	return line
+}
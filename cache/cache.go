@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"expvar"
+	"sync/atomic"
+	"time"
+)
+
+// Backend stores opaque values under string keys for a limited time.
+type Backend interface {
+	// Get returns the value for key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Metrics counts cache lookups. It implements expvar.Var so it can be
+// published under /debug/vars.
+type Metrics struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+func (m *Metrics) Hit()   { m.hits.Add(1) }
+func (m *Metrics) Miss()  { m.misses.Add(1) }
+func (m *Metrics) Error() { m.errors.Add(1) }
+
+func (m *Metrics) Hits() int64   { return m.hits.Load() }
+func (m *Metrics) Misses() int64 { return m.misses.Load() }
+func (m *Metrics) Errors() int64 { return m.errors.Load() }
+
+func (m *Metrics) String() string {
+	v := new(expvar.Map)
+	hits, misses := m.Hits(), m.Misses()
+	v.Add("hits", hits)
+	v.Add("misses", misses)
+	v.Add("errors", m.Errors())
+	ratio := new(expvar.Float)
+	if hits+misses > 0 {
+		ratio.Set(float64(hits) / float64(hits+misses))
+	}
+	v.Set("hit_ratio", ratio)
+	return v.String()
+}
+
+// NewMetrics creates Metrics and publishes them in expvar under name. Names
+// that are already published reuse the existing counters.
+func NewMetrics(name string) *Metrics {
+	if existing, ok := expvar.Get(name).(*Metrics); ok {
+		return existing
+	}
+	m := &Metrics{}
+	expvar.Publish(name, m)
+	return m
+}
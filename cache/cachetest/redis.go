@@ -0,0 +1,209 @@
+// Package cachetest provides an in-process fake Redis server for tests.
+package cachetest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisServer understands the subset of RESP2 commands used by cache.Redis:
+// PING, AUTH, SELECT, GET, SET (with EX/PX), DEL and FLUSHALL.
+type RedisServer struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	values   map[string]redisValue
+	commands int
+	wg       sync.WaitGroup
+}
+
+type redisValue struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewRedisServer listens on a random local port. An empty password disables
+// AUTH.
+func NewRedisServer(password string) (*RedisServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &RedisServer{listener: listener, password: password, conns: make(map[net.Conn]struct{}), values: make(map[string]redisValue)}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+func (s *RedisServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Commands returns how many commands the server has handled.
+func (s *RedisServer) Commands() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commands
+}
+
+func (s *RedisServer) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *RedisServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *RedisServer) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		command := strings.ToUpper(args[0])
+		if !authenticated && command != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		if command == "AUTH" {
+			if len(args) == 2 && args[1] == s.password {
+				authenticated = true
+				io.WriteString(conn, "+OK\r\n")
+			} else {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+			}
+			continue
+		}
+		io.WriteString(conn, s.execute(command, args[1:]))
+	}
+}
+
+func (s *RedisServer) execute(command string, args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands++
+
+	switch command {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "FLUSHALL":
+		s.values = make(map[string]redisValue)
+		return "+OK\r\n"
+	case "GET":
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments for 'get' command\r\n"
+		}
+		v, ok := s.values[args[0]]
+		if !ok || (!v.expiresAt.IsZero() && !time.Now().Before(v.expiresAt)) {
+			delete(s.values, args[0])
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v.value), v.value)
+	case "SET":
+		if len(args) < 2 {
+			return "-ERR wrong number of arguments for 'set' command\r\n"
+		}
+		v := redisValue{value: args[1]}
+		for i := 2; i+1 < len(args); i += 2 {
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return "-ERR value is not an integer or out of range\r\n"
+			}
+			switch strings.ToUpper(args[i]) {
+			case "EX":
+				v.expiresAt = time.Now().Add(time.Duration(n) * time.Second)
+			case "PX":
+				v.expiresAt = time.Now().Add(time.Duration(n) * time.Millisecond)
+			default:
+				return "-ERR syntax error\r\n"
+			}
+		}
+		s.values[args[0]] = v
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args {
+			if _, ok := s.values[key]; ok {
+				delete(s.values, key)
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", command)
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(header, "\r\n")[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	lru := NewLRU(2)
+
+	lru.Set(ctx, "a", []byte("1"), 0)
+	lru.Set(ctx, "b", []byte("2"), 0)
+	if _, found, _ := lru.Get(ctx, "a"); !found {
+		t.Fatal("expected a to be cached")
+	}
+	lru.Set(ctx, "c", []byte("3"), 0)
+
+	if _, found, _ := lru.Get(ctx, "b"); found {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found, _ := lru.Get(ctx, key); !found {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+}
+
+func TestLRUExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	lru := NewLRU(10)
+	lru.now = func() time.Time { return now }
+
+	lru.Set(ctx, "a", []byte("1"), time.Minute)
+	now = now.Add(59 * time.Second)
+	if _, found, _ := lru.Get(ctx, "a"); !found {
+		t.Fatal("expected a to be cached before its ttl")
+	}
+	now = now.Add(time.Second)
+	if _, found, _ := lru.Get(ctx, "a"); found {
+		t.Fatal("expected a to expire")
+	}
+	if lru.Len() != 0 {
+		t.Errorf("expected expired entry to be removed, have %d entries", lru.Len())
+	}
+}
+
+func TestLRUDelete(t *testing.T) {
+	ctx := context.Background()
+	lru := NewLRU(10)
+
+	lru.Set(ctx, "a", []byte("1"), 0)
+	lru.Set(ctx, "b", []byte("2"), 0)
+	lru.Delete(ctx, "a", "missing")
+
+	if _, found, _ := lru.Get(ctx, "a"); found {
+		t.Error("expected a to be deleted")
+	}
+	if value, found, _ := lru.Get(ctx, "b"); !found || string(value) != "2" {
+		t.Errorf("expected b=2, got %q found=%v", value, found)
+	}
+}
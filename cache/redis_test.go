@@ -0,0 +1,73 @@
+package cache_test
+
+import (
+	"context"
+	"customer-playground/cache"
+	"customer-playground/cache/cachetest"
+	"testing"
+	"time"
+)
+
+func newRedis(t *testing.T, password string) (*cache.Redis, *cachetest.RedisServer) {
+	t.Helper()
+	server, err := cachetest.NewRedisServer(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	redis := cache.NewRedis(server.Addr(), password, 0)
+	t.Cleanup(func() { redis.Close() })
+	return redis, server
+}
+
+func TestRedisRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	redis, _ := newRedis(t, "secret")
+
+	if _, found, err := redis.Get(ctx, "customer:1"); err != nil || found {
+		t.Fatalf("expected a miss, got found=%v err=%v", found, err)
+	}
+	value := []byte("binary\r\nvalue\x00")
+	if err := redis.Set(ctx, "customer:1", value, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := redis.Get(ctx, "customer:1")
+	if err != nil || !found || string(got) != string(value) {
+		t.Fatalf("expected %q, got %q found=%v err=%v", value, got, found, err)
+	}
+
+	if err := redis.Delete(ctx, "customer:1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := redis.Get(ctx, "customer:1"); found {
+		t.Fatal("expected the key to be deleted")
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	ctx := context.Background()
+	redis, _ := newRedis(t, "")
+
+	if err := redis.Set(ctx, "k", []byte("v"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, found, _ := redis.Get(ctx, "k"); found {
+		t.Fatal("expected the key to expire")
+	}
+}
+
+func TestRedisWrongPassword(t *testing.T) {
+	server, err := cachetest.NewRedisServer("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	redis := cache.NewRedis(server.Addr(), "wrong", 0)
+	defer redis.Close()
+	if err := redis.Ping(context.Background()); err == nil {
+		t.Fatal("expected an authentication error")
+	}
+}
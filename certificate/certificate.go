@@ -0,0 +1,92 @@
+// Package certificate signs and verifies erasure certificates with Ed25519.
+package certificate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"customer-playground/domain"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const Algorithm = "Ed25519"
+
+var ErrInvalidSignature = errors.New("certificate: invalid signature")
+
+// Signer signs certificates with one private key.
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewSigner returns a signer for the base64 encoded 32 byte Ed25519 seed.
+func NewSigner(seed string) (*Signer, error) {
+	decoded, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, fmt.Errorf("certificate: decode signing key: %w", err)
+	}
+	if len(decoded) != ed25519.SeedSize {
+		return nil, fmt.Errorf("certificate: signing key must be %d bytes, got %d", ed25519.SeedSize, len(decoded))
+	}
+	return newSigner(ed25519.NewKeyFromSeed(decoded)), nil
+}
+
+// GenerateSigner returns a signer with a new random key. Certificates it
+// signs cannot be verified once the key is gone.
+func GenerateSigner() (*Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newSigner(key), nil
+}
+
+func newSigner(key ed25519.PrivateKey) *Signer {
+	return &Signer{key: key, keyID: KeyID(key.Public().(ed25519.PublicKey))}
+}
+
+// KeyID names a public key by the start of its SHA-256.
+func KeyID(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s *Signer) Sign(payload domain.ErasureCertificatePayload) (domain.ErasureCertificate, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return domain.ErasureCertificate{}, err
+	}
+	return domain.ErasureCertificate{
+		Payload:   data,
+		Algorithm: Algorithm,
+		KeyID:     s.keyID,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, data)),
+	}, nil
+}
+
+func (s *Signer) SigningKey() domain.SigningKey {
+	return domain.SigningKey{
+		Algorithm: Algorithm,
+		KeyID:     s.keyID,
+		PublicKey: base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey)),
+	}
+}
+
+// Verify checks that certificate was signed with the private key of
+// publicKey and returns its payload.
+func Verify(certificate domain.ErasureCertificate, publicKey ed25519.PublicKey) (domain.ErasureCertificatePayload, error) {
+	var payload domain.ErasureCertificatePayload
+	if certificate.Algorithm != Algorithm {
+		return payload, ErrInvalidSignature
+	}
+	signature, err := base64.StdEncoding.DecodeString(certificate.Signature)
+	if err != nil || !ed25519.Verify(publicKey, certificate.Payload, signature) {
+		return payload, ErrInvalidSignature
+	}
+	err = json.Unmarshal(certificate.Payload, &payload)
+	return payload, err
+}
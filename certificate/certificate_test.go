@@ -0,0 +1,69 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"customer-playground/domain"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	seed := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	signer, err := NewSigner(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := domain.ErasureCertificatePayload{
+		RequestID:      3,
+		Tenant:         "default",
+		CustomerNumber: 1,
+		RequestedBy:    "jane",
+		ApprovedBy:     "bob",
+		ErasedAt:       time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		Erased:         domain.ErasureSummary{NotesRedacted: 2},
+	}
+	certificate, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := signer.SigningKey()
+	publicKey, _ := base64.StdEncoding.DecodeString(key.PublicKey)
+	if key.KeyID != certificate.KeyID || key.KeyID != KeyID(publicKey) {
+		t.Errorf("key id = %q, certificate key id = %q", key.KeyID, certificate.KeyID)
+	}
+
+	// The certificate survives being stored and read back as JSON.
+	stored, _ := json.Marshal(certificate)
+	var loaded domain.ErasureCertificate
+	if err := json.Unmarshal(stored, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Verify(loaded, publicKey)
+	if err != nil || got.RequestID != 3 || got.Erased.NotesRedacted != 2 || !got.ErasedAt.Equal(payload.ErasedAt) {
+		t.Fatalf("Verify() = %+v, %v", got, err)
+	}
+
+	tampered := loaded
+	tampered.Payload = bytes.Replace(loaded.Payload, []byte(`"approved_by":"bob"`), []byte(`"approved_by":"eve"`), 1)
+	if _, err := Verify(tampered, publicKey); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify(tampered) = %v, want ErrInvalidSignature", err)
+	}
+	other, _ := GenerateSigner()
+	otherKey, _ := base64.StdEncoding.DecodeString(other.SigningKey().PublicKey)
+	if _, err := Verify(loaded, otherKey); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify(other key) = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestNewSignerRejectsBadKeys(t *testing.T) {
+	for _, seed := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := NewSigner(seed); err == nil {
+			t.Errorf("NewSigner(%q) succeeded", seed)
+		}
+	}
+}
@@ -0,0 +1,573 @@
+// Package config loads the settings of the service from a TOML file, APP_
+// prefixed environment variables and secret files, and checks them before
+// anything is started.
+package config
+
+import (
+	"customer-playground/encryption"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// DefaultFile is read when no config file is given. Unlike a given file it
+// may be missing, the defaults and environment are enough to start.
+const DefaultFile = ".config.toml"
+
+// EnvPrefix starts the environment variable overriding a key: database.host
+// is APP_DATABASE_HOST and app.port is APP_APP_PORT.
+const EnvPrefix = "APP"
+
+// Config holds every setting, named after its key in the config file.
+type Config struct {
+	App          AppConfig          `mapstructure:"app"`
+	Log          LogConfig          `mapstructure:"log"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	Tenant       TenantConfig       `mapstructure:"tenant"`
+	Tenants      map[string]Tenant  `mapstructure:"tenants"`
+	Cache        CacheConfig        `mapstructure:"cache"`
+	Verification VerificationConfig `mapstructure:"verification"`
+	Attachment   AttachmentConfig   `mapstructure:"attachment"`
+	FollowUp     FollowUpConfig     `mapstructure:"followup"`
+	Privacy      PrivacyConfig      `mapstructure:"privacy"`
+	Encryption   EncryptionConfig   `mapstructure:"encryption"`
+	Dedupe       DedupeConfig       `mapstructure:"dedupe"`
+	RateLimit    RateLimitConfig    `mapstructure:"ratelimit"`
+
+	// File is the config file read, empty when there was none.
+	File string `mapstructure:"-"`
+}
+
+type AppConfig struct {
+	Name        string `mapstructure:"name"`
+	Environment string `mapstructure:"environment"`
+	Port        int    `mapstructure:"port"`
+	// TrustedProxies are the addresses or CIDR ranges whose X-Forwarded-For
+	// header names the client. Empty trusts none.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+// LogConfig is reloaded while running.
+type LogConfig struct {
+	Level string `mapstructure:"level"`
+}
+
+// DatabaseConfig is the connection of the application. AppPassword is the
+// password the migrate command gives the customer_app role, which the
+// migrations create without one; empty leaves it unchanged.
+type DatabaseConfig struct {
+	Host                  string          `mapstructure:"host"`
+	Port                  int             `mapstructure:"port"`
+	Name                  string          `mapstructure:"name"`
+	Username              string          `mapstructure:"username"`
+	Password              string          `mapstructure:"password"`
+	PasswordFile          string          `mapstructure:"password_file"`
+	AppPassword           string          `mapstructure:"app_password"`
+	AppPasswordFile       string          `mapstructure:"app_password_file"`
+	SSLMode               string          `mapstructure:"sslmode"`
+	ConnectTimeout        int             `mapstructure:"connect_timeout"`
+	MaxOpenConns          int             `mapstructure:"max_open_conns"`
+	MaxIdleConns          int             `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime       time.Duration   `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime       time.Duration   `mapstructure:"conn_max_idle_time"`
+	ReplicaHealthInterval time.Duration   `mapstructure:"replica_health_interval"`
+	StartupRetry          RetryConfig     `mapstructure:"startup_retry"`
+	QueryRetry            RetryConfig     `mapstructure:"query_retry"`
+	Replicas              []ReplicaConfig `mapstructure:"replicas"`
+}
+
+type RetryConfig struct {
+	MaxAttempts     int           `mapstructure:"max_attempts"`
+	InitialInterval time.Duration `mapstructure:"initial_interval"`
+	MaxInterval     time.Duration `mapstructure:"max_interval"`
+}
+
+// ReplicaConfig inherits every empty setting from DatabaseConfig.
+type ReplicaConfig struct {
+	Host         string `mapstructure:"host"`
+	Port         int    `mapstructure:"port"`
+	Username     string `mapstructure:"username"`
+	Password     string `mapstructure:"password"`
+	PasswordFile string `mapstructure:"password_file"`
+	Name         string `mapstructure:"name"`
+	SSLMode      string `mapstructure:"sslmode"`
+}
+
+type TenantConfig struct {
+	Default         string `mapstructure:"default"`
+	TokenSecret     string `mapstructure:"token_secret"`
+	TokenSecretFile string `mapstructure:"token_secret_file"`
+	TrustHeader     bool   `mapstructure:"trust_header"`
+}
+
+// Tenant is created on start up. Overrides replace the global setting of the
+// same name, e.g. "dedupe.threshold", for this tenant.
+type Tenant struct {
+	Name      string                 `mapstructure:"name"`
+	Overrides map[string]interface{} `mapstructure:"overrides"`
+}
+
+type CacheConfig struct {
+	Backend string        `mapstructure:"backend"`
+	TTL     time.Duration `mapstructure:"ttl"`
+	Size    int           `mapstructure:"size"`
+	Redis   RedisConfig   `mapstructure:"redis"`
+}
+
+type RedisConfig struct {
+	Addr         string `mapstructure:"addr"`
+	Password     string `mapstructure:"password"`
+	PasswordFile string `mapstructure:"password_file"`
+	DB           int    `mapstructure:"db"`
+}
+
+type VerificationConfig struct {
+	Sender   string `mapstructure:"sender"`
+	FilePath string `mapstructure:"file_path"`
+}
+
+type AttachmentConfig struct {
+	Backend         string        `mapstructure:"backend"`
+	Dir             string        `mapstructure:"dir"`
+	MaxSize         int64         `mapstructure:"max_size"`
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+	S3              S3Config      `mapstructure:"s3"`
+}
+
+type S3Config struct {
+	Endpoint      string `mapstructure:"endpoint"`
+	Region        string `mapstructure:"region"`
+	Bucket        string `mapstructure:"bucket"`
+	AccessKey     string `mapstructure:"access_key"`
+	AccessKeyFile string `mapstructure:"access_key_file"`
+	SecretKey     string `mapstructure:"secret_key"`
+	SecretKeyFile string `mapstructure:"secret_key_file"`
+}
+
+type FollowUpConfig struct {
+	Notifier  string        `mapstructure:"notifier"`
+	Interval  time.Duration `mapstructure:"interval"`
+	BatchSize int           `mapstructure:"batch_size"`
+	SMTP      SMTPConfig    `mapstructure:"smtp"`
+	Webhook   WebhookConfig `mapstructure:"webhook"`
+}
+
+type SMTPConfig struct {
+	Host         string `mapstructure:"host"`
+	Port         int    `mapstructure:"port"`
+	Username     string `mapstructure:"username"`
+	Password     string `mapstructure:"password"`
+	PasswordFile string `mapstructure:"password_file"`
+	From         string `mapstructure:"from"`
+	Domain       string `mapstructure:"domain"`
+}
+
+type WebhookConfig struct {
+	URL        string        `mapstructure:"url"`
+	Secret     string        `mapstructure:"secret"`
+	SecretFile string        `mapstructure:"secret_file"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+}
+
+type PrivacyConfig struct {
+	SigningKey     string `mapstructure:"signing_key"`
+	SigningKeyFile string `mapstructure:"signing_key_file"`
+}
+
+type EncryptionConfig struct {
+	Provider    string        `mapstructure:"provider"`
+	Keyfile     string        `mapstructure:"keyfile"`
+	Fields      []string      `mapstructure:"fields"`
+	Interval    time.Duration `mapstructure:"interval"`
+	RotateAfter time.Duration `mapstructure:"rotate_after"`
+}
+
+type DedupeConfig struct {
+	Threshold float64 `mapstructure:"threshold"`
+}
+
+// The limits of RateLimitConfig are reloaded while running.
+type RateLimitConfig struct {
+	Backend string                 `mapstructure:"backend"`
+	Default LimitConfig            `mapstructure:"default"`
+	Groups  map[string]GroupConfig `mapstructure:"groups"`
+}
+
+type LimitConfig struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+type GroupConfig struct {
+	Routes []string `mapstructure:"routes"`
+	Rate   float64  `mapstructure:"rate"`
+	Burst  int      `mapstructure:"burst"`
+}
+
+// Default returns the settings used for every key that is neither in the
+// config file nor in the environment. They suit development with
+// docker-compose; passwords and secrets are empty.
+func Default() Config {
+	return Config{
+		App: AppConfig{Name: "customer-playground", Environment: "development", Port: 8080, TrustedProxies: []string{}},
+		Log: LogConfig{Level: "info"},
+		Database: DatabaseConfig{
+			Host:                  "localhost",
+			Port:                  5432,
+			Name:                  "postgres",
+			Username:              "customer_app",
+			SSLMode:               "disable",
+			ConnectTimeout:        5,
+			MaxOpenConns:          25,
+			MaxIdleConns:          10,
+			ConnMaxLifetime:       30 * time.Minute,
+			ConnMaxIdleTime:       5 * time.Minute,
+			ReplicaHealthInterval: 10 * time.Second,
+			StartupRetry:          RetryConfig{MaxAttempts: 10, InitialInterval: 500 * time.Millisecond, MaxInterval: 10 * time.Second},
+			QueryRetry:            RetryConfig{MaxAttempts: 3, InitialInterval: 50 * time.Millisecond, MaxInterval: time.Second},
+		},
+		Tenant:       TenantConfig{Default: "default"},
+		Cache:        CacheConfig{Backend: "lru", TTL: 5 * time.Minute, Size: 10000, Redis: RedisConfig{Addr: "localhost:6379"}},
+		Verification: VerificationConfig{Sender: "log", FilePath: "verification-codes.log"},
+		Attachment:   AttachmentConfig{Backend: "local", Dir: "attachments", MaxSize: 10 << 20, CleanupInterval: 10 * time.Minute},
+		FollowUp: FollowUpConfig{
+			Notifier:  "log",
+			Interval:  time.Minute,
+			BatchSize: 100,
+			SMTP:      SMTPConfig{Host: "localhost", Port: 1025},
+			Webhook:   WebhookConfig{Timeout: 10 * time.Second},
+		},
+		Encryption: EncryptionConfig{
+			Provider:    "keyfile",
+			Keyfile:     "keys/keys.json",
+			Fields:      append([]string(nil), encryption.Fields...),
+			Interval:    time.Minute,
+			RotateAfter: 90 * 24 * time.Hour,
+		},
+		Dedupe:    DedupeConfig{Threshold: 0.5},
+		RateLimit: RateLimitConfig{Backend: "memory", Default: LimitConfig{Rate: 20, Burst: 40}},
+	}
+}
+
+// defaultTenants are created when no [tenants] are configured.
+func defaultTenants() map[string]Tenant {
+	return map[string]Tenant{"default": {Name: "Default"}}
+}
+
+// Load reads file, or DefaultFile when file is empty, over the defaults,
+// applies the environment, reads the secret files and validates the result.
+// Every invalid setting is reported, joined into one error.
+func Load(file string) (Config, error) {
+	v := viper.New()
+	v.SetConfigType("toml")
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// Environment variables only apply to keys viper knows about.
+	setDefaults(v, "", reflect.ValueOf(Default()))
+
+	v.SetConfigFile(file)
+	if file == "" {
+		v.SetConfigFile(DefaultFile)
+	}
+	found := true
+	if err := v.ReadInConfig(); err != nil {
+		if file != "" || !errors.Is(err, os.ErrNotExist) {
+			return Config{}, fmt.Errorf("config: read %s: %w", v.ConfigFileUsed(), err)
+		}
+		// Running on defaults and environment alone.
+		found = false
+	}
+
+	var config Config
+	// Unknown keys are most likely typos of a setting that is then silently
+	// left at its default.
+	if err := v.UnmarshalExact(&config); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	if found {
+		config.File = v.ConfigFileUsed()
+	}
+	if len(config.Tenants) == 0 {
+		config.Tenants = defaultTenants()
+	}
+	if err := config.readSecrets(); err != nil {
+		return Config{}, err
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// setDefaults registers the settings of value under prefix. Maps and lists
+// of tables have no defaults, so they cannot be set through the environment.
+func setDefaults(v *viper.Viper, prefix string, value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			setDefaults(v, key, value.Field(i))
+		case field.Type.Kind() == reflect.Map:
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+		default:
+			v.SetDefault(key, value.Field(i).Interface())
+		}
+	}
+}
+
+// secret is a setting that can also be read from a file, e.g. a mounted
+// Docker or Kubernetes secret.
+type secret struct {
+	key   string
+	value *string
+	file  string
+}
+
+func (c *Config) secrets() []secret {
+	secrets := []secret{
+		{"database.password", &c.Database.Password, c.Database.PasswordFile},
+		{"database.app_password", &c.Database.AppPassword, c.Database.AppPasswordFile},
+		{"tenant.token_secret", &c.Tenant.TokenSecret, c.Tenant.TokenSecretFile},
+		{"cache.redis.password", &c.Cache.Redis.Password, c.Cache.Redis.PasswordFile},
+		{"attachment.s3.access_key", &c.Attachment.S3.AccessKey, c.Attachment.S3.AccessKeyFile},
+		{"attachment.s3.secret_key", &c.Attachment.S3.SecretKey, c.Attachment.S3.SecretKeyFile},
+		{"followup.smtp.password", &c.FollowUp.SMTP.Password, c.FollowUp.SMTP.PasswordFile},
+		{"followup.webhook.secret", &c.FollowUp.Webhook.Secret, c.FollowUp.Webhook.SecretFile},
+		{"privacy.signing_key", &c.Privacy.SigningKey, c.Privacy.SigningKeyFile},
+	}
+	for i := range c.Database.Replicas {
+		replica := &c.Database.Replicas[i]
+		secrets = append(secrets, secret{fmt.Sprintf("database.replicas[%d].password", i), &replica.Password, replica.PasswordFile})
+	}
+	return secrets
+}
+
+// readSecrets replaces every secret that names a file with the content of the
+// file, without the trailing line break editors and echo add.
+func (c *Config) readSecrets() error {
+	var errs []error
+	for _, secret := range c.secrets() {
+		if secret.file == "" {
+			continue
+		}
+		if *secret.value != "" {
+			errs = append(errs, &FieldError{Key: secret.key, Msg: "is set together with " + secret.key + "_file, set only one"})
+			continue
+		}
+		b, err := os.ReadFile(secret.file)
+		if err != nil {
+			errs = append(errs, &FieldError{Key: secret.key + "_file", Msg: err.Error()})
+			continue
+		}
+		*secret.value = strings.TrimRight(string(b), "\r\n")
+	}
+	return errors.Join(errs...)
+}
+
+// FieldError reports a setting that is not acceptable.
+type FieldError struct {
+	Key string
+	Msg string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("config: %s %s", e.Key, e.Msg)
+}
+
+// Validate checks every setting and reports all that are invalid, joined into
+// one error.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(key string, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Key: key, Msg: fmt.Sprintf(format, args...)})
+	}
+	oneOf := func(key string, value string, allowed ...string) {
+		if !slices.Contains(allowed, value) {
+			invalid(key, "is %q, choose from %s", value, strings.Join(allowed, ", "))
+		}
+	}
+
+	if c.App.Port < 1 || c.App.Port > 65535 {
+		invalid("app.port", "is %d, must be between 1 and 65535", c.App.Port)
+	}
+	for _, proxy := range c.App.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			invalid("app.trusted_proxies", "has %q, which is neither an IP address nor a CIDR range", proxy)
+		}
+	}
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		invalid("log.level", "is %q, choose from panic, fatal, error, warn, info, debug, trace", c.Log.Level)
+	}
+
+	for key, value := range map[string]string{"database.host": c.Database.Host, "database.name": c.Database.Name, "database.username": c.Database.Username} {
+		if value == "" {
+			invalid(key, "is required")
+		}
+	}
+	sslModes := []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	oneOf("database.sslmode", c.Database.SSLMode, sslModes...)
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		invalid("database.port", "is %d, must be between 1 and 65535", c.Database.Port)
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		invalid("database.max_open_conns", "and database.max_idle_conns cannot be negative")
+	}
+	if c.Database.StartupRetry.MaxAttempts < 1 {
+		invalid("database.startup_retry.max_attempts", "is %d, must be at least 1", c.Database.StartupRetry.MaxAttempts)
+	}
+	for i, replica := range c.Database.Replicas {
+		if replica.Host == "" {
+			invalid(fmt.Sprintf("database.replicas[%d].host", i), "is required")
+		}
+		if replica.SSLMode != "" {
+			oneOf(fmt.Sprintf("database.replicas[%d].sslmode", i), replica.SSLMode, sslModes...)
+		}
+	}
+
+	if _, ok := c.Tenants[c.Tenant.Default]; c.Tenant.Default != "" && !ok {
+		invalid("tenant.default", "is %q, which is not in [tenants]", c.Tenant.Default)
+	}
+	// A temporary key signs certificates nobody can check after a restart.
+	if c.Privacy.SigningKey == "" && c.App.Environment != "development" {
+		invalid("privacy.signing_key", "is required when app.environment is %q, only development may sign with a temporary key", c.App.Environment)
+	}
+	if c.Tenant.TrustHeader && len(c.App.TrustedProxies) == 0 {
+		invalid("tenant.trust_header", "is set, which needs the proxies sending the headers in app.trusted_proxies")
+	}
+
+	oneOf("cache.backend", c.Cache.Backend, "", "none", "lru", "redis")
+	if c.Cache.Backend == "lru" && c.Cache.Size < 1 {
+		invalid("cache.size", "is %d, must be at least 1", c.Cache.Size)
+	}
+	if c.Cache.Backend == "redis" && c.Cache.Redis.Addr == "" {
+		invalid("cache.redis.addr", "is required for the redis backend")
+	}
+
+	oneOf("verification.sender", c.Verification.Sender, "", "log", "file")
+	if c.Verification.Sender == "file" && c.Verification.FilePath == "" {
+		invalid("verification.file_path", "is required for the file sender")
+	}
+
+	oneOf("attachment.backend", c.Attachment.Backend, "", "local", "s3")
+	if c.Attachment.Backend == "s3" && c.Attachment.S3.Bucket == "" {
+		invalid("attachment.s3.bucket", "is required for the s3 backend")
+	}
+	if c.Attachment.MaxSize < 0 {
+		invalid("attachment.max_size", "cannot be negative")
+	}
+
+	oneOf("followup.notifier", c.FollowUp.Notifier, "", "log", "smtp", "webhook")
+	if c.FollowUp.Notifier == "smtp" && c.FollowUp.SMTP.Host == "" {
+		invalid("followup.smtp.host", "is required for the smtp notifier")
+	}
+	if c.FollowUp.Notifier == "webhook" && c.FollowUp.Webhook.URL == "" {
+		invalid("followup.webhook.url", "is required for the webhook notifier")
+	}
+
+	oneOf("encryption.provider", c.Encryption.Provider, "", "keyfile")
+	if c.Encryption.Provider != "" && c.Encryption.Keyfile == "" {
+		invalid("encryption.keyfile", "is required for the keyfile provider")
+	}
+	for _, field := range c.Encryption.Fields {
+		if !slices.Contains(encryption.Fields, field) {
+			invalid("encryption.fields", "has %q, choose from %s", field, strings.Join(encryption.Fields, ", "))
+		}
+	}
+	if c.Encryption.RotateAfter < 0 {
+		invalid("encryption.rotate_after", "cannot be negative")
+	}
+
+	if c.Dedupe.Threshold < 0 || c.Dedupe.Threshold > 1 {
+		invalid("dedupe.threshold", "is %v, must be between 0 and 1", c.Dedupe.Threshold)
+	}
+
+	oneOf("ratelimit.backend", c.RateLimit.Backend, "", "memory", "postgres")
+	errs = append(errs, c.RateLimit.validateLimits()...)
+
+	// Intervals of background jobs must be positive for their ticker.
+	for key, interval := range map[string]time.Duration{
+		"database.replica_health_interval": c.Database.ReplicaHealthInterval,
+		"attachment.cleanup_interval":      c.Attachment.CleanupInterval,
+		"followup.interval":                c.FollowUp.Interval,
+		"encryption.interval":              c.Encryption.Interval,
+	} {
+		if interval <= 0 {
+			invalid(key, "is %v, must be positive", interval)
+		}
+	}
+
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errors.Join(errs...)
+}
+
+func (r RateLimitConfig) validateLimits() []error {
+	var errs []error
+	check := func(key string, rate float64, burst int) {
+		if rate < 0 {
+			errs = append(errs, &FieldError{Key: key + ".rate", Msg: "cannot be negative"})
+		}
+		if rate > 0 && burst < 1 {
+			errs = append(errs, &FieldError{Key: key + ".burst", Msg: fmt.Sprintf("is %d, must be at least 1 when rate is set", burst)})
+		}
+	}
+	check("ratelimit.default", r.Default.Rate, r.Default.Burst)
+	for name, group := range r.Groups {
+		check("ratelimit.groups."+name, group.Rate, group.Burst)
+		if len(group.Routes) == 0 {
+			errs = append(errs, &FieldError{Key: "ratelimit.groups." + name + ".routes", Msg: "is empty"})
+		}
+	}
+	return errs
+}
+
+// NeedsRestart reports whether next changes settings that are only applied
+// on start up. The log level and rate limits are applied while running.
+func (c Config) NeedsRestart(next Config) bool {
+	return !reflect.DeepEqual(c.withoutReloadable(), next.withoutReloadable())
+}
+
+func (c Config) withoutReloadable() Config {
+	c.Log = LogConfig{}
+	c.RateLimit.Default = LimitConfig{}
+	c.RateLimit.Groups = nil
+	return c
+}
+
+// Watch loads the config file again whenever it changes and passes the
+// result to onChange, or the error to onError when it does not load. It
+// returns at once; without a config file there is nothing to watch.
+func Watch(current Config, onChange func(next Config), onError func(err error)) {
+	if current.File == "" {
+		return
+	}
+	v := viper.New()
+	v.SetConfigType("toml")
+	v.SetConfigFile(current.File)
+	v.OnConfigChange(func(fsnotify.Event) {
+		next, err := Load(current.File)
+		if err != nil {
+			onError(err)
+			return
+		}
+		onChange(next)
+	})
+	v.WatchConfig()
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	// Without a config file in the working directory the defaults apply.
+	t.Chdir(t.TempDir())
+	config, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.File != "" || config.App.Port != 8080 || config.Database.ConnMaxLifetime != 30*time.Minute || config.Log.Level != "info" {
+		t.Errorf("Load() = %+v", config)
+	}
+	if _, ok := config.Tenants["default"]; !ok {
+		t.Errorf("Tenants = %v, want the default tenant", config.Tenants)
+	}
+}
+
+func TestLoadRepositoryConfig(t *testing.T) {
+	config, err := Load(filepath.Join("..", DefaultFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Database.Host != "postgres_db" || len(config.RateLimit.Groups) == 0 || config.RateLimit.Groups["customer-write"].Burst != 5 {
+		t.Errorf("Load() = %+v", config)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.toml")); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+}
+
+func TestLoadUnknownKey(t *testing.T) {
+	path := writeFile(t, "config.toml", "[databse]\nhost = \"db\"\n")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "databse") {
+		t.Errorf("Load() = %v, want the unknown key reported", err)
+	}
+}
+
+func TestLoadEnvironment(t *testing.T) {
+	path := writeFile(t, "config.toml", "[database]\nhost = \"postgres_db\"\n[app]\nport = 8080\n")
+	t.Setenv("APP_DATABASE_HOST", "db.internal")
+	t.Setenv("APP_APP_PORT", "9090")
+	t.Setenv("APP_DATABASE_CONN_MAX_LIFETIME", "1h")
+	t.Setenv("APP_ENCRYPTION_FIELDS", "email,phone")
+	t.Setenv("APP_CACHE_REDIS_DB", "3")
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Database.Host != "db.internal" || config.App.Port != 9090 || config.Database.ConnMaxLifetime != time.Hour || config.Cache.Redis.DB != 3 {
+		t.Errorf("Load() = %+v, want the environment to win", config)
+	}
+	if strings.Join(config.Encryption.Fields, " ") != "email phone" {
+		t.Errorf("Encryption.Fields = %v", config.Encryption.Fields)
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	password := writeFile(t, "password", "s3cret\n")
+	tokenSecret := writeFile(t, "token-secret", "t0ken")
+	path := writeFile(t, "config.toml", "[database]\npassword_file = \""+filepath.ToSlash(password)+"\"\n")
+	t.Setenv("APP_TENANT_TOKEN_SECRET_FILE", tokenSecret)
+	t.Setenv("APP_DATABASE_APP_PASSWORD_FILE", password)
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Database.Password != "s3cret" || config.Tenant.TokenSecret != "t0ken" || config.Database.AppPassword != "s3cret" {
+		t.Errorf("secrets = %q, %q and %q", config.Database.Password, config.Tenant.TokenSecret, config.Database.AppPassword)
+	}
+
+	t.Setenv("APP_DATABASE_PASSWORD", "plain")
+	t.Setenv("APP_TENANT_TOKEN_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = Load(path)
+	for _, key := range []string{"database.password", "tenant.token_secret_file"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("Load() = %v, want %s reported", err, key)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	config := Default()
+	config.Tenants = defaultTenants()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() of the defaults = %v", err)
+	}
+
+	config.App.Port = 0
+	config.App.TrustedProxies = []string{"10.0.0.0/8", "proxy.internal"}
+	config.Log.Level = "loud"
+	config.Database.Host = ""
+	config.Database.SSLMode = "maybe"
+	config.Tenant.Default = "acme"
+	config.Cache.Backend = "memcached"
+	config.Attachment.Backend = "s3"
+	config.Encryption.Fields = []string{"email", "name"}
+	config.Dedupe.Threshold = 2
+	config.RateLimit.Groups = map[string]GroupConfig{"customer-read": {Rate: 1}}
+	config.FollowUp.Interval = 0
+
+	err := config.Validate()
+	wantKeys := []string{
+		"app.port", "app.trusted_proxies", "log.level", "database.host", "database.sslmode", "tenant.default", "cache.backend",
+		"attachment.s3.bucket", "encryption.fields", "dedupe.threshold", "ratelimit.groups.customer-read.burst",
+		"ratelimit.groups.customer-read.routes", "followup.interval",
+	}
+	for _, key := range wantKeys {
+		if err == nil || !strings.Contains(err.Error(), "config: "+key+" ") {
+			t.Errorf("Validate() = %v, want %s reported", err, key)
+		}
+	}
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Errorf("Validate() = %T, want a *FieldError", err)
+	}
+
+	config = Default()
+	config.Tenants = defaultTenants()
+	config.Tenant.TrustHeader = true
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "config: tenant.trust_header ") {
+		t.Errorf("Validate() = %v, want tenant.trust_header without trusted proxies reported", err)
+	}
+
+	config = Default()
+	config.Tenants = defaultTenants()
+	config.App.Environment = "production"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "config: privacy.signing_key ") {
+		t.Errorf("Validate() = %v, want privacy.signing_key outside development reported", err)
+	}
+	config.Privacy.SigningKey = "key"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() with a signing key = %v", err)
+	}
+}
+
+func TestNeedsRestart(t *testing.T) {
+	config := Default()
+	next := Default()
+	next.Log.Level = "debug"
+	next.RateLimit.Default.Rate = 1
+	next.RateLimit.Groups = map[string]GroupConfig{"customer-read": {Routes: []string{"GET /customer"}, Rate: 1, Burst: 1}}
+	if config.NeedsRestart(next) {
+		t.Error("NeedsRestart() for the log level and rate limits = true")
+	}
+	next.Database.Host = "db.internal"
+	if !config.NeedsRestart(next) {
+		t.Error("NeedsRestart() for the database host = false")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := writeFile(t, "config.toml", "[log]\nlevel = \"info\"\n")
+	config, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan Config, 10)
+	failures := make(chan error, 10)
+	Watch(config, func(next Config) { changes <- next }, func(err error) { failures <- err })
+
+	if err := os.WriteFile(path, []byte("[log]\nlevel = \"loud\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(5 * time.Second)
+	for reported := false; !reported; {
+		select {
+		case err := <-failures:
+			reported = strings.Contains(err.Error(), "log.level")
+		case next := <-changes:
+			// Writes can be seen half done, e.g. as an empty file.
+			if next.Log.Level == "loud" {
+				t.Fatalf("onChange(%+v) for an invalid level", next.Log)
+			}
+		case <-deadline:
+			t.Fatal("no reload after writing an invalid config")
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("[log]\nlevel = \"debug\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	deadline = time.After(5 * time.Second)
+	for {
+		select {
+		case next := <-changes:
+			if next.Log.Level == "debug" {
+				return
+			}
+		case <-failures:
+		case <-deadline:
+			t.Fatal("no reload after writing a valid config")
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Package content reads request bodies and writes responses as JSON, XML,
+// MessagePack or protobuf, picked by the Content-Type and Accept headers.
+package content
+
+//go:generate protoc -I.. --go_out=.. --go_opt=paths=source_relative content/pb/api.proto
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	MIMEJSON     = binding.MIMEJSON
+	MIMEXML      = binding.MIMEXML
+	MIMEMsgPack  = binding.MIMEMSGPACK2
+	MIMEProtobuf = binding.MIMEPROTOBUF
+
+	mimeProtobuf2 = "application/protobuf"
+)
+
+// Offers are the media types responses can be sent as, JSON first as the
+// default. The aliases answer with the media type the client asked for.
+var Offers = []string{
+	MIMEJSON,
+	MIMEXML, binding.MIMEXML2,
+	MIMEMsgPack, binding.MIMEMSGPACK,
+	MIMEProtobuf, mimeProtobuf2,
+}
+
+// msgpackHandle writes times as the MessagePack timestamp extension and reads
+// strings as strings rather than bytes.
+var msgpackHandle = func() *codec.MsgpackHandle {
+	handle := &codec.MsgpackHandle{WriteExt: true}
+	handle.RawToString = true
+	return handle
+}()
+
+// Negotiate returns the offer the Accept header prefers, JSON when it
+// accepts none of them.
+func Negotiate(ctx *gin.Context) string {
+	if mediaType := ctx.NegotiateFormat(Offers...); mediaType != "" {
+		return mediaType
+	}
+	return MIMEJSON
+}
+
+// Render writes value in the negotiated media type. Values protobuf has no
+// message for are answered with 406 Not Acceptable. Values that fail to
+// encode are answered with 500 rather than code and part of the body.
+func Render(ctx *gin.Context, code int, value interface{}) {
+	ctx.Header("Vary", "Accept")
+	mediaType := Negotiate(ctx)
+	var encode func(w io.Writer) error
+	switch mediaType {
+	case MIMEXML, binding.MIMEXML2:
+		encode = func(w io.Writer) error { return encodeXML(w, value) }
+	case MIMEMsgPack, binding.MIMEMSGPACK:
+		encode = func(w io.Writer) error { return codec.NewEncoder(w, msgpackHandle).Encode(value) }
+	case MIMEProtobuf, mimeProtobuf2:
+		message, err := toProto(value)
+		if err != nil {
+			ctx.AbortWithError(http.StatusNotAcceptable, err)
+			return
+		}
+		encode = func(w io.Writer) error { return encodeProto(w, message) }
+	default:
+		ctx.JSON(code, value)
+		return
+	}
+	var buf bytes.Buffer
+	if err := encode(&buf); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Data(code, mediaType+"; charset=utf-8", buf.Bytes())
+}
+
+// Bind decodes the request body into value, a pointer, by its Content-Type.
+// A body without one is read as JSON. Like gin's Bind the result is
+// validated, but Bind does not abort the request on errors.
+func Bind(ctx *gin.Context, value interface{}) error {
+	var err error
+	switch contentType := ctx.ContentType(); contentType {
+	case MIMEJSON, "":
+		err = json.NewDecoder(ctx.Request.Body).Decode(value)
+	case MIMEXML, binding.MIMEXML2:
+		err = xml.NewDecoder(ctx.Request.Body).Decode(value)
+	case MIMEMsgPack, binding.MIMEMSGPACK:
+		err = codec.NewDecoder(ctx.Request.Body, msgpackHandle).Decode(value)
+	case MIMEProtobuf, mimeProtobuf2:
+		var b []byte
+		if b, err = io.ReadAll(ctx.Request.Body); err == nil {
+			err = fromProto(b, value)
+		}
+	default:
+		return fmt.Errorf("unsupported content type %s, send one of %s, %s, %s or %s", contentType, MIMEJSON, MIMEXML, MIMEMsgPack, MIMEProtobuf)
+	}
+	if err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(value)
+}
@@ -0,0 +1,304 @@
+package content
+
+import (
+	"bytes"
+	"customer-playground/content/pb"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+// echo answers a customer or note with what it was sent, in the media type
+// the request accepts.
+func echo() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/customer", func(ctx *gin.Context) {
+		var customer domain.Customer
+		if err := Bind(ctx, &customer); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		Render(ctx, http.StatusOK, customer)
+	})
+	r.POST("/note", func(ctx *gin.Context) {
+		var note domain.CustomerNote
+		if err := Bind(ctx, &note); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		Render(ctx, http.StatusOK, []domain.CustomerNote{note})
+	})
+	return r
+}
+
+// format encodes and decodes request and response bodies of one media type
+// the way a client would.
+type format struct {
+	mediaType string
+	encode    func(value interface{}) ([]byte, error)
+	decode    func(b []byte, value interface{}) error
+}
+
+var testFormats = []format{
+	{
+		mediaType: MIMEJSON,
+		encode:    json.Marshal,
+		decode:    json.Unmarshal,
+	},
+	{
+		mediaType: MIMEXML,
+		encode:    xml.Marshal,
+		decode: func(b []byte, value interface{}) error {
+			if notes, ok := value.(*[]domain.CustomerNote); ok {
+				var list struct {
+					Notes []domain.CustomerNote `xml:"note"`
+				}
+				err := xml.Unmarshal(b, &list)
+				*notes = list.Notes
+				return err
+			}
+			return xml.Unmarshal(b, value)
+		},
+	},
+	{
+		mediaType: MIMEMsgPack,
+		encode: func(value interface{}) ([]byte, error) {
+			var b []byte
+			err := codec.NewEncoderBytes(&b, msgpackHandle).Encode(value)
+			return b, err
+		},
+		decode: func(b []byte, value interface{}) error {
+			return codec.NewDecoderBytes(b, msgpackHandle).Decode(value)
+		},
+	},
+	{
+		mediaType: MIMEProtobuf,
+		encode: func(value interface{}) ([]byte, error) {
+			message, err := toProto(value)
+			if err != nil {
+				return nil, err
+			}
+			return proto.Marshal(message)
+		},
+		decode: func(b []byte, value interface{}) error {
+			switch value := value.(type) {
+			case *[]domain.CustomerNote:
+				var list pb.CustomerNoteList
+				if err := proto.Unmarshal(b, &list); err != nil {
+					return err
+				}
+				for _, message := range list.Notes {
+					*value = append(*value, noteFromProto(message))
+				}
+				return nil
+			}
+			return fromProto(b, value)
+		},
+	},
+}
+
+func post(t *testing.T, path string, f format, value interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	b, err := f.encode(value)
+	if err != nil {
+		t.Fatalf("encode %s: %v", f.mediaType, err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", f.mediaType)
+	req.Header.Set("Accept", f.mediaType)
+	rec := httptest.NewRecorder()
+	echo().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST %s as %s = %d", path, f.mediaType, rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, f.mediaType) {
+		t.Errorf("Content-Type = %q, want %s", contentType, f.mediaType)
+	}
+	return rec
+}
+
+func TestCustomerRoundTrip(t *testing.T) {
+	birthDate := time.Date(1995, 6, 12, 0, 0, 0, 0, time.UTC)
+	customer := domain.Customer{
+		CustomerNumber: 7,
+		Name:           "John Doe",
+		Email:          "john@example.com",
+		BirthDate:      types.NullTime{Time: birthDate, Valid: true},
+		CustomFields:   types.JSONMap{"tier": "gold", "visits": float64(3), "newsletter": true},
+	}
+	for _, f := range testFormats {
+		t.Run(f.mediaType, func(t *testing.T) {
+			rec := post(t, "/customer", f, customer)
+			var got domain.Customer
+			if err := f.decode(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !got.BirthDate.Valid || !got.BirthDate.Time.Equal(birthDate) {
+				t.Errorf("birth date = %+v, want %v", got.BirthDate, birthDate)
+			}
+			if got.CreatedAt.Valid || got.UpdatedAt.Valid {
+				t.Errorf("null times came back as %+v and %+v", got.CreatedAt, got.UpdatedAt)
+			}
+			if !reflect.DeepEqual(got.CustomFields, customer.CustomFields) {
+				t.Errorf("custom fields = %#v, want %#v", got.CustomFields, customer.CustomFields)
+			}
+			got.BirthDate = customer.BirthDate
+			if !reflect.DeepEqual(got, customer) {
+				t.Errorf("customer = %+v, want %+v", got, customer)
+			}
+		})
+	}
+}
+
+func TestNoteRoundTrip(t *testing.T) {
+	pinned := false
+	dueAt := time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC)
+	note := domain.CustomerNote{
+		ID:             3,
+		CustomerNumber: 7,
+		Note:           "Call back about *billing*",
+		Category:       domain.NoteCategoryCall,
+		Pinned:         &pinned,
+		DueAt:          types.NullTime{Time: dueAt, Valid: true},
+		Mentions:       []string{"bob.agent", "jane.agent"},
+	}
+	for _, f := range testFormats {
+		t.Run(f.mediaType, func(t *testing.T) {
+			rec := post(t, "/note", f, note)
+			var got []domain.CustomerNote
+			if err := f.decode(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("notes = %+v", got)
+			}
+			if !got[0].DueAt.Time.Equal(dueAt) {
+				t.Errorf("due at = %v, want %v", got[0].DueAt.Time, dueAt)
+			}
+			got[0].DueAt = note.DueAt
+			if !reflect.DeepEqual(got[0], note) {
+				t.Errorf("note = %+v, want %+v", got[0], note)
+			}
+		})
+	}
+}
+
+func TestXMLBody(t *testing.T) {
+	rec := post(t, "/customer", testFormats[1], domain.Customer{
+		CustomerNumber: 7,
+		Name:           "John Doe",
+		CustomFields:   types.JSONMap{"tier": "gold", "visits": 3},
+	})
+	want := `<customer><customer_number>7</customer_number><name>John Doe</name><email></email>` +
+		`<custom_fields><tier>gold</tier><visits type="number">3</visits></custom_fields></customer>`
+	if body := strings.TrimPrefix(rec.Body.String(), xml.Header); body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
+
+func TestMsgPackBody(t *testing.T) {
+	birthDate := time.Date(1995, 6, 12, 0, 0, 0, 0, time.UTC)
+	rec := post(t, "/customer", testFormats[2], domain.Customer{
+		CustomerNumber: 7,
+		BirthDate:      types.NullTime{Time: birthDate, Valid: true},
+		CustomFields:   types.JSONMap{"visits": 3},
+	})
+	var body map[string]interface{}
+	if err := testFormats[2].decode(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	// A timestamp rather than the fields of NullTime, and no null times.
+	if got, ok := body["birth_date"].(time.Time); !ok || !got.Equal(birthDate) {
+		t.Errorf("birth_date = %#v, want a timestamp", body["birth_date"])
+	}
+	if _, ok := body["created_at"]; ok {
+		t.Errorf("body = %v, want created_at left out", body)
+	}
+	// The integer sent was read as float64, like JSON numbers.
+	if visits := body["custom_fields"].(map[interface{}]interface{})["visits"]; visits != float64(3) {
+		t.Errorf("visits = %#v, want float64", visits)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                                  MIMEJSON,
+		"*/*":                               MIMEJSON,
+		"image/png":                         MIMEJSON,
+		"text/xml":                          "text/xml",
+		"application/x-msgpack":             "application/x-msgpack",
+		"text/html;q=0.5, application/xml":  MIMEXML,
+		"application/protobuf, */*;q=0.1":   "application/protobuf",
+		"application/json, application/xml": MIMEJSON,
+	} {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.Header.Set("Accept", accept)
+		if got := Negotiate(ctx); got != want {
+			t.Errorf("Negotiate(%q) = %s, want %s", accept, got, want)
+		}
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	for contentType, body := range map[string]string{
+		"text/plain":       "John Doe",
+		MIMEXML:            "<customer><birth_date>yesterday</birth_date></customer>",
+		MIMEXML + " ":      `<customer><custom_fields><visits type="number">many</visits></custom_fields></customer>`,
+		MIMEProtobuf:       "not protobuf",
+		"application/json": "{",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/customer", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		echo().ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("POST as %s = %d, want 400", contentType, rec.Code)
+		}
+	}
+}
+
+func TestRenderProtobufWithoutMessage(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx.Request.Header.Set("Accept", MIMEProtobuf)
+	Render(ctx, http.StatusOK, map[string]int{"count": 1})
+	if rec.Code != http.StatusNotAcceptable {
+		t.Errorf("Render() = %d, want 406", rec.Code)
+	}
+}
+
+// unencodable has an XML element, but XML cannot encode its field.
+type unencodable struct {
+	Name   string         `xml:"name"`
+	Fields map[string]int `xml:"fields"`
+}
+
+func TestRenderEncodingError(t *testing.T) {
+	elements[reflect.TypeOf(unencodable{})] = "unencodable"
+	defer delete(elements, reflect.TypeOf(unencodable{}))
+
+	for _, value := range []interface{}{unencodable{Name: "x", Fields: map[string]int{"count": 1}}, map[string]int{"count": 1}} {
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.Header.Set("Accept", MIMEXML)
+		Render(ctx, http.StatusOK, value)
+		if rec.Code != http.StatusInternalServerError || rec.Body.Len() != 0 {
+			t.Errorf("Render(%T) = %d %q, want 500 without a body", value, rec.Code, rec.Body)
+		}
+	}
+}
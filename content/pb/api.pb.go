@@ -0,0 +1,740 @@
+// Protobuf encoding of the customer and note API, selected with
+// application/x-protobuf. Field names follow the JSON names of the domain
+// types; optional timestamps are unset when the JSON value is null.
+// Regenerate api.pb.go with go generate ./content.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: content/pb/api.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Customer struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	CustomerNumber int64                  `protobuf:"varint,1,opt,name=customer_number,json=customerNumber,proto3" json:"customer_number,omitempty"`
+	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Email          string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	Phone          string                 `protobuf:"bytes,4,opt,name=phone,proto3" json:"phone,omitempty"`
+	BirthDate      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=birth_date,json=birthDate,proto3" json:"birth_date,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	CustomFields   *structpb.Struct       `protobuf:"bytes,8,opt,name=custom_fields,json=customFields,proto3" json:"custom_fields,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Customer) Reset() {
+	*x = Customer{}
+	mi := &file_content_pb_api_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Customer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Customer) ProtoMessage() {}
+
+func (x *Customer) ProtoReflect() protoreflect.Message {
+	mi := &file_content_pb_api_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Customer.ProtoReflect.Descriptor instead.
+func (*Customer) Descriptor() ([]byte, []int) {
+	return file_content_pb_api_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Customer) GetCustomerNumber() int64 {
+	if x != nil {
+		return x.CustomerNumber
+	}
+	return 0
+}
+
+func (x *Customer) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Customer) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *Customer) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *Customer) GetBirthDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.BirthDate
+	}
+	return nil
+}
+
+func (x *Customer) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Customer) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Customer) GetCustomFields() *structpb.Struct {
+	if x != nil {
+		return x.CustomFields
+	}
+	return nil
+}
+
+type CustomerList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Customers     []*Customer            `protobuf:"bytes,1,rep,name=customers,proto3" json:"customers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CustomerList) Reset() {
+	*x = CustomerList{}
+	mi := &file_content_pb_api_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CustomerList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomerList) ProtoMessage() {}
+
+func (x *CustomerList) ProtoReflect() protoreflect.Message {
+	mi := &file_content_pb_api_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomerList.ProtoReflect.Descriptor instead.
+func (*CustomerList) Descriptor() ([]byte, []int) {
+	return file_content_pb_api_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CustomerList) GetCustomers() []*Customer {
+	if x != nil {
+		return x.Customers
+	}
+	return nil
+}
+
+type CustomerNote struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	CustomerNumber int64                  `protobuf:"varint,2,opt,name=customer_number,json=customerNumber,proto3" json:"customer_number,omitempty"`
+	Note           string                 `protobuf:"bytes,3,opt,name=note,proto3" json:"note,omitempty"`
+	Author         string                 `protobuf:"bytes,4,opt,name=author,proto3" json:"author,omitempty"`
+	Category       string                 `protobuf:"bytes,5,opt,name=category,proto3" json:"category,omitempty"`
+	// Unset keeps the current value on updates.
+	Pinned        *bool                  `protobuf:"varint,6,opt,name=pinned,proto3,oneof" json:"pinned,omitempty"`
+	Visibility    string                 `protobuf:"bytes,7,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	DueAt         *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=due_at,json=dueAt,proto3" json:"due_at,omitempty"`
+	Assignee      string                 `protobuf:"bytes,9,opt,name=assignee,proto3" json:"assignee,omitempty"`
+	Status        string                 `protobuf:"bytes,10,opt,name=status,proto3" json:"status,omitempty"`
+	Mentions      []string               `protobuf:"bytes,11,rep,name=mentions,proto3" json:"mentions,omitempty"`
+	Revision      int64                  `protobuf:"varint,12,opt,name=revision,proto3" json:"revision,omitempty"`
+	UpdatedBy     string                 `protobuf:"bytes,13,opt,name=updated_by,json=updatedBy,proto3" json:"updated_by,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CustomerNote) Reset() {
+	*x = CustomerNote{}
+	mi := &file_content_pb_api_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CustomerNote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomerNote) ProtoMessage() {}
+
+func (x *CustomerNote) ProtoReflect() protoreflect.Message {
+	mi := &file_content_pb_api_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomerNote.ProtoReflect.Descriptor instead.
+func (*CustomerNote) Descriptor() ([]byte, []int) {
+	return file_content_pb_api_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CustomerNote) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *CustomerNote) GetCustomerNumber() int64 {
+	if x != nil {
+		return x.CustomerNumber
+	}
+	return 0
+}
+
+func (x *CustomerNote) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *CustomerNote) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *CustomerNote) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *CustomerNote) GetPinned() bool {
+	if x != nil && x.Pinned != nil {
+		return *x.Pinned
+	}
+	return false
+}
+
+func (x *CustomerNote) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+func (x *CustomerNote) GetDueAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DueAt
+	}
+	return nil
+}
+
+func (x *CustomerNote) GetAssignee() string {
+	if x != nil {
+		return x.Assignee
+	}
+	return ""
+}
+
+func (x *CustomerNote) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CustomerNote) GetMentions() []string {
+	if x != nil {
+		return x.Mentions
+	}
+	return nil
+}
+
+func (x *CustomerNote) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *CustomerNote) GetUpdatedBy() string {
+	if x != nil {
+		return x.UpdatedBy
+	}
+	return ""
+}
+
+func (x *CustomerNote) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *CustomerNote) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type CustomerNoteList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Notes         []*CustomerNote        `protobuf:"bytes,1,rep,name=notes,proto3" json:"notes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CustomerNoteList) Reset() {
+	*x = CustomerNoteList{}
+	mi := &file_content_pb_api_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CustomerNoteList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomerNoteList) ProtoMessage() {}
+
+func (x *CustomerNoteList) ProtoReflect() protoreflect.Message {
+	mi := &file_content_pb_api_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomerNoteList.ProtoReflect.Descriptor instead.
+func (*CustomerNoteList) Descriptor() ([]byte, []int) {
+	return file_content_pb_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CustomerNoteList) GetNotes() []*CustomerNote {
+	if x != nil {
+		return x.Notes
+	}
+	return nil
+}
+
+type CustomerNoteRevision struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Revision      int64                  `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	Note          string                 `protobuf:"bytes,2,opt,name=note,proto3" json:"note,omitempty"`
+	Category      string                 `protobuf:"bytes,3,opt,name=category,proto3" json:"category,omitempty"`
+	Pinned        bool                   `protobuf:"varint,4,opt,name=pinned,proto3" json:"pinned,omitempty"`
+	Visibility    string                 `protobuf:"bytes,5,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	DueAt         *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=due_at,json=dueAt,proto3" json:"due_at,omitempty"`
+	Assignee      string                 `protobuf:"bytes,7,opt,name=assignee,proto3" json:"assignee,omitempty"`
+	Status        string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	EditedBy      string                 `protobuf:"bytes,9,opt,name=edited_by,json=editedBy,proto3" json:"edited_by,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Diff          string                 `protobuf:"bytes,11,opt,name=diff,proto3" json:"diff,omitempty"`
+	Changes       []string               `protobuf:"bytes,12,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CustomerNoteRevision) Reset() {
+	*x = CustomerNoteRevision{}
+	mi := &file_content_pb_api_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CustomerNoteRevision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomerNoteRevision) ProtoMessage() {}
+
+func (x *CustomerNoteRevision) ProtoReflect() protoreflect.Message {
+	mi := &file_content_pb_api_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomerNoteRevision.ProtoReflect.Descriptor instead.
+func (*CustomerNoteRevision) Descriptor() ([]byte, []int) {
+	return file_content_pb_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CustomerNoteRevision) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *CustomerNoteRevision) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *CustomerNoteRevision) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *CustomerNoteRevision) GetPinned() bool {
+	if x != nil {
+		return x.Pinned
+	}
+	return false
+}
+
+func (x *CustomerNoteRevision) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+func (x *CustomerNoteRevision) GetDueAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DueAt
+	}
+	return nil
+}
+
+func (x *CustomerNoteRevision) GetAssignee() string {
+	if x != nil {
+		return x.Assignee
+	}
+	return ""
+}
+
+func (x *CustomerNoteRevision) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CustomerNoteRevision) GetEditedBy() string {
+	if x != nil {
+		return x.EditedBy
+	}
+	return ""
+}
+
+func (x *CustomerNoteRevision) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *CustomerNoteRevision) GetDiff() string {
+	if x != nil {
+		return x.Diff
+	}
+	return ""
+}
+
+func (x *CustomerNoteRevision) GetChanges() []string {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+type CustomerNoteRevisionList struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Revisions     []*CustomerNoteRevision `protobuf:"bytes,1,rep,name=revisions,proto3" json:"revisions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CustomerNoteRevisionList) Reset() {
+	*x = CustomerNoteRevisionList{}
+	mi := &file_content_pb_api_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CustomerNoteRevisionList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomerNoteRevisionList) ProtoMessage() {}
+
+func (x *CustomerNoteRevisionList) ProtoReflect() protoreflect.Message {
+	mi := &file_content_pb_api_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomerNoteRevisionList.ProtoReflect.Descriptor instead.
+func (*CustomerNoteRevisionList) Descriptor() ([]byte, []int) {
+	return file_content_pb_api_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CustomerNoteRevisionList) GetRevisions() []*CustomerNoteRevision {
+	if x != nil {
+		return x.Revisions
+	}
+	return nil
+}
+
+type Response struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Statuscode    int64                  `protobuf:"varint,2,opt,name=statuscode,proto3" json:"statuscode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Response) Reset() {
+	*x = Response{}
+	mi := &file_content_pb_api_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Response) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response) ProtoMessage() {}
+
+func (x *Response) ProtoReflect() protoreflect.Message {
+	mi := &file_content_pb_api_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response.ProtoReflect.Descriptor instead.
+func (*Response) Descriptor() ([]byte, []int) {
+	return file_content_pb_api_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Response) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Response) GetStatuscode() int64 {
+	if x != nil {
+		return x.Statuscode
+	}
+	return 0
+}
+
+var File_content_pb_api_proto protoreflect.FileDescriptor
+
+const file_content_pb_api_proto_rawDesc = "" +
+	"\n" +
+	"\x14content/pb/api.proto\x12\x16customerplayground.api\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xe2\x02\n" +
+	"\bCustomer\x12'\n" +
+	"\x0fcustomer_number\x18\x01 \x01(\x03R\x0ecustomerNumber\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05email\x18\x03 \x01(\tR\x05email\x12\x14\n" +
+	"\x05phone\x18\x04 \x01(\tR\x05phone\x129\n" +
+	"\n" +
+	"birth_date\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tbirthDate\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12<\n" +
+	"\rcustom_fields\x18\b \x01(\v2\x17.google.protobuf.StructR\fcustomFields\"N\n" +
+	"\fCustomerList\x12>\n" +
+	"\tcustomers\x18\x01 \x03(\v2 .customerplayground.api.CustomerR\tcustomers\"\x8b\x04\n" +
+	"\fCustomerNote\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12'\n" +
+	"\x0fcustomer_number\x18\x02 \x01(\x03R\x0ecustomerNumber\x12\x12\n" +
+	"\x04note\x18\x03 \x01(\tR\x04note\x12\x16\n" +
+	"\x06author\x18\x04 \x01(\tR\x06author\x12\x1a\n" +
+	"\bcategory\x18\x05 \x01(\tR\bcategory\x12\x1b\n" +
+	"\x06pinned\x18\x06 \x01(\bH\x00R\x06pinned\x88\x01\x01\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\a \x01(\tR\n" +
+	"visibility\x121\n" +
+	"\x06due_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\x05dueAt\x12\x1a\n" +
+	"\bassignee\x18\t \x01(\tR\bassignee\x12\x16\n" +
+	"\x06status\x18\n" +
+	" \x01(\tR\x06status\x12\x1a\n" +
+	"\bmentions\x18\v \x03(\tR\bmentions\x12\x1a\n" +
+	"\brevision\x18\f \x01(\x03R\brevision\x12\x1d\n" +
+	"\n" +
+	"updated_by\x18\r \x01(\tR\tupdatedBy\x129\n" +
+	"\n" +
+	"created_at\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\t\n" +
+	"\a_pinned\"N\n" +
+	"\x10CustomerNoteList\x12:\n" +
+	"\x05notes\x18\x01 \x03(\v2$.customerplayground.api.CustomerNoteR\x05notes\"\x87\x03\n" +
+	"\x14CustomerNoteRevision\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x03R\brevision\x12\x12\n" +
+	"\x04note\x18\x02 \x01(\tR\x04note\x12\x1a\n" +
+	"\bcategory\x18\x03 \x01(\tR\bcategory\x12\x16\n" +
+	"\x06pinned\x18\x04 \x01(\bR\x06pinned\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\x05 \x01(\tR\n" +
+	"visibility\x121\n" +
+	"\x06due_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x05dueAt\x12\x1a\n" +
+	"\bassignee\x18\a \x01(\tR\bassignee\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status\x12\x1b\n" +
+	"\tedited_by\x18\t \x01(\tR\beditedBy\x129\n" +
+	"\n" +
+	"created_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x12\n" +
+	"\x04diff\x18\v \x01(\tR\x04diff\x12\x18\n" +
+	"\achanges\x18\f \x03(\tR\achanges\"f\n" +
+	"\x18CustomerNoteRevisionList\x12J\n" +
+	"\trevisions\x18\x01 \x03(\v2,.customerplayground.api.CustomerNoteRevisionR\trevisions\"D\n" +
+	"\bResponse\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x1e\n" +
+	"\n" +
+	"statuscode\x18\x02 \x01(\x03R\n" +
+	"statuscodeB Z\x1ecustomer-playground/content/pbb\x06proto3"
+
+var (
+	file_content_pb_api_proto_rawDescOnce sync.Once
+	file_content_pb_api_proto_rawDescData []byte
+)
+
+func file_content_pb_api_proto_rawDescGZIP() []byte {
+	file_content_pb_api_proto_rawDescOnce.Do(func() {
+		file_content_pb_api_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_content_pb_api_proto_rawDesc), len(file_content_pb_api_proto_rawDesc)))
+	})
+	return file_content_pb_api_proto_rawDescData
+}
+
+var file_content_pb_api_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_content_pb_api_proto_goTypes = []any{
+	(*Customer)(nil),                 // 0: customerplayground.api.Customer
+	(*CustomerList)(nil),             // 1: customerplayground.api.CustomerList
+	(*CustomerNote)(nil),             // 2: customerplayground.api.CustomerNote
+	(*CustomerNoteList)(nil),         // 3: customerplayground.api.CustomerNoteList
+	(*CustomerNoteRevision)(nil),     // 4: customerplayground.api.CustomerNoteRevision
+	(*CustomerNoteRevisionList)(nil), // 5: customerplayground.api.CustomerNoteRevisionList
+	(*Response)(nil),                 // 6: customerplayground.api.Response
+	(*timestamppb.Timestamp)(nil),    // 7: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),          // 8: google.protobuf.Struct
+}
+var file_content_pb_api_proto_depIdxs = []int32{
+	7,  // 0: customerplayground.api.Customer.birth_date:type_name -> google.protobuf.Timestamp
+	7,  // 1: customerplayground.api.Customer.created_at:type_name -> google.protobuf.Timestamp
+	7,  // 2: customerplayground.api.Customer.updated_at:type_name -> google.protobuf.Timestamp
+	8,  // 3: customerplayground.api.Customer.custom_fields:type_name -> google.protobuf.Struct
+	0,  // 4: customerplayground.api.CustomerList.customers:type_name -> customerplayground.api.Customer
+	7,  // 5: customerplayground.api.CustomerNote.due_at:type_name -> google.protobuf.Timestamp
+	7,  // 6: customerplayground.api.CustomerNote.created_at:type_name -> google.protobuf.Timestamp
+	7,  // 7: customerplayground.api.CustomerNote.updated_at:type_name -> google.protobuf.Timestamp
+	2,  // 8: customerplayground.api.CustomerNoteList.notes:type_name -> customerplayground.api.CustomerNote
+	7,  // 9: customerplayground.api.CustomerNoteRevision.due_at:type_name -> google.protobuf.Timestamp
+	7,  // 10: customerplayground.api.CustomerNoteRevision.created_at:type_name -> google.protobuf.Timestamp
+	4,  // 11: customerplayground.api.CustomerNoteRevisionList.revisions:type_name -> customerplayground.api.CustomerNoteRevision
+	12, // [12:12] is the sub-list for method output_type
+	12, // [12:12] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_content_pb_api_proto_init() }
+func file_content_pb_api_proto_init() {
+	if File_content_pb_api_proto != nil {
+		return
+	}
+	file_content_pb_api_proto_msgTypes[2].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_content_pb_api_proto_rawDesc), len(file_content_pb_api_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_content_pb_api_proto_goTypes,
+		DependencyIndexes: file_content_pb_api_proto_depIdxs,
+		MessageInfos:      file_content_pb_api_proto_msgTypes,
+	}.Build()
+	File_content_pb_api_proto = out.File
+	file_content_pb_api_proto_goTypes = nil
+	file_content_pb_api_proto_depIdxs = nil
+}
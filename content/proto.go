@@ -0,0 +1,187 @@
+package content
+
+import (
+	"customer-playground/content/pb"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func encodeProto(w io.Writer, message proto.Message) error {
+	b, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// toProto returns the message of a response value.
+func toProto(value interface{}) (proto.Message, error) {
+	switch value := value.(type) {
+	case domain.Customer:
+		return customerToProto(value)
+	case []domain.Customer:
+		list := &pb.CustomerList{Customers: make([]*pb.Customer, 0, len(value))}
+		for _, customer := range value {
+			message, err := customerToProto(customer)
+			if err != nil {
+				return nil, err
+			}
+			list.Customers = append(list.Customers, message)
+		}
+		return list, nil
+	case domain.CustomerNote:
+		return noteToProto(value), nil
+	case []domain.CustomerNote:
+		list := &pb.CustomerNoteList{Notes: make([]*pb.CustomerNote, 0, len(value))}
+		for _, note := range value {
+			list.Notes = append(list.Notes, noteToProto(note))
+		}
+		return list, nil
+	case []domain.CustomerNoteRevision:
+		list := &pb.CustomerNoteRevisionList{Revisions: make([]*pb.CustomerNoteRevision, 0, len(value))}
+		for _, revision := range value {
+			list.Revisions = append(list.Revisions, revisionToProto(revision))
+		}
+		return list, nil
+	case domain.Response:
+		return &pb.Response{Message: value.Message, Statuscode: int64(value.StatusCode)}, nil
+	}
+	return nil, fmt.Errorf("no protobuf message for %T", value)
+}
+
+// fromProto decodes b into value, a pointer to a request type.
+func fromProto(b []byte, value interface{}) error {
+	switch value := value.(type) {
+	case *domain.Customer:
+		var message pb.Customer
+		if err := proto.Unmarshal(b, &message); err != nil {
+			return err
+		}
+		*value = customerFromProto(&message)
+		return nil
+	case *domain.CustomerNote:
+		var message pb.CustomerNote
+		if err := proto.Unmarshal(b, &message); err != nil {
+			return err
+		}
+		*value = noteFromProto(&message)
+		return nil
+	}
+	return fmt.Errorf("no protobuf message for %T", value)
+}
+
+func customerToProto(customer domain.Customer) (*pb.Customer, error) {
+	message := &pb.Customer{
+		CustomerNumber: int64(customer.CustomerNumber),
+		Name:           customer.Name,
+		Email:          customer.Email,
+		Phone:          customer.Phone,
+		BirthDate:      timestamp(customer.BirthDate),
+		CreatedAt:      timestamp(customer.CreatedAt),
+		UpdatedAt:      timestamp(customer.UpdatedAt),
+	}
+	if customer.CustomFields != nil {
+		customFields, err := structpb.NewStruct(customer.CustomFields)
+		if err != nil {
+			return nil, fmt.Errorf("custom fields of customer %d: %w", customer.CustomerNumber, err)
+		}
+		message.CustomFields = customFields
+	}
+	return message, nil
+}
+
+func customerFromProto(message *pb.Customer) domain.Customer {
+	customer := domain.Customer{
+		CustomerNumber: int(message.CustomerNumber),
+		Name:           message.Name,
+		Email:          message.Email,
+		Phone:          message.Phone,
+		BirthDate:      nullTime(message.BirthDate),
+		CreatedAt:      nullTime(message.CreatedAt),
+		UpdatedAt:      nullTime(message.UpdatedAt),
+	}
+	// Struct numbers are float64, as the custom field validation expects.
+	if fields := message.CustomFields.AsMap(); len(fields) > 0 {
+		customer.CustomFields = fields
+	}
+	return customer
+}
+
+func noteToProto(note domain.CustomerNote) *pb.CustomerNote {
+	return &pb.CustomerNote{
+		Id:             int64(note.ID),
+		CustomerNumber: int64(note.CustomerNumber),
+		Note:           note.Note,
+		Author:         note.Author,
+		Category:       note.Category,
+		Pinned:         note.Pinned,
+		Visibility:     note.Visibility,
+		DueAt:          timestamp(note.DueAt),
+		Assignee:       note.Assignee,
+		Status:         note.Status,
+		Mentions:       note.Mentions,
+		Revision:       int64(note.Revision),
+		UpdatedBy:      note.UpdatedBy,
+		CreatedAt:      timestamp(note.CreatedAt),
+		UpdatedAt:      timestamp(note.UpdatedAt),
+	}
+}
+
+func noteFromProto(message *pb.CustomerNote) domain.CustomerNote {
+	return domain.CustomerNote{
+		ID:             int(message.Id),
+		CustomerNumber: int(message.CustomerNumber),
+		Note:           message.Note,
+		Author:         message.Author,
+		Category:       message.Category,
+		Pinned:         message.Pinned,
+		Visibility:     message.Visibility,
+		DueAt:          nullTime(message.DueAt),
+		Assignee:       message.Assignee,
+		Status:         message.Status,
+		Mentions:       message.Mentions,
+		Revision:       int(message.Revision),
+		UpdatedBy:      message.UpdatedBy,
+		CreatedAt:      nullTime(message.CreatedAt),
+		UpdatedAt:      nullTime(message.UpdatedAt),
+	}
+}
+
+func revisionToProto(revision domain.CustomerNoteRevision) *pb.CustomerNoteRevision {
+	return &pb.CustomerNoteRevision{
+		Revision:   int64(revision.Revision),
+		Note:       revision.Note,
+		Category:   revision.Category,
+		Pinned:     revision.Pinned,
+		Visibility: revision.Visibility,
+		DueAt:      timestamp(revision.DueAt),
+		Assignee:   revision.Assignee,
+		Status:     revision.Status,
+		EditedBy:   revision.EditedBy,
+		CreatedAt:  timestamp(revision.CreatedAt),
+		Diff:       revision.Diff,
+		Changes:    revision.Changes,
+	}
+}
+
+// timestamp leaves a null time unset.
+func timestamp(t types.NullTime) *timestamppb.Timestamp {
+	if !t.Valid {
+		return nil
+	}
+	return timestamppb.New(t.Time)
+}
+
+func nullTime(t *timestamppb.Timestamp) types.NullTime {
+	if t == nil {
+		return types.NullTime{}
+	}
+	return types.NullTime{Time: t.AsTime(), Valid: true}
+}
@@ -0,0 +1,53 @@
+package content
+
+import (
+	"customer-playground/domain"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// elements names the XML element of each response type. A list is wrapped in
+// the plural, e.g. <customers><customer>...</customer></customers>.
+var elements = map[reflect.Type]string{
+	reflect.TypeOf(domain.Customer{}):             "customer",
+	reflect.TypeOf(domain.CustomerNote{}):         "note",
+	reflect.TypeOf(domain.CustomerNoteRevision{}): "revision",
+	reflect.TypeOf(domain.Response{}):             "response",
+	reflect.TypeOf(domain.ErrorResponse{}):        "error",
+}
+
+func encodeXML(w io.Writer, value interface{}) error {
+	v := reflect.ValueOf(value)
+	list := v.Kind() == reflect.Slice
+	t := v.Type()
+	if list {
+		t = t.Elem()
+	}
+	name, ok := elements[t]
+	if !ok {
+		return fmt.Errorf("no XML element for %s", t)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	if !list {
+		return encoder.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: name}})
+	}
+	root := xml.StartElement{Name: xml.Name{Local: name + "s"}}
+	if err := encoder.EncodeToken(root); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := encoder.EncodeElement(v.Index(i).Interface(), xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
@@ -0,0 +1,243 @@
+// Package customfield validates customer custom field values against their
+// admin defined definitions and describes them as JSON schema.
+package customfield
+
+import (
+	"customer-playground/domain"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+var nameFormat = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ValidationError reports a definition or value that is not acceptable.
+type ValidationError struct {
+	Field string
+	Msg   string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("custom field %q %s", e.Field, e.Msg)
+}
+
+// CheckDefinition validates a definition before it is stored.
+func CheckDefinition(definition *domain.CustomFieldDefinition) error {
+	if !nameFormat.MatchString(definition.Name) {
+		return &ValidationError{Msg: fmt.Sprintf("%q is not a valid custom field name, use up to 50 lower case letters, digits or '_'", definition.Name)}
+	}
+	switch definition.Type {
+	case domain.CustomFieldTypeString, domain.CustomFieldTypeInteger, domain.CustomFieldTypeNumber,
+		domain.CustomFieldTypeBoolean, domain.CustomFieldTypeDate:
+		if len(definition.EnumValues) > 0 {
+			return &ValidationError{Field: definition.Name, Msg: "can only have enum_values when its type is enum"}
+		}
+	case domain.CustomFieldTypeEnum:
+		if len(definition.EnumValues) == 0 {
+			return &ValidationError{Field: definition.Name, Msg: "needs enum_values"}
+		}
+	default:
+		return &ValidationError{Field: definition.Name, Msg: fmt.Sprintf("has unknown type %q", definition.Type)}
+	}
+	if definition.Pattern != "" {
+		if definition.Type != domain.CustomFieldTypeString {
+			return &ValidationError{Field: definition.Name, Msg: "can only have a pattern when its type is string"}
+		}
+		if _, err := regexp.Compile(definition.Pattern); err != nil {
+			return &ValidationError{Field: definition.Name, Msg: fmt.Sprintf("has an invalid pattern: %v", err)}
+		}
+	}
+	return nil
+}
+
+// Validate checks values against definitions and returns them in canonical
+// form. Unknown fields are rejected, and required fields must be present when
+// requireAll is set.
+func Validate(definitions []domain.CustomFieldDefinition, values map[string]interface{}, requireAll bool) (map[string]interface{}, error) {
+	byName := make(map[string]domain.CustomFieldDefinition, len(definitions))
+	for _, definition := range definitions {
+		byName[definition.Name] = definition
+	}
+
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	// Report the first problem deterministically.
+	sort.Strings(names)
+
+	normalized := make(map[string]interface{}, len(values))
+	for _, name := range names {
+		definition, ok := byName[name]
+		if !ok {
+			return nil, &ValidationError{Field: name, Msg: "is not defined"}
+		}
+		value, err := convert(definition, values[name])
+		if err != nil {
+			return nil, err
+		}
+		normalized[name] = value
+	}
+
+	if requireAll {
+		for _, definition := range definitions {
+			if _, ok := normalized[definition.Name]; definition.Required && !ok {
+				return nil, &ValidationError{Field: definition.Name, Msg: "is required"}
+			}
+		}
+	}
+	return normalized, nil
+}
+
+// ParseFilter converts query string values into typed values suitable for a
+// JSONB containment filter.
+func ParseFilter(definitions []domain.CustomFieldDefinition, raw map[string]interface{}) (map[string]interface{}, error) {
+	byName := make(map[string]domain.CustomFieldDefinition, len(definitions))
+	for _, definition := range definitions {
+		byName[definition.Name] = definition
+	}
+
+	filter := make(map[string]interface{}, len(raw))
+	for name, value := range raw {
+		definition, ok := byName[name]
+		if !ok {
+			return nil, &ValidationError{Field: name, Msg: "is not defined"}
+		}
+		if s, ok := value.(string); ok {
+			value = fromString(definition, s)
+		}
+		converted, err := convert(definition, value)
+		if err != nil {
+			return nil, err
+		}
+		filter[name] = converted
+	}
+	return filter, nil
+}
+
+func fromString(definition domain.CustomFieldDefinition, s string) interface{} {
+	switch definition.Type {
+	case domain.CustomFieldTypeInteger, domain.CustomFieldTypeNumber:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case domain.CustomFieldTypeBoolean:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
+
+// convert checks a JSON decoded value and returns its canonical form.
+func convert(definition domain.CustomFieldDefinition, value interface{}) (interface{}, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return &ValidationError{Field: definition.Name, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	switch definition.Type {
+	case domain.CustomFieldTypeString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, invalid("must be a string")
+		}
+		if definition.Pattern != "" {
+			pattern, err := regexp.Compile(definition.Pattern)
+			if err != nil || !pattern.MatchString(s) {
+				return nil, invalid("must match %s", definition.Pattern)
+			}
+		}
+		return s, nil
+	case domain.CustomFieldTypeInteger:
+		f, ok := value.(float64)
+		if !ok || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return nil, invalid("must be an integer")
+		}
+		return int64(f), nil
+	case domain.CustomFieldTypeNumber:
+		f, ok := value.(float64)
+		if !ok {
+			return nil, invalid("must be a number")
+		}
+		return f, nil
+	case domain.CustomFieldTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, invalid("must be true or false")
+		}
+		return b, nil
+	case domain.CustomFieldTypeDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, invalid("must be a date such as %s", dateLayout)
+		}
+		t, err := time.Parse(dateLayout, s)
+		if err != nil {
+			return nil, invalid("must be a date such as %s", dateLayout)
+		}
+		return t.Format(dateLayout), nil
+	case domain.CustomFieldTypeEnum:
+		s, ok := value.(string)
+		if ok {
+			for _, allowed := range definition.EnumValues {
+				if s == allowed {
+					return s, nil
+				}
+			}
+		}
+		return nil, invalid("must be one of %s", strings.Join(definition.EnumValues, ", "))
+	}
+	return nil, invalid("has unknown type %q", definition.Type)
+}
+
+// Schema describes the custom fields as a JSON schema object, as used in the
+// OpenAPI document.
+func Schema(definitions []domain.CustomFieldDefinition) map[string]interface{} {
+	properties := make(map[string]interface{}, len(definitions))
+	var required []string
+	for _, definition := range definitions {
+		property := map[string]interface{}{}
+		switch definition.Type {
+		case domain.CustomFieldTypeDate:
+			property["type"] = "string"
+			property["format"] = "date"
+		case domain.CustomFieldTypeEnum:
+			property["type"] = "string"
+			property["enum"] = definition.EnumValues
+		default:
+			property["type"] = definition.Type
+		}
+		if definition.Pattern != "" {
+			property["pattern"] = definition.Pattern
+		}
+		if definition.Label != "" {
+			property["title"] = definition.Label
+		}
+		if definition.Description != "" {
+			property["description"] = definition.Description
+		}
+		properties[definition.Name] = property
+		if definition.Required {
+			required = append(required, definition.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,125 @@
+package customfield
+
+import (
+	"customer-playground/domain"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var definitions = []domain.CustomFieldDefinition{
+	{Name: "loyalty_id", Type: domain.CustomFieldTypeString, Required: true, Pattern: `^LY[0-9]{4}$`},
+	{Name: "preferred_language", Type: domain.CustomFieldTypeEnum, EnumValues: []string{"en", "id"}},
+	{Name: "visits", Type: domain.CustomFieldTypeInteger},
+	{Name: "score", Type: domain.CustomFieldTypeNumber},
+	{Name: "newsletter", Type: domain.CustomFieldTypeBoolean},
+	{Name: "member_since", Type: domain.CustomFieldTypeDate},
+}
+
+func TestValidate(t *testing.T) {
+	values := map[string]interface{}{
+		"loyalty_id":         "LY0042",
+		"preferred_language": "id",
+		"visits":             float64(3),
+		"score":              4.5,
+		"newsletter":         true,
+		"member_since":       "2020-02-29",
+	}
+	got, err := Validate(definitions, values, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"loyalty_id":         "LY0042",
+		"preferred_language": "id",
+		"visits":             int64(3),
+		"score":              4.5,
+		"newsletter":         true,
+		"member_since":       "2020-02-29",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	tests := []struct {
+		values     map[string]interface{}
+		requireAll bool
+		field      string
+	}{
+		{map[string]interface{}{}, true, "loyalty_id"},
+		{map[string]interface{}{"unknown": "x"}, false, "unknown"},
+		{map[string]interface{}{"loyalty_id": "42"}, false, "loyalty_id"},
+		{map[string]interface{}{"preferred_language": "fr"}, false, "preferred_language"},
+		{map[string]interface{}{"visits": 1.5}, false, "visits"},
+		{map[string]interface{}{"visits": "3"}, false, "visits"},
+		{map[string]interface{}{"score": "high"}, false, "score"},
+		{map[string]interface{}{"newsletter": "yes"}, false, "newsletter"},
+		{map[string]interface{}{"member_since": "2021-02-29"}, false, "member_since"},
+	}
+	for _, test := range tests {
+		_, err := Validate(definitions, test.values, test.requireAll)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Field != test.field {
+			t.Errorf("Validate(%v) error = %v, want error for %q", test.values, err, test.field)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	got, err := ParseFilter(definitions, map[string]interface{}{
+		"visits":     "3",
+		"newsletter": "true",
+		"loyalty_id": "LY0042",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"visits": int64(3), "newsletter": true, "loyalty_id": "LY0042"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFilter() = %v, want %v", got, want)
+	}
+
+	if _, err := ParseFilter(definitions, map[string]interface{}{"visits": "many"}); err == nil {
+		t.Error("ParseFilter() accepted a non numeric integer")
+	}
+}
+
+func TestCheckDefinition(t *testing.T) {
+	tests := []struct {
+		definition domain.CustomFieldDefinition
+		valid      bool
+	}{
+		{domain.CustomFieldDefinition{Name: "loyalty_id", Type: "string", Pattern: `^LY\d+$`}, true},
+		{domain.CustomFieldDefinition{Name: "tier", Type: "enum", EnumValues: []string{"gold"}}, true},
+		{domain.CustomFieldDefinition{Name: "Loyalty", Type: "string"}, false},
+		{domain.CustomFieldDefinition{Name: "tier", Type: "enum"}, false},
+		{domain.CustomFieldDefinition{Name: "tier", Type: "string", EnumValues: []string{"gold"}}, false},
+		{domain.CustomFieldDefinition{Name: "visits", Type: "integer", Pattern: `^\d+$`}, false},
+		{domain.CustomFieldDefinition{Name: "code", Type: "string", Pattern: `(`}, false},
+		{domain.CustomFieldDefinition{Name: "code", Type: "json"}, false},
+	}
+	for _, test := range tests {
+		definition := test.definition
+		if err := CheckDefinition(&definition); (err == nil) != test.valid {
+			t.Errorf("CheckDefinition(%+v) = %v, want valid=%v", test.definition, err, test.valid)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema(definitions)
+	if !reflect.DeepEqual(schema["required"], []string{"loyalty_id"}) {
+		t.Errorf("required = %v", schema["required"])
+	}
+	properties := schema["properties"].(map[string]interface{})
+	language := properties["preferred_language"].(map[string]interface{})
+	if language["type"] != "string" || !reflect.DeepEqual(language["enum"], []string{"en", "id"}) {
+		t.Errorf("preferred_language = %v", language)
+	}
+	since := properties["member_since"].(map[string]interface{})
+	if since["format"] != "date" {
+		t.Errorf("member_since = %v", since)
+	}
+}
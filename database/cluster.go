@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// DB routes reads to healthy replicas and writes to the primary. Once a
+// request session has written, its reads stick to the primary so callers
+// always see their own writes.
+type DB struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+func NewDB(primary *sql.DB, replicas ...*sql.DB) *DB {
+	db := &DB{primary: primary}
+	for _, r := range replicas {
+		rep := &replica{db: r}
+		rep.healthy.Store(true)
+		db.replicas = append(db.replicas, rep)
+	}
+	return db
+}
+
+func (db *DB) Primary() *sql.DB {
+	return db.primary
+}
+
+// Writer returns the primary and marks the request session as written.
+func (db *DB) Writer(ctx context.Context) *sql.DB {
+	markWritten(ctx)
+	return db.primary
+}
+
+// Reader picks a healthy replica in round robin order, or the primary when the
+// session has written or no replica is available.
+func (db *DB) Reader(ctx context.Context) *sql.DB {
+	if len(db.replicas) == 0 || hasWritten(ctx) {
+		return db.primary
+	}
+	start := db.next.Add(1)
+	for i := range db.replicas {
+		rep := db.replicas[(start+uint64(i))%uint64(len(db.replicas))]
+		if rep.healthy.Load() {
+			return rep.db
+		}
+	}
+	return db.primary
+}
+
+// Read runs fn against a reader. When a replica fails with a connection level
+// error it is taken out of rotation and fn is run again on the primary.
+func (db *DB) Read(ctx context.Context, fn func(reader *sql.DB) error) error {
+	reader := db.Reader(ctx)
+	err := fn(reader)
+	if err == nil || reader == db.primary || !isConnectionError(err) {
+		return err
+	}
+
+	db.markUnhealthy(reader)
+	return fn(db.primary)
+}
+
+func (db *DB) markUnhealthy(reader *sql.DB) {
+	for _, rep := range db.replicas {
+		if rep.db == reader {
+			rep.healthy.Store(false)
+		}
+	}
+}
+
+// CheckReplicas pings every replica and updates its health.
+func (db *DB) CheckReplicas(ctx context.Context, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	for _, rep := range db.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		rep.healthy.Store(rep.db.PingContext(pingCtx) == nil)
+		cancel()
+	}
+}
+
+// MonitorReplicas runs CheckReplicas every interval until ctx is done.
+func (db *DB) MonitorReplicas(ctx context.Context, interval time.Duration) {
+	if len(db.replicas) == 0 || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			db.CheckReplicas(ctx, interval)
+		}
+	}
+}
+
+func (db *DB) Close() error {
+	errs := []error{db.primary.Close()}
+	for _, rep := range db.replicas {
+		errs = append(errs, rep.db.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer is a database whose pings fail while it is down. It prepares
+// statements, calling onPrepare first when set, but cannot run them.
+type fakeServer struct {
+	down      atomic.Bool
+	onPrepare func(query string)
+}
+
+func (s *fakeServer) Connect(ctx context.Context) (driver.Conn, error) {
+	if s.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return fakeConn{s}, nil
+}
+
+func (s *fakeServer) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	server *fakeServer
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if c.server.onPrepare != nil {
+		c.server.onPrepare(query)
+	}
+	return fakeStmt{}, nil
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeStmt struct {
+	driver.Stmt
+}
+
+func (fakeStmt) Close() error {
+	return nil
+}
+
+func (fakeStmt) NumInput() int {
+	return -1
+}
+
+func (c fakeConn) Ping(ctx context.Context) error {
+	if c.server.down.Load() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func newFakeDB(t *testing.T, replicas int) (*DB, []*fakeServer) {
+	t.Helper()
+	primary := sql.OpenDB(&fakeServer{})
+	t.Cleanup(func() { primary.Close() })
+	var servers []*fakeServer
+	var dbs []*sql.DB
+	for i := 0; i < replicas; i++ {
+		server := &fakeServer{}
+		db := sql.OpenDB(server)
+		t.Cleanup(func() { db.Close() })
+		servers = append(servers, server)
+		dbs = append(dbs, db)
+	}
+	return NewDB(primary, dbs...), servers
+}
+
+func TestReadUsesReplicas(t *testing.T) {
+	db, _ := newFakeDB(t, 2)
+	seen := map[*sql.DB]int{}
+	for i := 0; i < 10; i++ {
+		db.Read(context.Background(), func(reader *sql.DB) error {
+			seen[reader]++
+			return nil
+		})
+	}
+	if seen[db.Primary()] != 0 || len(seen) != 2 || seen[db.replicas[0].db] != 5 {
+		t.Errorf("reads = %v, want them spread over both replicas", seen)
+	}
+}
+
+func TestReaderWithoutReplicas(t *testing.T) {
+	db, _ := newFakeDB(t, 0)
+	if db.Reader(context.Background()) != db.Primary() {
+		t.Error("Reader() without replicas is not the primary")
+	}
+}
+
+func TestReadYourWrites(t *testing.T) {
+	db, _ := newFakeDB(t, 2)
+	ctx := WithSession(context.Background())
+	if db.Reader(ctx) == db.Primary() {
+		t.Fatal("Reader() before a write is the primary")
+	}
+	if db.Writer(ctx) != db.Primary() {
+		t.Fatal("Writer() is not the primary")
+	}
+	for i := 0; i < 3; i++ {
+		if db.Reader(ctx) != db.Primary() {
+			t.Fatal("Reader() after a write left the primary")
+		}
+	}
+
+	// Other sessions, and requests without one, still read from replicas.
+	if db.Reader(WithSession(context.Background())) == db.Primary() {
+		t.Error("Reader() of another session is the primary")
+	}
+	db.Writer(context.Background())
+	if db.Reader(context.Background()) == db.Primary() {
+		t.Error("Reader() without a session is the primary")
+	}
+}
+
+func TestReadFallsBackToPrimary(t *testing.T) {
+	db, _ := newFakeDB(t, 2)
+	var readers []*sql.DB
+	for i := 0; i < 2; i++ {
+		err := db.Read(context.Background(), func(reader *sql.DB) error {
+			readers = append(readers, reader)
+			if reader != db.Primary() {
+				return driver.ErrBadConn
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Read() = %v", err)
+		}
+	}
+	// Each read failed on a replica and was repeated on the primary.
+	if len(readers) != 4 || readers[1] != db.Primary() || readers[3] != db.Primary() || readers[0] == readers[2] {
+		t.Fatalf("readers = %v", readers)
+	}
+	for i, rep := range db.replicas {
+		if rep.healthy.Load() {
+			t.Errorf("replica %d is still healthy", i)
+		}
+	}
+	if db.Reader(context.Background()) != db.Primary() {
+		t.Error("Reader() with every replica unhealthy is not the primary")
+	}
+}
+
+func TestReadKeepsReplicaOnQueryErrors(t *testing.T) {
+	db, _ := newFakeDB(t, 1)
+	queryErr := errors.New("syntax error")
+	calls := 0
+	err := db.Read(context.Background(), func(reader *sql.DB) error {
+		calls++
+		return queryErr
+	})
+	if !errors.Is(err, queryErr) || calls != 1 || !db.replicas[0].healthy.Load() {
+		t.Errorf("Read() = %v after %d calls, healthy %v", err, calls, db.replicas[0].healthy.Load())
+	}
+}
+
+func TestCheckReplicas(t *testing.T) {
+	db, servers := newFakeDB(t, 2)
+	servers[1].down.Store(true)
+	db.CheckReplicas(context.Background(), time.Second)
+	if !db.replicas[0].healthy.Load() || db.replicas[1].healthy.Load() {
+		t.Fatalf("health = %v, %v, want true, false", db.replicas[0].healthy.Load(), db.replicas[1].healthy.Load())
+	}
+	for i := 0; i < 4; i++ {
+		if db.Reader(context.Background()) != db.replicas[0].db {
+			t.Fatal("Reader() picked the replica that is down")
+		}
+	}
+}
+
+func TestMonitorReplicasRecovers(t *testing.T) {
+	db, _ := newFakeDB(t, 1)
+	db.markUnhealthy(db.replicas[0].db)
+	if db.Reader(context.Background()) != db.Primary() {
+		t.Fatal("Reader() used the unhealthy replica")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		db.MonitorReplicas(ctx, time.Millisecond)
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for !db.replicas[0].healthy.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("MonitorReplicas() did not bring the replica back")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if db.Reader(context.Background()) != db.replicas[0].db {
+		t.Error("Reader() after recovery is not the replica")
+	}
+	cancel()
+	<-done
+}
@@ -1,52 +1,114 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type DatabaseConnector struct {
-	Host           string
-	Port           int
-	Username       string
-	Password       string
-	DBName         string
-	ConnectTimeout int
-	SSLCert        string
-	SSLKey         string
-	SSLRootCert    string
-	SSLMode        string
+	Host            string
+	Port            int
+	Username        string
+	Password        string
+	DBName          string
+	ConnectTimeout  int
+	SSLCert         string
+	SSLKey          string
+	SSLRootCert     string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	StartupRetry    RetryPolicy
 }
 
-func (dbConnector DatabaseConnector) Connect() (*sql.DB, error) {
-	var sslMode string
+func (dbConnector DatabaseConnector) dataSourceName() string {
+	params := url.Values{}
 	if dbConnector.SSLMode != "" && dbConnector.SSLMode != "disabled" {
-		sslMode = fmt.Sprintf("sslmode=%s&sslrootcert=%s&sslcert=%s&sslkey=%s",
-			dbConnector.SSLMode,
-			dbConnector.SSLRootCert,
-			dbConnector.SSLCert,
-			dbConnector.SSLKey)
-	}
-
-	dbConfig := fmt.Sprintf("postgresql://%s:%s@%s:%d/%s?%s",
-		dbConnector.Username,
-		dbConnector.Password,
-		dbConnector.Host,
-		dbConnector.Port,
-		dbConnector.DBName,
-		sslMode)
-
-	db, err := sql.Open("postgres", dbConfig)
+		params.Set("sslmode", dbConnector.SSLMode)
+		if dbConnector.SSLRootCert != "" {
+			params.Set("sslrootcert", dbConnector.SSLRootCert)
+		}
+		if dbConnector.SSLCert != "" {
+			params.Set("sslcert", dbConnector.SSLCert)
+		}
+		if dbConnector.SSLKey != "" {
+			params.Set("sslkey", dbConnector.SSLKey)
+		}
+	}
+	if dbConnector.ConnectTimeout > 0 {
+		params.Set("connect_timeout", strconv.Itoa(dbConnector.ConnectTimeout))
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgresql",
+		User:     url.UserPassword(dbConnector.Username, dbConnector.Password),
+		Host:     fmt.Sprintf("%s:%d", dbConnector.Host, dbConnector.Port),
+		Path:     dbConnector.DBName,
+		RawQuery: params.Encode(),
+	}
+	return dsn.String()
+}
+
+// Open configures the pool without contacting the server.
+func (dbConnector DatabaseConnector) Open() (*sql.DB, error) {
+	db, err := sql.Open("postgres", dbConnector.dataSourceName())
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(dbConnector.MaxOpenConns)
+	if dbConnector.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(dbConnector.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(dbConnector.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(dbConnector.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+// Connect opens the pool and pings the server, retrying with the StartupRetry
+// policy so the application can start before Postgres accepts connections.
+func (dbConnector DatabaseConnector) Connect() (*sql.DB, error) {
+	db, err := dbConnector.Open()
 	if err != nil {
 		return nil, err
 	}
 
-	err = db.Ping()
+	err = dbConnector.StartupRetry.DoIf(context.Background(), isStartupRetryable, func() error {
+		ctx := context.Background()
+		if dbConnector.ConnectTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(dbConnector.ConnectTimeout)*time.Second)
+			defer cancel()
+		}
+		return db.PingContext(ctx)
+	})
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return db, nil
 }
+
+// isStartupRetryable treats every error as temporary except the ones that
+// cannot resolve themselves by waiting, such as bad credentials.
+func isStartupRetryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "28", "3D":
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,247 @@
+// Package pgtest runs integration tests against a throwaway Postgres cluster.
+// The cluster is created with the initdb and postgres binaries found in
+// PG_BIN, on the PATH or under /usr/lib/postgresql, started once per test
+// binary on a free port and brought up to date with the migrations. Tests are
+// skipped when no cluster can be started, unless PGTEST_REQUIRED is set.
+//
+// Packages using it run their tests through Main:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(pgtest.Main(m))
+//	}
+package pgtest
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"customer-playground/migrations"
+	"customer-playground/tenant"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const databaseName = "customer"
+
+// queryReset empties every table but tenant and schema_migrations and
+// restarts the sequences. Tenants added by a test are removed as well.
+const queryReset = `
+	DO $$
+	BEGIN
+		EXECUTE (
+			SELECT 'TRUNCATE ' || string_agg(format('%I', tablename), ', ') || ' RESTART IDENTITY CASCADE'
+			FROM pg_tables
+			WHERE schemaname = 'public' AND tablename NOT IN ('tenant', 'schema_migrations')
+		);
+		DELETE FROM tenant WHERE slug <> 'default';
+	END
+	$$
+`
+
+// DefaultTenant is the tenant the migrations create and Context scopes to.
+var DefaultTenant = domain.Tenant{ID: 1, Slug: "default", Name: "Default"}
+
+var (
+	once     sync.Once
+	server   *cluster
+	startErr error
+)
+
+type cluster struct {
+	dir   string
+	cmd   *exec.Cmd
+	admin *sql.DB
+	app   *sql.DB
+}
+
+// Main runs the tests of m and stops the cluster once they are done.
+func Main(m *testing.M) int {
+	code := m.Run()
+	if server != nil {
+		server.stop()
+	}
+	return code
+}
+
+// Open returns a connection to an empty database with every migration
+// applied, starting the cluster on first use. Every call empties the
+// tables, so tests must not run in parallel.
+func Open(t testing.TB) *database.DB {
+	t.Helper()
+	once.Do(func() {
+		server, startErr = start()
+	})
+	if startErr != nil {
+		if os.Getenv("PGTEST_REQUIRED") != "" {
+			t.Fatalf("pgtest: %v", startErr)
+		}
+		t.Skipf("pgtest: %v", startErr)
+	}
+	if _, err := server.admin.Exec(queryReset); err != nil {
+		t.Fatalf("pgtest: reset: %v", err)
+	}
+	return database.NewDB(server.app)
+}
+
+// Context returns a context scoped to DefaultTenant.
+func Context() context.Context {
+	return tenant.WithTenant(context.Background(), DefaultTenant)
+}
+
+func start() (*cluster, error) {
+	if os.Geteuid() == 0 {
+		return nil, errors.New("postgres refuses to run as root")
+	}
+	bin, err := binDir()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "pgtest-")
+	if err != nil {
+		return nil, err
+	}
+	data := filepath.Join(dir, "data")
+	initdb := exec.Command(filepath.Join(bin, "initdb"), "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync")
+	if out, err := initdb.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("initdb: %v: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	logFile, err := os.Create(filepath.Join(dir, "postgres.log"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	defer logFile.Close()
+	// Durability is of no use for a database thrown away after the tests.
+	cmd := exec.Command(filepath.Join(bin, "postgres"),
+		"-D", data,
+		"-p", strconv.Itoa(port),
+		"-k", dir,
+		"-c", "listen_addresses=127.0.0.1",
+		"-c", "fsync=off",
+		"-c", "synchronous_commit=off",
+		"-c", "full_page_writes=off",
+	)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	c := &cluster{dir: dir, cmd: cmd}
+
+	if err := c.load(port); err != nil {
+		log, _ := os.ReadFile(filepath.Join(dir, "postgres.log"))
+		c.stop()
+		return nil, fmt.Errorf("%w\n%s", err, log)
+	}
+	return c, nil
+}
+
+// load creates the test database, migrates it and opens the connections to
+// it.
+func (c *cluster) load(port int) error {
+	postgres, err := sql.Open("postgres", dsn("postgres", "postgres", port))
+	if err != nil {
+		return err
+	}
+	defer postgres.Close()
+	if err := waitReady(postgres, 30*time.Second); err != nil {
+		return err
+	}
+	if _, err := postgres.Exec("CREATE DATABASE " + databaseName); err != nil {
+		return err
+	}
+
+	if c.admin, err = sql.Open("postgres", dsn("postgres", databaseName, port)); err != nil {
+		return err
+	}
+	if _, err := migrations.Up(context.Background(), c.admin); err != nil {
+		return err
+	}
+	// The application connects as the role the migrations create, so row
+	// level security applies as it does in production. Logins are trusted,
+	// the password is never checked.
+	if err := migrations.SetAppPassword(context.Background(), c.admin, "pgtest"); err != nil {
+		return err
+	}
+	c.app, err = sql.Open("postgres", dsn(migrations.AppRole, databaseName, port))
+	return err
+}
+
+func (c *cluster) stop() {
+	for _, db := range []*sql.DB{c.app, c.admin} {
+		if db != nil {
+			db.Close()
+		}
+	}
+	// SIGINT is the fast shutdown: open sessions are ended, nothing is
+	// waited for.
+	if c.cmd.Process != nil {
+		c.cmd.Process.Signal(os.Interrupt)
+		c.cmd.Wait()
+	}
+	os.RemoveAll(c.dir)
+}
+
+func binDir() (string, error) {
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		return dir, nil
+	}
+	if path, err := exec.LookPath("initdb"); err == nil {
+		return filepath.Dir(path), nil
+	}
+	// Debian and Ubuntu keep the server binaries off the PATH.
+	matches, _ := filepath.Glob("/usr/lib/postgresql/*/bin/initdb")
+	if len(matches) == 0 {
+		return "", errors.New("initdb not found, install Postgres or set PG_BIN")
+	}
+	sort.Strings(matches)
+	return filepath.Dir(matches[len(matches)-1]), nil
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func dsn(user string, name string, port int) string {
+	return fmt.Sprintf("postgres://%s@127.0.0.1:%d/%s?sslmode=disable", user, port, name)
+}
+
+func waitReady(db *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := db.Ping()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("postgres did not start: %w", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RetryPolicy describes an exponential backoff with full jitter. A zero value
+// runs the operation exactly once.
+type RetryPolicy struct {
+	MaxAttempts     int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     3,
+	InitialInterval: 50 * time.Millisecond,
+	MaxInterval:     time.Second,
+	Multiplier:      2,
+}
+
+// Do runs fn until it succeeds, returns a non transient error, the attempts
+// are exhausted or ctx is done. Only errors that guarantee fn had no effect
+// are retried, so fn may write.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	return p.DoIf(ctx, IsTransient, fn)
+}
+
+// DoRead is like Do for an fn that only reads. It also retries a broken
+// connection, after which a read can simply run again.
+func (p RetryPolicy) DoRead(ctx context.Context, fn func() error) error {
+	return p.DoIf(ctx, isConnectionError, fn)
+}
+
+// DoTransaction is like Do for an fn that runs one whole transaction. It also
+// retries serialization failures and deadlocks, which roll the transaction
+// back, so fn must begin a new transaction on every call.
+func (p RetryPolicy) DoTransaction(ctx context.Context, fn func() error) error {
+	return p.DoIf(ctx, func(err error) bool {
+		return IsTransient(err) || isSerializationError(err)
+	}, fn)
+}
+
+// DoIf is like Do but lets the caller decide which errors are retried.
+func (p RetryPolicy) DoIf(ctx context.Context, retryable func(error) bool, fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || !retryable(err) || attempt+1 >= p.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	interval := float64(p.InitialInterval)
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	for i := 0; i < attempt; i++ {
+		interval *= multiplier
+		if p.MaxInterval > 0 && interval >= float64(p.MaxInterval) {
+			interval = float64(p.MaxInterval)
+			break
+		}
+	}
+	if interval <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(interval)) + 1)
+}
+
+// IsTransient reports whether err is safe to retry whatever fn did:
+// driver.ErrBadConn, which database/sql and the driver only return when the
+// statement was never sent. A dropped connection, a reset or a shutdown after
+// sending leaves it unknown whether the server committed, and retrying would
+// repeat non-idempotent writes such as inserts with serial keys.
+func IsTransient(err error) bool {
+	return errors.Is(err, driver.ErrBadConn)
+}
+
+// isConnectionError reports whether err means the server was unreachable or
+// the connection broke. Whether the statement ran is unknown, so only reads
+// may be repeated after one.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03": // cannot_connect_now
+			return true
+		}
+		return pqErr.Code.Class() == "08"
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isSerializationError reports whether err rolled back the transaction it
+// happened in, so running the whole transaction again is safe.
+func isSerializationError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code {
+	case "40001", // serialization_failure
+		"40P01": // deadlock_detected
+		return true
+	}
+	return false
+}
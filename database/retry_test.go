@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		// The server may have committed before these.
+		{io.EOF, false},
+		{io.ErrUnexpectedEOF, false},
+		{syscall.ECONNRESET, false},
+		{syscall.EPIPE, false},
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, false},
+		{&pq.Error{Code: "08006"}, false},
+		{&pq.Error{Code: "57P01"}, false},
+		// Only rerunning the whole transaction recovers from these.
+		{&pq.Error{Code: "40001"}, false},
+		{&pq.Error{Code: "40P01"}, false},
+		{&pq.Error{Code: "23505"}, false},
+		{errors.New("boom"), false},
+	}
+	for _, test := range tests {
+		if got := IsTransient(test.err); got != test.want {
+			t.Errorf("IsTransient(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	for _, err := range []error{driver.ErrBadConn, io.EOF, syscall.ECONNREFUSED, &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, &pq.Error{Code: "08006"}, &pq.Error{Code: "57P03"}} {
+		if !isConnectionError(err) {
+			t.Errorf("isConnectionError(%v) = false", err)
+		}
+	}
+	for _, err := range []error{nil, &pq.Error{Code: "40001"}, &pq.Error{Code: "23505"}, errors.New("boom")} {
+		if isConnectionError(err) {
+			t.Errorf("isConnectionError(%v) = true", err)
+		}
+	}
+}
+
+var testPolicy = RetryPolicy{MaxAttempts: 4, InitialInterval: time.Millisecond, MaxInterval: 4 * time.Millisecond, Multiplier: 2}
+
+func TestDo(t *testing.T) {
+	tests := []struct {
+		name  string
+		errs  []error
+		calls int
+		want  error
+	}{
+		{"success", []error{nil}, 1, nil},
+		{"recovers", []error{driver.ErrBadConn, driver.ErrBadConn, nil}, 3, nil},
+		{"max attempts", []error{driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn, nil}, 4, driver.ErrBadConn},
+		{"ambiguous", []error{io.EOF, nil}, 1, io.EOF},
+		{"serialization", []error{&pq.Error{Code: "40001"}, nil}, 1, &pq.Error{Code: "40001"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			err := testPolicy.Do(context.Background(), func() error {
+				calls++
+				return test.errs[calls-1]
+			})
+			if calls != test.calls || fmt.Sprint(err) != fmt.Sprint(test.want) {
+				t.Errorf("Do() = %v after %d calls, want %v after %d", err, calls, test.want, test.calls)
+			}
+		})
+	}
+}
+
+func TestDoRead(t *testing.T) {
+	// A reset after the query was sent is not retried for writes, but a read
+	// can run again.
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	for _, first := range []error{reset, io.EOF, &pq.Error{Code: "57P01"}, driver.ErrBadConn} {
+		calls := 0
+		err := testPolicy.DoRead(context.Background(), func() error {
+			calls++
+			if calls == 1 {
+				return first
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Errorf("DoRead() after %v = %v after %d calls", first, err, calls)
+		}
+	}
+
+	calls := 0
+	err := testPolicy.DoRead(context.Background(), func() error {
+		calls++
+		return sql.ErrNoRows
+	})
+	if !errors.Is(err, sql.ErrNoRows) || calls != 1 {
+		t.Errorf("DoRead() = %v after %d calls, want sql.ErrNoRows after 1", err, calls)
+	}
+}
+
+func TestDoTransaction(t *testing.T) {
+	for _, first := range []error{&pq.Error{Code: "40001"}, &pq.Error{Code: "40P01"}, driver.ErrBadConn} {
+		calls := 0
+		err := testPolicy.DoTransaction(context.Background(), func() error {
+			calls++
+			if calls == 1 {
+				return first
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Errorf("DoTransaction() after %v = %v after %d calls", first, err, calls)
+		}
+	}
+
+	calls := 0
+	err := testPolicy.DoTransaction(context.Background(), func() error {
+		calls++
+		return &pq.Error{Code: "08006"}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("DoTransaction() after a dropped connection = %v after %d calls, want no retry", err, calls)
+	}
+}
+
+func TestDoZeroPolicy(t *testing.T) {
+	calls := 0
+	RetryPolicy{}.Do(context.Background(), func() error {
+		calls++
+		return driver.ErrBadConn
+	})
+	if calls != 1 {
+		t.Errorf("zero policy made %d calls, want 1", calls)
+	}
+}
+
+func TestDoContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{MaxAttempts: 10, InitialInterval: time.Hour, MaxInterval: time.Hour}
+	calls := 0
+	err := policy.Do(ctx, func() error {
+		calls++
+		cancel()
+		return driver.ErrBadConn
+	})
+	if calls != 1 || !errors.Is(err, context.Canceled) || !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("Do() = %v after %d calls, want both errors after 1", err, calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialInterval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond, Multiplier: 2}
+	// Full jitter: every delay is in (0, min(initial*2^attempt, max)].
+	for attempt, limit := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond} {
+		for i := 0; i < 100; i++ {
+			if got := policy.backoff(attempt); got <= 0 || got > limit {
+				t.Fatalf("backoff(%d) = %v, want in (0, %v]", attempt, got, limit)
+			}
+		}
+	}
+	if got := (RetryPolicy{}).backoff(3); got != 0 {
+		t.Errorf("backoff() without an interval = %v", got)
+	}
+}
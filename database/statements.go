@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+	"golang.org/x/sync/singleflight"
+)
+
+// Statements caches prepared statements per pool and query. Statements are
+// prepared on first use and kept until Close. database/sql re-prepares a
+// statement on every new connection it hands out, so a statement survives
+// connection loss; Exec and Query additionally re-prepare once when the server
+// reports the statement as gone.
+type Statements struct {
+	mu     sync.Mutex
+	stmts  map[statementKey]*sql.Stmt
+	closed bool
+	// group prepares each statement once when callers miss it together.
+	group singleflight.Group
+}
+
+type statementKey struct {
+	db    *sql.DB
+	query string
+}
+
+var errStatementsClosed = errors.New("database: statement cache is closed")
+
+func NewStatements() *Statements {
+	return &Statements{stmts: make(map[statementKey]*sql.Stmt)}
+}
+
+// Prepare returns the cached statement for query on db, preparing it if needed.
+// The server round trip happens outside the lock, so a slow prepare only
+// holds up callers of the same query, which share its result.
+func (s *Statements) Prepare(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, error) {
+	key := statementKey{db: db, query: query}
+	if stmt, err := s.lookup(key); stmt != nil || err != nil {
+		return stmt, err
+	}
+
+	stmt, err, _ := s.group.Do(fmt.Sprintf("%p\x00%s", db, query), func() (interface{}, error) {
+		if stmt, err := s.lookup(key); stmt != nil || err != nil {
+			return stmt, err
+		}
+		stmt, err := db.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.closed {
+			stmt.Close()
+			return nil, errStatementsClosed
+		}
+		s.stmts[key] = stmt
+		return stmt, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stmt.(*sql.Stmt), nil
+}
+
+func (s *Statements) lookup(key statementKey) (*sql.Stmt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errStatementsClosed
+	}
+	return s.stmts[key], nil
+}
+
+// Run executes fn with the cached statement, re-preparing it once if the
+// server no longer knows it. When ctx carries a tenant the statement runs in
+// a transaction scoped to it.
+func (s *Statements) Run(ctx context.Context, db *sql.DB, query string, fn func(stmt *sql.Stmt) error) error {
+	stmt, err := s.Prepare(ctx, db, query)
+	if err != nil {
+		return err
+	}
+	err = s.run(ctx, db, stmt, fn)
+	if !isStaleStatement(err) {
+		return err
+	}
+
+	s.evict(db, query, stmt)
+	stmt, err = s.Prepare(ctx, db, query)
+	if err != nil {
+		return err
+	}
+	return s.run(ctx, db, stmt, fn)
+}
+
+func (s *Statements) run(ctx context.Context, db *sql.DB, stmt *sql.Stmt, fn func(stmt *sql.Stmt) error) error {
+	if _, ok := TenantID(ctx); !ok {
+		return fn(stmt)
+	}
+	return Transaction(ctx, db, func(tx *sql.Tx) error {
+		return fn(tx.StmtContext(ctx, stmt))
+	})
+}
+
+func (s *Statements) evict(db *sql.DB, query string, stale *sql.Stmt) {
+	key := statementKey{db: db, query: query}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stmts[key] == stale {
+		delete(s.stmts, key)
+		stale.Close()
+	}
+}
+
+// Close closes every cached statement. Later calls to Prepare fail.
+func (s *Statements) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for key, stmt := range s.stmts {
+		errs = append(errs, stmt.Close())
+		delete(s.stmts, key)
+	}
+	s.closed = true
+	return errors.Join(errs...)
+}
+
+func isStaleStatement(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "26000": // invalid_sql_statement_name
+			return true
+		case "0A000": // feature_not_supported, e.g. "cached plan must not change result type"
+			return strings.Contains(pqErr.Message, "cached plan")
+		}
+	}
+	return strings.Contains(err.Error(), "sql: statement is closed")
+}
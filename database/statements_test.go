@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrepareCaches(t *testing.T) {
+	var prepares atomic.Int32
+	db := sql.OpenDB(&fakeServer{onPrepare: func(query string) { prepares.Add(1) }})
+	defer db.Close()
+	stmts := NewStatements()
+	defer stmts.Close()
+
+	first, err := stmts.Prepare(context.Background(), db, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := stmts.Prepare(context.Background(), db, "SELECT 1")
+	other, _ := stmts.Prepare(context.Background(), db, "SELECT 2")
+	if first != second || first == other || prepares.Load() != 2 {
+		t.Errorf("Prepare() made %d prepares, same statement %v", prepares.Load(), first == second)
+	}
+}
+
+func TestPrepareDoesNotBlockOtherQueries(t *testing.T) {
+	release := make(chan struct{})
+	var prepares atomic.Int32
+	db := sql.OpenDB(&fakeServer{onPrepare: func(query string) {
+		prepares.Add(1)
+		if query == "SELECT slow" {
+			<-release
+		}
+	}})
+	defer db.Close()
+	stmts := NewStatements()
+	defer stmts.Close()
+	if _, err := stmts.Prepare(context.Background(), db, "SELECT cached"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Callers of the slow query wait for one shared prepare.
+	var wg sync.WaitGroup
+	results := make([]*sql.Stmt, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = stmts.Prepare(context.Background(), db, "SELECT slow")
+		}()
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := stmts.Prepare(context.Background(), db, "SELECT cached")
+		if err == nil {
+			_, err = stmts.Prepare(context.Background(), db, "SELECT fast")
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Prepare() of other queries waited for the slow prepare")
+	}
+
+	close(release)
+	wg.Wait()
+	for _, stmt := range results {
+		if stmt == nil || stmt != results[0] {
+			t.Fatalf("callers of the slow query got %v", results)
+		}
+	}
+	// cached, fast and slow once.
+	if prepares.Load() != 3 {
+		t.Errorf("%d prepares, want 3", prepares.Load())
+	}
+}
+
+func TestPrepareAfterClose(t *testing.T) {
+	release := make(chan struct{})
+	db := sql.OpenDB(&fakeServer{onPrepare: func(query string) { <-release }})
+	defer db.Close()
+	stmts := NewStatements()
+
+	result := make(chan error)
+	go func() {
+		_, err := stmts.Prepare(context.Background(), db, "SELECT 1")
+		result <- err
+	}()
+	// Close while the prepare is in flight; its statement must not be cached.
+	time.Sleep(10 * time.Millisecond)
+	if err := stmts.Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if err := <-result; !errors.Is(err, errStatementsClosed) {
+		t.Errorf("Prepare() during Close = %v", err)
+	}
+	if len(stmts.stmts) != 0 {
+		t.Errorf("%d statements cached after Close", len(stmts.stmts))
+	}
+	if _, err := stmts.Prepare(context.Background(), db, "SELECT 1"); !errors.Is(err, errStatementsClosed) {
+		t.Errorf("Prepare() after Close = %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+type tenantKey struct{}
+
+// querySetTenant scopes the current transaction to a tenant. The schema reads
+// it through app_tenant() in column defaults, queries and row level security
+// policies.
+const querySetTenant = `SELECT set_config('app.tenant_id', $1, true)`
+
+// WithTenant binds every statement run with ctx to tenantID.
+func WithTenant(ctx context.Context, tenantID int) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantID returns the tenant ctx is bound to.
+func TenantID(ctx context.Context) (int, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(int)
+	return tenantID, ok
+}
+
+// setTenant scopes tx to the tenant of ctx. Without a tenant app_tenant() is
+// NULL and no tenant's rows are visible.
+func setTenant(ctx context.Context, tx *sql.Tx) error {
+	tenantID, ok := TenantID(ctx)
+	if !ok {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, querySetTenant, strconv.Itoa(tenantID))
+	return err
+}
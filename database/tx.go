@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Transaction runs fn inside a transaction on db, scoped to the tenant of
+// ctx if it has one. The transaction is committed when fn returns nil and
+// rolled back otherwise.
+func Transaction(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := setTenant(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Tx runs statements from a Statements cache inside one transaction.
+type Tx struct {
+	ctx   context.Context
+	tx    *sql.Tx
+	db    *sql.DB
+	stmts *Statements
+}
+
+// Transaction is like the package level Transaction but hands fn a Tx bound
+// to the statement cache.
+func (s *Statements) Transaction(ctx context.Context, db *sql.DB, fn func(tx *Tx) error) error {
+	return Transaction(ctx, db, func(tx *sql.Tx) error {
+		return fn(&Tx{ctx: ctx, tx: tx, db: db, stmts: s})
+	})
+}
+
+func (t *Tx) Stmt(query string) (*sql.Stmt, error) {
+	stmt, err := t.stmts.Prepare(t.ctx, t.db, query)
+	if err != nil {
+		return nil, err
+	}
+	return t.tx.StmtContext(t.ctx, stmt), nil
+}
+
+func (t *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := t.Stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(t.ctx, args...)
+}
+
+func (t *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := t.Stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(t.ctx, args...)
+}
+
+func (t *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	stmt, err := t.Stmt(query)
+	if err != nil {
+		// sql.Row cannot carry an error, so run the query unprepared and let
+		// Scan report the failure.
+		return t.tx.QueryRowContext(t.ctx, query, args...)
+	}
+	return stmt.QueryRowContext(t.ctx, args...)
+}
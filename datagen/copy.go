@@ -0,0 +1,187 @@
+package datagen
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"customer-playground/encryption"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// COPY FROM is not supported on tables with row level security, so records
+// are copied into temporary tables first and moved over with one INSERT per
+// table, which the policies do check. tenant_id is left to its default, the
+// tenant of the transaction.
+const (
+	queryReserveCustomerNumbers = `SELECT nextval('customer_customer_number_seq') FROM generate_series(1, $1)`
+	queryReserveNoteIds         = `SELECT nextval('customer_note_id_seq') FROM generate_series(1, $1)`
+)
+
+// copyTable is a table filled through a temporary copy of some of its
+// columns.
+type copyTable struct {
+	name    string
+	columns string
+}
+
+var (
+	customerTable = copyTable{"customer", "customer_number, name, email, email_index, phone, birth_date, created_at, updated_at"}
+	contactTable  = copyTable{"customer_contact_point", "customer_number, type, value, value_index, is_primary, created_at, updated_at"}
+	noteTable     = copyTable{"customer_note", "id, customer_number, note, author, category, pinned, visibility, revision, updated_by, created_at, updated_at"}
+	revisionTable = copyTable{"customer_note_revision", "note_id, revision, note, category, pinned, visibility, edited_by, created_at"}
+)
+
+// Writer stores records in bulk, with the customer fields sealed like the
+// customer repository seals them.
+type Writer struct {
+	db     *sql.DB
+	cipher *encryption.Cipher
+}
+
+func NewWriter(db *sql.DB, cipher *encryption.Cipher) *Writer {
+	return &Writer{db: db, cipher: cipher}
+}
+
+// Write stores records for the tenant of ctx in one transaction and sets
+// their customer numbers and note IDs. Customers get primary contact points
+// for their email and phone, and notes their first revision, as if they were
+// added through the API.
+func (w *Writer) Write(ctx context.Context, records []Record) error {
+	if _, ok := database.TenantID(ctx); !ok {
+		return fmt.Errorf("datagen: no tenant in context")
+	}
+	return database.Transaction(ctx, w.db, func(tx *sql.Tx) error {
+		customerNumbers, err := reserve(ctx, tx, queryReserveCustomerNumbers, len(records))
+		if err != nil {
+			return err
+		}
+		noteCount := 0
+		for _, record := range records {
+			noteCount += len(record.Notes)
+		}
+		noteIds, err := reserve(ctx, tx, queryReserveNoteIds, noteCount)
+		if err != nil {
+			return err
+		}
+
+		var customers, contacts, notes, revisions [][]interface{}
+		for i := range records {
+			customer := &records[i].Customer
+			customer.CustomerNumber = customerNumbers[i]
+			stored, err := w.cipher.SealCustomer(ctx, *customer)
+			if err != nil {
+				return fmt.Errorf("customer %d: %w", customer.CustomerNumber, err)
+			}
+			createdAt, updatedAt := customer.CreatedAt.Time.UTC(), customer.UpdatedAt.Time.UTC()
+			customers = append(customers, []interface{}{
+				customer.CustomerNumber, customer.Name, stored.Email, nullValue(stored.EmailIndex), nullValue(stored.Phone), nullValue(stored.BirthDate), createdAt, updatedAt,
+			})
+			contacts = append(contacts, []interface{}{customer.CustomerNumber, domain.ContactTypeEmail, stored.Email, nullValue(stored.EmailIndex), true, createdAt, updatedAt})
+			if customer.Phone != "" {
+				phoneIndex := w.cipher.BlindIndex(domain.ContactTypePhone, customer.Phone)
+				contacts = append(contacts, []interface{}{customer.CustomerNumber, domain.ContactTypePhone, stored.Phone.String, phoneIndex, true, createdAt, updatedAt})
+			}
+
+			for j := range records[i].Notes {
+				note := &records[i].Notes[j]
+				note.ID, noteIds = noteIds[0], noteIds[1:]
+				note.CustomerNumber = customer.CustomerNumber
+				pinned := note.Pinned != nil && *note.Pinned
+				createdAt, updatedAt := note.CreatedAt.Time.UTC(), note.UpdatedAt.Time.UTC()
+				notes = append(notes, []interface{}{
+					note.ID, note.CustomerNumber, note.Note, note.Author, note.Category, pinned, note.Visibility, note.Revision, note.UpdatedBy, createdAt, updatedAt,
+				})
+				revisions = append(revisions, []interface{}{note.ID, note.Revision, note.Note, note.Category, pinned, note.Visibility, note.Author, createdAt})
+			}
+		}
+
+		for _, table := range []struct {
+			copyTable
+			rows [][]interface{}
+		}{
+			{customerTable, customers},
+			{contactTable, contacts},
+			{noteTable, notes},
+			{revisionTable, revisions},
+		} {
+			if err := table.copy(ctx, tx, table.rows); err != nil {
+				return fmt.Errorf("copy %s: %w", table.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (t copyTable) copy(ctx context.Context, tx *sql.Tx, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	staging := "datagen_" + t.name
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`CREATE TEMPORARY TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA`, staging, t.columns, t.name))
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(staging, splitColumns(t.columns)...))
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	// An Exec without arguments flushes the copied rows.
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s`, t.name, t.columns, t.columns, staging))
+	return err
+}
+
+// reserve takes n values of a sequence, so rows can reference each other
+// before they are stored.
+func reserve(ctx context.Context, tx *sql.Tx, query string, n int) ([]int, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	rows, err := tx.QueryContext(ctx, query, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make([]int, 0, n)
+	for rows.Next() {
+		var value int
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func nullValue(s sql.NullString) interface{} {
+	if !s.Valid {
+		return nil
+	}
+	return s.String
+}
+
+func splitColumns(columns string) []string {
+	var names []string
+	for _, name := range strings.Split(columns, ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names
+}
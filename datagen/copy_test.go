@@ -0,0 +1,83 @@
+package datagen
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/database/pgtest"
+	"customer-playground/domain"
+	"customer-playground/encryption"
+	repository_customer "customer-playground/services/customer/repository"
+	repository_customernote "customer-playground/services/customernote/repository"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestWriter(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := pgtest.Context()
+	keyfile, err := encryption.OpenKeyfile(filepath.Join(t.TempDir(), "keys.json"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := encryption.NewCipher(context.Background(), keyfile, encryption.Fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	records := generate(Config{Seed: 1, Now: now}, 20)
+	writer := NewWriter(db.Primary(), cipher)
+	if err := writer.Write(ctx, records); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Write(context.Background(), records); err == nil {
+		t.Error("Write() without a tenant succeeded")
+	}
+
+	customers := repository_customer.NewCustomerRepository(db, database.DefaultRetryPolicy, cipher, logger)
+	notes := repository_customernote.NewCustomerNoteRepository(db, database.DefaultRetryPolicy, logger)
+	for _, record := range records {
+		want := record.Customer
+		got, err := customers.GetByCustomerNumber(want.CustomerNumber, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != want.Name || got.Email != want.Email || got.Phone != want.Phone || got.BirthDate.Valid != want.BirthDate.Valid {
+			t.Errorf("GetByCustomerNumber() = %+v, want %+v", got, want)
+		}
+		// The email is found through its blind index.
+		if found, err := customers.GetAll(domain.CustomerFilter{Email: want.Email}, ctx); err != nil || len(found) != 1 {
+			t.Errorf("GetAll() by email %s = %+v, %v", want.Email, found, err)
+		}
+
+		stored, err := notes.GetByCustomerNumber(want.CustomerNumber, ctx)
+		if err != nil || len(stored) != len(record.Notes) {
+			t.Fatalf("GetByCustomerNumber() = %d notes, %v, want %d", len(stored), err, len(record.Notes))
+		}
+		for _, note := range record.Notes {
+			revisions, err := notes.GetRevisions(note.ID, ctx)
+			if err != nil || len(revisions) != 1 || revisions[0].Note != note.Note {
+				t.Errorf("GetRevisions(%d) = %+v, %v", note.ID, revisions, err)
+			}
+		}
+	}
+
+	// The next customer added through the repository gets a number of its own.
+	customer := records[0].Customer
+	customer.CustomerNumber, customer.Email = 0, "new@example.com"
+	if message, err := customers.Insert(&customer, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() = %+v, %v", message, err)
+	}
+	if last := records[len(records)-1].Customer.CustomerNumber; customer.CustomerNumber <= last {
+		t.Errorf("Insert() got number %d, generated customers go up to %d", customer.CustomerNumber, last)
+	}
+}
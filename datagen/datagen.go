@@ -0,0 +1,290 @@
+// Package datagen makes up customers and notes for testing with volume.
+//
+// The data is deterministic: the same Config gives the same records. Names
+// and phones follow the configured locales, birth dates put most customers
+// between 25 and 55, customers sign up more often recently than long ago and
+// mostly during office hours, and notes cluster shortly after sign up.
+package datagen
+
+import (
+	"customer-playground/domain"
+	"customer-playground/types"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultSpan is how far back customers are created.
+	DefaultSpan = 3 * 365 * 24 * time.Hour
+	// DefaultNotesPerCustomer is the mean number of notes of a customer.
+	DefaultNotesPerCustomer = 3
+)
+
+// DefaultAgents write the generated notes.
+var DefaultAgents = []string{"jane.agent", "bob.agent", "support"}
+
+// Share of customers without a phone or birth date.
+const (
+	missingPhone     = 0.1
+	missingBirthDate = 0.15
+)
+
+// emailDomains are reserved for examples, so no mail reaches anyone.
+var emailDomains = []string{"example.com", "example.net", "example.org"}
+
+// Config tunes the generated data. Zero values pick the defaults.
+type Config struct {
+	Seed int64
+	// Locales are picked evenly per customer, DefaultLocale when empty.
+	Locales []Locale
+	// Customers are created between Now minus Span and Now. Now defaults
+	// to the start of the current day, so data made on the same day
+	// matches.
+	Now  time.Time
+	Span time.Duration
+	// NotesPerCustomer is the mean number of notes per customer, none when
+	// negative. Counts are geometrically distributed, so a few customers
+	// have many notes.
+	NotesPerCustomer float64
+	Agents           []string
+}
+
+// Record is a customer with its notes.
+type Record struct {
+	Customer domain.Customer
+	Notes    []domain.CustomerNote
+}
+
+// Generator makes up records. It is not safe for concurrent use.
+type Generator struct {
+	cfg    Config
+	rng    *rand.Rand
+	emails map[string]bool
+}
+
+func New(cfg Config) *Generator {
+	if len(cfg.Locales) == 0 {
+		cfg.Locales = []Locale{Locales[DefaultLocale]}
+	}
+	if cfg.Now.IsZero() {
+		cfg.Now = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	if cfg.Span <= 0 {
+		cfg.Span = DefaultSpan
+	}
+	if cfg.NotesPerCustomer < 0 {
+		cfg.NotesPerCustomer = 0
+	} else if cfg.NotesPerCustomer == 0 {
+		cfg.NotesPerCustomer = DefaultNotesPerCustomer
+	}
+	if len(cfg.Agents) == 0 {
+		cfg.Agents = DefaultAgents
+	}
+	return &Generator{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed)), emails: map[string]bool{}}
+}
+
+// Next makes up the next customer and its notes. Customer numbers and note
+// IDs are left for the database to assign.
+func (g *Generator) Next() Record {
+	customer := g.Customer()
+	return Record{Customer: customer, Notes: g.notes(customer)}
+}
+
+// Customer makes up a customer with an email no earlier customer of the
+// generator has.
+func (g *Generator) Customer() domain.Customer {
+	locale := g.cfg.Locales[g.rng.Intn(len(g.cfg.Locales))]
+	first := locale.FirstNames[g.rng.Intn(len(locale.FirstNames))]
+	last := locale.LastNames[g.rng.Intn(len(locale.LastNames))]
+	createdAt := g.createdAt(locale)
+
+	customer := domain.Customer{
+		Name:      first + " " + last,
+		CreatedAt: types.NullTime{Time: createdAt, Valid: true},
+		UpdatedAt: types.NullTime{Time: createdAt, Valid: true},
+	}
+	if g.rng.Float64() >= missingBirthDate {
+		customer.BirthDate = types.NullTime{Time: g.birthDate(createdAt), Valid: true}
+	}
+	if g.rng.Float64() >= missingPhone {
+		customer.Phone = locale.phone(g.rng)
+	}
+	customer.Email = g.email(first, last, customer.BirthDate)
+	return customer
+}
+
+// createdAt leans towards recent dates, as a growing business signs up more
+// customers every month, and towards office hours on weekdays.
+func (g *Generator) createdAt(locale Locale) time.Time {
+	start := g.cfg.Now.Add(-g.cfg.Span)
+	for {
+		offset := time.Duration(math.Sqrt(g.rng.Float64()) * float64(g.cfg.Span))
+		t := g.officeHours(start.Add(offset), locale)
+		if weekday := t.In(locale.Zone).Weekday(); (weekday == time.Saturday || weekday == time.Sunday) && g.rng.Float64() < 0.7 {
+			continue
+		}
+		if t.Before(g.cfg.Now) && !t.Before(start) {
+			return t
+		}
+	}
+}
+
+// hourWeights is how often a customer gets in touch at each hour of the day.
+var hourWeights = [24]int{0, 0, 0, 0, 0, 0, 1, 2, 6, 10, 12, 12, 9, 10, 11, 10, 8, 6, 4, 3, 2, 1, 1, 0}
+
+// officeHours moves t to a random time of its day in the locale's time zone,
+// weighted by hourWeights.
+func (g *Generator) officeHours(t time.Time, locale Locale) time.Time {
+	total := 0
+	for _, weight := range hourWeights {
+		total += weight
+	}
+	pick := g.rng.Intn(total)
+	hour := 0
+	for pick >= hourWeights[hour] {
+		pick -= hourWeights[hour]
+		hour++
+	}
+	local := t.In(locale.Zone)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, locale.Zone)
+	return day.Add(time.Duration(hour)*time.Hour + time.Duration(g.rng.Intn(3600))*time.Second).UTC()
+}
+
+// birthDate makes the customer between 18 and 90 years old when signing up,
+// most of them between 25 and 55.
+func (g *Generator) birthDate(createdAt time.Time) time.Time {
+	age := g.rng.NormFloat64()*13 + 40
+	age = math.Max(18, math.Min(90, age))
+	born := createdAt.AddDate(-int(age), 0, -g.rng.Intn(365))
+	return time.Date(born.Year(), born.Month(), born.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// email spells the name the way people pick addresses, numbered when
+// another customer already has it.
+func (g *Generator) email(first string, last string, birthDate types.NullTime) string {
+	first, last = emailName(first), emailName(last)
+	var local string
+	switch g.rng.Intn(6) {
+	case 0:
+		local = first + "." + last
+	case 1:
+		local = first + last
+	case 2:
+		local = first[:1] + "." + last
+	case 3:
+		local = first + "_" + last
+	case 4:
+		local = last + "." + first
+	default:
+		year := 1970 + g.rng.Intn(40)
+		if birthDate.Valid {
+			year = birthDate.Time.Year()
+		}
+		local = fmt.Sprintf("%s.%s%02d", first, last, year%100)
+	}
+	host := emailDomains[g.rng.Intn(len(emailDomains))]
+
+	email := local + "@" + host
+	for n := 2; g.emails[email]; n++ {
+		email = fmt.Sprintf("%s%d@%s", local, n, host)
+	}
+	g.emails[email] = true
+	return email
+}
+
+// noteWeights is how often each category of note is written.
+var noteWeights = []struct {
+	category string
+	weight   int
+}{
+	{domain.NoteCategoryCall, 35},
+	{domain.NoteCategoryEmail, 30},
+	{domain.NoteCategoryMeeting, 10},
+	{domain.NoteCategoryComplaint, 10},
+	{domain.NoteCategoryOther, 15},
+}
+
+var noteTexts = map[string][]string{
+	domain.NoteCategoryCall: {
+		"Customer called about a billing issue",
+		"Called back about the open invoice, no answer",
+		"Asked by phone how to change the delivery address",
+		"Customer called to **cancel** the last order",
+	},
+	domain.NoteCategoryEmail: {
+		"Sent the invoice again by email",
+		"Asked for a copy of the contract",
+		"Replied to the question about opening hours",
+		"Sent the new price list:\n\n- basic plan\n- premium plan",
+	},
+	domain.NoteCategoryMeeting: {
+		"Met at the branch office to discuss an upgrade",
+		"Demo of the new mobile app, customer was *interested*",
+	},
+	domain.NoteCategoryComplaint: {
+		"Complained about a late delivery",
+		"Received a damaged item, replacement requested",
+		"Unhappy with the waiting time on the hotline",
+	},
+	domain.NoteCategoryOther: {
+		"Prefers to be contacted in the morning",
+		"Moved to a new address",
+		"Interested in the loyalty programme",
+	},
+}
+
+// notes writes a geometrically distributed number of notes, most of them
+// shortly after the customer signed up.
+func (g *Generator) notes(customer domain.Customer) []domain.CustomerNote {
+	mean := g.cfg.NotesPerCustomer
+	count := 0
+	if mean > 0 {
+		count = int(math.Log(1-g.rng.Float64()) / math.Log(mean/(mean+1)))
+	}
+
+	createdAt := customer.CreatedAt.Time
+	lifetime := g.cfg.Now.Sub(createdAt)
+	notes := make([]domain.CustomerNote, 0, count)
+	for i := 0; i < count; i++ {
+		category := g.category()
+		texts := noteTexts[category]
+		at := createdAt.Add(time.Duration(math.Pow(g.rng.Float64(), 2) * float64(lifetime))).Truncate(time.Second)
+		author := g.cfg.Agents[g.rng.Intn(len(g.cfg.Agents))]
+		pinned := g.rng.Float64() < 0.03
+		visibility := domain.NoteVisibilityInternal
+		if g.rng.Float64() < 0.08 {
+			visibility = domain.NoteVisibilityPrivate
+		}
+		notes = append(notes, domain.CustomerNote{
+			Note:       texts[g.rng.Intn(len(texts))],
+			Author:     author,
+			Category:   category,
+			Pinned:     &pinned,
+			Visibility: visibility,
+			Revision:   1,
+			UpdatedBy:  author,
+			CreatedAt:  types.NullTime{Time: at, Valid: true},
+			UpdatedAt:  types.NullTime{Time: at, Valid: true},
+		})
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].CreatedAt.Time.Before(notes[j].CreatedAt.Time) })
+	return notes
+}
+
+func (g *Generator) category() string {
+	total := 0
+	for _, w := range noteWeights {
+		total += w.weight
+	}
+	pick := g.rng.Intn(total)
+	for _, w := range noteWeights {
+		if pick < w.weight {
+			return w.category
+		}
+		pick -= w.weight
+	}
+	return domain.NoteCategoryOther
+}
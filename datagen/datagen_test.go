@@ -0,0 +1,124 @@
+package datagen
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func generate(cfg Config, n int) []Record {
+	generator := New(cfg)
+	records := make([]Record, n)
+	for i := range records {
+		records[i] = generator.Next()
+	}
+	return records
+}
+
+func TestDeterministic(t *testing.T) {
+	cfg := Config{Seed: 7, Now: now}
+	if first, again := generate(cfg, 50), generate(cfg, 50); !reflect.DeepEqual(first, again) {
+		t.Error("the same config generated different records")
+	}
+	cfg.Seed = 8
+	if first, other := generate(Config{Seed: 7, Now: now}, 1), generate(cfg, 1); reflect.DeepEqual(first, other) {
+		t.Error("seeds 7 and 8 generated the same record")
+	}
+}
+
+func TestCustomers(t *testing.T) {
+	records := generate(Config{Seed: 1, Now: now, Span: 365 * 24 * time.Hour}, 2000)
+	emails := map[string]bool{}
+	var withBirthDate, adults, recent, officeHours, notes int
+	for _, record := range records {
+		customer := record.Customer
+		if emails[customer.Email] {
+			t.Errorf("email %s generated twice", customer.Email)
+		}
+		emails[customer.Email] = true
+
+		createdAt := customer.CreatedAt.Time
+		if createdAt.After(now) || createdAt.Before(now.AddDate(-1, 0, 0)) {
+			t.Fatalf("created_at %v outside of the span", createdAt)
+		}
+		if createdAt.After(now.AddDate(0, -6, 0)) {
+			recent++
+		}
+		if hour := createdAt.In(Locales[DefaultLocale].Zone).Hour(); hour >= 8 && hour < 18 {
+			officeHours++
+		}
+		if customer.BirthDate.Valid {
+			withBirthDate++
+			age := createdAt.Sub(customer.BirthDate.Time).Hours() / 24 / 365
+			if age < 17.9 || age > 91 {
+				t.Errorf("customer aged %.1f at sign up", age)
+			}
+			if age >= 25 && age < 55 {
+				adults++
+			}
+		}
+		for _, note := range record.Notes {
+			notes++
+			if note.CreatedAt.Time.Before(createdAt) || note.CreatedAt.Time.After(now) || note.Author == "" || note.Pinned == nil {
+				t.Fatalf("note %+v of a customer created at %v", note, createdAt)
+			}
+		}
+	}
+
+	// Loose bounds on the distributions, which are fixed by the seed.
+	n := len(records)
+	if recent < n*6/10 {
+		t.Errorf("%d of %d customers created in the last half of the span, want most", recent, n)
+	}
+	if officeHours < n*8/10 {
+		t.Errorf("%d of %d customers created in office hours", officeHours, n)
+	}
+	if withBirthDate < n*8/10 || withBirthDate == n {
+		t.Errorf("%d of %d customers with a birth date", withBirthDate, n)
+	}
+	if adults < withBirthDate*6/10 {
+		t.Errorf("%d of %d customers between 25 and 55", adults, withBirthDate)
+	}
+	if mean := float64(notes) / float64(n); mean < 2.5 || mean > 3.5 {
+		t.Errorf("%.2f notes per customer, want about %d", mean, DefaultNotesPerCustomer)
+	}
+}
+
+func TestLocales(t *testing.T) {
+	patterns := map[string]*regexp.Regexp{
+		"id_ID": regexp.MustCompile(`^0[2-8]\d{8,10}$`),
+		"en_US": regexp.MustCompile(`^(\([2-9]\d\d\) [2-9]\d\d-\d{4}|[2-9]\d\d-[2-9]\d\d-\d{4}|\+1 [2-9]\d\d [2-9]\d\d \d{4})$`),
+		"de_DE": regexp.MustCompile(`^(0\d{2,3} \d{7,8}|\+49 40 \d{7})$`),
+	}
+	for code, pattern := range patterns {
+		locales, err := LookupLocales([]string{code})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range generate(Config{Seed: 1, Now: now, Locales: locales}, 200) {
+			customer := record.Customer
+			if customer.Phone != "" && !pattern.MatchString(customer.Phone) {
+				t.Errorf("%s phone %q", code, customer.Phone)
+			}
+			if strings.ContainsAny(customer.Email, "äöüß' ") {
+				t.Errorf("%s email %q", code, customer.Email)
+			}
+		}
+	}
+
+	if _, err := LookupLocales([]string{"xx_XX"}); err == nil {
+		t.Error("LookupLocales() of an unknown locale succeeded")
+	}
+}
+
+func TestEmailName(t *testing.T) {
+	for name, want := range map[string]string{"Müller": "mueller", "Groß": "gross", "O'Brien": "obrien", "Budi": "budi"} {
+		if got := emailName(name); got != want {
+			t.Errorf("emailName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package datagen
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Locale is where generated customers come from: their names, the way their
+// phone numbers are written and the time zone they contact agents in.
+type Locale struct {
+	Code       string
+	FirstNames []string
+	LastNames  []string
+	// PhoneFormats are phone numbers with # standing for any digit and N
+	// for a digit from 2 to 9.
+	PhoneFormats []string
+	Zone         *time.Location
+}
+
+// Locales are the locales data can be generated for, by code.
+var Locales = map[string]Locale{
+	"id_ID": {
+		Code: "id_ID",
+		FirstNames: []string{
+			"Adi", "Agus", "Ahmad", "Ayu", "Bambang", "Budi", "Citra", "Dewi", "Dian", "Eko",
+			"Fajar", "Fitri", "Gita", "Hadi", "Hendra", "Indah", "Joko", "Kartika", "Maya", "Muhammad",
+			"Nur", "Putri", "Rina", "Rudi", "Sari", "Siti", "Sri", "Wahyu", "Yusuf", "Yulia",
+		},
+		LastNames: []string{
+			"Gunawan", "Halim", "Hasibuan", "Hidayat", "Kusuma", "Lestari", "Nasution", "Nugroho", "Pratama", "Putra",
+			"Rahmawati", "Santoso", "Saputra", "Setiawan", "Simanjuntak", "Siregar", "Susanto", "Tanjung", "Wibowo", "Wijaya",
+		},
+		PhoneFormats: []string{"0811########", "0812########", "0813########", "0821########", "0852########", "0857#######", "0878########", "0896########", "021N#######"},
+		Zone:         time.FixedZone("WIB", 7*60*60),
+	},
+	"en_US": {
+		Code: "en_US",
+		FirstNames: []string{
+			"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth",
+			"William", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Christopher", "Karen",
+			"Daniel", "Emily", "Matthew", "Ashley",
+		},
+		LastNames: []string{
+			"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+			"Hernandez", "Lopez", "Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin", "Lee", "O'Brien",
+		},
+		PhoneFormats: []string{"(N##) N##-####", "N##-N##-####", "+1 N## N## ####"},
+		Zone:         time.FixedZone("EST", -5*60*60),
+	},
+	"de_DE": {
+		Code: "de_DE",
+		FirstNames: []string{
+			"Lukas", "Leon", "Finn", "Jonas", "Paul", "Felix", "Maximilian", "Anna", "Lena", "Lea",
+			"Hannah", "Sophie", "Marie", "Jürgen", "Uwe", "Sabine", "Petra", "Monika", "Klaus", "Stefan", "Katrin", "Jörg",
+		},
+		LastNames: []string{
+			"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann",
+			"Schäfer", "Koch", "Bauer", "Richter", "Klein", "Wolf", "Schröder", "Neumann", "Schwarz", "Zimmermann", "Krüger", "Groß",
+		},
+		PhoneFormats: []string{"0151 ########", "0160 #######", "0171 #######", "030 ########", "089 #######", "+49 40 #######"},
+		Zone:         time.FixedZone("CET", 1*60*60),
+	},
+}
+
+// DefaultLocale matches the sample customer of the first migration.
+const DefaultLocale = "id_ID"
+
+// LookupLocales returns the locales of codes, e.g. "de_DE".
+func LookupLocales(codes []string) ([]Locale, error) {
+	locales := make([]Locale, 0, len(codes))
+	for _, code := range codes {
+		locale, ok := Locales[code]
+		if !ok {
+			return nil, fmt.Errorf("unknown locale %q, want one of %s", code, strings.Join(LocaleCodes(), ", "))
+		}
+		locales = append(locales, locale)
+	}
+	return locales, nil
+}
+
+// LocaleCodes lists the codes of Locales.
+func LocaleCodes() []string {
+	codes := make([]string, 0, len(Locales))
+	for code := range Locales {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func (l Locale) phone(rng *rand.Rand) string {
+	format := l.PhoneFormats[rng.Intn(len(l.PhoneFormats))]
+	phone := []byte(format)
+	for i, c := range phone {
+		switch c {
+		case '#':
+			phone[i] = byte('0' + rng.Intn(10))
+		case 'N':
+			phone[i] = byte('2' + rng.Intn(8))
+		}
+	}
+	return string(phone)
+}
+
+// transliterations spell names in the ASCII of email addresses.
+var transliterations = strings.NewReplacer(
+	"ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss",
+	"Ä", "Ae", "Ö", "Oe", "Ü", "Ue",
+	"'", "", " ", "",
+)
+
+func emailName(name string) string {
+	return strings.ToLower(transliterations.Replace(name))
+}
@@ -0,0 +1,249 @@
+// Package dedupe scores pairs of customers that are likely to be the same
+// person.
+//
+// Customers are first grouped by cheap blocking keys (normalised email, phone
+// digits, name token and birth date) so only customers sharing a key are
+// compared. Each pair is then scored between 0 and 1 from its normalised
+// email, phone digits, name similarity and birth date.
+package dedupe
+
+import (
+	"customer-playground/domain"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultThreshold is the score from which a pair is reported.
+const DefaultThreshold = 0.5
+
+// maxBlockSize keeps very common keys, such as a popular first name, from
+// turning the scan quadratic.
+const maxBlockSize = 500
+
+const (
+	weightEmail = 0.4
+	weightPhone = 0.3
+	weightName  = 0.2
+	weightBirth = 0.1
+
+	// penaltyBirth applies when both birth dates are known and differ.
+	penaltyBirth = 0.15
+
+	// phoneSuffix is the number of trailing digits compared, which ignores
+	// country and trunk prefixes such as +62 and 0.
+	phoneSuffix = 9
+)
+
+// Match is a scored pair of customers, with A < B.
+type Match struct {
+	A       int
+	B       int
+	Score   float64
+	Reasons []string
+}
+
+// NormalizeEmail lower cases the address, drops "+tag" suffixes and the dots
+// Gmail ignores.
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, host := email[:at], email[at+1:]
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+	if host == "gmail.com" || host == "googlemail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+		host = "gmail.com"
+	}
+	return local + "@" + host
+}
+
+// PhoneDigits returns the trailing digits of a phone number, or "" when the
+// number is too short to compare.
+func PhoneDigits(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	s := digits.String()
+	if len(s) < 7 {
+		return ""
+	}
+	if len(s) > phoneSuffix {
+		s = s[len(s)-phoneSuffix:]
+	}
+	return s
+}
+
+// NormalizeName lower cases the name, drops punctuation and sorts its tokens
+// so "Doe, John" and "john doe" compare equal.
+func NormalizeName(name string) string {
+	tokens := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+// Score compares two customers.
+func Score(a, b domain.Customer) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	if email := NormalizeEmail(a.Email); email != "" && email == NormalizeEmail(b.Email) {
+		score += weightEmail
+		reasons = append(reasons, "email")
+	}
+	if phone := PhoneDigits(a.Phone); phone != "" && phone == PhoneDigits(b.Phone) {
+		score += weightPhone
+		reasons = append(reasons, "phone")
+	}
+	if similarity := JaroWinkler(NormalizeName(a.Name), NormalizeName(b.Name)); similarity >= 0.8 {
+		score += weightName * similarity
+		reasons = append(reasons, fmt.Sprintf("name %.2f", similarity))
+	}
+	if a.BirthDate.Valid && b.BirthDate.Valid {
+		if a.BirthDate.Time.Format("2006-01-02") == b.BirthDate.Time.Format("2006-01-02") {
+			score += weightBirth
+			reasons = append(reasons, "birth_date")
+		} else {
+			score -= penaltyBirth
+		}
+	}
+	if score < 0 {
+		score = 0
+	}
+	return score, reasons
+}
+
+// FindCandidates returns every pair scoring at least threshold, best first.
+func FindCandidates(customers []domain.Customer, threshold float64) []Match {
+	blocks := make(map[string][]int)
+	for i, customer := range customers {
+		for _, key := range blockingKeys(customer) {
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+
+	type pair struct{ i, j int }
+	seen := make(map[pair]bool)
+	var matches []Match
+	for _, members := range blocks {
+		if len(members) < 2 || len(members) > maxBlockSize {
+			continue
+		}
+		for x := 0; x < len(members); x++ {
+			for y := x + 1; y < len(members); y++ {
+				p := pair{members[x], members[y]}
+				if seen[p] {
+					continue
+				}
+				seen[p] = true
+
+				a, b := customers[p.i], customers[p.j]
+				score, reasons := Score(a, b)
+				if score < threshold {
+					continue
+				}
+				if a.CustomerNumber > b.CustomerNumber {
+					a, b = b, a
+				}
+				matches = append(matches, Match{A: a.CustomerNumber, B: b.CustomerNumber, Score: score, Reasons: reasons})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if matches[i].A != matches[j].A {
+			return matches[i].A < matches[j].A
+		}
+		return matches[i].B < matches[j].B
+	})
+	return matches
+}
+
+func blockingKeys(customer domain.Customer) []string {
+	var keys []string
+	if email := NormalizeEmail(customer.Email); email != "" {
+		keys = append(keys, "e:"+email)
+	}
+	if phone := PhoneDigits(customer.Phone); phone != "" {
+		keys = append(keys, "p:"+phone)
+	}
+	name := NormalizeName(customer.Name)
+	for _, token := range strings.Fields(name) {
+		if len(token) >= 3 {
+			keys = append(keys, "n:"+token)
+		}
+	}
+	if customer.BirthDate.Valid {
+		keys = append(keys, "b:"+customer.BirthDate.Time.Format("2006-01-02"))
+	}
+	return keys
+}
+
+// JaroWinkler returns the Jaro-Winkler similarity of two strings, from 0 for
+// nothing in common to 1 for equal strings.
+func JaroWinkler(a, b string) float64 {
+	s1, s2 := []rune(a), []rune(b)
+	if len(s1) == 0 && len(s2) == 0 {
+		return 1
+	}
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	window := max(len(s1), len(s2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matched1 := make([]bool, len(s1))
+	matched2 := make([]bool, len(s2))
+	matches := 0
+	for i := range s1 {
+		for j := max(0, i-window); j < min(len(s2), i+window+1); j++ {
+			if !matched2[j] && s1[i] == s2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range s1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[j] {
+			j++
+		}
+		if s1[i] != s2[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(s1), len(s2)) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
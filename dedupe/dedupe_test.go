@@ -0,0 +1,108 @@
+package dedupe
+
+import (
+	"customer-playground/domain"
+	"customer-playground/types"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func birthDate(s string) types.NullTime {
+	t, _ := time.Parse("2006-01-02", s)
+	return types.NullTime{Time: t, Valid: true}
+}
+
+func TestNormalize(t *testing.T) {
+	emails := map[string]string{
+		" John.Doe+news@GMail.com ": "johndoe@gmail.com",
+		"john.doe@googlemail.com":   "johndoe@gmail.com",
+		"john.doe+x@example.com":    "john.doe@example.com",
+		"not-an-email":              "not-an-email",
+	}
+	for in, want := range emails {
+		if got := NormalizeEmail(in); got != want {
+			t.Errorf("NormalizeEmail(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	phones := map[string]string{
+		"+62 812-3456-7890": "234567890",
+		"0812 3456 7890":    "234567890",
+		"12345":             "",
+		"555-1234":          "5551234",
+	}
+	for in, want := range phones {
+		if got := PhoneDigits(in); got != want {
+			t.Errorf("PhoneDigits(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	if got := NormalizeName("Doe, John"); got != "doe john" {
+		t.Errorf("NormalizeName() = %q, want %q", got, "doe john")
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"martha", "marhta", 0.961},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.813},
+		{"same", "same", 1},
+		{"", "", 1},
+		{"abc", "", 0},
+		{"abc", "xyz", 0},
+	}
+	for _, test := range tests {
+		if got := JaroWinkler(test.a, test.b); math.Abs(got-test.want) > 0.001 {
+			t.Errorf("JaroWinkler(%q, %q) = %.3f, want %.3f", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	a := domain.Customer{CustomerNumber: 1, Name: "John Doe", Email: "john.doe@gmail.com", Phone: "+62 812 3456 7890", BirthDate: birthDate("1990-01-02")}
+	b := domain.Customer{CustomerNumber: 2, Name: "Doe John", Email: "johndoe+shop@gmail.com", Phone: "081234567890", BirthDate: birthDate("1990-01-02")}
+
+	score, reasons := Score(a, b)
+	if math.Abs(score-1) > 1e-9 {
+		t.Errorf("Score() = %v, want 1", score)
+	}
+	if want := []string{"email", "phone", "name 1.00", "birth_date"}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("Score() reasons = %v, want %v", reasons, want)
+	}
+
+	b.BirthDate = birthDate("1991-01-02")
+	if score, _ := Score(a, b); math.Abs(score-(weightEmail+weightPhone+weightName-penaltyBirth)) > 1e-9 {
+		t.Errorf("Score() with different birth dates = %v", score)
+	}
+
+	c := domain.Customer{CustomerNumber: 3, Name: "Jane Roe", Email: "jane@example.com"}
+	if score, reasons := Score(a, c); score != 0 || reasons != nil {
+		t.Errorf("Score() of unrelated customers = %v %v, want 0", score, reasons)
+	}
+}
+
+func TestFindCandidates(t *testing.T) {
+	customers := []domain.Customer{
+		{CustomerNumber: 4, Name: "John Doe", Email: "john.doe@example.com", Phone: "081234567890"},
+		{CustomerNumber: 2, Name: "Jon Doe", Email: "JOHN.DOE@example.com"},
+		{CustomerNumber: 3, Name: "Jane Roe", Email: "jane@example.com", Phone: "081299999999"},
+		{CustomerNumber: 7, Name: "Jane Roe", Phone: "+62 812 9999 9999"},
+	}
+
+	matches := FindCandidates(customers, DefaultThreshold)
+	if len(matches) != 2 {
+		t.Fatalf("FindCandidates() = %+v, want 2 matches", matches)
+	}
+	if matches[0].A != 2 || matches[0].B != 4 {
+		t.Errorf("best match = %d/%d, want 2/4", matches[0].A, matches[0].B)
+	}
+	if matches[1].A != 3 || matches[1].B != 7 {
+		t.Errorf("second match = %d/%d, want 3/7", matches[1].A, matches[1].B)
+	}
+}
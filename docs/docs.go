@@ -15,23 +15,23 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/customer": {
+        "/custom-field": {
             "get": {
-                "description": "Retrieves all customers",
+                "description": "Retrieves every custom field definition",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "custom-field"
                 ],
-                "summary": "Get all customers",
+                "summary": "Get all custom fields",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/domain.Customer"
+                                "$ref": "#/definitions/domain.CustomFieldDefinition"
                             }
                         }
                     },
@@ -43,8 +43,8 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
-                "description": "Updates an existing customer by customer number or ID",
+            "post": {
+                "description": "Defines a custom field that customers can carry in custom_fields. Types are string, integer, number, boolean, date and enum; strings can have a validation pattern.",
                 "consumes": [
                     "application/json"
                 ],
@@ -52,17 +52,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "custom-field"
                 ],
-                "summary": "Update customer",
+                "summary": "Define a custom field",
                 "parameters": [
                     {
-                        "description": "Customer payload",
-                        "name": "customer",
+                        "description": "Custom Field Payload",
+                        "name": "definition",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.Customer"
+                            "$ref": "#/definitions/domain.CustomFieldDefinition"
                         }
                     }
                 ],
@@ -86,35 +86,32 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Adds a new customer to the database",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/custom-field/{id}": {
+            "get": {
+                "description": "Retrieves a custom field definition by its ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "custom-field"
                 ],
-                "summary": "Insert new customer",
+                "summary": "Get a custom field",
                 "parameters": [
                     {
-                        "description": "Customer payload",
-                        "name": "customer",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.Customer"
-                        }
+                        "type": "integer",
+                        "description": "Custom Field ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.Response"
+                            "$ref": "#/definitions/domain.CustomFieldDefinition"
                         }
                     },
                     "400": {
@@ -130,11 +127,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/customer-note": {
+            },
             "put": {
-                "description": "Updates an existing customer note",
+                "description": "Updates a custom field definition. The name and type cannot change, required is always taken from the payload and other empty fields keep their current value. Stored values are not revalidated.",
                 "consumes": [
                     "application/json"
                 ],
@@ -142,79 +137,81 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "customer-note"
+                    "custom-field"
                 ],
-                "summary": "Update a customer note",
+                "summary": "Update a custom field",
                 "parameters": [
                     {
-                        "description": "Customer Note Payload",
-                        "name": "customerNote",
+                        "type": "integer",
+                        "description": "Custom Field ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Custom Field Payload",
+                        "name": "definition",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.CustomerNote"
+                            "$ref": "#/definitions/domain.CustomFieldDefinition"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Update result",
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/domain.Response"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     }
                 }
             },
-            "post": {
-                "description": "Inserts a new customer note into the system",
-                "consumes": [
-                    "application/json"
-                ],
+            "delete": {
+                "description": "Deletes a custom field definition and removes its value from every customer",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customer-note"
+                    "custom-field"
                 ],
-                "summary": "Create a new customer note",
+                "summary": "Delete a custom field",
                 "parameters": [
                     {
-                        "description": "Customer Note Payload",
-                        "name": "customerNote",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.CustomerNote"
-                        }
+                        "type": "integer",
+                        "description": "Custom Field ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Insert result",
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/domain.Response"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
@@ -222,101 +219,160 @@ const docTemplate = `{
                 }
             }
         },
-        "/customer-note/get-all": {
+        "/customer": {
             "get": {
-                "description": "Retrieves all customer notes from the system",
+                "description": "Retrieves all customers. Filter by custom fields with cf[name]=value, e.g. cf[preferred_language]=en. Email, phone and birth date are masked unless the caller holds pii:read.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
                 ],
                 "tags": [
-                    "customer-note"
+                    "customer"
+                ],
+                "summary": "Get all customers",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only the customer with this email, regardless of case",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only customers whose custom field name has this value",
+                        "name": "cf[name]",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all customer notes",
                 "responses": {
                     "200": {
-                        "description": "List of customer notes",
+                        "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/domain.CustomerNote"
+                                "$ref": "#/definitions/domain.Customer"
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/customer-note/get-by-customer-number/{customer_number}": {
-            "get": {
-                "description": "Retrieves all notes associated with a given customer number",
+            },
+            "put": {
+                "description": "Updates an existing customer by customer number or ID",
+                "consumes": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
                 ],
                 "tags": [
-                    "customer-note"
+                    "customer"
                 ],
-                "summary": "Get customer notes by customer number",
+                "summary": "Update customer",
+                "deprecated": true,
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Customer Number",
-                        "name": "customer_number",
-                        "in": "path",
-                        "required": true
+                        "description": "Customer payload",
+                        "name": "customer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Customer"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Customer notes for the customer number",
+                        "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.CustomerNote"
-                            }
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/customer-note/get-by-id/{id}": {
-            "get": {
-                "description": "Retrieves a customer note by its unique ID",
+            },
+            "post": {
+                "description": "Adds a new customer to the database",
+                "consumes": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
                 ],
                 "tags": [
-                    "customer-note"
+                    "customer"
                 ],
-                "summary": "Get a customer note by ID",
+                "summary": "Insert new customer",
+                "deprecated": true,
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Customer Note ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Customer payload",
+                        "name": "customer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Customer"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Customer note",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.CustomerNote"
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
@@ -324,34 +380,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/customer-note/{id}": {
-            "delete": {
-                "description": "Deletes a customer note by its ID",
+        "/customer-duplicate": {
+            "get": {
+                "description": "Retrieves the pairs of customers that are likely duplicates, best match first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customer-note"
+                    "customer-merge"
                 ],
-                "summary": "Delete a customer note by ID",
+                "summary": "Get duplicate review queue",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Customer Note ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "pending (default) or dismissed",
+                        "name": "status",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Delete result",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.Response"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.DuplicateCandidate"
+                            }
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/domain.ErrorResponse"
                         }
@@ -359,30 +417,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/customer/{customer_number}": {
-            "get": {
-                "description": "Retrieves a customer by their customer number",
+        "/customer-duplicate/scan": {
+            "post": {
+                "description": "Scores every customer against the others and queues likely duplicates for review. Dismissed pairs stay dismissed.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
-                ],
-                "summary": "Get customer by number",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Customer Number",
-                        "name": "customer_number",
-                        "in": "path",
-                        "required": true
-                    }
+                    "customer-merge"
                 ],
+                "summary": "Scan for duplicates",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.Customer"
+                            "$ref": "#/definitions/domain.DuplicateScanResult"
                         }
                     },
                     "500": {
@@ -392,21 +441,23 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Deletes a customer based on customer number",
+            }
+        },
+        "/customer-duplicate/{id}/dismiss": {
+            "post": {
+                "description": "Marks a pair as not being duplicates",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "customer-merge"
                 ],
-                "summary": "Delete customer by number",
+                "summary": "Dismiss duplicate",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Customer Number",
-                        "name": "customer_number",
+                        "description": "Duplicate Candidate ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -432,50 +483,3051 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "domain.Customer": {
-            "type": "object",
-            "properties": {
-                "birth_date": {
-                    "type": "string",
-                    "example": "1995-06-12T00:00:00Z"
-                },
-                "created_at": {
-                    "$ref": "#/definitions/types.NullTime"
-                },
-                "customer_number": {
-                    "type": "integer"
-                },
-                "email": {
-                    "type": "string"
-                },
-                "name": {
-                    "type": "string"
-                },
-                "phone": {
-                    "type": "string"
-                },
-                "updated_at": {
-                    "$ref": "#/definitions/types.NullTime"
-                }
-            }
         },
-        "domain.CustomerNote": {
-            "type": "object",
+        "/customer-duplicate/{id}/merge": {
+            "post": {
+                "description": "Merges a pair from the review queue. Without survivor_customer_number the customer with the lower number survives.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-merge"
+                ],
+                "summary": "Merge duplicate",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Duplicate Candidate ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Only survivor_customer_number is used",
+                        "name": "merge",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerMergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-erasure": {
+            "get": {
+                "description": "Retrieves erasure requests, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Get erasure requests",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pending, rejected or completed; all when empty",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.ErasureRequest"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-erasure/signing-key": {
+            "get": {
+                "description": "Retrieves the public key erasure certificates are signed with",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Get certificate signing key",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SigningKey"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-erasure/{id}": {
+            "get": {
+                "description": "Retrieves one erasure request with its certificate once completed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Get erasure request by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Erasure Request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErasureRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-erasure/{id}/approve": {
+            "post": {
+                "description": "Carries out a pending erasure request: the customer is anonymised, addresses, contact points and attachments are deleted and notes with their revisions are redacted. The customer number and everything referring to it stay. The approver is the subject of the bearer token, must hold privacy:approve and must not be the requester.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Approve erasure",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Erasure Request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer token of the agent approving the erasure",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-erasure/{id}/certificate": {
+            "get": {
+                "description": "Retrieves the signed certificate of a completed erasure. The signature covers the payload bytes as returned; check it with the key from /customer-erasure/signing-key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Get erasure certificate",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Erasure Request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErasureCertificate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-erasure/{id}/reject": {
+            "post": {
+                "description": "Rejects a pending erasure request. The reviewer is the subject of the bearer token, must hold privacy:approve and must not be the requester.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Reject erasure",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Erasure Request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer token of the agent rejecting the erasure",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-merge": {
+            "post": {
+                "description": "Moves notes, addresses, contact points and tags of the merged customer to the survivor in one transaction, records the merge and redirects the merged customer number to the survivor",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-merge"
+                ],
+                "summary": "Merge customers",
+                "parameters": [
+                    {
+                        "description": "Customer Merge Payload",
+                        "name": "merge",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerMergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-note": {
+            "put": {
+                "description": "Updates an existing customer note and records the new version in its revision history. Author and created_at cannot be changed.",
+                "consumes": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "customer-note"
+                ],
+                "summary": "Update a customer note",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Customer Note Payload",
+                        "name": "customerNote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerNote"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent making the request",
+                        "name": "X-User",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Update result",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Inserts a new customer note into the system. The note text is Markdown. The author is taken from the X-User header when it is set.",
+                "consumes": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "customer-note"
+                ],
+                "summary": "Create a new customer note",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "Customer Note Payload",
+                        "name": "customerNote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerNote"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent making the request",
+                        "name": "X-User",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Insert result",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-note/get-all": {
+            "get": {
+                "description": "Retrieves all customer notes from the system",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "customer-note"
+                ],
+                "summary": "Get all customer notes",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "enum": [
+                            "markdown",
+                            "html",
+                            "text"
+                        ],
+                        "type": "string",
+                        "description": "Form of the note text",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of customer notes",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.CustomerNote"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-note/get-by-customer-number/{customer_number}": {
+            "get": {
+                "description": "Retrieves all notes associated with a given customer number",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "customer-note"
+                ],
+                "summary": "Get customer notes by customer number",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "markdown",
+                            "html",
+                            "text"
+                        ],
+                        "type": "string",
+                        "description": "Form of the note text",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Customer notes for the customer number",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.CustomerNote"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-note/get-by-id/{id}": {
+            "get": {
+                "description": "Retrieves a customer note by its unique ID. Notes are written in Markdown; with Accept text/html, text/markdown or text/plain only the note text is returned in that form, with the format parameter the note field of the JSON.",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf",
+                    "text/html",
+                    "text/plain",
+                    "text/markdown"
+                ],
+                "tags": [
+                    "customer-note"
+                ],
+                "summary": "Get a customer note by ID",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "markdown",
+                            "html",
+                            "text"
+                        ],
+                        "type": "string",
+                        "description": "Form of the note text",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Customer note",
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerNote"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-note/{id}": {
+            "delete": {
+                "description": "Deletes a customer note by its ID",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "customer-note"
+                ],
+                "summary": "Delete a customer note by ID",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delete result",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-note/{id}/attachments": {
+            "get": {
+                "description": "Retrieves the files attached to a customer note",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "note-attachment"
+                ],
+                "summary": "Get note attachments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent making the request, needed for private notes",
+                        "name": "X-User",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.NoteAttachment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Attaches a PDF, image or plain text file to a customer note. The type is sniffed from the content; identical files are stored once.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "note-attachment"
+                ],
+                "summary": "Upload a note attachment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to attach",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent making the request",
+                        "name": "X-User",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-note/{id}/attachments/{attachment_id}": {
+            "get": {
+                "description": "Downloads the content of a note attachment",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "note-attachment"
+                ],
+                "summary": "Download a note attachment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Attachment ID",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent making the request, needed for private notes",
+                        "name": "X-User",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes an attachment from a note. The stored file is removed once no note refers to it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "note-attachment"
+                ],
+                "summary": "Delete a note attachment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Attachment ID",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent making the request, needed for private notes",
+                        "name": "X-User",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-note/{id}/revisions": {
+            "get": {
+                "description": "Retrieves every version of a note, oldest first, each with a diff against the version before it",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "customer-note"
+                ],
+                "summary": "Get the revisions of a customer note",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent making the request; private notes need a bearer token instead",
+                        "name": "X-User",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Customer note revisions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.CustomerNoteRevision"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}": {
+            "get": {
+                "description": "Retrieves a customer by their customer number. The number of a merged customer redirects to the customer it was merged into. Email, phone and birth date are masked unless the caller holds pii:read.",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "customer"
+                ],
+                "summary": "Get customer by number",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Customer"
+                        }
+                    },
+                    "301": {
+                        "description": "Customer was merged, see Location"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a customer based on customer number",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "customer"
+                ],
+                "summary": "Delete customer by number",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}/addresses": {
+            "get": {
+                "description": "Retrieves every address of a customer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-address"
+                ],
+                "summary": "Get customer addresses",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.CustomerAddress"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds a billing or shipping address to a customer. Marking it as default clears the previous default of the same type.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-address"
+                ],
+                "summary": "Add a customer address",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Customer Address Payload",
+                        "name": "customerAddress",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerAddress"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}/addresses/{id}": {
+            "get": {
+                "description": "Retrieves one address of a customer by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-address"
+                ],
+                "summary": "Get a customer address",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Customer Address ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerAddress"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates an address of a customer. Empty fields keep their current value.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-address"
+                ],
+                "summary": "Update a customer address",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Customer Address ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Customer Address Payload",
+                        "name": "customerAddress",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerAddress"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes an address of a customer by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-address"
+                ],
+                "summary": "Delete a customer address",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Customer Address ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}/contact-points": {
+            "get": {
+                "description": "Retrieves every email address and phone number of a customer. Values are masked unless the caller holds pii:read.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-contact-point"
+                ],
+                "summary": "Get customer contact points",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.CustomerContactPoint"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds an email address or phone number to a customer. The first contact point of a type becomes primary; marking another one as primary replaces it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-contact-point"
+                ],
+                "summary": "Add a customer contact point",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Contact Point Payload",
+                        "name": "contactPoint",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerContactPoint"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}/contact-points/{id}": {
+            "get": {
+                "description": "Retrieves one contact point of a customer by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-contact-point"
+                ],
+                "summary": "Get a customer contact point",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Contact Point ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerContactPoint"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates a contact point of a customer. Empty fields keep their current value, the type cannot change and changing the value clears its verification.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-contact-point"
+                ],
+                "summary": "Update a customer contact point",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Contact Point ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Contact Point Payload",
+                        "name": "contactPoint",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomerContactPoint"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a contact point of a customer by its ID. Deleting a primary promotes the next contact point of the same type.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-contact-point"
+                ],
+                "summary": "Delete a customer contact point",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Contact Point ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}/contact-points/{id}/confirm": {
+            "post": {
+                "description": "Marks the contact point as verified when the code matches. A code can be tried 5 times.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-contact-point"
+                ],
+                "summary": "Confirm a verification code",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Contact Point ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Verification Code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.ContactVerificationCode"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}/contact-points/{id}/verify": {
+            "post": {
+                "description": "Sends a one-time code to the contact point. The code expires after 10 minutes and a new request replaces any pending code.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-contact-point"
+                ],
+                "summary": "Send a verification code",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Contact Point ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}/data-export": {
+            "get": {
+                "description": "Assembles everything held about a customer for a data subject access request: profile, addresses, contact points, tags, notes with their revisions and attachments, merges and erasure requests. Private notes are included. format=zip adds the attached files. Email, phone and birth date are masked unless the caller holds pii:read.",
+                "produces": [
+                    "application/json",
+                    "application/zip"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Export customer data",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or zip",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent making the export",
+                        "name": "X-User",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DataSubjectExport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}/erasure-requests": {
+            "post": {
+                "description": "Asks to erase the personal data of a customer. The requester is the subject of the bearer token; someone other than the requester has to approve it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "privacy"
+                ],
+                "summary": "Request erasure",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Only reason is used",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErasureRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer token of the agent requesting the erasure",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}/merges": {
+            "get": {
+                "description": "Retrieves the merges a customer took part in, as survivor or as merged customer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer-merge"
+                ],
+                "summary": "Get merge history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.CustomerMerge"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{customer_number}/tags": {
+            "get": {
+                "description": "Retrieves the tags of a customer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tag"
+                ],
+                "summary": "Get customer tags",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer Number",
+                        "name": "customer_number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Tag"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/segment": {
+            "get": {
+                "description": "Retrieves every saved segment",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "segment"
+                ],
+                "summary": "Get all segments",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Segment"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Saves a segment. The definition is checked before it is stored, e.g. \"tag = vip AND birth_date IN THIS MONTH\".",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "segment"
+                ],
+                "summary": "Create a segment",
+                "parameters": [
+                    {
+                        "description": "Segment Payload",
+                        "name": "segment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Segment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/segment/evaluate": {
+            "post": {
+                "description": "Evaluates an unsaved definition, e.g. to preview a segment before saving it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "segment"
+                ],
+                "summary": "Evaluate a definition",
+                "parameters": [
+                    {
+                        "description": "Segment Definition",
+                        "name": "definition",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.SegmentDefinition"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Customers to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SegmentMembers"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/segment/{id}": {
+            "get": {
+                "description": "Retrieves a saved segment by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "segment"
+                ],
+                "summary": "Get a segment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Segment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Segment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates a saved segment. Empty fields keep their current value.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "segment"
+                ],
+                "summary": "Update a segment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Segment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Segment Payload",
+                        "name": "segment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Segment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a saved segment by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "segment"
+                ],
+                "summary": "Delete a segment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Segment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/segment/{id}/count": {
+            "get": {
+                "description": "Evaluates a saved segment and returns the number of its customers",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "segment"
+                ],
+                "summary": "Count segment members",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Segment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SegmentCount"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/segment/{id}/members": {
+            "get": {
+                "description": "Evaluates a saved segment and returns one page of its customers with the total count",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "segment"
+                ],
+                "summary": "Get segment members",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Segment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Customers to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SegmentMembers"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tag": {
+            "get": {
+                "description": "Retrieves every tag with the number of customers carrying it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tag"
+                ],
+                "summary": "Get all tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Tag"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tag/add": {
+            "post": {
+                "description": "Adds every tag to every customer in one transaction. Unknown tags are created; nothing is tagged when a customer does not exist.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tag"
+                ],
+                "summary": "Tag customers",
+                "parameters": [
+                    {
+                        "description": "Tag Assignment Payload",
+                        "name": "assignment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.TagAssignment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tag/remove": {
+            "post": {
+                "description": "Removes every tag from every customer. Tags themselves are kept.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tag"
+                ],
+                "summary": "Untag customers",
+                "parameters": [
+                    {
+                        "description": "Tag Assignment Payload",
+                        "name": "assignment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.TagAssignment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{user}/follow-ups": {
+            "get": {
+                "description": "Retrieves the open follow-up notes assigned to a user across all customers, the earliest due first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "follow-up"
+                ],
+                "summary": "Get a user's open follow-ups",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Assignee",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent making the request, needed for private notes",
+                        "name": "X-User",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.CustomerNote"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{user}/mentions": {
+            "get": {
+                "description": "Retrieves the notes that mention a user as @user, the newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "follow-up"
+                ],
+                "summary": "Get notes mentioning a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mentioned user",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent making the request, needed for private notes",
+                        "name": "X-User",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.CustomerNote"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "domain.ContactVerificationCode": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "domain.CustomFieldDefinition": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "enum_values": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en",
+                        "id"
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "Preferred language"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "preferred_language"
+                },
+                "pattern": {
+                    "type": "string",
+                    "example": "^[A-Z]{2}[0-9]{6}$"
+                },
+                "required": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "string",
+                        "integer",
+                        "number",
+                        "boolean",
+                        "date",
+                        "enum"
+                    ],
+                    "example": "enum"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                }
+            }
+        },
+        "domain.Customer": {
+            "type": "object",
+            "properties": {
+                "birth_date": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "custom_fields": {
+                    "description": "CustomFields holds the values of admin defined custom fields, see\nCustomFieldDefinition.",
+                    "type": "object"
+                },
+                "customer_number": {
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                }
+            }
+        },
+        "domain.CustomerAddress": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "country": {
+                    "type": "string",
+                    "example": "ID"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "customer_number": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_default": {
+                    "type": "boolean"
+                },
+                "line1": {
+                    "type": "string"
+                },
+                "line2": {
+                    "type": "string"
+                },
+                "postal_code": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "billing",
+                        "shipping"
+                    ],
+                    "example": "shipping"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                }
+            }
+        },
+        "domain.CustomerContactPoint": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "customer_number": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_primary": {
+                    "type": "boolean"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "work"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "email",
+                        "phone"
+                    ],
+                    "example": "email"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "value": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "verified_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                }
+            }
+        },
+        "domain.CustomerMerge": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "merged_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "merged_customer": {
+                    "type": "object"
+                },
+                "merged_customer_number": {
+                    "type": "integer"
+                },
+                "survivor_customer_number": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.CustomerMergeRequest": {
+            "type": "object",
+            "properties": {
+                "merged_customer_number": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "survivor_customer_number": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "domain.CustomerNote": {
+            "type": "object",
+            "properties": {
+                "assignee": {
+                    "type": "string",
+                    "example": "bob.agent"
+                },
+                "author": {
+                    "type": "string",
+                    "example": "jane.agent"
+                },
+                "category": {
+                    "type": "string",
+                    "enum": [
+                        "call",
+                        "email",
+                        "complaint",
+                        "meeting",
+                        "other"
+                    ],
+                    "example": "call"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "customer_number": {
+                    "type": "integer"
+                },
+                "due_at": {
+                    "type": "string",
+                    "example": "2024-06-11T09:00:00Z"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "mentions": {
+                    "description": "Mentions are the @user names found in the note text.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "bob.agent"
+                    ]
+                },
+                "note": {
+                    "type": "string"
+                },
+                "pinned": {
+                    "description": "Pinned is a pointer so an update without it keeps the current value.",
+                    "type": "boolean"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "open",
+                        "done"
+                    ],
+                    "example": "open"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "updated_by": {
+                    "type": "string",
+                    "example": "jane.agent"
+                },
+                "visibility": {
+                    "type": "string",
+                    "enum": [
+                        "internal",
+                        "private"
+                    ],
+                    "example": "internal"
+                }
+            }
+        },
+        "domain.CustomerNoteRevision": {
+            "type": "object",
+            "properties": {
+                "assignee": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "note",
+                        "category"
+                    ]
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "diff": {
+                    "type": "string",
+                    "example": "-about billing\n+about shipping\n"
+                },
+                "due_at": {
+                    "type": "string",
+                    "example": "2024-06-11T09:00:00Z"
+                },
+                "edited_by": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "pinned": {
+                    "type": "boolean"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "visibility": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.DataSubjectExport": {
+            "type": "object",
+            "properties": {
+                "addresses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.CustomerAddress"
+                    }
+                },
+                "contact_points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.CustomerContactPoint"
+                    }
+                },
+                "customer": {
+                    "$ref": "#/definitions/domain.Customer"
+                },
+                "erasure_requests": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ErasureRequest"
+                    }
+                },
+                "exported_at": {
+                    "type": "string"
+                },
+                "exported_by": {
+                    "type": "string",
+                    "example": "jane.agent"
+                },
+                "merges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.CustomerMerge"
+                    }
+                },
+                "notes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DataSubjectNote"
+                    }
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Tag"
+                    }
+                }
+            }
+        },
+        "domain.DataSubjectNote": {
+            "type": "object",
             "properties": {
+                "assignee": {
+                    "type": "string",
+                    "example": "bob.agent"
+                },
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.NoteAttachment"
+                    }
+                },
+                "author": {
+                    "type": "string",
+                    "example": "jane.agent"
+                },
+                "category": {
+                    "type": "string",
+                    "enum": [
+                        "call",
+                        "email",
+                        "complaint",
+                        "meeting",
+                        "other"
+                    ],
+                    "example": "call"
+                },
                 "created_at": {
-                    "$ref": "#/definitions/types.NullTime"
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
                 },
                 "customer_number": {
                     "type": "integer"
                 },
+                "due_at": {
+                    "type": "string",
+                    "example": "2024-06-11T09:00:00Z"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "mentions": {
+                    "description": "Mentions are the @user names found in the note text.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "bob.agent"
+                    ]
+                },
                 "note": {
                     "type": "string"
+                },
+                "pinned": {
+                    "description": "Pinned is a pointer so an update without it keeps the current value.",
+                    "type": "boolean"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "revisions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.CustomerNoteRevision"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "open",
+                        "done"
+                    ],
+                    "example": "open"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "updated_by": {
+                    "type": "string",
+                    "example": "jane.agent"
+                },
+                "visibility": {
+                    "type": "string",
+                    "enum": [
+                        "internal",
+                        "private"
+                    ],
+                    "example": "internal"
+                }
+            }
+        },
+        "domain.DuplicateCandidate": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "customer_a": {
+                    "$ref": "#/definitions/domain.Customer"
+                },
+                "customer_b": {
+                    "$ref": "#/definitions/domain.Customer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reasons": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "email",
+                        "name 0.97"
+                    ]
+                },
+                "reviewed_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "score": {
+                    "type": "number",
+                    "example": 0.9
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                }
+            }
+        },
+        "domain.DuplicateScanResult": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "type": "integer"
+                },
+                "scanned": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.ErasureCertificate": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string",
+                    "example": "Ed25519"
+                },
+                "key_id": {
+                    "type": "string",
+                    "example": "5c1f0e3a9b7d2468"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "signature": {
+                    "type": "string",
+                    "example": "base64 signature of payload"
+                }
+            }
+        },
+        "domain.ErasureRequest": {
+            "type": "object",
+            "properties": {
+                "certificate": {
+                    "$ref": "#/definitions/domain.ErasureCertificate"
+                },
+                "customer_number": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Art. 17 request received by mail"
+                },
+                "requested_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "requested_by": {
+                    "type": "string",
+                    "example": "jane.agent"
+                },
+                "reviewed_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "reviewed_by": {
+                    "type": "string",
+                    "example": "bob.agent"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "rejected",
+                        "completed"
+                    ],
+                    "example": "pending"
                 }
             }
         },
@@ -487,6 +3539,40 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.NoteAttachment": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string",
+                    "example": "application/pdf"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "file_name": {
+                    "type": "string",
+                    "example": "invoice.pdf"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "note_id": {
+                    "type": "integer"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 48213
+                },
+                "uploaded_by": {
+                    "type": "string",
+                    "example": "jane.agent"
+                }
+            }
+        },
         "domain.Response": {
             "type": "object",
             "properties": {
@@ -498,14 +3584,125 @@ const docTemplate = `{
                 }
             }
         },
-        "types.NullTime": {
+        "domain.Segment": {
             "type": "object",
             "properties": {
-                "time": {
-                    "type": "string"
+                "created_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
                 },
-                "valid": {
-                    "type": "boolean"
+                "definition": {
+                    "type": "string",
+                    "example": "tag = vip AND birth_date IN THIS MONTH"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "vip-birthdays"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                }
+            }
+        },
+        "domain.SegmentCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SegmentDefinition": {
+            "type": "object",
+            "properties": {
+                "definition": {
+                    "type": "string",
+                    "example": "tag = vip AND birth_date IN THIS MONTH"
+                }
+            }
+        },
+        "domain.SegmentMembers": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "customers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Customer"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SigningKey": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string",
+                    "example": "Ed25519"
+                },
+                "key_id": {
+                    "type": "string",
+                    "example": "5c1f0e3a9b7d2468"
+                },
+                "public_key": {
+                    "type": "string",
+                    "example": "base64 public key"
+                }
+            }
+        },
+        "domain.Tag": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "1995-06-12T00:00:00Z"
+                },
+                "customer_count": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "vip"
+                }
+            }
+        },
+        "domain.TagAssignment": {
+            "type": "object",
+            "properties": {
+                "customer_numbers": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        1,
+                        2
+                    ]
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "vip",
+                        "churn-risk"
+                    ]
                 }
             }
         }
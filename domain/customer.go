@@ -3,21 +3,33 @@ package domain
 import (
 	"context"
 	"customer-playground/types"
+	"fmt"
 )
 
 type Customer struct {
-	CustomerNumber int            `json:"customer_number"`
-	Name           string         `json:"name"`
-	Email          string         `json:"email"`
-	Phone          string         `json:"phone,omitempty"`
-	BirthDate      types.NullTime `json:"birth_date,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
-	CreatedAt      types.NullTime `json:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
-	UpdatedAt      types.NullTime `json:"updated_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	CustomerNumber int            `json:"customer_number" xml:"customer_number"`
+	Name           string         `json:"name" xml:"name"`
+	Email          string         `json:"email" xml:"email"`
+	Phone          string         `json:"phone,omitempty" xml:"phone,omitempty"`
+	BirthDate      types.NullTime `json:"birth_date,omitempty" xml:"birth_date,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	CreatedAt      types.NullTime `json:"created_at,omitempty" xml:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	UpdatedAt      types.NullTime `json:"updated_at,omitempty" xml:"updated_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	// CustomFields holds the values of admin defined custom fields, see
+	// CustomFieldDefinition.
+	CustomFields types.JSONMap `json:"custom_fields,omitempty" xml:"custom_fields,omitempty" swaggertype:"object"`
+}
+
+// CustomerFilter narrows customer listings. Customers match when every entry
+// of CustomFields equals their custom field value and, when set, their email
+// equals Email regardless of case.
+type CustomerFilter struct {
+	CustomFields types.JSONMap
+	Email        string
 }
 
 type (
 	CustomerUseCase interface {
-		GetAll(ctx context.Context) ([]Customer, error)
+		GetAll(filter CustomerFilter, ctx context.Context) ([]Customer, error)
 		GetByCustomerNumber(customerNumber int, ctx context.Context) (Customer, error)
 		Insert(customer *Customer, ctx context.Context) (Response, error)
 		Update(customer *Customer, ctx context.Context) (Response, error)
@@ -25,10 +37,36 @@ type (
 	}
 
 	CustomerRepository interface {
-		GetAll(ctx context.Context) ([]Customer, error)
+		GetAll(filter CustomerFilter, ctx context.Context) ([]Customer, error)
 		GetByCustomerNumber(customerNumber int, ctx context.Context) (Customer, error)
 		Insert(customer *Customer, ctx context.Context) (Response, error)
 		Update(customer *Customer, ctx context.Context) (Response, error)
 		DeleteByCustomerNumber(customerNumber int, ctx context.Context) (Response, error)
+		// GetRedirect returns the customer number a merged customer number
+		// now points to, or sql.ErrNoRows when it was never merged.
+		GetRedirect(customerNumber int, ctx context.Context) (int, error)
 	}
 )
+
+// CustomerMovedError is returned when a customer was merged into another one.
+type CustomerMovedError struct {
+	CustomerNumber int
+	MovedTo        int
+}
+
+func (e *CustomerMovedError) Error() string {
+	return fmt.Sprintf("customer %d was merged into customer %d", e.CustomerNumber, e.MovedTo)
+}
+
+// CustomerInvalidator is implemented by customer repositories that keep
+// copies of customers, so changes made outside the repository can drop them.
+type CustomerInvalidator interface {
+	Invalidate(customerNumber int, ctx context.Context)
+}
+
+// ReEncrypter is implemented by repositories storing encrypted fields.
+// ReEncrypt rewrites up to batchSize rows not sealed with the current key and
+// fields and returns how many it rewrote.
+type ReEncrypter interface {
+	ReEncrypt(batchSize int, ctx context.Context) (int, error)
+}
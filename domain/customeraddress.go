@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"customer-playground/types"
+)
+
+const (
+	AddressTypeBilling  = "billing"
+	AddressTypeShipping = "shipping"
+)
+
+type CustomerAddress struct {
+	ID             int            `json:"id"`
+	CustomerNumber int            `json:"customer_number"`
+	Type           string         `json:"type" enums:"billing,shipping" example:"shipping"`
+	IsDefault      bool           `json:"is_default"`
+	Line1          string         `json:"line1"`
+	Line2          string         `json:"line2,omitempty"`
+	City           string         `json:"city"`
+	State          string         `json:"state,omitempty"`
+	PostalCode     string         `json:"postal_code"`
+	Country        string         `json:"country" example:"ID"`
+	CreatedAt      types.NullTime `json:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	UpdatedAt      types.NullTime `json:"updated_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+}
+
+type (
+	CustomerAddressUseCase interface {
+		GetByCustomerNumber(customerNumber int, ctx context.Context) ([]CustomerAddress, error)
+		GetById(customerNumber int, id int, ctx context.Context) (CustomerAddress, error)
+		Insert(customerAddress *CustomerAddress, ctx context.Context) (Response, error)
+		Update(customerAddress *CustomerAddress, ctx context.Context) (Response, error)
+		DeleteById(customerNumber int, id int, ctx context.Context) (Response, error)
+	}
+	CustomerAddressRepository interface {
+		GetByCustomerNumber(customerNumber int, ctx context.Context) ([]CustomerAddress, error)
+		GetById(customerNumber int, id int, ctx context.Context) (CustomerAddress, error)
+		Insert(customerAddress *CustomerAddress, ctx context.Context) (Response, error)
+		Update(customerAddress *CustomerAddress, ctx context.Context) (Response, error)
+		DeleteById(customerNumber int, id int, ctx context.Context) (Response, error)
+	}
+)
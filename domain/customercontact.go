@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"context"
+	"customer-playground/types"
+)
+
+const (
+	ContactTypeEmail = "email"
+	ContactTypePhone = "phone"
+)
+
+// CustomerContactPoint is one email address or phone number of a customer.
+// The primary contact point of each type is mirrored into Customer.Email and
+// Customer.Phone.
+type CustomerContactPoint struct {
+	ID             int            `json:"id"`
+	CustomerNumber int            `json:"customer_number"`
+	Type           string         `json:"type" enums:"email,phone" example:"email"`
+	Label          string         `json:"label,omitempty" example:"work"`
+	Value          string         `json:"value" example:"john.doe@example.com"`
+	IsPrimary      bool           `json:"is_primary"`
+	VerifiedAt     types.NullTime `json:"verified_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	CreatedAt      types.NullTime `json:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	UpdatedAt      types.NullTime `json:"updated_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+}
+
+// ContactVerification is a pending one-time code for a contact point. Only
+// the hash of the code is stored.
+type ContactVerification struct {
+	ContactPointID int
+	CodeHash       string
+	Attempts       int
+	ExpiresAt      types.NullTime
+}
+
+type ContactVerificationCode struct {
+	Code string `json:"code" example:"123456"`
+}
+
+type (
+	CustomerContactPointUseCase interface {
+		GetByCustomerNumber(customerNumber int, ctx context.Context) ([]CustomerContactPoint, error)
+		GetById(customerNumber int, id int, ctx context.Context) (CustomerContactPoint, error)
+		Insert(contactPoint *CustomerContactPoint, ctx context.Context) (Response, error)
+		Update(contactPoint *CustomerContactPoint, ctx context.Context) (Response, error)
+		DeleteById(customerNumber int, id int, ctx context.Context) (Response, error)
+		StartVerification(customerNumber int, id int, ctx context.Context) (Response, error)
+		ConfirmVerification(customerNumber int, id int, code string, ctx context.Context) (Response, error)
+	}
+	CustomerContactPointRepository interface {
+		GetByCustomerNumber(customerNumber int, ctx context.Context) ([]CustomerContactPoint, error)
+		GetById(customerNumber int, id int, ctx context.Context) (CustomerContactPoint, error)
+		Insert(contactPoint *CustomerContactPoint, ctx context.Context) (Response, error)
+		Update(contactPoint *CustomerContactPoint, ctx context.Context) (Response, error)
+		DeleteById(customerNumber int, id int, ctx context.Context) (Response, error)
+		SaveVerification(verification *ContactVerification, ctx context.Context) error
+		GetVerification(contactPointID int, ctx context.Context) (ContactVerification, error)
+		// AddVerificationAttempt counts an attempt at the pending code and
+		// returns the attempts made, or sql.ErrNoRows when maxAttempts were
+		// already made. Concurrent attempts never exceed maxAttempts.
+		AddVerificationAttempt(contactPointID int, maxAttempts int, ctx context.Context) (int, error)
+		// ConsumeVerification deletes the pending code and marks the contact
+		// point as verified.
+		ConsumeVerification(contactPointID int, ctx context.Context) error
+	}
+
+	// VerificationSender delivers one-time codes to a contact point.
+	VerificationSender interface {
+		Send(contactPoint CustomerContactPoint, code string, ctx context.Context) error
+	}
+)
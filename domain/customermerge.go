@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"customer-playground/types"
+)
+
+const (
+	DuplicateStatusPending   = "pending"
+	DuplicateStatusDismissed = "dismissed"
+)
+
+// DuplicateCandidate is a pair of customers the dedupe scan thinks are the
+// same person, waiting for review. CustomerA has the lower customer number.
+type DuplicateCandidate struct {
+	ID         int            `json:"id"`
+	CustomerA  Customer       `json:"customer_a"`
+	CustomerB  Customer       `json:"customer_b"`
+	Score      float64        `json:"score" example:"0.9"`
+	Reasons    []string       `json:"reasons" example:"email,name 0.97"`
+	Status     string         `json:"status" example:"pending"`
+	CreatedAt  types.NullTime `json:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	ReviewedAt types.NullTime `json:"reviewed_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+}
+
+type DuplicateScanResult struct {
+	Scanned    int `json:"scanned"`
+	Candidates int `json:"candidates"`
+}
+
+// CustomerMergeRequest merges MergedCustomerNumber into
+// SurvivorCustomerNumber. The merged customer is removed and its number
+// redirects to the survivor.
+type CustomerMergeRequest struct {
+	SurvivorCustomerNumber int `json:"survivor_customer_number" example:"1"`
+	MergedCustomerNumber   int `json:"merged_customer_number" example:"2"`
+}
+
+// CustomerMerge records a merge, with the merged customer as it was just
+// before it was removed.
+type CustomerMerge struct {
+	ID                     int            `json:"id"`
+	SurvivorCustomerNumber int            `json:"survivor_customer_number"`
+	MergedCustomerNumber   int            `json:"merged_customer_number"`
+	MergedCustomer         types.JSONMap  `json:"merged_customer" swaggertype:"object"`
+	MergedAt               types.NullTime `json:"merged_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+}
+
+type (
+	CustomerMergeUseCase interface {
+		Scan(ctx context.Context) (DuplicateScanResult, error)
+		GetCandidates(status string, ctx context.Context) ([]DuplicateCandidate, error)
+		Dismiss(id int, ctx context.Context) (Response, error)
+		MergeCandidate(id int, survivorCustomerNumber int, ctx context.Context) (Response, error)
+		Merge(request *CustomerMergeRequest, ctx context.Context) (Response, error)
+		GetHistory(customerNumber int, ctx context.Context) ([]CustomerMerge, error)
+	}
+	CustomerMergeRepository interface {
+		SaveCandidates(candidates []DuplicateCandidate, ctx context.Context) (int, error)
+		GetCandidates(status string, ctx context.Context) ([]DuplicateCandidate, error)
+		GetCandidateById(id int, ctx context.Context) (DuplicateCandidate, error)
+		SetCandidateStatus(id int, status string, ctx context.Context) (Response, error)
+		Merge(request *CustomerMergeRequest, ctx context.Context) (Response, error)
+		GetHistory(customerNumber int, ctx context.Context) ([]CustomerMerge, error)
+	}
+)
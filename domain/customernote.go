@@ -5,11 +5,85 @@ import (
 	"customer-playground/types"
 )
 
+const (
+	NoteCategoryCall      = "call"
+	NoteCategoryEmail     = "email"
+	NoteCategoryComplaint = "complaint"
+	NoteCategoryMeeting   = "meeting"
+	NoteCategoryOther     = "other"
+
+	// NoteVisibilityInternal notes are shown to every agent,
+	// NoteVisibilityPrivate notes only to their author.
+	NoteVisibilityInternal = "internal"
+	NoteVisibilityPrivate  = "private"
+
+	// A note with a due date or an assignee is a follow-up; it stays open
+	// until someone marks it done.
+	NoteStatusOpen = "open"
+	NoteStatusDone = "done"
+)
+
 type CustomerNote struct {
-	ID             int            `json:"id"`
-	CustomerNumber int            `json:"customer_number"`
-	Note           string         `json:"note"`
-	CreatedAt      types.NullTime `json:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	ID             int    `json:"id" xml:"id"`
+	CustomerNumber int    `json:"customer_number" xml:"customer_number"`
+	Note           string `json:"note" xml:"note"`
+	Author         string `json:"author" xml:"author" example:"jane.agent"`
+	Category       string `json:"category" xml:"category" example:"call" enums:"call,email,complaint,meeting,other"`
+	// Pinned is a pointer so an update without it keeps the current value.
+	Pinned     *bool          `json:"pinned,omitempty" xml:"pinned,omitempty"`
+	Visibility string         `json:"visibility" xml:"visibility" example:"internal" enums:"internal,private"`
+	DueAt      types.NullTime `json:"due_at,omitempty" xml:"due_at,omitempty" swaggertype:"string" example:"2024-06-11T09:00:00Z"`
+	Assignee   string         `json:"assignee,omitempty" xml:"assignee,omitempty" example:"bob.agent"`
+	Status     string         `json:"status,omitempty" xml:"status,omitempty" example:"open" enums:"open,done"`
+	// Mentions are the @user names found in the note text.
+	Mentions  []string       `json:"mentions,omitempty" xml:"mentions>mention,omitempty" example:"bob.agent"`
+	Revision  int            `json:"revision" xml:"revision"`
+	UpdatedBy string         `json:"updated_by,omitempty" xml:"updated_by,omitempty" example:"jane.agent"`
+	CreatedAt types.NullTime `json:"created_at,omitempty" xml:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	UpdatedAt types.NullTime `json:"updated_at,omitempty" xml:"updated_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+}
+
+// VisibleTo reports whether user may see the note: private notes are only
+// shown to their author.
+func (n CustomerNote) VisibleTo(user string) bool {
+	return n.Visibility != NoteVisibilityPrivate || (user != "" && n.Author == user)
+}
+
+// VisibleNotes returns the notes of customerNotes user may see, reusing its
+// backing array.
+func VisibleNotes(customerNotes []CustomerNote, user string) []CustomerNote {
+	visible := customerNotes[:0]
+	for _, customerNote := range customerNotes {
+		if customerNote.VisibleTo(user) {
+			visible = append(visible, customerNote)
+		}
+	}
+	return visible
+}
+
+// NoteReader returns the user private notes in ctx are shown to: the subject
+// of the verified bearer token. X-User alone does not count, any client can
+// send it.
+func NoteReader(ctx context.Context) string {
+	return SubjectFromContext(ctx)
+}
+
+// CustomerNoteRevision is one immutable version of a note. Diff shows the
+// note text changes against the previous revision and Changes names every
+// field that changed.
+type CustomerNoteRevision struct {
+	Revision   int            `json:"revision" xml:"revision"`
+	Note       string         `json:"note" xml:"note"`
+	Category   string         `json:"category" xml:"category"`
+	Pinned     bool           `json:"pinned" xml:"pinned"`
+	Visibility string         `json:"visibility" xml:"visibility"`
+	DueAt      types.NullTime `json:"due_at,omitempty" xml:"due_at,omitempty" swaggertype:"string" example:"2024-06-11T09:00:00Z"`
+	Assignee   string         `json:"assignee,omitempty" xml:"assignee,omitempty"`
+	Status     string         `json:"status,omitempty" xml:"status,omitempty"`
+	EditedBy   string         `json:"edited_by" xml:"edited_by"`
+	CreatedAt  types.NullTime `json:"created_at,omitempty" xml:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	Diff       string         `json:"diff,omitempty" xml:"diff,omitempty" example:"-about billing\n+about shipping\n"`
+	Changes    []string       `json:"changes,omitempty" xml:"changes>change,omitempty" example:"note,category"`
 }
 
 type (
@@ -17,6 +91,7 @@ type (
 		GetAll(ctx context.Context) ([]CustomerNote, error)
 		GetByCustomerNumber(customerNumber int, ctx context.Context) ([]CustomerNote, error)
 		GetById(id int, ctx context.Context) (CustomerNote, error)
+		GetRevisions(id int, ctx context.Context) ([]CustomerNoteRevision, error)
 		Insert(customerNote *CustomerNote, ctx context.Context) (Response, error)
 		Update(customerNote *CustomerNote, ctx context.Context) (Response, error)
 		DeleteById(id int, ctx context.Context) (Response, error)
@@ -25,6 +100,7 @@ type (
 		GetAll(ctx context.Context) ([]CustomerNote, error)
 		GetByCustomerNumber(customerNumber int, ctx context.Context) ([]CustomerNote, error)
 		GetById(id int, ctx context.Context) (CustomerNote, error)
+		GetRevisions(id int, ctx context.Context) ([]CustomerNoteRevision, error)
 		Insert(customerNote *CustomerNote, ctx context.Context) (Response, error)
 		Update(customerNote *CustomerNote, ctx context.Context) (Response, error)
 		DeleteById(id int, ctx context.Context) (Response, error)
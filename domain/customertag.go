@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"context"
+	"customer-playground/types"
+)
+
+type Tag struct {
+	ID            int            `json:"id"`
+	Name          string         `json:"name" example:"vip"`
+	CustomerCount int            `json:"customer_count"`
+	CreatedAt     types.NullTime `json:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+}
+
+// TagAssignment adds or removes every tag in Tags on every customer in
+// CustomerNumbers.
+type TagAssignment struct {
+	CustomerNumbers []int    `json:"customer_numbers" example:"1,2"`
+	Tags            []string `json:"tags" example:"vip,churn-risk"`
+}
+
+type (
+	TagUseCase interface {
+		GetAll(ctx context.Context) ([]Tag, error)
+		GetByCustomerNumber(customerNumber int, ctx context.Context) ([]Tag, error)
+		AddTags(assignment *TagAssignment, ctx context.Context) (Response, error)
+		RemoveTags(assignment *TagAssignment, ctx context.Context) (Response, error)
+	}
+	TagRepository interface {
+		GetAll(ctx context.Context) ([]Tag, error)
+		GetByCustomerNumber(customerNumber int, ctx context.Context) ([]Tag, error)
+		AddTags(assignment *TagAssignment, ctx context.Context) (Response, error)
+		RemoveTags(assignment *TagAssignment, ctx context.Context) (Response, error)
+	}
+)
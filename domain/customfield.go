@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"customer-playground/types"
+)
+
+const (
+	CustomFieldTypeString  = "string"
+	CustomFieldTypeInteger = "integer"
+	CustomFieldTypeNumber  = "number"
+	CustomFieldTypeBoolean = "boolean"
+	CustomFieldTypeDate    = "date"
+	CustomFieldTypeEnum    = "enum"
+)
+
+// CustomFieldDefinition describes an admin defined attribute kept in
+// Customer.CustomFields under Name.
+type CustomFieldDefinition struct {
+	ID          int            `json:"id"`
+	Name        string         `json:"name" example:"preferred_language"`
+	Label       string         `json:"label,omitempty" example:"Preferred language"`
+	Description string         `json:"description,omitempty"`
+	Type        string         `json:"type" enums:"string,integer,number,boolean,date,enum" example:"enum"`
+	Required    bool           `json:"required"`
+	EnumValues  []string       `json:"enum_values,omitempty" example:"en,id"`
+	Pattern     string         `json:"pattern,omitempty" example:"^[A-Z]{2}[0-9]{6}$"`
+	CreatedAt   types.NullTime `json:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	UpdatedAt   types.NullTime `json:"updated_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+}
+
+type (
+	CustomFieldUseCase interface {
+		GetAll(ctx context.Context) ([]CustomFieldDefinition, error)
+		GetById(id int, ctx context.Context) (CustomFieldDefinition, error)
+		Insert(definition *CustomFieldDefinition, ctx context.Context) (Response, error)
+		Update(definition *CustomFieldDefinition, ctx context.Context) (Response, error)
+		DeleteById(id int, ctx context.Context) (Response, error)
+	}
+	CustomFieldRepository interface {
+		GetAll(ctx context.Context) ([]CustomFieldDefinition, error)
+		GetById(id int, ctx context.Context) (CustomFieldDefinition, error)
+		Insert(definition *CustomFieldDefinition, ctx context.Context) (Response, error)
+		Update(definition *CustomFieldDefinition, ctx context.Context) (Response, error)
+		// DeleteById also removes the field from every customer.
+		DeleteById(id int, ctx context.Context) (Response, error)
+	}
+)
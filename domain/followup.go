@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	NotificationReminder = "reminder"
+	NotificationMention  = "mention"
+)
+
+// Notification tells Recipient that Note is due or mentions them.
+type Notification struct {
+	Kind      string       `json:"kind" enums:"reminder,mention"`
+	Recipient string       `json:"recipient" example:"bob.agent"`
+	Note      CustomerNote `json:"note"`
+}
+
+type (
+	// Notifier delivers notifications, e.g. to the log, by mail or to a
+	// webhook. A failed delivery is retried on the next run.
+	Notifier interface {
+		Notify(notification Notification, ctx context.Context) error
+	}
+	FollowUpUseCase interface {
+		// GetOpenByAssignee returns the open follow-ups assigned to user
+		// across all customers, the earliest due first.
+		GetOpenByAssignee(user string, ctx context.Context) ([]CustomerNote, error)
+		GetMentioning(user string, ctx context.Context) ([]CustomerNote, error)
+		// DispatchReminders notifies the assignees of open follow-ups that
+		// are due and returns how many reminders were sent.
+		DispatchReminders(ctx context.Context) (int, error)
+		// DispatchMentions notifies users of notes that newly mention them
+		// and returns how many mentions were handled.
+		DispatchMentions(ctx context.Context) (int, error)
+	}
+	FollowUpRepository interface {
+		GetOpenByAssignee(user string, ctx context.Context) ([]CustomerNote, error)
+		GetMentioning(user string, ctx context.Context) ([]CustomerNote, error)
+		// DispatchDue calls send for up to limit open follow-ups due at now
+		// that have no reminder yet, and records a reminder for every note
+		// send succeeds for. Other instances skip the notes meanwhile.
+		DispatchDue(now time.Time, limit int, send func(CustomerNote) error, ctx context.Context) (int, error)
+		// DispatchMentions does the same for up to limit mentions not yet
+		// notified.
+		DispatchMentions(limit int, send func(user string, note CustomerNote) error, ctx context.Context) (int, error)
+	}
+)
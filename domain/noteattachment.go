@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"context"
+	"customer-playground/types"
+	"io"
+)
+
+// NoteAttachment describes a file attached to a customer note. Files with
+// the same SHA256 are stored once, however often they are attached.
+type NoteAttachment struct {
+	ID          int            `json:"id"`
+	NoteID      int            `json:"note_id"`
+	FileName    string         `json:"file_name" example:"invoice.pdf"`
+	ContentType string         `json:"content_type" example:"application/pdf"`
+	Size        int64          `json:"size" example:"48213"`
+	SHA256      string         `json:"sha256"`
+	UploadedBy  string         `json:"uploaded_by" example:"jane.agent"`
+	CreatedAt   types.NullTime `json:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+}
+
+type (
+	NoteAttachmentUseCase interface {
+		GetByNoteId(noteId int, ctx context.Context) ([]NoteAttachment, error)
+		// Upload attaches content to a note. Size, ContentType and SHA256
+		// are derived from the content.
+		Upload(attachment *NoteAttachment, content []byte, ctx context.Context) (Response, error)
+		Download(noteId int, id int, ctx context.Context) (NoteAttachment, io.ReadCloser, error)
+		DeleteById(noteId int, id int, ctx context.Context) (Response, error)
+		// Cleanup removes stored files no attachment refers to anymore, such
+		// as those of deleted notes and customers.
+		Cleanup(ctx context.Context) (int, error)
+	}
+	NoteAttachmentRepository interface {
+		GetByNoteId(noteId int, ctx context.Context) ([]NoteAttachment, error)
+		GetById(id int, ctx context.Context) (NoteAttachment, error)
+		Insert(attachment *NoteAttachment, content []byte, ctx context.Context) (Response, error)
+		Open(sha256 string, ctx context.Context) (io.ReadCloser, error)
+		DeleteById(id int, ctx context.Context) (Response, error)
+		DeleteOrphanedBlobs(ctx context.Context) (int, error)
+	}
+)
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"context"
+	"customer-playground/types"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+const (
+	ErasureStatusPending   = "pending"
+	ErasureStatusRejected  = "rejected"
+	ErasureStatusCompleted = "completed"
+)
+
+// ErasedText replaces personal data removed by an erasure.
+const ErasedText = "[erased]"
+
+// ErasureRequest asks to erase the personal data of a customer. It is carried
+// out once someone other than the requester approves it.
+type ErasureRequest struct {
+	ID             int                 `json:"id"`
+	CustomerNumber int                 `json:"customer_number" example:"1"`
+	Reason         string              `json:"reason" example:"Art. 17 request received by mail"`
+	Status         string              `json:"status" example:"pending" enums:"pending,rejected,completed"`
+	RequestedBy    string              `json:"requested_by" example:"jane.agent"`
+	RequestedAt    types.NullTime      `json:"requested_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	ReviewedBy     string              `json:"reviewed_by,omitempty" example:"bob.agent"`
+	ReviewedAt     types.NullTime      `json:"reviewed_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	Certificate    *ErasureCertificate `json:"certificate,omitempty"`
+}
+
+// ErasureSummary counts what an erasure removed or redacted.
+type ErasureSummary struct {
+	NotesRedacted              int `json:"notes_redacted"`
+	RevisionsRedacted          int `json:"revisions_redacted"`
+	AttachmentsDeleted         int `json:"attachments_deleted"`
+	AddressesDeleted           int `json:"addresses_deleted"`
+	ContactPointsDeleted       int `json:"contact_points_deleted"`
+	MergeSnapshotsRedacted     int `json:"merge_snapshots_redacted"`
+	DuplicateCandidatesDeleted int `json:"duplicate_candidates_deleted"`
+}
+
+// ErasureCertificatePayload is what an erasure certificate attests.
+type ErasureCertificatePayload struct {
+	RequestID      int            `json:"request_id"`
+	Tenant         string         `json:"tenant" example:"default"`
+	CustomerNumber int            `json:"customer_number"`
+	Reason         string         `json:"reason"`
+	RequestedBy    string         `json:"requested_by"`
+	RequestedAt    time.Time      `json:"requested_at"`
+	ApprovedBy     string         `json:"approved_by"`
+	ErasedAt       time.Time      `json:"erased_at"`
+	Erased         ErasureSummary `json:"erased"`
+}
+
+// ErasureCertificate carries the payload exactly as it was signed, so the
+// signature can be checked against these bytes with the signing key.
+type ErasureCertificate struct {
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+	Algorithm string          `json:"algorithm" example:"Ed25519"`
+	KeyID     string          `json:"key_id" example:"5c1f0e3a9b7d2468"`
+	Signature string          `json:"signature" example:"base64 signature of payload"`
+}
+
+// SigningKey is the public half of the key erasure certificates are signed
+// with.
+type SigningKey struct {
+	Algorithm string `json:"algorithm" example:"Ed25519"`
+	KeyID     string `json:"key_id" example:"5c1f0e3a9b7d2468"`
+	PublicKey string `json:"public_key" example:"base64 public key"`
+}
+
+// DataSubjectNote is a note with its full history and attachments.
+type DataSubjectNote struct {
+	CustomerNote
+	Revisions   []CustomerNoteRevision `json:"revisions"`
+	Attachments []NoteAttachment       `json:"attachments"`
+}
+
+// DataSubjectExport is everything held about one customer, for a data
+// subject access request.
+type DataSubjectExport struct {
+	ExportedAt      time.Time              `json:"exported_at"`
+	ExportedBy      string                 `json:"exported_by" example:"jane.agent"`
+	Customer        Customer               `json:"customer"`
+	Addresses       []CustomerAddress      `json:"addresses"`
+	ContactPoints   []CustomerContactPoint `json:"contact_points"`
+	Tags            []Tag                  `json:"tags"`
+	Notes           []DataSubjectNote      `json:"notes"`
+	Merges          []CustomerMerge        `json:"merges"`
+	ErasureRequests []ErasureRequest       `json:"erasure_requests"`
+}
+
+type (
+	// CertificateSigner signs erasure certificates.
+	CertificateSigner interface {
+		Sign(payload ErasureCertificatePayload) (ErasureCertificate, error)
+		SigningKey() SigningKey
+	}
+	PrivacyUseCase interface {
+		// Export collects everything about a customer, private notes
+		// included.
+		Export(customerNumber int, ctx context.Context) (DataSubjectExport, error)
+		// WriteBundle writes export as a ZIP file together with the files
+		// attached to the notes.
+		WriteBundle(export DataSubjectExport, w io.Writer, ctx context.Context) error
+		RequestErasure(request *ErasureRequest, ctx context.Context) (Response, error)
+		GetErasureRequests(status string, ctx context.Context) ([]ErasureRequest, error)
+		GetErasureRequestById(id int, ctx context.Context) (ErasureRequest, error)
+		// ApproveErasure carries out a pending request and signs its
+		// certificate.
+		ApproveErasure(id int, ctx context.Context) (Response, error)
+		RejectErasure(id int, ctx context.Context) (Response, error)
+		SigningKey() SigningKey
+	}
+	PrivacyRepository interface {
+		InsertErasureRequest(request *ErasureRequest, ctx context.Context) (Response, error)
+		// GetErasureRequests filters by customer and status unless they are
+		// zero.
+		GetErasureRequests(customerNumber int, status string, ctx context.Context) ([]ErasureRequest, error)
+		GetErasureRequestById(id int, ctx context.Context) (ErasureRequest, error)
+		RejectErasureRequest(id int, reviewer string, ctx context.Context) (Response, error)
+		// Erase anonymises the customer of the pending request id, redacts
+		// its notes and stores the certificate sign returns, all in one
+		// transaction. It returns sql.ErrNoRows when the request is not
+		// pending.
+		Erase(id int, reviewer string, sign func(request ErasureRequest, summary ErasureSummary, erasedAt time.Time) (ErasureCertificate, error), ctx context.Context) (ErasureRequest, error)
+	}
+)
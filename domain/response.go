@@ -2,10 +2,10 @@ package domain
 
 type (
 	ErrorResponse struct {
-		Message string `json:"message"`
+		Message string `json:"message" xml:"message"`
 	}
 	Response struct {
-		Message    string `json:"message"`
-		StatusCode int    `json:"statuscode"`
+		Message    string `json:"message" xml:"message"`
+		StatusCode int    `json:"statuscode" xml:"statuscode"`
 	}
 )
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"customer-playground/types"
+)
+
+// Segment is a saved customer filter written in the segment definition
+// language, e.g. "tag = vip AND birth_date IN THIS MONTH".
+type Segment struct {
+	ID         int            `json:"id"`
+	Name       string         `json:"name" example:"vip-birthdays"`
+	Definition string         `json:"definition" example:"tag = vip AND birth_date IN THIS MONTH"`
+	CreatedAt  types.NullTime `json:"created_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+	UpdatedAt  types.NullTime `json:"updated_at,omitempty" swaggertype:"string" example:"1995-06-12T00:00:00Z"`
+}
+
+// SegmentDefinition is an unsaved definition evaluated ad hoc.
+type SegmentDefinition struct {
+	Definition string `json:"definition" example:"tag = vip AND birth_date IN THIS MONTH"`
+}
+
+type SegmentMembers struct {
+	Count     int        `json:"count"`
+	Limit     int        `json:"limit"`
+	Offset    int        `json:"offset"`
+	Customers []Customer `json:"customers"`
+}
+
+type SegmentCount struct {
+	Count int `json:"count"`
+}
+
+type (
+	SegmentUseCase interface {
+		GetAll(ctx context.Context) ([]Segment, error)
+		GetById(id int, ctx context.Context) (Segment, error)
+		Insert(segment *Segment, ctx context.Context) (Response, error)
+		Update(segment *Segment, ctx context.Context) (Response, error)
+		DeleteById(id int, ctx context.Context) (Response, error)
+		// Members evaluates a definition. The error is a
+		// *segment.SyntaxError when the definition is invalid.
+		Members(definition string, limit int, offset int, ctx context.Context) (SegmentMembers, error)
+		Count(definition string, ctx context.Context) (SegmentCount, error)
+	}
+	SegmentRepository interface {
+		GetAll(ctx context.Context) ([]Segment, error)
+		GetById(id int, ctx context.Context) (Segment, error)
+		Insert(segment *Segment, ctx context.Context) (Response, error)
+		Update(segment *Segment, ctx context.Context) (Response, error)
+		DeleteById(id int, ctx context.Context) (Response, error)
+		// Members and Count evaluate a compiled condition over the customer
+		// table aliased as c.
+		Members(where string, args []interface{}, limit int, offset int, ctx context.Context) ([]Customer, error)
+		Count(where string, args []interface{}, ctx context.Context) (int, error)
+	}
+)
@@ -0,0 +1,58 @@
+package domain
+
+import "context"
+
+// TenantHeader carries the slug of the tenant a request is for when it has
+// no token naming one.
+const TenantHeader = "X-Tenant"
+
+// Tenant is one brand hosted on the deployment. Every customer and the data
+// hanging off it belongs to exactly one tenant.
+type Tenant struct {
+	ID   int    `json:"id"`
+	Slug string `json:"slug" example:"acme"`
+	Name string `json:"name" example:"ACME Corp."`
+	// Overrides replace global settings for this tenant, keyed like the
+	// global setting, e.g. "dedupe.threshold".
+	Overrides map[string]interface{} `json:"-"`
+}
+
+// Float64 returns the tenant's override of key, or fallback.
+func (t Tenant) Float64(key string, fallback float64) float64 {
+	switch value := t.Overrides[key].(type) {
+	case float64:
+		return value
+	case int64:
+		return float64(value)
+	case int:
+		return float64(value)
+	}
+	return fallback
+}
+
+// Int64 returns the tenant's override of key, or fallback.
+func (t Tenant) Int64(key string, fallback int64) int64 {
+	switch value := t.Overrides[key].(type) {
+	case int64:
+		return value
+	case int:
+		return int64(value)
+	case float64:
+		return int64(value)
+	}
+	return fallback
+}
+
+type tenantKey struct{}
+
+// WithTenant records the tenant a request is for.
+func WithTenant(ctx context.Context, tenant Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the request, or the zero Tenant
+// without overrides when there is none.
+func TenantFromContext(ctx context.Context) Tenant {
+	tenant, _ := ctx.Value(tenantKey{}).(Tenant)
+	return tenant
+}
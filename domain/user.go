@@ -0,0 +1,63 @@
+package domain
+
+import "context"
+
+// UserHeader carries the name of the agent making a request.
+const UserHeader = "X-User"
+
+type userKey struct{}
+
+// WithUser records the agent making the request.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the agent making the request, or "" when unknown.
+func UserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+type subjectKey struct{}
+
+// WithSubject records the subject of the verified bearer token of the
+// request. Unlike the user, it cannot come from a header.
+func WithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// SubjectFromContext returns the subject of the verified bearer token, or ""
+// when the request has no token.
+func SubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(subjectKey{}).(string)
+	return subject
+}
+
+// PermissionReadPII lets the agent see personal data unmasked.
+const PermissionReadPII = "pii:read"
+
+// PermissionApproveErasure lets the agent approve or reject erasure requests.
+const PermissionApproveErasure = "privacy:approve"
+
+// PermissionReadMetrics lets the agent read the runtime metrics at
+// /debug/vars.
+const PermissionReadMetrics = "metrics:read"
+
+type permissionsKey struct{}
+
+// WithPermissions records the permissions of the agent making the request.
+func WithPermissions(ctx context.Context, permissions []string) context.Context {
+	return context.WithValue(ctx, permissionsKey{}, permissions)
+}
+
+// HasPermission reports whether the agent making the request holds
+// permission.
+func HasPermission(ctx context.Context, permission string) bool {
+	permissions, _ := ctx.Value(permissionsKey{}).([]string)
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
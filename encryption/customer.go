@@ -0,0 +1,100 @@
+package encryption
+
+import (
+	"context"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// The customer fields that can be encrypted. Contact points use the field of
+// their type, so values can be copied between them and the customer.
+const (
+	FieldEmail     = "email"
+	FieldPhone     = "phone"
+	FieldBirthDate = "birth_date"
+)
+
+// Fields lists every field that can be encrypted.
+var Fields = []string{FieldEmail, FieldPhone, FieldBirthDate}
+
+const dateLayout = "2006-01-02"
+
+// StoredCustomer holds the customer columns that may be encrypted, as they
+// are stored.
+type StoredCustomer struct {
+	Email      string
+	EmailIndex sql.NullString
+	Phone      sql.NullString
+	BirthDate  sql.NullString
+}
+
+// SealCustomer returns the stored form of the customer's fields.
+func (c *Cipher) SealCustomer(ctx context.Context, customer domain.Customer) (StoredCustomer, error) {
+	var stored StoredCustomer
+	var err error
+	if stored.Email, err = c.Seal(ctx, FieldEmail, customer.Email); err != nil {
+		return StoredCustomer{}, err
+	}
+	stored.EmailIndex = nullString(c.BlindIndex(FieldEmail, customer.Email))
+	phone, err := c.Seal(ctx, FieldPhone, customer.Phone)
+	if err != nil {
+		return StoredCustomer{}, err
+	}
+	stored.Phone = sql.NullString{String: phone, Valid: true}
+	if customer.BirthDate.Valid {
+		birthDate, err := c.Seal(ctx, FieldBirthDate, customer.BirthDate.Time.Format(dateLayout))
+		if err != nil {
+			return StoredCustomer{}, err
+		}
+		stored.BirthDate = nullString(birthDate)
+	}
+	return stored, nil
+}
+
+// OpenCustomer decrypts stored into customer.
+func (c *Cipher) OpenCustomer(ctx context.Context, stored StoredCustomer, customer *domain.Customer) error {
+	var err error
+	if customer.Email, err = c.Open(ctx, FieldEmail, stored.Email); err != nil {
+		return err
+	}
+	if customer.Phone, err = c.Open(ctx, FieldPhone, stored.Phone.String); err != nil {
+		return err
+	}
+	customer.BirthDate = types.NullTime{}
+	if stored.BirthDate.Valid {
+		birthDate, err := c.Open(ctx, FieldBirthDate, stored.BirthDate.String)
+		if err != nil {
+			return err
+		}
+		t, err := time.Parse(dateLayout, birthDate)
+		if err != nil {
+			return fmt.Errorf("encryption: invalid birth_date: %w", err)
+		}
+		customer.BirthDate = types.NullTime{Time: t, Valid: true}
+	}
+	return nil
+}
+
+// ResealCustomer opens stored and seals it again with the current key and
+// selected fields.
+func (c *Cipher) ResealCustomer(ctx context.Context, stored StoredCustomer) (StoredCustomer, error) {
+	var customer domain.Customer
+	if err := c.OpenCustomer(ctx, stored, &customer); err != nil {
+		return StoredCustomer{}, err
+	}
+	resealed, err := c.SealCustomer(ctx, customer)
+	if err != nil {
+		return StoredCustomer{}, err
+	}
+	if !stored.Phone.Valid && customer.Phone == "" {
+		resealed.Phone = stored.Phone
+	}
+	return resealed, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,53 @@
+package encryption
+
+import (
+	"context"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSealCustomer(t *testing.T) {
+	cipher, keyfile := newTestCipher(t, FieldEmail, FieldBirthDate)
+	ctx := context.Background()
+	customer := domain.Customer{
+		Email:     "John.Doe@example.com",
+		Phone:     "08123456789",
+		BirthDate: types.NullTime{Time: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC), Valid: true},
+	}
+
+	stored, err := cipher.SealCustomer(ctx, customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored.Email, "enc:v1:") || !strings.HasPrefix(stored.BirthDate.String, "enc:v1:") {
+		t.Errorf("SealCustomer() = %+v, want email and birth_date sealed", stored)
+	}
+	if stored.Phone.String != "08123456789" || stored.EmailIndex.String != cipher.BlindIndex(FieldEmail, "john.doe@example.com") {
+		t.Errorf("SealCustomer() = %+v", stored)
+	}
+
+	var opened domain.Customer
+	if err := cipher.OpenCustomer(ctx, stored, &opened); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opened, customer) {
+		t.Errorf("OpenCustomer() = %+v, want %+v", opened, customer)
+	}
+
+	// Once phone is encrypted and email is not, a reseal swaps them.
+	other, err := NewCipher(ctx, keyfile, []string{FieldPhone, FieldBirthDate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resealed, err := other.ResealCustomer(ctx, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resealed.Email != customer.Email || !strings.HasPrefix(resealed.Phone.String, "enc:v1:") || resealed.EmailIndex != stored.EmailIndex {
+		t.Errorf("ResealCustomer() = %+v", resealed)
+	}
+}
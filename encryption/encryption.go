@@ -0,0 +1,257 @@
+// Package encryption encrypts selected customer fields before they are
+// stored. Every value is sealed with AES-256-GCM under a data key, and the
+// data key is stored next to it wrapped by a key from a KeyProvider
+// (envelope encryption), so rotating that key only rewraps data keys.
+//
+// A sealed value looks like
+//
+//	enc:v1:<key id>:<wrapped data key>:<nonce and ciphertext>
+//
+// with both parts in unpadded base64url. Values without the prefix are
+// plaintext, e.g. written before a field was selected for encryption.
+package encryption
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// KeySize is the size of every key, AES-256 and HMAC-SHA256 alike.
+	KeySize = 32
+	// BatchSize is how many rows one re-encryption step rewrites.
+	BatchSize = 500
+
+	prefix = "enc:v1:"
+	// maxDataKeyUses bounds how many values share one data key, well below
+	// the limit of random GCM nonces.
+	maxDataKeyUses = 1 << 20
+	// maxOpenedKeys bounds the cache of unwrapped data keys.
+	maxOpenedKeys = 1024
+)
+
+var (
+	ErrUnknownKey = errors.New("encryption: unknown key")
+	ErrDecrypt    = errors.New("encryption: cannot decrypt value")
+)
+
+// KeyProvider holds the key encryption keys. It never hands them out; data
+// keys are sent to it for wrapping, as a KMS would require.
+type KeyProvider interface {
+	// CurrentKeyID names the key new data keys are wrapped with.
+	CurrentKeyID(ctx context.Context) (string, error)
+	WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+	// IndexKey is the HMAC key of blind indexes. Unlike the other keys it
+	// cannot rotate without recomputing every index.
+	IndexKey(ctx context.Context) ([]byte, error)
+}
+
+type dataKey struct {
+	keyID   string
+	key     []byte
+	wrapped string
+	uses    int
+}
+
+// Cipher seals the values of the selected fields. Field names are bound to
+// the ciphertext, so a value only opens as the field it was sealed for.
+type Cipher struct {
+	provider KeyProvider
+	fields   map[string]bool
+	indexKey []byte
+
+	mu      sync.Mutex
+	current *dataKey
+	opened  map[string][]byte
+}
+
+// NewCipher encrypts fields with keys from provider.
+func NewCipher(ctx context.Context, provider KeyProvider, fields []string) (*Cipher, error) {
+	indexKey, err := provider.IndexKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(indexKey) != KeySize {
+		return nil, fmt.Errorf("encryption: index key must be %d bytes, got %d", KeySize, len(indexKey))
+	}
+	c := &Cipher{provider: provider, fields: map[string]bool{}, indexKey: indexKey, opened: map[string][]byte{}}
+	for _, field := range fields {
+		if !slices.Contains(Fields, field) {
+			return nil, fmt.Errorf("encryption: field %q cannot be encrypted, choose from %v", field, Fields)
+		}
+		c.fields[field] = true
+	}
+	return c, nil
+}
+
+// Reload picks up keys added to the provider since, for providers that
+// support it.
+func (c *Cipher) Reload() error {
+	if reloader, ok := c.provider.(interface{ Reload() error }); ok {
+		return reloader.Reload()
+	}
+	return nil
+}
+
+// Encrypts reports whether values of field are sealed.
+func (c *Cipher) Encrypts(field string) bool {
+	return c.fields[field]
+}
+
+// Seal encrypts value when field is selected. Empty values stay empty.
+func (c *Cipher) Seal(ctx context.Context, field, value string) (string, error) {
+	if !c.fields[field] || value == "" {
+		return value, nil
+	}
+	key, err := c.dataKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key.key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(field))
+	return prefix + key.keyID + ":" + key.wrapped + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a sealed value and returns plaintext values as they are,
+// whether field is selected or not.
+func (c *Cipher) Open(ctx context.Context, field, value string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrDecrypt
+	}
+	key, err := c.unwrap(ctx, parts[0], parts[1])
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plaintext), nil
+}
+
+// Reseal opens value and seals it again with the current key, or leaves it
+// as plaintext when field is no longer selected.
+func (c *Cipher) Reseal(ctx context.Context, field, value string) (string, error) {
+	plaintext, err := c.Open(ctx, field, value)
+	if err != nil {
+		return "", err
+	}
+	return c.Seal(ctx, field, plaintext)
+}
+
+// CurrentPattern is a LIKE pattern matching the values sealed with the
+// current key. Values of selected fields not matching it need a Reseal.
+func (c *Cipher) CurrentPattern(ctx context.Context) (string, error) {
+	keyID, err := c.provider.CurrentKeyID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return prefix + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(keyID) + ":%", nil
+}
+
+// SealedPattern is a LIKE pattern matching every sealed value.
+func SealedPattern() string {
+	return prefix + "%"
+}
+
+// BlindIndex returns a keyed hash of value for equality lookups on a sealed
+// field. Values are compared case insensitively and without surrounding
+// space; empty values have no index.
+func (c *Cipher) BlindIndex(field, value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// dataKey returns the data key to seal with, making a new one when the
+// provider rotated or the current one was used often enough.
+func (c *Cipher) dataKey(ctx context.Context) (*dataKey, error) {
+	keyID, err := c.provider.CurrentKeyID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current != nil && c.current.keyID == keyID && c.current.uses < maxDataKeyUses {
+		c.current.uses++
+		return c.current, nil
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := c.provider.WrapKey(ctx, keyID, key)
+	if err != nil {
+		return nil, err
+	}
+	c.current = &dataKey{keyID: keyID, key: key, wrapped: base64.RawURLEncoding.EncodeToString(wrapped), uses: 1}
+	return c.current, nil
+}
+
+func (c *Cipher) unwrap(ctx context.Context, keyID, wrapped string) ([]byte, error) {
+	cacheKey := keyID + ":" + wrapped
+	c.mu.Lock()
+	key, ok := c.opened[cacheKey]
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	key, err = c.provider.UnwrapKey(ctx, keyID, decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if len(c.opened) >= maxOpenedKeys {
+		c.opened = map[string][]byte{}
+	}
+	c.opened[cacheKey] = key
+	c.mu.Unlock()
+	return key, nil
+}
@@ -0,0 +1,210 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCipher(t *testing.T, fields ...string) (*Cipher, *Keyfile) {
+	t.Helper()
+	keyfile, err := OpenKeyfile(filepath.Join(t.TempDir(), "keys", "keys.json"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := NewCipher(context.Background(), keyfile, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipher, keyfile
+}
+
+func TestSealAndOpen(t *testing.T) {
+	cipher, _ := newTestCipher(t, "email")
+	ctx := context.Background()
+
+	sealed, err := cipher.Seal(ctx, "email", "john.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v1:k1:") || strings.Contains(sealed, "john") {
+		t.Fatalf("Seal() = %q", sealed)
+	}
+	again, _ := cipher.Seal(ctx, "email", "john.doe@example.com")
+	if again == sealed {
+		t.Error("Seal() is deterministic")
+	}
+
+	opened, err := cipher.Open(ctx, "email", sealed)
+	if err != nil || opened != "john.doe@example.com" {
+		t.Errorf("Open() = %q, %v", opened, err)
+	}
+	if _, err := cipher.Open(ctx, "phone", sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() as another field = %v, want ErrDecrypt", err)
+	}
+	if opened, err := cipher.Open(ctx, "email", "plain@example.com"); err != nil || opened != "plain@example.com" {
+		t.Errorf("Open() of plaintext = %q, %v", opened, err)
+	}
+
+	for _, value := range []string{"", "0812"} {
+		field := "phone"
+		if value == "" {
+			field = "email"
+		}
+		if got, err := cipher.Seal(ctx, field, value); err != nil || got != value {
+			t.Errorf("Seal(%q, %q) = %q, %v, want it unchanged", field, value, got, err)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	cipher, keyfile := newTestCipher(t, "email")
+	ctx := context.Background()
+
+	old, err := cipher.Seal(ctx, "email", "john.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keyfile.Rotate("k2"); err != nil {
+		t.Fatal(err)
+	}
+	pattern, err := cipher.CurrentPattern(ctx)
+	if err != nil || pattern != "enc:v1:k2:%" {
+		t.Fatalf("CurrentPattern() = %q, %v", pattern, err)
+	}
+
+	resealed, err := cipher.Reseal(ctx, "email", old)
+	if err != nil || !strings.HasPrefix(resealed, "enc:v1:k2:") {
+		t.Fatalf("Reseal() = %q, %v", resealed, err)
+	}
+	for _, value := range []string{old, resealed} {
+		if opened, err := cipher.Open(ctx, "email", value); err != nil || opened != "john.doe@example.com" {
+			t.Errorf("Open() = %q, %v", opened, err)
+		}
+	}
+
+	// Another instance reading the rotated keyfile still opens old values.
+	reopened, err := OpenKeyfile(keyfile.path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewCipher(ctx, reopened, []string{"email"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := other.Open(ctx, "email", old); err != nil || opened != "john.doe@example.com" {
+		t.Errorf("Open() with the reloaded keyfile = %q, %v", opened, err)
+	}
+	if cipher.BlindIndex("email", "a@b.c") != other.BlindIndex("email", "a@b.c") {
+		t.Error("blind index changed with the rotation")
+	}
+}
+
+func TestResealDropsDeselectedFields(t *testing.T) {
+	cipher, keyfile := newTestCipher(t, "phone")
+	ctx := context.Background()
+	sealed, err := cipher.Seal(ctx, "phone", "08123456789")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := NewCipher(ctx, keyfile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := plain.Reseal(ctx, "phone", sealed); err != nil || got != "08123456789" {
+		t.Errorf("Reseal() = %q, %v, want plaintext", got, err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	cipher, _ := newTestCipher(t)
+
+	index := cipher.BlindIndex("email", "John.Doe@example.com ")
+	if len(index) != 64 || index != cipher.BlindIndex("email", "john.doe@example.com") {
+		t.Errorf("BlindIndex() = %q, want case and space insensitive", index)
+	}
+	if cipher.BlindIndex("phone", "john.doe@example.com") == index {
+		t.Error("BlindIndex() is the same for another field")
+	}
+	if cipher.BlindIndex("email", " ") != "" {
+		t.Error("BlindIndex() of an empty value is not empty")
+	}
+}
+
+func TestOpenKeyfileRejectsBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"missing current": `{"current": "k2", "keys": {"k1": "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="}, "index_key": "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="}`,
+		"short key":       `{"current": "k1", "keys": {"k1": "c2hvcnQ="}, "index_key": "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="}`,
+		"colon in id":     `{"current": "k:1", "keys": {"k:1": "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="}, "index_key": "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="}`,
+		"no index key":    `{"current": "k1", "keys": {"k1": "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="}}`,
+	}
+	for name, contents := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".json")
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenKeyfile(path, false); err == nil {
+			t.Errorf("%s: OpenKeyfile() succeeded", name)
+		}
+	}
+	if _, err := OpenKeyfile(filepath.Join(dir, "absent.json"), false); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenKeyfile() of a missing file = %v", err)
+	}
+}
+
+func TestRotateIfOlder(t *testing.T) {
+	_, keyfile := newTestCipher(t)
+	ctx := context.Background()
+
+	if keyID, err := keyfile.RotateIfOlder(time.Hour); err != nil || keyID != "" {
+		t.Fatalf("RotateIfOlder() of a new key = %q, %v", keyID, err)
+	}
+	keyID, err := keyfile.RotateIfOlder(0)
+	if err != nil || keyID != "k2" {
+		t.Fatalf("RotateIfOlder(0) = %q, %v, want k2", keyID, err)
+	}
+	if current, _ := keyfile.CurrentKeyID(ctx); current != "k2" {
+		t.Errorf("CurrentKeyID() = %q, want k2", current)
+	}
+	if err := keyfile.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if keyID, _ := keyfile.RotateIfOlder(time.Hour); keyID != "" {
+		t.Errorf("RotateIfOlder() after reload rotated to %q", keyID)
+	}
+}
+
+func TestRotateIfOlderSharedKeyfile(t *testing.T) {
+	_, keyfile := newTestCipher(t)
+	other, err := OpenKeyfile(keyfile.path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keyID, err := keyfile.RotateIfOlder(0); err != nil || keyID != "k2" {
+		t.Fatalf("RotateIfOlder(0) = %q, %v, want k2", keyID, err)
+	}
+	// other still holds k1 but reads the keyfile again before deciding.
+	if keyID, err := other.RotateIfOlder(time.Hour); err != nil || keyID != "" {
+		t.Errorf("RotateIfOlder() of the other instance = %q, %v, want no rotation", keyID, err)
+	}
+	if current, _ := other.CurrentKeyID(context.Background()); current != "k2" {
+		t.Errorf("CurrentKeyID() of the other instance = %q, want k2", current)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(keyfile.path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
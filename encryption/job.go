@@ -0,0 +1,31 @@
+package encryption
+
+import (
+	"context"
+	"customer-playground/domain"
+)
+
+// ReEncrypt returns a job that rewrites, batch by batch, whatever
+// repositories hold that is not sealed with the current key and fields. It
+// reloads the keys first, so a rotation made elsewhere is picked up.
+func ReEncrypt(cipher *Cipher, repositories ...domain.ReEncrypter) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		if err := cipher.Reload(); err != nil {
+			return 0, err
+		}
+		total := 0
+		for _, repository := range repositories {
+			for {
+				rewritten, err := repository.ReEncrypt(BatchSize, ctx)
+				total += rewritten
+				if err != nil {
+					return total, err
+				}
+				if rewritten < BatchSize {
+					break
+				}
+			}
+		}
+		return total, nil
+	}
+}
@@ -0,0 +1,298 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Keyfile is a KeyProvider for development that keeps its keys in a JSON
+// file:
+//
+//	{"current": "k1", "keys": {"k1": "<base64 32 bytes>"}, "index_key": "<base64 32 bytes>",
+//	 "created": {"k1": "2024-05-01T00:00:00Z"}}
+//
+// Data keys are wrapped with AES-256-GCM under the named key.
+type Keyfile struct {
+	path string
+
+	mu       sync.RWMutex
+	contents keyfileContents
+}
+
+type keyfileContents struct {
+	Current  string               `json:"current"`
+	Keys     map[string][]byte    `json:"keys"`
+	IndexKey []byte               `json:"index_key"`
+	Created  map[string]time.Time `json:"created,omitempty"`
+}
+
+func (c keyfileContents) validate() error {
+	if _, ok := c.Keys[c.Current]; !ok {
+		return fmt.Errorf("encryption: current key %q is not in the keyfile", c.Current)
+	}
+	for id, key := range c.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return fmt.Errorf("encryption: invalid key id %q", id)
+		}
+		if len(key) != KeySize {
+			return fmt.Errorf("encryption: key %q must be %d bytes, got %d", id, KeySize, len(key))
+		}
+	}
+	if len(c.IndexKey) != KeySize {
+		return fmt.Errorf("encryption: index key must be %d bytes, got %d", KeySize, len(c.IndexKey))
+	}
+	return nil
+}
+
+// OpenKeyfile reads the keyfile at path. A missing file is created with new
+// keys when create is set.
+func OpenKeyfile(path string, create bool) (*Keyfile, error) {
+	k := &Keyfile{path: path}
+	err := k.Reload()
+	if errors.Is(err, os.ErrNotExist) && create {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		return k, k.locked(func() error {
+			// Another instance may have created it in the meantime.
+			if err := k.Reload(); !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			indexKey, err := newKey()
+			if err != nil {
+				return err
+			}
+			k.contents = keyfileContents{Keys: map[string][]byte{}, IndexKey: indexKey}
+			return k.rotate("k1")
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload reads the keyfile again, e.g. after a key was added.
+func (k *Keyfile) Reload() error {
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return err
+	}
+	var contents keyfileContents
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return fmt.Errorf("encryption: read keyfile %s: %w", k.path, err)
+	}
+	if err := contents.validate(); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	k.contents = contents
+	k.mu.Unlock()
+	return nil
+}
+
+// Rotate adds a new key named keyID, makes it the current one and writes the
+// keyfile. Older keys stay so existing data can still be read.
+func (k *Keyfile) Rotate(keyID string) error {
+	return k.locked(func() error {
+		if err := k.Reload(); err != nil {
+			return err
+		}
+		return k.rotate(keyID)
+	})
+}
+
+// RotateIfOlder rotates to a new key named k<n> when the current key was
+// created more than maxAge ago, and returns its id. Keys added by hand
+// without a creation time are due at once. The keyfile is read again under
+// the lock, so instances sharing it rotate once between them.
+func (k *Keyfile) RotateIfOlder(maxAge time.Duration) (string, error) {
+	keyID := ""
+	err := k.locked(func() error {
+		if err := k.Reload(); err != nil {
+			return err
+		}
+		k.mu.RLock()
+		created, ok := k.contents.Created[k.contents.Current]
+		next := ""
+		for n := len(k.contents.Keys) + 1; next == ""; n++ {
+			if _, exists := k.contents.Keys[fmt.Sprintf("k%d", n)]; !exists {
+				next = fmt.Sprintf("k%d", n)
+			}
+		}
+		k.mu.RUnlock()
+		if ok && time.Since(created) < maxAge {
+			return nil
+		}
+		if err := k.rotate(next); err != nil {
+			return err
+		}
+		keyID = next
+		return nil
+	})
+	return keyID, err
+}
+
+// locked runs fn holding an exclusive lock on the keyfile, taken on a
+// separate .lock file as the keyfile itself is replaced by every write.
+func (k *Keyfile) locked(fn func() error) error {
+	lock, err := lockFile(k.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("encryption: lock keyfile %s: %w", k.path, err)
+	}
+	defer lock.Close()
+	return fn()
+}
+
+// rotate is Rotate for callers holding the keyfile lock.
+func (k *Keyfile) rotate(keyID string) error {
+	key, err := newKey()
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.contents.Keys[keyID]; ok {
+		return fmt.Errorf("encryption: key %q already exists", keyID)
+	}
+	contents := keyfileContents{
+		Current:  keyID,
+		Keys:     map[string][]byte{keyID: key},
+		IndexKey: k.contents.IndexKey,
+		Created:  map[string]time.Time{keyID: time.Now().UTC().Truncate(time.Second)},
+	}
+	for id, key := range k.contents.Keys {
+		contents.Keys[id] = key
+	}
+	for id, created := range k.contents.Created {
+		contents.Created[id] = created
+	}
+	if err := contents.validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(contents, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(k.path, data); err != nil {
+		return err
+	}
+	k.contents = contents
+	return nil
+}
+
+// writeFile replaces path with data through a synced temporary file, so a
+// crash leaves either the old or the new keyfile and never a truncated one.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func (k *Keyfile) CurrentKeyID(ctx context.Context) (string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.contents.Current, nil
+}
+
+func (k *Keyfile) WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error) {
+	aead, err := k.aead(keyID)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(keyID)), nil
+}
+
+// UnwrapKey reloads the keyfile once when keyID is unknown, as another
+// instance may have rotated to a key added since.
+func (k *Keyfile) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, err := k.aead(keyID)
+	if errors.Is(err, ErrUnknownKey) {
+		if reloadErr := k.Reload(); reloadErr != nil {
+			return nil, reloadErr
+		}
+		aead, err = k.aead(keyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	dataKey, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(keyID))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return dataKey, nil
+}
+
+func (k *Keyfile) IndexKey(ctx context.Context) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.contents.IndexKey, nil
+}
+
+func (k *Keyfile) aead(keyID string) (cipher.AEAD, error) {
+	k.mu.RLock()
+	key, ok := k.contents.Keys[keyID]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	return newAEAD(key)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func newKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package encryption
+
+import "os"
+
+// lockFile only opens path: there is no flock here, so instances sharing a
+// keyfile on this platform may rotate at the same time.
+func lockFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package encryption
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile opens path and takes an exclusive flock on it; closing the file
+// releases it.
+func lockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
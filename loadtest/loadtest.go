@@ -0,0 +1,273 @@
+// Package loadtest drives the HTTP API with a weighted mix of read and write
+// scenarios and reports the latency of each.
+//
+// Workers pick a scenario by weight, make its request and record how long it
+// took. Reads go to customers and notes the API already has, found with one
+// listing before the clock starts, and to the ones the run creates.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMix is mostly reads, like the traffic of the agents' UI.
+const DefaultMix = "get-customer=40,find-customer=10,list-notes=20,get-note=10,create-customer=5,update-customer=5,add-note=10"
+
+// Config describes a run. It ends after Duration or after Requests requests,
+// whichever comes first.
+type Config struct {
+	BaseURL     string
+	Client      *http.Client
+	Concurrency int
+	Duration    time.Duration
+	Requests    int
+	// Rate caps the requests per second of all workers together, 0 sends
+	// them as fast as the API answers.
+	Rate float64
+	// Mix weighs the scenarios by name, see ParseMix.
+	Mix map[string]int
+	// Header is sent with every request, e.g. X-Tenant and X-User.
+	Header http.Header
+	Seed   int64
+}
+
+// Stats sum up the requests of one scenario. Latencies are in milliseconds.
+type Stats struct {
+	Name     string      `json:"name"`
+	Requests int         `json:"requests"`
+	Errors   int         `json:"errors"`
+	Statuses map[int]int `json:"statuses"`
+	// LastError is the last failure, e.g. "429 Too Many Requests".
+	LastError  string  `json:"last_error,omitempty"`
+	Throughput float64 `json:"throughput"`
+	Mean       float64 `json:"mean_ms"`
+	P50        float64 `json:"p50_ms"`
+	P90        float64 `json:"p90_ms"`
+	P95        float64 `json:"p95_ms"`
+	P99        float64 `json:"p99_ms"`
+	Max        float64 `json:"max_ms"`
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	Seconds   float64 `json:"seconds"`
+	Scenarios []Stats `json:"scenarios"`
+	Total     Stats   `json:"total"`
+}
+
+// sample is one request.
+type sample struct {
+	scenario string
+	latency  time.Duration
+	status   int
+	err      error
+}
+
+// ParseMix reads weights like "get-customer=60,add-note=40".
+func ParseMix(s string) (map[string]int, error) {
+	mix := map[string]int{}
+	for _, pair := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("loadtest: %q is not scenario=weight", pair)
+		}
+		if _, ok := scenarios[name]; !ok {
+			return nil, fmt.Errorf("loadtest: unknown scenario %q, want one of %s", name, strings.Join(Scenarios(), ", "))
+		}
+		n, err := strconv.Atoi(weight)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("loadtest: weight of %s must be a number from 0", name)
+		}
+		mix[name] = n
+	}
+	return mix, nil
+}
+
+// Scenarios lists the names of the scenarios.
+func Scenarios() []string {
+	names := make([]string, 0, len(scenarios))
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run drives the API until the run is over or ctx is done.
+func Run(ctx context.Context, cfg Config) (Report, error) {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Duration <= 0 && cfg.Requests <= 0 {
+		return Report{}, errors.New("loadtest: set a duration or a number of requests")
+	}
+	picker, err := newPicker(cfg.Mix)
+	if err != nil {
+		return Report{}, err
+	}
+
+	api := &client{baseURL: strings.TrimRight(cfg.BaseURL, "/"), http: cfg.Client, header: cfg.Header}
+	state := newState(cfg.Seed)
+	if err := state.load(ctx, api); err != nil {
+		return Report{}, fmt.Errorf("loadtest: list customers: %w", err)
+	}
+	if len(state.customers) == 0 && cfg.Mix["create-customer"] == 0 {
+		return Report{}, errors.New("loadtest: the API has no customers to read, seed some or add create-customer to the mix")
+	}
+
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+	var ticks <-chan time.Time
+	if cfg.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.Rate))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	var issued int64
+	samples := make([][]sample, cfg.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(cfg.Seed + int64(i)))
+			for ctx.Err() == nil {
+				if cfg.Requests > 0 && atomic.AddInt64(&issued, 1) > int64(cfg.Requests) {
+					return
+				}
+				if ticks != nil {
+					select {
+					case <-ticks:
+					case <-ctx.Done():
+						return
+					}
+				}
+				name, run := picker.pick(rng)
+				begin := time.Now()
+				status, err := run(ctx, api, state, rng)
+				latency := time.Since(begin)
+				if err != nil && ctx.Err() != nil {
+					// Cut short by the end of the run.
+					return
+				}
+				samples[i] = append(samples[i], sample{scenario: name, latency: latency, status: status, err: err})
+			}
+		}(i)
+	}
+	wg.Wait()
+	return report(time.Since(start), samples), nil
+}
+
+func report(elapsed time.Duration, samples [][]sample) Report {
+	byScenario := map[string][]sample{}
+	var all []sample
+	for _, worker := range samples {
+		for _, s := range worker {
+			byScenario[s.scenario] = append(byScenario[s.scenario], s)
+		}
+		all = append(all, worker...)
+	}
+
+	report := Report{Seconds: elapsed.Seconds(), Total: stats("total", all, elapsed)}
+	for _, name := range Scenarios() {
+		if samples, ok := byScenario[name]; ok {
+			report.Scenarios = append(report.Scenarios, stats(name, samples, elapsed))
+		}
+	}
+	return report
+}
+
+func stats(name string, samples []sample, elapsed time.Duration) Stats {
+	stats := Stats{Name: name, Requests: len(samples), Statuses: map[int]int{}}
+	if len(samples) == 0 {
+		return stats
+	}
+	latencies := make([]time.Duration, 0, len(samples))
+	var sum time.Duration
+	for _, s := range samples {
+		latencies = append(latencies, s.latency)
+		sum += s.latency
+		if s.status != 0 {
+			stats.Statuses[s.status]++
+		}
+		if s.err != nil {
+			stats.Errors++
+			stats.LastError = s.err.Error()
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	stats.Throughput = float64(len(samples)) / elapsed.Seconds()
+	stats.Mean = milliseconds(sum / time.Duration(len(samples)))
+	stats.P50 = milliseconds(percentile(latencies, 50))
+	stats.P90 = milliseconds(percentile(latencies, 90))
+	stats.P95 = milliseconds(percentile(latencies, 95))
+	stats.P99 = milliseconds(percentile(latencies, 99))
+	stats.Max = milliseconds(latencies[len(latencies)-1])
+	return stats
+}
+
+// percentile returns the nearest rank percentile p of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// picker chooses scenarios by weight.
+type picker struct {
+	names   []string
+	weights []int
+	total   int
+}
+
+func newPicker(mix map[string]int) (*picker, error) {
+	p := &picker{}
+	for _, name := range Scenarios() {
+		if weight := mix[name]; weight > 0 {
+			p.names = append(p.names, name)
+			p.weights = append(p.weights, weight)
+			p.total += weight
+		}
+	}
+	if p.total == 0 {
+		return nil, errors.New("loadtest: the mix has no scenario with a weight")
+	}
+	return p, nil
+}
+
+func (p *picker) pick(rng *rand.Rand) (string, scenario) {
+	n := rng.Intn(p.total)
+	for i, weight := range p.weights {
+		if n < weight {
+			return p.names[i], scenarios[p.names[i]]
+		}
+		n -= weight
+	}
+	panic("unreachable")
+}
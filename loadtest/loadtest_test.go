@@ -0,0 +1,188 @@
+package loadtest
+
+import (
+	"context"
+	"customer-playground/domain"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAPI answers the routes the scenarios use, keeping customers and notes
+// in memory. Updates are rejected to have some errors to count.
+type fakeAPI struct {
+	mu        sync.Mutex
+	customers []domain.Customer
+	notes     []domain.CustomerNote
+	requests  map[string]int
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+	f.requests[r.Method+" "+strings.SplitN(path[1:], "/", 2)[0]]++
+	if r.Header.Get(domain.TenantHeader) != "acme" {
+		http.Error(w, "no tenant", http.StatusBadRequest)
+		return
+	}
+
+	segments := strings.Split(path[1:], "/")
+	switch {
+	case r.Method == http.MethodGet && path == "/customers":
+		json.NewEncoder(w).Encode(f.customers)
+	case r.Method == http.MethodGet && len(segments) == 2 && segments[0] == "customers":
+		json.NewEncoder(w).Encode(f.customers[0])
+	case r.Method == http.MethodPost && path == "/customers":
+		var customer domain.Customer
+		json.NewDecoder(r.Body).Decode(&customer)
+		customer.CustomerNumber = len(f.customers) + 1
+		f.customers = append(f.customers, customer)
+		json.NewEncoder(w).Encode(domain.Response{Message: fmt.Sprintf("Succes Insert Customer with number %d", customer.CustomerNumber), StatusCode: 200})
+	case r.Method == http.MethodPut && len(segments) == 2 && segments[0] == "customers":
+		http.Error(w, "read only", http.StatusTooManyRequests)
+	case r.Method == http.MethodGet && len(segments) == 3 && segments[2] == "notes":
+		customerNumber, _ := strconv.Atoi(segments[1])
+		notes := []domain.CustomerNote{}
+		for _, note := range f.notes {
+			if note.CustomerNumber == customerNumber {
+				notes = append(notes, note)
+			}
+		}
+		json.NewEncoder(w).Encode(notes)
+	case r.Method == http.MethodGet && len(segments) == 2 && segments[0] == "notes":
+		json.NewEncoder(w).Encode(f.notes[0])
+	case r.Method == http.MethodPost && len(segments) == 3 && segments[2] == "notes":
+		var note domain.CustomerNote
+		json.NewDecoder(r.Body).Decode(&note)
+		note.ID = len(f.notes) + 1
+		note.CustomerNumber, _ = strconv.Atoi(segments[1])
+		f.notes = append(f.notes, note)
+		json.NewEncoder(w).Encode(domain.Response{Message: fmt.Sprintf("Succes Insert Customer Note with id %d", note.ID), StatusCode: 200})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRun(t *testing.T) {
+	api := &fakeAPI{customers: []domain.Customer{{CustomerNumber: 1, Name: "John Doe"}}, requests: map[string]int{}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	mix, err := ParseMix("get-customer=3,find-customer=1,list-notes=1,get-note=1,create-customer=2,update-customer=1,add-note=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Run(context.Background(), Config{
+		BaseURL:     server.URL,
+		Concurrency: 4,
+		Requests:    300,
+		Mix:         mix,
+		Header:      http.Header{domain.TenantHeader: {"acme"}},
+		Seed:        1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Total.Requests != 300 || len(report.Scenarios) != 7 {
+		t.Fatalf("Run() = %d requests in %d scenarios", report.Total.Requests, len(report.Scenarios))
+	}
+	sum := 0
+	for _, stats := range report.Scenarios {
+		sum += stats.Requests
+		if stats.P50 > stats.P90 || stats.P90 > stats.P99 || stats.P99 > stats.Max || stats.Mean <= 0 {
+			t.Errorf("%s latencies = %+v", stats.Name, stats)
+		}
+		if stats.Name == "update-customer" {
+			if stats.Errors != stats.Requests || stats.Statuses[http.StatusTooManyRequests] != stats.Requests || stats.LastError != "429 Too Many Requests" {
+				t.Errorf("update-customer = %+v, want every request rejected", stats)
+			}
+		} else if stats.Errors != 0 {
+			t.Errorf("%s = %+v, want no errors", stats.Name, stats)
+		}
+	}
+	if sum != 300 {
+		t.Errorf("scenarios add up to %d requests", sum)
+	}
+	// The customers the run created were read as well.
+	if len(api.customers) < 20 || len(api.notes) < 20 {
+		t.Errorf("%d customers and %d notes created", len(api.customers), len(api.notes))
+	}
+	if api.requests["GET customers"] < report.Total.Requests/4 {
+		t.Errorf("requests = %v", api.requests)
+	}
+}
+
+func TestRunDuration(t *testing.T) {
+	api := &fakeAPI{customers: []domain.Customer{{CustomerNumber: 1}}, requests: map[string]int{}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	start := time.Now()
+	report, err := Run(context.Background(), Config{
+		BaseURL:  server.URL,
+		Duration: 200 * time.Millisecond,
+		Rate:     50,
+		Mix:      map[string]int{"get-customer": 1},
+		Header:   http.Header{domain.TenantHeader: {"acme"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %v", elapsed)
+	}
+	// 50 per second for 200ms, with some slack for slow machines.
+	if report.Total.Requests == 0 || report.Total.Requests > 12 {
+		t.Errorf("Run() made %d requests at 50 per second", report.Total.Requests)
+	}
+}
+
+func TestRunWithoutCustomers(t *testing.T) {
+	api := &fakeAPI{requests: map[string]int{}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	_, err := Run(context.Background(), Config{
+		BaseURL:  server.URL,
+		Requests: 10,
+		Mix:      map[string]int{"get-customer": 1},
+		Header:   http.Header{domain.TenantHeader: {"acme"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "no customers") {
+		t.Errorf("Run() = %v, want no customers reported", err)
+	}
+}
+
+func TestParseMix(t *testing.T) {
+	if _, err := ParseMix(DefaultMix); err != nil {
+		t.Errorf("ParseMix(DefaultMix) = %v", err)
+	}
+	for _, mix := range []string{"get-customer", "get-customer=x", "get-customer=-1", "delete-everything=1"} {
+		if _, err := ParseMix(mix); err == nil {
+			t.Errorf("ParseMix(%q) succeeded", mix)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[float64]time.Duration{50: 50 * time.Millisecond, 90: 90 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := percentile(latencies, p); got != want {
+			t.Errorf("percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+	if got := percentile(latencies[:1], 50); got != time.Millisecond {
+		t.Errorf("percentile() of one latency = %v", got)
+	}
+}
@@ -0,0 +1,282 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"customer-playground/datagen"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// scenario makes one request and returns its status.
+type scenario func(ctx context.Context, api *client, state *state, rng *rand.Rand) (int, error)
+
+var scenarios = map[string]scenario{
+	"get-customer":    getCustomer,
+	"find-customer":   findCustomer,
+	"list-customers":  listCustomers,
+	"list-notes":      listNotes,
+	"get-note":        getNote,
+	"create-customer": createCustomer,
+	"update-customer": updateCustomer,
+	"add-note":        addNote,
+}
+
+func getCustomer(ctx context.Context, api *client, state *state, rng *rand.Rand) (int, error) {
+	customerNumber, ok := state.customer(rng)
+	if !ok {
+		return 0, errNothingToRead
+	}
+	return api.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/customers/%d", customerNumber), nil, nil)
+}
+
+// findCustomer looks up customers the run created by email, the only ones
+// whose email it knows unmasked.
+func findCustomer(ctx context.Context, api *client, state *state, rng *rand.Rand) (int, error) {
+	email, ok := state.email(rng)
+	if !ok {
+		return getCustomer(ctx, api, state, rng)
+	}
+	return api.do(ctx, http.MethodGet, "/api/v1/customers?email="+url.QueryEscape(email), nil, nil)
+}
+
+func listCustomers(ctx context.Context, api *client, state *state, rng *rand.Rand) (int, error) {
+	return api.do(ctx, http.MethodGet, "/api/v1/customers", nil, nil)
+}
+
+func listNotes(ctx context.Context, api *client, state *state, rng *rand.Rand) (int, error) {
+	customerNumber, ok := state.customer(rng)
+	if !ok {
+		return 0, errNothingToRead
+	}
+	var notes []struct {
+		ID int `json:"id"`
+	}
+	status, err := api.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/customers/%d/notes", customerNumber), nil, &notes)
+	for _, note := range notes {
+		state.addNote(note.ID)
+	}
+	return status, err
+}
+
+// getNote reads notes seen in listings or added by the run, and lists the
+// notes of a customer until it knows some.
+func getNote(ctx context.Context, api *client, state *state, rng *rand.Rand) (int, error) {
+	id, ok := state.note(rng)
+	if !ok {
+		return listNotes(ctx, api, state, rng)
+	}
+	return api.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/notes/%d", id), nil, nil)
+}
+
+func createCustomer(ctx context.Context, api *client, state *state, rng *rand.Rand) (int, error) {
+	customer := state.newCustomer()
+	var message domain.Response
+	status, err := api.do(ctx, http.MethodPost, "/api/v1/customers", customer, &message)
+	if err == nil {
+		if customerNumber, ok := createdID(message); ok {
+			state.addCustomer(customerNumber, customer.Email)
+		}
+	}
+	return status, err
+}
+
+func updateCustomer(ctx context.Context, api *client, state *state, rng *rand.Rand) (int, error) {
+	customerNumber, ok := state.customer(rng)
+	if !ok {
+		return 0, errNothingToRead
+	}
+	update := domain.Customer{Name: state.newCustomer().Name}
+	return api.do(ctx, http.MethodPut, fmt.Sprintf("/api/v1/customers/%d", customerNumber), update, nil)
+}
+
+func addNote(ctx context.Context, api *client, state *state, rng *rand.Rand) (int, error) {
+	customerNumber, ok := state.customer(rng)
+	if !ok {
+		return 0, errNothingToRead
+	}
+	note := domain.CustomerNote{
+		Note:     fmt.Sprintf("Load test note %d", rng.Int()),
+		Category: domain.NoteCategoryOther,
+	}
+	var message domain.Response
+	status, err := api.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/customers/%d/notes", customerNumber), note, &message)
+	if err == nil {
+		if id, ok := createdID(message); ok {
+			state.addNote(id)
+		}
+	}
+	return status, err
+}
+
+var errNothingToRead = errors.New("no customer to read yet")
+
+// createdPattern finds the number in "Succes Insert Customer with number 7".
+var createdPattern = regexp.MustCompile(`(\d+)$`)
+
+func createdID(message domain.Response) (int, bool) {
+	match := createdPattern.FindStringSubmatch(message.Message)
+	if match == nil {
+		return 0, false
+	}
+	id, err := strconv.Atoi(match[1])
+	return id, err == nil
+}
+
+// state holds what the run knows about the data of the API.
+type state struct {
+	mu        sync.Mutex
+	customers []int
+	// emails of the customers the run created.
+	emails    []string
+	notes     []int
+	seen      map[int]bool
+	generator *datagen.Generator
+	// run tells the emails of this run from the ones of earlier runs.
+	run int64
+}
+
+func newState(seed int64) *state {
+	return &state{
+		seen:      map[int]bool{},
+		generator: datagen.New(datagen.Config{Seed: seed}),
+		run:       time.Now().Unix(),
+	}
+}
+
+// load lists the customers of the API.
+func (s *state) load(ctx context.Context, api *client) error {
+	var customers []struct {
+		CustomerNumber int `json:"customer_number"`
+	}
+	if _, err := api.do(ctx, http.MethodGet, "/api/v1/customers", nil, &customers); err != nil {
+		return err
+	}
+	for _, customer := range customers {
+		s.customers = append(s.customers, customer.CustomerNumber)
+	}
+	return nil
+}
+
+func (s *state) newCustomer() domain.Customer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	customer := s.generator.Customer()
+	customer.Email = fmt.Sprintf("loadtest.%d.%s", s.run, customer.Email)
+	// The API sets them to the time of the request.
+	customer.CreatedAt, customer.UpdatedAt = types.NullTime{}, types.NullTime{}
+	return customer
+}
+
+func (s *state) customer(rng *rand.Rand) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.customers) == 0 {
+		return 0, false
+	}
+	return s.customers[rng.Intn(len(s.customers))], true
+}
+
+func (s *state) email(rng *rand.Rand) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.emails) == 0 {
+		return "", false
+	}
+	return s.emails[rng.Intn(len(s.emails))], true
+}
+
+func (s *state) note(rng *rand.Rand) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.notes) == 0 {
+		return 0, false
+	}
+	return s.notes[rng.Intn(len(s.notes))], true
+}
+
+func (s *state) addCustomer(customerNumber int, email string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.customers = append(s.customers, customerNumber)
+	s.emails = append(s.emails, email)
+}
+
+func (s *state) addNote(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.seen[id] {
+		s.seen[id] = true
+		s.notes = append(s.notes, id)
+	}
+}
+
+// client makes JSON requests to the API.
+type client struct {
+	baseURL string
+	http    *http.Client
+	header  http.Header
+}
+
+// statusError is a response other than 2xx.
+type statusError struct {
+	Status string
+}
+
+func (e *statusError) Error() string {
+	return e.Status
+}
+
+// do sends body as JSON and decodes a successful response into out unless
+// it is nil. The body is always read, so the connection can be reused.
+func (c *client) do(ctx context.Context, method string, path string, body interface{}, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	for name, values := range c.header {
+		request.Header[name] = values
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, &statusError{Status: response.Status}
+	}
+	if out != nil {
+		if err := json.Unmarshal(content, out); err != nil {
+			return response.StatusCode, fmt.Errorf("decode %s %s: %w", method, path, err)
+		}
+	}
+	return response.StatusCode, nil
+}
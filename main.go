@@ -1,7 +1,12 @@
 package main
 
-import "customer-playground/app"
+import (
+	"customer-playground/app"
+	"os"
+)
 
 func main() {
-	app.Run()
+	if err := app.NewCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
 }
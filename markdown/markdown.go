@@ -0,0 +1,76 @@
+// Package markdown renders note text written in Markdown to sanitised HTML
+// and to plain text.
+package markdown
+
+import (
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+	"golang.org/x/net/html"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatText     = "text"
+)
+
+// Render returns source as HTML that is safe to embed in a page. HTML in
+// source is kept only as far as Sanitize allows. Quotes and dashes are left
+// as typed.
+func Render(source string) string {
+	renderer := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{Flags: blackfriday.UseXHTML})
+	rendered := blackfriday.Run([]byte(source),
+		blackfriday.WithExtensions(blackfriday.CommonExtensions),
+		blackfriday.WithRenderer(renderer),
+	)
+	return Sanitize(string(rendered))
+}
+
+// PlainText returns the text of source without Markdown syntax, one line
+// per block.
+func PlainText(source string) string {
+	var b strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(Render(source)))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			var lines []string
+			for _, line := range strings.Split(b.String(), "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					lines = append(lines, line)
+				}
+			}
+			return strings.Join(lines, "\n")
+		case html.TextToken:
+			b.Write(tokenizer.Text())
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			if name, _ := tokenizer.TagName(); blocks[string(name)] {
+				b.WriteString("\n")
+			}
+		}
+	}
+}
+
+// Format returns source in format, one of FormatMarkdown, FormatHTML or
+// FormatText. The source is the Markdown.
+func Format(source string, format string) string {
+	switch format {
+	case FormatHTML:
+		return Render(source)
+	case FormatText:
+		return PlainText(source)
+	default:
+		return source
+	}
+}
+
+// ValidFormat reports whether format is one Format knows.
+func ValidFormat(format string) bool {
+	return format == FormatMarkdown || format == FormatHTML || format == FormatText
+}
+
+var blocks = map[string]bool{
+	"p": true, "br": true, "hr": true, "li": true, "pre": true, "blockquote": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
@@ -0,0 +1,92 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := map[string]string{
+		"**bold** and _em_":           "<p><strong>bold</strong> and <em>em</em></p>\n",
+		"line one\\\nline two":        "<p>line one<br />\nline two</p>\n",
+		"[docs](https://example.com)": `<p><a href="https://example.com" rel="nofollow noopener noreferrer">docs</a></p>` + "\n",
+		"```go\nfmt.Println(1)\n```":  `<pre><code class="language-go">fmt.Println(1)` + "\n</code></pre>\n",
+		"- one\n- two":                "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n",
+		"call back <b>today</b>":      "<p>call back <b>today</b></p>\n",
+		"1 < 2 & \"quoted\"":          "<p>1 &lt; 2 &amp; &#34;quoted&#34;</p>\n",
+		"# Title":                     "<h1>Title</h1>\n",
+	}
+	for source, want := range tests {
+		if got := Render(source); got != want {
+			t.Errorf("Render(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestSanitizeRemovesScript(t *testing.T) {
+	attacks := []string{
+		`<script>alert(1)</script>`,
+		`<img src=x onerror=alert(1)>`,
+		`<a href="javascript:alert(1)">x</a>`,
+		`<a href="&#106;avascript:alert(1)">x</a>`,
+		`<a href="java&#09;script:alert(1)">x</a>`,
+		`<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`,
+		`<p onclick="alert(1)" style="x">x</p>`,
+		`<svg><script>alert(1)</script></svg>`,
+		`<iframe src="https://evil.example"></iframe>`,
+		`<scr<script>ipt>alert(1)</script>`,
+		`<style>*{background:url(javascript:alert(1))}</style>`,
+		`<a href="https://ok.example" onmouseover="alert(1)">x</a>`,
+		`<code class="x onload=alert(1)">x</code>`,
+		`<math><mi xlink:href="javascript:alert(1)">x</mi></math>`,
+		`<<script>script>alert(1)<</script>/script>`,
+	}
+	for _, attack := range attacks {
+		for _, got := range []string{Sanitize(attack), Render(attack)} {
+			lower := strings.ToLower(got)
+			for _, bad := range []string{"<script", "javascript:", "onerror", "onclick", "onmouseover", "onload", "<iframe", "<img", "<svg", "style=", "data:"} {
+				if strings.Contains(lower, bad) {
+					t.Errorf("Sanitize(%q) = %q contains %q", attack, got, bad)
+				}
+			}
+		}
+	}
+}
+
+func TestSanitizeBalancesTags(t *testing.T) {
+	tests := map[string]string{
+		"<p><strong>open":         "<p><strong>open</strong></p>",
+		"</em>stray":              "stray",
+		"<p><em>a</p>b":           "<p><em>a</em></p>b",
+		"<ol start=\"3\"><li>x":   `<ol start="3"><li>x</li></ol>`,
+		"<ol start=\"3;x\"><li>x": "<ol><li>x</li></ol>",
+	}
+	for in, want := range tests {
+		if got := Sanitize(in); got != want {
+			t.Errorf("Sanitize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	source := "# Call back\n\nCustomer asked about **invoice 42**.\n\n- check payment\n- <script>alert(1)</script>mail [Jane](mailto:jane@example.com)"
+	want := "Call back\nCustomer asked about invoice 42.\ncheck payment\nmail Jane"
+	if got := PlainText(source); got != want {
+		t.Errorf("PlainText() = %q, want %q", got, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format("*x*", FormatMarkdown); got != "*x*" {
+		t.Errorf("Format(markdown) = %q", got)
+	}
+	if got := Format("*x*", FormatText); got != "x" {
+		t.Errorf("Format(text) = %q", got)
+	}
+	if got := Format("*x*", FormatHTML); got != "<p><em>x</em></p>\n" {
+		t.Errorf("Format(html) = %q", got)
+	}
+	if ValidFormat("pdf") || !ValidFormat(FormatText) {
+		t.Error("ValidFormat() is wrong")
+	}
+}
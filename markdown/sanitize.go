@@ -0,0 +1,145 @@
+package markdown
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowedTags maps every tag that may appear in rendered notes to the
+// attributes it may keep. Everything else is removed, its text kept.
+var allowedTags = map[string]map[string]bool{
+	"p": {}, "br": {}, "hr": {},
+	"h1": {}, "h2": {}, "h3": {}, "h4": {}, "h5": {}, "h6": {},
+	"strong": {}, "b": {}, "em": {}, "i": {}, "del": {}, "s": {},
+	"code": {"class": true}, "pre": {}, "blockquote": {},
+	"ul": {}, "ol": {"start": true}, "li": {},
+	"dl": {}, "dt": {}, "dd": {},
+	"a":     {"href": true, "title": true},
+	"table": {}, "thead": {}, "tbody": {}, "tr": {},
+	"th": {"align": true}, "td": {"align": true},
+}
+
+// droppedTags are removed together with their content.
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "template": true, "textarea": true, "title": true, "svg": true, "math": true,
+}
+
+var voidTags = map[string]bool{"br": true, "hr": true}
+
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+var (
+	languageClass = regexp.MustCompile(`^language-[\w+-]+$`)
+	alignment     = regexp.MustCompile(`^(left|right|center)$`)
+	number        = regexp.MustCompile(`^[0-9]{1,9}$`)
+)
+
+// Sanitize keeps the allowlisted tags and attributes of fragment and escapes
+// everything else, so the result cannot run script in a browser. Unclosed
+// tags are closed at the end.
+func Sanitize(fragment string) string {
+	var b strings.Builder
+	var open []string
+	dropping, dropDepth := "", 0
+
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+
+		if dropping != "" {
+			switch {
+			case tokenType == html.StartTagToken && token.Data == dropping:
+				dropDepth++
+			case tokenType == html.EndTagToken && token.Data == dropping:
+				dropDepth--
+				if dropDepth == 0 {
+					dropping = ""
+				}
+			}
+			continue
+		}
+
+		switch tokenType {
+		case html.TextToken:
+			b.WriteString(html.EscapeString(token.Data))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[token.Data] {
+				if tokenType == html.StartTagToken {
+					dropping, dropDepth = token.Data, 1
+				}
+				continue
+			}
+			attributes, ok := allowedTags[token.Data]
+			if !ok {
+				continue
+			}
+			b.WriteString("<" + token.Data)
+			for _, attribute := range token.Attr {
+				if attribute.Namespace != "" || !attributes[attribute.Key] || !allowedValue(token.Data, attribute) {
+					continue
+				}
+				b.WriteString(" " + attribute.Key + `="` + html.EscapeString(attribute.Val) + `"`)
+			}
+			if token.Data == "a" {
+				b.WriteString(` rel="nofollow noopener noreferrer"`)
+			}
+			if voidTags[token.Data] {
+				b.WriteString(" />")
+				continue
+			}
+			b.WriteString(">")
+			if tokenType == html.SelfClosingTagToken {
+				b.WriteString("</" + token.Data + ">")
+				continue
+			}
+			open = append(open, token.Data)
+		case html.EndTagToken:
+			// Close up to the matching open tag; stray end tags are dropped.
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != token.Data {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+func allowedValue(tag string, attribute html.Attribute) bool {
+	switch attribute.Key {
+	case "href":
+		return safeURL(attribute.Val)
+	case "class":
+		return tag == "code" && languageClass.MatchString(attribute.Val)
+	case "align":
+		return alignment.MatchString(attribute.Val)
+	case "start":
+		return number.MatchString(attribute.Val)
+	}
+	return true
+}
+
+// safeURL allows absolute http, https and mailto links only.
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	return allowedSchemes[strings.ToLower(u.Scheme)]
+}
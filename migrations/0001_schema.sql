@@ -0,0 +1,228 @@
+CREATE TABLE customer (
+    customer_number SERIAL PRIMARY KEY,
+    name            VARCHAR(100) NOT NULL,
+    email           VARCHAR(100) NOT NULL UNIQUE,
+    phone           VARCHAR(20),
+    birth_date      DATE,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    custom_fields   JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX customer_custom_fields_idx ON customer USING GIN (custom_fields jsonb_path_ops);
+
+CREATE TABLE custom_field_definition (
+    id              SERIAL PRIMARY KEY,
+    name            VARCHAR(50) NOT NULL UNIQUE,
+    label           VARCHAR(100) NOT NULL DEFAULT '',
+    description     TEXT NOT NULL DEFAULT '',
+    type            VARCHAR(20) NOT NULL CHECK (type IN ('string', 'integer', 'number', 'boolean', 'date', 'enum')),
+    required        BOOLEAN NOT NULL DEFAULT FALSE,
+    enum_values     TEXT[],
+    pattern         TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE customer_note (
+    id              SERIAL PRIMARY KEY,
+    customer_number INTEGER NOT NULL REFERENCES customer(customer_number) ON DELETE CASCADE,
+    note            TEXT NOT NULL,
+    author          VARCHAR(100) NOT NULL DEFAULT '',
+    category        VARCHAR(20) NOT NULL DEFAULT 'other' CHECK (category IN ('call', 'email', 'complaint', 'meeting', 'other')),
+    pinned          BOOLEAN NOT NULL DEFAULT FALSE,
+    visibility      VARCHAR(20) NOT NULL DEFAULT 'internal' CHECK (visibility IN ('internal', 'private')),
+    due_at          TIMESTAMP,
+    assignee        VARCHAR(100) NOT NULL DEFAULT '',
+    status          VARCHAR(10) NOT NULL DEFAULT '' CHECK (status IN ('', 'open', 'done')),
+    reminded_at     TIMESTAMP,
+    revision        INTEGER NOT NULL DEFAULT 1,
+    updated_by      VARCHAR(100) NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX customer_note_customer_number_idx ON customer_note (customer_number);
+CREATE INDEX customer_note_assignee_idx ON customer_note (assignee, due_at) WHERE status = 'open';
+-- Follow-ups the scheduler still has to remind of.
+CREATE INDEX customer_note_due_idx ON customer_note (due_at) WHERE status = 'open' AND reminded_at IS NULL;
+
+CREATE TABLE customer_note_revision (
+    note_id         INTEGER NOT NULL REFERENCES customer_note(id) ON DELETE CASCADE,
+    revision        INTEGER NOT NULL,
+    note            TEXT NOT NULL,
+    category        VARCHAR(20) NOT NULL,
+    pinned          BOOLEAN NOT NULL,
+    visibility      VARCHAR(20) NOT NULL,
+    due_at          TIMESTAMP,
+    assignee        VARCHAR(100) NOT NULL DEFAULT '',
+    status          VARCHAR(10) NOT NULL DEFAULT '',
+    edited_by       VARCHAR(100) NOT NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (note_id, revision)
+);
+
+-- Revisions are history; they go away with their note but never change.
+CREATE FUNCTION customer_note_revision_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'customer_note_revision rows cannot be updated';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER customer_note_revision_immutable
+    BEFORE UPDATE ON customer_note_revision
+    FOR EACH ROW EXECUTE FUNCTION customer_note_revision_immutable();
+
+-- @user mentions in note text; notified_at is set once the user was told.
+CREATE TABLE customer_note_mention (
+    note_id         INTEGER NOT NULL REFERENCES customer_note(id) ON DELETE CASCADE,
+    username        VARCHAR(100) NOT NULL,
+    notified_at     TIMESTAMP,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (note_id, username)
+);
+
+CREATE INDEX customer_note_mention_username_idx ON customer_note_mention (username);
+CREATE INDEX customer_note_mention_pending_idx ON customer_note_mention (created_at) WHERE notified_at IS NULL;
+
+CREATE TABLE customer_address (
+    id              SERIAL PRIMARY KEY,
+    customer_number INTEGER NOT NULL REFERENCES customer(customer_number) ON DELETE CASCADE,
+    type            VARCHAR(20) NOT NULL CHECK (type IN ('billing', 'shipping')),
+    is_default      BOOLEAN NOT NULL DEFAULT FALSE,
+    line1           VARCHAR(200) NOT NULL,
+    line2           VARCHAR(200) NOT NULL DEFAULT '',
+    city            VARCHAR(100) NOT NULL,
+    state           VARCHAR(100) NOT NULL DEFAULT '',
+    postal_code     VARCHAR(20) NOT NULL,
+    country         CHAR(2) NOT NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX customer_address_customer_number_idx ON customer_address (customer_number);
+CREATE UNIQUE INDEX customer_address_default_idx ON customer_address (customer_number, type) WHERE is_default;
+
+CREATE TABLE customer_contact_point (
+    id              SERIAL PRIMARY KEY,
+    customer_number INTEGER NOT NULL REFERENCES customer(customer_number) ON DELETE CASCADE,
+    type            VARCHAR(20) NOT NULL CHECK (type IN ('email', 'phone')),
+    label           VARCHAR(50) NOT NULL DEFAULT '',
+    value           VARCHAR(100) NOT NULL,
+    is_primary      BOOLEAN NOT NULL DEFAULT FALSE,
+    verified_at     TIMESTAMP,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX customer_contact_point_customer_number_idx ON customer_contact_point (customer_number);
+CREATE UNIQUE INDEX customer_contact_point_primary_idx ON customer_contact_point (customer_number, type) WHERE is_primary;
+
+CREATE TABLE customer_contact_verification (
+    contact_point_id INTEGER PRIMARY KEY REFERENCES customer_contact_point(id) ON DELETE CASCADE,
+    code_hash        CHAR(64) NOT NULL,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    expires_at       TIMESTAMP NOT NULL
+);
+
+-- Attached files are stored once per SHA-256 in the blob store.
+CREATE TABLE note_attachment_blob (
+    sha256          CHAR(64) PRIMARY KEY,
+    size            BIGINT NOT NULL,
+    content_type    VARCHAR(100) NOT NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE customer_note_attachment (
+    id              SERIAL PRIMARY KEY,
+    note_id         INTEGER NOT NULL REFERENCES customer_note(id) ON DELETE CASCADE,
+    file_name       VARCHAR(255) NOT NULL,
+    content_type    VARCHAR(100) NOT NULL,
+    size            BIGINT NOT NULL,
+    sha256          CHAR(64) NOT NULL REFERENCES note_attachment_blob(sha256),
+    uploaded_by     VARCHAR(100) NOT NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (note_id, sha256)
+);
+
+CREATE INDEX customer_note_attachment_sha256_idx ON customer_note_attachment (sha256);
+
+CREATE TABLE tag (
+    id              SERIAL PRIMARY KEY,
+    name            VARCHAR(50) NOT NULL UNIQUE,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE customer_tag (
+    customer_number INTEGER NOT NULL REFERENCES customer(customer_number) ON DELETE CASCADE,
+    tag_id          INTEGER NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (customer_number, tag_id)
+);
+
+CREATE INDEX customer_tag_tag_id_idx ON customer_tag (tag_id);
+
+CREATE TABLE segment (
+    id              SERIAL PRIMARY KEY,
+    name            VARCHAR(100) NOT NULL UNIQUE,
+    definition      TEXT NOT NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE customer_duplicate_candidate (
+    id              SERIAL PRIMARY KEY,
+    customer_a      INTEGER NOT NULL REFERENCES customer(customer_number) ON DELETE CASCADE,
+    customer_b      INTEGER NOT NULL REFERENCES customer(customer_number) ON DELETE CASCADE,
+    score           DOUBLE PRECISION NOT NULL,
+    reasons         TEXT[] NOT NULL DEFAULT '{}',
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'dismissed')),
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at     TIMESTAMP,
+    UNIQUE (customer_a, customer_b),
+    CHECK (customer_a < customer_b)
+);
+
+CREATE INDEX customer_duplicate_candidate_customer_b_idx ON customer_duplicate_candidate (customer_b);
+
+-- Merged customers are removed, so the history keeps their numbers without a
+-- foreign key and a copy of the row as it was.
+CREATE TABLE customer_merge (
+    id                       SERIAL PRIMARY KEY,
+    survivor_customer_number INTEGER NOT NULL,
+    merged_customer_number   INTEGER NOT NULL,
+    merged_customer          JSONB NOT NULL,
+    merged_at                TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX customer_merge_survivor_idx ON customer_merge (survivor_customer_number);
+CREATE INDEX customer_merge_merged_idx ON customer_merge (merged_customer_number);
+
+CREATE TABLE customer_redirect (
+    old_customer_number INTEGER PRIMARY KEY,
+    customer_number     INTEGER NOT NULL REFERENCES customer(customer_number) ON DELETE CASCADE,
+    created_at          TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX customer_redirect_customer_number_idx ON customer_redirect (customer_number);
+
+CREATE TABLE rate_limit_bucket (
+    key             VARCHAR(200) PRIMARY KEY,
+    tokens          DOUBLE PRECISION NOT NULL,
+    allowed         BOOLEAN NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL
+);
+
+INSERT INTO customer (name, email, phone, birth_date)
+VALUES ('John Doe', 'john.doe@example.com', '08123456789', '1990-05-15');
+
+INSERT INTO customer_contact_point (customer_number, type, value, is_primary)
+SELECT customer_number, 'email', email, TRUE FROM customer
+UNION ALL
+SELECT customer_number, 'phone', phone, TRUE FROM customer WHERE COALESCE(phone, '') <> '';
+
+INSERT INTO customer_note (customer_number, note, author, category, updated_by)
+VALUES (1, 'Customer called about billing issue', 'support', 'call', 'support');
+
+INSERT INTO customer_note_revision (note_id, revision, note, category, pinned, visibility, edited_by, created_at)
+SELECT id, revision, note, category, pinned, visibility, author, created_at FROM customer_note;
@@ -0,0 +1,157 @@
+-- Every row belongs to a tenant. The application names the tenant of a
+-- statement in the app.tenant_id setting, which app_tenant() reads; row level
+-- security at the end of this file keeps each tenant to its own rows. Rows
+-- that exist already go to the default tenant.
+CREATE TABLE tenant (
+    id              SERIAL PRIMARY KEY,
+    slug            VARCHAR(50) NOT NULL UNIQUE,
+    name            VARCHAR(100) NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO tenant (slug, name) VALUES ('default', 'Default');
+
+CREATE FUNCTION app_tenant() RETURNS INTEGER AS $$
+    SELECT NULLIF(current_setting('app.tenant_id', true), '')::INTEGER
+$$ LANGUAGE sql STABLE;
+
+-- tenant_id is added with the default tenant for the existing rows, then
+-- defaults to the tenant of the statement.
+DO $$
+DECLARE
+    tenant_table TEXT;
+BEGIN
+    FOREACH tenant_table IN ARRAY ARRAY[
+        'customer', 'custom_field_definition', 'customer_note', 'customer_note_revision',
+        'customer_note_mention', 'customer_address', 'customer_contact_point',
+        'customer_contact_verification', 'note_attachment_blob', 'customer_note_attachment',
+        'tag', 'customer_tag', 'segment', 'customer_duplicate_candidate', 'customer_merge',
+        'customer_redirect'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenant(id)', tenant_table);
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN tenant_id SET DEFAULT app_tenant()', tenant_table);
+    END LOOP;
+END;
+$$;
+
+-- Names are unique per tenant, and rows refer to rows of their own tenant.
+ALTER TABLE customer
+    DROP CONSTRAINT customer_email_key,
+    ADD CONSTRAINT customer_tenant_id_email_key UNIQUE (tenant_id, email),
+    ADD CONSTRAINT customer_tenant_id_customer_number_key UNIQUE (tenant_id, customer_number);
+
+ALTER TABLE custom_field_definition
+    DROP CONSTRAINT custom_field_definition_name_key,
+    ADD CONSTRAINT custom_field_definition_tenant_id_name_key UNIQUE (tenant_id, name);
+
+ALTER TABLE customer_note
+    DROP CONSTRAINT customer_note_customer_number_fkey,
+    ADD CONSTRAINT customer_note_tenant_id_id_key UNIQUE (tenant_id, id),
+    ADD FOREIGN KEY (tenant_id, customer_number) REFERENCES customer(tenant_id, customer_number) ON DELETE CASCADE;
+
+DROP INDEX customer_note_assignee_idx;
+CREATE INDEX customer_note_assignee_idx ON customer_note (tenant_id, assignee, due_at) WHERE status = 'open';
+DROP INDEX customer_note_due_idx;
+-- Follow-ups the scheduler still has to remind of.
+CREATE INDEX customer_note_due_idx ON customer_note (tenant_id, due_at) WHERE status = 'open' AND reminded_at IS NULL;
+
+ALTER TABLE customer_note_revision
+    DROP CONSTRAINT customer_note_revision_note_id_fkey,
+    ADD FOREIGN KEY (tenant_id, note_id) REFERENCES customer_note(tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE customer_note_mention
+    DROP CONSTRAINT customer_note_mention_note_id_fkey,
+    ADD FOREIGN KEY (tenant_id, note_id) REFERENCES customer_note(tenant_id, id) ON DELETE CASCADE;
+
+DROP INDEX customer_note_mention_username_idx;
+CREATE INDEX customer_note_mention_username_idx ON customer_note_mention (tenant_id, username);
+DROP INDEX customer_note_mention_pending_idx;
+CREATE INDEX customer_note_mention_pending_idx ON customer_note_mention (tenant_id, created_at) WHERE notified_at IS NULL;
+
+ALTER TABLE customer_address
+    DROP CONSTRAINT customer_address_customer_number_fkey,
+    ADD FOREIGN KEY (tenant_id, customer_number) REFERENCES customer(tenant_id, customer_number) ON DELETE CASCADE;
+
+ALTER TABLE customer_contact_point
+    DROP CONSTRAINT customer_contact_point_customer_number_fkey,
+    ADD CONSTRAINT customer_contact_point_tenant_id_id_key UNIQUE (tenant_id, id),
+    ADD FOREIGN KEY (tenant_id, customer_number) REFERENCES customer(tenant_id, customer_number) ON DELETE CASCADE;
+
+ALTER TABLE customer_contact_verification
+    DROP CONSTRAINT customer_contact_verification_contact_point_id_fkey,
+    ADD FOREIGN KEY (tenant_id, contact_point_id) REFERENCES customer_contact_point(tenant_id, id) ON DELETE CASCADE;
+
+-- Attached files are stored once per tenant and SHA-256 in the blob store.
+ALTER TABLE customer_note_attachment
+    DROP CONSTRAINT customer_note_attachment_note_id_fkey,
+    DROP CONSTRAINT customer_note_attachment_sha256_fkey;
+
+ALTER TABLE note_attachment_blob
+    DROP CONSTRAINT note_attachment_blob_pkey,
+    ADD PRIMARY KEY (tenant_id, sha256);
+
+ALTER TABLE customer_note_attachment
+    ADD FOREIGN KEY (tenant_id, note_id) REFERENCES customer_note(tenant_id, id) ON DELETE CASCADE,
+    ADD FOREIGN KEY (tenant_id, sha256) REFERENCES note_attachment_blob(tenant_id, sha256);
+
+DROP INDEX customer_note_attachment_sha256_idx;
+CREATE INDEX customer_note_attachment_sha256_idx ON customer_note_attachment (tenant_id, sha256);
+
+ALTER TABLE tag
+    DROP CONSTRAINT tag_name_key,
+    ADD CONSTRAINT tag_tenant_id_name_key UNIQUE (tenant_id, name),
+    ADD CONSTRAINT tag_tenant_id_id_key UNIQUE (tenant_id, id);
+
+ALTER TABLE customer_tag
+    DROP CONSTRAINT customer_tag_customer_number_fkey,
+    DROP CONSTRAINT customer_tag_tag_id_fkey,
+    ADD FOREIGN KEY (tenant_id, customer_number) REFERENCES customer(tenant_id, customer_number) ON DELETE CASCADE,
+    ADD FOREIGN KEY (tenant_id, tag_id) REFERENCES tag(tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE segment
+    DROP CONSTRAINT segment_name_key,
+    ADD CONSTRAINT segment_tenant_id_name_key UNIQUE (tenant_id, name);
+
+ALTER TABLE customer_duplicate_candidate
+    DROP CONSTRAINT customer_duplicate_candidate_customer_a_fkey,
+    DROP CONSTRAINT customer_duplicate_candidate_customer_b_fkey,
+    ADD FOREIGN KEY (tenant_id, customer_a) REFERENCES customer(tenant_id, customer_number) ON DELETE CASCADE,
+    ADD FOREIGN KEY (tenant_id, customer_b) REFERENCES customer(tenant_id, customer_number) ON DELETE CASCADE;
+
+ALTER TABLE customer_redirect
+    DROP CONSTRAINT customer_redirect_customer_number_fkey,
+    ADD FOREIGN KEY (tenant_id, customer_number) REFERENCES customer(tenant_id, customer_number) ON DELETE CASCADE;
+
+-- The repositories filter every statement by tenant as well; the policies
+-- keep a missed filter from leaking rows. FORCE applies them to the table
+-- owner too, superusers still bypass them.
+DO $$
+DECLARE
+    tenant_table TEXT;
+BEGIN
+    FOREACH tenant_table IN ARRAY ARRAY[
+        'customer', 'custom_field_definition', 'customer_note', 'customer_note_revision',
+        'customer_note_mention', 'customer_address', 'customer_contact_point',
+        'customer_contact_verification', 'note_attachment_blob', 'customer_note_attachment',
+        'tag', 'customer_tag', 'segment', 'customer_duplicate_candidate', 'customer_merge',
+        'customer_redirect'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', tenant_table);
+        EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', tenant_table);
+        EXECUTE format('CREATE POLICY tenant_isolation ON %I USING (tenant_id = app_tenant()) WITH CHECK (tenant_id = app_tenant())', tenant_table);
+    END LOOP;
+END;
+$$;
+
+-- The service connects as customer_app, which is not a superuser, so the
+-- policies apply to it. Roles belong to the cluster, so it may exist already.
+-- It cannot log in until the migrate command sets database.app_password.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'customer_app') THEN
+        CREATE ROLE customer_app NOLOGIN;
+    END IF;
+END;
+$$;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO customer_app;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO customer_app;
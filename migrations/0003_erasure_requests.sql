@@ -0,0 +1,37 @@
+-- Revisions are history; they go away with their note but never change,
+-- except that an erasure may redact their text.
+CREATE OR REPLACE FUNCTION customer_note_revision_immutable() RETURNS trigger AS $$
+BEGIN
+    IF NEW.note = '[erased]' THEN
+        NEW := OLD;
+        NEW.note := '[erased]';
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'customer_note_revision rows cannot be updated';
+END;
+$$ LANGUAGE plpgsql;
+
+-- Data subject erasures. The customer row is anonymised rather than removed,
+-- and requests are kept after the erasure as its record, so there is no
+-- foreign key. certificate is TEXT to keep the signed bytes as they are.
+CREATE TABLE customer_erasure_request (
+    id              SERIAL PRIMARY KEY,
+    tenant_id       INTEGER NOT NULL DEFAULT app_tenant() REFERENCES tenant(id),
+    customer_number INTEGER NOT NULL,
+    reason          TEXT NOT NULL DEFAULT '',
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'rejected', 'completed')),
+    requested_by    VARCHAR(100) NOT NULL,
+    requested_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reviewed_by     VARCHAR(100) NOT NULL DEFAULT '',
+    reviewed_at     TIMESTAMP,
+    certificate     TEXT
+);
+
+CREATE INDEX customer_erasure_request_customer_number_idx ON customer_erasure_request (tenant_id, customer_number);
+
+ALTER TABLE customer_erasure_request ENABLE ROW LEVEL SECURITY;
+ALTER TABLE customer_erasure_request FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON customer_erasure_request USING (tenant_id = app_tenant()) WITH CHECK (tenant_id = app_tenant());
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON customer_erasure_request TO customer_app;
+GRANT USAGE, SELECT ON customer_erasure_request_id_seq TO customer_app;
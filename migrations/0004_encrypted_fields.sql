@@ -0,0 +1,21 @@
+-- email, phone and birth_date may be encrypted by the application (see
+-- encryption.fields), so they are TEXT; birth_date holds YYYY-MM-DD when it is
+-- not. Emails are unique and looked up through email_index, a keyed hash the
+-- application computes; the re-encryption job fills it in for the existing
+-- rows and seals them.
+ALTER TABLE customer
+    ALTER COLUMN email TYPE TEXT,
+    ALTER COLUMN phone TYPE TEXT,
+    ALTER COLUMN birth_date TYPE TEXT USING to_char(birth_date, 'YYYY-MM-DD'),
+    ADD COLUMN email_index VARCHAR(64),
+    DROP CONSTRAINT customer_tenant_id_email_key,
+    ADD CONSTRAINT customer_tenant_id_email_index_key UNIQUE (tenant_id, email_index);
+
+-- plain_date reads a birth_date that is not encrypted; encrypted ones are NULL.
+CREATE FUNCTION plain_date(value TEXT) RETURNS DATE AS $$
+    SELECT CASE WHEN value NOT LIKE 'enc:%' THEN value::date END
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE customer_contact_point
+    ALTER COLUMN value TYPE TEXT,
+    ADD COLUMN value_index VARCHAR(64);
@@ -0,0 +1,219 @@
+// Package migrations holds the versioned changes of the database schema and
+// applies them. A migration is a file named after its version and what it
+// does, e.g. 0002_tenants.sql; once released it is never edited, a change
+// goes into a new file. The versions applied to a database are recorded in
+// the schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Migration is one schema change.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// ErrOutdated is returned by Check when migrations are missing.
+var ErrOutdated = errors.New("migrations: the database schema is outdated, run migrate")
+
+// lockID keeps concurrent runs of Up from applying the same migration twice.
+const lockID = 4829061
+
+const (
+	queryCreateTable = `
+		CREATE TABLE schema_migrations (
+			version         INTEGER PRIMARY KEY,
+			name            VARCHAR(100) NOT NULL,
+			applied_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		GRANT SELECT ON schema_migrations TO PUBLIC
+	`
+	queryHasTable = `SELECT to_regclass('public.schema_migrations') IS NOT NULL`
+	queryVersion  = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	queryInsert   = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+	// queryLegacy tells whether the schema was created by init/init.sql,
+	// before migrations were versioned, and whether that was the init.sql
+	// with tenants, which already held the first legacyTenantVersion
+	// migrations.
+	queryLegacy = `SELECT to_regclass('public.customer') IS NOT NULL, to_regclass('public.tenant') IS NOT NULL`
+)
+
+const legacyTenantVersion = 4
+
+// All returns every migration ordered by version. Versions start at 1 and
+// have no gaps.
+func All() ([]Migration, error) {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		prefix, rest, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || rest == "" {
+			return nil, fmt.Errorf("migrations: %s is not named like 0001_name.sql", name)
+		}
+		script, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: rest, SQL: string(script)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migrations: expected version %d, found %s", i+1, migration)
+		}
+	}
+	return migrations, nil
+}
+
+// Version returns the latest version applied to db, 0 when none is.
+func Version(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, queryHasTable).Scan(&exists); err != nil || !exists {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRowContext(ctx, queryVersion).Scan(&version)
+	return version, err
+}
+
+// Check returns ErrOutdated unless every migration is applied to db.
+func Check(ctx context.Context, db *sql.DB) error {
+	migrations, err := All()
+	if err != nil {
+		return err
+	}
+	version, err := Version(ctx, db)
+	if err != nil {
+		return err
+	}
+	if latest := len(migrations); version < latest {
+		return fmt.Errorf("%w: it is at version %d, this build needs %d", ErrOutdated, version, latest)
+	}
+	return nil
+}
+
+// AppRole is the role the application connects as. The migrations create it
+// without the right to log in, SetAppPassword gives it.
+const AppRole = "customer_app"
+
+// SetAppPassword lets AppRole log in with password. The password is kept out
+// of the migrations so that it is not the same in every installation.
+func SetAppPassword(ctx context.Context, db *sql.DB, password string) error {
+	_, err := db.ExecContext(ctx, "ALTER ROLE "+pq.QuoteIdentifier(AppRole)+" LOGIN PASSWORD "+pq.QuoteLiteral(password))
+	return err
+}
+
+// Up applies the migrations db does not have yet and returns them. Each runs
+// in its own transaction, together with its schema_migrations row, so a
+// failed migration leaves the database at the version before it. A schema
+// created by init/init.sql is taken as version 1, or as version 4 when it has
+// tenants.
+func Up(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	var exists bool
+	if err := conn.QueryRowContext(ctx, queryHasTable).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := createTable(ctx, conn, migrations); err != nil {
+			return nil, err
+		}
+	}
+
+	var version int
+	if err := conn.QueryRowContext(ctx, queryVersion).Scan(&version); err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	for _, migration := range migrations[min(version, len(migrations)):] {
+		if err := apply(ctx, conn, migration); err != nil {
+			return applied, fmt.Errorf("migrations: %s: %w", migration, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// createTable creates schema_migrations, recording the migrations a schema
+// created by init/init.sql already holds as applied.
+func createTable(ctx context.Context, conn *sql.Conn, migrations []Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var legacy, tenants bool
+	if err := tx.QueryRowContext(ctx, queryLegacy).Scan(&legacy, &tenants); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, queryCreateTable); err != nil {
+		return err
+	}
+	var held []Migration
+	switch {
+	case tenants:
+		held = migrations[:min(legacyTenantVersion, len(migrations))]
+	case legacy:
+		held = migrations[:1]
+	}
+	for _, migration := range held {
+		if _, err := tx.ExecContext(ctx, queryInsert, migration.Version, migration.Name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Without arguments the script is sent as is, so it may hold several
+	// statements.
+	if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, queryInsert, migration.Version, migration.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
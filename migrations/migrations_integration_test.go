@@ -0,0 +1,34 @@
+package migrations_test
+
+import (
+	"context"
+	"customer-playground/database/pgtest"
+	"customer-playground/migrations"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestUpIsIdempotent(t *testing.T) {
+	db := pgtest.Open(t).Primary()
+	ctx := context.Background()
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pgtest applied every migration already.
+	if err := migrations.Check(ctx, db); err != nil {
+		t.Fatalf("Check() = %v", err)
+	}
+	if version, err := migrations.Version(ctx, db); err != nil || version != len(all) {
+		t.Fatalf("Version() = %d, %v, want %d", version, err, len(all))
+	}
+	applied, err := migrations.Up(ctx, db)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("Up() on a migrated database = %v, %v, want nothing applied", applied, err)
+	}
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	migrations, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 || migrations[0].String() != "0001_schema" {
+		t.Fatalf("All() = %v, want 0001_schema first", migrations)
+	}
+	for _, migration := range migrations {
+		if strings.TrimSpace(migration.SQL) == "" {
+			t.Errorf("%s is empty", migration)
+		}
+	}
+}
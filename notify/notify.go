@@ -0,0 +1,56 @@
+// Package notify delivers follow-up reminders and mentions to agents.
+package notify
+
+import (
+	"context"
+	"customer-playground/domain"
+	"customer-playground/markdown"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogNotifier writes notifications to the application log. It is meant for
+// local development only.
+type LogNotifier struct {
+	logger *logrus.Logger
+}
+
+func NewLogNotifier(logger *logrus.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(notification domain.Notification, ctx context.Context) error {
+	n.logger.WithFields(logrus.Fields{
+		"kind":            notification.Kind,
+		"recipient":       notification.Recipient,
+		"note_id":         notification.Note.ID,
+		"customer_number": notification.Note.CustomerNumber,
+	}).Info(subject(notification))
+	return nil
+}
+
+func subject(notification domain.Notification) string {
+	switch notification.Kind {
+	case domain.NotificationReminder:
+		return fmt.Sprintf("Follow-up due for customer %d", notification.Note.CustomerNumber)
+	case domain.NotificationMention:
+		return fmt.Sprintf("%s mentioned you on customer %d", notification.Note.Author, notification.Note.CustomerNumber)
+	default:
+		return fmt.Sprintf("Note %d on customer %d", notification.Note.ID, notification.Note.CustomerNumber)
+	}
+}
+
+func body(notification domain.Notification) string {
+	var b strings.Builder
+	note := notification.Note
+	fmt.Fprintf(&b, "Customer: %d\n", note.CustomerNumber)
+	fmt.Fprintf(&b, "Note: %d by %s\n", note.ID, note.Author)
+	if note.DueAt.Valid {
+		fmt.Fprintf(&b, "Due: %s\n", note.DueAt.Time.Format(time.RFC1123))
+	}
+	fmt.Fprintf(&b, "\n%s\n", markdown.PlainText(note.Note))
+	return b.String()
+}
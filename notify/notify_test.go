@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var reminder = domain.Notification{
+	Kind:      domain.NotificationReminder,
+	Recipient: "bob.agent",
+	Note: domain.CustomerNote{
+		ID:             7,
+		CustomerNumber: 42,
+		Note:           "call back about the invoice",
+		Author:         "jane.agent",
+		DueAt:          types.NullTime{Time: time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC), Valid: true},
+	},
+}
+
+// smtpServer accepts one mail and returns the recipient and the data.
+func smtpServer(t *testing.T) (string, int, <-chan [2]string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan [2]string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+		reply("220 localhost ESMTP test")
+		var rcpt string
+		var data strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM"):
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO"):
+				rcpt = strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>")
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- [2]string{rcpt, data.String()}
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return host, portNumber, received
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	host, port, received := smtpServer(t)
+	notifier := NewSMTPNotifier(SMTPConfig{Host: host, Port: port, From: "crm@example.com", Domain: "example.com"})
+
+	if err := notifier.Notify(reminder, context.Background()); err != nil {
+		t.Fatalf("Notify() = %v", err)
+	}
+	mail := <-received
+	if mail[0] != "bob.agent@example.com" {
+		t.Errorf("recipient = %q, want bob.agent@example.com", mail[0])
+	}
+	for _, want := range []string{"Subject: Follow-up due for customer 42", "call back about the invoice", "Due: Tue, 11 Jun 2024"} {
+		if !strings.Contains(mail[1], want) {
+			t.Errorf("mail does not contain %q:\n%s", want, mail[1])
+		}
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got domain.Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != "sha256="+Sign("s3cret", body) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		json.Unmarshal(body, &got)
+	}))
+	defer server.Close()
+
+	if err := NewWebhookNotifier(server.URL, "s3cret", nil).Notify(reminder, context.Background()); err != nil {
+		t.Fatalf("Notify() = %v", err)
+	}
+	if got.Recipient != "bob.agent" || got.Note.ID != 7 || !got.Note.DueAt.Valid {
+		t.Errorf("webhook received %+v", got)
+	}
+	if err := NewWebhookNotifier(server.URL, "wrong", nil).Notify(reminder, context.Background()); err == nil {
+		t.Error("Notify() with a rejected signature succeeded")
+	}
+}
@@ -0,0 +1,34 @@
+package pii
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a logrus hook masking email addresses and phone numbers in the
+// message and fields of every entry, e.g. in database errors quoting a row.
+type Hook struct{}
+
+func NewHook() *Hook {
+	return &Hook{}
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	entry.Message = Text(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = Text(v)
+		case error:
+			entry.Data[key] = Text(v.Error())
+		case fmt.Stringer:
+			entry.Data[key] = Text(v.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// Package pii masks personal data: in API responses for callers without
+// domain.PermissionReadPII, and in every log entry.
+package pii
+
+import (
+	"context"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// 7 to 15 digits with the separators contact points accept, like
+	// normalize in the customercontact use case. Dates and IP addresses
+	// match as well and are left alone by Text.
+	phonePattern = regexp.MustCompile(`(?:\+|\(|\b)\d(?:[ ().-]{0,2}\d){6,14}\b`)
+	datePattern  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// Email keeps the first character of the local part and the domain, e.g.
+// j***@example.com.
+func Email(email string) string {
+	local, domainPart, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return strings.Repeat("*", len(email))
+	}
+	return local[:1] + "***@" + domainPart
+}
+
+// Phone keeps the last three digits and any separators, e.g. ********789.
+func Phone(phone string) string {
+	digits := 0
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	var masked strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits--
+			if digits >= 3 {
+				r = '*'
+			}
+		} else if !strings.ContainsRune("+ ().-", r) {
+			r = '*'
+		}
+		masked.WriteRune(r)
+	}
+	return masked.String()
+}
+
+// Text masks every email address and phone number in s.
+func Text(s string) string {
+	s = emailPattern.ReplaceAllStringFunc(s, Email)
+	return phonePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if datePattern.MatchString(match) || net.ParseIP(match) != nil {
+			return match
+		}
+		return Phone(match)
+	})
+}
+
+// ErrMasked is returned by Unmask for a value that would store a mask.
+var ErrMasked = errors.New("masked values cannot be stored, send the full value")
+
+// Masked reports whether value looks like an email or phone number masked by
+// Email or Phone.
+func Masked(value string) bool {
+	if local, _, ok := strings.Cut(value, "@"); ok {
+		first, ok := strings.CutSuffix(local, "***")
+		return ok && utf8.RuneCountInString(first) == 1
+	}
+	return strings.Contains(value, "*")
+}
+
+// Unmask returns the value to store when value replaces stored, which callers
+// without domain.PermissionReadPII were shown as masked. A client sending the
+// mask back unchanged keeps stored; other masked values fail with ErrMasked.
+func Unmask(value, stored, masked string) (string, error) {
+	if stored != "" && value == masked {
+		return stored, nil
+	}
+	if Masked(value) {
+		return "", ErrMasked
+	}
+	return value, nil
+}
+
+// CanRead reports whether the caller may see personal data unmasked.
+func CanRead(ctx context.Context) bool {
+	return domain.HasPermission(ctx, domain.PermissionReadPII)
+}
+
+// MaskCustomer hides the email, phone and birth date of customer.
+func MaskCustomer(customer domain.Customer) domain.Customer {
+	customer.Email = Email(customer.Email)
+	customer.Phone = Phone(customer.Phone)
+	customer.BirthDate = types.NullTime{}
+	return customer
+}
+
+// Customer masks customer unless the caller may read personal data.
+func Customer(ctx context.Context, customer domain.Customer) domain.Customer {
+	if CanRead(ctx) {
+		return customer
+	}
+	return MaskCustomer(customer)
+}
+
+// Customers returns a masked copy of customers unless the caller may read
+// personal data. Slices may be shared with a cache, so they are not masked in
+// place.
+func Customers(ctx context.Context, customers []domain.Customer) []domain.Customer {
+	if CanRead(ctx) {
+		return customers
+	}
+	masked := make([]domain.Customer, len(customers))
+	for i, customer := range customers {
+		masked[i] = MaskCustomer(customer)
+	}
+	return masked
+}
+
+// ContactPoints returns a copy of contactPoints with masked values unless the
+// caller may read personal data.
+func ContactPoints(ctx context.Context, contactPoints []domain.CustomerContactPoint) []domain.CustomerContactPoint {
+	if CanRead(ctx) {
+		return contactPoints
+	}
+	masked := make([]domain.CustomerContactPoint, len(contactPoints))
+	for i, contactPoint := range contactPoints {
+		masked[i] = MaskContactPoint(contactPoint)
+	}
+	return masked
+}
+
+// ContactPoint masks the value of contactPoint unless the caller may read
+// personal data.
+func ContactPoint(ctx context.Context, contactPoint domain.CustomerContactPoint) domain.CustomerContactPoint {
+	if CanRead(ctx) {
+		return contactPoint
+	}
+	return MaskContactPoint(contactPoint)
+}
+
+// Candidates returns a copy of candidates with both customers of every pair
+// masked unless the caller may read personal data.
+func Candidates(ctx context.Context, candidates []domain.DuplicateCandidate) []domain.DuplicateCandidate {
+	if CanRead(ctx) {
+		return candidates
+	}
+	masked := make([]domain.DuplicateCandidate, len(candidates))
+	for i, candidate := range candidates {
+		candidate.CustomerA = MaskCustomer(candidate.CustomerA)
+		candidate.CustomerB = MaskCustomer(candidate.CustomerB)
+		masked[i] = candidate
+	}
+	return masked
+}
+
+// Merges returns a copy of merges with the snapshots of merged customers
+// masked unless the caller may read personal data.
+func Merges(ctx context.Context, merges []domain.CustomerMerge) []domain.CustomerMerge {
+	if CanRead(ctx) {
+		return merges
+	}
+	masked := make([]domain.CustomerMerge, len(merges))
+	for i, merge := range merges {
+		merge.MergedCustomer = maskSnapshot(merge.MergedCustomer)
+		masked[i] = merge
+	}
+	return masked
+}
+
+// Export masks the customer, contact points and merges of a data subject
+// export unless the caller may read personal data.
+func Export(ctx context.Context, export domain.DataSubjectExport) domain.DataSubjectExport {
+	export.Customer = Customer(ctx, export.Customer)
+	export.ContactPoints = ContactPoints(ctx, export.ContactPoints)
+	export.Merges = Merges(ctx, export.Merges)
+	return export
+}
+
+// MaskContactPoint hides the value of contactPoint.
+func MaskContactPoint(contactPoint domain.CustomerContactPoint) domain.CustomerContactPoint {
+	if contactPoint.Type == domain.ContactTypeEmail {
+		contactPoint.Value = Email(contactPoint.Value)
+	} else {
+		contactPoint.Value = Phone(contactPoint.Value)
+	}
+	return contactPoint
+}
+
+func maskSnapshot(snapshot types.JSONMap) types.JSONMap {
+	if snapshot == nil {
+		return nil
+	}
+	masked := make(types.JSONMap, len(snapshot))
+	for key, value := range snapshot {
+		masked[key] = value
+	}
+	if email, ok := masked["email"].(string); ok {
+		masked["email"] = Email(email)
+	}
+	if phone, ok := masked["phone"].(string); ok {
+		masked["phone"] = Phone(phone)
+	}
+	if _, ok := masked["birth_date"]; ok {
+		masked["birth_date"] = nil
+	}
+	return masked
+}
@@ -0,0 +1,161 @@
+package pii
+
+import (
+	"context"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestEmailAndPhone(t *testing.T) {
+	tests := []struct {
+		mask  func(string) string
+		value string
+		want  string
+	}{
+		{Email, "john.doe@example.com", "j***@example.com"},
+		{Email, "", ""},
+		{Email, "not-an-email", "************"},
+		{Phone, "08123456789", "********789"},
+		{Phone, "+62 812-3456-789", "+** ***-****-789"},
+		{Phone, "(021) 555.0199", "(***) ***.*199"},
+		{Phone, "12", "12"},
+		{Phone, "", ""},
+	}
+	for _, test := range tests {
+		if got := test.mask(test.value); got != test.want {
+			t.Errorf("mask(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestUnmask(t *testing.T) {
+	tests := []struct {
+		value   string
+		stored  string
+		want    string
+		wantErr bool
+	}{
+		{"j***@example.com", "john.doe@example.com", "john.doe@example.com", false},
+		{"********789", "08123456789", "08123456789", false},
+		{"jane@example.com", "john.doe@example.com", "jane@example.com", false},
+		{"x***@example.com", "john.doe@example.com", "", true},
+		{"********123", "08123456789", "", true},
+		{"j***@example.org", "", "", true},
+		{"jo***@example.com", "john.doe@example.com", "jo***@example.com", false},
+	}
+	for _, test := range tests {
+		mask := Phone
+		if strings.Contains(test.stored, "@") || strings.Contains(test.value, "@") {
+			mask = Email
+		}
+		got, err := Unmask(test.value, test.stored, mask(test.stored))
+		if got != test.want || errors.Is(err, ErrMasked) != test.wantErr {
+			t.Errorf("Unmask(%q, %q) = %q, %v", test.value, test.stored, got, err)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	got := Text(`pq: duplicate key (email)=(john.doe@example.com), phone 08123456789 on 2024-05-01, order 12345`)
+	want := `pq: duplicate key (email)=(j***@example.com), phone ********789 on 2024-05-01, order 12345`
+	if got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+// TestTextPhones masks what contact points accept as phone numbers, 7 to 15
+// digits with or without separators, and nothing shorter.
+func TestTextPhones(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"call 12345", "call 12345"},
+		{"call 123456", "call 123456"},
+		{"call 5550199", "call ****199"},
+		{"call 555-0199", "call ***-*199"},
+		{"call 55500199", "call *****199"},
+		{"call 5550 0199", "call **** *199"},
+		{"call (021) 555.0199", "call (***) ***.*199"},
+		{"call +62 812-3456-789", "call +** ***-****-789"},
+		{"call +123456789012345", "call +************345"},
+		{"on 2024-05-01 from 10.0.0.12", "on 2024-05-01 from 10.0.0.12"},
+	}
+	for _, test := range tests {
+		if got := Text(test.text); got != test.want {
+			t.Errorf("Text(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestCustomers(t *testing.T) {
+	customers := []domain.Customer{{
+		Name:      "John Doe",
+		Email:     "john.doe@example.com",
+		Phone:     "08123456789",
+		BirthDate: types.NullTime{Time: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC), Valid: true},
+	}}
+
+	masked := Customers(context.Background(), customers)
+	if masked[0].Name != "John Doe" || masked[0].Email != "j***@example.com" || masked[0].Phone != "********789" || masked[0].BirthDate.Valid {
+		t.Errorf("Customers() = %+v", masked[0])
+	}
+	if customers[0].Email != "john.doe@example.com" {
+		t.Error("Customers() masked the slice it was given")
+	}
+
+	ctx := domain.WithPermissions(context.Background(), []string{domain.PermissionReadPII})
+	if unmasked := Customers(ctx, customers); unmasked[0].Email != "john.doe@example.com" || !unmasked[0].BirthDate.Valid {
+		t.Errorf("Customers() with pii:read = %+v", unmasked[0])
+	}
+}
+
+func TestExport(t *testing.T) {
+	export := domain.DataSubjectExport{
+		Customer: domain.Customer{Email: "john.doe@example.com"},
+		ContactPoints: []domain.CustomerContactPoint{
+			{Type: domain.ContactTypeEmail, Value: "jd@example.com"},
+			{Type: domain.ContactTypePhone, Value: "08123456789"},
+		},
+		Merges: []domain.CustomerMerge{{MergedCustomer: types.JSONMap{"name": "John", "email": "john@example.com", "birth_date": "1990-01-02"}}},
+	}
+
+	masked := Export(context.Background(), export)
+	if masked.Customer.Email != "j***@example.com" || masked.ContactPoints[0].Value != "j***@example.com" || masked.ContactPoints[1].Value != "********789" {
+		t.Errorf("Export() = %+v", masked)
+	}
+	snapshot := masked.Merges[0].MergedCustomer
+	if snapshot["name"] != "John" || snapshot["email"] != "j***@example.com" || snapshot["birth_date"] != nil {
+		t.Errorf("Export() snapshot = %v", snapshot)
+	}
+	if export.Merges[0].MergedCustomer["email"] != "john@example.com" {
+		t.Error("Export() masked the snapshot it was given")
+	}
+}
+
+func TestHook(t *testing.T) {
+	logger := logrus.New()
+	var out strings.Builder
+	logger.Out = &out
+	logger.Formatter = &logrus.JSONFormatter{}
+	logger.AddHook(NewHook())
+
+	logger.WithField("email", "john.doe@example.com").
+		WithError(errors.New("no customer with phone +6281234567")).
+		Errorf("customerRepository/Store :%v", "jane@example.org")
+
+	for _, leaked := range []string{"john.doe", "jane@", "6281234567"} {
+		if strings.Contains(out.String(), leaked) {
+			t.Errorf("log %s contains %q", out.String(), leaked)
+		}
+	}
+	if !strings.Contains(out.String(), "j***@example.com") || !strings.Contains(out.String(), "567") {
+		t.Errorf("log %s is not masked as expected", out.String())
+	}
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"customer-playground/domain"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Group applies Limit to the routes it lists. A route is either a gin route
+// pattern such as "/api/v1/customers/:number", matching every method, or a
+// method followed by a pattern such as "DELETE /api/v1/customers/:number".
+type Group struct {
+	Name   string
+	Routes []string
+	Limit  Limit
+}
+
+// Limiter is a gin middleware that keeps a token bucket per client and route
+// group. Clients are identified within their tenant by the subject of their
+// bearer token, or by IP address when they do not send one. It has to run
+// after tenant.Resolver, and the engine has to trust only the proxies that
+// set X-Forwarded-For, see gin.Engine.SetTrustedProxies.
+type Limiter struct {
+	store    Store
+	mu       sync.RWMutex
+	groups   []Group
+	fallback Group
+	logger   *logrus.Logger
+}
+
+func NewLimiter(store Store, groups []Group, fallback Limit, logger *logrus.Logger) *Limiter {
+	return &Limiter{
+		store:    store,
+		groups:   groups,
+		fallback: Group{Name: "default", Limit: fallback},
+		logger:   logger,
+	}
+}
+
+// SetLimits replaces the groups and the fallback limit, e.g. after the
+// configuration was reloaded. Buckets are kept, so a changed limit applies to
+// what is left in them.
+func (l *Limiter) SetLimits(groups []Group, fallback Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.groups = groups
+	l.fallback = Group{Name: "default", Limit: fallback}
+}
+
+func (l *Limiter) Handler(ctx *gin.Context) {
+	group := l.group(ctx.Request.Method, ctx.FullPath())
+	if group.Limit.Rate <= 0 {
+		ctx.Next()
+		return
+	}
+
+	res, err := l.store.Take(ctx, group.Name+":"+l.client(ctx), group.Limit)
+	if err != nil {
+		// Fail open: an unavailable store must not take the API down.
+		l.logger.Errorf("%s : %v", "Limiter/Handler/Take", err)
+		ctx.Next()
+		return
+	}
+
+	header := ctx.Writer.Header()
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", group.Limit.Burst, seconds(group.Limit.Window())))
+	header.Set("RateLimit-Limit", strconv.Itoa(group.Limit.Burst))
+	header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+	if !res.Allowed {
+		header.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+		ctx.AbortWithStatusJSON(http.StatusTooManyRequests, domain.ErrorResponse{Message: "rate limit exceeded"})
+		return
+	}
+	ctx.Next()
+}
+
+func (l *Limiter) group(method, route string) Group {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, group := range l.groups {
+		for _, pattern := range group.Routes {
+			patternMethod, patternRoute, ok := strings.Cut(pattern, " ")
+			if !ok {
+				patternMethod, patternRoute = "", pattern
+			}
+			if patternRoute == route && (patternMethod == "" || strings.EqualFold(patternMethod, method)) {
+				return group
+			}
+		}
+	}
+	return l.fallback
+}
+
+// client names the bucket owner. Headers the client sends itself, such as an
+// API key, are not used: a new value would give it a new bucket.
+func (l *Limiter) client(ctx *gin.Context) string {
+	requestCtx := ctx.Request.Context()
+	tenantID := domain.TenantFromContext(requestCtx).ID
+	if subject := domain.SubjectFromContext(requestCtx); subject != "" {
+		sum := sha256.Sum256([]byte(subject))
+		return fmt.Sprintf("%d:sub:%s", tenantID, hex.EncodeToString(sum[:16]))
+	}
+	return fmt.Sprintf("%d:ip:%s", tenantID, ctx.ClientIP())
+}
+
+// seconds rounds d up to whole seconds as required by Retry-After.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
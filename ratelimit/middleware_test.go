@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"context"
+	"customer-playground/domain"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMemoryRefills(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemory()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if res, _ := store.Take(ctx, "k", limit); !res.Allowed {
+			t.Fatalf("take %d: expected to be allowed", i)
+		}
+	}
+	res, _ := store.Take(ctx, "k", limit)
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Fatalf("expected a denial with 1s retry, got %+v", res)
+	}
+
+	now = now.Add(time.Second)
+	if res, _ := store.Take(ctx, "k", limit); !res.Allowed {
+		t.Fatal("expected a token after one second")
+	}
+}
+
+func TestLimiterHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	limiter := NewLimiter(NewMemory(), []Group{{
+		Name:   "customer-write",
+		Routes: []string{"DELETE /customer/:customer_number"},
+		Limit:  Limit{Rate: 0.5, Burst: 1},
+	}}, Limit{}, logger)
+
+	r := gin.New()
+	r.Use(limiter.Handler)
+	r.GET("/customer/:customer_number", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	r.DELETE("/customer/:customer_number", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	do := func(method, subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/customer/1", nil)
+		req = req.WithContext(domain.WithSubject(req.Context(), subject))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodDelete, "a"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("expected first delete to pass, got %d %v", rec.Code, rec.Header())
+	}
+	rec := do(http.MethodDelete, "a")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "2" || rec.Header().Get("RateLimit-Limit") != "1" {
+		t.Errorf("unexpected headers %v", rec.Header())
+	}
+	if rec := do(http.MethodDelete, "b"); rec.Code != http.StatusOK {
+		t.Errorf("expected another token subject to have its own bucket, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "a"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("expected routes outside the group to be unlimited, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestLimiterSetLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	limiter := NewLimiter(NewMemory(), nil, Limit{}, logger)
+	r := gin.New()
+	r.Use(limiter.Handler)
+	r.GET("/customer", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	do := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/customer", nil))
+		return rec
+	}
+
+	if rec := do(); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Fatalf("expected no limit, got %d %v", rec.Code, rec.Header())
+	}
+	limiter.SetLimits([]Group{{Name: "customer-read", Routes: []string{"GET /customer"}, Limit: Limit{Rate: 0.5, Burst: 1}}}, Limit{})
+	if rec := do(); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatalf("expected the new group to apply, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := do(); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", rec.Code)
+	}
+}
+
+func TestLimiterIgnoresClientHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	limiter := NewLimiter(NewMemory(), []Group{{
+		Name:   "customer-contact-verification",
+		Routes: []string{"POST /customer/:customer_number/contact-points/:id/verify"},
+		Limit:  Limit{Rate: 0.1, Burst: 1},
+	}}, Limit{}, logger)
+
+	r := gin.New()
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	r.Use(limiter.Handler)
+	r.POST("/customer/:customer_number/contact-points/:id/verify", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/customer/1/contact-points/1/verify", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		req.Header.Set("X-Real-IP", fmt.Sprintf("198.51.100.%d", i))
+		req.Header.Set("X-API-Key", fmt.Sprintf("key-%d", i))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if want := http.StatusTooManyRequests; i > 0 && rec.Code != want {
+			t.Fatalf("attempt %d with new headers: expected %d, got %d", i, want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Postgres keeps buckets in the rate_limit_bucket table so every instance of
+// the service shares the same limits. Each Take is a single atomic upsert.
+type Postgres struct {
+	db *sql.DB
+}
+
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var tokens float64
+	var allowed bool
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO rate_limit_bucket AS b (key, tokens, allowed, updated_at)
+		VALUES ($1, $3::float8 - 1, $3::float8 >= 1, clock_timestamp())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE
+				WHEN LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at)::float8 * $2::float8) >= 1
+				THEN LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at)::float8 * $2::float8) - 1
+				ELSE LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at)::float8 * $2::float8)
+			END,
+			allowed = LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at)::float8 * $2::float8) >= 1,
+			updated_at = clock_timestamp()
+		RETURNING tokens, allowed
+	`, key, limit.Rate, limit.Burst).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, err
+	}
+	return result(allowed, tokens, limit), nil
+}
+
+// Cleanup deletes buckets that have not been touched for olderThan.
+func (p *Postgres) Cleanup(ctx context.Context, olderThan time.Duration) error {
+	_, err := p.db.ExecContext(ctx, `
+		DELETE FROM rate_limit_bucket
+		WHERE updated_at < clock_timestamp() - make_interval(secs => $1)
+	`, olderThan.Seconds())
+	return err
+}
+
+// RunCleanup calls Cleanup every interval until ctx is done.
+func (p *Postgres) RunCleanup(ctx context.Context, interval, olderThan time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Cleanup(ctx, olderThan); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
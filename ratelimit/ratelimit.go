@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket refilled with Rate tokens per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Window is the time an empty bucket needs to refill completely.
+func (l Limit) Window() time.Duration {
+	if l.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+// Result describes the bucket after a Take.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Store keeps token buckets. Implementations must be safe for concurrent use.
+type Store interface {
+	// Take removes one token from the bucket for key if one is available.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// result computes the Result for a bucket holding tokens after the take.
+func result(allowed bool, tokens float64, limit Limit) Result {
+	res := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+	}
+	if limit.Rate > 0 {
+		res.Reset = time.Duration((float64(limit.Burst) - tokens) / limit.Rate * float64(time.Second))
+		if !allowed {
+			res.RetryAfter = time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+		}
+	}
+	return res
+}
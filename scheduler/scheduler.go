@@ -0,0 +1,50 @@
+// Package scheduler runs background jobs at fixed intervals.
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Job is run every Interval. Run returns how many items it handled, which
+// is logged when positive.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) (int, error)
+}
+
+// Start runs every job in its own goroutine until ctx is done. A job that
+// fails is logged and run again at its next interval; jobs without an
+// interval are not run.
+func Start(ctx context.Context, logger *logrus.Logger, jobs ...Job) {
+	for _, job := range jobs {
+		if job.Interval <= 0 {
+			logger.Warnf("scheduler: job %s has no interval and is disabled", job.Name)
+			continue
+		}
+		go run(ctx, logger, job)
+	}
+}
+
+func run(ctx context.Context, logger *logrus.Logger, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			handled, err := job.Run(ctx)
+			if err != nil {
+				logger.Errorf("scheduler: job %s: %v", job.Name, err)
+				continue
+			}
+			if handled > 0 {
+				logger.Infof("scheduler: job %s handled %d", job.Name, handled)
+			}
+		}
+	}
+}
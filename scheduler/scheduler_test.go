@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestStart(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = io.Discard
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var runs, failures atomic.Int32
+	done, retried := make(chan struct{}), make(chan struct{})
+	Start(ctx, logger,
+		Job{Name: "count", Interval: time.Millisecond, Run: func(ctx context.Context) (int, error) {
+			if runs.Add(1) == 3 {
+				close(done)
+			}
+			return 1, nil
+		}},
+		Job{Name: "fail", Interval: time.Millisecond, Run: func(ctx context.Context) (int, error) {
+			if failures.Add(1) == 2 {
+				close(retried)
+			}
+			return 0, errors.New("boom")
+		}},
+		Job{Name: "disabled", Run: func(ctx context.Context) (int, error) {
+			t.Error("a job without interval ran")
+			return 0, nil
+		}},
+	)
+
+	for _, wait := range []chan struct{}{done, retried} {
+		select {
+		case <-wait:
+		case <-time.After(5 * time.Second):
+			t.Fatal("jobs were not run again")
+		}
+	}
+	cancel()
+
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	if after := runs.Load(); after > stopped+1 {
+		t.Errorf("job ran %d times after the context was done", after-stopped)
+	}
+}
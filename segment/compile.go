@@ -0,0 +1,261 @@
+package segment
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query is a compiled definition. Where is a SQL condition over the customer
+// table aliased as c, with Args bound to $1...$n.
+type Query struct {
+	Where string
+	Args  []interface{}
+}
+
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindInt
+	kindDate
+	kindTimestamp
+	kindTag
+)
+
+// field is a column of the customer table. Fields that may be encrypted
+// name their stored column in sealed and, when they can still be compared
+// for equality, their blind index column in index.
+type field struct {
+	column string
+	kind   fieldKind
+	sealed string
+	index  string
+}
+
+var fields = map[string]field{
+	"customer_number": {column: "c.customer_number", kind: kindInt},
+	"name":            {column: "c.name", kind: kindText},
+	"email":           {column: "c.email", kind: kindText, sealed: "c.email", index: "c.email_index"},
+	"phone":           {column: "COALESCE(c.phone, '')", kind: kindText, sealed: "COALESCE(c.phone, '')"},
+	"birth_date":      {column: "plain_date(c.birth_date)", kind: kindDate, sealed: "c.birth_date"},
+	"created_at":      {column: "c.created_at", kind: kindTimestamp},
+	"updated_at":      {column: "c.updated_at", kind: kindTimestamp},
+	"tag":             {kind: kindTag},
+}
+
+var comparisonOps = map[string]string{
+	"=":  "=",
+	"!=": "IS DISTINCT FROM",
+	"<":  "<",
+	"<=": "<=",
+	">":  ">",
+	">=": ">=",
+}
+
+const tagExists = `EXISTS (SELECT 1 FROM customer_tag ct JOIN tag t ON t.id = ct.tag_id WHERE ct.tenant_id = c.tenant_id AND ct.customer_number = c.customer_number AND t.name %s)`
+
+// Encryption tells which customer fields are stored encrypted, see package
+// encryption.
+type Encryption interface {
+	Encrypts(field string) bool
+	BlindIndex(field, value string) string
+}
+
+// Compile parses and compiles a definition. Fields encrypted by enc can only
+// be compared with =, != and IN, through their blind index, or with an empty
+// string; enc may be nil when nothing is encrypted.
+func Compile(src string, enc Encryption) (Query, error) {
+	node, err := Parse(src)
+	if err != nil {
+		return Query{}, err
+	}
+	c := compiler{enc: enc}
+	where, err := c.compile(node)
+	if err != nil {
+		return Query{}, err
+	}
+	return Query{Where: where, Args: c.args}, nil
+}
+
+type compiler struct {
+	enc  Encryption
+	args []interface{}
+}
+
+func (c *compiler) encrypted(name string, f field) bool {
+	return f.sealed != "" && c.enc != nil && c.enc.Encrypts(name)
+}
+
+func (c *compiler) bind(value interface{}) string {
+	c.args = append(c.args, value)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+func (c *compiler) compile(node Node) (string, error) {
+	switch n := node.(type) {
+	case And:
+		return c.binary(n.Left, "AND", n.Right)
+	case Or:
+		return c.binary(n.Left, "OR", n.Right)
+	case Not:
+		expr, err := c.compile(n.Expr)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + expr, nil
+	case Comparison:
+		return c.comparison(n)
+	case Period:
+		return c.period(n)
+	}
+	return "", fmt.Errorf("segment: unknown node %T", node)
+}
+
+func (c *compiler) binary(left Node, op string, right Node) (string, error) {
+	l, err := c.compile(left)
+	if err != nil {
+		return "", err
+	}
+	r, err := c.compile(right)
+	if err != nil {
+		return "", err
+	}
+	return "(" + l + " " + op + " " + r + ")", nil
+}
+
+func lookupField(pos int, name string) (field, error) {
+	f, ok := fields[name]
+	if !ok {
+		return field{}, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("unknown field %q", name)}
+	}
+	return f, nil
+}
+
+func (c *compiler) comparison(n Comparison) (string, error) {
+	f, err := lookupField(n.Pos, n.Field)
+	if err != nil {
+		return "", err
+	}
+
+	if f.kind == kindTag {
+		return c.tag(n)
+	}
+	if c.encrypted(n.Field, f) {
+		return c.sealed(n, f)
+	}
+
+	column := f.column
+	if f.kind == kindTimestamp {
+		// Timestamps are compared by calendar day.
+		column += "::date"
+	}
+
+	if n.Op == "~" {
+		if f.kind != kindText {
+			return "", &SyntaxError{Pos: n.Pos, Msg: fmt.Sprintf(`operator "~" is not supported for %s`, n.Field)}
+		}
+		return fmt.Sprintf("%s ILIKE %s", column, c.bind("%"+escapeLike(n.Values[0])+"%")), nil
+	}
+
+	var placeholders []string
+	for _, value := range n.Values {
+		arg, err := convert(n, f.kind, value)
+		if err != nil {
+			return "", err
+		}
+		placeholders = append(placeholders, c.bind(arg))
+	}
+	if n.Op == "IN" {
+		return fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")), nil
+	}
+	return fmt.Sprintf("%s %s %s", column, comparisonOps[n.Op], placeholders[0]), nil
+}
+
+func (c *compiler) tag(n Comparison) (string, error) {
+	var placeholders []string
+	for _, value := range n.Values {
+		placeholders = append(placeholders, c.bind(strings.ToLower(value)))
+	}
+	switch n.Op {
+	case "=":
+		return fmt.Sprintf(tagExists, "= "+placeholders[0]), nil
+	case "!=":
+		return "NOT " + fmt.Sprintf(tagExists, "= "+placeholders[0]), nil
+	case "IN":
+		return fmt.Sprintf(tagExists, "IN ("+strings.Join(placeholders, ", ")+")"), nil
+	}
+	return "", &SyntaxError{Pos: n.Pos, Msg: fmt.Sprintf("operator %q is not supported for tag", n.Op)}
+}
+
+// sealed compares an encrypted field. Empty values are stored as they are;
+// others only match through the blind index.
+func (c *compiler) sealed(n Comparison, f field) (string, error) {
+	if (n.Op == "=" || n.Op == "!=") && n.Values[0] == "" {
+		return fmt.Sprintf("%s %s %s", f.sealed, comparisonOps[n.Op], c.bind("")), nil
+	}
+	if f.index == "" {
+		return "", &SyntaxError{Pos: n.Pos, Msg: fmt.Sprintf("%s is encrypted and can only be compared with an empty string", n.Field)}
+	}
+	if n.Op != "=" && n.Op != "!=" && n.Op != "IN" {
+		return "", &SyntaxError{Pos: n.Pos, Msg: fmt.Sprintf("%s is encrypted and only supports =, != and IN", n.Field)}
+	}
+	var placeholders []string
+	for _, value := range n.Values {
+		placeholders = append(placeholders, c.bind(c.enc.BlindIndex(n.Field, value)))
+	}
+	if n.Op == "IN" {
+		return fmt.Sprintf("%s IN (%s)", f.index, strings.Join(placeholders, ", ")), nil
+	}
+	return fmt.Sprintf("%s %s %s", f.index, comparisonOps[n.Op], placeholders[0]), nil
+}
+
+func (c *compiler) period(n Period) (string, error) {
+	f, err := lookupField(n.Pos, n.Field)
+	if err != nil {
+		return "", err
+	}
+	if c.encrypted(n.Field, f) {
+		return "", &SyntaxError{Pos: n.Pos, Msg: fmt.Sprintf("%s is encrypted and cannot be matched by period", n.Field)}
+	}
+	switch f.kind {
+	case kindDate:
+		// Dates such as birth_date recur every year, so a period matches
+		// the anniversary rather than the date itself.
+		switch n.Unit {
+		case "day":
+			return fmt.Sprintf("to_char(%s, 'MM-DD') = to_char(CURRENT_DATE, 'MM-DD')", f.column), nil
+		case "month":
+			return fmt.Sprintf("EXTRACT(MONTH FROM %s) = EXTRACT(MONTH FROM CURRENT_DATE)", f.column), nil
+		}
+		return "", &SyntaxError{Pos: n.Pos, Msg: fmt.Sprintf("%s only supports IN TODAY and IN THIS MONTH", n.Field)}
+	case kindTimestamp:
+		return fmt.Sprintf("date_trunc('%s', %s) = date_trunc('%s', LOCALTIMESTAMP)", n.Unit, f.column, n.Unit), nil
+	}
+	return "", &SyntaxError{Pos: n.Pos, Msg: fmt.Sprintf("%s is not a date field", n.Field)}
+}
+
+func convert(n Comparison, kind fieldKind, value string) (interface{}, error) {
+	switch kind {
+	case kindInt:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, &SyntaxError{Pos: n.Pos, Msg: fmt.Sprintf("%s expects a number but got %q", n.Field, value)}
+		}
+		return i, nil
+	case kindDate, kindTimestamp:
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, &SyntaxError{Pos: n.Pos, Msg: fmt.Sprintf("%s expects a date such as 2006-01-02 but got %q", n.Field, value)}
+		}
+		// Bound as text so Postgres reads it as a plain date, free of any
+		// time zone.
+		return t.Format("2006-01-02"), nil
+	}
+	return value, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
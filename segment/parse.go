@@ -0,0 +1,343 @@
+// Package segment implements the customer segment definition language.
+//
+// A definition is a boolean expression over customer fields and tags:
+//
+//	tag = vip AND birth_date IN THIS MONTH
+//	(tag IN (vip, loyal) OR name ~ "doe") AND NOT tag = churn-risk
+//	created_at >= 2024-01-01 AND phone != ""
+//
+// Comparisons use =, !=, <, <=, >, >=, ~ (contains, case insensitive) and
+// IN with either a list of values or a period (TODAY, THIS WEEK, THIS MONTH,
+// THIS YEAR). Keywords are case insensitive. Values are bare words or quoted
+// strings. Definitions compile to a parameterised SQL condition; no value
+// from the definition is ever spliced into the SQL text.
+package segment
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength bounds the size of a definition.
+const MaxLength = 2000
+
+const maxDepth = 32
+
+// SyntaxError reports an invalid definition. Pos is the byte offset of the
+// offending token.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("segment: %s at position %d", e.Msg, e.Pos+1)
+}
+
+// Node is an expression of the definition language.
+type Node interface {
+	node()
+}
+
+type (
+	And struct {
+		Left, Right Node
+	}
+	Or struct {
+		Left, Right Node
+	}
+	Not struct {
+		Expr Node
+	}
+	// Comparison compares a field with one value, or with a list of values
+	// when Op is IN.
+	Comparison struct {
+		Pos    int
+		Field  string
+		Op     string
+		Values []string
+	}
+	// Period matches a date field against the current day, week, month or
+	// year.
+	Period struct {
+		Pos   int
+		Field string
+		Unit  string
+	}
+)
+
+func (And) node()        {}
+func (Or) node()         {}
+func (Not) node()        {}
+func (Comparison) node() {}
+func (Period) node()     {}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	pos   int
+	upper string
+}
+
+func (t token) is(keyword string) bool {
+	return t.kind == tokenWord && t.upper == keyword
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of definition"
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.@+:", r)
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		r := rune(src[i])
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var value strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, &SyntaxError{Pos: start, Msg: "unterminated string"}
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					value.WriteByte(src[i])
+					continue
+				}
+				if rune(src[i]) == r {
+					i++
+					break
+				}
+				value.WriteByte(src[i])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value.String(), pos: start})
+		case strings.ContainsRune("=!<>~", r):
+			start := i
+			op := string(r)
+			if i+1 < len(src) && src[i+1] == '=' && r != '=' && r != '~' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, &SyntaxError{Pos: start, Msg: `expected "!="`}
+			}
+			i += len(op)
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: start})
+		default:
+			start := i
+			for _, w := range src[i:] {
+				if !isWordRune(w) {
+					break
+				}
+				i += len(string(w))
+			}
+			if i == start {
+				unexpected, _ := utf8.DecodeRuneInString(src[start:])
+				return nil, &SyntaxError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", unexpected)}
+			}
+			word := src[start:i]
+			tokens = append(tokens, token{kind: tokenWord, text: word, upper: strings.ToUpper(word), pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// Parse parses a definition into its syntax tree.
+func Parse(src string) (Node, error) {
+	if len(src) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength, Msg: fmt.Sprintf("definition is longer than %d bytes", MaxLength)}
+	}
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &SyntaxError{Pos: 0, Msg: "definition is empty"}
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected " + t.describe()}
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("AND") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, &SyntaxError{Pos: p.peek().pos, Msg: "definition is nested too deeply"}
+	}
+
+	if p.peek().is("NOT") {
+		p.next()
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, &SyntaxError{Pos: t.pos, Msg: `expected ")" but found ` + t.describe()}
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	field := p.next()
+	if field.kind != tokenWord {
+		return nil, &SyntaxError{Pos: field.pos, Msg: "expected a field but found " + field.describe()}
+	}
+	name := strings.ToLower(field.text)
+
+	op := p.next()
+	switch {
+	case op.kind == tokenOp:
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return Comparison{Pos: field.pos, Field: name, Op: op.text, Values: []string{value}}, nil
+	case op.is("IN"):
+		return p.parseIn(field.pos, name)
+	default:
+		return nil, &SyntaxError{Pos: op.pos, Msg: "expected an operator but found " + op.describe()}
+	}
+}
+
+func (p *parser) parseIn(pos int, field string) (Node, error) {
+	t := p.next()
+	switch {
+	case t.is("TODAY"):
+		return Period{Pos: pos, Field: field, Unit: "day"}, nil
+	case t.is("THIS"):
+		unit := p.next()
+		switch unit.upper {
+		case "WEEK", "MONTH", "YEAR":
+			return Period{Pos: pos, Field: field, Unit: strings.ToLower(unit.upper)}, nil
+		}
+		return nil, &SyntaxError{Pos: unit.pos, Msg: "expected WEEK, MONTH or YEAR but found " + unit.describe()}
+	case t.kind == tokenLParen:
+		var values []string
+		for {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			sep := p.next()
+			if sep.kind == tokenRParen {
+				return Comparison{Pos: pos, Field: field, Op: "IN", Values: values}, nil
+			}
+			if sep.kind != tokenComma {
+				return nil, &SyntaxError{Pos: sep.pos, Msg: `expected "," or ")" but found ` + sep.describe()}
+			}
+		}
+	default:
+		return nil, &SyntaxError{Pos: t.pos, Msg: `expected "(", TODAY or THIS after IN but found ` + t.describe()}
+	}
+}
+
+func (p *parser) parseValue() (string, error) {
+	t := p.next()
+	if t.kind != tokenWord && t.kind != tokenString {
+		return "", &SyntaxError{Pos: t.pos, Msg: "expected a value but found " + t.describe()}
+	}
+	return t.text, nil
+}
@@ -0,0 +1,160 @@
+package segment
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		src   string
+		where string
+		args  []interface{}
+	}{
+		{
+			src:   "tag = VIP",
+			where: "EXISTS (SELECT 1 FROM customer_tag ct JOIN tag t ON t.id = ct.tag_id WHERE ct.tenant_id = c.tenant_id AND ct.customer_number = c.customer_number AND t.name = $1)",
+			args:  []interface{}{"vip"},
+		},
+		{
+			src:   "tag = vip and birth_date in this month",
+			where: "(EXISTS (SELECT 1 FROM customer_tag ct JOIN tag t ON t.id = ct.tag_id WHERE ct.tenant_id = c.tenant_id AND ct.customer_number = c.customer_number AND t.name = $1) AND EXTRACT(MONTH FROM plain_date(c.birth_date)) = EXTRACT(MONTH FROM CURRENT_DATE))",
+			args:  []interface{}{"vip"},
+		},
+		{
+			src:   `name ~ "50%_off" OR NOT customer_number IN (1, 2)`,
+			where: `(c.name ILIKE $1 OR NOT c.customer_number IN ($2, $3))`,
+			args:  []interface{}{`%50\%\_off%`, 1, 2},
+		},
+		{
+			src:   `created_at >= 2024-01-01 AND (phone != '' OR email = 'john.doe@example.com')`,
+			where: `(c.created_at::date >= $1 AND (COALESCE(c.phone, '') IS DISTINCT FROM $2 OR c.email = $3))`,
+			args:  []interface{}{"2024-01-01", "", "john.doe@example.com"},
+		},
+		{
+			src:   "updated_at IN THIS WEEK AND tag != churn-risk",
+			where: "(date_trunc('week', c.updated_at) = date_trunc('week', LOCALTIMESTAMP) AND NOT EXISTS (SELECT 1 FROM customer_tag ct JOIN tag t ON t.id = ct.tag_id WHERE ct.tenant_id = c.tenant_id AND ct.customer_number = c.customer_number AND t.name = $1))",
+			args:  []interface{}{"churn-risk"},
+		},
+	}
+	for _, test := range tests {
+		query, err := Compile(test.src, nil)
+		if err != nil {
+			t.Errorf("Compile(%q) error = %v", test.src, err)
+			continue
+		}
+		if query.Where != test.where {
+			t.Errorf("Compile(%q) where =\n%s\nwant\n%s", test.src, query.Where, test.where)
+		}
+		if !reflect.DeepEqual(query.Args, test.args) {
+			t.Errorf("Compile(%q) args = %#v, want %#v", test.src, query.Args, test.args)
+		}
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	node, err := Parse("tag = a OR tag = b AND NOT tag = c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	or, ok := node.(Or)
+	if !ok {
+		t.Fatalf("Parse() = %T, want Or at the root", node)
+	}
+	and, ok := or.Right.(And)
+	if !ok {
+		t.Fatalf("right operand = %T, want And", or.Right)
+	}
+	if _, ok := and.Right.(Not); !ok {
+		t.Fatalf("right operand of AND = %T, want Not", and.Right)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+		msg string
+	}{
+		{"", 0, "definition is empty"},
+		{"tag", 3, "expected an operator"},
+		{"tag = ", 6, "expected a value"},
+		{"tag = vip AND", 13, "expected a field"},
+		{"(tag = vip", 10, `expected ")"`},
+		{"tag = vip)", 9, "unexpected"},
+		{`name = "doe`, 7, "unterminated string"},
+		{"name ! doe", 5, `expected "!="`},
+		{"age = 30", 0, `unknown field "age"`},
+		{"customer_number = one", 0, "expects a number"},
+		{"birth_date < 1990-13-01", 0, "expects a date"},
+		{"birth_date IN THIS WEEK", 0, "only supports"},
+		{"name IN TODAY", 0, "not a date field"},
+		{"tag < vip", 0, "not supported for tag"},
+		{"birth_date ~ 1990", 0, "not supported for birth_date"},
+		{"name = a; DROP TABLE customer", 8, "unexpected character"},
+		{strings.Repeat("(", 40) + "tag = vip" + strings.Repeat(")", 40), 32, "nested too deeply"},
+		{strings.Repeat("x", MaxLength+1), MaxLength, "longer than"},
+	}
+	for _, test := range tests {
+		_, err := Compile(test.src, nil)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Compile(%.20q) error = %v, want *SyntaxError", test.src, err)
+			continue
+		}
+		if syntaxErr.Pos != test.pos || !strings.Contains(syntaxErr.Msg, test.msg) {
+			t.Errorf("Compile(%.20q) error = %v (pos %d), want %q at %d", test.src, err, syntaxErr.Pos, test.msg, test.pos)
+		}
+	}
+}
+
+// fakeEncryption encrypts every field and indexes values by upper-casing them.
+type fakeEncryption struct{}
+
+func (fakeEncryption) Encrypts(field string) bool { return true }
+
+func (fakeEncryption) BlindIndex(field, value string) string {
+	return field + ":" + strings.ToUpper(value)
+}
+
+func TestCompileEncrypted(t *testing.T) {
+	tests := []struct {
+		src   string
+		where string
+		args  []interface{}
+	}{
+		{
+			src:   `email = "john@example.com" OR email IN (a@b.c, d@e.f)`,
+			where: `(c.email_index = $1 OR c.email_index IN ($2, $3))`,
+			args:  []interface{}{"email:JOHN@EXAMPLE.COM", "email:A@B.C", "email:D@E.F"},
+		},
+		{
+			src:   `phone != "" AND NOT name ~ doe`,
+			where: `(COALESCE(c.phone, '') IS DISTINCT FROM $1 AND NOT c.name ILIKE $2)`,
+			args:  []interface{}{"", "%doe%"},
+		},
+	}
+	for _, test := range tests {
+		query, err := Compile(test.src, fakeEncryption{})
+		if err != nil {
+			t.Errorf("Compile(%q) error = %v", test.src, err)
+			continue
+		}
+		if query.Where != test.where {
+			t.Errorf("Compile(%q) where =\n%s\nwant\n%s", test.src, query.Where, test.where)
+		}
+		if !reflect.DeepEqual(query.Args, test.args) {
+			t.Errorf("Compile(%q) args = %#v, want %#v", test.src, query.Args, test.args)
+		}
+	}
+
+	for _, src := range []string{"email ~ example.com", "phone = 0812", "birth_date >= 1990-01-01", "birth_date IN THIS MONTH"} {
+		_, err := Compile(src, fakeEncryption{})
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || !strings.Contains(syntaxErr.Msg, "encrypted") {
+			t.Errorf("Compile(%q) error = %v, want it to mention encryption", src, err)
+		}
+	}
+}
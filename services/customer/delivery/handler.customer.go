@@ -1,7 +1,15 @@
 package delivery_customer
 
 import (
+	"customer-playground/apiversion"
+	"customer-playground/content"
+	"customer-playground/customfield"
 	"customer-playground/domain"
+	"customer-playground/pii"
+	"customer-playground/types"
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -17,63 +25,111 @@ type CustomerHandler struct {
 func NewCustomerHandler(r *gin.Engine, c domain.CustomerUseCase, l *logrus.Logger) *gin.Engine {
 	handler := &CustomerHandler{customerUseCase: c, logger: l}
 
-	r.GET("/customer", handler.HandlerGetAllCustomer)
-	r.GET("/customer/:customer_number", handler.HandlerGetCustomerByNumber)
-	r.POST("/customer", handler.HandlerInsertCustomer)
-	r.PUT("/customer", handler.HandlerUpdateCustomer)
-	r.DELETE("/customer/:customer_number", handler.HandlerDeleteCustomerByNumber)
+	v1 := r.Group(apiversion.V1)
+	v1.GET("/customers", handler.HandlerListCustomers)
+	v1.POST("/customers", handler.HandlerCreateCustomer)
+	v1.GET("/customers/:number", handler.HandlerGetCustomer)
+	v1.PUT("/customers/:number", handler.HandlerReplaceCustomer)
+	v1.DELETE("/customers/:number", handler.HandlerDeleteCustomer)
+
+	// Deprecated aliases of the routes above, removed at apiversion.LegacySunset
+	successor := apiversion.V1 + "/customers/:customer_number"
+	r.GET("/customer", apiversion.Deprecated(apiversion.V1+"/customers"), handler.HandlerGetAllCustomer)
+	r.GET("/customer/:customer_number", apiversion.Deprecated(successor), handler.HandlerGetCustomerByNumber)
+	r.POST("/customer", apiversion.Deprecated(apiversion.V1+"/customers"), handler.HandlerInsertCustomer)
+	r.PUT("/customer", apiversion.Deprecated(successor), handler.HandlerUpdateCustomer)
+	r.DELETE("/customer/:customer_number", apiversion.Deprecated(successor), handler.HandlerDeleteCustomerByNumber)
 
 	return r
 }
 
 // HandlerGetAllCustomer godoc
 // @Summary Get all customers
-// @Description Retrieves all customers
-// @Tags customers
-// @Produce json
+// @Description Retrieves all customers. Filter by custom fields with cf[name]=value, e.g. cf[preferred_language]=en. Email, phone and birth date are masked unless the caller holds pii:read.
+// @Tags customer
+// @Deprecated
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param email query string false "Only the customer with this email, regardless of case"
+// @Param cf[name] query string false "Only customers whose custom field name has this value"
 // @Success 200 {array} domain.Customer
+// @Failure 400 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
 // @Router /customer [get]
 func (c *CustomerHandler) HandlerGetAllCustomer(ctx *gin.Context) {
-	customers, err := c.customerUseCase.GetAll(ctx)
+	c.list(ctx)
+}
+
+func (c *CustomerHandler) list(ctx *gin.Context) {
+	filter := domain.CustomerFilter{Email: ctx.Query("email")}
+	for name, value := range ctx.QueryMap("cf") {
+		if filter.CustomFields == nil {
+			filter.CustomFields = types.JSONMap{}
+		}
+		filter.CustomFields[name] = value
+	}
+	customers, err := c.customerUseCase.GetAll(filter, ctx)
+	var validationErr *customfield.ValidationError
+	if errors.As(err, &validationErr) {
+		content.Render(ctx, http.StatusBadRequest, domain.Response{Message: err.Error(), StatusCode: 400})
+		return
+	}
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerHandler/HandlerGetAllCustomer", err)
+		c.logger.Errorf("%s : %v", "CustomerHandler/list", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, customers)
+	content.Render(ctx, http.StatusOK, pii.Customers(ctx, customers))
 	return
 }
 
 // HandlerGetCustomerByNumber godoc
 // @Summary Get customer by number
-// @Description Retrieves a customer by their customer number
-// @Tags customers
-// @Produce json
+// @Description Retrieves a customer by their customer number. The number of a merged customer redirects to the customer it was merged into. Email, phone and birth date are masked unless the caller holds pii:read.
+// @Tags customer
+// @Deprecated
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
 // @Param customer_number path int true "Customer Number"
 // @Success 200 {object} domain.Customer
+// @Success 301 "Customer was merged, see Location"
+// @Failure 404 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
 // @Router /customer/{customer_number} [get]
 func (c *CustomerHandler) HandlerGetCustomerByNumber(ctx *gin.Context) {
-	customerNumber, err := strconv.Atoi(ctx.Param("customer_number"))
+	customerNumber, _ := strconv.Atoi(ctx.Param("customer_number"))
+	c.get(ctx, customerNumber, "/customer/%d")
+}
+
+// get responds with a customer, or redirects to location, a format for the
+// number of the customer it was merged into.
+func (c *CustomerHandler) get(ctx *gin.Context, customerNumber int, location string) {
 	customer, err := c.customerUseCase.GetByCustomerNumber(customerNumber, ctx)
+	var moved *domain.CustomerMovedError
+	if errors.As(err, &moved) {
+		ctx.Redirect(http.StatusMovedPermanently, fmt.Sprintf(location, moved.MovedTo))
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.notFound(ctx, customerNumber)
+		return
+	}
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerHandler/HandlerGetCustomerByNumber", err)
+		c.logger.Errorf("%s : %v", "CustomerHandler/get", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, customer)
+	content.Render(ctx, http.StatusOK, pii.Customer(ctx, customer))
 	return
 }
 
 // HandlerInsertCustomer godoc
 // @Summary Insert new customer
 // @Description Adds a new customer to the database
-// @Tags customers
-// @Accept json
-// @Produce json
+// @Tags customer
+// @Deprecated
+// @Accept json,application/xml,application/msgpack,application/x-protobuf
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
 // @Param customer body domain.Customer true "Customer payload"
 // @Success 200 {object} domain.Response
 // @Failure 400 {object} domain.ErrorResponse
@@ -81,79 +137,109 @@ func (c *CustomerHandler) HandlerGetCustomerByNumber(ctx *gin.Context) {
 // @Router /customer [post]
 func (c *CustomerHandler) HandlerInsertCustomer(ctx *gin.Context) {
 	var customer domain.Customer
-	err := ctx.Bind(&customer)
+	err := content.Bind(ctx, &customer)
 	if err != nil {
 		c.logger.Errorf("%s : %v", "CustomerHandler/HandlerInsertCustomer/ParseBodyData", err)
 		ctx.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
-	message, err := c.customerUseCase.Insert(&customer, ctx)
+	c.insert(ctx, &customer)
+}
+
+func (c *CustomerHandler) insert(ctx *gin.Context, customer *domain.Customer) {
+	message, err := c.customerUseCase.Insert(customer, ctx)
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerHandler/HandlerInsertCustomer/Insert", err)
+		c.logger.Errorf("%s : %v", "CustomerHandler/insert", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
 	}
 	if message.StatusCode != 200 {
-		ctx.JSON(http.StatusBadRequest, message)
+		content.Render(ctx, http.StatusBadRequest, message)
 		return
 	}
-	ctx.JSON(http.StatusOK, message)
+	content.Render(ctx, http.StatusOK, message)
 	return
 }
 
 // HandlerUpdateCustomer godoc
 // @Summary Update customer
 // @Description Updates an existing customer by customer number or ID
-// @Tags customers
-// @Accept json
-// @Produce json
+// @Tags customer
+// @Deprecated
+// @Accept json,application/xml,application/msgpack,application/x-protobuf
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
 // @Param customer body domain.Customer true "Customer payload"
 // @Success 200 {object} domain.Response
 // @Failure 400 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
 // @Router /customer [put]
 func (c *CustomerHandler) HandlerUpdateCustomer(ctx *gin.Context) {
 	var customer domain.Customer
-	err := ctx.Bind(&customer)
+	err := content.Bind(ctx, &customer)
 	if err != nil {
 		c.logger.Errorf("%s : %v", "CustomerHandler/HandlerUpdateCustomer/ParseBodyData", err)
 		ctx.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
-	message, err := c.customerUseCase.Update(&customer, ctx)
+	c.update(ctx, &customer)
+}
+
+func (c *CustomerHandler) update(ctx *gin.Context, customer *domain.Customer) {
+	message, err := c.customerUseCase.Update(customer, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.notFound(ctx, customer.CustomerNumber)
+		return
+	}
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerHandler/HandlerUpdateCustomer/Update", err)
+		c.logger.Errorf("%s : %v", "CustomerHandler/update", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
 	}
 	if message.StatusCode != 200 {
-		ctx.JSON(http.StatusBadRequest, message)
+		content.Render(ctx, http.StatusBadRequest, message)
 		return
 	}
-	ctx.JSON(http.StatusOK, message)
+	content.Render(ctx, http.StatusOK, message)
 	return
 }
 
 // HandlerDeleteCustomerByNumber godoc
 // @Summary Delete customer by number
 // @Description Deletes a customer based on customer number
-// @Tags customers
-// @Produce json
+// @Tags customer
+// @Deprecated
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
 // @Param customer_number path int true "Customer Number"
 // @Success 200 {object} domain.Response
 // @Failure 400 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
 // @Router /customer/{customer_number} [delete]
 func (c *CustomerHandler) HandlerDeleteCustomerByNumber(ctx *gin.Context) {
-	customerNumber, err := strconv.Atoi(ctx.Param("customer_number"))
+	customerNumber, _ := strconv.Atoi(ctx.Param("customer_number"))
+	c.delete(ctx, customerNumber)
+}
+
+func (c *CustomerHandler) delete(ctx *gin.Context, customerNumber int) {
 	message, err := c.customerUseCase.DeleteByCustomerNumber(customerNumber, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.notFound(ctx, customerNumber)
+		return
+	}
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerHandler/HandlerDeleteCustomerByNumber/Delete", err)
+		c.logger.Errorf("%s : %v", "CustomerHandler/delete", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 	if message.StatusCode != 200 {
-		ctx.JSON(http.StatusBadRequest, message)
+		content.Render(ctx, http.StatusBadRequest, message)
 		return
 	}
-	ctx.JSON(http.StatusOK, message)
+	content.Render(ctx, http.StatusOK, message)
 	return
 }
+
+func (c *CustomerHandler) notFound(ctx *gin.Context, customerNumber int) {
+	content.Render(ctx, http.StatusNotFound, domain.ErrorResponse{Message: fmt.Sprintf("No customer found with customer_number %d", customerNumber)})
+}
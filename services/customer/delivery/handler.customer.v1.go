@@ -0,0 +1,133 @@
+package delivery_customer
+
+import (
+	"customer-playground/apiversion"
+	"customer-playground/content"
+	"customer-playground/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// customerNumber reads the number of the customer of a /api/v1 route.
+func (c *CustomerHandler) customerNumber(ctx *gin.Context) (int, bool) {
+	customerNumber, err := strconv.Atoi(ctx.Param("number"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerHandler/customerNumber", err)
+		content.Render(ctx, http.StatusBadRequest, domain.Response{Message: "customer number must be a number", StatusCode: 400})
+		return 0, false
+	}
+	return customerNumber, true
+}
+
+// HandlerListCustomers godoc
+// @Summary List customers
+// @Description Retrieves all customers. Filter by custom fields with cf[name]=value, e.g. cf[preferred_language]=en. Email, phone and birth date are masked unless the caller holds pii:read.
+// @Tags customers
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param email query string false "Only the customer with this email, regardless of case"
+// @Param cf[name] query string false "Only customers whose custom field name has this value"
+// @Success 200 {array} domain.Customer
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /api/v1/customers [get]
+func (c *CustomerHandler) HandlerListCustomers(ctx *gin.Context) {
+	c.list(ctx)
+}
+
+// HandlerCreateCustomer godoc
+// @Summary Create a customer
+// @Description Adds a new customer. The customer number is assigned by the server.
+// @Tags customers
+// @Accept json,application/xml,application/msgpack,application/x-protobuf
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param customer body domain.Customer true "Customer payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /api/v1/customers [post]
+func (c *CustomerHandler) HandlerCreateCustomer(ctx *gin.Context) {
+	var customer domain.Customer
+	err := content.Bind(ctx, &customer)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerHandler/HandlerCreateCustomer/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	customer.CustomerNumber = 0
+	c.insert(ctx, &customer)
+}
+
+// HandlerGetCustomer godoc
+// @Summary Get a customer
+// @Description Retrieves a customer by their customer number. The number of a merged customer redirects to the customer it was merged into. Email, phone and birth date are masked unless the caller holds pii:read.
+// @Tags customers
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param number path int true "Customer Number"
+// @Success 200 {object} domain.Customer
+// @Success 301 "Customer was merged, see Location"
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /api/v1/customers/{number} [get]
+func (c *CustomerHandler) HandlerGetCustomer(ctx *gin.Context) {
+	customerNumber, ok := c.customerNumber(ctx)
+	if !ok {
+		return
+	}
+	c.get(ctx, customerNumber, apiversion.V1+"/customers/%d")
+}
+
+// HandlerReplaceCustomer godoc
+// @Summary Update a customer
+// @Description Updates the customer with the number in the path. A customer_number in the body must be the same or left out.
+// @Tags customers
+// @Accept json,application/xml,application/msgpack,application/x-protobuf
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param number path int true "Customer Number"
+// @Param customer body domain.Customer true "Customer payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /api/v1/customers/{number} [put]
+func (c *CustomerHandler) HandlerReplaceCustomer(ctx *gin.Context) {
+	customerNumber, ok := c.customerNumber(ctx)
+	if !ok {
+		return
+	}
+	var customer domain.Customer
+	err := content.Bind(ctx, &customer)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerHandler/HandlerReplaceCustomer/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if customer.CustomerNumber != 0 && customer.CustomerNumber != customerNumber {
+		content.Render(ctx, http.StatusBadRequest, domain.Response{Message: fmt.Sprintf("customer_number %d does not match the path", customer.CustomerNumber), StatusCode: 400})
+		return
+	}
+	customer.CustomerNumber = customerNumber
+	c.update(ctx, &customer)
+}
+
+// HandlerDeleteCustomer godoc
+// @Summary Delete a customer
+// @Description Deletes the customer with the number in the path
+// @Tags customers
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param number path int true "Customer Number"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /api/v1/customers/{number} [delete]
+func (c *CustomerHandler) HandlerDeleteCustomer(ctx *gin.Context) {
+	customerNumber, ok := c.customerNumber(ctx)
+	if !ok {
+		return
+	}
+	c.delete(ctx, customerNumber)
+}
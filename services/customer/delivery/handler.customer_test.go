@@ -0,0 +1,324 @@
+package delivery_customer
+
+import (
+	"context"
+	"customer-playground/content"
+	"customer-playground/content/pb"
+	"customer-playground/database"
+	"customer-playground/database/pgtest"
+	"customer-playground/domain"
+	"customer-playground/encryption"
+	repository_customer "customer-playground/services/customer/repository"
+	usecase_customer "customer-playground/services/customer/usecase"
+	repository_customfield "customer-playground/services/customfield/repository"
+	"customer-playground/tenant"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+// newRouter serves the customer routes backed by the test database. Requests
+// are scoped to the default tenant and may read personal data unless they
+// carry X-Masked.
+func newRouter(t *testing.T) *gin.Engine {
+	db := pgtest.Open(t)
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	keyfile, err := encryption.OpenKeyfile(filepath.Join(t.TempDir(), "keys.json"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := encryption.NewCipher(context.Background(), keyfile, encryption.Fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customerRepository := repository_customer.NewCustomerRepository(db, database.DefaultRetryPolicy, cipher, logger)
+	customFieldRepository := repository_customfield.NewCustomFieldRepository(db, database.DefaultRetryPolicy, logger)
+	t.Cleanup(func() {
+		customerRepository.(io.Closer).Close()
+		customFieldRepository.(io.Closer).Close()
+	})
+	return route(usecase_customer.NewCustomerUseCase(customerRepository, customFieldRepository, logger), logger)
+}
+
+type stubCustomFieldRepository struct {
+	domain.CustomFieldRepository
+}
+
+func (s stubCustomFieldRepository) GetAll(ctx context.Context) ([]domain.CustomFieldDefinition, error) {
+	return nil, nil
+}
+
+// newMemoryRouter serves the customer routes backed by an in-memory
+// repository without custom fields, for tests of the routes themselves.
+func newMemoryRouter() *gin.Engine {
+	logger := logrus.New()
+	logger.Out = io.Discard
+	return route(usecase_customer.NewCustomerUseCase(repository_customer.NewMemoryCustomerRepository(), stubCustomFieldRepository{}, logger), logger)
+}
+
+func route(customerUseCase domain.CustomerUseCase, logger *logrus.Logger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.ContextWithFallback = true
+	r.Use(func(ctx *gin.Context) {
+		requestCtx := tenant.WithTenant(ctx.Request.Context(), pgtest.DefaultTenant)
+		if ctx.GetHeader("X-Masked") == "" {
+			requestCtx = domain.WithPermissions(requestCtx, []string{domain.PermissionReadPII})
+		}
+		ctx.Request = ctx.Request.WithContext(requestCtx)
+		ctx.Next()
+	})
+	return NewCustomerHandler(r, customerUseCase, logger)
+}
+
+func serve(r *gin.Engine, method string, path string, body string, headers ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for _, header := range headers {
+		request.Header.Set(header, "1")
+	}
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func decode(t *testing.T, recorder *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %s: %v", recorder.Body, err)
+	}
+}
+
+func TestCustomerHandlerCRUD(t *testing.T) {
+	r := newRouter(t)
+
+	recorder := serve(r, http.MethodPost, "/customer", `{"name": "John Doe", "email": "john.doe@example.com", "phone": "08123456789", "birth_date": "1990-01-02T00:00:00Z"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("POST /customer = %d %s", recorder.Code, recorder.Body)
+	}
+
+	var customers []domain.Customer
+	recorder = serve(r, http.MethodGet, "/customer", "")
+	decode(t, recorder, &customers)
+	if recorder.Code != http.StatusOK || len(customers) != 1 || customers[0].Email != "john.doe@example.com" {
+		t.Fatalf("GET /customer = %d %s", recorder.Code, recorder.Body)
+	}
+	path := fmt.Sprintf("/customer/%d", customers[0].CustomerNumber)
+
+	// Fields left out of an update keep their value.
+	body := fmt.Sprintf(`{"customer_number": %d, "name": "John Q. Doe"}`, customers[0].CustomerNumber)
+	if recorder := serve(r, http.MethodPut, "/customer", body); recorder.Code != http.StatusOK {
+		t.Fatalf("PUT /customer = %d %s", recorder.Code, recorder.Body)
+	}
+	var customer domain.Customer
+	recorder = serve(r, http.MethodGet, path, "")
+	decode(t, recorder, &customer)
+	if customer.Name != "John Q. Doe" || customer.Email != "john.doe@example.com" || customer.Phone != "08123456789" || !customer.BirthDate.Valid {
+		t.Errorf("GET %s = %s", path, recorder.Body)
+	}
+
+	var masked domain.Customer
+	recorder = serve(r, http.MethodGet, path, "", "X-Masked")
+	decode(t, recorder, &masked)
+	if masked.Email != "j***@example.com" || masked.Phone != "********789" || masked.BirthDate.Valid {
+		t.Errorf("GET %s without pii:read = %s", path, recorder.Body)
+	}
+
+	if recorder := serve(r, http.MethodDelete, path, ""); recorder.Code != http.StatusOK {
+		t.Fatalf("DELETE %s = %d %s", path, recorder.Code, recorder.Body)
+	}
+	recorder = serve(r, http.MethodGet, "/customer", "")
+	decode(t, recorder, &customers)
+	if recorder.Code != http.StatusOK || len(customers) != 0 {
+		t.Errorf("GET /customer after delete = %d %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestCustomerHandlerUniqueEmail(t *testing.T) {
+	r := newRouter(t)
+
+	if recorder := serve(r, http.MethodPost, "/customer", `{"name": "John Doe", "email": "john.doe@example.com"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("POST /customer = %d %s", recorder.Code, recorder.Body)
+	}
+	recorder := serve(r, http.MethodPost, "/customer", `{"name": "Johnny Doe", "email": "JOHN.DOE@example.com"}`)
+	var message domain.Response
+	decode(t, recorder, &message)
+	if recorder.Code != http.StatusBadRequest || message.StatusCode != 400 {
+		t.Errorf("POST /customer with a taken email = %d %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestCustomerHandlerNotFound(t *testing.T) {
+	r := newRouter(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{http.MethodGet, "/customer/42", "", http.StatusNotFound},
+		{http.MethodPut, "/customer", `{"customer_number": 42, "name": "Nobody"}`, http.StatusNotFound},
+		{http.MethodDelete, "/customer/42", "", http.StatusNotFound},
+		{http.MethodPost, "/customer", `{"name": `, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if recorder := serve(r, test.method, test.path, test.body); recorder.Code != test.want {
+			t.Errorf("%s %s = %d %s, want %d", test.method, test.path, recorder.Code, recorder.Body, test.want)
+		}
+	}
+}
+
+func TestCustomerHandlerV1(t *testing.T) {
+	r := newMemoryRouter()
+
+	// The customer number is assigned by the server.
+	recorder := serve(r, http.MethodPost, "/api/v1/customers", `{"customer_number": 42, "name": "John Doe", "email": "john.doe@example.com"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("POST /api/v1/customers = %d %s", recorder.Code, recorder.Body)
+	}
+	var customers []domain.Customer
+	recorder = serve(r, http.MethodGet, "/api/v1/customers", "")
+	decode(t, recorder, &customers)
+	if recorder.Code != http.StatusOK || len(customers) != 1 || customers[0].CustomerNumber == 42 {
+		t.Fatalf("GET /api/v1/customers = %d %s", recorder.Code, recorder.Body)
+	}
+	path := fmt.Sprintf("/api/v1/customers/%d", customers[0].CustomerNumber)
+
+	// The number is taken from the path.
+	if recorder := serve(r, http.MethodPut, path, `{"name": "John Q. Doe"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("PUT %s = %d %s", path, recorder.Code, recorder.Body)
+	}
+	var customer domain.Customer
+	recorder = serve(r, http.MethodGet, path, "")
+	decode(t, recorder, &customer)
+	if recorder.Code != http.StatusOK || customer.Name != "John Q. Doe" || customer.Email != "john.doe@example.com" {
+		t.Errorf("GET %s = %d %s", path, recorder.Code, recorder.Body)
+	}
+	if recorder.Header().Get("Deprecation") != "" {
+		t.Errorf("GET %s is deprecated", path)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{http.MethodPut, path, `{"customer_number": 42, "name": "Nobody"}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/customers/john", "", http.StatusBadRequest},
+		{http.MethodDelete, "/api/v1/customers/john", "", http.StatusBadRequest},
+		{http.MethodDelete, path, "", http.StatusOK},
+		{http.MethodGet, path, "", http.StatusNotFound},
+		{http.MethodPut, path, `{"name": "Nobody"}`, http.StatusNotFound},
+		{http.MethodDelete, path, "", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		if recorder := serve(r, test.method, test.path, test.body); recorder.Code != test.want {
+			t.Errorf("%s %s = %d %s, want %d", test.method, test.path, recorder.Code, recorder.Body, test.want)
+		}
+	}
+
+	var notFound domain.ErrorResponse
+	recorder = serve(r, http.MethodGet, path, "")
+	decode(t, recorder, &notFound)
+	if want := fmt.Sprintf("No customer found with customer_number %d", customers[0].CustomerNumber); notFound.Message != want {
+		t.Errorf("GET %s = %s, want message %q", path, recorder.Body, want)
+	}
+}
+
+func TestCustomerHandlerLegacyRoutes(t *testing.T) {
+	r := newMemoryRouter()
+
+	if recorder := serve(r, http.MethodPost, "/customer", `{"name": "John Doe", "email": "john.doe@example.com"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("POST /customer = %d %s", recorder.Code, recorder.Body)
+	}
+	var customers []domain.Customer
+	recorder := serve(r, http.MethodGet, "/customer", "")
+	decode(t, recorder, &customers)
+	if recorder.Code != http.StatusOK || len(customers) != 1 {
+		t.Fatalf("GET /customer = %d %s", recorder.Code, recorder.Body)
+	}
+
+	recorder = serve(r, http.MethodGet, fmt.Sprintf("/customer/%d", customers[0].CustomerNumber), "")
+	header := recorder.Header()
+	if recorder.Code != http.StatusOK || header.Get("Deprecation") == "" || header.Get("Sunset") == "" {
+		t.Errorf("GET /customer/%d = %d %v", customers[0].CustomerNumber, recorder.Code, header)
+	}
+	if want := fmt.Sprintf(`</api/v1/customers/%d>; rel="successor-version"`, customers[0].CustomerNumber); header.Get("Link") != want {
+		t.Errorf("Link = %q, want %q", header.Get("Link"), want)
+	}
+}
+
+func TestCustomerHandlerContentNegotiation(t *testing.T) {
+	r := newMemoryRouter()
+	send := func(method string, path string, contentType string, accept string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+		request.Header.Set("Accept", accept)
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send(http.MethodPost, "/api/v1/customers", content.MIMEXML, content.MIMEXML,
+		`<customer><name>John Doe</name><email>john.doe@example.com</email><birth_date>1990-01-02T00:00:00Z</birth_date></customer>`)
+	var message domain.Response
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &message); recorder.Code != http.StatusOK || err != nil || message.StatusCode != 200 {
+		t.Fatalf("POST XML = %d %s", recorder.Code, recorder.Body)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/xml; charset=utf-8" {
+		t.Errorf("Content-Type = %s", contentType)
+	}
+
+	birthDate := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	recorder = send(http.MethodGet, "/api/v1/customers", "", content.MIMEMsgPack, "")
+	var customers []domain.Customer
+	if err := codec.NewDecoderBytes(recorder.Body.Bytes(), &codec.MsgpackHandle{}).Decode(&customers); err != nil || len(customers) != 1 {
+		t.Fatalf("GET MessagePack = %d %v %v", recorder.Code, customers, err)
+	}
+	if customer := customers[0]; !customer.BirthDate.Time.Equal(birthDate) || !customer.CreatedAt.Valid {
+		t.Errorf("GET MessagePack = %+v", customer)
+	}
+	if vary := recorder.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("Vary = %q", vary)
+	}
+
+	path := fmt.Sprintf("/api/v1/customers/%d", customers[0].CustomerNumber)
+	update, _ := proto.Marshal(&pb.Customer{Name: "John Q. Doe"})
+	if recorder := send(http.MethodPut, path, content.MIMEProtobuf, content.MIMEJSON, string(update)); recorder.Code != http.StatusOK {
+		t.Fatalf("PUT protobuf = %d %s", recorder.Code, recorder.Body)
+	}
+	recorder = send(http.MethodGet, path, "", content.MIMEProtobuf, "")
+	var customer pb.Customer
+	if err := proto.Unmarshal(recorder.Body.Bytes(), &customer); err != nil || customer.Name != "John Q. Doe" || !customer.BirthDate.AsTime().Equal(birthDate) {
+		t.Errorf("GET protobuf = %d %v %v", recorder.Code, &customer, err)
+	}
+
+	if recorder := send(http.MethodPost, "/api/v1/customers", "text/csv", content.MIMEJSON, "John Doe"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("POST CSV = %d, want 400", recorder.Code)
+	}
+}
@@ -0,0 +1,210 @@
+package repository_customer
+
+import (
+	"bytes"
+	"context"
+	"customer-playground/cache"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"customer-playground/encryption"
+	"customer-playground/types"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// cachedCustomerRepository is a read-through cache in front of another
+// CustomerRepository. Lookups by customer number are cached for ttl and
+// concurrent misses for the same customer share one database query. The
+// fields the cipher encrypts are cached sealed, so a shared backend such as
+// Redis never holds them in plaintext.
+type cachedCustomerRepository struct {
+	next    domain.CustomerRepository
+	backend cache.Backend
+	ttl     time.Duration
+	cipher  *encryption.Cipher
+	group   *singleflight.Group
+	metrics *cache.Metrics
+	logger  *logrus.Logger
+	// generations count invalidations per key, striped by hash, so a load
+	// can tell that the customer changed while it was reading it.
+	generations *[256]atomic.Uint64
+}
+
+func (c cachedCustomerRepository) GetAll(filter domain.CustomerFilter, ctx context.Context) ([]domain.Customer, error) {
+	return c.next.GetAll(filter, ctx)
+}
+
+func (c cachedCustomerRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) (domain.Customer, error) {
+	key := customerCacheKey(customerNumber, ctx)
+
+	value, found, err := c.backend.Get(ctx, key)
+	if err != nil {
+		c.metrics.Error()
+		c.logger.Errorf("failed to read cache: %v", err)
+	}
+	if found {
+		customer, err := c.decode(ctx, value)
+		if err == nil {
+			c.metrics.Hit()
+			return customer, nil
+		}
+		c.metrics.Error()
+		c.logger.Errorf("failed to decode cached customer: %v", err)
+	}
+	c.metrics.Miss()
+
+	// The shared load must not fail because the request that started it went
+	// away while other requests are still waiting for it.
+	loadCtx := context.WithoutCancel(ctx)
+	result, err, _ := c.group.Do(key, func() (interface{}, error) {
+		generation := c.generation(key)
+		before := generation.Load()
+		customer, err := c.next.GetByCustomerNumber(customerNumber, loadCtx)
+		if err != nil {
+			return domain.Customer{}, err
+		}
+		if generation.Load() != before {
+			return customer, nil
+		}
+
+		value, err := c.encode(loadCtx, customer)
+		if err != nil {
+			c.metrics.Error()
+			c.logger.Errorf("failed to encode cached customer: %v", err)
+			return customer, nil
+		}
+		if err := c.backend.Set(loadCtx, key, value, c.ttl); err != nil {
+			c.metrics.Error()
+			c.logger.Errorf("failed to write cache: %v", err)
+		}
+		// An invalidation between the check above and the write may have
+		// deleted the key before the stale value was written.
+		if generation.Load() != before {
+			c.delete(loadCtx, key)
+		}
+		return customer, nil
+	})
+	if err != nil {
+		return domain.Customer{}, err
+	}
+	return result.(domain.Customer), nil
+}
+
+func (c cachedCustomerRepository) Insert(customer *domain.Customer, ctx context.Context) (domain.Response, error) {
+	defer c.Invalidate(customer.CustomerNumber, ctx)
+	return c.next.Insert(customer, ctx)
+}
+
+func (c cachedCustomerRepository) Update(customer *domain.Customer, ctx context.Context) (domain.Response, error) {
+	defer c.Invalidate(customer.CustomerNumber, ctx)
+	return c.next.Update(customer, ctx)
+}
+
+func (c cachedCustomerRepository) DeleteByCustomerNumber(customerNumber int, ctx context.Context) (domain.Response, error) {
+	defer c.Invalidate(customerNumber, ctx)
+	return c.next.DeleteByCustomerNumber(customerNumber, ctx)
+}
+
+func (c cachedCustomerRepository) GetRedirect(customerNumber int, ctx context.Context) (int, error) {
+	return c.next.GetRedirect(customerNumber, ctx)
+}
+
+// ReEncrypt passes on to the wrapped repository. Cached customers name the
+// key they were sealed with, so they stay readable after a rotation.
+func (c cachedCustomerRepository) ReEncrypt(batchSize int, ctx context.Context) (int, error) {
+	if reEncrypter, ok := c.next.(domain.ReEncrypter); ok {
+		return reEncrypter.ReEncrypt(batchSize, ctx)
+	}
+	return 0, nil
+}
+
+// Invalidate drops the cached copy of a customer. Loads that started before
+// it do not cache what they read.
+func (c cachedCustomerRepository) Invalidate(customerNumber int, ctx context.Context) {
+	key := customerCacheKey(customerNumber, ctx)
+	c.generation(key).Add(1)
+	c.group.Forget(key)
+	c.delete(context.WithoutCancel(ctx), key)
+}
+
+func (c cachedCustomerRepository) delete(ctx context.Context, key string) {
+	if err := c.backend.Delete(ctx, key); err != nil {
+		c.metrics.Error()
+		c.logger.Errorf("failed to invalidate cache: %v", err)
+	}
+}
+
+// generation returns the invalidation counter of key. Keys sharing a counter
+// only skip caching more often.
+func (c cachedCustomerRepository) generation(key string) *atomic.Uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &c.generations[h.Sum32()%uint32(len(c.generations))]
+}
+
+// Close closes the wrapped repository.
+func (c cachedCustomerRepository) Close() error {
+	if closer, ok := c.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// cachedCustomer is the cached form of a customer. Customer has the sealed
+// fields cleared; Stored holds them.
+type cachedCustomer struct {
+	Customer domain.Customer
+	Stored   encryption.StoredCustomer
+}
+
+func (c cachedCustomerRepository) encode(ctx context.Context, customer domain.Customer) ([]byte, error) {
+	stored, err := c.cipher.SealCustomer(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	customer.Email, customer.Phone, customer.BirthDate = "", "", types.NullTime{}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cachedCustomer{Customer: customer, Stored: stored}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c cachedCustomerRepository) decode(ctx context.Context, value []byte) (domain.Customer, error) {
+	var cached cachedCustomer
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&cached); err != nil {
+		return domain.Customer{}, err
+	}
+	if err := c.cipher.OpenCustomer(ctx, cached.Stored, &cached.Customer); err != nil {
+		return domain.Customer{}, err
+	}
+	return cached.Customer, nil
+}
+
+// customerCacheKey includes the tenant, so a cached customer is never served
+// to another tenant.
+func customerCacheKey(customerNumber int, ctx context.Context) string {
+	tenantID, _ := database.TenantID(ctx)
+	return fmt.Sprintf("customer:%d:%d", tenantID, customerNumber)
+}
+
+func NewCachedCustomerRepository(next domain.CustomerRepository, backend cache.Backend, ttl time.Duration, cipher *encryption.Cipher, metrics *cache.Metrics, log *logrus.Logger) domain.CustomerRepository {
+	return &cachedCustomerRepository{
+		next:    next,
+		backend: backend,
+		ttl:     ttl,
+		cipher:  cipher,
+		group:   &singleflight.Group{},
+		metrics: metrics,
+		logger:  log,
+
+		generations: &[256]atomic.Uint64{},
+	}
+}
@@ -0,0 +1,195 @@
+package repository_customer
+
+import (
+	"bytes"
+	"context"
+	"customer-playground/cache"
+	"customer-playground/cache/cachetest"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type stubCustomerRepository struct {
+	domain.CustomerRepository
+	lookups   atomic.Int32
+	release   chan struct{}
+	customers map[int]domain.Customer
+}
+
+// GetByCustomerNumber reads the customer before waiting for release, like a
+// query whose result is on its way back.
+func (s *stubCustomerRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) (domain.Customer, error) {
+	customer := s.customers[customerNumber]
+	s.lookups.Add(1)
+	if s.release != nil {
+		<-s.release
+	}
+	return customer, nil
+}
+
+func (s *stubCustomerRepository) Update(customer *domain.Customer, ctx context.Context) (domain.Response, error) {
+	s.customers[customer.CustomerNumber] = *customer
+	return domain.Response{Message: "Succes Update", StatusCode: 200}, nil
+}
+
+func newCachedRepository(t *testing.T, backend cache.Backend) (domain.CustomerRepository, *stubCustomerRepository, *cache.Metrics) {
+	logger := logrus.New()
+	logger.Out = io.Discard
+	stub := &stubCustomerRepository{customers: map[int]domain.Customer{
+		1: {CustomerNumber: 1, Name: "John Doe", Email: "john.doe@example.com"},
+	}}
+	metrics := &cache.Metrics{}
+	return NewCachedCustomerRepository(stub, backend, time.Minute, newTestCipher(t), metrics, logger), stub, metrics
+}
+
+func TestCachedCustomerRepositoryReadThrough(t *testing.T) {
+	ctx := context.Background()
+	repository, stub, metrics := newCachedRepository(t, cache.NewLRU(10))
+
+	for i := 0; i < 3; i++ {
+		customer, err := repository.GetByCustomerNumber(1, ctx)
+		if err != nil || customer.Name != "John Doe" {
+			t.Fatalf("unexpected customer %+v err=%v", customer, err)
+		}
+	}
+	if stub.lookups.Load() != 1 {
+		t.Errorf("expected 1 database lookup, got %d", stub.lookups.Load())
+	}
+	if metrics.Hits() != 2 || metrics.Misses() != 1 {
+		t.Errorf("expected 2 hits and 1 miss, got %d and %d", metrics.Hits(), metrics.Misses())
+	}
+}
+
+func TestCachedCustomerRepositoryCachesSealedFields(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.NewLRU(10)
+	repository, stub, _ := newCachedRepository(t, backend)
+	birthDate := types.NullTime{Time: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC), Valid: true}
+	stub.customers[1] = domain.Customer{CustomerNumber: 1, Name: "John Doe", Email: "john.doe@example.com", Phone: "+4915112345678", BirthDate: birthDate}
+
+	if _, err := repository.GetByCustomerNumber(1, ctx); err != nil {
+		t.Fatal(err)
+	}
+	value, found, err := backend.Get(ctx, customerCacheKey(1, ctx))
+	if err != nil || !found {
+		t.Fatalf("expected the customer to be cached, found=%v err=%v", found, err)
+	}
+	for _, plaintext := range []string{"john.doe@example.com", "+4915112345678", "1990-05-17"} {
+		if bytes.Contains(value, []byte(plaintext)) {
+			t.Errorf("cached value contains %q in plaintext", plaintext)
+		}
+	}
+
+	customer, err := repository.GetByCustomerNumber(1, ctx)
+	if err != nil || customer.Email != "john.doe@example.com" || customer.Phone != "+4915112345678" || !customer.BirthDate.Time.Equal(birthDate.Time) {
+		t.Fatalf("expected the cached customer decrypted, got %+v err=%v", customer, err)
+	}
+	if stub.lookups.Load() != 1 {
+		t.Errorf("expected 1 database lookup, got %d", stub.lookups.Load())
+	}
+}
+
+func TestCachedCustomerRepositoryIsPerTenant(t *testing.T) {
+	repository, stub, _ := newCachedRepository(t, cache.NewLRU(10))
+
+	for _, tenantID := range []int{1, 2, 1, 2} {
+		if _, err := repository.GetByCustomerNumber(1, database.WithTenant(context.Background(), tenantID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stub.lookups.Load() != 2 {
+		t.Errorf("expected 1 database lookup per tenant, got %d", stub.lookups.Load())
+	}
+}
+
+func TestCachedCustomerRepositoryInvalidatesOnUpdate(t *testing.T) {
+	ctx := context.Background()
+	server, err := cachetest.NewRedisServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	redis := cache.NewRedis(server.Addr(), "", 0)
+	defer redis.Close()
+
+	repository, stub, _ := newCachedRepository(t, redis)
+	if _, err := repository.GetByCustomerNumber(1, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.Update(&domain.Customer{CustomerNumber: 1, Name: "Jane Doe"}, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	customer, err := repository.GetByCustomerNumber(1, ctx)
+	if err != nil || customer.Name != "Jane Doe" {
+		t.Fatalf("expected the updated customer, got %+v err=%v", customer, err)
+	}
+	if stub.lookups.Load() != 2 {
+		t.Errorf("expected 2 database lookups, got %d", stub.lookups.Load())
+	}
+}
+
+func TestCachedCustomerRepositoryCollapsesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	repository, stub, _ := newCachedRepository(t, cache.NewLRU(10))
+	stub.release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repository.GetByCustomerNumber(1, ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(stub.release)
+	wg.Wait()
+
+	if stub.lookups.Load() != 1 {
+		t.Errorf("expected concurrent misses to share 1 lookup, got %d", stub.lookups.Load())
+	}
+}
+
+func TestCachedCustomerRepositoryDropsLoadsRacingAnUpdate(t *testing.T) {
+	ctx := context.Background()
+	repository, stub, _ := newCachedRepository(t, cache.NewLRU(10))
+	stub.release = make(chan struct{})
+
+	done := make(chan domain.Customer)
+	go func() {
+		customer, err := repository.GetByCustomerNumber(1, ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- customer
+	}()
+	for stub.lookups.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := repository.Update(&domain.Customer{CustomerNumber: 1, Name: "Jane Doe"}, ctx); err != nil {
+		t.Fatal(err)
+	}
+	close(stub.release)
+	if customer := <-done; customer.Name != "John Doe" {
+		t.Fatalf("expected the slow load to return what it read, got %+v", customer)
+	}
+
+	customer, err := repository.GetByCustomerNumber(1, ctx)
+	if err != nil || customer.Name != "Jane Doe" {
+		t.Fatalf("expected the updated customer, got %+v err=%v", customer, err)
+	}
+	if stub.lookups.Load() != 2 {
+		t.Errorf("expected the stale load not to be cached, got %d database lookups", stub.lookups.Load())
+	}
+}
@@ -0,0 +1,342 @@
+package repository_customer
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/database/pgtest"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// contractFixture is what the contract suite needs from an implementation:
+// an empty repository and a way to add tenants to it.
+type contractFixture struct {
+	repository domain.CustomerRepository
+	newTenant  func(t *testing.T, slug string) context.Context
+}
+
+func TestMemoryCustomerRepositoryContract(t *testing.T) {
+	testCustomerRepositoryContract(t, func(t *testing.T) contractFixture {
+		tenantID := 0
+		return contractFixture{
+			repository: NewMemoryCustomerRepository(),
+			newTenant: func(t *testing.T, slug string) context.Context {
+				tenantID++
+				return database.WithTenant(context.Background(), tenantID)
+			},
+		}
+	})
+}
+
+func TestCustomerRepositoryContract(t *testing.T) {
+	testCustomerRepositoryContract(t, func(t *testing.T) contractFixture {
+		repository, db := newIntegrationRepository(t)
+		return contractFixture{
+			repository: repository,
+			newTenant: func(t *testing.T, slug string) context.Context {
+				var tenantID int
+				err := database.Transaction(pgtest.Context(), db.Primary(), func(tx *sql.Tx) error {
+					return tx.QueryRow(`INSERT INTO tenant (slug, name) VALUES ($1, $1) RETURNING id`, slug).Scan(&tenantID)
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return database.WithTenant(context.Background(), tenantID)
+			},
+		}
+	})
+}
+
+// testCustomerRepositoryContract checks the behaviour every CustomerRepository
+// has to share with the Postgres one. newFixture is called once per subtest.
+func testCustomerRepositoryContract(t *testing.T, newFixture func(t *testing.T) contractFixture) {
+	insert := func(t *testing.T, repository domain.CustomerRepository, customer *domain.Customer, ctx context.Context) {
+		t.Helper()
+		if message, err := repository.Insert(customer, ctx); err != nil || message.StatusCode != 200 {
+			t.Fatalf("Insert() = %+v, %v", message, err)
+		}
+	}
+
+	t.Run("InsertAssignsNumbers", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		first, second := newCustomer("John Doe", "john.doe@example.com"), newCustomer("Jane Doe", "jane.doe@example.com")
+		insert(t, f.repository, first, ctx)
+		insert(t, f.repository, second, ctx)
+		if first.CustomerNumber == 0 || second.CustomerNumber <= first.CustomerNumber {
+			t.Errorf("Insert() assigned %d and %d", first.CustomerNumber, second.CustomerNumber)
+		}
+
+		explicit := newCustomer("Jim Doe", "jim.doe@example.com")
+		explicit.CustomerNumber = 4242
+		insert(t, f.repository, explicit, ctx)
+		if explicit.CustomerNumber != 4242 {
+			t.Errorf("Insert() changed the number to %d", explicit.CustomerNumber)
+		}
+		duplicate := newCustomer("Joe Doe", "joe.doe@example.com")
+		duplicate.CustomerNumber = 4242
+		if message, err := f.repository.Insert(duplicate, ctx); err != nil || message.StatusCode != 400 {
+			t.Errorf("Insert() of a taken number = %+v, %v, want status 400", message, err)
+		}
+	})
+
+	t.Run("StoredValues", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		customer := newCustomer("John Doe", "john.doe@example.com")
+		customer.CreatedAt = types.NullTime{Time: time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.FixedZone("WIB", 7*60*60)), Valid: true}
+		customer.BirthDate = types.NullTime{Time: time.Date(1990, 1, 2, 15, 4, 5, 0, time.UTC), Valid: true}
+		customer.CustomFields = types.JSONMap{"visits": 3, "tags": []string{"vip"}}
+		insert(t, f.repository, customer, ctx)
+
+		got, err := f.repository.GetByCustomerNumber(customer.CustomerNumber, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Timestamps lose their zone and anything below a microsecond, birth
+		// dates their time of day.
+		if want := time.Date(2024, 5, 1, 12, 0, 0, 123457000, time.UTC); !got.CreatedAt.Time.Equal(want) {
+			t.Errorf("CreatedAt = %v, want %v", got.CreatedAt.Time, want)
+		}
+		if want := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC); !got.BirthDate.Time.Equal(want) {
+			t.Errorf("BirthDate = %v, want %v", got.BirthDate.Time, want)
+		}
+		if got.CustomFields["visits"] != float64(3) || fmt.Sprint(got.CustomFields["tags"]) != "[vip]" {
+			t.Errorf("CustomFields = %#v", got.CustomFields)
+		}
+
+		// Changing what was read does not change what is stored.
+		got.CustomFields["visits"] = float64(4)
+		again, err := f.repository.GetByCustomerNumber(customer.CustomerNumber, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.CustomFields["visits"] != float64(3) {
+			t.Errorf("CustomFields after changing a copy = %#v", again.CustomFields)
+		}
+
+		blank := newCustomer("Jane Doe", "jane.doe@example.com")
+		blank.BirthDate = types.NullTime{}
+		blank.CustomFields = types.JSONMap{}
+		insert(t, f.repository, blank, ctx)
+		if got, err := f.repository.GetByCustomerNumber(blank.CustomerNumber, ctx); err != nil || got.BirthDate.Valid || got.CustomFields != nil {
+			t.Errorf("GetByCustomerNumber() = %+v, %v, want no birth date and no custom fields", got, err)
+		}
+	})
+
+	t.Run("InsertRejectsInvalid", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		long := newCustomer(fmt.Sprintf("%0101d", 0), "john.doe@example.com")
+		undated := newCustomer("John Doe", "john.doe@example.com")
+		undated.CreatedAt = types.NullTime{}
+		for _, customer := range []*domain.Customer{long, undated} {
+			if message, err := f.repository.Insert(customer, ctx); err != nil || message.StatusCode != 400 {
+				t.Errorf("Insert(%q) = %+v, %v, want status 400", customer.Name, message, err)
+			}
+		}
+		if message, err := f.repository.Insert(newCustomer("John Doe", "john.doe@example.com"), context.Background()); err == nil && message.StatusCode == 200 {
+			t.Errorf("Insert() without a tenant = %+v", message)
+		}
+		if customers, err := f.repository.GetAll(domain.CustomerFilter{}, ctx); err != nil || len(customers) != 0 {
+			t.Errorf("GetAll() = %+v, %v, want nothing inserted", customers, err)
+		}
+	})
+
+	t.Run("GetAllFilters", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		john, jane, jim := newCustomer("John Doe", "john.doe@example.com"), newCustomer("Jane Doe", "jane.doe@example.com"), newCustomer("Jim Doe", "")
+		john.CustomFields = types.JSONMap{"tier": "gold", "visits": 3, "tags": []string{"vip", "b2b"}}
+		jane.CustomFields = types.JSONMap{"tier": "silver", "visits": 3}
+		for _, customer := range []*domain.Customer{john, jane, jim} {
+			insert(t, f.repository, customer, ctx)
+		}
+
+		tests := []struct {
+			filter domain.CustomerFilter
+			want   []int
+		}{
+			{domain.CustomerFilter{}, []int{john.CustomerNumber, jane.CustomerNumber, jim.CustomerNumber}},
+			{domain.CustomerFilter{Email: "  JOHN.Doe@Example.com "}, []int{john.CustomerNumber}},
+			{domain.CustomerFilter{Email: "nobody@example.com"}, nil},
+			{domain.CustomerFilter{CustomFields: map[string]interface{}{"visits": 3}}, []int{john.CustomerNumber, jane.CustomerNumber}},
+			{domain.CustomerFilter{CustomFields: map[string]interface{}{"tier": "gold", "visits": 3}}, []int{john.CustomerNumber}},
+			{domain.CustomerFilter{CustomFields: map[string]interface{}{"tags": []interface{}{"b2b"}}}, []int{john.CustomerNumber}},
+			{domain.CustomerFilter{CustomFields: map[string]interface{}{"tier": nil}}, nil},
+			{domain.CustomerFilter{CustomFields: map[string]interface{}{"tier": "bronze"}}, nil},
+		}
+		for _, test := range tests {
+			customers, err := f.repository.GetAll(test.filter, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, customer := range customers {
+				got = append(got, customer.CustomerNumber)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("GetAll(%+v) = %v, want %v", test.filter, got, test.want)
+			}
+		}
+	})
+
+	t.Run("UniqueEmailPerTenant", func(t *testing.T) {
+		f := newFixture(t)
+		acme, globex := f.newTenant(t, "acme"), f.newTenant(t, "globex")
+		insert(t, f.repository, newCustomer("John Doe", "john.doe@example.com"), acme)
+		if message, err := f.repository.Insert(newCustomer("Johnny Doe", " John.Doe@example.com"), acme); err != nil || message.StatusCode != 400 {
+			t.Errorf("Insert() of a taken email = %+v, %v, want status 400", message, err)
+		}
+		// Other tenants and customers without an email are not affected.
+		insert(t, f.repository, newCustomer("John Doe", "john.doe@example.com"), globex)
+		insert(t, f.repository, newCustomer("Jim Doe", ""), acme)
+		insert(t, f.repository, newCustomer("Joe Doe", ""), acme)
+
+		jane := newCustomer("Jane Doe", "jane.doe@example.com")
+		insert(t, f.repository, jane, acme)
+		jane.Email = "JOHN.DOE@example.com"
+		if message, err := f.repository.Update(jane, acme); err != nil || message.StatusCode != 400 {
+			t.Errorf("Update() to a taken email = %+v, %v, want status 400", message, err)
+		}
+		got, err := f.repository.GetByCustomerNumber(jane.CustomerNumber, acme)
+		if err != nil || got.Email != "jane.doe@example.com" {
+			t.Errorf("GetByCustomerNumber() after a failed update = %+v, %v", got, err)
+		}
+		// Keeping the own email is fine.
+		got.Name = "Jane Q. Doe"
+		if message, err := f.repository.Update(&got, acme); err != nil || message.StatusCode != 200 {
+			t.Errorf("Update() = %+v, %v", message, err)
+		}
+	})
+
+	t.Run("TenantIsolation", func(t *testing.T) {
+		f := newFixture(t)
+		acme, globex := f.newTenant(t, "acme"), f.newTenant(t, "globex")
+		customer := newCustomer("John Doe", "john.doe@example.com")
+		insert(t, f.repository, customer, acme)
+
+		for name, ctx := range map[string]context.Context{"other tenant": globex, "no tenant": context.Background()} {
+			if _, err := f.repository.GetByCustomerNumber(customer.CustomerNumber, ctx); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("GetByCustomerNumber() for %s = %v, want sql.ErrNoRows", name, err)
+			}
+			if customers, err := f.repository.GetAll(domain.CustomerFilter{}, ctx); err != nil || len(customers) != 0 {
+				t.Errorf("GetAll() for %s = %+v, %v", name, customers, err)
+			}
+			if _, err := f.repository.DeleteByCustomerNumber(customer.CustomerNumber, ctx); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("DeleteByCustomerNumber() for %s = %v, want sql.ErrNoRows", name, err)
+			}
+		}
+		if _, err := f.repository.GetByCustomerNumber(customer.CustomerNumber, acme); err != nil {
+			t.Errorf("GetByCustomerNumber() = %v", err)
+		}
+
+		// Numbers are shared by the tenants.
+		other := newCustomer("Jane Doe", "jane.doe@example.com")
+		other.CustomerNumber = customer.CustomerNumber
+		if message, err := f.repository.Insert(other, globex); err != nil || message.StatusCode != 400 {
+			t.Errorf("Insert() of a number taken by another tenant = %+v, %v, want status 400", message, err)
+		}
+	})
+
+	t.Run("UpdateReplaces", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		customer := newCustomer("John Doe", "john.doe@example.com")
+		customer.CustomFields = types.JSONMap{"tier": "gold"}
+		insert(t, f.repository, customer, ctx)
+
+		// The repository stores what it is given; merging is up to the use case.
+		update := domain.Customer{
+			CustomerNumber: customer.CustomerNumber,
+			Name:           "John Q. Doe",
+			Email:          "jq.doe@example.com",
+			CreatedAt:      customer.CreatedAt,
+			UpdatedAt:      types.NullTime{Time: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC), Valid: true},
+		}
+		if message, err := f.repository.Update(&update, ctx); err != nil || message.StatusCode != 200 {
+			t.Fatalf("Update() = %+v, %v", message, err)
+		}
+		got, err := f.repository.GetByCustomerNumber(customer.CustomerNumber, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != "John Q. Doe" || got.Email != "jq.doe@example.com" || got.Phone != "" || got.BirthDate.Valid || got.CustomFields != nil || !got.UpdatedAt.Time.Equal(update.UpdatedAt.Time) {
+			t.Errorf("GetByCustomerNumber() after update = %+v", got)
+		}
+
+		missing := newCustomer("Nobody", "nobody@example.com")
+		missing.CustomerNumber = customer.CustomerNumber + 100
+		if message, err := f.repository.Update(missing, ctx); !errors.Is(err, sql.ErrNoRows) || message.StatusCode != 500 {
+			t.Errorf("Update() of a missing customer = %+v, %v, want sql.ErrNoRows", message, err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		customer := newCustomer("John Doe", "john.doe@example.com")
+		insert(t, f.repository, customer, ctx)
+
+		if message, err := f.repository.DeleteByCustomerNumber(customer.CustomerNumber, ctx); err != nil || message.StatusCode != 200 {
+			t.Fatalf("DeleteByCustomerNumber() = %+v, %v", message, err)
+		}
+		message, err := f.repository.DeleteByCustomerNumber(customer.CustomerNumber, ctx)
+		if !errors.Is(err, sql.ErrNoRows) || message.StatusCode != 500 {
+			t.Errorf("DeleteByCustomerNumber() twice = %+v, %v, want sql.ErrNoRows", message, err)
+		}
+		if _, err := f.repository.GetByCustomerNumber(customer.CustomerNumber, ctx); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetByCustomerNumber() after delete = %v, want sql.ErrNoRows", err)
+		}
+		if _, err := f.repository.GetRedirect(customer.CustomerNumber, ctx); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetRedirect() = %v, want sql.ErrNoRows", err)
+		}
+		// The email is free again.
+		insert(t, f.repository, newCustomer("John Doe", "john.doe@example.com"), ctx)
+	})
+}
+
+func TestMemoryCustomerRepositoryConcurrent(t *testing.T) {
+	repository := NewMemoryCustomerRepository()
+	ctx := database.WithTenant(context.Background(), 1)
+
+	var wg sync.WaitGroup
+	numbers := make([]int, 50)
+	for i := range numbers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			customer := newCustomer("John Doe", fmt.Sprintf("john.doe+%d@example.com", i))
+			if message, err := repository.Insert(customer, ctx); err != nil || message.StatusCode != 200 {
+				t.Errorf("Insert() = %+v, %v", message, err)
+				return
+			}
+			numbers[i] = customer.CustomerNumber
+			if _, err := repository.GetAll(domain.CustomerFilter{}, ctx); err != nil {
+				t.Error(err)
+			}
+			customer.Name = "John Q. Doe"
+			if message, err := repository.Update(customer, ctx); err != nil || message.StatusCode != 200 {
+				t.Errorf("Update() = %+v, %v", message, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int]bool)
+	for _, number := range numbers {
+		if seen[number] {
+			t.Errorf("number %d was assigned twice", number)
+		}
+		seen[number] = true
+	}
+	if customers, err := repository.GetAll(domain.CustomerFilter{}, ctx); err != nil || len(customers) != len(numbers) {
+		t.Errorf("GetAll() = %d customers, %v, want %d", len(customers), err, len(numbers))
+	}
+}
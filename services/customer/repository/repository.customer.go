@@ -2,21 +2,19 @@ package repository_customer
 
 import (
 	"context"
+	"customer-playground/database"
 	"customer-playground/domain"
+	"customer-playground/encryption"
 	"database/sql"
+	"errors"
 	"fmt"
 
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
-type customerRepository struct {
-	dbPool *sql.DB
-	logger *logrus.Logger
-}
-
-func (c customerRepository) GetAll(ctx context.Context) ([]domain.Customer, error) {
-
-	stmt, err := c.dbPool.Prepare(`
+const (
+	queryGetAll = `
 		SELECT
 			customer_number,
 			name,
@@ -24,44 +22,39 @@ func (c customerRepository) GetAll(ctx context.Context) ([]domain.Customer, erro
 			phone,
 			birth_date,
 			created_at,
-			updated_at
+			updated_at,
+			custom_fields
 		FROM customer
-	`)
-	if err != nil {
-		return nil, err
-	}
-
-	rows, err := stmt.QueryContext(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	defer rows.Close()
-
-	var customers []domain.Customer
-	for rows.Next() {
-		var customer domain.Customer
-		err := rows.Scan(
-			&customer.CustomerNumber,
-			&customer.Name,
-			&customer.Email,
-			&customer.Phone,
-			&customer.BirthDate,
-			&customer.CreatedAt,
-			&customer.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		customers = append(customers, customer)
-	}
-
-	return customers, nil
-}
-
-func (c customerRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) (domain.Customer, error) {
-	stmt, err := c.dbPool.Prepare(`
+		WHERE tenant_id = app_tenant()
+	`
+	queryGetAllByCustomFields = `
+		SELECT
+			customer_number,
+			name,
+			email,
+			phone,
+			birth_date,
+			created_at,
+			updated_at,
+			custom_fields
+		FROM customer
+		WHERE tenant_id = app_tenant() AND custom_fields @> $1
+	`
+	// Encrypted emails are found through their blind index.
+	queryGetAllByEmail = `
+		SELECT
+			customer_number,
+			name,
+			email,
+			phone,
+			birth_date,
+			created_at,
+			updated_at,
+			custom_fields
+		FROM customer
+		WHERE tenant_id = app_tenant() AND email_index = $1 AND custom_fields @> $2
+	`
+	queryGetByCustomerNumber = `
 		SELECT
 			customer_number,
 			name,
@@ -69,24 +62,181 @@ func (c customerRepository) GetByCustomerNumber(customerNumber int, ctx context.
 			phone,
 			birth_date,
 			created_at,
-			updated_at
+			updated_at,
+			custom_fields
 		FROM customer
-		WHERE customer_number = $1
+		WHERE tenant_id = app_tenant() AND customer_number = $1
 		ORDER BY name
-	`)
-	if err != nil {
-		return domain.Customer{}, err
-	}
-	var customer domain.Customer
-	err = stmt.QueryRowContext(ctx, customerNumber).Scan(
+	`
+	queryInsert = `
+		INSERT INTO customer(
+			customer_number,
+			name,
+			email,
+			email_index,
+			phone,
+			birth_date,
+			created_at,
+			updated_at,
+			custom_fields) VALUES (
+		COALESCE(NULLIF($1, 0), nextval('customer_customer_number_seq')), $2, $3, $4, $5, $6, $7, $8, $9
+	)
+		RETURNING customer_number
+	`
+	queryUpdate = `
+		UPDATE customer SET
+			name = $2,
+			email = $3,
+			email_index = $4,
+			phone = $5,
+			birth_date = $6,
+			created_at = $7,
+			updated_at = $8,
+			custom_fields = $9
+		WHERE 
+			tenant_id = app_tenant() AND customer_number = $1
+	`
+	// querySyncPrimaryContact keeps the primary contact point of a type in line
+	// with customer.email and customer.phone, creating it when missing. Values
+	// are compared by blind index, as encrypted ones differ on every write.
+	querySyncPrimaryContact = `
+		WITH updated AS (
+			UPDATE customer_contact_point SET
+				value = $3,
+				value_index = $4,
+				verified_at = CASE WHEN value_index = $4 THEN verified_at END,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE tenant_id = app_tenant() AND customer_number = $1 AND type = $2 AND is_primary AND $3 <> ''
+			RETURNING id
+		)
+		INSERT INTO customer_contact_point (customer_number, type, value, value_index, is_primary)
+		SELECT $1, $2, $3, $4, TRUE
+		WHERE $3 <> ''
+			AND NOT EXISTS (SELECT 1 FROM updated)
+			AND NOT EXISTS (
+				SELECT 1 FROM customer_contact_point
+				WHERE tenant_id = app_tenant() AND customer_number = $1 AND type = $2 AND is_primary
+			)
+	`
+	queryGetRedirect = `
+		SELECT customer_number
+		FROM customer_redirect
+		WHERE tenant_id = app_tenant() AND old_customer_number = $1
+	`
+	// queryGetStale finds customers whose fields are not sealed as configured:
+	// $1 matches values sealed with the current key, $2 any sealed value and
+	// $3 to $5 tell whether email, phone and birth_date are encrypted.
+	queryGetStale = `
+		SELECT
+			customer_number,
+			email,
+			email_index,
+			phone,
+			birth_date
+		FROM customer
+		WHERE tenant_id = app_tenant() AND (
+			(email_index IS NULL AND email <> '')
+			OR CASE WHEN $3 THEN email NOT LIKE $1 ELSE email LIKE $2 END
+			OR CASE WHEN $4 THEN phone <> '' AND phone NOT LIKE $1 ELSE phone LIKE $2 END
+			OR CASE WHEN $5 THEN birth_date NOT LIKE $1 ELSE birth_date LIKE $2 END
+		)
+		ORDER BY customer_number
+		LIMIT $6
+		FOR UPDATE SKIP LOCKED
+	`
+	// queryReEncrypt leaves updated_at alone, the customer did not change.
+	queryReEncrypt = `
+		UPDATE customer SET
+			email = $2,
+			email_index = $3,
+			phone = $4,
+			birth_date = $5
+		WHERE tenant_id = app_tenant() AND customer_number = $1
+	`
+	queryDeleteByCustomerNumber = `
+		DELETE
+		FROM customer
+		WHERE tenant_id = app_tenant() AND customer_number = $1
+	`
+)
+
+type customerRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	cipher *encryption.Cipher
+	logger *logrus.Logger
+}
+
+// scan reads a customer selected with the columns of queryGetAll and
+// decrypts its fields.
+func (c customerRepository) scan(ctx context.Context, row interface{ Scan(...interface{}) error }, customer *domain.Customer) error {
+	var stored encryption.StoredCustomer
+	err := row.Scan(
 		&customer.CustomerNumber,
 		&customer.Name,
-		&customer.Email,
-		&customer.Phone,
-		&customer.BirthDate,
+		&stored.Email,
+		&stored.Phone,
+		&stored.BirthDate,
 		&customer.CreatedAt,
 		&customer.UpdatedAt,
+		&customer.CustomFields,
 	)
+	if err != nil {
+		return err
+	}
+	return c.cipher.OpenCustomer(ctx, stored, customer)
+}
+
+func (c customerRepository) GetAll(filter domain.CustomerFilter, ctx context.Context) ([]domain.Customer, error) {
+	query, args := queryGetAll, []interface{}(nil)
+	if len(filter.CustomFields) > 0 {
+		query, args = queryGetAllByCustomFields, []interface{}{filter.CustomFields}
+	}
+	if filter.Email != "" {
+		query, args = queryGetAllByEmail, []interface{}{c.cipher.BlindIndex(encryption.FieldEmail, filter.Email), filter.CustomFields}
+	}
+
+	var customers []domain.Customer
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, query, func(stmt *sql.Stmt) error {
+				customers = nil
+				rows, err := stmt.QueryContext(ctx, args...)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var customer domain.Customer
+					if err := c.scan(ctx, rows, &customer); err != nil {
+						return err
+					}
+
+					customers = append(customers, customer)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return customers, nil
+}
+
+func (c customerRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) (domain.Customer, error) {
+	var customer domain.Customer
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetByCustomerNumber, func(stmt *sql.Stmt) error {
+				return c.scan(ctx, stmt.QueryRowContext(ctx, customerNumber), &customer)
+			})
+		})
+	})
 	if err != nil {
 		return domain.Customer{}, err
 	}
@@ -96,38 +246,37 @@ func (c customerRepository) GetByCustomerNumber(customerNumber int, ctx context.
 
 func (c customerRepository) Insert(customer *domain.Customer, ctx context.Context) (domain.Response, error) {
 	var message domain.Response
-	stmt, err := c.dbPool.PrepareContext(ctx, `
-		INSERT INTO customer(
-			customer_number,
-			name,
-			email,
-			phone,
-			birth_date,
-			created_at,
-			updated_at) VALUES (
-		$1, $2, $3, $4, $5, $6, $7
-	)
-	`)
-	if err != nil {
+	stored, err := c.cipher.SealCustomer(ctx, *customer)
+	if err == nil {
+		err = c.transaction(ctx, func(tx *database.Tx) error {
+			err := tx.QueryRow(queryInsert,
+				customer.CustomerNumber,
+				customer.Name,
+				stored.Email,
+				stored.EmailIndex,
+				stored.Phone,
+				stored.BirthDate,
+				customer.CreatedAt,
+				customer.UpdatedAt,
+				customer.CustomFields,
+			).Scan(&customer.CustomerNumber)
+			if err != nil {
+				return err
+			}
+			return c.syncPrimaryContacts(ctx, tx, customer, stored)
+		})
+	}
+	if isInvalidInput(err) {
 		message.Message = "Failed to Insert Customer"
-		message.StatusCode = 500
-		c.logger.Errorf("failed to prepare statement: %v", err)
-	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(
-		customer.CustomerNumber,
-		customer.Name,
-		customer.Email,
-		customer.Phone,
-		customer.BirthDate,
-		customer.CreatedAt,
-		customer.UpdatedAt,
-	)
+		message.StatusCode = 400
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, nil
+	}
 	if err != nil {
-		message.Message = "Failed to Insert Customer Note"
+		message.Message = "Failed to Insert Customer"
 		message.StatusCode = 500
 		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
 	}
 	message.Message = fmt.Sprintf("Succes Insert Customer with number %d", customer.CustomerNumber)
 	message.StatusCode = 200
@@ -136,37 +285,49 @@ func (c customerRepository) Insert(customer *domain.Customer, ctx context.Contex
 
 func (c customerRepository) Update(customer *domain.Customer, ctx context.Context) (domain.Response, error) {
 	var message domain.Response
-	stmt, err := c.dbPool.PrepareContext(ctx, `
-		UPDATE customer SET
-			name = $2,
-			email = $3,
-			phone = $4,
-			birth_date = $5, 
-			created_at = $6,
-			updated_at = $7
-		WHERE 
-			customer_number = $1
-	`)
-	if err != nil {
-		message.Message = fmt.Sprintf("Failed Update number %d", customer.CustomerNumber)
+	stored, err := c.cipher.SealCustomer(ctx, *customer)
+	if err == nil {
+		err = c.transaction(ctx, func(tx *database.Tx) error {
+			result, err := tx.Exec(queryUpdate,
+				customer.CustomerNumber,
+				customer.Name,
+				stored.Email,
+				stored.EmailIndex,
+				stored.Phone,
+				stored.BirthDate,
+				customer.CreatedAt,
+				customer.UpdatedAt,
+				customer.CustomFields,
+			)
+			if err != nil {
+				return err
+			}
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return sql.ErrNoRows
+			}
+			return c.syncPrimaryContacts(ctx, tx, customer, stored)
+		})
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		message.Message = fmt.Sprintf("No customer found with number %d", customer.CustomerNumber)
 		message.StatusCode = 500
-		c.logger.Errorf("failed to prepare statement: %v", err)
-	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(
-		customer.CustomerNumber,
-		customer.Name,
-		customer.Email,
-		customer.Phone,
-		customer.BirthDate,
-		customer.CreatedAt,
-		customer.UpdatedAt,
-	)
+		return message, err
+	}
+	if isInvalidInput(err) {
+		message.Message = fmt.Sprintf("Failed Update number %d", customer.CustomerNumber)
+		message.StatusCode = 400
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, nil
+	}
 	if err != nil {
-		message.Message = fmt.Sprintf("Failed Update id %d", customer.CustomerNumber)
+		message.Message = fmt.Sprintf("Failed Update number %d", customer.CustomerNumber)
 		message.StatusCode = 500
 		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
 	}
 	message.Message = "Succes Update"
 	message.StatusCode = 200
@@ -175,19 +336,14 @@ func (c customerRepository) Update(customer *domain.Customer, ctx context.Contex
 
 func (c customerRepository) DeleteByCustomerNumber(customerNumber int, ctx context.Context) (domain.Response, error) {
 	var message domain.Response
-	stmt, err := c.dbPool.Prepare(`
-		DELETE
-		FROM customer
-		WHERE customer_number = $1
-	`)
-	if err != nil {
-		message.Message = fmt.Sprintf("Failed Delete number %d", customerNumber)
-		message.StatusCode = 500
-		return message, err
-	}
-	defer stmt.Close()
-
-	result, err := stmt.ExecContext(ctx, customerNumber)
+	var result sql.Result
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryDeleteByCustomerNumber, func(stmt *sql.Stmt) error {
+			var err error
+			result, err = stmt.ExecContext(ctx, customerNumber)
+			return err
+		})
+	})
 	if err != nil {
 		message.Message = fmt.Sprintf("Failed Delete number %d", customerNumber)
 		message.StatusCode = 500
@@ -201,7 +357,7 @@ func (c customerRepository) DeleteByCustomerNumber(customerNumber int, ctx conte
 		return message, err
 	}
 	if rowsAffected == 0 {
-		message.Message = fmt.Sprintf("No customer note found with number %d", customerNumber)
+		message.Message = fmt.Sprintf("No customer found with number %d", customerNumber)
 		message.StatusCode = 500
 		return message, sql.ErrNoRows
 	}
@@ -210,9 +366,122 @@ func (c customerRepository) DeleteByCustomerNumber(customerNumber int, ctx conte
 	return message, nil
 }
 
-func NewCustomerRepository(db *sql.DB, log *logrus.Logger) domain.CustomerRepository {
+func (c customerRepository) GetRedirect(customerNumber int, ctx context.Context) (int, error) {
+	var movedTo int
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetRedirect, func(stmt *sql.Stmt) error {
+				return stmt.QueryRowContext(ctx, customerNumber).Scan(&movedTo)
+			})
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return movedTo, nil
+}
+
+// ReEncrypt rewrites up to batchSize customers whose fields are not sealed
+// with the current key and selected fields, or have no email index yet.
+func (c customerRepository) ReEncrypt(batchSize int, ctx context.Context) (int, error) {
+	pattern, err := c.cipher.CurrentPattern(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var rewritten int
+	err = c.transaction(ctx, func(tx *database.Tx) error {
+		rewritten = 0
+		rows, err := tx.Query(queryGetStale,
+			pattern,
+			encryption.SealedPattern(),
+			c.cipher.Encrypts(encryption.FieldEmail),
+			c.cipher.Encrypts(encryption.FieldPhone),
+			c.cipher.Encrypts(encryption.FieldBirthDate),
+			batchSize,
+		)
+		if err != nil {
+			return err
+		}
+		stale := map[int]encryption.StoredCustomer{}
+		for rows.Next() {
+			var customerNumber int
+			var stored encryption.StoredCustomer
+			if err := rows.Scan(&customerNumber, &stored.Email, &stored.EmailIndex, &stored.Phone, &stored.BirthDate); err != nil {
+				rows.Close()
+				return err
+			}
+			stale[customerNumber] = stored
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for customerNumber, stored := range stale {
+			resealed, err := c.cipher.ResealCustomer(ctx, stored)
+			if err != nil {
+				return fmt.Errorf("customer %d: %w", customerNumber, err)
+			}
+			_, err = tx.Exec(queryReEncrypt, customerNumber, resealed.Email, resealed.EmailIndex, resealed.Phone, resealed.BirthDate)
+			if err != nil {
+				return fmt.Errorf("customer %d: %w", customerNumber, err)
+			}
+			rewritten++
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Errorf("failed to re-encrypt customers: %v", err)
+		return 0, err
+	}
+	return rewritten, nil
+}
+
+// syncPrimaryContacts passes the sealed email and phone on to the primary
+// contact points, with the index contact points keep for every type.
+func (c customerRepository) syncPrimaryContacts(ctx context.Context, tx *database.Tx, customer *domain.Customer, stored encryption.StoredCustomer) error {
+	_, err := tx.Exec(querySyncPrimaryContact, customer.CustomerNumber, domain.ContactTypeEmail, stored.Email, stored.EmailIndex)
+	if err != nil {
+		return err
+	}
+	phoneIndex := c.cipher.BlindIndex(domain.ContactTypePhone, customer.Phone)
+	_, err = tx.Exec(querySyncPrimaryContact, customer.CustomerNumber, domain.ContactTypePhone, stored.Phone.String, phoneIndex)
+	return err
+}
+
+// isInvalidInput reports whether err rejected the values of a statement, such
+// as a taken email or a name that is too long, rather than failed it.
+func isInvalidInput(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code.Class() {
+	case "22", // data_exception
+		"23": // integrity_constraint_violation
+		return true
+	}
+	return false
+}
+
+func (c customerRepository) transaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	return c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), fn)
+	})
+}
+
+// Close releases the prepared statements held by the repository.
+func (c customerRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewCustomerRepository(db *database.DB, retry database.RetryPolicy, cipher *encryption.Cipher, log *logrus.Logger) domain.CustomerRepository {
 	return &customerRepository{
-		dbPool: db,
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
+		cipher: cipher,
 		logger: log,
 	}
 }
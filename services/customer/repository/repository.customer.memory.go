@@ -0,0 +1,279 @@
+package repository_customer
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var errMemoryConstraint = errors.New("memory: constraint violated")
+
+// memoryCustomerRepository keeps customers in memory and behaves like the
+// Postgres repository: customers are scoped to the tenant of ctx, customer
+// numbers come from one sequence shared by the tenants, emails are unique per
+// tenant regardless of case and values come back the way Postgres stores
+// them. Merges are not supported, so there are no redirects.
+type memoryCustomerRepository struct {
+	mu         sync.RWMutex
+	customers  map[int]memoryCustomer
+	nextNumber int
+	onDelete   []func(customerNumber int)
+}
+
+type memoryCustomer struct {
+	tenantID int
+	customer domain.Customer
+}
+
+func (c *memoryCustomerRepository) GetAll(filter domain.CustomerFilter, ctx context.Context) ([]domain.Customer, error) {
+	tenantID, ok := database.TenantID(ctx)
+	if !ok {
+		return nil, nil
+	}
+	var wanted map[string]interface{}
+	if len(filter.CustomFields) > 0 {
+		wanted = map[string]interface{}(roundTrip(filter.CustomFields))
+	}
+	email := emailKey(filter.Email)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var customers []domain.Customer
+	for _, number := range c.sortedNumbers() {
+		stored := c.customers[number]
+		if stored.tenantID != tenantID {
+			continue
+		}
+		if filter.Email != "" && (email == "" || emailKey(stored.customer.Email) != email) {
+			continue
+		}
+		if wanted != nil && !jsonContains(map[string]interface{}(stored.customer.CustomFields), wanted) {
+			continue
+		}
+		customers = append(customers, copyCustomer(stored.customer))
+	}
+	return customers, nil
+}
+
+func (c *memoryCustomerRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) (domain.Customer, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	stored, ok := c.lookup(customerNumber, ctx)
+	if !ok {
+		return domain.Customer{}, sql.ErrNoRows
+	}
+	return copyCustomer(stored.customer), nil
+}
+
+func (c *memoryCustomerRepository) Insert(customer *domain.Customer, ctx context.Context) (domain.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	customerNumber := customer.CustomerNumber
+	if customerNumber == 0 {
+		// Like nextval, a failed insert still uses up the number.
+		c.nextNumber++
+		customerNumber = c.nextNumber
+	}
+	tenantID, ok := database.TenantID(ctx)
+	_, taken := c.customers[customerNumber]
+	if !ok || taken || c.check(tenantID, customerNumber, customer) != nil {
+		return domain.Response{Message: "Failed to Insert Customer", StatusCode: 400}, nil
+	}
+
+	customer.CustomerNumber = customerNumber
+	c.customers[customerNumber] = memoryCustomer{tenantID: tenantID, customer: stored(*customer)}
+	return domain.Response{Message: fmt.Sprintf("Succes Insert Customer with number %d", customerNumber), StatusCode: 200}, nil
+}
+
+func (c *memoryCustomerRepository) Update(customer *domain.Customer, ctx context.Context) (domain.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, ok := c.lookup(customer.CustomerNumber, ctx)
+	if !ok {
+		return domain.Response{Message: fmt.Sprintf("No customer found with number %d", customer.CustomerNumber), StatusCode: 500}, sql.ErrNoRows
+	}
+	if c.check(current.tenantID, customer.CustomerNumber, customer) != nil {
+		return domain.Response{Message: fmt.Sprintf("Failed Update number %d", customer.CustomerNumber), StatusCode: 400}, nil
+	}
+
+	c.customers[customer.CustomerNumber] = memoryCustomer{tenantID: current.tenantID, customer: stored(*customer)}
+	return domain.Response{Message: "Succes Update", StatusCode: 200}, nil
+}
+
+func (c *memoryCustomerRepository) DeleteByCustomerNumber(customerNumber int, ctx context.Context) (domain.Response, error) {
+	c.mu.Lock()
+	_, ok := c.lookup(customerNumber, ctx)
+	if ok {
+		delete(c.customers, customerNumber)
+	}
+	onDelete := c.onDelete
+	c.mu.Unlock()
+
+	if !ok {
+		return domain.Response{Message: fmt.Sprintf("No customer found with number %d", customerNumber), StatusCode: 500}, sql.ErrNoRows
+	}
+	for _, fn := range onDelete {
+		fn(customerNumber)
+	}
+	return domain.Response{Message: "Succes Delete!!", StatusCode: 200}, nil
+}
+
+func (c *memoryCustomerRepository) GetRedirect(customerNumber int, ctx context.Context) (int, error) {
+	return 0, sql.ErrNoRows
+}
+
+// OnDelete calls fn after a customer was deleted, so repositories of data
+// referring to customers can delete it with them like ON DELETE CASCADE.
+func (c *memoryCustomerRepository) OnDelete(fn func(customerNumber int)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onDelete = append(c.onDelete, fn)
+}
+
+// lookup returns the customer if it belongs to the tenant of ctx. c.mu must
+// be held.
+func (c *memoryCustomerRepository) lookup(customerNumber int, ctx context.Context) (memoryCustomer, bool) {
+	tenantID, ok := database.TenantID(ctx)
+	if !ok {
+		return memoryCustomer{}, false
+	}
+	stored, ok := c.customers[customerNumber]
+	if !ok || stored.tenantID != tenantID {
+		return memoryCustomer{}, false
+	}
+	return stored, true
+}
+
+// check applies the constraints of the customer table. c.mu must be held.
+func (c *memoryCustomerRepository) check(tenantID int, customerNumber int, customer *domain.Customer) error {
+	if utf8.RuneCountInString(customer.Name) > 100 || !customer.CreatedAt.Valid || !customer.UpdatedAt.Valid {
+		return errMemoryConstraint
+	}
+	email := emailKey(customer.Email)
+	if email == "" {
+		return nil
+	}
+	for number, other := range c.customers {
+		if number != customerNumber && other.tenantID == tenantID && emailKey(other.customer.Email) == email {
+			return errMemoryConstraint
+		}
+	}
+	return nil
+}
+
+func (c *memoryCustomerRepository) sortedNumbers() []int {
+	numbers := make([]int, 0, len(c.customers))
+	for number := range c.customers {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+// emailKey is what the blind index of an email is computed from.
+func emailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// stored returns customer as Postgres keeps it: timestamps without time zone
+// in microseconds, the birth date as a date and custom fields as JSON.
+func stored(customer domain.Customer) domain.Customer {
+	customer.CreatedAt = storedTime(customer.CreatedAt)
+	customer.UpdatedAt = storedTime(customer.UpdatedAt)
+	if customer.BirthDate.Valid {
+		year, month, day := customer.BirthDate.Time.Date()
+		customer.BirthDate = types.NullTime{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+	customer.CustomFields = roundTrip(customer.CustomFields)
+	return customer
+}
+
+func storedTime(t types.NullTime) types.NullTime {
+	if !t.Valid {
+		return t
+	}
+	wall := t.Time.Round(time.Microsecond)
+	return types.NullTime{
+		Time:  time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), time.UTC),
+		Valid: true,
+	}
+}
+
+func copyCustomer(customer domain.Customer) domain.Customer {
+	customer.CustomFields = roundTrip(customer.CustomFields)
+	return customer
+}
+
+// roundTrip returns a copy of fields as read back from a JSONB column: numbers
+// become float64 and an empty object nil.
+func roundTrip(fields types.JSONMap) types.JSONMap {
+	if len(fields) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(map[string]interface{}(fields))
+	if err != nil {
+		return nil
+	}
+	var decoded types.JSONMap
+	if err := decoded.Scan(b); err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// jsonContains reports whether a contains b like the JSONB @> operator.
+func jsonContains(a interface{}, b interface{}) bool {
+	switch b := b.(type) {
+	case map[string]interface{}:
+		a, ok := a.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range b {
+			element, ok := a[key]
+			if !ok || !jsonContains(element, value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		a, ok := a.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, wanted := range b {
+			found := false
+			for _, element := range a {
+				if jsonContains(element, wanted) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// NewMemoryCustomerRepository returns an empty in-memory CustomerRepository,
+// e.g. to test use cases without a database.
+func NewMemoryCustomerRepository() domain.CustomerRepository {
+	return &memoryCustomerRepository{customers: make(map[int]memoryCustomer)}
+}
@@ -0,0 +1,192 @@
+package repository_customer
+
+import (
+	"customer-playground/database"
+	"customer-playground/database/pgtest"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func newIntegrationRepository(t *testing.T) (domain.CustomerRepository, *database.DB) {
+	db := pgtest.Open(t)
+	logger := logrus.New()
+	logger.Out = io.Discard
+	repository := NewCustomerRepository(db, database.DefaultRetryPolicy, newTestCipher(t), logger)
+	t.Cleanup(func() { repository.(io.Closer).Close() })
+	return repository, db
+}
+
+func newCustomer(name string, email string) *domain.Customer {
+	now := types.NullTime{Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Valid: true}
+	return &domain.Customer{
+		Name:      name,
+		Email:     email,
+		Phone:     "08123456789",
+		BirthDate: types.NullTime{Time: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC), Valid: true},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestCustomerRepositoryCRUD(t *testing.T) {
+	repository, _ := newIntegrationRepository(t)
+	ctx := pgtest.Context()
+
+	customer := newCustomer("John Doe", "john.doe@example.com")
+	if message, err := repository.Insert(customer, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() = %+v, %v", message, err)
+	}
+	if customer.CustomerNumber == 0 {
+		t.Fatal("Insert() did not set the customer number")
+	}
+
+	got, err := repository.GetByCustomerNumber(customer.CustomerNumber, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "John Doe" || got.Email != "john.doe@example.com" || got.Phone != "08123456789" || !got.BirthDate.Time.Equal(customer.BirthDate.Time) {
+		t.Errorf("GetByCustomerNumber() = %+v", got)
+	}
+
+	got.Name = "John Q. Doe"
+	got.Email = "jq.doe@example.com"
+	if message, err := repository.Update(&got, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Update() = %+v, %v", message, err)
+	}
+	customers, err := repository.GetAll(domain.CustomerFilter{Email: "JQ.Doe@example.com"}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(customers) != 1 || customers[0].Name != "John Q. Doe" {
+		t.Errorf("GetAll() by email = %+v", customers)
+	}
+
+	if message, err := repository.DeleteByCustomerNumber(customer.CustomerNumber, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("DeleteByCustomerNumber() = %+v, %v", message, err)
+	}
+	if customers, err := repository.GetAll(domain.CustomerFilter{}, ctx); err != nil || len(customers) != 0 {
+		t.Errorf("GetAll() after delete = %+v, %v", customers, err)
+	}
+}
+
+func TestCustomerRepositoryStoresEncrypted(t *testing.T) {
+	repository, db := newIntegrationRepository(t)
+	ctx := pgtest.Context()
+
+	customer := newCustomer("John Doe", "john.doe@example.com")
+	if message, err := repository.Insert(customer, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() = %+v, %v", message, err)
+	}
+
+	var email, phone string
+	err := db.Primary().QueryRow(`SELECT email, phone FROM customer WHERE customer_number = $1`, customer.CustomerNumber).Scan(&email, &phone)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("row visible without a tenant: %v", err)
+	}
+	err = database.Transaction(ctx, db.Primary(), func(tx *sql.Tx) error {
+		return tx.QueryRow(`SELECT email, phone FROM customer WHERE customer_number = $1`, customer.CustomerNumber).Scan(&email, &phone)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email == customer.Email || phone == customer.Phone {
+		t.Errorf("stored email %q and phone %q in plaintext", email, phone)
+	}
+}
+
+func TestCustomerRepositoryUniqueEmail(t *testing.T) {
+	repository, _ := newIntegrationRepository(t)
+	ctx := pgtest.Context()
+
+	if message, err := repository.Insert(newCustomer("John Doe", "john.doe@example.com"), ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() = %+v, %v", message, err)
+	}
+	// Emails are unique regardless of case.
+	message, err := repository.Insert(newCustomer("Johnny Doe", "John.Doe@example.com"), ctx)
+	if err != nil || message.StatusCode != 500 {
+		t.Errorf("Insert() of a taken email = %+v, %v, want status 500", message, err)
+	}
+
+	other := newCustomer("Jane Doe", "jane.doe@example.com")
+	if message, err := repository.Insert(other, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() = %+v, %v", message, err)
+	}
+	other.Email = "john.doe@example.com"
+	if message, err := repository.Update(other, ctx); err != nil || message.StatusCode != 500 {
+		t.Errorf("Update() to a taken email = %+v, %v, want status 500", message, err)
+	}
+
+	customers, err := repository.GetAll(domain.CustomerFilter{}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(customers) != 2 {
+		t.Errorf("GetAll() = %d customers, want 2", len(customers))
+	}
+}
+
+func TestCustomerRepositoryNotFound(t *testing.T) {
+	repository, _ := newIntegrationRepository(t)
+	ctx := pgtest.Context()
+
+	if _, err := repository.GetByCustomerNumber(42, ctx); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByCustomerNumber() = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := repository.GetRedirect(42, ctx); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetRedirect() = %v, want sql.ErrNoRows", err)
+	}
+	message, err := repository.DeleteByCustomerNumber(42, ctx)
+	if !errors.Is(err, sql.ErrNoRows) || message.StatusCode != 500 {
+		t.Errorf("DeleteByCustomerNumber() = %+v, %v, want sql.ErrNoRows", message, err)
+	}
+}
+
+func TestCustomerRepositoryDeleteCascades(t *testing.T) {
+	repository, db := newIntegrationRepository(t)
+	ctx := pgtest.Context()
+
+	customer := newCustomer("John Doe", "john.doe@example.com")
+	if message, err := repository.Insert(customer, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() = %+v, %v", message, err)
+	}
+	err := database.Transaction(ctx, db.Primary(), func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT INTO customer_note (customer_number, note, author) VALUES ($1, 'called', 'jane')`, customer.CustomerNumber); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO customer_address (customer_number, type, line1, city, postal_code, country) VALUES ($1, 'billing', 'Main St 1', 'Springfield', '12345', 'US')`, customer.CustomerNumber)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message, err := repository.DeleteByCustomerNumber(customer.CustomerNumber, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("DeleteByCustomerNumber() = %+v, %v", message, err)
+	}
+
+	// The primary contact points were created by Insert.
+	for _, table := range []string{"customer_note", "customer_address", "customer_contact_point"} {
+		var count int
+		err := database.Transaction(ctx, db.Primary(), func(tx *sql.Tx) error {
+			return tx.QueryRow(`SELECT count(*) FROM `+table+` WHERE customer_number = $1`, customer.CustomerNumber).Scan(&count)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%s has %d rows of the deleted customer", table, count)
+		}
+	}
+}
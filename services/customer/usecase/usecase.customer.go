@@ -2,20 +2,41 @@ package usecase_customer
 
 import (
 	"context"
+	"customer-playground/customfield"
 	"customer-playground/domain"
+	"customer-playground/pii"
 	"customer-playground/types"
+	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 type customerUseCase struct {
-	customerRepository domain.CustomerRepository
-	logger             *logrus.Logger
+	customerRepository    domain.CustomerRepository
+	customFieldRepository domain.CustomFieldRepository
+	logger                *logrus.Logger
 }
 
-func (c customerUseCase) GetAll(ctx context.Context) ([]domain.Customer, error) {
-	customers, err := c.customerRepository.GetAll(ctx)
+// GetAll lists customers. Custom field filters arrive as strings from the
+// query and are converted to the type of their definition; an unknown field
+// or invalid value is reported as a *customfield.ValidationError.
+func (c customerUseCase) GetAll(filter domain.CustomerFilter, ctx context.Context) ([]domain.Customer, error) {
+	if len(filter.CustomFields) > 0 {
+		definitions, err := c.customFieldRepository.GetAll(ctx)
+		if err != nil {
+			c.logger.Errorf("customerUseCase/GetAll/GetCustomFields :%v", err)
+			return nil, err
+		}
+		filter.CustomFields, err = customfield.ParseFilter(definitions, filter.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	customers, err := c.customerRepository.GetAll(filter, ctx)
 	if err != nil {
 		c.logger.Errorf("customerUseCase/GetAll :%v", err)
 		return nil, err
@@ -25,6 +46,16 @@ func (c customerUseCase) GetAll(ctx context.Context) ([]domain.Customer, error)
 
 func (c customerUseCase) GetByCustomerNumber(customerNumber int, ctx context.Context) (domain.Customer, error) {
 	customer, err := c.customerRepository.GetByCustomerNumber(customerNumber, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Merged customers leave a redirect to the customer they live on in.
+		movedTo, redirectErr := c.customerRepository.GetRedirect(customerNumber, ctx)
+		if redirectErr == nil {
+			return domain.Customer{}, &domain.CustomerMovedError{CustomerNumber: customerNumber, MovedTo: movedTo}
+		}
+		if !errors.Is(redirectErr, sql.ErrNoRows) {
+			err = redirectErr
+		}
+	}
 	if err != nil {
 		c.logger.Errorf("customerUseCase/GetByCustomerNumber :%v", err)
 		return domain.Customer{}, err
@@ -43,6 +74,16 @@ func (c customerUseCase) Insert(customer *domain.Customer, ctx context.Context)
 		customer.UpdatedAt = types.NullTime{Time: now, Valid: true}
 	}
 
+	definitions, err := c.customFieldRepository.GetAll(ctx)
+	if err != nil {
+		c.logger.Errorf("customerUseCase/Insert/GetCustomFields :%v", err)
+		return domain.Response{Message: "Failed to Insert Customer", StatusCode: 500}, err
+	}
+	customer.CustomFields, err = customfield.Validate(definitions, customer.CustomFields, true)
+	if err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+
 	mesage, err := c.customerRepository.Insert(customer, ctx)
 	if err != nil {
 		c.logger.Errorf("customerUseCase/Insert :%v", err)
@@ -74,9 +115,28 @@ func (c customerUseCase) Update(newCustomer *domain.Customer, ctx context.Contex
 	if !newCustomer.BirthDate.Valid {
 		newCustomer.BirthDate = currentCustomer.BirthDate
 	}
+	// Callers without pii:read may send back the masked values they got.
+	masked := pii.MaskCustomer(currentCustomer)
+	if newCustomer.Email, err = pii.Unmask(newCustomer.Email, currentCustomer.Email, masked.Email); err != nil {
+		return domain.Response{Message: "email: " + err.Error(), StatusCode: 400}, nil
+	}
+	if newCustomer.Phone, err = pii.Unmask(newCustomer.Phone, currentCustomer.Phone, masked.Phone); err != nil {
+		return domain.Response{Message: "phone: " + err.Error(), StatusCode: 400}, nil
+	}
 	newCustomer.CreatedAt = currentCustomer.CreatedAt
 	newCustomer.UpdatedAt = types.NullTime{Time: now, Valid: true}
 
+	customFields, err := c.mergeCustomFields(currentCustomer.CustomFields, newCustomer.CustomFields, ctx)
+	if err != nil {
+		var validationErr *customfield.ValidationError
+		if errors.As(err, &validationErr) {
+			return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+		}
+		c.logger.Errorf("customerUseCase/Update/MergeCustomFields :%v", err)
+		return domain.Response{Message: fmt.Sprintf("Failed Update number %d", newCustomer.CustomerNumber), StatusCode: 500}, err
+	}
+	newCustomer.CustomFields = customFields
+
 	message, err = c.customerRepository.Update(newCustomer, ctx)
 	if err != nil {
 		c.logger.Errorf("customerUseCase/Update :%v", err)
@@ -85,6 +145,49 @@ func (c customerUseCase) Update(newCustomer *domain.Customer, ctx context.Contex
 	return message, nil
 }
 
+// mergeCustomFields applies the custom fields of an update to the current
+// ones. Only the fields in the update are validated; a null value removes the
+// field. Values of fields that are no longer defined are dropped.
+func (c customerUseCase) mergeCustomFields(current types.JSONMap, update types.JSONMap, ctx context.Context) (types.JSONMap, error) {
+	definitions, err := c.customFieldRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defined := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		defined[definition.Name] = true
+	}
+
+	changed := make(map[string]interface{}, len(update))
+	merged := make(map[string]interface{}, len(current)+len(update))
+	for name, value := range current {
+		if defined[name] {
+			merged[name] = value
+		}
+	}
+	for name, value := range update {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		changed[name] = value
+	}
+
+	changed, err = customfield.Validate(definitions, changed, false)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range changed {
+		merged[name] = value
+	}
+	for _, definition := range definitions {
+		if _, ok := merged[definition.Name]; definition.Required && !ok {
+			return nil, &customfield.ValidationError{Field: definition.Name, Msg: "is required"}
+		}
+	}
+	return merged, nil
+}
+
 func (c customerUseCase) DeleteByCustomerNumber(customerNumber int, ctx context.Context) (domain.Response, error) {
 	message, err := c.customerRepository.DeleteByCustomerNumber(customerNumber, ctx)
 	if err != nil {
@@ -94,9 +197,10 @@ func (c customerUseCase) DeleteByCustomerNumber(customerNumber int, ctx context.
 	return message, nil
 }
 
-func NewCustomerUseCase(c domain.CustomerRepository, log *logrus.Logger) domain.CustomerUseCase {
+func NewCustomerUseCase(c domain.CustomerRepository, customFieldRepository domain.CustomFieldRepository, log *logrus.Logger) domain.CustomerUseCase {
 	return &customerUseCase{
-		customerRepository: c,
-		logger:             log,
+		customerRepository:    c,
+		customFieldRepository: customFieldRepository,
+		logger:                log,
 	}
 }
@@ -0,0 +1,205 @@
+package usecase_customer
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	repository_customer "customer-playground/services/customer/repository"
+	"customer-playground/types"
+	"database/sql"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type stubCustomFieldRepository struct {
+	domain.CustomFieldRepository
+	definitions []domain.CustomFieldDefinition
+}
+
+func (s *stubCustomFieldRepository) GetAll(ctx context.Context) ([]domain.CustomFieldDefinition, error) {
+	return s.definitions, nil
+}
+
+var definitions = []domain.CustomFieldDefinition{
+	{Name: "tier", Type: domain.CustomFieldTypeEnum, EnumValues: []string{"gold", "silver"}, Required: true},
+	{Name: "visits", Type: domain.CustomFieldTypeInteger},
+	{Name: "newsletter", Type: domain.CustomFieldTypeBoolean},
+}
+
+// newUseCase returns a use case on an in-memory repository holding one
+// customer, and the number of that customer.
+func newUseCase(t *testing.T) (domain.CustomerUseCase, *stubCustomFieldRepository, int, context.Context) {
+	logger := logrus.New()
+	logger.Out = io.Discard
+	ctx := database.WithTenant(context.Background(), 1)
+	customFields := &stubCustomFieldRepository{definitions: definitions}
+	usecase := NewCustomerUseCase(repository_customer.NewMemoryCustomerRepository(), customFields, logger)
+
+	created := types.NullTime{Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Valid: true}
+	customer := &domain.Customer{
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		Phone:        "08123456789",
+		BirthDate:    types.NullTime{Time: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC), Valid: true},
+		CustomFields: types.JSONMap{"tier": "gold", "visits": float64(3)},
+		CreatedAt:    created,
+		UpdatedAt:    created,
+	}
+	if message, err := usecase.Insert(customer, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() = %+v, %v", message, err)
+	}
+	return usecase, customFields, customer.CustomerNumber, ctx
+}
+
+func TestUpdateKeepsEmptyFields(t *testing.T) {
+	usecase, _, customerNumber, ctx := newUseCase(t)
+
+	before := time.Now()
+	update := &domain.Customer{CustomerNumber: customerNumber, Name: "John Q. Doe", CreatedAt: types.NullTime{Time: time.Now(), Valid: true}}
+	if message, err := usecase.Update(update, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Update() = %+v, %v", message, err)
+	}
+
+	got, err := usecase.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "John Q. Doe" || got.Email != "john.doe@example.com" || got.Phone != "08123456789" || !got.BirthDate.Valid {
+		t.Errorf("GetByCustomerNumber() = %+v, want only the name changed", got)
+	}
+	if got.CustomFields["tier"] != "gold" || got.CustomFields["visits"] != float64(3) {
+		t.Errorf("CustomFields = %#v, want them kept", got.CustomFields)
+	}
+	// created_at cannot be changed, updated_at is the time of the update.
+	if want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC); !got.CreatedAt.Time.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", got.CreatedAt.Time, want)
+	}
+	if got.UpdatedAt.Time.Before(before.UTC().Add(-time.Second)) {
+		t.Errorf("UpdatedAt = %v, want the time of the update", got.UpdatedAt.Time)
+	}
+}
+
+func TestUpdateReplacesGivenFields(t *testing.T) {
+	usecase, _, customerNumber, ctx := newUseCase(t)
+
+	update := &domain.Customer{
+		CustomerNumber: customerNumber,
+		Email:          "jq.doe@example.com",
+		Phone:          "08987654321",
+		BirthDate:      types.NullTime{Time: time.Date(1991, 3, 4, 0, 0, 0, 0, time.UTC), Valid: true},
+	}
+	if message, err := usecase.Update(update, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Update() = %+v, %v", message, err)
+	}
+	got, err := usecase.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "John Doe" || got.Email != "jq.doe@example.com" || got.Phone != "08987654321" || got.BirthDate.Time.Year() != 1991 {
+		t.Errorf("GetByCustomerNumber() = %+v", got)
+	}
+}
+
+func TestUpdateWithMaskedValues(t *testing.T) {
+	usecase, _, customerNumber, ctx := newUseCase(t)
+
+	// The masked values of a GET sent back keep the stored ones.
+	update := &domain.Customer{CustomerNumber: customerNumber, Name: "John Q. Doe", Email: "j***@example.com", Phone: "********789"}
+	if message, err := usecase.Update(update, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Update() = %+v, %v", message, err)
+	}
+	got, err := usecase.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != "john.doe@example.com" || got.Phone != "08123456789" {
+		t.Errorf("GetByCustomerNumber() = %+v, want the stored email and phone", got)
+	}
+
+	update = &domain.Customer{CustomerNumber: customerNumber, Email: "x***@example.com"}
+	if message, err := usecase.Update(update, ctx); err != nil || message.StatusCode != 400 {
+		t.Errorf("Update() with another masked email = %+v, %v, want 400", message, err)
+	}
+}
+
+func TestUpdateMergesCustomFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		update types.JSONMap
+		status int
+		want   map[string]interface{}
+	}{
+		{"none", nil, 200, map[string]interface{}{"tier": "gold", "visits": float64(3)}},
+		{"add", types.JSONMap{"newsletter": true}, 200, map[string]interface{}{"tier": "gold", "visits": float64(3), "newsletter": true}},
+		{"change", types.JSONMap{"visits": float64(4)}, 200, map[string]interface{}{"tier": "gold", "visits": float64(4)}},
+		{"remove", types.JSONMap{"visits": nil}, 200, map[string]interface{}{"tier": "gold"}},
+		{"invalid", types.JSONMap{"tier": "bronze"}, 400, nil},
+		{"undefined", types.JSONMap{"shoe_size": float64(42)}, 400, nil},
+		{"remove required", types.JSONMap{"tier": nil}, 400, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usecase, _, customerNumber, ctx := newUseCase(t)
+
+			update := &domain.Customer{CustomerNumber: customerNumber, CustomFields: test.update}
+			message, err := usecase.Update(update, ctx)
+			if err != nil || message.StatusCode != test.status {
+				t.Fatalf("Update() = %+v, %v, want status %d", message, err, test.status)
+			}
+			if test.want == nil {
+				test.want = map[string]interface{}{"tier": "gold", "visits": float64(3)}
+			}
+			got, err := usecase.GetByCustomerNumber(customerNumber, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.CustomFields) != len(test.want) {
+				t.Fatalf("CustomFields = %#v, want %#v", got.CustomFields, test.want)
+			}
+			for name, value := range test.want {
+				if got.CustomFields[name] != value {
+					t.Errorf("CustomFields = %#v, want %#v", got.CustomFields, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateDropsUndefinedCustomFields(t *testing.T) {
+	usecase, customFields, customerNumber, ctx := newUseCase(t)
+
+	// visits is no longer defined.
+	customFields.definitions = definitions[:1]
+	if message, err := usecase.Update(&domain.Customer{CustomerNumber: customerNumber, Name: "John Q. Doe"}, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Update() = %+v, %v", message, err)
+	}
+	got, err := usecase.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.CustomFields["visits"]; ok || got.CustomFields["tier"] != "gold" {
+		t.Errorf("CustomFields = %#v, want only tier", got.CustomFields)
+	}
+}
+
+func TestUpdateMissingCustomer(t *testing.T) {
+	usecase, _, customerNumber, ctx := newUseCase(t)
+
+	message, err := usecase.Update(&domain.Customer{CustomerNumber: customerNumber + 1, Name: "Nobody"}, ctx)
+	if !errors.Is(err, sql.ErrNoRows) || message.StatusCode != 500 {
+		t.Errorf("Update() = %+v, %v, want sql.ErrNoRows", message, err)
+	}
+	// Other tenants cannot update the customer either.
+	other := database.WithTenant(context.Background(), 2)
+	if _, err := usecase.Update(&domain.Customer{CustomerNumber: customerNumber, Name: "Nobody"}, other); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Update() from another tenant = %v, want sql.ErrNoRows", err)
+	}
+	got, err := usecase.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil || got.Name != "John Doe" {
+		t.Errorf("GetByCustomerNumber() = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,216 @@
+package delivery_customeraddress
+
+import (
+	"customer-playground/domain"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type CustomerAddressHandler struct {
+	customerAddressUseCase domain.CustomerAddressUseCase
+	logger                 *logrus.Logger
+}
+
+func NewCustomerAddressHandler(r *gin.Engine, c domain.CustomerAddressUseCase, l *logrus.Logger) *gin.Engine {
+	handler := &CustomerAddressHandler{customerAddressUseCase: c, logger: l}
+
+	r.GET("/customer/:customer_number/addresses", handler.HandlerGetAllCustomerAddress)
+	r.GET("/customer/:customer_number/addresses/:id", handler.HandlerGetByIdCustomerAddress)
+	r.POST("/customer/:customer_number/addresses", handler.HandlerInsertCustomerAddress)
+	r.PUT("/customer/:customer_number/addresses/:id", handler.HandlerUpdateCustomerAddress)
+	r.DELETE("/customer/:customer_number/addresses/:id", handler.HandlerDeleteCustomerAddressById)
+
+	return r
+}
+
+// pathIds parses the customer number and, when present, the address id.
+func pathIds(ctx *gin.Context) (int, int, error) {
+	customerNumber, err := strconv.Atoi(ctx.Param("customer_number"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if ctx.Param("id") == "" {
+		return customerNumber, 0, nil
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return 0, 0, err
+	}
+	return customerNumber, id, nil
+}
+
+// HandlerGetAllCustomerAddress godoc
+// @Summary Get customer addresses
+// @Description Retrieves every address of a customer
+// @Tags customer-address
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Success 200 {array} domain.CustomerAddress
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/addresses [get]
+func (c *CustomerAddressHandler) HandlerGetAllCustomerAddress(ctx *gin.Context) {
+	customerNumber, _, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerGetAllCustomerAddress/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	customerAddresses, err := c.customerAddressUseCase.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerGetAllCustomerAddress", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, customerAddresses)
+	return
+}
+
+// HandlerGetByIdCustomerAddress godoc
+// @Summary Get a customer address
+// @Description Retrieves one address of a customer by its ID
+// @Tags customer-address
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param id path int true "Customer Address ID"
+// @Success 200 {object} domain.CustomerAddress
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/addresses/{id} [get]
+func (c *CustomerAddressHandler) HandlerGetByIdCustomerAddress(ctx *gin.Context) {
+	customerNumber, id, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerGetByIdCustomerAddress/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	customerAddress, err := c.customerAddressUseCase.GetById(customerNumber, id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerGetByIdCustomerAddress", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, customerAddress)
+	return
+}
+
+// HandlerInsertCustomerAddress godoc
+// @Summary Add a customer address
+// @Description Adds a billing or shipping address to a customer. Marking it as default clears the previous default of the same type.
+// @Tags customer-address
+// @Accept json
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param customerAddress body domain.CustomerAddress true "Customer Address Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/addresses [post]
+func (c *CustomerAddressHandler) HandlerInsertCustomerAddress(ctx *gin.Context) {
+	customerNumber, _, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerInsertCustomerAddress/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	var customerAddress domain.CustomerAddress
+	err = ctx.Bind(&customerAddress)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerInsertCustomerAddress/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	customerAddress.CustomerNumber = customerNumber
+	message, err := c.customerAddressUseCase.Insert(&customerAddress, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerInsertCustomerAddress/Insert", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerUpdateCustomerAddress godoc
+// @Summary Update a customer address
+// @Description Updates an address of a customer. Empty fields keep their current value.
+// @Tags customer-address
+// @Accept json
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param id path int true "Customer Address ID"
+// @Param customerAddress body domain.CustomerAddress true "Customer Address Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/addresses/{id} [put]
+func (c *CustomerAddressHandler) HandlerUpdateCustomerAddress(ctx *gin.Context) {
+	customerNumber, id, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerUpdateCustomerAddress/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	var customerAddress domain.CustomerAddress
+	err = ctx.Bind(&customerAddress)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerUpdateCustomerAddress/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	customerAddress.CustomerNumber = customerNumber
+	customerAddress.ID = id
+	message, err := c.customerAddressUseCase.Update(&customerAddress, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerUpdateCustomerAddress/Update", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerDeleteCustomerAddressById godoc
+// @Summary Delete a customer address
+// @Description Deletes an address of a customer by its ID
+// @Tags customer-address
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param id path int true "Customer Address ID"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/addresses/{id} [delete]
+func (c *CustomerAddressHandler) HandlerDeleteCustomerAddressById(ctx *gin.Context) {
+	customerNumber, id, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerDeleteCustomerAddressById/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.customerAddressUseCase.DeleteById(customerNumber, id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerAddressHandler/HandlerDeleteCustomerAddressById/Delete", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
@@ -0,0 +1,306 @@
+package repository_customeraddress
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queryGetByCustomerNumber = `
+		SELECT
+			id,
+			customer_number,
+			type,
+			is_default,
+			line1,
+			line2,
+			city,
+			state,
+			postal_code,
+			country,
+			created_at,
+			updated_at
+		FROM customer_address
+		WHERE tenant_id = app_tenant() AND customer_number = $1
+		ORDER BY type, is_default DESC, id
+	`
+	queryGetById = `
+		SELECT
+			id,
+			customer_number,
+			type,
+			is_default,
+			line1,
+			line2,
+			city,
+			state,
+			postal_code,
+			country,
+			created_at,
+			updated_at
+		FROM customer_address
+		WHERE tenant_id = app_tenant() AND customer_number = $1 AND id = $2
+	`
+	queryClearDefault = `
+		UPDATE customer_address SET
+			is_default = FALSE
+		WHERE tenant_id = app_tenant() AND customer_number = $1 AND type = $2 AND id <> $3 AND is_default
+	`
+	queryInsert = `
+		INSERT INTO customer_address(
+			customer_number,
+			type,
+			is_default,
+			line1,
+			line2,
+			city,
+			state,
+			postal_code,
+			country,
+			created_at,
+			updated_at) VALUES (
+		$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+	)
+		RETURNING id
+	`
+	queryUpdate = `
+		UPDATE customer_address SET
+			type = $3,
+			is_default = $4,
+			line1 = $5,
+			line2 = $6,
+			city = $7,
+			state = $8,
+			postal_code = $9,
+			country = $10,
+			updated_at = $11
+		WHERE
+			tenant_id = app_tenant() AND customer_number = $1 AND id = $2
+	`
+	queryDeleteById = `
+		DELETE
+		FROM customer_address
+		WHERE tenant_id = app_tenant() AND customer_number = $1 AND id = $2
+	`
+)
+
+type customerAddressRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	logger *logrus.Logger
+}
+
+func scanCustomerAddress(row interface{ Scan(...interface{}) error }, customerAddress *domain.CustomerAddress) error {
+	return row.Scan(
+		&customerAddress.ID,
+		&customerAddress.CustomerNumber,
+		&customerAddress.Type,
+		&customerAddress.IsDefault,
+		&customerAddress.Line1,
+		&customerAddress.Line2,
+		&customerAddress.City,
+		&customerAddress.State,
+		&customerAddress.PostalCode,
+		&customerAddress.Country,
+		&customerAddress.CreatedAt,
+		&customerAddress.UpdatedAt,
+	)
+}
+
+func (c customerAddressRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) ([]domain.CustomerAddress, error) {
+	var customerAddresses []domain.CustomerAddress
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetByCustomerNumber, func(stmt *sql.Stmt) error {
+				customerAddresses = nil
+				rows, err := stmt.QueryContext(ctx, customerNumber)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var customerAddress domain.CustomerAddress
+					if err := scanCustomerAddress(rows, &customerAddress); err != nil {
+						return err
+					}
+
+					customerAddresses = append(customerAddresses, customerAddress)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return customerAddresses, nil
+}
+
+func (c customerAddressRepository) GetById(customerNumber int, id int, ctx context.Context) (domain.CustomerAddress, error) {
+	var customerAddress domain.CustomerAddress
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetById, func(stmt *sql.Stmt) error {
+				return scanCustomerAddress(stmt.QueryRowContext(ctx, customerNumber, id), &customerAddress)
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return domain.CustomerAddress{}, err
+	}
+
+	return customerAddress, nil
+}
+
+func (c customerAddressRepository) Insert(customerAddress *domain.CustomerAddress, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	err := c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), func(tx *database.Tx) error {
+			if err := c.clearDefault(tx, customerAddress); err != nil {
+				return err
+			}
+			return tx.QueryRow(queryInsert,
+				customerAddress.CustomerNumber,
+				customerAddress.Type,
+				customerAddress.IsDefault,
+				customerAddress.Line1,
+				customerAddress.Line2,
+				customerAddress.City,
+				customerAddress.State,
+				customerAddress.PostalCode,
+				customerAddress.Country,
+				customerAddress.CreatedAt,
+				customerAddress.UpdatedAt,
+			).Scan(&customerAddress.ID)
+		})
+	})
+	if isForeignKeyViolation(err) {
+		message.Message = fmt.Sprintf("No customer found with number %d", customerAddress.CustomerNumber)
+		message.StatusCode = 400
+		return message, nil
+	}
+	if err != nil {
+		message.Message = "Failed to Insert Customer Address"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, nil
+	}
+	message.Message = fmt.Sprintf("Succes Insert Customer Address with id %d", customerAddress.ID)
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c customerAddressRepository) Update(customerAddress *domain.CustomerAddress, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	err := c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), func(tx *database.Tx) error {
+			if err := c.clearDefault(tx, customerAddress); err != nil {
+				return err
+			}
+			result, err := tx.Exec(queryUpdate,
+				customerAddress.CustomerNumber,
+				customerAddress.ID,
+				customerAddress.Type,
+				customerAddress.IsDefault,
+				customerAddress.Line1,
+				customerAddress.Line2,
+				customerAddress.City,
+				customerAddress.State,
+				customerAddress.PostalCode,
+				customerAddress.Country,
+				customerAddress.UpdatedAt,
+			)
+			if err != nil {
+				return err
+			}
+			if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+				return errors.Join(sql.ErrNoRows, err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Update id %d", customerAddress.ID)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, nil
+	}
+	message.Message = "Succes Update"
+	message.StatusCode = 200
+	return message, nil
+}
+
+// clearDefault keeps a single default address per customer and type. It runs
+// before the write so the partial unique index is never violated.
+func (c customerAddressRepository) clearDefault(tx *database.Tx, customerAddress *domain.CustomerAddress) error {
+	if !customerAddress.IsDefault {
+		return nil
+	}
+	_, err := tx.Exec(queryClearDefault, customerAddress.CustomerNumber, customerAddress.Type, customerAddress.ID)
+	return err
+}
+
+func (c customerAddressRepository) DeleteById(customerNumber int, id int, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	var result sql.Result
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryDeleteById, func(stmt *sql.Stmt) error {
+			var err error
+			result, err = stmt.ExecContext(ctx, customerNumber, id)
+			return err
+		})
+	})
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Delete id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		message.Message = fmt.Sprintf("Could not determine rows affected for id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to get rows affected: %v", err)
+		return message, err
+	}
+	if rowsAffected == 0 {
+		message.Message = fmt.Sprintf("No customer address found with id %d", id)
+		message.StatusCode = 500
+		return message, sql.ErrNoRows
+	}
+	message.StatusCode = 200
+	message.Message = "Succes Delete!!"
+	return message, nil
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// Close releases the prepared statements held by the repository.
+func (c customerAddressRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewCustomerAddressRepository(db *database.DB, retry database.RetryPolicy, log *logrus.Logger) domain.CustomerAddressRepository {
+	return &customerAddressRepository{
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
+		logger: log,
+	}
+}
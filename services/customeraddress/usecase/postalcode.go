@@ -0,0 +1,56 @@
+package usecase_customeraddress
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// postalCodeFormats holds the postal code format of each supported ISO 3166-1
+// alpha-2 country code. Other countries only get the generic check.
+var postalCodeFormats = map[string]*regexp.Regexp{
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z] ?\d[ABCEGHJ-NPRSTV-Z]\d$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"ID": regexp.MustCompile(`^\d{5}$`),
+	"IN": regexp.MustCompile(`^[1-9]\d{5}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"MY": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"PH": regexp.MustCompile(`^\d{4}$`),
+	"SG": regexp.MustCompile(`^\d{6}$`),
+	"TH": regexp.MustCompile(`^\d{5}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"VN": regexp.MustCompile(`^\d{6}$`),
+}
+
+var (
+	countryCodeFormat   = regexp.MustCompile(`^[A-Z]{2}$`)
+	genericPostalFormat = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`)
+)
+
+// validatePostalCode checks postalCode against the format of country. Both
+// are expected to be upper case already.
+func validatePostalCode(country string, postalCode string) error {
+	if !countryCodeFormat.MatchString(country) {
+		return fmt.Errorf("country %q is not an ISO 3166-1 alpha-2 code", country)
+	}
+	format, ok := postalCodeFormats[country]
+	if !ok {
+		format = genericPostalFormat
+	}
+	if !format.MatchString(postalCode) {
+		return fmt.Errorf("postal code %q is not valid for country %s", postalCode, country)
+	}
+	return nil
+}
+
+func normalizePostalCode(postalCode string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(postalCode), " "))
+}
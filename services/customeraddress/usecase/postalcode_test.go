@@ -0,0 +1,29 @@
+package usecase_customeraddress
+
+import "testing"
+
+func TestValidatePostalCode(t *testing.T) {
+	tests := []struct {
+		country    string
+		postalCode string
+		valid      bool
+	}{
+		{"ID", "12190", true},
+		{"ID", "1219", false},
+		{"US", "94105-1234", true},
+		{"US", "9410", false},
+		{"GB", normalizePostalCode("sw1a  1aa"), true},
+		{"NL", "1012 AB", true},
+		{"CA", "K1A 0B1", true},
+		{"JP", "100-0001", true},
+		{"ZZ", "AB-123", true},
+		{"ZZ", "", false},
+		{"Indonesia", "12190", false},
+	}
+	for _, test := range tests {
+		err := validatePostalCode(test.country, test.postalCode)
+		if (err == nil) != test.valid {
+			t.Errorf("validatePostalCode(%q, %q) = %v, want valid=%v", test.country, test.postalCode, err, test.valid)
+		}
+	}
+}
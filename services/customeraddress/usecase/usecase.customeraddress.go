@@ -0,0 +1,133 @@
+package usecase_customeraddress
+
+import (
+	"context"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type customerAddressUseCase struct {
+	customerAddressRepository domain.CustomerAddressRepository
+	logger                    *logrus.Logger
+}
+
+func (c customerAddressUseCase) GetByCustomerNumber(customerNumber int, ctx context.Context) ([]domain.CustomerAddress, error) {
+	customerAddresses, err := c.customerAddressRepository.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("customerAddressUseCase/GetByCustomerNumber :%v", err)
+		return nil, err
+	}
+	return customerAddresses, nil
+}
+
+func (c customerAddressUseCase) GetById(customerNumber int, id int, ctx context.Context) (domain.CustomerAddress, error) {
+	customerAddress, err := c.customerAddressRepository.GetById(customerNumber, id, ctx)
+	if err != nil {
+		c.logger.Errorf("customerAddressUseCase/GetById :%v", err)
+		return domain.CustomerAddress{}, err
+	}
+	return customerAddress, nil
+}
+
+func (c customerAddressUseCase) Insert(customerAddress *domain.CustomerAddress, ctx context.Context) (domain.Response, error) {
+	now := time.Now()
+	customerAddress.CreatedAt = types.NullTime{Time: now, Valid: true}
+	customerAddress.UpdatedAt = types.NullTime{Time: now, Valid: true}
+
+	if err := validate(customerAddress); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+
+	message, err := c.customerAddressRepository.Insert(customerAddress, ctx)
+	if err != nil {
+		c.logger.Errorf("customerAddressUseCase/Insert :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+// Update merges newCustomerAddress into the stored address: empty fields keep
+// their current value, while IsDefault is always taken from the payload.
+func (c customerAddressUseCase) Update(newCustomerAddress *domain.CustomerAddress, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	currentCustomerAddress, err := c.customerAddressRepository.GetById(newCustomerAddress.CustomerNumber, newCustomerAddress.ID, ctx)
+	if err != nil {
+		message.Message = "this id is not found"
+		message.StatusCode = 500
+		return message, err
+	}
+
+	if newCustomerAddress.Type == "" {
+		newCustomerAddress.Type = currentCustomerAddress.Type
+	}
+	if newCustomerAddress.Line1 == "" {
+		newCustomerAddress.Line1 = currentCustomerAddress.Line1
+	}
+	if newCustomerAddress.Line2 == "" {
+		newCustomerAddress.Line2 = currentCustomerAddress.Line2
+	}
+	if newCustomerAddress.City == "" {
+		newCustomerAddress.City = currentCustomerAddress.City
+	}
+	if newCustomerAddress.State == "" {
+		newCustomerAddress.State = currentCustomerAddress.State
+	}
+	if newCustomerAddress.PostalCode == "" {
+		newCustomerAddress.PostalCode = currentCustomerAddress.PostalCode
+	}
+	if newCustomerAddress.Country == "" {
+		newCustomerAddress.Country = currentCustomerAddress.Country
+	}
+	newCustomerAddress.CreatedAt = currentCustomerAddress.CreatedAt
+	newCustomerAddress.UpdatedAt = types.NullTime{Time: time.Now(), Valid: true}
+
+	if err := validate(newCustomerAddress); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+
+	message, err = c.customerAddressRepository.Update(newCustomerAddress, ctx)
+	if err != nil {
+		c.logger.Errorf("customerAddressUseCase/Update :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+func (c customerAddressUseCase) DeleteById(customerNumber int, id int, ctx context.Context) (domain.Response, error) {
+	message, err := c.customerAddressRepository.DeleteById(customerNumber, id, ctx)
+	if err != nil {
+		c.logger.Errorf("customerAddressUseCase/DeleteById :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+// validate normalises the address in place and reports the first problem.
+func validate(customerAddress *domain.CustomerAddress) error {
+	customerAddress.Type = strings.ToLower(strings.TrimSpace(customerAddress.Type))
+	customerAddress.Country = strings.ToUpper(strings.TrimSpace(customerAddress.Country))
+	customerAddress.PostalCode = normalizePostalCode(customerAddress.PostalCode)
+
+	if customerAddress.Type != domain.AddressTypeBilling && customerAddress.Type != domain.AddressTypeShipping {
+		return fmt.Errorf("type must be %q or %q", domain.AddressTypeBilling, domain.AddressTypeShipping)
+	}
+	if strings.TrimSpace(customerAddress.Line1) == "" {
+		return fmt.Errorf("line1 is required")
+	}
+	if strings.TrimSpace(customerAddress.City) == "" {
+		return fmt.Errorf("city is required")
+	}
+	return validatePostalCode(customerAddress.Country, customerAddress.PostalCode)
+}
+
+func NewCustomerAddressUseCase(c domain.CustomerAddressRepository, log *logrus.Logger) domain.CustomerAddressUseCase {
+	return &customerAddressUseCase{
+		customerAddressRepository: c,
+		logger:                    log,
+	}
+}
@@ -0,0 +1,292 @@
+package delivery_customercontact
+
+import (
+	"customer-playground/domain"
+	"customer-playground/pii"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type CustomerContactPointHandler struct {
+	customerContactPointUseCase domain.CustomerContactPointUseCase
+	logger                      *logrus.Logger
+}
+
+func NewCustomerContactPointHandler(r *gin.Engine, c domain.CustomerContactPointUseCase, l *logrus.Logger) *gin.Engine {
+	handler := &CustomerContactPointHandler{customerContactPointUseCase: c, logger: l}
+
+	r.GET("/customer/:customer_number/contact-points", handler.HandlerGetAllCustomerContactPoint)
+	r.GET("/customer/:customer_number/contact-points/:id", handler.HandlerGetByIdCustomerContactPoint)
+	r.POST("/customer/:customer_number/contact-points", handler.HandlerInsertCustomerContactPoint)
+	r.PUT("/customer/:customer_number/contact-points/:id", handler.HandlerUpdateCustomerContactPoint)
+	r.DELETE("/customer/:customer_number/contact-points/:id", handler.HandlerDeleteCustomerContactPointById)
+	r.POST("/customer/:customer_number/contact-points/:id/verify", handler.HandlerStartVerification)
+	r.POST("/customer/:customer_number/contact-points/:id/confirm", handler.HandlerConfirmVerification)
+
+	return r
+}
+
+// pathIds parses the customer number and, when present, the contact point id.
+func pathIds(ctx *gin.Context) (int, int, error) {
+	customerNumber, err := strconv.Atoi(ctx.Param("customer_number"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if ctx.Param("id") == "" {
+		return customerNumber, 0, nil
+	}
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return 0, 0, err
+	}
+	return customerNumber, id, nil
+}
+
+// HandlerGetAllCustomerContactPoint godoc
+// @Summary Get customer contact points
+// @Description Retrieves every email address and phone number of a customer. Values are masked unless the caller holds pii:read.
+// @Tags customer-contact-point
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Success 200 {array} domain.CustomerContactPoint
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/contact-points [get]
+func (c *CustomerContactPointHandler) HandlerGetAllCustomerContactPoint(ctx *gin.Context) {
+	customerNumber, _, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerGetAllCustomerContactPoint/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	contactPoints, err := c.customerContactPointUseCase.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerGetAllCustomerContactPoint", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pii.ContactPoints(ctx, contactPoints))
+	return
+}
+
+// HandlerGetByIdCustomerContactPoint godoc
+// @Summary Get a customer contact point
+// @Description Retrieves one contact point of a customer by its ID
+// @Tags customer-contact-point
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param id path int true "Contact Point ID"
+// @Success 200 {object} domain.CustomerContactPoint
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/contact-points/{id} [get]
+func (c *CustomerContactPointHandler) HandlerGetByIdCustomerContactPoint(ctx *gin.Context) {
+	customerNumber, id, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerGetByIdCustomerContactPoint/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	contactPoint, err := c.customerContactPointUseCase.GetById(customerNumber, id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerGetByIdCustomerContactPoint", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pii.ContactPoint(ctx, contactPoint))
+	return
+}
+
+// HandlerInsertCustomerContactPoint godoc
+// @Summary Add a customer contact point
+// @Description Adds an email address or phone number to a customer. The first contact point of a type becomes primary; marking another one as primary replaces it.
+// @Tags customer-contact-point
+// @Accept json
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param contactPoint body domain.CustomerContactPoint true "Contact Point Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/contact-points [post]
+func (c *CustomerContactPointHandler) HandlerInsertCustomerContactPoint(ctx *gin.Context) {
+	customerNumber, _, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerInsertCustomerContactPoint/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	var contactPoint domain.CustomerContactPoint
+	err = ctx.Bind(&contactPoint)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerInsertCustomerContactPoint/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	contactPoint.CustomerNumber = customerNumber
+	message, err := c.customerContactPointUseCase.Insert(&contactPoint, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerInsertCustomerContactPoint/Insert", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerUpdateCustomerContactPoint godoc
+// @Summary Update a customer contact point
+// @Description Updates a contact point of a customer. Empty fields keep their current value, the type cannot change and changing the value clears its verification.
+// @Tags customer-contact-point
+// @Accept json
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param id path int true "Contact Point ID"
+// @Param contactPoint body domain.CustomerContactPoint true "Contact Point Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/contact-points/{id} [put]
+func (c *CustomerContactPointHandler) HandlerUpdateCustomerContactPoint(ctx *gin.Context) {
+	customerNumber, id, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerUpdateCustomerContactPoint/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	var contactPoint domain.CustomerContactPoint
+	err = ctx.Bind(&contactPoint)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerUpdateCustomerContactPoint/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	contactPoint.CustomerNumber = customerNumber
+	contactPoint.ID = id
+	message, err := c.customerContactPointUseCase.Update(&contactPoint, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerUpdateCustomerContactPoint/Update", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerDeleteCustomerContactPointById godoc
+// @Summary Delete a customer contact point
+// @Description Deletes a contact point of a customer by its ID. Deleting a primary promotes the next contact point of the same type.
+// @Tags customer-contact-point
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param id path int true "Contact Point ID"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/contact-points/{id} [delete]
+func (c *CustomerContactPointHandler) HandlerDeleteCustomerContactPointById(ctx *gin.Context) {
+	customerNumber, id, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerDeleteCustomerContactPointById/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.customerContactPointUseCase.DeleteById(customerNumber, id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerDeleteCustomerContactPointById/Delete", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerStartVerification godoc
+// @Summary Send a verification code
+// @Description Sends a one-time code to the contact point. The code expires after 10 minutes and a new request replaces any pending code.
+// @Tags customer-contact-point
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param id path int true "Contact Point ID"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/contact-points/{id}/verify [post]
+func (c *CustomerContactPointHandler) HandlerStartVerification(ctx *gin.Context) {
+	customerNumber, id, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerStartVerification/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.customerContactPointUseCase.StartVerification(customerNumber, id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerStartVerification/StartVerification", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerConfirmVerification godoc
+// @Summary Confirm a verification code
+// @Description Marks the contact point as verified when the code matches. A code can be tried 5 times.
+// @Tags customer-contact-point
+// @Accept json
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param id path int true "Contact Point ID"
+// @Param code body domain.ContactVerificationCode true "Verification Code"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/contact-points/{id}/confirm [post]
+func (c *CustomerContactPointHandler) HandlerConfirmVerification(ctx *gin.Context) {
+	customerNumber, id, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerConfirmVerification/ParsePath", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	var code domain.ContactVerificationCode
+	err = ctx.Bind(&code)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerConfirmVerification/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.customerContactPointUseCase.ConfirmVerification(customerNumber, id, code.Code, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerContactPointHandler/HandlerConfirmVerification/ConfirmVerification", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
@@ -0,0 +1,551 @@
+package repository_customercontact
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"customer-playground/encryption"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queryGetByCustomerNumber = `
+		SELECT
+			id,
+			customer_number,
+			type,
+			label,
+			value,
+			is_primary,
+			verified_at,
+			created_at,
+			updated_at
+		FROM customer_contact_point
+		WHERE tenant_id = app_tenant() AND customer_number = $1
+		ORDER BY type, is_primary DESC, id
+	`
+	queryGetById = `
+		SELECT
+			id,
+			customer_number,
+			type,
+			label,
+			value,
+			is_primary,
+			verified_at,
+			created_at,
+			updated_at
+		FROM customer_contact_point
+		WHERE tenant_id = app_tenant() AND customer_number = $1 AND id = $2
+	`
+	queryHasPrimary = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM customer_contact_point
+			WHERE tenant_id = app_tenant() AND customer_number = $1 AND type = $2 AND is_primary
+		)
+	`
+	queryClearPrimary = `
+		UPDATE customer_contact_point SET
+			is_primary = FALSE
+		WHERE tenant_id = app_tenant() AND customer_number = $1 AND type = $2 AND id <> $3 AND is_primary
+	`
+	queryInsert = `
+		INSERT INTO customer_contact_point(
+			customer_number,
+			type,
+			label,
+			value,
+			value_index,
+			is_primary,
+			created_at,
+			updated_at) VALUES (
+		$1, $2, $3, $4, $5, $6, $7, $8
+	)
+		RETURNING id
+	`
+	// Values are compared by blind index, as encrypted ones differ on every
+	// write.
+	queryUpdate = `
+		UPDATE customer_contact_point SET
+			label = $3,
+			value = $4,
+			value_index = $5,
+			is_primary = $6,
+			verified_at = CASE WHEN value_index = $5 THEN verified_at ELSE NULL END,
+			updated_at = $7
+		WHERE
+			tenant_id = app_tenant() AND customer_number = $1 AND id = $2
+	`
+	queryDeleteById = `
+		DELETE
+		FROM customer_contact_point
+		WHERE tenant_id = app_tenant() AND customer_number = $1 AND id = $2
+		RETURNING type, is_primary
+	`
+	// queryPromotePrimary picks a new primary after the old one was deleted,
+	// preferring verified contact points.
+	queryPromotePrimary = `
+		UPDATE customer_contact_point SET
+			is_primary = TRUE
+		WHERE tenant_id = app_tenant() AND id = (
+			SELECT id
+			FROM customer_contact_point
+			WHERE tenant_id = app_tenant() AND customer_number = $1 AND type = $2
+			ORDER BY verified_at IS NULL, id
+			LIMIT 1
+		)
+	`
+	// querySyncCustomer mirrors the primary contact points into customer.
+	// Emails are compared by blind index; phones are copied as stored in both
+	// directions, so the stored values match while they are in sync.
+	querySyncCustomer = `
+		WITH primary_contact AS (
+			SELECT
+				(SELECT value FROM customer_contact_point WHERE tenant_id = app_tenant() AND customer_number = $1 AND type = 'email' AND is_primary) AS email,
+				(SELECT value_index FROM customer_contact_point WHERE tenant_id = app_tenant() AND customer_number = $1 AND type = 'email' AND is_primary) AS email_index,
+				(SELECT value FROM customer_contact_point WHERE tenant_id = app_tenant() AND customer_number = $1 AND type = 'phone' AND is_primary) AS phone
+		)
+		UPDATE customer SET
+			email = COALESCE(p.email, customer.email),
+			email_index = COALESCE(p.email_index, customer.email_index),
+			phone = COALESCE(p.phone, ''),
+			updated_at = CURRENT_TIMESTAMP
+		FROM primary_contact p
+		WHERE customer.tenant_id = app_tenant() AND customer.customer_number = $1
+			AND (customer.email_index IS DISTINCT FROM COALESCE(p.email_index, customer.email_index)
+				OR customer.phone IS DISTINCT FROM COALESCE(p.phone, ''))
+	`
+	querySaveVerification = `
+		INSERT INTO customer_contact_verification(
+			contact_point_id,
+			code_hash,
+			attempts,
+			expires_at) VALUES (
+		$1, $2, $3, $4
+	)
+		ON CONFLICT (contact_point_id) DO UPDATE SET
+			code_hash = EXCLUDED.code_hash,
+			attempts = EXCLUDED.attempts,
+			expires_at = EXCLUDED.expires_at
+	`
+	queryGetVerification = `
+		SELECT
+			contact_point_id,
+			code_hash,
+			attempts,
+			expires_at
+		FROM customer_contact_verification
+		WHERE tenant_id = app_tenant() AND contact_point_id = $1
+	`
+	queryAddVerificationAttempt = `
+		UPDATE customer_contact_verification SET
+			attempts = attempts + 1
+		WHERE tenant_id = app_tenant() AND contact_point_id = $1 AND attempts < $2
+		RETURNING attempts
+	`
+	queryDeleteVerification = `
+		DELETE
+		FROM customer_contact_verification
+		WHERE tenant_id = app_tenant() AND contact_point_id = $1
+	`
+	// queryGetStale finds contact points whose value is not sealed as
+	// configured: $1 matches values sealed with the current key, $2 any sealed
+	// value and $3 and $4 tell whether emails and phones are encrypted.
+	queryGetStale = `
+		SELECT
+			id,
+			type,
+			value
+		FROM customer_contact_point
+		WHERE tenant_id = app_tenant() AND (
+			value_index IS NULL
+			OR CASE WHEN (type = 'email' AND $3) OR (type = 'phone' AND $4) THEN value NOT LIKE $1 ELSE value LIKE $2 END
+		)
+		ORDER BY id
+		LIMIT $5
+		FOR UPDATE SKIP LOCKED
+	`
+	queryReEncrypt = `
+		UPDATE customer_contact_point SET
+			value = $2,
+			value_index = $3
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+	queryMarkVerified = `
+		UPDATE customer_contact_point SET
+			verified_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+)
+
+type customerContactPointRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	cipher *encryption.Cipher
+	logger *logrus.Logger
+}
+
+// scan reads a contact point and decrypts its value, which is encrypted as
+// the customer field of its type.
+func (c customerContactPointRepository) scan(ctx context.Context, row interface{ Scan(...interface{}) error }, contactPoint *domain.CustomerContactPoint) error {
+	err := row.Scan(
+		&contactPoint.ID,
+		&contactPoint.CustomerNumber,
+		&contactPoint.Type,
+		&contactPoint.Label,
+		&contactPoint.Value,
+		&contactPoint.IsPrimary,
+		&contactPoint.VerifiedAt,
+		&contactPoint.CreatedAt,
+		&contactPoint.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	contactPoint.Value, err = c.cipher.Open(ctx, contactPoint.Type, contactPoint.Value)
+	return err
+}
+
+// seal returns the stored value of a contact point and its blind index.
+func (c customerContactPointRepository) seal(ctx context.Context, contactPoint *domain.CustomerContactPoint) (string, string, error) {
+	value, err := c.cipher.Seal(ctx, contactPoint.Type, contactPoint.Value)
+	if err != nil {
+		return "", "", err
+	}
+	return value, c.cipher.BlindIndex(contactPoint.Type, contactPoint.Value), nil
+}
+
+func (c customerContactPointRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) ([]domain.CustomerContactPoint, error) {
+	var contactPoints []domain.CustomerContactPoint
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetByCustomerNumber, func(stmt *sql.Stmt) error {
+				contactPoints = nil
+				rows, err := stmt.QueryContext(ctx, customerNumber)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var contactPoint domain.CustomerContactPoint
+					if err := c.scan(ctx, rows, &contactPoint); err != nil {
+						return err
+					}
+
+					contactPoints = append(contactPoints, contactPoint)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return contactPoints, nil
+}
+
+func (c customerContactPointRepository) GetById(customerNumber int, id int, ctx context.Context) (domain.CustomerContactPoint, error) {
+	var contactPoint domain.CustomerContactPoint
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetById, func(stmt *sql.Stmt) error {
+				return c.scan(ctx, stmt.QueryRowContext(ctx, customerNumber, id), &contactPoint)
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return domain.CustomerContactPoint{}, err
+	}
+
+	return contactPoint, nil
+}
+
+func (c customerContactPointRepository) Insert(contactPoint *domain.CustomerContactPoint, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	value, valueIndex, err := c.seal(ctx, contactPoint)
+	if err != nil {
+		message.Message = "Failed to Insert Customer Contact Point"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to encrypt contact point: %v", err)
+		return message, nil
+	}
+	err = c.transaction(ctx, func(tx *database.Tx) error {
+		if !contactPoint.IsPrimary {
+			// The first contact point of a type becomes its primary.
+			var hasPrimary bool
+			if err := tx.QueryRow(queryHasPrimary, contactPoint.CustomerNumber, contactPoint.Type).Scan(&hasPrimary); err != nil {
+				return err
+			}
+			contactPoint.IsPrimary = !hasPrimary
+		}
+		if contactPoint.IsPrimary {
+			if _, err := tx.Exec(queryClearPrimary, contactPoint.CustomerNumber, contactPoint.Type, 0); err != nil {
+				return err
+			}
+		}
+		err := tx.QueryRow(queryInsert,
+			contactPoint.CustomerNumber,
+			contactPoint.Type,
+			contactPoint.Label,
+			value,
+			valueIndex,
+			contactPoint.IsPrimary,
+			contactPoint.CreatedAt,
+			contactPoint.UpdatedAt,
+		).Scan(&contactPoint.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(querySyncCustomer, contactPoint.CustomerNumber)
+		return err
+	})
+	if message, ok := constraintMessage(err, contactPoint); ok {
+		return message, nil
+	}
+	if err != nil {
+		message.Message = "Failed to Insert Customer Contact Point"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, nil
+	}
+	message.Message = fmt.Sprintf("Succes Insert Customer Contact Point with id %d", contactPoint.ID)
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c customerContactPointRepository) Update(contactPoint *domain.CustomerContactPoint, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	value, valueIndex, err := c.seal(ctx, contactPoint)
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Update id %d", contactPoint.ID)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to encrypt contact point: %v", err)
+		return message, nil
+	}
+	err = c.transaction(ctx, func(tx *database.Tx) error {
+		if contactPoint.IsPrimary {
+			if _, err := tx.Exec(queryClearPrimary, contactPoint.CustomerNumber, contactPoint.Type, contactPoint.ID); err != nil {
+				return err
+			}
+		}
+		result, err := tx.Exec(queryUpdate,
+			contactPoint.CustomerNumber,
+			contactPoint.ID,
+			contactPoint.Label,
+			value,
+			valueIndex,
+			contactPoint.IsPrimary,
+			contactPoint.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+			return errors.Join(sql.ErrNoRows, err)
+		}
+		_, err = tx.Exec(querySyncCustomer, contactPoint.CustomerNumber)
+		return err
+	})
+	if message, ok := constraintMessage(err, contactPoint); ok {
+		return message, nil
+	}
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Update id %d", contactPoint.ID)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, nil
+	}
+	message.Message = "Succes Update"
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c customerContactPointRepository) DeleteById(customerNumber int, id int, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	err := c.transaction(ctx, func(tx *database.Tx) error {
+		var contactType string
+		var wasPrimary bool
+		if err := tx.QueryRow(queryDeleteById, customerNumber, id).Scan(&contactType, &wasPrimary); err != nil {
+			return err
+		}
+		if wasPrimary {
+			if _, err := tx.Exec(queryPromotePrimary, customerNumber, contactType); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(querySyncCustomer, customerNumber)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		message.Message = fmt.Sprintf("No customer contact point found with id %d", id)
+		message.StatusCode = 500
+		return message, err
+	}
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Delete id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	message.StatusCode = 200
+	message.Message = "Succes Delete!!"
+	return message, nil
+}
+
+func (c customerContactPointRepository) SaveVerification(verification *domain.ContactVerification, ctx context.Context) error {
+	return c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), querySaveVerification, func(stmt *sql.Stmt) error {
+			_, err := stmt.ExecContext(ctx,
+				verification.ContactPointID,
+				verification.CodeHash,
+				verification.Attempts,
+				verification.ExpiresAt,
+			)
+			return err
+		})
+	})
+}
+
+// GetVerification reads from the primary: codes are issued and checked within
+// seconds of each other, faster than a replica may catch up.
+func (c customerContactPointRepository) GetVerification(contactPointID int, ctx context.Context) (domain.ContactVerification, error) {
+	var verification domain.ContactVerification
+	err := c.retry.DoRead(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Primary(), queryGetVerification, func(stmt *sql.Stmt) error {
+			return stmt.QueryRowContext(ctx, contactPointID).Scan(
+				&verification.ContactPointID,
+				&verification.CodeHash,
+				&verification.Attempts,
+				&verification.ExpiresAt,
+			)
+		})
+	})
+	return verification, err
+}
+
+func (c customerContactPointRepository) AddVerificationAttempt(contactPointID int, maxAttempts int, ctx context.Context) (int, error) {
+	var attempts int
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryAddVerificationAttempt, func(stmt *sql.Stmt) error {
+			return stmt.QueryRowContext(ctx, contactPointID, maxAttempts).Scan(&attempts)
+		})
+	})
+	return attempts, err
+}
+
+func (c customerContactPointRepository) ConsumeVerification(contactPointID int, ctx context.Context) error {
+	return c.transaction(ctx, func(tx *database.Tx) error {
+		if _, err := tx.Exec(queryDeleteVerification, contactPointID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(queryMarkVerified, contactPointID)
+		return err
+	})
+}
+
+// ReEncrypt rewrites up to batchSize contact points whose value is not
+// sealed with the current key and selected fields, or has no index yet.
+func (c customerContactPointRepository) ReEncrypt(batchSize int, ctx context.Context) (int, error) {
+	pattern, err := c.cipher.CurrentPattern(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var rewritten int
+	err = c.transaction(ctx, func(tx *database.Tx) error {
+		rewritten = 0
+		rows, err := tx.Query(queryGetStale,
+			pattern,
+			encryption.SealedPattern(),
+			c.cipher.Encrypts(domain.ContactTypeEmail),
+			c.cipher.Encrypts(domain.ContactTypePhone),
+			batchSize,
+		)
+		if err != nil {
+			return err
+		}
+		var stale []domain.CustomerContactPoint
+		for rows.Next() {
+			var contactPoint domain.CustomerContactPoint
+			if err := rows.Scan(&contactPoint.ID, &contactPoint.Type, &contactPoint.Value); err != nil {
+				rows.Close()
+				return err
+			}
+			stale = append(stale, contactPoint)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, contactPoint := range stale {
+			if contactPoint.Value, err = c.cipher.Open(ctx, contactPoint.Type, contactPoint.Value); err != nil {
+				return fmt.Errorf("contact point %d: %w", contactPoint.ID, err)
+			}
+			value, valueIndex, err := c.seal(ctx, &contactPoint)
+			if err != nil {
+				return fmt.Errorf("contact point %d: %w", contactPoint.ID, err)
+			}
+			if _, err := tx.Exec(queryReEncrypt, contactPoint.ID, value, valueIndex); err != nil {
+				return fmt.Errorf("contact point %d: %w", contactPoint.ID, err)
+			}
+			rewritten++
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Errorf("failed to re-encrypt contact points: %v", err)
+		return 0, err
+	}
+	return rewritten, nil
+}
+
+func (c customerContactPointRepository) transaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	return c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), fn)
+	})
+}
+
+// constraintMessage turns constraint violations caused by the payload into a
+// client error message.
+func constraintMessage(err error, contactPoint *domain.CustomerContactPoint) (domain.Response, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return domain.Response{}, false
+	}
+	switch pqErr.Code {
+	case "23503":
+		return domain.Response{Message: fmt.Sprintf("No customer found with number %d", contactPoint.CustomerNumber), StatusCode: 400}, true
+	case "23505":
+		return domain.Response{Message: fmt.Sprintf("%s %s is already the primary contact of another customer", contactPoint.Type, contactPoint.Value), StatusCode: 400}, true
+	}
+	return domain.Response{}, false
+}
+
+// Close releases the prepared statements held by the repository.
+func (c customerContactPointRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewCustomerContactPointRepository(db *database.DB, retry database.RetryPolicy, cipher *encryption.Cipher, log *logrus.Logger) domain.CustomerContactPointRepository {
+	return &customerContactPointRepository{
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
+		cipher: cipher,
+		logger: log,
+	}
+}
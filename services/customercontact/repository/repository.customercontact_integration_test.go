@@ -0,0 +1,94 @@
+package repository_customercontact
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/database/pgtest"
+	"customer-playground/domain"
+	"customer-playground/encryption"
+	"customer-playground/types"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	repository_customer "customer-playground/services/customer/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestCustomerContactPointRepositoryConcurrentAttempts(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := pgtest.Context()
+	logger := logrus.New()
+	logger.Out = io.Discard
+	keyfile, err := encryption.OpenKeyfile(filepath.Join(t.TempDir(), "keys.json"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := encryption.NewCipher(context.Background(), keyfile, encryption.Fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customers := repository_customer.NewCustomerRepository(db, database.DefaultRetryPolicy, cipher, logger)
+	t.Cleanup(func() { customers.(io.Closer).Close() })
+	repository := NewCustomerContactPointRepository(db, database.DefaultRetryPolicy, cipher, logger)
+	t.Cleanup(func() { repository.(io.Closer).Close() })
+
+	customer := &domain.Customer{Name: "John Doe", Email: "john.doe@example.com"}
+	if message, err := customers.Insert(customer, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() customer = %+v, %v", message, err)
+	}
+	contactPoint := &domain.CustomerContactPoint{CustomerNumber: customer.CustomerNumber, Type: domain.ContactTypePhone, Value: "+628123456789"}
+	if message, err := repository.Insert(contactPoint, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() contact point = %+v, %v", message, err)
+	}
+	err = repository.SaveVerification(&domain.ContactVerification{
+		ContactPointID: contactPoint.ID,
+		CodeHash:       "hash",
+		ExpiresAt:      types.NullTime{Time: time.Now().Add(time.Minute), Valid: true},
+	}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const maxAttempts = 5
+	var mu sync.Mutex
+	counted := map[int]bool{}
+	refused := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempts, err := repository.AddVerificationAttempt(contactPoint.ID, maxAttempts, ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				refused++
+			case err != nil:
+				t.Error(err)
+			default:
+				counted[attempts] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(counted) != maxAttempts || refused != 20-maxAttempts {
+		t.Errorf("AddVerificationAttempt() counted attempts %v and refused %d, want 1 to %d counted once", counted, refused, maxAttempts)
+	}
+	verification, err := repository.GetVerification(contactPoint.ID, ctx)
+	if err != nil || verification.Attempts != maxAttempts {
+		t.Errorf("GetVerification() = %+v, %v, want %d attempts", verification, err, maxAttempts)
+	}
+}
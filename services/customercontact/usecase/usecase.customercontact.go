@@ -0,0 +1,279 @@
+package usecase_customercontact
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"customer-playground/domain"
+	"customer-playground/pii"
+	"customer-playground/types"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	verificationCodeDigits = 6
+	verificationTTL        = 10 * time.Minute
+	verificationAttempts   = 5
+)
+
+var phoneFormat = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+
+type customerContactPointUseCase struct {
+	customerContactPointRepository domain.CustomerContactPointRepository
+	sender                         domain.VerificationSender
+	customerInvalidator            domain.CustomerInvalidator
+	logger                         *logrus.Logger
+}
+
+func (c customerContactPointUseCase) GetByCustomerNumber(customerNumber int, ctx context.Context) ([]domain.CustomerContactPoint, error) {
+	contactPoints, err := c.customerContactPointRepository.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("customerContactPointUseCase/GetByCustomerNumber :%v", err)
+		return nil, err
+	}
+	return contactPoints, nil
+}
+
+func (c customerContactPointUseCase) GetById(customerNumber int, id int, ctx context.Context) (domain.CustomerContactPoint, error) {
+	contactPoint, err := c.customerContactPointRepository.GetById(customerNumber, id, ctx)
+	if err != nil {
+		c.logger.Errorf("customerContactPointUseCase/GetById :%v", err)
+		return domain.CustomerContactPoint{}, err
+	}
+	return contactPoint, nil
+}
+
+func (c customerContactPointUseCase) Insert(contactPoint *domain.CustomerContactPoint, ctx context.Context) (domain.Response, error) {
+	now := time.Now()
+	contactPoint.VerifiedAt = types.NullTime{}
+	contactPoint.CreatedAt = types.NullTime{Time: now, Valid: true}
+	contactPoint.UpdatedAt = types.NullTime{Time: now, Valid: true}
+
+	if err := normalize(contactPoint); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+
+	message, err := c.customerContactPointRepository.Insert(contactPoint, ctx)
+	if err != nil {
+		c.logger.Errorf("customerContactPointUseCase/Insert :%v", err)
+		return message, err
+	}
+	c.invalidateCustomer(contactPoint.CustomerNumber, ctx)
+	return message, nil
+}
+
+// Update merges newContactPoint into the stored contact point. The type cannot
+// change, and a primary contact point stays primary until another one of the
+// same type is made primary.
+func (c customerContactPointUseCase) Update(newContactPoint *domain.CustomerContactPoint, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	currentContactPoint, err := c.customerContactPointRepository.GetById(newContactPoint.CustomerNumber, newContactPoint.ID, ctx)
+	if err != nil {
+		message.Message = "this id is not found"
+		message.StatusCode = 500
+		return message, err
+	}
+
+	newContactPoint.Type = currentContactPoint.Type
+	if newContactPoint.Label == "" {
+		newContactPoint.Label = currentContactPoint.Label
+	}
+	if newContactPoint.Value == "" {
+		newContactPoint.Value = currentContactPoint.Value
+	}
+	// Callers without pii:read may send back the masked value they got.
+	newContactPoint.Value, err = pii.Unmask(newContactPoint.Value, currentContactPoint.Value, pii.MaskContactPoint(currentContactPoint).Value)
+	if err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+	newContactPoint.IsPrimary = newContactPoint.IsPrimary || currentContactPoint.IsPrimary
+	newContactPoint.CreatedAt = currentContactPoint.CreatedAt
+	newContactPoint.UpdatedAt = types.NullTime{Time: time.Now(), Valid: true}
+
+	if err := normalize(newContactPoint); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+
+	message, err = c.customerContactPointRepository.Update(newContactPoint, ctx)
+	if err != nil {
+		c.logger.Errorf("customerContactPointUseCase/Update :%v", err)
+		return message, err
+	}
+	c.invalidateCustomer(newContactPoint.CustomerNumber, ctx)
+	return message, nil
+}
+
+// DeleteById removes a contact point. The last email of a customer cannot be
+// removed because Customer.Email is mandatory.
+func (c customerContactPointUseCase) DeleteById(customerNumber int, id int, ctx context.Context) (domain.Response, error) {
+	contactPoints, err := c.customerContactPointRepository.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("customerContactPointUseCase/DeleteById/GetByCustomerNumber :%v", err)
+		return domain.Response{Message: fmt.Sprintf("Failed Delete id %d", id), StatusCode: 500}, err
+	}
+	emails := 0
+	deletesEmail := false
+	for _, contactPoint := range contactPoints {
+		if contactPoint.Type == domain.ContactTypeEmail {
+			emails++
+			deletesEmail = deletesEmail || contactPoint.ID == id
+		}
+	}
+	if deletesEmail && emails == 1 {
+		return domain.Response{Message: "the last email of a customer cannot be deleted", StatusCode: 400}, nil
+	}
+
+	message, err := c.customerContactPointRepository.DeleteById(customerNumber, id, ctx)
+	if err != nil {
+		c.logger.Errorf("customerContactPointUseCase/DeleteById :%v", err)
+		return message, err
+	}
+	c.invalidateCustomer(customerNumber, ctx)
+	return message, nil
+}
+
+// StartVerification issues a new one-time code for the contact point,
+// replacing any pending one, and hands it to the sender.
+func (c customerContactPointUseCase) StartVerification(customerNumber int, id int, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	contactPoint, err := c.customerContactPointRepository.GetById(customerNumber, id, ctx)
+	if err != nil {
+		message.Message = "this id is not found"
+		message.StatusCode = 500
+		return message, err
+	}
+	if contactPoint.VerifiedAt.Valid {
+		return domain.Response{Message: "contact point is already verified", StatusCode: 400}, nil
+	}
+
+	code, err := generateCode(verificationCodeDigits)
+	if err != nil {
+		c.logger.Errorf("customerContactPointUseCase/StartVerification/GenerateCode :%v", err)
+		return domain.Response{Message: "Failed to start verification", StatusCode: 500}, err
+	}
+	err = c.customerContactPointRepository.SaveVerification(&domain.ContactVerification{
+		ContactPointID: contactPoint.ID,
+		CodeHash:       hashCode(contactPoint.ID, code),
+		ExpiresAt:      types.NullTime{Time: time.Now().Add(verificationTTL), Valid: true},
+	}, ctx)
+	if err != nil {
+		c.logger.Errorf("customerContactPointUseCase/StartVerification/SaveVerification :%v", err)
+		return domain.Response{Message: "Failed to start verification", StatusCode: 500}, err
+	}
+	if err := c.sender.Send(contactPoint, code, ctx); err != nil {
+		c.logger.Errorf("customerContactPointUseCase/StartVerification/Send :%v", err)
+		return domain.Response{Message: "Failed to send verification code", StatusCode: 500}, err
+	}
+
+	message.Message = fmt.Sprintf("Verification code sent to %s", pii.MaskContactPoint(contactPoint).Value)
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c customerContactPointUseCase) ConfirmVerification(customerNumber int, id int, code string, ctx context.Context) (domain.Response, error) {
+	contactPoint, err := c.customerContactPointRepository.GetById(customerNumber, id, ctx)
+	if err != nil {
+		return domain.Response{Message: "this id is not found", StatusCode: 500}, err
+	}
+
+	verification, err := c.customerContactPointRepository.GetVerification(contactPoint.ID, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Response{Message: "no verification is pending for this contact point", StatusCode: 400}, nil
+	}
+	if err != nil {
+		c.logger.Errorf("customerContactPointUseCase/ConfirmVerification/GetVerification :%v", err)
+		return domain.Response{Message: "Failed to confirm verification", StatusCode: 500}, err
+	}
+	if !time.Now().Before(verification.ExpiresAt.Time) {
+		return domain.Response{Message: "verification code has expired", StatusCode: 400}, nil
+	}
+
+	// The attempt is counted before the code is compared, so concurrent
+	// guesses cannot all pass the limit.
+	_, err = c.customerContactPointRepository.AddVerificationAttempt(contactPoint.ID, verificationAttempts, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Response{Message: "too many attempts, request a new code", StatusCode: 400}, nil
+	}
+	if err != nil {
+		c.logger.Errorf("customerContactPointUseCase/ConfirmVerification/AddVerificationAttempt :%v", err)
+		return domain.Response{Message: "Failed to confirm verification", StatusCode: 500}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(verification.CodeHash), []byte(hashCode(contactPoint.ID, strings.TrimSpace(code)))) != 1 {
+		return domain.Response{Message: "verification code is not valid", StatusCode: 400}, nil
+	}
+
+	if err := c.customerContactPointRepository.ConsumeVerification(contactPoint.ID, ctx); err != nil {
+		c.logger.Errorf("customerContactPointUseCase/ConfirmVerification/ConsumeVerification :%v", err)
+		return domain.Response{Message: "Failed to confirm verification", StatusCode: 500}, err
+	}
+	return domain.Response{Message: "Succes Verify", StatusCode: 200}, nil
+}
+
+func (c customerContactPointUseCase) invalidateCustomer(customerNumber int, ctx context.Context) {
+	if c.customerInvalidator != nil {
+		c.customerInvalidator.Invalidate(customerNumber, ctx)
+	}
+}
+
+// normalize validates the contact point and brings its value into canonical
+// form: lower case emails and phone numbers without separators.
+func normalize(contactPoint *domain.CustomerContactPoint) error {
+	contactPoint.Type = strings.ToLower(strings.TrimSpace(contactPoint.Type))
+	contactPoint.Label = strings.ToLower(strings.TrimSpace(contactPoint.Label))
+	value := strings.TrimSpace(contactPoint.Value)
+
+	switch contactPoint.Type {
+	case domain.ContactTypeEmail:
+		address, err := mail.ParseAddress(value)
+		if err != nil || address.Address != value {
+			return fmt.Errorf("%q is not a valid email address", value)
+		}
+		contactPoint.Value = strings.ToLower(value)
+	case domain.ContactTypePhone:
+		value = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(value)
+		if !phoneFormat.MatchString(value) {
+			return fmt.Errorf("%q is not a valid phone number", contactPoint.Value)
+		}
+		contactPoint.Value = value
+	default:
+		return fmt.Errorf("type must be %q or %q", domain.ContactTypeEmail, domain.ContactTypePhone)
+	}
+	return nil
+}
+
+func generateCode(digits int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+// hashCode binds the code to its contact point so a leaked hash cannot be
+// replayed against another one.
+func hashCode(contactPointID int, code string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", contactPointID, code)))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewCustomerContactPointUseCase(c domain.CustomerContactPointRepository, sender domain.VerificationSender, customerInvalidator domain.CustomerInvalidator, log *logrus.Logger) domain.CustomerContactPointUseCase {
+	return &customerContactPointUseCase{
+		customerContactPointRepository: c,
+		sender:                         sender,
+		customerInvalidator:            customerInvalidator,
+		logger:                         log,
+	}
+}
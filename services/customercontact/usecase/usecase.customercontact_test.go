@@ -0,0 +1,172 @@
+package usecase_customercontact
+
+import (
+	"context"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"database/sql"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		contactType string
+		value       string
+		want        string
+		valid       bool
+	}{
+		{"email", " John.Doe@Example.com ", "john.doe@example.com", true},
+		{"EMAIL", "john.doe@example.com", "john.doe@example.com", true},
+		{"email", "John Doe <john.doe@example.com>", "", false},
+		{"email", "john.doe", "", false},
+		{"phone", "+62 812-3456-789", "+628123456789", true},
+		{"phone", "(021) 555.0199", "0215550199", true},
+		{"phone", "555-0199", "5550199", true},
+		{"phone", "5550 0199", "55500199", true},
+		{"phone", "12345", "", false},
+		{"phone", "123456", "", false},
+		{"phone", "0812abc", "", false},
+		{"fax", "0215550199", "", false},
+	}
+	for _, test := range tests {
+		contactPoint := domain.CustomerContactPoint{Type: test.contactType, Value: test.value}
+		err := normalize(&contactPoint)
+		if (err == nil) != test.valid {
+			t.Errorf("normalize(%q, %q) = %v, want valid=%v", test.contactType, test.value, err, test.valid)
+			continue
+		}
+		if test.valid && contactPoint.Value != test.want {
+			t.Errorf("normalize(%q, %q) value = %q, want %q", test.contactType, test.value, contactPoint.Value, test.want)
+		}
+	}
+}
+
+func TestGenerateCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := generateCode(verificationCodeDigits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != verificationCodeDigits {
+			t.Fatalf("generateCode() = %q, want %d digits", code, verificationCodeDigits)
+		}
+	}
+}
+
+func TestHashCodeIsBoundToContactPoint(t *testing.T) {
+	if hashCode(1, "123456") == hashCode(2, "123456") {
+		t.Error("hashCode() is equal for different contact points")
+	}
+	if hashCode(1, "123456") != hashCode(1, "123456") {
+		t.Error("hashCode() is not deterministic")
+	}
+}
+
+// verificationRepository keeps one pending code and counts attempts like the
+// SQL repository does, atomically.
+type verificationRepository struct {
+	domain.CustomerContactPointRepository
+	mu           sync.Mutex
+	verification domain.ContactVerification
+	consumed     int
+}
+
+func (r *verificationRepository) GetById(customerNumber int, id int, ctx context.Context) (domain.CustomerContactPoint, error) {
+	return domain.CustomerContactPoint{ID: id, CustomerNumber: customerNumber, Type: domain.ContactTypePhone, Value: "+628123456789"}, nil
+}
+
+func (r *verificationRepository) GetVerification(contactPointID int, ctx context.Context) (domain.ContactVerification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.verification, nil
+}
+
+func (r *verificationRepository) AddVerificationAttempt(contactPointID int, maxAttempts int, ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.verification.Attempts >= maxAttempts {
+		return 0, sql.ErrNoRows
+	}
+	r.verification.Attempts++
+	return r.verification.Attempts, nil
+}
+
+func (r *verificationRepository) ConsumeVerification(contactPointID int, ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consumed++
+	return nil
+}
+
+func TestConfirmVerificationLimitsConcurrentAttempts(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = io.Discard
+	repository := &verificationRepository{verification: domain.ContactVerification{
+		ContactPointID: 1,
+		CodeHash:       hashCode(1, "123456"),
+		ExpiresAt:      types.NullTime{Time: time.Now().Add(time.Minute), Valid: true},
+	}}
+	useCase := NewCustomerContactPointUseCase(repository, nil, nil, logger)
+
+	var mu sync.Mutex
+	messages := map[string]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			message, err := useCase.ConfirmVerification(1, 1, "000000", context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			messages[message.Message]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if messages["verification code is not valid"] != verificationAttempts || messages["too many attempts, request a new code"] != 20-verificationAttempts {
+		t.Fatalf("expected %d codes to be checked, got %v", verificationAttempts, messages)
+	}
+	message, _ := useCase.ConfirmVerification(1, 1, "123456", context.Background())
+	if message.StatusCode != 400 || repository.consumed != 0 {
+		t.Errorf("expected the right code to be refused after the limit, got %+v", message)
+	}
+}
+
+type stubSender struct {
+	code string
+}
+
+func (s *stubSender) Send(contactPoint domain.CustomerContactPoint, code string, ctx context.Context) error {
+	s.code = code
+	return nil
+}
+
+func (r *verificationRepository) SaveVerification(verification *domain.ContactVerification, ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.verification = *verification
+	return nil
+}
+
+func TestStartVerificationMasksValue(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = io.Discard
+	sender := &stubSender{}
+	useCase := NewCustomerContactPointUseCase(&verificationRepository{}, sender, nil, logger)
+
+	message, err := useCase.StartVerification(1, 1, context.Background())
+	if err != nil || message.StatusCode != 200 || sender.code == "" {
+		t.Fatalf("StartVerification() = %+v, %v", message, err)
+	}
+	if want := "Verification code sent to +*********789"; message.Message != want {
+		t.Errorf("StartVerification() message = %q, want %q", message.Message, want)
+	}
+}
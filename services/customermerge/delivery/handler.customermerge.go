@@ -0,0 +1,200 @@
+package delivery_customermerge
+
+import (
+	"customer-playground/domain"
+	"customer-playground/pii"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type CustomerMergeHandler struct {
+	customerMergeUseCase domain.CustomerMergeUseCase
+	logger               *logrus.Logger
+}
+
+func NewCustomerMergeHandler(r *gin.Engine, c domain.CustomerMergeUseCase, l *logrus.Logger) *gin.Engine {
+	handler := &CustomerMergeHandler{customerMergeUseCase: c, logger: l}
+
+	r.GET("/customer-duplicate", handler.HandlerGetDuplicateCandidates)
+	r.POST("/customer-duplicate/scan", handler.HandlerScanDuplicates)
+	r.POST("/customer-duplicate/:id/dismiss", handler.HandlerDismissDuplicate)
+	r.POST("/customer-duplicate/:id/merge", handler.HandlerMergeDuplicate)
+	r.POST("/customer-merge", handler.HandlerMergeCustomer)
+	r.GET("/customer/:customer_number/merges", handler.HandlerGetMergeHistory)
+
+	return r
+}
+
+// HandlerGetDuplicateCandidates godoc
+// @Summary Get duplicate review queue
+// @Description Retrieves the pairs of customers that are likely duplicates, best match first
+// @Tags customer-merge
+// @Produce json
+// @Param status query string false "pending (default) or dismissed"
+// @Success 200 {array} domain.DuplicateCandidate
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-duplicate [get]
+func (c *CustomerMergeHandler) HandlerGetDuplicateCandidates(ctx *gin.Context) {
+	candidates, err := c.customerMergeUseCase.GetCandidates(ctx.Query("status"), ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerGetDuplicateCandidates", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pii.Candidates(ctx, candidates))
+	return
+}
+
+// HandlerScanDuplicates godoc
+// @Summary Scan for duplicates
+// @Description Scores every customer against the others and queues likely duplicates for review. Dismissed pairs stay dismissed.
+// @Tags customer-merge
+// @Produce json
+// @Success 200 {object} domain.DuplicateScanResult
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-duplicate/scan [post]
+func (c *CustomerMergeHandler) HandlerScanDuplicates(ctx *gin.Context) {
+	result, err := c.customerMergeUseCase.Scan(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerScanDuplicates", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+	return
+}
+
+// HandlerDismissDuplicate godoc
+// @Summary Dismiss duplicate
+// @Description Marks a pair as not being duplicates
+// @Tags customer-merge
+// @Produce json
+// @Param id path int true "Duplicate Candidate ID"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-duplicate/{id}/dismiss [post]
+func (c *CustomerMergeHandler) HandlerDismissDuplicate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerDismissDuplicate/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.customerMergeUseCase.Dismiss(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerDismissDuplicate", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerMergeDuplicate godoc
+// @Summary Merge duplicate
+// @Description Merges a pair from the review queue. Without survivor_customer_number the customer with the lower number survives.
+// @Tags customer-merge
+// @Accept json
+// @Produce json
+// @Param id path int true "Duplicate Candidate ID"
+// @Param merge body domain.CustomerMergeRequest false "Only survivor_customer_number is used"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-duplicate/{id}/merge [post]
+func (c *CustomerMergeHandler) HandlerMergeDuplicate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerMergeDuplicate/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	var request domain.CustomerMergeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerMergeDuplicate/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.customerMergeUseCase.MergeCandidate(id, request.SurvivorCustomerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerMergeDuplicate/Merge", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerMergeCustomer godoc
+// @Summary Merge customers
+// @Description Moves notes, addresses, contact points and tags of the merged customer to the survivor in one transaction, records the merge and redirects the merged customer number to the survivor
+// @Tags customer-merge
+// @Accept json
+// @Produce json
+// @Param merge body domain.CustomerMergeRequest true "Customer Merge Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-merge [post]
+func (c *CustomerMergeHandler) HandlerMergeCustomer(ctx *gin.Context) {
+	var request domain.CustomerMergeRequest
+	err := ctx.Bind(&request)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerMergeCustomer/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.customerMergeUseCase.Merge(&request, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerMergeCustomer/Merge", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerGetMergeHistory godoc
+// @Summary Get merge history
+// @Description Retrieves the merges a customer took part in, as survivor or as merged customer
+// @Tags customer-merge
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Success 200 {array} domain.CustomerMerge
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/merges [get]
+func (c *CustomerMergeHandler) HandlerGetMergeHistory(ctx *gin.Context) {
+	customerNumber, err := strconv.Atoi(ctx.Param("customer_number"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerGetMergeHistory/ParseCustomerNumber", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	merges, err := c.customerMergeUseCase.GetHistory(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerMergeHandler/HandlerGetMergeHistory", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pii.Merges(ctx, merges))
+	return
+}
@@ -0,0 +1,559 @@
+package repository_customermerge
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"customer-playground/encryption"
+	"customer-playground/types"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// queryUpsertCandidate refreshes the score of a pending pair but leaves
+	// dismissed pairs alone, so a rescan does not bring them back.
+	queryUpsertCandidate = `
+		INSERT INTO customer_duplicate_candidate (customer_a, customer_b, score, reasons)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (customer_a, customer_b) DO UPDATE SET
+			score = EXCLUDED.score,
+			reasons = EXCLUDED.reasons
+		WHERE customer_duplicate_candidate.status = 'pending'
+	`
+	querySelectCandidate = `
+		SELECT
+			d.id,
+			a.customer_number, a.name, a.email, a.phone, a.birth_date, a.created_at, a.updated_at, a.custom_fields,
+			b.customer_number, b.name, b.email, b.phone, b.birth_date, b.created_at, b.updated_at, b.custom_fields,
+			d.score,
+			d.reasons,
+			d.status,
+			d.created_at,
+			d.reviewed_at
+		FROM customer_duplicate_candidate d
+		JOIN customer a ON a.tenant_id = d.tenant_id AND a.customer_number = d.customer_a
+		JOIN customer b ON b.tenant_id = d.tenant_id AND b.customer_number = d.customer_b
+		WHERE d.tenant_id = app_tenant()
+	`
+	queryGetCandidates      = querySelectCandidate + ` AND d.status = $1 ORDER BY d.score DESC, d.id`
+	queryGetCandidateById   = querySelectCandidate + ` AND d.id = $1`
+	querySetCandidateStatus = `
+		UPDATE customer_duplicate_candidate SET
+			status = $2,
+			reviewed_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+	queryGetHistory = `
+		SELECT
+			id,
+			survivor_customer_number,
+			merged_customer_number,
+			merged_customer,
+			merged_at
+		FROM customer_merge
+		WHERE tenant_id = app_tenant() AND (survivor_customer_number = $1 OR merged_customer_number = $1)
+		ORDER BY merged_at DESC, id DESC
+	`
+
+	// The merge statements below run in one transaction, in this order.
+	// Every table referencing customer(customer_number) has to be handled
+	// here, otherwise its rows are dropped with the merged customer.
+	queryLockCustomers = `
+		SELECT customer_number
+		FROM customer
+		WHERE tenant_id = app_tenant() AND customer_number IN ($1, $2)
+		ORDER BY customer_number
+		FOR UPDATE
+	`
+	// The snapshot keeps email, phone and birth_date as stored, encrypted or
+	// not; the blind index is left out.
+	querySnapshotCustomer = `
+		SELECT to_jsonb(c) - 'email_index'
+		FROM customer c
+		WHERE tenant_id = app_tenant() AND customer_number = $1
+	`
+	queryMergeCustomer = `
+		UPDATE customer s SET
+			birth_date = COALESCE(s.birth_date, m.birth_date),
+			custom_fields = m.custom_fields || s.custom_fields,
+			created_at = LEAST(s.created_at, m.created_at),
+			updated_at = $3
+		FROM customer m
+		WHERE s.tenant_id = app_tenant() AND m.tenant_id = app_tenant()
+			AND s.customer_number = $1 AND m.customer_number = $2
+	`
+	queryMoveNotes = `
+		UPDATE customer_note SET customer_number = $1 WHERE tenant_id = app_tenant() AND customer_number = $2
+	`
+	// The survivor keeps its default addresses and primary contact points.
+	queryDemoteAddresses = `
+		UPDATE customer_address m SET is_default = FALSE
+		WHERE m.tenant_id = app_tenant() AND m.customer_number = $2 AND m.is_default
+			AND EXISTS (
+				SELECT 1 FROM customer_address s
+				WHERE s.tenant_id = m.tenant_id AND s.customer_number = $1 AND s.type = m.type AND s.is_default
+			)
+	`
+	queryMoveAddresses = `
+		UPDATE customer_address SET customer_number = $1 WHERE tenant_id = app_tenant() AND customer_number = $2
+	`
+	queryDropDuplicateContacts = `
+		DELETE FROM customer_contact_point m
+		USING customer_contact_point s
+		WHERE m.tenant_id = app_tenant() AND s.tenant_id = m.tenant_id
+			AND m.customer_number = $2 AND s.customer_number = $1
+			AND s.type = m.type AND s.value_index = m.value_index
+	`
+	queryDemoteContacts = `
+		UPDATE customer_contact_point m SET is_primary = FALSE
+		WHERE m.tenant_id = app_tenant() AND m.customer_number = $2 AND m.is_primary
+			AND EXISTS (
+				SELECT 1 FROM customer_contact_point s
+				WHERE s.tenant_id = m.tenant_id AND s.customer_number = $1 AND s.type = m.type AND s.is_primary
+			)
+	`
+	queryMoveContacts = `
+		UPDATE customer_contact_point SET customer_number = $1, updated_at = $3 WHERE tenant_id = app_tenant() AND customer_number = $2
+	`
+	// querySyncCustomerContacts fills a phone the survivor did not have from
+	// the primary phone it inherited.
+	querySyncCustomerContacts = `
+		UPDATE customer SET
+			phone = COALESCE((
+				SELECT value FROM customer_contact_point
+				WHERE tenant_id = app_tenant() AND customer_number = $1 AND type = 'phone' AND is_primary
+			), phone)
+		WHERE tenant_id = app_tenant() AND customer_number = $1
+	`
+	queryMoveTags = `
+		INSERT INTO customer_tag (customer_number, tag_id, created_at)
+		SELECT $1, tag_id, created_at
+		FROM customer_tag
+		WHERE tenant_id = app_tenant() AND customer_number = $2
+		ON CONFLICT DO NOTHING
+	`
+	// queryMoveRedirects keeps redirects one hop long when a survivor is
+	// merged again later.
+	queryMoveRedirects = `
+		UPDATE customer_redirect SET customer_number = $1 WHERE tenant_id = app_tenant() AND customer_number = $2
+	`
+	queryMoveErasureRequests = `
+		UPDATE customer_erasure_request SET customer_number = $1 WHERE tenant_id = app_tenant() AND customer_number = $2
+	`
+	queryInsertRedirect = `
+		INSERT INTO customer_redirect (old_customer_number, customer_number, created_at)
+		VALUES ($2, $1, $3)
+	`
+	queryInsertMerge = `
+		INSERT INTO customer_merge (survivor_customer_number, merged_customer_number, merged_customer, merged_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+	// queryGetStale finds snapshots whose fields are not sealed as configured:
+	// $1 matches values sealed with the current key, $2 any sealed value and
+	// $3 to $5 tell whether email, phone and birth_date are encrypted.
+	queryGetStale = `
+		SELECT
+			id,
+			merged_customer
+		FROM customer_merge
+		WHERE tenant_id = app_tenant() AND (
+			CASE WHEN $3 THEN merged_customer->>'email' NOT LIKE $1 ELSE merged_customer->>'email' LIKE $2 END
+			OR CASE WHEN $4 THEN merged_customer->>'phone' <> '' AND merged_customer->>'phone' NOT LIKE $1 ELSE merged_customer->>'phone' LIKE $2 END
+			OR CASE WHEN $5 THEN merged_customer->>'birth_date' NOT LIKE $1 ELSE merged_customer->>'birth_date' LIKE $2 END
+		)
+		ORDER BY id
+		LIMIT $6
+		FOR UPDATE SKIP LOCKED
+	`
+	queryReEncrypt = `
+		UPDATE customer_merge SET
+			merged_customer = $2
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+	queryDeleteMerged = `
+		DELETE
+		FROM customer
+		WHERE tenant_id = app_tenant() AND customer_number = $1
+	`
+)
+
+type customerMergeRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	cipher *encryption.Cipher
+	logger *logrus.Logger
+}
+
+func (c customerMergeRepository) scanCandidate(ctx context.Context, row interface{ Scan(...interface{}) error }, candidate *domain.DuplicateCandidate) error {
+	var storedA, storedB encryption.StoredCustomer
+	err := row.Scan(
+		&candidate.ID,
+		&candidate.CustomerA.CustomerNumber,
+		&candidate.CustomerA.Name,
+		&storedA.Email,
+		&storedA.Phone,
+		&storedA.BirthDate,
+		&candidate.CustomerA.CreatedAt,
+		&candidate.CustomerA.UpdatedAt,
+		&candidate.CustomerA.CustomFields,
+		&candidate.CustomerB.CustomerNumber,
+		&candidate.CustomerB.Name,
+		&storedB.Email,
+		&storedB.Phone,
+		&storedB.BirthDate,
+		&candidate.CustomerB.CreatedAt,
+		&candidate.CustomerB.UpdatedAt,
+		&candidate.CustomerB.CustomFields,
+		&candidate.Score,
+		pq.Array(&candidate.Reasons),
+		&candidate.Status,
+		&candidate.CreatedAt,
+		&candidate.ReviewedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if err := c.cipher.OpenCustomer(ctx, storedA, &candidate.CustomerA); err != nil {
+		return err
+	}
+	return c.cipher.OpenCustomer(ctx, storedB, &candidate.CustomerB)
+}
+
+// resealSnapshot applies fn to the fields of a merged customer snapshot that
+// may be encrypted.
+func resealSnapshot(snapshot types.JSONMap, fn func(field, value string) (string, error)) error {
+	for _, field := range encryption.Fields {
+		value, ok := snapshot[field].(string)
+		if !ok {
+			continue
+		}
+		value, err := fn(field, value)
+		if err != nil {
+			return err
+		}
+		snapshot[field] = value
+	}
+	return nil
+}
+
+// SaveCandidates stores the pairs found by a scan and returns how many are
+// pending review.
+func (c customerMergeRepository) SaveCandidates(candidates []domain.DuplicateCandidate, ctx context.Context) (int, error) {
+	var saved int64
+	err := c.transaction(ctx, func(tx *database.Tx) error {
+		saved = 0
+		for _, candidate := range candidates {
+			result, err := tx.Exec(queryUpsertCandidate,
+				candidate.CustomerA.CustomerNumber,
+				candidate.CustomerB.CustomerNumber,
+				candidate.Score,
+				pq.Array(candidate.Reasons),
+			)
+			if err != nil {
+				return err
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			saved += affected
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return 0, err
+	}
+	return int(saved), nil
+}
+
+func (c customerMergeRepository) GetCandidates(status string, ctx context.Context) ([]domain.DuplicateCandidate, error) {
+	var candidates []domain.DuplicateCandidate
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetCandidates, func(stmt *sql.Stmt) error {
+				candidates = nil
+				rows, err := stmt.QueryContext(ctx, status)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var candidate domain.DuplicateCandidate
+					if err := c.scanCandidate(ctx, rows, &candidate); err != nil {
+						return err
+					}
+					candidates = append(candidates, candidate)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+func (c customerMergeRepository) GetCandidateById(id int, ctx context.Context) (domain.DuplicateCandidate, error) {
+	var candidate domain.DuplicateCandidate
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetCandidateById, func(stmt *sql.Stmt) error {
+				return c.scanCandidate(ctx, stmt.QueryRowContext(ctx, id), &candidate)
+			})
+		})
+	})
+	if err != nil {
+		return domain.DuplicateCandidate{}, err
+	}
+
+	return candidate, nil
+}
+
+func (c customerMergeRepository) SetCandidateStatus(id int, status string, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	var result sql.Result
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), querySetCandidateStatus, func(stmt *sql.Stmt) error {
+			var err error
+			result, err = stmt.ExecContext(ctx, id, status)
+			return err
+		})
+	})
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Update id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		message.Message = fmt.Sprintf("Could not determine rows affected for id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to get rows affected: %v", err)
+		return message, err
+	}
+	if rowsAffected == 0 {
+		message.Message = fmt.Sprintf("No duplicate candidate found with id %d", id)
+		message.StatusCode = 500
+		return message, sql.ErrNoRows
+	}
+	message.Message = "Succes Update"
+	message.StatusCode = 200
+	return message, nil
+}
+
+// Merge moves everything of the merged customer to the survivor, records the
+// merge, leaves a redirect and removes the merged customer, all in one
+// transaction.
+func (c customerMergeRepository) Merge(request *domain.CustomerMergeRequest, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	var missing bool
+	var mergeId int
+	survivor, merged := request.SurvivorCustomerNumber, request.MergedCustomerNumber
+	err := c.transaction(ctx, func(tx *database.Tx) error {
+		now := time.Now()
+
+		rows, err := tx.Query(queryLockCustomers, survivor, merged)
+		if err != nil {
+			return err
+		}
+		locked := 0
+		for rows.Next() {
+			locked++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		missing = locked != 2
+		if missing {
+			return nil
+		}
+
+		var snapshot types.JSONMap
+		if err := tx.QueryRow(querySnapshotCustomer, merged).Scan(&snapshot); err != nil {
+			return err
+		}
+
+		steps := []struct {
+			query string
+			args  []interface{}
+		}{
+			{queryMergeCustomer, []interface{}{survivor, merged, now}},
+			{queryMoveNotes, []interface{}{survivor, merged}},
+			{queryDemoteAddresses, []interface{}{survivor, merged}},
+			{queryMoveAddresses, []interface{}{survivor, merged}},
+			{queryDropDuplicateContacts, []interface{}{survivor, merged}},
+			{queryDemoteContacts, []interface{}{survivor, merged}},
+			{queryMoveContacts, []interface{}{survivor, merged, now}},
+			{querySyncCustomerContacts, []interface{}{survivor}},
+			{queryMoveTags, []interface{}{survivor, merged}},
+			{queryMoveRedirects, []interface{}{survivor, merged}},
+			{queryMoveErasureRequests, []interface{}{survivor, merged}},
+			{queryInsertRedirect, []interface{}{survivor, merged, now}},
+		}
+		for _, step := range steps {
+			if _, err := tx.Exec(step.query, step.args...); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.QueryRow(queryInsertMerge, survivor, merged, snapshot, now).Scan(&mergeId); err != nil {
+			return err
+		}
+		_, err = tx.Exec(queryDeleteMerged, merged)
+		return err
+	})
+	if err != nil {
+		message.Message = "Failed to Merge Customer"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	if missing {
+		message.Message = fmt.Sprintf("No customer found with number %d or %d", survivor, merged)
+		message.StatusCode = 400
+		return message, nil
+	}
+	message.Message = fmt.Sprintf("Succes Merge customer %d into %d with id %d", merged, survivor, mergeId)
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c customerMergeRepository) GetHistory(customerNumber int, ctx context.Context) ([]domain.CustomerMerge, error) {
+	var merges []domain.CustomerMerge
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetHistory, func(stmt *sql.Stmt) error {
+				merges = nil
+				rows, err := stmt.QueryContext(ctx, customerNumber)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var merge domain.CustomerMerge
+					err := rows.Scan(
+						&merge.ID,
+						&merge.SurvivorCustomerNumber,
+						&merge.MergedCustomerNumber,
+						&merge.MergedCustomer,
+						&merge.MergedAt,
+					)
+					if err != nil {
+						return err
+					}
+					err = resealSnapshot(merge.MergedCustomer, func(field, value string) (string, error) {
+						return c.cipher.Open(ctx, field, value)
+					})
+					if err != nil {
+						return err
+					}
+
+					merges = append(merges, merge)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return merges, nil
+}
+
+// ReEncrypt rewrites up to batchSize merged customer snapshots whose fields
+// are not sealed with the current key and selected fields.
+func (c customerMergeRepository) ReEncrypt(batchSize int, ctx context.Context) (int, error) {
+	pattern, err := c.cipher.CurrentPattern(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var rewritten int
+	err = c.transaction(ctx, func(tx *database.Tx) error {
+		rewritten = 0
+		rows, err := tx.Query(queryGetStale,
+			pattern,
+			encryption.SealedPattern(),
+			c.cipher.Encrypts(encryption.FieldEmail),
+			c.cipher.Encrypts(encryption.FieldPhone),
+			c.cipher.Encrypts(encryption.FieldBirthDate),
+			batchSize,
+		)
+		if err != nil {
+			return err
+		}
+		stale := map[int]types.JSONMap{}
+		for rows.Next() {
+			var id int
+			var snapshot types.JSONMap
+			if err := rows.Scan(&id, &snapshot); err != nil {
+				rows.Close()
+				return err
+			}
+			stale[id] = snapshot
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, snapshot := range stale {
+			err := resealSnapshot(snapshot, func(field, value string) (string, error) {
+				return c.cipher.Reseal(ctx, field, value)
+			})
+			if err != nil {
+				return fmt.Errorf("merge %d: %w", id, err)
+			}
+			if _, err := tx.Exec(queryReEncrypt, id, snapshot); err != nil {
+				return fmt.Errorf("merge %d: %w", id, err)
+			}
+			rewritten++
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Errorf("failed to re-encrypt merge snapshots: %v", err)
+		return 0, err
+	}
+	return rewritten, nil
+}
+
+func (c customerMergeRepository) transaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	return c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), fn)
+	})
+}
+
+// Close releases the prepared statements held by the repository.
+func (c customerMergeRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewCustomerMergeRepository(db *database.DB, retry database.RetryPolicy, cipher *encryption.Cipher, log *logrus.Logger) domain.CustomerMergeRepository {
+	return &customerMergeRepository{
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
+		cipher: cipher,
+		logger: log,
+	}
+}
@@ -0,0 +1,145 @@
+package usecase_customermerge
+
+import (
+	"context"
+	"customer-playground/dedupe"
+	"customer-playground/domain"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+type customerMergeUseCase struct {
+	customerMergeRepository domain.CustomerMergeRepository
+	customerRepository      domain.CustomerRepository
+	invalidator             domain.CustomerInvalidator
+	threshold               float64
+	logger                  *logrus.Logger
+}
+
+// Scan scores every customer against the others and queues the likely
+// duplicates for review. A tenant may override the threshold with
+// dedupe.threshold.
+func (c customerMergeUseCase) Scan(ctx context.Context) (domain.DuplicateScanResult, error) {
+	customers, err := c.customerRepository.GetAll(domain.CustomerFilter{}, ctx)
+	if err != nil {
+		c.logger.Errorf("customerMergeUseCase/Scan/GetCustomers :%v", err)
+		return domain.DuplicateScanResult{}, err
+	}
+
+	threshold := domain.TenantFromContext(ctx).Float64("dedupe.threshold", c.threshold)
+	matches := dedupe.FindCandidates(customers, threshold)
+	candidates := make([]domain.DuplicateCandidate, 0, len(matches))
+	for _, match := range matches {
+		candidates = append(candidates, domain.DuplicateCandidate{
+			CustomerA: domain.Customer{CustomerNumber: match.A},
+			CustomerB: domain.Customer{CustomerNumber: match.B},
+			Score:     match.Score,
+			Reasons:   match.Reasons,
+		})
+	}
+
+	saved, err := c.customerMergeRepository.SaveCandidates(candidates, ctx)
+	if err != nil {
+		c.logger.Errorf("customerMergeUseCase/Scan/SaveCandidates :%v", err)
+		return domain.DuplicateScanResult{}, err
+	}
+	return domain.DuplicateScanResult{Scanned: len(customers), Candidates: saved}, nil
+}
+
+func (c customerMergeUseCase) GetCandidates(status string, ctx context.Context) ([]domain.DuplicateCandidate, error) {
+	if status == "" {
+		status = domain.DuplicateStatusPending
+	}
+	candidates, err := c.customerMergeRepository.GetCandidates(status, ctx)
+	if err != nil {
+		c.logger.Errorf("customerMergeUseCase/GetCandidates :%v", err)
+		return nil, err
+	}
+	return candidates, nil
+}
+
+func (c customerMergeUseCase) Dismiss(id int, ctx context.Context) (domain.Response, error) {
+	message, err := c.customerMergeRepository.SetCandidateStatus(id, domain.DuplicateStatusDismissed, ctx)
+	if err != nil {
+		c.logger.Errorf("customerMergeUseCase/Dismiss :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+// MergeCandidate merges a reviewed pair. The customer that is not the
+// survivor is merged into it; without a survivor the older customer, with
+// the lower number, survives.
+func (c customerMergeUseCase) MergeCandidate(id int, survivorCustomerNumber int, ctx context.Context) (domain.Response, error) {
+	candidate, err := c.customerMergeRepository.GetCandidateById(id, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Response{Message: fmt.Sprintf("No duplicate candidate found with id %d", id), StatusCode: 400}, nil
+	}
+	if err != nil {
+		c.logger.Errorf("customerMergeUseCase/MergeCandidate/GetCandidate :%v", err)
+		return domain.Response{Message: "Failed to Merge Customer", StatusCode: 500}, err
+	}
+	if candidate.Status != domain.DuplicateStatusPending {
+		return domain.Response{Message: fmt.Sprintf("duplicate candidate %d is %s", id, candidate.Status), StatusCode: 400}, nil
+	}
+
+	request := domain.CustomerMergeRequest{
+		SurvivorCustomerNumber: candidate.CustomerA.CustomerNumber,
+		MergedCustomerNumber:   candidate.CustomerB.CustomerNumber,
+	}
+	switch survivorCustomerNumber {
+	case 0, candidate.CustomerA.CustomerNumber:
+	case candidate.CustomerB.CustomerNumber:
+		request.SurvivorCustomerNumber, request.MergedCustomerNumber = request.MergedCustomerNumber, request.SurvivorCustomerNumber
+	default:
+		return domain.Response{Message: fmt.Sprintf("survivor_customer_number must be %d or %d", candidate.CustomerA.CustomerNumber, candidate.CustomerB.CustomerNumber), StatusCode: 400}, nil
+	}
+	return c.Merge(&request, ctx)
+}
+
+func (c customerMergeUseCase) Merge(request *domain.CustomerMergeRequest, ctx context.Context) (domain.Response, error) {
+	if request.SurvivorCustomerNumber <= 0 || request.MergedCustomerNumber <= 0 {
+		return domain.Response{Message: "survivor_customer_number and merged_customer_number are required", StatusCode: 400}, nil
+	}
+	if request.SurvivorCustomerNumber == request.MergedCustomerNumber {
+		return domain.Response{Message: "a customer cannot be merged into itself", StatusCode: 400}, nil
+	}
+
+	message, err := c.customerMergeRepository.Merge(request, ctx)
+	if err != nil {
+		c.logger.Errorf("customerMergeUseCase/Merge :%v", err)
+		return message, err
+	}
+	if message.StatusCode == 200 && c.invalidator != nil {
+		c.invalidator.Invalidate(request.SurvivorCustomerNumber, ctx)
+		c.invalidator.Invalidate(request.MergedCustomerNumber, ctx)
+	}
+	return message, nil
+}
+
+func (c customerMergeUseCase) GetHistory(customerNumber int, ctx context.Context) ([]domain.CustomerMerge, error) {
+	merges, err := c.customerMergeRepository.GetHistory(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("customerMergeUseCase/GetHistory :%v", err)
+		return nil, err
+	}
+	return merges, nil
+}
+
+// NewCustomerMergeUseCase scans for duplicates scoring at least threshold.
+// The invalidator may be nil when customers are not cached.
+func NewCustomerMergeUseCase(customerMergeRepository domain.CustomerMergeRepository, customerRepository domain.CustomerRepository, invalidator domain.CustomerInvalidator, threshold float64, log *logrus.Logger) domain.CustomerMergeUseCase {
+	if threshold <= 0 {
+		threshold = dedupe.DefaultThreshold
+	}
+	return &customerMergeUseCase{
+		customerMergeRepository: customerMergeRepository,
+		customerRepository:      customerRepository,
+		invalidator:             invalidator,
+		threshold:               threshold,
+		logger:                  log,
+	}
+}
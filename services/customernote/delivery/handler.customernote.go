@@ -1,16 +1,32 @@
 package delivery_customernote
 
 import (
+	"customer-playground/apiversion"
+	"customer-playground/content"
 	"customer-playground/domain"
+	"customer-playground/markdown"
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	_ "customer-playground/docs"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/sirupsen/logrus"
 )
 
+const mimeMarkdown = "text/markdown"
+
+// formats maps the media types a note can be sent as to its format.
+var formats = map[string]string{
+	binding.MIMEHTML:  markdown.FormatHTML,
+	mimeMarkdown:      markdown.FormatMarkdown,
+	binding.MIMEPlain: markdown.FormatText,
+}
+
 type CustomerNoteHandler struct {
 	customerNoteUseCase domain.CustomerNoteUseCase
 	logger              *logrus.Logger
@@ -19,33 +35,86 @@ type CustomerNoteHandler struct {
 func NewCustomerNoteHandler(r *gin.Engine, c domain.CustomerNoteUseCase, l *logrus.Logger) *gin.Engine {
 	handler := &CustomerNoteHandler{customerNoteUseCase: c, logger: l}
 
-	r.GET("/customer-note/get-all", handler.HandlerGetAllCustomerNote)
-	r.GET("/customer-note/get-by-customer-number/:customer_number", handler.HandlerGetByCustomerNumberCustomerNote)
-	r.GET("/customer-note/get-by-id/:id", handler.HandlerGetByIdCustomerNote)
-	r.POST("/customer-note", handler.HandlerInsertCustomerNote)
-	r.PUT("/customer-note", handler.HandlerUpdateCustomerNote)
-	r.DELETE("/customer-note/:id", handler.HandlerDeleteCustomerNoteById)
+	v1 := r.Group(apiversion.V1)
+	v1.GET("/customers/:number/notes", handler.HandlerListCustomerNotes)
+	v1.POST("/customers/:number/notes", handler.HandlerCreateNote)
+	v1.GET("/notes", handler.HandlerListNotes)
+	v1.GET("/notes/:id", handler.HandlerGetNote)
+	v1.PUT("/notes/:id", handler.HandlerReplaceNote)
+	v1.DELETE("/notes/:id", handler.HandlerDeleteNote)
+	v1.GET("/notes/:id/revisions", handler.HandlerListNoteRevisions)
+
+	// Deprecated aliases of the routes above, removed at apiversion.LegacySunset
+	successor := apiversion.V1 + "/notes/:id"
+	r.GET("/customer-note/get-all", apiversion.Deprecated(apiversion.V1+"/notes"), handler.HandlerGetAllCustomerNote)
+	r.GET("/customer-note/get-by-customer-number/:customer_number", apiversion.Deprecated(apiversion.V1+"/customers/:customer_number/notes"), handler.HandlerGetByCustomerNumberCustomerNote)
+	r.GET("/customer-note/get-by-id/:id", apiversion.Deprecated(successor), handler.HandlerGetByIdCustomerNote)
+	r.GET("/customer-note/:id/revisions", apiversion.Deprecated(successor+"/revisions"), handler.HandlerGetCustomerNoteRevisions)
+	r.POST("/customer-note", apiversion.Deprecated(apiversion.V1+"/customers/:customer_number/notes"), handler.HandlerInsertCustomerNote)
+	r.PUT("/customer-note", apiversion.Deprecated(successor), handler.HandlerUpdateCustomerNote)
+	r.DELETE("/customer-note/:id", apiversion.Deprecated(successor), handler.HandlerDeleteCustomerNoteById)
 
 	return r
 }
 
+// noteOffers are the media types of a note: the encodings of content and the
+// forms of the note text.
+var noteOffers = append(append([]string{}, content.Offers...), binding.MIMEHTML, mimeMarkdown, binding.MIMEPlain)
+
+// noteFormat picks the form notes are returned in from the format query
+// parameter or else the Accept header. mediaType is set when the client
+// asked for the note itself rather than one of the encodings of content.
+func noteFormat(ctx *gin.Context) (format string, mediaType string, err error) {
+	ctx.Header("Vary", "Accept")
+	if format := ctx.Query("format"); format != "" {
+		if !markdown.ValidFormat(format) {
+			return "", "", fmt.Errorf("format must be markdown, html or text")
+		}
+		return format, "", nil
+	}
+	mediaType = ctx.NegotiateFormat(noteOffers...)
+	if format, ok := formats[mediaType]; ok {
+		return format, mediaType, nil
+	}
+	return markdown.FormatMarkdown, "", nil
+}
+
+func formatNotes(customerNotes []domain.CustomerNote, format string) {
+	for i := range customerNotes {
+		customerNotes[i].Note = markdown.Format(customerNotes[i].Note, format)
+	}
+}
+
 // HandlerGetAllCustomerNote godoc
 // @Summary Get all customer notes
 // @Description Retrieves all customer notes from the system
 // @Tags customer-note
-// @Produce json
+// @Deprecated
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param format query string false "Form of the note text" Enums(markdown, html, text)
 // @Success 200 {array} domain.CustomerNote "List of customer notes"
 // @Failure 500 {object} domain.ErrorResponse "Internal server error"
 // @Router /customer-note/get-all [get]
 func (c *CustomerNoteHandler) HandlerGetAllCustomerNote(ctx *gin.Context) {
+	c.listAll(ctx)
+}
+
+func (c *CustomerNoteHandler) listAll(ctx *gin.Context) {
+	format, _, err := noteFormat(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/listAll/Format", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
 	customerNotes, err := c.customerNoteUseCase.GetAll(ctx)
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerGetAllCustomerNote", err)
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/listAll", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
+	formatNotes(customerNotes, format)
 
-	ctx.JSON(http.StatusOK, customerNotes)
+	content.Render(ctx, http.StatusOK, customerNotes)
 	return
 }
 
@@ -53,107 +122,195 @@ func (c *CustomerNoteHandler) HandlerGetAllCustomerNote(ctx *gin.Context) {
 // @Summary Get customer notes by customer number
 // @Description Retrieves all notes associated with a given customer number
 // @Tags customer-note
-// @Produce json
+// @Deprecated
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
 // @Param customer_number path int true "Customer Number"
+// @Param format query string false "Form of the note text" Enums(markdown, html, text)
 // @Success 200 {array} domain.CustomerNote "Customer notes for the customer number"
 // @Failure 500 {object} domain.ErrorResponse "Internal server error"
 // @Router /customer-note/get-by-customer-number/{customer_number} [get]
 func (c *CustomerNoteHandler) HandlerGetByCustomerNumberCustomerNote(ctx *gin.Context) {
-	customerNumber, err := strconv.Atoi(ctx.Param("customer_number"))
+	customerNumber, _ := strconv.Atoi(ctx.Param("customer_number"))
+	c.listByCustomer(ctx, customerNumber)
+}
+
+func (c *CustomerNoteHandler) listByCustomer(ctx *gin.Context, customerNumber int) {
+	format, _, err := noteFormat(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/listByCustomer/Format", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
 	customerNotes, err := c.customerNoteUseCase.GetByCustomerNumber(customerNumber, ctx)
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerGetByCustomerNumberCustomerNote", err)
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/listByCustomer", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
+	formatNotes(customerNotes, format)
 
-	ctx.JSON(http.StatusOK, customerNotes)
+	content.Render(ctx, http.StatusOK, customerNotes)
 	return
 }
 
 // HandlerGetByIdCustomerNote godoc
 // @Summary Get a customer note by ID
-// @Description Retrieves a customer note by its unique ID
+// @Description Retrieves a customer note by its unique ID. Notes are written in Markdown; with Accept text/html, text/markdown or text/plain only the note text is returned in that form, with the format parameter the note field of the JSON.
 // @Tags customer-note
-// @Produce json
+// @Deprecated
+// @Produce json,application/xml,application/msgpack,application/x-protobuf,html,plain,text/markdown
 // @Param id path int true "Customer Note ID"
+// @Param format query string false "Form of the note text" Enums(markdown, html, text)
 // @Success 200 {object} domain.CustomerNote "Customer note"
+// @Failure 404 {object} domain.ErrorResponse "Not found"
 // @Failure 500 {object} domain.ErrorResponse "Internal server error"
 // @Router /customer-note/get-by-id/{id} [get]
 func (c *CustomerNoteHandler) HandlerGetByIdCustomerNote(ctx *gin.Context) {
-	id, err := strconv.Atoi(ctx.Param("id"))
+	id, _ := strconv.Atoi(ctx.Param("id"))
+	c.get(ctx, id)
+}
+
+func (c *CustomerNoteHandler) get(ctx *gin.Context, id int) {
+	format, mediaType, err := noteFormat(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/get/Format", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
 	customerNote, err := c.customerNoteUseCase.GetById(id, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.notFound(ctx, id)
+		return
+	}
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerGetByIdCustomerNote", err)
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/get", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
+	customerNote.Note = markdown.Format(customerNote.Note, format)
 
-	ctx.JSON(http.StatusOK, customerNote)
+	if mediaType != "" {
+		ctx.Header("X-Content-Type-Options", "nosniff")
+		ctx.Header("Content-Security-Policy", "default-src 'none'")
+		ctx.Data(http.StatusOK, mediaType+"; charset=utf-8", []byte(customerNote.Note))
+		return
+	}
+	content.Render(ctx, http.StatusOK, customerNote)
+	return
+}
+
+// HandlerGetCustomerNoteRevisions godoc
+// @Summary Get the revisions of a customer note
+// @Description Retrieves every version of a note, oldest first, each with a diff against the version before it
+// @Tags customer-note
+// @Deprecated
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param id path int true "Customer Note ID"
+// @Param X-User header string false "Agent making the request; private notes need a bearer token instead"
+// @Success 200 {array} domain.CustomerNoteRevision "Customer note revisions"
+// @Failure 400 {object} domain.ErrorResponse "Bad request"
+// @Failure 404 {object} domain.ErrorResponse "Not found"
+// @Failure 500 {object} domain.ErrorResponse "Internal server error"
+// @Router /customer-note/{id}/revisions [get]
+func (c *CustomerNoteHandler) HandlerGetCustomerNoteRevisions(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerGetCustomerNoteRevisions/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	c.revisions(ctx, id)
+}
+
+func (c *CustomerNoteHandler) revisions(ctx *gin.Context, id int) {
+	revisions, err := c.customerNoteUseCase.GetRevisions(id, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.notFound(ctx, id)
+		return
+	}
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/revisions", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	content.Render(ctx, http.StatusOK, revisions)
 	return
 }
 
 // HandlerInsertCustomerNote godoc
 // @Summary Create a new customer note
-// @Description Inserts a new customer note into the system
+// @Description Inserts a new customer note into the system. The note text is Markdown. The author is taken from the X-User header when it is set.
 // @Tags customer-note
-// @Accept json
-// @Produce json
+// @Deprecated
+// @Accept json,application/xml,application/msgpack,application/x-protobuf
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
 // @Param customerNote body domain.CustomerNote true "Customer Note Payload"
+// @Param X-User header string false "Agent making the request"
 // @Success 200 {object} domain.Response "Insert result"
 // @Failure 400 {object} domain.ErrorResponse "Bad request"
 // @Failure 500 {object} domain.ErrorResponse "Internal server error"
 // @Router /customer-note [post]
 func (c *CustomerNoteHandler) HandlerInsertCustomerNote(ctx *gin.Context) {
 	var customerNote domain.CustomerNote
-	err := ctx.Bind(&customerNote)
+	err := content.Bind(ctx, &customerNote)
 	if err != nil {
 		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerInsertCustomerNote/ParseBodyData", err)
 		ctx.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
-	message, err := c.customerNoteUseCase.Insert(&customerNote, ctx)
+	c.insert(ctx, &customerNote)
+}
+
+func (c *CustomerNoteHandler) insert(ctx *gin.Context, customerNote *domain.CustomerNote) {
+	message, err := c.customerNoteUseCase.Insert(customerNote, ctx)
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerInsertCustomerNote/Insert", err)
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/insert", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 	}
 	if message.StatusCode != 200 {
-		ctx.JSON(http.StatusBadRequest, message)
+		content.Render(ctx, http.StatusBadRequest, message)
 		return
 	}
-	ctx.JSON(http.StatusOK, message)
+	content.Render(ctx, http.StatusOK, message)
 	return
 }
 
 // HandlerUpdateCustomerNote godoc
 // @Summary Update a customer note
-// @Description Updates an existing customer note
+// @Description Updates an existing customer note and records the new version in its revision history. Author and created_at cannot be changed.
 // @Tags customer-note
-// @Accept json
-// @Produce json
+// @Deprecated
+// @Accept json,application/xml,application/msgpack,application/x-protobuf
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
 // @Param customerNote body domain.CustomerNote true "Customer Note Payload"
+// @Param X-User header string false "Agent making the request"
 // @Success 200 {object} domain.Response "Update result"
 // @Failure 400 {object} domain.ErrorResponse "Bad request"
 // @Failure 500 {object} domain.ErrorResponse "Internal server error"
 // @Router /customer-note [put]
 func (c *CustomerNoteHandler) HandlerUpdateCustomerNote(ctx *gin.Context) {
 	var customerNote domain.CustomerNote
-	err := ctx.Bind(&customerNote)
+	err := content.Bind(ctx, &customerNote)
 	if err != nil {
 		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerUpdateCustomerNote/ParseBodyData", err)
 		ctx.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
-	message, err := c.customerNoteUseCase.Update(&customerNote, ctx)
+	c.update(ctx, &customerNote)
+}
+
+func (c *CustomerNoteHandler) update(ctx *gin.Context, customerNote *domain.CustomerNote) {
+	message, err := c.customerNoteUseCase.Update(customerNote, ctx)
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerUpdateCustomerNote/Update", err)
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/update", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 	}
 	if message.StatusCode != 200 {
-		ctx.JSON(http.StatusBadRequest, message)
+		content.Render(ctx, http.StatusBadRequest, message)
 		return
 	}
-	ctx.JSON(http.StatusOK, message)
+	content.Render(ctx, http.StatusOK, message)
 	return
 }
 
@@ -161,23 +318,39 @@ func (c *CustomerNoteHandler) HandlerUpdateCustomerNote(ctx *gin.Context) {
 // @Summary Delete a customer note by ID
 // @Description Deletes a customer note by its ID
 // @Tags customer-note
-// @Produce json
+// @Deprecated
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
 // @Param id path int true "Customer Note ID"
 // @Success 200 {object} domain.Response "Delete result"
+// @Failure 404 {object} domain.ErrorResponse "Not found"
 // @Failure 500 {object} domain.ErrorResponse "Internal server error"
 // @Router /customer-note/{id} [delete]
 func (c *CustomerNoteHandler) HandlerDeleteCustomerNoteById(ctx *gin.Context) {
-	id, err := strconv.Atoi(ctx.Param("id"))
+	id, _ := strconv.Atoi(ctx.Param("id"))
+	c.delete(ctx, id)
+}
+
+func (c *CustomerNoteHandler) delete(ctx *gin.Context, id int) {
 	message, err := c.customerNoteUseCase.DeleteById(id, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.notFound(ctx, id)
+		return
+	}
 	if err != nil {
-		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerDeleteCustomerNoteById/Delete", err)
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/delete", err)
 		ctx.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 	if message.StatusCode != 200 {
-		ctx.JSON(http.StatusBadRequest, message)
+		content.Render(ctx, http.StatusBadRequest, message)
 		return
 	}
-	ctx.JSON(http.StatusOK, message)
+	content.Render(ctx, http.StatusOK, message)
 	return
 }
+
+// notFound answers for a note that does not exist or is private to another
+// author.
+func (c *CustomerNoteHandler) notFound(ctx *gin.Context, id int) {
+	content.Render(ctx, http.StatusNotFound, domain.ErrorResponse{Message: fmt.Sprintf("No customer note found with id %d", id)})
+}
@@ -0,0 +1,180 @@
+package delivery_customernote
+
+import (
+	"customer-playground/content"
+	"customer-playground/domain"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// param reads a numeric path parameter of a /api/v1 route.
+func (c *CustomerNoteHandler) param(ctx *gin.Context, name string) (int, bool) {
+	value, err := strconv.Atoi(ctx.Param(name))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/param", err)
+		content.Render(ctx, http.StatusBadRequest, domain.Response{Message: name + " must be a number", StatusCode: 400})
+		return 0, false
+	}
+	return value, true
+}
+
+// HandlerListNotes godoc
+// @Summary List notes
+// @Description Retrieves the notes of every customer
+// @Tags notes
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param format query string false "Form of the note text" Enums(markdown, html, text)
+// @Success 200 {array} domain.CustomerNote "List of customer notes"
+// @Failure 400 {object} domain.ErrorResponse "Bad request"
+// @Failure 500 {object} domain.ErrorResponse "Internal server error"
+// @Router /api/v1/notes [get]
+func (c *CustomerNoteHandler) HandlerListNotes(ctx *gin.Context) {
+	c.listAll(ctx)
+}
+
+// HandlerListCustomerNotes godoc
+// @Summary List the notes of a customer
+// @Description Retrieves all notes of the customer with the number in the path
+// @Tags notes
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param number path int true "Customer Number"
+// @Param format query string false "Form of the note text" Enums(markdown, html, text)
+// @Success 200 {array} domain.CustomerNote "Customer notes for the customer number"
+// @Failure 400 {object} domain.ErrorResponse "Bad request"
+// @Failure 500 {object} domain.ErrorResponse "Internal server error"
+// @Router /api/v1/customers/{number}/notes [get]
+func (c *CustomerNoteHandler) HandlerListCustomerNotes(ctx *gin.Context) {
+	customerNumber, ok := c.param(ctx, "number")
+	if !ok {
+		return
+	}
+	c.listByCustomer(ctx, customerNumber)
+}
+
+// HandlerCreateNote godoc
+// @Summary Add a note to a customer
+// @Description Adds a note to the customer with the number in the path. The note text is Markdown. The author is taken from the X-User header when it is set. A customer_number in the body must be the same or left out.
+// @Tags notes
+// @Accept json,application/xml,application/msgpack,application/x-protobuf
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param number path int true "Customer Number"
+// @Param customerNote body domain.CustomerNote true "Customer Note Payload"
+// @Param X-User header string false "Agent making the request"
+// @Success 200 {object} domain.Response "Insert result"
+// @Failure 400 {object} domain.ErrorResponse "Bad request"
+// @Failure 500 {object} domain.ErrorResponse "Internal server error"
+// @Router /api/v1/customers/{number}/notes [post]
+func (c *CustomerNoteHandler) HandlerCreateNote(ctx *gin.Context) {
+	customerNumber, ok := c.param(ctx, "number")
+	if !ok {
+		return
+	}
+	var customerNote domain.CustomerNote
+	err := content.Bind(ctx, &customerNote)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerCreateNote/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if customerNote.CustomerNumber != 0 && customerNote.CustomerNumber != customerNumber {
+		content.Render(ctx, http.StatusBadRequest, domain.Response{Message: fmt.Sprintf("customer_number %d does not match the path", customerNote.CustomerNumber), StatusCode: 400})
+		return
+	}
+	customerNote.ID, customerNote.CustomerNumber = 0, customerNumber
+	c.insert(ctx, &customerNote)
+}
+
+// HandlerGetNote godoc
+// @Summary Get a note
+// @Description Retrieves a note by its ID. Notes are written in Markdown; with Accept text/html, text/markdown or text/plain only the note text is returned in that form, with the format parameter the note field of the JSON.
+// @Tags notes
+// @Produce json,application/xml,application/msgpack,application/x-protobuf,html,plain,text/markdown
+// @Param id path int true "Customer Note ID"
+// @Param format query string false "Form of the note text" Enums(markdown, html, text)
+// @Success 200 {object} domain.CustomerNote "Customer note"
+// @Failure 400 {object} domain.ErrorResponse "Bad request"
+// @Failure 404 {object} domain.ErrorResponse "Not found"
+// @Failure 500 {object} domain.ErrorResponse "Internal server error"
+// @Router /api/v1/notes/{id} [get]
+func (c *CustomerNoteHandler) HandlerGetNote(ctx *gin.Context) {
+	id, ok := c.param(ctx, "id")
+	if !ok {
+		return
+	}
+	c.get(ctx, id)
+}
+
+// HandlerReplaceNote godoc
+// @Summary Update a note
+// @Description Updates the note with the ID in the path and records the new version in its revision history. Author and created_at cannot be changed. An id in the body must be the same or left out.
+// @Tags notes
+// @Accept json,application/xml,application/msgpack,application/x-protobuf
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param id path int true "Customer Note ID"
+// @Param customerNote body domain.CustomerNote true "Customer Note Payload"
+// @Param X-User header string false "Agent making the request"
+// @Success 200 {object} domain.Response "Update result"
+// @Failure 400 {object} domain.ErrorResponse "Bad request"
+// @Failure 500 {object} domain.ErrorResponse "Internal server error"
+// @Router /api/v1/notes/{id} [put]
+func (c *CustomerNoteHandler) HandlerReplaceNote(ctx *gin.Context) {
+	id, ok := c.param(ctx, "id")
+	if !ok {
+		return
+	}
+	var customerNote domain.CustomerNote
+	err := content.Bind(ctx, &customerNote)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomerNoteHandler/HandlerReplaceNote/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if customerNote.ID != 0 && customerNote.ID != id {
+		content.Render(ctx, http.StatusBadRequest, domain.Response{Message: fmt.Sprintf("id %d does not match the path", customerNote.ID), StatusCode: 400})
+		return
+	}
+	customerNote.ID = id
+	c.update(ctx, &customerNote)
+}
+
+// HandlerDeleteNote godoc
+// @Summary Delete a note
+// @Description Deletes the note with the ID in the path
+// @Tags notes
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param id path int true "Customer Note ID"
+// @Success 200 {object} domain.Response "Delete result"
+// @Failure 400 {object} domain.ErrorResponse "Bad request"
+// @Failure 404 {object} domain.ErrorResponse "Not found"
+// @Failure 500 {object} domain.ErrorResponse "Internal server error"
+// @Router /api/v1/notes/{id} [delete]
+func (c *CustomerNoteHandler) HandlerDeleteNote(ctx *gin.Context) {
+	id, ok := c.param(ctx, "id")
+	if !ok {
+		return
+	}
+	c.delete(ctx, id)
+}
+
+// HandlerListNoteRevisions godoc
+// @Summary List the revisions of a note
+// @Description Retrieves every version of a note, oldest first, each with a diff against the version before it
+// @Tags notes
+// @Produce json,application/xml,application/msgpack,application/x-protobuf
+// @Param id path int true "Customer Note ID"
+// @Param X-User header string false "Agent making the request; private notes need a bearer token instead"
+// @Success 200 {array} domain.CustomerNoteRevision "Customer note revisions"
+// @Failure 400 {object} domain.ErrorResponse "Bad request"
+// @Failure 404 {object} domain.ErrorResponse "Not found"
+// @Failure 500 {object} domain.ErrorResponse "Internal server error"
+// @Router /api/v1/notes/{id}/revisions [get]
+func (c *CustomerNoteHandler) HandlerListNoteRevisions(ctx *gin.Context) {
+	id, ok := c.param(ctx, "id")
+	if !ok {
+		return
+	}
+	c.revisions(ctx, id)
+}
@@ -0,0 +1,324 @@
+package delivery_customernote
+
+import (
+	"bytes"
+	"customer-playground/content"
+	"customer-playground/content/pb"
+	"customer-playground/database"
+	"customer-playground/database/pgtest"
+	"customer-playground/domain"
+	repository_customer "customer-playground/services/customer/repository"
+	repository_customernote "customer-playground/services/customernote/repository"
+	usecase_customernote "customer-playground/services/customernote/usecase"
+	"customer-playground/tenant"
+	"customer-playground/types"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+// newRouter serves the note routes backed by the test database, with every
+// request made by jane.agent of the default tenant. It returns the number of
+// a customer to add notes to.
+func newRouter(t *testing.T) (*gin.Engine, int) {
+	db := pgtest.Open(t)
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	ctx := pgtest.Context()
+	var customerNumber int
+	err := database.Transaction(ctx, db.Primary(), func(tx *sql.Tx) error {
+		return tx.QueryRow(`INSERT INTO customer (name, email) VALUES ('John Doe', 'john.doe@example.com') RETURNING customer_number`).Scan(&customerNumber)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customerNoteRepository := repository_customernote.NewCustomerNoteRepository(db, database.DefaultRetryPolicy, logger)
+	t.Cleanup(func() { customerNoteRepository.(io.Closer).Close() })
+	return route(usecase_customernote.NewCustomerNoteUseCase(customerNoteRepository, logger), logger), customerNumber
+}
+
+// newMemoryRouter is newRouter backed by in-memory repositories, for tests of
+// the routes themselves.
+func newMemoryRouter(t *testing.T) (*gin.Engine, int) {
+	logger := logrus.New()
+	logger.Out = io.Discard
+
+	customers := repository_customer.NewMemoryCustomerRepository()
+	now := types.NullTime{Time: time.Now(), Valid: true}
+	customer := domain.Customer{Name: "John Doe", Email: "john.doe@example.com", CreatedAt: now, UpdatedAt: now}
+	if message, err := customers.Insert(&customer, pgtest.Context()); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() = %+v, %v", message, err)
+	}
+	customerNoteRepository := repository_customernote.NewMemoryCustomerNoteRepository(customers)
+	return route(usecase_customernote.NewCustomerNoteUseCase(customerNoteRepository, logger), logger), customer.CustomerNumber
+}
+
+func route(customerNoteUseCase domain.CustomerNoteUseCase, logger *logrus.Logger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.ContextWithFallback = true
+	r.Use(func(ctx *gin.Context) {
+		requestCtx := tenant.WithTenant(ctx.Request.Context(), pgtest.DefaultTenant)
+		ctx.Request = ctx.Request.WithContext(domain.WithUser(requestCtx, "jane.agent"))
+		ctx.Next()
+	})
+	return NewCustomerNoteHandler(r, customerNoteUseCase, logger)
+}
+
+func serve(r *gin.Engine, method string, path string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Accept", "application/json")
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func decode(t *testing.T, recorder *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %s: %v", recorder.Body, err)
+	}
+}
+
+func TestCustomerNoteHandlerCRUD(t *testing.T) {
+	r, customerNumber := newRouter(t)
+
+	body := fmt.Sprintf(`{"customer_number": %d, "note": "called about **billing**", "category": "call"}`, customerNumber)
+	if recorder := serve(r, http.MethodPost, "/customer-note", body); recorder.Code != http.StatusOK {
+		t.Fatalf("POST /customer-note = %d %s", recorder.Code, recorder.Body)
+	}
+
+	var notes []domain.CustomerNote
+	recorder := serve(r, http.MethodGet, fmt.Sprintf("/customer-note/get-by-customer-number/%d", customerNumber), "")
+	decode(t, recorder, &notes)
+	if recorder.Code != http.StatusOK || len(notes) != 1 || notes[0].Author != "jane.agent" || notes[0].Category != "call" {
+		t.Fatalf("GET notes of the customer = %d %s", recorder.Code, recorder.Body)
+	}
+	id := notes[0].ID
+
+	body = fmt.Sprintf(`{"id": %d, "note": "called about shipping"}`, id)
+	if recorder := serve(r, http.MethodPut, "/customer-note", body); recorder.Code != http.StatusOK {
+		t.Fatalf("PUT /customer-note = %d %s", recorder.Code, recorder.Body)
+	}
+	var note domain.CustomerNote
+	recorder = serve(r, http.MethodGet, fmt.Sprintf("/customer-note/get-by-id/%d", id), "")
+	decode(t, recorder, &note)
+	if note.Note != "called about shipping" || note.Category != "call" || note.Revision != 2 || note.CustomerNumber != customerNumber {
+		t.Errorf("GET note = %s", recorder.Body)
+	}
+
+	var revisions []domain.CustomerNoteRevision
+	recorder = serve(r, http.MethodGet, fmt.Sprintf("/customer-note/%d/revisions", id), "")
+	decode(t, recorder, &revisions)
+	if len(revisions) != 2 || revisions[1].Diff == "" {
+		t.Errorf("GET revisions = %s", recorder.Body)
+	}
+
+	if recorder := serve(r, http.MethodDelete, fmt.Sprintf("/customer-note/%d", id), ""); recorder.Code != http.StatusOK {
+		t.Fatalf("DELETE note = %d %s", recorder.Code, recorder.Body)
+	}
+	recorder = serve(r, http.MethodGet, "/customer-note/get-all", "")
+	decode(t, recorder, &notes)
+	if recorder.Code != http.StatusOK || len(notes) != 0 {
+		t.Errorf("GET /customer-note/get-all after delete = %d %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestCustomerNoteHandlerNotFound(t *testing.T) {
+	r, customerNumber := newRouter(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{http.MethodGet, "/customer-note/get-by-id/42", "", http.StatusNotFound},
+		{http.MethodPut, "/customer-note", `{"id": 42, "note": "gone"}`, http.StatusInternalServerError},
+		{http.MethodDelete, "/customer-note/42", "", http.StatusNotFound},
+		// The customer has to exist.
+		{http.MethodPost, "/customer-note", fmt.Sprintf(`{"customer_number": %d, "note": "called"}`, customerNumber+1), http.StatusBadRequest},
+		{http.MethodPost, "/customer-note", `{"note": `, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if recorder := serve(r, test.method, test.path, test.body); recorder.Code != test.want {
+			t.Errorf("%s %s = %d %s, want %d", test.method, test.path, recorder.Code, recorder.Body, test.want)
+		}
+	}
+	var notes []domain.CustomerNote
+	recorder := serve(r, http.MethodGet, fmt.Sprintf("/customer-note/get-by-customer-number/%d", customerNumber), "")
+	decode(t, recorder, &notes)
+	if len(notes) != 0 {
+		t.Errorf("notes were added: %s", recorder.Body)
+	}
+}
+
+func TestCustomerNoteHandlerV1(t *testing.T) {
+	r, customerNumber := newMemoryRouter(t)
+	notesPath := fmt.Sprintf("/api/v1/customers/%d/notes", customerNumber)
+
+	// The customer is taken from the path.
+	if recorder := serve(r, http.MethodPost, notesPath, `{"note": "called about **billing**", "category": "call"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("POST %s = %d %s", notesPath, recorder.Code, recorder.Body)
+	}
+	var notes []domain.CustomerNote
+	recorder := serve(r, http.MethodGet, notesPath, "")
+	decode(t, recorder, &notes)
+	if recorder.Code != http.StatusOK || len(notes) != 1 || notes[0].Author != "jane.agent" || notes[0].CustomerNumber != customerNumber {
+		t.Fatalf("GET %s = %d %s", notesPath, recorder.Code, recorder.Body)
+	}
+	path := fmt.Sprintf("/api/v1/notes/%d", notes[0].ID)
+
+	// The ID is taken from the path.
+	if recorder := serve(r, http.MethodPut, path, `{"note": "called about shipping"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("PUT %s = %d %s", path, recorder.Code, recorder.Body)
+	}
+	var note domain.CustomerNote
+	recorder = serve(r, http.MethodGet, path, "")
+	decode(t, recorder, &note)
+	if recorder.Code != http.StatusOK || note.Note != "called about shipping" || note.Revision != 2 {
+		t.Errorf("GET %s = %d %s", path, recorder.Code, recorder.Body)
+	}
+	var revisions []domain.CustomerNoteRevision
+	recorder = serve(r, http.MethodGet, path+"/revisions", "")
+	decode(t, recorder, &revisions)
+	if len(revisions) != 2 {
+		t.Errorf("GET %s/revisions = %s", path, recorder.Body)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{http.MethodPost, notesPath, fmt.Sprintf(`{"customer_number": %d, "note": "called"}`, customerNumber+1), http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/customers/john/notes", `{"note": "called"}`, http.StatusBadRequest},
+		{http.MethodPut, path, `{"id": 42, "note": "gone"}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/notes/first", "", http.StatusBadRequest},
+		{http.MethodDelete, path, "", http.StatusOK},
+		{http.MethodGet, path, "", http.StatusNotFound},
+		{http.MethodGet, path + "/revisions", "", http.StatusNotFound},
+		{http.MethodDelete, path, "", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		if recorder := serve(r, test.method, test.path, test.body); recorder.Code != test.want {
+			t.Errorf("%s %s = %d %s, want %d", test.method, test.path, recorder.Code, recorder.Body, test.want)
+		}
+	}
+	recorder = serve(r, http.MethodGet, "/api/v1/notes", "")
+	decode(t, recorder, &notes)
+	if recorder.Code != http.StatusOK || len(notes) != 0 {
+		t.Errorf("GET /api/v1/notes after delete = %d %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestCustomerNoteHandlerLegacyRoutes(t *testing.T) {
+	r, customerNumber := newMemoryRouter(t)
+
+	body := fmt.Sprintf(`{"customer_number": %d, "note": "called"}`, customerNumber)
+	recorder := serve(r, http.MethodPost, "/customer-note", body)
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Deprecation") == "" || recorder.Header().Get("Link") != "" {
+		t.Fatalf("POST /customer-note = %d %v %s", recorder.Code, recorder.Header(), recorder.Body)
+	}
+	var notes []domain.CustomerNote
+	recorder = serve(r, http.MethodGet, fmt.Sprintf("/customer-note/get-by-customer-number/%d", customerNumber), "")
+	decode(t, recorder, &notes)
+	if want := fmt.Sprintf(`</api/v1/customers/%d/notes>; rel="successor-version"`, customerNumber); recorder.Header().Get("Link") != want {
+		t.Errorf("Link = %q, want %q", recorder.Header().Get("Link"), want)
+	}
+	recorder = serve(r, http.MethodGet, fmt.Sprintf("/customer-note/%d/revisions", notes[0].ID), "")
+	if want := fmt.Sprintf(`</api/v1/notes/%d/revisions>; rel="successor-version"`, notes[0].ID); recorder.Header().Get("Link") != want || recorder.Header().Get("Sunset") == "" {
+		t.Errorf("GET revisions headers = %v", recorder.Header())
+	}
+}
+
+func TestCustomerNoteHandlerContentNegotiation(t *testing.T) {
+	r, customerNumber := newMemoryRouter(t)
+	notesPath := fmt.Sprintf("/api/v1/customers/%d/notes", customerNumber)
+	send := func(method string, path string, contentType string, accept string, body []byte) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+		request.Header.Set("Accept", accept)
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	dueAt := time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC)
+	var body []byte
+	err := codec.NewEncoderBytes(&body, &codec.MsgpackHandle{WriteExt: true}).Encode(map[string]interface{}{
+		"note":     "call back @bob.agent",
+		"category": "call",
+		"due_at":   dueAt,
+		"assignee": "bob.agent",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder := send(http.MethodPost, notesPath, content.MIMEMsgPack, content.MIMEJSON, body); recorder.Code != http.StatusOK {
+		t.Fatalf("POST MessagePack = %d %s", recorder.Code, recorder.Body)
+	}
+
+	recorder := send(http.MethodGet, notesPath, "", "text/xml", nil)
+	var notes struct {
+		Notes []domain.CustomerNote `xml:"note"`
+	}
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &notes); err != nil || len(notes.Notes) != 1 {
+		t.Fatalf("GET XML = %d %s", recorder.Code, recorder.Body)
+	}
+	note := notes.Notes[0]
+	if !note.DueAt.Time.Equal(dueAt) || note.Assignee != "bob.agent" || len(note.Mentions) != 1 {
+		t.Errorf("GET XML = %s", recorder.Body)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/xml; charset=utf-8" {
+		t.Errorf("Content-Type = %s", contentType)
+	}
+
+	// Updates, notes and revisions in protobuf.
+	path := fmt.Sprintf("/api/v1/notes/%d", note.ID)
+	body, _ = proto.Marshal(&pb.CustomerNote{Note: "called back", Category: "call", Status: domain.NoteStatusDone})
+	if recorder := send(http.MethodPut, path, content.MIMEProtobuf, content.MIMEProtobuf, body); recorder.Code != http.StatusOK {
+		t.Fatalf("PUT protobuf = %d %s", recorder.Code, recorder.Body)
+	}
+	recorder = send(http.MethodGet, path, "", content.MIMEProtobuf, nil)
+	var message pb.CustomerNote
+	if err := proto.Unmarshal(recorder.Body.Bytes(), &message); err != nil || message.Note != "called back" || message.CreatedAt == nil {
+		t.Errorf("GET protobuf = %d %v %v", recorder.Code, &message, err)
+	}
+	recorder = send(http.MethodGet, path+"/revisions", "", content.MIMEProtobuf, nil)
+	var revisions pb.CustomerNoteRevisionList
+	if err := proto.Unmarshal(recorder.Body.Bytes(), &revisions); err != nil || len(revisions.Revisions) != 2 || revisions.Revisions[0].DueAt == nil {
+		t.Errorf("GET revisions as protobuf = %d %v %v", recorder.Code, &revisions, err)
+	}
+
+	// The note text itself is still negotiated.
+	recorder = send(http.MethodGet, path, "", "text/markdown", nil)
+	if recorder.Body.String() != "called back" {
+		t.Errorf("GET markdown = %s", recorder.Body)
+	}
+}
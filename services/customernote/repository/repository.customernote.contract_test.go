@@ -0,0 +1,395 @@
+package repository_customernote
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/database/pgtest"
+	"customer-playground/domain"
+	repository_customer "customer-playground/services/customer/repository"
+	"customer-playground/types"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// contractFixture is what the contract suite needs from an implementation:
+// an empty repository and a way to add tenants and customers to it.
+type contractFixture struct {
+	repository     domain.CustomerNoteRepository
+	newTenant      func(t *testing.T, slug string) context.Context
+	addCustomer    func(t *testing.T, ctx context.Context) int
+	deleteCustomer func(t *testing.T, ctx context.Context, customerNumber int)
+}
+
+func TestMemoryCustomerNoteRepositoryContract(t *testing.T) {
+	testCustomerNoteRepositoryContract(t, func(t *testing.T) contractFixture {
+		customers := repository_customer.NewMemoryCustomerRepository()
+		tenantID := 0
+		return contractFixture{
+			repository: NewMemoryCustomerNoteRepository(customers),
+			newTenant: func(t *testing.T, slug string) context.Context {
+				tenantID++
+				return database.WithTenant(context.Background(), tenantID)
+			},
+			addCustomer: func(t *testing.T, ctx context.Context) int {
+				return insertMemoryCustomer(t, customers, ctx)
+			},
+			deleteCustomer: func(t *testing.T, ctx context.Context, customerNumber int) {
+				if _, err := customers.DeleteByCustomerNumber(customerNumber, ctx); err != nil {
+					t.Fatal(err)
+				}
+			},
+		}
+	})
+}
+
+func TestCustomerNoteRepositoryContract(t *testing.T) {
+	testCustomerNoteRepositoryContract(t, func(t *testing.T) contractFixture {
+		repository, db := newIntegrationRepository(t)
+		customers := 0
+		return contractFixture{
+			repository: repository,
+			newTenant: func(t *testing.T, slug string) context.Context {
+				var tenantID int
+				err := database.Transaction(pgtest.Context(), db.Primary(), func(tx *sql.Tx) error {
+					return tx.QueryRow(`INSERT INTO tenant (slug, name) VALUES ($1, $1) RETURNING id`, slug).Scan(&tenantID)
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return database.WithTenant(context.Background(), tenantID)
+			},
+			addCustomer: func(t *testing.T, ctx context.Context) int {
+				customers++
+				return insertCustomer(t, db, ctx, fmt.Sprintf("john.doe+%d@example.com", customers))
+			},
+			deleteCustomer: func(t *testing.T, ctx context.Context, customerNumber int) {
+				err := database.Transaction(ctx, db.Primary(), func(tx *sql.Tx) error {
+					_, err := tx.Exec(`DELETE FROM customer WHERE customer_number = $1`, customerNumber)
+					return err
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+		}
+	})
+}
+
+func insertMemoryCustomer(t *testing.T, customers domain.CustomerRepository, ctx context.Context) int {
+	t.Helper()
+	now := types.NullTime{Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Valid: true}
+	customer := &domain.Customer{Name: "John Doe", CreatedAt: now, UpdatedAt: now}
+	if message, err := customers.Insert(customer, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() customer = %+v, %v", message, err)
+	}
+	return customer.CustomerNumber
+}
+
+// testCustomerNoteRepositoryContract checks the behaviour every
+// CustomerNoteRepository has to share with the Postgres one. newFixture is
+// called once per subtest.
+func testCustomerNoteRepositoryContract(t *testing.T, newFixture func(t *testing.T) contractFixture) {
+	insert := func(t *testing.T, repository domain.CustomerNoteRepository, note *domain.CustomerNote, ctx context.Context) {
+		t.Helper()
+		if message, err := repository.Insert(note, ctx); err != nil || message.StatusCode != 200 {
+			t.Fatalf("Insert() = %+v, %v", message, err)
+		}
+	}
+	at := func(day int) types.NullTime {
+		return types.NullTime{Time: time.Date(2024, 5, day, 10, 0, 0, 0, time.UTC), Valid: true}
+	}
+
+	t.Run("InsertAssignsIDs", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		customerNumber := f.addCustomer(t, ctx)
+		first, second := newNote(customerNumber, "called"), newNote(customerNumber, "met")
+		insert(t, f.repository, first, ctx)
+		insert(t, f.repository, second, ctx)
+		if first.ID == 0 || second.ID <= first.ID || first.Revision != 1 || second.Revision != 1 {
+			t.Errorf("Insert() assigned ids %d and %d, revisions %d and %d", first.ID, second.ID, first.Revision, second.Revision)
+		}
+
+		explicit := newNote(customerNumber, "emailed")
+		explicit.ID = 4242
+		insert(t, f.repository, explicit, ctx)
+		duplicate := newNote(customerNumber, "emailed again")
+		duplicate.ID = 4242
+		if message, err := f.repository.Insert(duplicate, ctx); err != nil || message.StatusCode != 500 {
+			t.Errorf("Insert() of a taken id = %+v, %v, want status 500", message, err)
+		}
+	})
+
+	t.Run("StoredValues", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		note := newNote(f.addCustomer(t, ctx), "called @carol.agent and @bob.agent")
+		note.Mentions = []string{"carol.agent", "bob.agent", "carol.agent"}
+		note.UpdatedBy = "someone.else"
+		note.CreatedAt = types.NullTime{Time: time.Date(2024, 5, 1, 17, 0, 0, 123456789, time.FixedZone("WIB", 7*60*60)), Valid: true}
+		insert(t, f.repository, note, ctx)
+
+		got, err := f.repository.GetById(note.ID, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(got.Mentions) != "[bob.agent carol.agent]" {
+			t.Errorf("Mentions = %v, want them sorted once each", got.Mentions)
+		}
+		if got.Pinned == nil || *got.Pinned {
+			t.Errorf("Pinned = %v, want false", got.Pinned)
+		}
+		if got.UpdatedBy != "jane.agent" {
+			t.Errorf("UpdatedBy = %q, want the author", got.UpdatedBy)
+		}
+		if want := time.Date(2024, 5, 1, 17, 0, 0, 123457000, time.UTC); !got.CreatedAt.Time.Equal(want) {
+			t.Errorf("CreatedAt = %v, want %v", got.CreatedAt.Time, want)
+		}
+
+		bare := newNote(note.CustomerNumber, "met")
+		bare.Mentions = nil
+		insert(t, f.repository, bare, ctx)
+		if got, err := f.repository.GetById(bare.ID, ctx); err != nil || got.Mentions == nil || len(got.Mentions) != 0 {
+			t.Errorf("GetById() = %+v, %v, want empty mentions", got, err)
+		}
+	})
+
+	t.Run("InsertRejectsInvalid", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		customerNumber := f.addCustomer(t, ctx)
+
+		tests := map[string]func(note *domain.CustomerNote){
+			"category":   func(note *domain.CustomerNote) { note.Category = "letter" },
+			"visibility": func(note *domain.CustomerNote) { note.Visibility = "public" },
+			"status":     func(note *domain.CustomerNote) { note.Status = "closed" },
+			"created_at": func(note *domain.CustomerNote) { note.CreatedAt = types.NullTime{} },
+			"customer":   func(note *domain.CustomerNote) { note.CustomerNumber = customerNumber + 100 },
+		}
+		for name, change := range tests {
+			note := newNote(customerNumber, "called")
+			change(note)
+			if message, err := f.repository.Insert(note, ctx); err != nil || message.StatusCode != 500 {
+				t.Errorf("Insert() with an invalid %s = %+v, %v, want status 500", name, message, err)
+			}
+		}
+		if message, err := f.repository.Insert(newNote(customerNumber, "called"), context.Background()); err == nil && message.StatusCode == 200 {
+			t.Errorf("Insert() without a tenant = %+v", message)
+		}
+		if notes, err := f.repository.GetAll(ctx); err != nil || len(notes) != 0 {
+			t.Errorf("GetAll() = %+v, %v, want nothing inserted", notes, err)
+		}
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		customerNumber, otherNumber := f.addCustomer(t, ctx), f.addCustomer(t, ctx)
+
+		old, recent, sameTime, other := newNote(customerNumber, "old"), newNote(customerNumber, "recent"), newNote(customerNumber, "same time"), newNote(otherNumber, "other")
+		old.CreatedAt = at(1)
+		recent.CreatedAt = at(3)
+		sameTime.CreatedAt = at(3)
+		other.CreatedAt = at(2)
+		isPinned := true
+		pinnedNote := newNote(customerNumber, "pinned")
+		pinnedNote.Pinned = &isPinned
+		for _, note := range []*domain.CustomerNote{pinnedNote, old, recent, sameTime, other} {
+			insert(t, f.repository, note, ctx)
+		}
+
+		ids := func(notes []domain.CustomerNote) []int {
+			var ids []int
+			for _, note := range notes {
+				ids = append(ids, note.ID)
+			}
+			return ids
+		}
+		// Pinned notes first, then newest first.
+		notes, err := f.repository.GetByCustomerNumber(customerNumber, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{pinnedNote.ID, sameTime.ID, recent.ID, old.ID}; fmt.Sprint(ids(notes)) != fmt.Sprint(want) {
+			t.Errorf("GetByCustomerNumber() = %v, want %v", ids(notes), want)
+		}
+		notes, err = f.repository.GetAll(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{pinnedNote.ID, sameTime.ID, recent.ID, other.ID, old.ID}; fmt.Sprint(ids(notes)) != fmt.Sprint(want) {
+			t.Errorf("GetAll() = %v, want %v", ids(notes), want)
+		}
+	})
+
+	t.Run("UpdateKeepsRevisions", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		note := newNote(f.addCustomer(t, ctx), "called")
+		insert(t, f.repository, note, ctx)
+
+		update := *note
+		update.Note = "called back"
+		update.Author = "bob.agent"
+		update.Status = domain.NoteStatusOpen
+		update.Mentions = []string{"carol.agent"}
+		update.UpdatedBy = "bob.agent"
+		update.CreatedAt = at(9)
+		update.UpdatedAt = at(2)
+		if message, err := f.repository.Update(&update, ctx); err != nil || message.StatusCode != 200 {
+			t.Fatalf("Update() = %+v, %v", message, err)
+		}
+		if update.Revision != 2 {
+			t.Errorf("Update() set revision %d, want 2", update.Revision)
+		}
+
+		got, err := f.repository.GetById(note.ID, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Author and created_at stay with the first revision.
+		if got.Note != "called back" || got.Author != "jane.agent" || !got.CreatedAt.Time.Equal(note.CreatedAt.Time) || got.UpdatedBy != "bob.agent" || got.Revision != 2 {
+			t.Errorf("GetById() after update = %+v", got)
+		}
+		if fmt.Sprint(got.Mentions) != "[carol.agent]" {
+			t.Errorf("Mentions = %v, want [carol.agent]", got.Mentions)
+		}
+
+		revisions, err := f.repository.GetRevisions(note.ID, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 2 {
+			t.Fatalf("GetRevisions() = %+v, want 2 revisions", revisions)
+		}
+		if first := revisions[0]; first.Revision != 1 || first.Note != "called" || first.EditedBy != "jane.agent" || !first.CreatedAt.Time.Equal(note.CreatedAt.Time) {
+			t.Errorf("first revision = %+v", first)
+		}
+		if second := revisions[1]; second.Revision != 2 || second.Note != "called back" || second.Status != domain.NoteStatusOpen || second.EditedBy != "bob.agent" || !second.CreatedAt.Time.Equal(at(2).Time) {
+			t.Errorf("second revision = %+v", second)
+		}
+
+		invalid := update
+		invalid.Category = "letter"
+		if message, err := f.repository.Update(&invalid, ctx); err != nil || message.StatusCode != 500 {
+			t.Errorf("Update() with an invalid category = %+v, %v, want status 500", message, err)
+		}
+		missing := update
+		missing.ID = note.ID + 100
+		if message, err := f.repository.Update(&missing, ctx); err != nil || message.StatusCode != 500 {
+			t.Errorf("Update() of a missing note = %+v, %v, want status 500", message, err)
+		}
+		if revisions, err := f.repository.GetRevisions(note.ID, ctx); err != nil || len(revisions) != 2 {
+			t.Errorf("GetRevisions() after failed updates = %d revisions, %v, want 2", len(revisions), err)
+		}
+	})
+
+	t.Run("TenantIsolation", func(t *testing.T) {
+		f := newFixture(t)
+		acme, globex := f.newTenant(t, "acme"), f.newTenant(t, "globex")
+		note := newNote(f.addCustomer(t, acme), "called")
+		insert(t, f.repository, note, acme)
+
+		for name, ctx := range map[string]context.Context{"other tenant": globex, "no tenant": context.Background()} {
+			if _, err := f.repository.GetById(note.ID, ctx); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("GetById() for %s = %v, want sql.ErrNoRows", name, err)
+			}
+			if notes, err := f.repository.GetAll(ctx); err != nil || len(notes) != 0 {
+				t.Errorf("GetAll() for %s = %+v, %v", name, notes, err)
+			}
+			if revisions, err := f.repository.GetRevisions(note.ID, ctx); err != nil || len(revisions) != 0 {
+				t.Errorf("GetRevisions() for %s = %+v, %v", name, revisions, err)
+			}
+			if _, err := f.repository.DeleteById(note.ID, ctx); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("DeleteById() for %s = %v, want sql.ErrNoRows", name, err)
+			}
+		}
+		// Customers of another tenant do not exist.
+		if message, err := f.repository.Insert(newNote(note.CustomerNumber, "called"), globex); err != nil || message.StatusCode != 500 {
+			t.Errorf("Insert() for a customer of another tenant = %+v, %v, want status 500", message, err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		note := newNote(f.addCustomer(t, ctx), "called")
+		insert(t, f.repository, note, ctx)
+
+		if message, err := f.repository.DeleteById(note.ID, ctx); err != nil || message.StatusCode != 200 {
+			t.Fatalf("DeleteById() = %+v, %v", message, err)
+		}
+		message, err := f.repository.DeleteById(note.ID, ctx)
+		if !errors.Is(err, sql.ErrNoRows) || message.StatusCode != 500 {
+			t.Errorf("DeleteById() twice = %+v, %v, want sql.ErrNoRows", message, err)
+		}
+		if revisions, err := f.repository.GetRevisions(note.ID, ctx); err != nil || len(revisions) != 0 {
+			t.Errorf("GetRevisions() after delete = %+v, %v", revisions, err)
+		}
+	})
+
+	t.Run("CustomerDeleteCascades", func(t *testing.T) {
+		f := newFixture(t)
+		ctx := f.newTenant(t, "acme")
+		customerNumber, otherNumber := f.addCustomer(t, ctx), f.addCustomer(t, ctx)
+		note, other := newNote(customerNumber, "called"), newNote(otherNumber, "met")
+		insert(t, f.repository, note, ctx)
+		insert(t, f.repository, other, ctx)
+
+		f.deleteCustomer(t, ctx, customerNumber)
+		notes, err := f.repository.GetAll(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 1 || notes[0].ID != other.ID {
+			t.Errorf("GetAll() after deleting the customer = %+v", notes)
+		}
+		if _, err := f.repository.GetById(note.ID, ctx); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetById() of a note of the deleted customer = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestMemoryCustomerNoteRepositoryConcurrent(t *testing.T) {
+	customers := repository_customer.NewMemoryCustomerRepository()
+	repository := NewMemoryCustomerNoteRepository(customers)
+	ctx := database.WithTenant(context.Background(), 1)
+	customerNumber := insertMemoryCustomer(t, customers, ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			note := newNote(customerNumber, "called")
+			if message, err := repository.Insert(note, ctx); err != nil || message.StatusCode != 200 {
+				t.Errorf("Insert() = %+v, %v", message, err)
+				return
+			}
+			note.UpdatedBy = "bob.agent"
+			if message, err := repository.Update(note, ctx); err != nil || message.StatusCode != 200 {
+				t.Errorf("Update() = %+v, %v", message, err)
+			}
+			if _, err := repository.GetByCustomerNumber(customerNumber, ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	notes, err := repository.GetAll(ctx)
+	if err != nil || len(notes) != 50 {
+		t.Fatalf("GetAll() = %d notes, %v, want 50", len(notes), err)
+	}
+	seen := make(map[int]bool)
+	for _, note := range notes {
+		if seen[note.ID] || note.Revision != 2 {
+			t.Errorf("note %d with revision %d", note.ID, note.Revision)
+		}
+		seen[note.ID] = true
+	}
+}
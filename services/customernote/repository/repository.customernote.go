@@ -2,127 +2,260 @@ package repository_customernote
 
 import (
 	"context"
+	"customer-playground/database"
 	"customer-playground/domain"
+	"customer-playground/types"
 	"database/sql"
 	"fmt"
 
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
-type customerNoteRepository struct {
-	dbPool *sql.DB
-	logger *logrus.Logger
-}
-
-func (c customerNoteRepository) GetAll(ctx context.Context) ([]domain.CustomerNote, error) {
-	stmt, err := c.dbPool.Prepare(`
+const (
+	queryGetAll = `
+		SELECT
+			id,
+			customer_number,
+			note,
+			author,
+			category,
+			pinned,
+			visibility,
+			due_at,
+			assignee,
+			status,
+			ARRAY(SELECT username FROM customer_note_mention m WHERE m.note_id = customer_note.id ORDER BY username),
+			revision,
+			updated_by,
+			created_at,
+			updated_at
+		FROM customer_note
+		WHERE tenant_id = app_tenant()
+		ORDER BY pinned DESC, created_at DESC, id DESC
+	`
+	queryGetByCustomerNumber = `
 		SELECT
 			id,
 			customer_number,
 			note,
+			author,
+			category,
+			pinned,
+			visibility,
+			due_at,
+			assignee,
+			status,
+			ARRAY(SELECT username FROM customer_note_mention m WHERE m.note_id = customer_note.id ORDER BY username),
+			revision,
+			updated_by,
+			created_at,
+			updated_at
+		FROM customer_note
+		WHERE
+		tenant_id = app_tenant() AND customer_number = $1
+		ORDER BY pinned DESC, created_at DESC, id DESC
+	`
+	queryGetById = `
+		SELECT
+			id,
+			customer_number,
+			note,
+			author,
+			category,
+			pinned,
+			visibility,
+			due_at,
+			assignee,
+			status,
+			ARRAY(SELECT username FROM customer_note_mention m WHERE m.note_id = customer_note.id ORDER BY username),
+			revision,
+			updated_by,
+			created_at,
+			updated_at
+		FROM customer_note
+		WHERE
+		tenant_id = app_tenant() AND id = $1
+	`
+	queryGetRevisions = `
+		SELECT
+			revision,
+			note,
+			category,
+			pinned,
+			visibility,
+			due_at,
+			assignee,
+			status,
+			edited_by,
 			created_at
+		FROM customer_note_revision
+		WHERE tenant_id = app_tenant() AND note_id = $1
+		ORDER BY revision
+	`
+	queryInsert = `
+		INSERT INTO customer_note(
+			id,
+			customer_number,
+			note,
+			author,
+			category,
+			pinned,
+			visibility,
+			due_at,
+			assignee,
+			status,
+			revision,
+			updated_by,
+			created_at,
+			updated_at) VALUES (
+		COALESCE(NULLIF($1, 0), nextval('customer_note_id_seq')), $2, $3, $4, $5, $6, $7, $8, $9, $10, 1, $4, $11, $12
+	)
+		RETURNING id, revision
+	`
+	// queryUpdate never touches author and created_at; they belong to the
+	// first revision. A new due date or reopening the follow-up makes it
+	// due for another reminder.
+	queryUpdate = `
+		UPDATE customer_note SET
+			customer_number = $2,
+			note = $3,
+			category = $4,
+			pinned = $5,
+			visibility = $6,
+			due_at = $7,
+			assignee = $8,
+			status = $9,
+			reminded_at = CASE
+				WHEN due_at IS DISTINCT FROM $7 OR status <> $9 THEN NULL
+				ELSE reminded_at
+			END,
+			updated_by = $10,
+			updated_at = $11,
+			revision = revision + 1
+		WHERE 
+			tenant_id = app_tenant() AND id = $1
+		RETURNING revision
+	`
+	queryInsertRevision = `
+		INSERT INTO customer_note_revision(
+			note_id,
+			revision,
+			note,
+			category,
+			pinned,
+			visibility,
+			due_at,
+			assignee,
+			status,
+			edited_by,
+			created_at) VALUES (
+		$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+	)
+	`
+	// New mentions are notified by the follow-up scheduler; mentions removed
+	// from the text go away.
+	queryInsertMentions = `
+		INSERT INTO customer_note_mention (note_id, username, created_at)
+		SELECT $1, username, $3 FROM unnest($2::text[]) AS username
+		ON CONFLICT (note_id, username) DO NOTHING
+	`
+	queryDeleteMentions = `
+		DELETE
+		FROM customer_note_mention
+		WHERE tenant_id = app_tenant() AND note_id = $1 AND username <> ALL($2::text[])
+	`
+	queryDeleteById = `
+		DELETE
 		FROM customer_note
-	`)
-	if err != nil {
-		c.logger.Errorf("failed to prepare statement: %v", err)
-		return nil, err
-	}
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+)
+
+type customerNoteRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	logger *logrus.Logger
+}
+
+func scanCustomerNote(row interface{ Scan(...interface{}) error }, customerNote *domain.CustomerNote) error {
+	return row.Scan(
+		&customerNote.ID,
+		&customerNote.CustomerNumber,
+		&customerNote.Note,
+		&customerNote.Author,
+		&customerNote.Category,
+		&customerNote.Pinned,
+		&customerNote.Visibility,
+		&customerNote.DueAt,
+		&customerNote.Assignee,
+		&customerNote.Status,
+		pq.Array(&customerNote.Mentions),
+		&customerNote.Revision,
+		&customerNote.UpdatedBy,
+		&customerNote.CreatedAt,
+		&customerNote.UpdatedAt,
+	)
+}
 
-	rows, err := stmt.QueryContext(ctx)
+func (c customerNoteRepository) GetAll(ctx context.Context) ([]domain.CustomerNote, error) {
+	customerNotes, err := c.query(ctx, queryGetAll)
 	if err != nil {
 		c.logger.Errorf("failed to execute statement: %v", err)
 		return nil, err
 	}
 
-	defer rows.Close()
-
-	var customerNotes []domain.CustomerNote
-	for rows.Next() {
-		var customerNote domain.CustomerNote
-		err := rows.Scan(
-			&customerNote.ID,
-			&customerNote.CustomerNumber,
-			&customerNote.Note,
-			&customerNote.CreatedAt,
-		)
-		if err != nil {
-			c.logger.Errorf("failed to fetch data statement: %v", err)
-			return nil, err
-		}
-
-		customerNotes = append(customerNotes, customerNote)
-	}
-
 	return customerNotes, nil
 }
 
 func (c customerNoteRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) ([]domain.CustomerNote, error) {
-	stmt, err := c.dbPool.Prepare(`
-		SELECT
-			id,
-			customer_number,
-			note,
-			created_at
-		FROM customer_note
-		WHERE
-		customer_number = $1
-	`)
-	if err != nil {
-		c.logger.Errorf("failed to prepare statement: %v", err)
-		return nil, err
-	}
-
-	rows, err := stmt.QueryContext(ctx, customerNumber)
+	customerNotes, err := c.query(ctx, queryGetByCustomerNumber, customerNumber)
 	if err != nil {
 		c.logger.Errorf("failed to execute statement: %v", err)
 		return nil, err
 	}
 
-	defer rows.Close()
+	return customerNotes, nil
+}
 
+func (c customerNoteRepository) query(ctx context.Context, query string, args ...interface{}) ([]domain.CustomerNote, error) {
 	var customerNotes []domain.CustomerNote
-	for rows.Next() {
-		var customerNote domain.CustomerNote
-		err := rows.Scan(
-			&customerNote.ID,
-			&customerNote.CustomerNumber,
-			&customerNote.Note,
-			&customerNote.CreatedAt,
-		)
-		if err != nil {
-			c.logger.Errorf("failed to fetch data statement: %v", err)
-			return nil, err
-		}
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, query, func(stmt *sql.Stmt) error {
+				customerNotes = nil
+				rows, err := stmt.QueryContext(ctx, args...)
+				if err != nil {
+					return err
+				}
 
-		customerNotes = append(customerNotes, customerNote)
-	}
+				defer rows.Close()
 
-	return customerNotes, nil
+				for rows.Next() {
+					var customerNote domain.CustomerNote
+					if err := scanCustomerNote(rows, &customerNote); err != nil {
+						return err
+					}
+
+					customerNotes = append(customerNotes, customerNote)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	return customerNotes, err
 }
 
 func (c customerNoteRepository) GetById(id int, ctx context.Context) (domain.CustomerNote, error) {
-	stmt, err := c.dbPool.Prepare(`
-		SELECT
-			id,
-			customer_number,
-			note,
-			created_at
-		FROM customer_note
-		WHERE
-		id = $1
-	`)
-	if err != nil {
-		c.logger.Errorf("failed to prepare statement: %v", err)
-		return domain.CustomerNote{}, err
-	}
-
 	var customerNote domain.CustomerNote
-	err = stmt.QueryRowContext(ctx, id).Scan(
-		&customerNote.ID,
-		&customerNote.CustomerNumber,
-		&customerNote.Note,
-		&customerNote.CreatedAt,
-	)
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetById, func(stmt *sql.Stmt) error {
+				return scanCustomerNote(stmt.QueryRowContext(ctx, id), &customerNote)
+			})
+		})
+	})
 	if err != nil {
 		c.logger.Errorf("failed to execute statement: %v", err)
 		return domain.CustomerNote{}, err
@@ -131,31 +264,78 @@ func (c customerNoteRepository) GetById(id int, ctx context.Context) (domain.Cus
 	return customerNote, nil
 }
 
-func (c customerNoteRepository) Insert(customerNote *domain.CustomerNote, ctx context.Context) (domain.Response, error) {
-	var message domain.Response
-	stmt, err := c.dbPool.PrepareContext(ctx, `
-		INSERT INTO customer_note(
-			id,
-			customer_number,
-			note,
-			created_at) VALUES (
-		$1, $2, $3, $4
-	)
-	`)
+// GetRevisions returns every version of a note, oldest first.
+func (c customerNoteRepository) GetRevisions(id int, ctx context.Context) ([]domain.CustomerNoteRevision, error) {
+	var revisions []domain.CustomerNoteRevision
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetRevisions, func(stmt *sql.Stmt) error {
+				revisions = nil
+				rows, err := stmt.QueryContext(ctx, id)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var revision domain.CustomerNoteRevision
+					err := rows.Scan(
+						&revision.Revision,
+						&revision.Note,
+						&revision.Category,
+						&revision.Pinned,
+						&revision.Visibility,
+						&revision.DueAt,
+						&revision.Assignee,
+						&revision.Status,
+						&revision.EditedBy,
+						&revision.CreatedAt,
+					)
+					if err != nil {
+						return err
+					}
+
+					revisions = append(revisions, revision)
+				}
+				return rows.Err()
+			})
+		})
+	})
 	if err != nil {
-		message.Message = "Failed to Insert Customer Note"
-		message.StatusCode = 500
-		c.logger.Errorf("failed to prepare statement: %v", err)
-		return message, nil
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(
-		customerNote.ID,
-		customerNote.CustomerNumber,
-		customerNote.Note,
-		customerNote.CreatedAt,
-	)
+	return revisions, nil
+}
+
+// Insert stores the note and its first revision in one transaction.
+func (c customerNoteRepository) Insert(customerNote *domain.CustomerNote, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	err := c.transaction(ctx, func(tx *database.Tx) error {
+		err := tx.QueryRow(queryInsert,
+			customerNote.ID,
+			customerNote.CustomerNumber,
+			customerNote.Note,
+			customerNote.Author,
+			customerNote.Category,
+			pinned(customerNote),
+			customerNote.Visibility,
+			customerNote.DueAt,
+			customerNote.Assignee,
+			customerNote.Status,
+			customerNote.CreatedAt,
+			customerNote.UpdatedAt,
+		).Scan(&customerNote.ID, &customerNote.Revision)
+		if err != nil {
+			return err
+		}
+		if err := saveMentions(tx, customerNote, customerNote.CreatedAt); err != nil {
+			return err
+		}
+		return insertRevision(tx, customerNote, customerNote.Author, customerNote.CreatedAt)
+	})
 	if err != nil {
 		message.Message = "Failed to Insert Customer Note"
 		message.StatusCode = 500
@@ -167,30 +347,31 @@ func (c customerNoteRepository) Insert(customerNote *domain.CustomerNote, ctx co
 	return message, nil
 }
 
+// Update changes the note and appends a revision in one transaction.
 func (c customerNoteRepository) Update(customerNote *domain.CustomerNote, ctx context.Context) (domain.Response, error) {
 	var message domain.Response
-	stmt, err := c.dbPool.PrepareContext(ctx, `
-		UPDATE customer_note SET
-			customer_number = $2,
-			note = $3,
-			created_at = $4
-		WHERE 
-			id = $1
-	`)
-	if err != nil {
-		message.Message = fmt.Sprintf("Failed Update id %d", customerNote.ID)
-		message.StatusCode = 500
-		c.logger.Errorf("failed to prepare statement: %v", err)
-		return message, nil
-	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(
-		customerNote.ID,
-		customerNote.CustomerNumber,
-		customerNote.Note,
-		customerNote.CreatedAt,
-	)
+	err := c.transaction(ctx, func(tx *database.Tx) error {
+		err := tx.QueryRow(queryUpdate,
+			customerNote.ID,
+			customerNote.CustomerNumber,
+			customerNote.Note,
+			customerNote.Category,
+			pinned(customerNote),
+			customerNote.Visibility,
+			customerNote.DueAt,
+			customerNote.Assignee,
+			customerNote.Status,
+			customerNote.UpdatedBy,
+			customerNote.UpdatedAt,
+		).Scan(&customerNote.Revision)
+		if err != nil {
+			return err
+		}
+		if err := saveMentions(tx, customerNote, customerNote.UpdatedAt); err != nil {
+			return err
+		}
+		return insertRevision(tx, customerNote, customerNote.UpdatedBy, customerNote.UpdatedAt)
+	})
 	if err != nil {
 		message.Message = fmt.Sprintf("Failed Update id %d", customerNote.ID)
 		message.StatusCode = 500
@@ -204,20 +385,14 @@ func (c customerNoteRepository) Update(customerNote *domain.CustomerNote, ctx co
 
 func (c customerNoteRepository) DeleteById(id int, ctx context.Context) (domain.Response, error) {
 	var message domain.Response
-	stmt, err := c.dbPool.Prepare(`
-		DELETE
-		FROM customer_note
-		WHERE id = $1
-	`)
-	if err != nil {
-		message.Message = fmt.Sprintf("Failed Delete id %d", id)
-		message.StatusCode = 500
-		c.logger.Errorf("failed to prepare statement: %v", err)
-		return message, err
-	}
-	defer stmt.Close()
-
-	result, err := stmt.ExecContext(ctx, id)
+	var result sql.Result
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryDeleteById, func(stmt *sql.Stmt) error {
+			var err error
+			result, err = stmt.ExecContext(ctx, id)
+			return err
+		})
+	})
 	if err != nil {
 		message.Message = fmt.Sprintf("Failed Delete id %d", id)
 		message.StatusCode = 500
@@ -241,9 +416,54 @@ func (c customerNoteRepository) DeleteById(id int, ctx context.Context) (domain.
 	return message, nil
 }
 
-func NewCustomerNoteRepository(db *sql.DB, log *logrus.Logger) domain.CustomerNoteRepository {
+func insertRevision(tx *database.Tx, customerNote *domain.CustomerNote, editedBy string, at types.NullTime) error {
+	_, err := tx.Exec(queryInsertRevision,
+		customerNote.ID,
+		customerNote.Revision,
+		customerNote.Note,
+		customerNote.Category,
+		pinned(customerNote),
+		customerNote.Visibility,
+		customerNote.DueAt,
+		customerNote.Assignee,
+		customerNote.Status,
+		editedBy,
+		at,
+	)
+	return err
+}
+
+func saveMentions(tx *database.Tx, customerNote *domain.CustomerNote, at types.NullTime) error {
+	// A nil slice would be NULL and keep every mention.
+	mentions := pq.StringArray{}
+	mentions = append(mentions, customerNote.Mentions...)
+	if _, err := tx.Exec(queryDeleteMentions, customerNote.ID, mentions); err != nil {
+		return err
+	}
+	_, err := tx.Exec(queryInsertMentions, customerNote.ID, mentions, at)
+	return err
+}
+
+func pinned(customerNote *domain.CustomerNote) bool {
+	return customerNote.Pinned != nil && *customerNote.Pinned
+}
+
+func (c customerNoteRepository) transaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	return c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), fn)
+	})
+}
+
+// Close releases the prepared statements held by the repository.
+func (c customerNoteRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewCustomerNoteRepository(db *database.DB, retry database.RetryPolicy, log *logrus.Logger) domain.CustomerNoteRepository {
 	return &customerNoteRepository{
-		dbPool: db,
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
 		logger: log,
 	}
 }
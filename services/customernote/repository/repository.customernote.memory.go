@@ -0,0 +1,285 @@
+package repository_customernote
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// memoryCustomerNoteRepository keeps notes in memory and behaves like the
+// Postgres repository: notes are scoped to the tenant of ctx, belong to an
+// existing customer of customers and keep every revision. When customers
+// reports deleted customers, as the in-memory customer repository does,
+// their notes are deleted with them.
+type memoryCustomerNoteRepository struct {
+	customers domain.CustomerRepository
+
+	mu     sync.RWMutex
+	notes  map[int]*memoryNote
+	nextID int
+}
+
+type memoryNote struct {
+	tenantID  int
+	note      domain.CustomerNote
+	revisions []domain.CustomerNoteRevision
+}
+
+// customerDeleteNotifier is implemented by repositories that report deleted
+// customers.
+type customerDeleteNotifier interface {
+	OnDelete(fn func(customerNumber int))
+}
+
+func (c *memoryCustomerNoteRepository) GetAll(ctx context.Context) ([]domain.CustomerNote, error) {
+	return c.list(ctx, func(note domain.CustomerNote) bool { return true }), nil
+}
+
+func (c *memoryCustomerNoteRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) ([]domain.CustomerNote, error) {
+	return c.list(ctx, func(note domain.CustomerNote) bool { return note.CustomerNumber == customerNumber }), nil
+}
+
+// list returns the notes of the tenant matching keep, pinned ones first and
+// then newest first.
+func (c *memoryCustomerNoteRepository) list(ctx context.Context, keep func(note domain.CustomerNote) bool) []domain.CustomerNote {
+	tenantID, ok := database.TenantID(ctx)
+	if !ok {
+		return nil
+	}
+
+	c.mu.RLock()
+	var customerNotes []domain.CustomerNote
+	for _, stored := range c.notes {
+		if stored.tenantID == tenantID && keep(stored.note) {
+			customerNotes = append(customerNotes, copyNote(stored.note))
+		}
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(customerNotes, func(i, j int) bool {
+		a, b := customerNotes[i], customerNotes[j]
+		if *a.Pinned != *b.Pinned {
+			return *a.Pinned
+		}
+		if !a.CreatedAt.Time.Equal(b.CreatedAt.Time) {
+			return a.CreatedAt.Time.After(b.CreatedAt.Time)
+		}
+		return a.ID > b.ID
+	})
+	return customerNotes
+}
+
+func (c *memoryCustomerNoteRepository) GetById(id int, ctx context.Context) (domain.CustomerNote, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	stored, ok := c.lookup(id, ctx)
+	if !ok {
+		return domain.CustomerNote{}, sql.ErrNoRows
+	}
+	return copyNote(stored.note), nil
+}
+
+func (c *memoryCustomerNoteRepository) GetRevisions(id int, ctx context.Context) ([]domain.CustomerNoteRevision, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	stored, ok := c.lookup(id, ctx)
+	if !ok {
+		return nil, nil
+	}
+	return append([]domain.CustomerNoteRevision(nil), stored.revisions...), nil
+}
+
+func (c *memoryCustomerNoteRepository) Insert(customerNote *domain.CustomerNote, ctx context.Context) (domain.Response, error) {
+	failed := domain.Response{Message: "Failed to Insert Customer Note", StatusCode: 500}
+	tenantID, ok := database.TenantID(ctx)
+	if !ok || !c.customerExists(customerNote.CustomerNumber, ctx) || checkNote(customerNote, customerNote.CreatedAt) != nil || !customerNote.UpdatedAt.Valid {
+		return failed, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := customerNote.ID
+	if id == 0 {
+		c.nextID++
+		id = c.nextID
+	}
+	if _, taken := c.notes[id]; taken {
+		return failed, nil
+	}
+
+	note := storedNote(*customerNote)
+	note.ID = id
+	note.Revision = 1
+	note.UpdatedBy = note.Author
+	c.notes[id] = &memoryNote{
+		tenantID:  tenantID,
+		note:      note,
+		revisions: []domain.CustomerNoteRevision{revisionOf(note, note.Author, note.CreatedAt)},
+	}
+	customerNote.ID = id
+	customerNote.Revision = 1
+	return domain.Response{Message: fmt.Sprintf("Succes Insert Customer Note with id %d", id), StatusCode: 200}, nil
+}
+
+func (c *memoryCustomerNoteRepository) Update(customerNote *domain.CustomerNote, ctx context.Context) (domain.Response, error) {
+	failed := domain.Response{Message: fmt.Sprintf("Failed Update id %d", customerNote.ID), StatusCode: 500}
+	if !c.customerExists(customerNote.CustomerNumber, ctx) || checkNote(customerNote, customerNote.UpdatedAt) != nil {
+		return failed, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored, ok := c.lookup(customerNote.ID, ctx)
+	if !ok {
+		return failed, nil
+	}
+
+	// Author and created_at belong to the first revision.
+	note := storedNote(*customerNote)
+	note.Author = stored.note.Author
+	note.CreatedAt = stored.note.CreatedAt
+	note.Revision = stored.note.Revision + 1
+	stored.note = note
+	stored.revisions = append(stored.revisions, revisionOf(note, note.UpdatedBy, note.UpdatedAt))
+	customerNote.Revision = note.Revision
+	return domain.Response{Message: "Succes Update", StatusCode: 200}, nil
+}
+
+func (c *memoryCustomerNoteRepository) DeleteById(id int, ctx context.Context) (domain.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.lookup(id, ctx); !ok {
+		return domain.Response{Message: fmt.Sprintf("No customer note found with id %d", id), StatusCode: 500}, sql.ErrNoRows
+	}
+	delete(c.notes, id)
+	return domain.Response{Message: "Succes Delete!!", StatusCode: 200}, nil
+}
+
+func (c *memoryCustomerNoteRepository) deleteCustomer(customerNumber int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, stored := range c.notes {
+		if stored.note.CustomerNumber == customerNumber {
+			delete(c.notes, id)
+		}
+	}
+}
+
+// lookup returns the note if it belongs to the tenant of ctx. c.mu must be
+// held.
+func (c *memoryCustomerNoteRepository) lookup(id int, ctx context.Context) (*memoryNote, bool) {
+	tenantID, ok := database.TenantID(ctx)
+	if !ok {
+		return nil, false
+	}
+	stored, ok := c.notes[id]
+	if !ok || stored.tenantID != tenantID {
+		return nil, false
+	}
+	return stored, true
+}
+
+func (c *memoryCustomerNoteRepository) customerExists(customerNumber int, ctx context.Context) bool {
+	_, err := c.customers.GetByCustomerNumber(customerNumber, ctx)
+	return err == nil
+}
+
+// checkNote applies the constraints of the customer_note table.
+func checkNote(customerNote *domain.CustomerNote, at types.NullTime) error {
+	switch {
+	case !at.Valid:
+		return fmt.Errorf("memory: timestamp is required")
+	case utf8.RuneCountInString(customerNote.Author) > 100 || utf8.RuneCountInString(customerNote.Assignee) > 100 || utf8.RuneCountInString(customerNote.UpdatedBy) > 100:
+		return fmt.Errorf("memory: value too long")
+	}
+	switch customerNote.Category {
+	case domain.NoteCategoryCall, domain.NoteCategoryEmail, domain.NoteCategoryComplaint, domain.NoteCategoryMeeting, domain.NoteCategoryOther:
+	default:
+		return fmt.Errorf("memory: invalid category %q", customerNote.Category)
+	}
+	switch customerNote.Visibility {
+	case domain.NoteVisibilityInternal, domain.NoteVisibilityPrivate:
+	default:
+		return fmt.Errorf("memory: invalid visibility %q", customerNote.Visibility)
+	}
+	switch customerNote.Status {
+	case "", domain.NoteStatusOpen, domain.NoteStatusDone:
+	default:
+		return fmt.Errorf("memory: invalid status %q", customerNote.Status)
+	}
+	return nil
+}
+
+// storedNote returns customerNote as Postgres keeps it: pinned is never
+// NULL, timestamps are without time zone in microseconds and mentions are a
+// sorted set.
+func storedNote(customerNote domain.CustomerNote) domain.CustomerNote {
+	isPinned := pinned(&customerNote)
+	customerNote.Pinned = &isPinned
+	customerNote.DueAt = storedTime(customerNote.DueAt)
+	customerNote.CreatedAt = storedTime(customerNote.CreatedAt)
+	customerNote.UpdatedAt = storedTime(customerNote.UpdatedAt)
+
+	seen := make(map[string]bool, len(customerNote.Mentions))
+	mentions := []string{}
+	for _, username := range customerNote.Mentions {
+		if !seen[username] {
+			seen[username] = true
+			mentions = append(mentions, username)
+		}
+	}
+	sort.Strings(mentions)
+	customerNote.Mentions = mentions
+	return customerNote
+}
+
+func storedTime(t types.NullTime) types.NullTime {
+	if !t.Valid {
+		return t
+	}
+	wall := t.Time.Round(time.Microsecond)
+	return types.NullTime{
+		Time:  time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), time.UTC),
+		Valid: true,
+	}
+}
+
+func copyNote(customerNote domain.CustomerNote) domain.CustomerNote {
+	isPinned := *customerNote.Pinned
+	customerNote.Pinned = &isPinned
+	customerNote.Mentions = append([]string{}, customerNote.Mentions...)
+	return customerNote
+}
+
+func revisionOf(customerNote domain.CustomerNote, editedBy string, at types.NullTime) domain.CustomerNoteRevision {
+	return domain.CustomerNoteRevision{
+		Revision:   customerNote.Revision,
+		Note:       customerNote.Note,
+		Category:   customerNote.Category,
+		Pinned:     *customerNote.Pinned,
+		Visibility: customerNote.Visibility,
+		DueAt:      customerNote.DueAt,
+		Assignee:   customerNote.Assignee,
+		Status:     customerNote.Status,
+		EditedBy:   editedBy,
+		CreatedAt:  at,
+	}
+}
+
+// NewMemoryCustomerNoteRepository returns an empty in-memory
+// CustomerNoteRepository whose notes belong to the customers of customers,
+// e.g. to test use cases without a database.
+func NewMemoryCustomerNoteRepository(customers domain.CustomerRepository) domain.CustomerNoteRepository {
+	repository := &memoryCustomerNoteRepository{customers: customers, notes: make(map[int]*memoryNote)}
+	if notifier, ok := customers.(customerDeleteNotifier); ok {
+		notifier.OnDelete(repository.deleteCustomer)
+	}
+	return repository
+}
@@ -0,0 +1,176 @@
+package repository_customernote
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/database/pgtest"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func newIntegrationRepository(t *testing.T) (domain.CustomerNoteRepository, *database.DB) {
+	db := pgtest.Open(t)
+	logger := logrus.New()
+	logger.Out = io.Discard
+	repository := NewCustomerNoteRepository(db, database.DefaultRetryPolicy, logger)
+	t.Cleanup(func() { repository.(io.Closer).Close() })
+	return repository, db
+}
+
+// insertCustomer adds a customer directly, the notes only need it to exist.
+func insertCustomer(t *testing.T, db *database.DB, ctx context.Context, email string) int {
+	t.Helper()
+	var customerNumber int
+	err := database.Transaction(ctx, db.Primary(), func(tx *sql.Tx) error {
+		return tx.QueryRow(`INSERT INTO customer (name, email) VALUES ('John Doe', $1) RETURNING customer_number`, email).Scan(&customerNumber)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return customerNumber
+}
+
+func newNote(customerNumber int, note string) *domain.CustomerNote {
+	now := types.NullTime{Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Valid: true}
+	return &domain.CustomerNote{
+		CustomerNumber: customerNumber,
+		Note:           note,
+		Author:         "jane.agent",
+		Category:       domain.NoteCategoryCall,
+		Visibility:     domain.NoteVisibilityInternal,
+		Mentions:       []string{"bob.agent"},
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+func TestCustomerNoteRepositoryCRUD(t *testing.T) {
+	repository, db := newIntegrationRepository(t)
+	ctx := pgtest.Context()
+	customerNumber := insertCustomer(t, db, ctx, "john.doe@example.com")
+
+	note := newNote(customerNumber, "called about billing, @bob.agent to follow up")
+	if message, err := repository.Insert(note, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() = %+v, %v", message, err)
+	}
+	if note.ID == 0 || note.Revision != 1 {
+		t.Fatalf("Insert() set id %d and revision %d", note.ID, note.Revision)
+	}
+
+	got, err := repository.GetById(note.ID, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Note != note.Note || got.Author != "jane.agent" || len(got.Mentions) != 1 || got.Mentions[0] != "bob.agent" {
+		t.Errorf("GetById() = %+v", got)
+	}
+
+	got.Note = "called about shipping"
+	got.Mentions = nil
+	got.UpdatedBy = "bob.agent"
+	got.UpdatedAt = types.NullTime{Time: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC), Valid: true}
+	if message, err := repository.Update(&got, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("Update() = %+v, %v", message, err)
+	}
+	notes, err := repository.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].Note != "called about shipping" || notes[0].Revision != 2 || len(notes[0].Mentions) != 0 {
+		t.Errorf("GetByCustomerNumber() = %+v", notes)
+	}
+	revisions, err := repository.GetRevisions(note.ID, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].EditedBy != "jane.agent" || revisions[1].EditedBy != "bob.agent" {
+		t.Errorf("GetRevisions() = %+v", revisions)
+	}
+
+	if message, err := repository.DeleteById(note.ID, ctx); err != nil || message.StatusCode != 200 {
+		t.Fatalf("DeleteById() = %+v, %v", message, err)
+	}
+	if notes, err := repository.GetAll(ctx); err != nil || len(notes) != 0 {
+		t.Errorf("GetAll() after delete = %+v, %v", notes, err)
+	}
+	// Revisions go away with their note.
+	if revisions, err := repository.GetRevisions(note.ID, ctx); err != nil || len(revisions) != 0 {
+		t.Errorf("GetRevisions() after delete = %+v, %v", revisions, err)
+	}
+}
+
+func TestCustomerNoteRepositoryNotFound(t *testing.T) {
+	repository, db := newIntegrationRepository(t)
+	ctx := pgtest.Context()
+
+	if _, err := repository.GetById(42, ctx); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetById() = %v, want sql.ErrNoRows", err)
+	}
+	message, err := repository.DeleteById(42, ctx)
+	if !errors.Is(err, sql.ErrNoRows) || message.StatusCode != 500 {
+		t.Errorf("DeleteById() = %+v, %v, want sql.ErrNoRows", message, err)
+	}
+	missing := newNote(insertCustomer(t, db, ctx, "john.doe@example.com"), "called")
+	missing.ID = 42
+	if message, err := repository.Update(missing, ctx); err != nil || message.StatusCode != 500 {
+		t.Errorf("Update() of a missing note = %+v, %v, want status 500", message, err)
+	}
+	// A note needs an existing customer.
+	if message, err := repository.Insert(newNote(4242, "called"), ctx); err != nil || message.StatusCode != 500 {
+		t.Errorf("Insert() for a missing customer = %+v, %v, want status 500", message, err)
+	}
+}
+
+func TestCustomerNoteRepositoryCustomerDeleteCascades(t *testing.T) {
+	repository, db := newIntegrationRepository(t)
+	ctx := pgtest.Context()
+	customerNumber := insertCustomer(t, db, ctx, "john.doe@example.com")
+	otherNumber := insertCustomer(t, db, ctx, "jane.doe@example.com")
+
+	note := newNote(customerNumber, "called @bob.agent")
+	for _, n := range []*domain.CustomerNote{note, newNote(otherNumber, "met")} {
+		if message, err := repository.Insert(n, ctx); err != nil || message.StatusCode != 200 {
+			t.Fatalf("Insert() = %+v, %v", message, err)
+		}
+	}
+
+	err := database.Transaction(ctx, db.Primary(), func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM customer WHERE customer_number = $1`, customerNumber)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notes, err := repository.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].CustomerNumber != otherNumber {
+		t.Errorf("GetAll() after deleting the customer = %+v", notes)
+	}
+	for _, table := range []string{"customer_note_revision", "customer_note_mention"} {
+		var count int
+		err := database.Transaction(ctx, db.Primary(), func(tx *sql.Tx) error {
+			return tx.QueryRow(`SELECT count(*) FROM `+table+` WHERE note_id = $1`, note.ID).Scan(&count)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%s has %d rows of the deleted note", table, count)
+		}
+	}
+}
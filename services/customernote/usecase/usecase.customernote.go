@@ -3,12 +3,24 @@ package usecase_customernote
 import (
 	"context"
 	"customer-playground/domain"
+	"customer-playground/textdiff"
 	"customer-playground/types"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// mentionPattern finds @user names that do not follow a word, so e-mail
+// addresses are not taken for mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@(\w[\w.-]*)`)
+
+const maxUserLength = 100
+
 type customerNoteUseCase struct {
 	customerNoteRepository domain.CustomerNoteRepository
 	logger                 *logrus.Logger
@@ -20,7 +32,7 @@ func (c customerNoteUseCase) GetAll(ctx context.Context) ([]domain.CustomerNote,
 		c.logger.Errorf("customerNoteUseCase/GetAll :%v", err)
 		return nil, err
 	}
-	return customerNotes, nil
+	return domain.VisibleNotes(customerNotes, domain.NoteReader(ctx)), nil
 }
 func (c customerNoteUseCase) GetByCustomerNumber(customerNumber int, ctx context.Context) ([]domain.CustomerNote, error) {
 	customerNotes, err := c.customerNoteRepository.GetByCustomerNumber(customerNumber, ctx)
@@ -28,11 +40,16 @@ func (c customerNoteUseCase) GetByCustomerNumber(customerNumber int, ctx context
 		c.logger.Errorf("customerNoteUseCase/GetByCustomerNumber :%v", err)
 		return nil, err
 	}
-	return customerNotes, nil
+	return domain.VisibleNotes(customerNotes, domain.NoteReader(ctx)), nil
 }
 
+// GetById hides private notes of other authors as if they did not exist.
+// Private notes are only shown to the subject of a bearer token.
 func (c customerNoteUseCase) GetById(id int, ctx context.Context) (domain.CustomerNote, error) {
 	customerNote, err := c.customerNoteRepository.GetById(id, ctx)
+	if err == nil && !customerNote.VisibleTo(domain.NoteReader(ctx)) {
+		err = sql.ErrNoRows
+	}
 	if err != nil {
 		c.logger.Errorf("customerNoteUseCase/GetById :%v", err)
 		return domain.CustomerNote{}, err
@@ -40,11 +57,79 @@ func (c customerNoteUseCase) GetById(id int, ctx context.Context) (domain.Custom
 	return customerNote, nil
 }
 
+// GetRevisions returns every version of a note, each with the changes
+// against the version before it.
+func (c customerNoteUseCase) GetRevisions(id int, ctx context.Context) ([]domain.CustomerNoteRevision, error) {
+	if _, err := c.GetById(id, ctx); err != nil {
+		return nil, err
+	}
+	revisions, err := c.customerNoteRepository.GetRevisions(id, ctx)
+	if err != nil {
+		c.logger.Errorf("customerNoteUseCase/GetRevisions :%v", err)
+		return nil, err
+	}
+
+	var previous domain.CustomerNoteRevision
+	for i := range revisions {
+		revision := &revisions[i]
+		revision.Diff = textdiff.Unified(previous.Note, revision.Note)
+		if i == 0 {
+			previous = *revision
+			continue
+		}
+		if revision.Note != previous.Note {
+			revision.Changes = append(revision.Changes, "note")
+		}
+		if revision.Category != previous.Category {
+			revision.Changes = append(revision.Changes, "category")
+		}
+		if revision.Pinned != previous.Pinned {
+			revision.Changes = append(revision.Changes, "pinned")
+		}
+		if revision.Visibility != previous.Visibility {
+			revision.Changes = append(revision.Changes, "visibility")
+		}
+		if !revision.DueAt.Time.Equal(previous.DueAt.Time) || revision.DueAt.Valid != previous.DueAt.Valid {
+			revision.Changes = append(revision.Changes, "due_at")
+		}
+		if revision.Assignee != previous.Assignee {
+			revision.Changes = append(revision.Changes, "assignee")
+		}
+		if revision.Status != previous.Status {
+			revision.Changes = append(revision.Changes, "status")
+		}
+		previous = *revision
+	}
+	return revisions, nil
+}
+
 func (c customerNoteUseCase) Insert(customerNote *domain.CustomerNote, ctx context.Context) (domain.Response, error) {
 	now := time.Now()
 	if !customerNote.CreatedAt.Valid {
 		customerNote.CreatedAt = types.NullTime{Time: now, Valid: true}
 	}
+	customerNote.UpdatedAt = customerNote.CreatedAt
+	if user := domain.UserFromContext(ctx); user != "" {
+		customerNote.Author = user
+	}
+	if customerNote.Author == "" {
+		return domain.Response{Message: "author is required, send it in the " + domain.UserHeader + " header", StatusCode: 400}, nil
+	}
+	if customerNote.Category == "" {
+		customerNote.Category = domain.NoteCategoryOther
+	}
+	if customerNote.Visibility == "" {
+		customerNote.Visibility = domain.NoteVisibilityInternal
+	}
+	followUpDefaults(customerNote)
+	customerNote.Mentions = parseMentions(customerNote.Note)
+	if err := validate(customerNote); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+	if customerNote.Visibility == domain.NoteVisibilityPrivate && customerNote.Author != domain.NoteReader(ctx) {
+		return domain.Response{Message: "private notes need a bearer token naming their author", StatusCode: 400}, nil
+	}
+
 	message, err := c.customerNoteRepository.Insert(customerNote, ctx)
 	if err != nil {
 		c.logger.Errorf("customerNoteUseCase/Insert :%v", err)
@@ -53,24 +138,67 @@ func (c customerNoteUseCase) Insert(customerNote *domain.CustomerNote, ctx conte
 	return message, nil
 }
 
+// Update merges the change into the current note and records it as a new
+// revision. Author and created_at always keep their current values.
 func (c customerNoteUseCase) Update(newCustomerNote *domain.CustomerNote, ctx context.Context) (domain.Response, error) {
 	var message domain.Response
-	currentCustomerNote, err := c.customerNoteRepository.GetById(newCustomerNote.CustomerNumber, ctx)
+	currentCustomerNote, err := c.GetById(newCustomerNote.ID, ctx)
 	if err != nil {
 		message.Message = "this id is not found"
 		message.StatusCode = 500
 		return message, err
 	}
 
+	editor := domain.UserFromContext(ctx)
+	if editor == "" {
+		editor = newCustomerNote.UpdatedBy
+	}
+	if editor == "" {
+		return domain.Response{Message: "updated_by is required, send it in the " + domain.UserHeader + " header", StatusCode: 400}, nil
+	}
+
 	if newCustomerNote.CustomerNumber == 0 {
 		newCustomerNote.CustomerNumber = currentCustomerNote.CustomerNumber
 	}
 	if newCustomerNote.Note == "" {
 		newCustomerNote.Note = currentCustomerNote.Note
 	}
-	if !newCustomerNote.CreatedAt.Valid {
-		newCustomerNote.CreatedAt = currentCustomerNote.CreatedAt
+	if newCustomerNote.Category == "" {
+		newCustomerNote.Category = currentCustomerNote.Category
+	}
+	if newCustomerNote.Pinned == nil {
+		newCustomerNote.Pinned = currentCustomerNote.Pinned
+	}
+	if newCustomerNote.Visibility == "" {
+		newCustomerNote.Visibility = currentCustomerNote.Visibility
+	}
+	if !newCustomerNote.DueAt.Valid {
+		newCustomerNote.DueAt = currentCustomerNote.DueAt
+	}
+	if newCustomerNote.Assignee == "" {
+		newCustomerNote.Assignee = currentCustomerNote.Assignee
 	}
+	if newCustomerNote.Status == "" {
+		newCustomerNote.Status = currentCustomerNote.Status
+	}
+	newCustomerNote.Author = currentCustomerNote.Author
+	followUpDefaults(newCustomerNote)
+	newCustomerNote.Mentions = parseMentions(newCustomerNote.Note)
+	if err := validate(newCustomerNote); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+	if newCustomerNote.Visibility == domain.NoteVisibilityPrivate && currentCustomerNote.Author != domain.NoteReader(ctx) {
+		return domain.Response{Message: "only the author can make a note private", StatusCode: 400}, nil
+	}
+	newCustomerNote.CreatedAt = currentCustomerNote.CreatedAt
+
+	if unchanged(currentCustomerNote, *newCustomerNote) {
+		*newCustomerNote = currentCustomerNote
+		return domain.Response{Message: "Succes Update", StatusCode: 200}, nil
+	}
+	newCustomerNote.UpdatedBy = editor
+	newCustomerNote.UpdatedAt = types.NullTime{Time: time.Now(), Valid: true}
+
 	message, err = c.customerNoteRepository.Update(newCustomerNote, ctx)
 	if err != nil {
 		c.logger.Errorf("customerNoteUseCase/Update :%v", err)
@@ -79,7 +207,12 @@ func (c customerNoteUseCase) Update(newCustomerNote *domain.CustomerNote, ctx co
 	return message, nil
 }
 
+// DeleteById only deletes notes the user can see; private notes of other
+// authors are not found.
 func (c customerNoteUseCase) DeleteById(id int, ctx context.Context) (domain.Response, error) {
+	if _, err := c.GetById(id, ctx); err != nil {
+		return domain.Response{Message: fmt.Sprintf("No customer note found with id %d", id), StatusCode: 500}, err
+	}
 	message, err := c.customerNoteRepository.DeleteById(id, ctx)
 	if err != nil {
 		c.logger.Errorf("customerNoteUseCase/DeleteById/DelCustomerNumber :%v", err)
@@ -88,6 +221,81 @@ func (c customerNoteUseCase) DeleteById(id int, ctx context.Context) (domain.Res
 	return message, nil
 }
 
+func validate(customerNote *domain.CustomerNote) error {
+	switch customerNote.Category {
+	case domain.NoteCategoryCall, domain.NoteCategoryEmail, domain.NoteCategoryComplaint, domain.NoteCategoryMeeting, domain.NoteCategoryOther:
+	default:
+		return fmt.Errorf("category must be one of call, email, complaint, meeting or other")
+	}
+	switch customerNote.Visibility {
+	case domain.NoteVisibilityInternal, domain.NoteVisibilityPrivate:
+	default:
+		return fmt.Errorf("visibility must be internal or private")
+	}
+	if strings.TrimSpace(customerNote.Note) == "" {
+		return fmt.Errorf("note is required")
+	}
+	switch customerNote.Status {
+	case "":
+	case domain.NoteStatusOpen, domain.NoteStatusDone:
+		if !customerNote.DueAt.Valid && customerNote.Assignee == "" {
+			return fmt.Errorf("status needs a due_at or an assignee")
+		}
+	default:
+		return fmt.Errorf("status must be open or done")
+	}
+	if len(customerNote.Assignee) > maxUserLength {
+		return fmt.Errorf("assignee must be at most %d characters", maxUserLength)
+	}
+	if customerNote.Visibility == domain.NoteVisibilityPrivate && customerNote.Assignee != "" && customerNote.Assignee != customerNote.Author {
+		return fmt.Errorf("a private note can only be assigned to its author")
+	}
+	return nil
+}
+
+// followUpDefaults opens a note that got a due date or an assignee and
+// assigns a due note without an assignee to its author.
+func followUpDefaults(customerNote *domain.CustomerNote) {
+	if customerNote.DueAt.Valid && customerNote.Assignee == "" {
+		customerNote.Assignee = customerNote.Author
+	}
+	if customerNote.Status == "" && (customerNote.DueAt.Valid || customerNote.Assignee != "") {
+		customerNote.Status = domain.NoteStatusOpen
+	}
+}
+
+// parseMentions returns the distinct @user names in text, sorted.
+func parseMentions(text string) []string {
+	seen := map[string]bool{}
+	var mentions []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		user := strings.TrimRight(match[1], ".-")
+		if user == "" || len(user) > maxUserLength || seen[user] {
+			continue
+		}
+		seen[user] = true
+		mentions = append(mentions, user)
+	}
+	sort.Strings(mentions)
+	return mentions
+}
+
+func unchanged(current, updated domain.CustomerNote) bool {
+	return current.CustomerNumber == updated.CustomerNumber &&
+		current.Note == updated.Note &&
+		current.Category == updated.Category &&
+		isPinned(current) == isPinned(updated) &&
+		current.Visibility == updated.Visibility &&
+		current.DueAt.Valid == updated.DueAt.Valid &&
+		current.DueAt.Time.Equal(updated.DueAt.Time) &&
+		current.Assignee == updated.Assignee &&
+		current.Status == updated.Status
+}
+
+func isPinned(customerNote domain.CustomerNote) bool {
+	return customerNote.Pinned != nil && *customerNote.Pinned
+}
+
 func NewCustomerNoteUseCase(c domain.CustomerNoteRepository, log *logrus.Logger) domain.CustomerNoteUseCase {
 	return &customerNoteUseCase{
 		customerNoteRepository: c,
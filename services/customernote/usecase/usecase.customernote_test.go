@@ -0,0 +1,249 @@
+package usecase_customernote
+
+import (
+	"context"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"database/sql"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type stubCustomerNoteRepository struct {
+	domain.CustomerNoteRepository
+	notes     map[int]domain.CustomerNote
+	revisions []domain.CustomerNoteRevision
+	updated   *domain.CustomerNote
+	inserted  *domain.CustomerNote
+}
+
+func (s *stubCustomerNoteRepository) Insert(customerNote *domain.CustomerNote, ctx context.Context) (domain.Response, error) {
+	inserted := *customerNote
+	s.inserted = &inserted
+	return domain.Response{Message: "Succes Insert", StatusCode: 200}, nil
+}
+
+func (s *stubCustomerNoteRepository) GetById(id int, ctx context.Context) (domain.CustomerNote, error) {
+	note, ok := s.notes[id]
+	if !ok {
+		return domain.CustomerNote{}, sql.ErrNoRows
+	}
+	return note, nil
+}
+
+func (s *stubCustomerNoteRepository) GetRevisions(id int, ctx context.Context) ([]domain.CustomerNoteRevision, error) {
+	return s.revisions, nil
+}
+
+func (s *stubCustomerNoteRepository) DeleteById(id int, ctx context.Context) (domain.Response, error) {
+	if _, ok := s.notes[id]; !ok {
+		return domain.Response{StatusCode: 500}, sql.ErrNoRows
+	}
+	delete(s.notes, id)
+	return domain.Response{Message: "Succes Delete!!", StatusCode: 200}, nil
+}
+
+func (s *stubCustomerNoteRepository) Update(customerNote *domain.CustomerNote, ctx context.Context) (domain.Response, error) {
+	updated := *customerNote
+	s.updated = &updated
+	return domain.Response{Message: "Succes Update", StatusCode: 200}, nil
+}
+
+func newUseCase(repository *stubCustomerNoteRepository) domain.CustomerNoteUseCase {
+	logger := logrus.New()
+	logger.Out = io.Discard
+	return NewCustomerNoteUseCase(repository, logger)
+}
+
+func TestUpdateKeepsAuthorAndCreatedAt(t *testing.T) {
+	createdAt := types.NullTime{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true}
+	pinned := true
+	repository := &stubCustomerNoteRepository{notes: map[int]domain.CustomerNote{
+		7: {ID: 7, CustomerNumber: 1, Note: "called", Author: "jane", Category: "call", Pinned: &pinned, Visibility: "internal", CreatedAt: createdAt},
+	}}
+	useCase := newUseCase(repository)
+
+	ctx := domain.WithUser(context.Background(), "bob")
+	update := domain.CustomerNote{
+		ID:        7,
+		Note:      "called twice",
+		Author:    "mallory",
+		CreatedAt: types.NullTime{Time: time.Now(), Valid: true},
+	}
+	message, err := useCase.Update(&update, ctx)
+	if err != nil || message.StatusCode != 200 {
+		t.Fatalf("Update() = %v, %v", message, err)
+	}
+
+	got := repository.updated
+	if got == nil {
+		t.Fatal("Update() did not reach the repository")
+	}
+	if got.Author != "jane" || got.CreatedAt != createdAt {
+		t.Errorf("Update() author/created_at = %q/%v, want jane/%v", got.Author, got.CreatedAt, createdAt)
+	}
+	if got.UpdatedBy != "bob" || !got.UpdatedAt.Valid {
+		t.Errorf("Update() updated_by/updated_at = %q/%v", got.UpdatedBy, got.UpdatedAt)
+	}
+	if got.CustomerNumber != 1 || got.Category != "call" || got.Pinned == nil || !*got.Pinned {
+		t.Errorf("Update() did not keep unset fields: %+v", got)
+	}
+}
+
+func TestUpdateWithoutChangesKeepsRevision(t *testing.T) {
+	repository := &stubCustomerNoteRepository{notes: map[int]domain.CustomerNote{
+		7: {ID: 7, CustomerNumber: 1, Note: "called", Author: "jane", Category: "call", Visibility: "internal"},
+	}}
+	useCase := newUseCase(repository)
+
+	message, err := useCase.Update(&domain.CustomerNote{ID: 7, Note: "called"}, domain.WithUser(context.Background(), "bob"))
+	if err != nil || message.StatusCode != 200 {
+		t.Fatalf("Update() = %v, %v", message, err)
+	}
+	if repository.updated != nil {
+		t.Error("Update() without changes wrote a revision")
+	}
+}
+
+// withToken is the context of a request with a bearer token for user.
+func withToken(user string) context.Context {
+	return domain.WithSubject(domain.WithUser(context.Background(), user), user)
+}
+
+func TestPrivateNotesAreHiddenFromOthers(t *testing.T) {
+	repository := &stubCustomerNoteRepository{notes: map[int]domain.CustomerNote{
+		7: {ID: 7, CustomerNumber: 1, Note: "secret", Author: "jane", Category: "other", Visibility: "private"},
+	}}
+	useCase := newUseCase(repository)
+
+	if _, err := useCase.GetById(7, withToken("jane")); err != nil {
+		t.Errorf("GetById() by the author = %v", err)
+	}
+	if _, err := useCase.GetById(7, domain.WithUser(context.Background(), "jane")); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetById() by the author without a token = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := useCase.GetById(7, withToken("bob")); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetById() by someone else = %v, want sql.ErrNoRows", err)
+	}
+	message, err := useCase.Update(&domain.CustomerNote{ID: 7, Note: "x"}, withToken("bob"))
+	if err == nil || message.StatusCode == 200 {
+		t.Errorf("Update() by someone else = %v, %v", message, err)
+	}
+	if message, err := useCase.DeleteById(7, withToken("bob")); !errors.Is(err, sql.ErrNoRows) || message.StatusCode == 200 {
+		t.Errorf("DeleteById() by someone else = %v, %v, want sql.ErrNoRows", message, err)
+	}
+	if _, ok := repository.notes[7]; !ok {
+		t.Fatal("DeleteById() by someone else deleted the note")
+	}
+	if message, err := useCase.DeleteById(7, withToken("jane")); err != nil || message.StatusCode != 200 {
+		t.Errorf("DeleteById() by the author = %v, %v", message, err)
+	}
+}
+
+func TestPrivateNotesNeedToken(t *testing.T) {
+	repository := &stubCustomerNoteRepository{notes: map[int]domain.CustomerNote{}}
+	useCase := newUseCase(repository)
+
+	customerNote := domain.CustomerNote{CustomerNumber: 1, Note: "secret", Category: "other", Visibility: "private"}
+	message, err := useCase.Insert(&customerNote, domain.WithUser(context.Background(), "jane"))
+	if err != nil || message.StatusCode != 400 {
+		t.Errorf("Insert() of a private note without a token = %v, %v, want 400", message, err)
+	}
+}
+
+func TestGetRevisionsDiffs(t *testing.T) {
+	repository := &stubCustomerNoteRepository{
+		notes: map[int]domain.CustomerNote{7: {ID: 7, Visibility: "internal"}},
+		revisions: []domain.CustomerNoteRevision{
+			{Revision: 1, Note: "called\nabout billing", Category: "call", Visibility: "internal"},
+			{Revision: 2, Note: "called\nabout shipping", Category: "complaint", Visibility: "internal"},
+			{Revision: 3, Note: "called\nabout shipping", Category: "complaint", Pinned: true, Visibility: "internal"},
+		},
+	}
+	revisions, err := newUseCase(repository).GetRevisions(7, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "+called\n+about billing\n"; revisions[0].Diff != want || revisions[0].Changes != nil {
+		t.Errorf("revision 1 diff = %q %v, want %q", revisions[0].Diff, revisions[0].Changes, want)
+	}
+	if want := " called\n-about billing\n+about shipping\n"; revisions[1].Diff != want {
+		t.Errorf("revision 2 diff = %q, want %q", revisions[1].Diff, want)
+	}
+	if !reflect.DeepEqual(revisions[1].Changes, []string{"note", "category"}) {
+		t.Errorf("revision 2 changes = %v", revisions[1].Changes)
+	}
+	if revisions[2].Diff != "" || !reflect.DeepEqual(revisions[2].Changes, []string{"pinned"}) {
+		t.Errorf("revision 3 = %q %v, want only pinned changed", revisions[2].Diff, revisions[2].Changes)
+	}
+}
+
+func TestParseMentions(t *testing.T) {
+	tests := map[string][]string{
+		"@bob please call back":                    {"bob"},
+		"cc @jane.agent, @bob and @jane.agent.":    {"bob", "jane.agent"},
+		"mail jane@example.com":                    nil,
+		"(@ops-team) see @@nobody":                 {"ops-team"},
+		"no mentions here":                         nil,
+		"@" + strings.Repeat("x", maxUserLength+1): nil,
+	}
+	for text, want := range tests {
+		if got := parseMentions(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseMentions(%.30q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestInsertFollowUp(t *testing.T) {
+	dueAt := types.NullTime{Time: time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC), Valid: true}
+	repository := &stubCustomerNoteRepository{}
+	useCase := newUseCase(repository)
+	ctx := domain.WithUser(context.Background(), "jane")
+
+	message, err := useCase.Insert(&domain.CustomerNote{CustomerNumber: 1, Note: "call back, @bob knows", DueAt: dueAt}, ctx)
+	if err != nil || message.StatusCode != 200 {
+		t.Fatalf("Insert() = %v, %v", message, err)
+	}
+	got := repository.inserted
+	if got.Assignee != "jane" || got.Status != domain.NoteStatusOpen {
+		t.Errorf("Insert() assignee/status = %q/%q, want jane/open", got.Assignee, got.Status)
+	}
+	if !reflect.DeepEqual(got.Mentions, []string{"bob"}) {
+		t.Errorf("Insert() mentions = %v, want [bob]", got.Mentions)
+	}
+
+	rejected := []domain.CustomerNote{
+		{CustomerNumber: 1, Note: "x", Status: domain.NoteStatusOpen},
+		{CustomerNumber: 1, Note: "x", DueAt: dueAt, Status: "later"},
+		{CustomerNumber: 1, Note: "x", Assignee: "bob", Visibility: domain.NoteVisibilityPrivate},
+	}
+	for _, note := range rejected {
+		if message, err := useCase.Insert(&note, ctx); err != nil || message.StatusCode != 400 {
+			t.Errorf("Insert(%+v) = %v, %v, want 400", note, message, err)
+		}
+	}
+}
+
+func TestUpdateMarksFollowUpDone(t *testing.T) {
+	dueAt := types.NullTime{Time: time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC), Valid: true}
+	repository := &stubCustomerNoteRepository{notes: map[int]domain.CustomerNote{
+		7: {ID: 7, CustomerNumber: 1, Note: "call back", Author: "jane", Category: "call", Visibility: "internal", DueAt: dueAt, Assignee: "bob", Status: "open"},
+	}}
+	useCase := newUseCase(repository)
+
+	message, err := useCase.Update(&domain.CustomerNote{ID: 7, Status: domain.NoteStatusDone}, domain.WithUser(context.Background(), "bob"))
+	if err != nil || message.StatusCode != 200 {
+		t.Fatalf("Update() = %v, %v", message, err)
+	}
+	got := repository.updated
+	if got == nil || got.Status != domain.NoteStatusDone || got.Assignee != "bob" || got.DueAt != dueAt {
+		t.Errorf("Update() = %+v, want done and the rest kept", got)
+	}
+}
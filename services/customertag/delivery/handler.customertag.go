@@ -0,0 +1,140 @@
+package delivery_customertag
+
+import (
+	"customer-playground/domain"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type TagHandler struct {
+	tagUseCase domain.TagUseCase
+	logger     *logrus.Logger
+}
+
+func NewTagHandler(r *gin.Engine, c domain.TagUseCase, l *logrus.Logger) *gin.Engine {
+	handler := &TagHandler{tagUseCase: c, logger: l}
+
+	r.GET("/tag", handler.HandlerGetAllTag)
+	r.GET("/customer/:customer_number/tags", handler.HandlerGetTagByCustomerNumber)
+	r.POST("/tag/add", handler.HandlerAddTags)
+	r.POST("/tag/remove", handler.HandlerRemoveTags)
+
+	return r
+}
+
+// HandlerGetAllTag godoc
+// @Summary Get all tags
+// @Description Retrieves every tag with the number of customers carrying it
+// @Tags tag
+// @Produce json
+// @Success 200 {array} domain.Tag
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /tag [get]
+func (c *TagHandler) HandlerGetAllTag(ctx *gin.Context) {
+	tags, err := c.tagUseCase.GetAll(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "TagHandler/HandlerGetAllTag", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tags)
+	return
+}
+
+// HandlerGetTagByCustomerNumber godoc
+// @Summary Get customer tags
+// @Description Retrieves the tags of a customer
+// @Tags tag
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Success 200 {array} domain.Tag
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/tags [get]
+func (c *TagHandler) HandlerGetTagByCustomerNumber(ctx *gin.Context) {
+	customerNumber, err := strconv.Atoi(ctx.Param("customer_number"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "TagHandler/HandlerGetTagByCustomerNumber/ParseCustomerNumber", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	tags, err := c.tagUseCase.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "TagHandler/HandlerGetTagByCustomerNumber", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tags)
+	return
+}
+
+// HandlerAddTags godoc
+// @Summary Tag customers
+// @Description Adds every tag to every customer in one transaction. Unknown tags are created; nothing is tagged when a customer does not exist.
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Param assignment body domain.TagAssignment true "Tag Assignment Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /tag/add [post]
+func (c *TagHandler) HandlerAddTags(ctx *gin.Context) {
+	var assignment domain.TagAssignment
+	err := ctx.Bind(&assignment)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "TagHandler/HandlerAddTags/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.tagUseCase.AddTags(&assignment, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "TagHandler/HandlerAddTags/AddTags", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerRemoveTags godoc
+// @Summary Untag customers
+// @Description Removes every tag from every customer. Tags themselves are kept.
+// @Tags tag
+// @Accept json
+// @Produce json
+// @Param assignment body domain.TagAssignment true "Tag Assignment Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /tag/remove [post]
+func (c *TagHandler) HandlerRemoveTags(ctx *gin.Context) {
+	var assignment domain.TagAssignment
+	err := ctx.Bind(&assignment)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "TagHandler/HandlerRemoveTags/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.tagUseCase.RemoveTags(&assignment, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "TagHandler/HandlerRemoveTags/RemoveTags", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
@@ -0,0 +1,223 @@
+package repository_customertag
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queryGetAll = `
+		SELECT
+			t.id,
+			t.name,
+			COUNT(ct.customer_number),
+			t.created_at
+		FROM tag t
+		LEFT JOIN customer_tag ct ON ct.tag_id = t.id AND ct.tenant_id = t.tenant_id
+		WHERE t.tenant_id = app_tenant()
+		GROUP BY t.id
+		ORDER BY t.name
+	`
+	queryGetByCustomerNumber = `
+		SELECT
+			t.id,
+			t.name,
+			(SELECT COUNT(*) FROM customer_tag WHERE tenant_id = t.tenant_id AND tag_id = t.id),
+			t.created_at
+		FROM tag t
+		JOIN customer_tag ct ON ct.tag_id = t.id AND ct.tenant_id = t.tenant_id
+		WHERE t.tenant_id = app_tenant() AND ct.customer_number = $1
+		ORDER BY t.name
+	`
+	queryInsertTags = `
+		INSERT INTO tag (name)
+		SELECT DISTINCT unnest($1::varchar[])
+		ON CONFLICT (tenant_id, name) DO NOTHING
+	`
+	queryMissingCustomers = `
+		SELECT n
+		FROM unnest($1::integer[]) AS n
+		WHERE NOT EXISTS (SELECT 1 FROM customer WHERE tenant_id = app_tenant() AND customer_number = n)
+		ORDER BY n
+	`
+	queryAddTags = `
+		INSERT INTO customer_tag (customer_number, tag_id)
+		SELECT DISTINCT c.n, t.id
+		FROM unnest($1::integer[]) AS c(n)
+		CROSS JOIN tag t
+		WHERE t.tenant_id = app_tenant() AND t.name = ANY($2::varchar[])
+		ON CONFLICT DO NOTHING
+	`
+	queryRemoveTags = `
+		DELETE
+		FROM customer_tag ct
+		USING tag t
+		WHERE t.id = ct.tag_id
+			AND t.tenant_id = app_tenant()
+			AND ct.tenant_id = app_tenant()
+			AND ct.customer_number = ANY($1::integer[])
+			AND t.name = ANY($2::varchar[])
+	`
+)
+
+type tagRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	logger *logrus.Logger
+}
+
+func (c tagRepository) query(ctx context.Context, query string, args ...interface{}) ([]domain.Tag, error) {
+	var tags []domain.Tag
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, query, func(stmt *sql.Stmt) error {
+				tags = nil
+				rows, err := stmt.QueryContext(ctx, args...)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var tag domain.Tag
+					err := rows.Scan(
+						&tag.ID,
+						&tag.Name,
+						&tag.CustomerCount,
+						&tag.CreatedAt,
+					)
+					if err != nil {
+						return err
+					}
+
+					tags = append(tags, tag)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (c tagRepository) GetAll(ctx context.Context) ([]domain.Tag, error) {
+	return c.query(ctx, queryGetAll)
+}
+
+func (c tagRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) ([]domain.Tag, error) {
+	return c.query(ctx, queryGetByCustomerNumber, customerNumber)
+}
+
+// AddTags creates missing tags and assigns them in one transaction. Nothing is
+// assigned when one of the customers does not exist.
+func (c tagRepository) AddTags(assignment *domain.TagAssignment, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	var missing []int
+	var added int64
+	err := c.transaction(ctx, func(tx *database.Tx) error {
+		missing = nil
+		rows, err := tx.Query(queryMissingCustomers, pq.Array(assignment.CustomerNumbers))
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var customerNumber int
+			if err := rows.Scan(&customerNumber); err != nil {
+				rows.Close()
+				return err
+			}
+			missing = append(missing, customerNumber)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return nil
+		}
+
+		if _, err := tx.Exec(queryInsertTags, pq.Array(assignment.Tags)); err != nil {
+			return err
+		}
+		result, err := tx.Exec(queryAddTags, pq.Array(assignment.CustomerNumbers), pq.Array(assignment.Tags))
+		if err != nil {
+			return err
+		}
+		added, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		message.Message = "Failed to Add Tags"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	if len(missing) > 0 {
+		message.Message = fmt.Sprintf("No customer found with number %v", missing)
+		message.StatusCode = 400
+		return message, nil
+	}
+	message.Message = fmt.Sprintf("Succes Add %d Tags", added)
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c tagRepository) RemoveTags(assignment *domain.TagAssignment, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	var result sql.Result
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryRemoveTags, func(stmt *sql.Stmt) error {
+			var err error
+			result, err = stmt.ExecContext(ctx, pq.Array(assignment.CustomerNumbers), pq.Array(assignment.Tags))
+			return err
+		})
+	})
+	if err != nil {
+		message.Message = "Failed to Remove Tags"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		message.Message = "Could not determine removed tags"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to get rows affected: %v", err)
+		return message, err
+	}
+	message.Message = fmt.Sprintf("Succes Remove %d Tags", removed)
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c tagRepository) transaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	return c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), fn)
+	})
+}
+
+// Close releases the prepared statements held by the repository.
+func (c tagRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewTagRepository(db *database.DB, retry database.RetryPolicy, log *logrus.Logger) domain.TagRepository {
+	return &tagRepository{
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
+		logger: log,
+	}
+}
@@ -0,0 +1,115 @@
+package usecase_customertag
+
+import (
+	"context"
+	"customer-playground/domain"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxCustomersPerRequest = 1000
+	maxTagsPerRequest      = 50
+)
+
+var tagFormat = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+type tagUseCase struct {
+	tagRepository domain.TagRepository
+	logger        *logrus.Logger
+}
+
+func (c tagUseCase) GetAll(ctx context.Context) ([]domain.Tag, error) {
+	tags, err := c.tagRepository.GetAll(ctx)
+	if err != nil {
+		c.logger.Errorf("tagUseCase/GetAll :%v", err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (c tagUseCase) GetByCustomerNumber(customerNumber int, ctx context.Context) ([]domain.Tag, error) {
+	tags, err := c.tagRepository.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("tagUseCase/GetByCustomerNumber :%v", err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (c tagUseCase) AddTags(assignment *domain.TagAssignment, ctx context.Context) (domain.Response, error) {
+	if err := normalize(assignment); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+	message, err := c.tagRepository.AddTags(assignment, ctx)
+	if err != nil {
+		c.logger.Errorf("tagUseCase/AddTags :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+func (c tagUseCase) RemoveTags(assignment *domain.TagAssignment, ctx context.Context) (domain.Response, error) {
+	if err := normalize(assignment); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+	message, err := c.tagRepository.RemoveTags(assignment, ctx)
+	if err != nil {
+		c.logger.Errorf("tagUseCase/RemoveTags :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+// normalize lower cases and deduplicates tag names and customer numbers and
+// checks the request size.
+func normalize(assignment *domain.TagAssignment) error {
+	if len(assignment.CustomerNumbers) == 0 || len(assignment.Tags) == 0 {
+		return fmt.Errorf("customer_numbers and tags must not be empty")
+	}
+
+	seenCustomers := make(map[int]bool)
+	var customerNumbers []int
+	for _, customerNumber := range assignment.CustomerNumbers {
+		if customerNumber <= 0 {
+			return fmt.Errorf("%d is not a valid customer number", customerNumber)
+		}
+		if !seenCustomers[customerNumber] {
+			seenCustomers[customerNumber] = true
+			customerNumbers = append(customerNumbers, customerNumber)
+		}
+	}
+
+	seenTags := make(map[string]bool)
+	var tags []string
+	for _, tag := range assignment.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !tagFormat.MatchString(tag) {
+			return fmt.Errorf("%q is not a valid tag, use up to 50 letters, digits, '-' or '_'", tag)
+		}
+		if !seenTags[tag] {
+			seenTags[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	if len(customerNumbers) > maxCustomersPerRequest {
+		return fmt.Errorf("at most %d customers can be tagged at once", maxCustomersPerRequest)
+	}
+	if len(tags) > maxTagsPerRequest {
+		return fmt.Errorf("at most %d tags can be assigned at once", maxTagsPerRequest)
+	}
+	assignment.CustomerNumbers = customerNumbers
+	assignment.Tags = tags
+	return nil
+}
+
+func NewTagUseCase(c domain.TagRepository, log *logrus.Logger) domain.TagUseCase {
+	return &tagUseCase{
+		tagRepository: c,
+		logger:        log,
+	}
+}
@@ -0,0 +1,40 @@
+package usecase_customertag
+
+import (
+	"customer-playground/domain"
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	assignment := domain.TagAssignment{
+		CustomerNumbers: []int{2, 1, 2},
+		Tags:            []string{" VIP ", "churn-risk", "vip"},
+	}
+	if err := normalize(&assignment); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 1}; !reflect.DeepEqual(assignment.CustomerNumbers, want) {
+		t.Errorf("CustomerNumbers = %v, want %v", assignment.CustomerNumbers, want)
+	}
+	if want := []string{"vip", "churn-risk"}; !reflect.DeepEqual(assignment.Tags, want) {
+		t.Errorf("Tags = %v, want %v", assignment.Tags, want)
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	tests := []domain.TagAssignment{
+		{CustomerNumbers: nil, Tags: []string{"vip"}},
+		{CustomerNumbers: []int{1}, Tags: nil},
+		{CustomerNumbers: []int{0}, Tags: []string{"vip"}},
+		{CustomerNumbers: []int{1}, Tags: []string{"-vip"}},
+		{CustomerNumbers: []int{1}, Tags: []string{"very important"}},
+		{CustomerNumbers: []int{1}, Tags: []string{""}},
+	}
+	for _, test := range tests {
+		assignment := test
+		if err := normalize(&assignment); err == nil {
+			t.Errorf("normalize(%+v) = nil, want error", test)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package delivery_customfield
+
+import (
+	"customer-playground/domain"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type CustomFieldHandler struct {
+	customFieldUseCase domain.CustomFieldUseCase
+	logger             *logrus.Logger
+}
+
+func NewCustomFieldHandler(r *gin.Engine, c domain.CustomFieldUseCase, l *logrus.Logger) *gin.Engine {
+	handler := &CustomFieldHandler{customFieldUseCase: c, logger: l}
+
+	r.GET("/custom-field", handler.HandlerGetAllCustomField)
+	r.GET("/custom-field/:id", handler.HandlerGetByIdCustomField)
+	r.POST("/custom-field", handler.HandlerInsertCustomField)
+	r.PUT("/custom-field/:id", handler.HandlerUpdateCustomField)
+	r.DELETE("/custom-field/:id", handler.HandlerDeleteCustomFieldById)
+
+	return r
+}
+
+// HandlerGetAllCustomField godoc
+// @Summary Get all custom fields
+// @Description Retrieves every custom field definition
+// @Tags custom-field
+// @Produce json
+// @Success 200 {array} domain.CustomFieldDefinition
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /custom-field [get]
+func (c *CustomFieldHandler) HandlerGetAllCustomField(ctx *gin.Context) {
+	definitions, err := c.customFieldUseCase.GetAll(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomFieldHandler/HandlerGetAllCustomField", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, definitions)
+	return
+}
+
+// HandlerGetByIdCustomField godoc
+// @Summary Get a custom field
+// @Description Retrieves a custom field definition by its ID
+// @Tags custom-field
+// @Produce json
+// @Param id path int true "Custom Field ID"
+// @Success 200 {object} domain.CustomFieldDefinition
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /custom-field/{id} [get]
+func (c *CustomFieldHandler) HandlerGetByIdCustomField(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomFieldHandler/HandlerGetByIdCustomField/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	definition, err := c.customFieldUseCase.GetById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomFieldHandler/HandlerGetByIdCustomField", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, definition)
+	return
+}
+
+// HandlerInsertCustomField godoc
+// @Summary Define a custom field
+// @Description Defines a custom field that customers can carry in custom_fields. Types are string, integer, number, boolean, date and enum; strings can have a validation pattern.
+// @Tags custom-field
+// @Accept json
+// @Produce json
+// @Param definition body domain.CustomFieldDefinition true "Custom Field Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /custom-field [post]
+func (c *CustomFieldHandler) HandlerInsertCustomField(ctx *gin.Context) {
+	var definition domain.CustomFieldDefinition
+	err := ctx.Bind(&definition)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomFieldHandler/HandlerInsertCustomField/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.customFieldUseCase.Insert(&definition, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomFieldHandler/HandlerInsertCustomField/Insert", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerUpdateCustomField godoc
+// @Summary Update a custom field
+// @Description Updates a custom field definition. The name and type cannot change, required is always taken from the payload and other empty fields keep their current value. Stored values are not revalidated.
+// @Tags custom-field
+// @Accept json
+// @Produce json
+// @Param id path int true "Custom Field ID"
+// @Param definition body domain.CustomFieldDefinition true "Custom Field Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /custom-field/{id} [put]
+func (c *CustomFieldHandler) HandlerUpdateCustomField(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomFieldHandler/HandlerUpdateCustomField/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	var definition domain.CustomFieldDefinition
+	err = ctx.Bind(&definition)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomFieldHandler/HandlerUpdateCustomField/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	definition.ID = id
+	message, err := c.customFieldUseCase.Update(&definition, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomFieldHandler/HandlerUpdateCustomField/Update", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerDeleteCustomFieldById godoc
+// @Summary Delete a custom field
+// @Description Deletes a custom field definition and removes its value from every customer
+// @Tags custom-field
+// @Produce json
+// @Param id path int true "Custom Field ID"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /custom-field/{id} [delete]
+func (c *CustomFieldHandler) HandlerDeleteCustomFieldById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomFieldHandler/HandlerDeleteCustomFieldById/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.customFieldUseCase.DeleteById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "CustomFieldHandler/HandlerDeleteCustomFieldById/Delete", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
@@ -0,0 +1,263 @@
+package repository_customfield
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queryGetAll = `
+		SELECT
+			id,
+			name,
+			label,
+			description,
+			type,
+			required,
+			enum_values,
+			pattern,
+			created_at,
+			updated_at
+		FROM custom_field_definition
+		WHERE tenant_id = app_tenant()
+		ORDER BY name
+	`
+	queryGetById = `
+		SELECT
+			id,
+			name,
+			label,
+			description,
+			type,
+			required,
+			enum_values,
+			pattern,
+			created_at,
+			updated_at
+		FROM custom_field_definition
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+	queryInsert = `
+		INSERT INTO custom_field_definition(
+			name,
+			label,
+			description,
+			type,
+			required,
+			enum_values,
+			pattern,
+			created_at,
+			updated_at) VALUES (
+		$1, $2, $3, $4, $5, $6, $7, $8, $9
+	)
+		RETURNING id
+	`
+	queryUpdate = `
+		UPDATE custom_field_definition SET
+			label = $2,
+			description = $3,
+			required = $4,
+			enum_values = $5,
+			pattern = $6,
+			updated_at = $7
+		WHERE
+			tenant_id = app_tenant() AND id = $1
+	`
+	queryDeleteById = `
+		DELETE
+		FROM custom_field_definition
+		WHERE tenant_id = app_tenant() AND id = $1
+		RETURNING name
+	`
+	queryRemoveValues = `
+		UPDATE customer SET
+			custom_fields = custom_fields - $1::text
+		WHERE tenant_id = app_tenant() AND custom_fields ? $1::text
+	`
+)
+
+type customFieldRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	logger *logrus.Logger
+}
+
+func scanCustomFieldDefinition(row interface{ Scan(...interface{}) error }, definition *domain.CustomFieldDefinition) error {
+	return row.Scan(
+		&definition.ID,
+		&definition.Name,
+		&definition.Label,
+		&definition.Description,
+		&definition.Type,
+		&definition.Required,
+		pq.Array(&definition.EnumValues),
+		&definition.Pattern,
+		&definition.CreatedAt,
+		&definition.UpdatedAt,
+	)
+}
+
+func (c customFieldRepository) GetAll(ctx context.Context) ([]domain.CustomFieldDefinition, error) {
+	var definitions []domain.CustomFieldDefinition
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetAll, func(stmt *sql.Stmt) error {
+				definitions = nil
+				rows, err := stmt.QueryContext(ctx)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var definition domain.CustomFieldDefinition
+					if err := scanCustomFieldDefinition(rows, &definition); err != nil {
+						return err
+					}
+
+					definitions = append(definitions, definition)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return definitions, nil
+}
+
+func (c customFieldRepository) GetById(id int, ctx context.Context) (domain.CustomFieldDefinition, error) {
+	var definition domain.CustomFieldDefinition
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetById, func(stmt *sql.Stmt) error {
+				return scanCustomFieldDefinition(stmt.QueryRowContext(ctx, id), &definition)
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return domain.CustomFieldDefinition{}, err
+	}
+
+	return definition, nil
+}
+
+func (c customFieldRepository) Insert(definition *domain.CustomFieldDefinition, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryInsert, func(stmt *sql.Stmt) error {
+			return stmt.QueryRowContext(ctx,
+				definition.Name,
+				definition.Label,
+				definition.Description,
+				definition.Type,
+				definition.Required,
+				pq.Array(definition.EnumValues),
+				definition.Pattern,
+				definition.CreatedAt,
+				definition.UpdatedAt,
+			).Scan(&definition.ID)
+		})
+	})
+	if isUniqueViolation(err) {
+		message.Message = fmt.Sprintf("A custom field named %q already exists", definition.Name)
+		message.StatusCode = 400
+		return message, nil
+	}
+	if err != nil {
+		message.Message = "Failed to Insert Custom Field"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, nil
+	}
+	message.Message = fmt.Sprintf("Succes Insert Custom Field with id %d", definition.ID)
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c customFieldRepository) Update(definition *domain.CustomFieldDefinition, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryUpdate, func(stmt *sql.Stmt) error {
+			_, err := stmt.ExecContext(ctx,
+				definition.ID,
+				definition.Label,
+				definition.Description,
+				definition.Required,
+				pq.Array(definition.EnumValues),
+				definition.Pattern,
+				definition.UpdatedAt,
+			)
+			return err
+		})
+	})
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Update id %d", definition.ID)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, nil
+	}
+	message.Message = "Succes Update"
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c customFieldRepository) DeleteById(id int, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	err := c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), func(tx *database.Tx) error {
+			var name string
+			if err := tx.QueryRow(queryDeleteById, id).Scan(&name); err != nil {
+				return err
+			}
+			_, err := tx.Exec(queryRemoveValues, name)
+			return err
+		})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		message.Message = fmt.Sprintf("No custom field found with id %d", id)
+		message.StatusCode = 500
+		return message, err
+	}
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Delete id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	message.StatusCode = 200
+	message.Message = "Succes Delete!!"
+	return message, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// Close releases the prepared statements held by the repository.
+func (c customFieldRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewCustomFieldRepository(db *database.DB, retry database.RetryPolicy, log *logrus.Logger) domain.CustomFieldRepository {
+	return &customFieldRepository{
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
+		logger: log,
+	}
+}
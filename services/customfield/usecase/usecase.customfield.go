@@ -0,0 +1,114 @@
+package usecase_customfield
+
+import (
+	"context"
+	"customer-playground/customfield"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type customFieldUseCase struct {
+	customFieldRepository domain.CustomFieldRepository
+	logger                *logrus.Logger
+}
+
+func (c customFieldUseCase) GetAll(ctx context.Context) ([]domain.CustomFieldDefinition, error) {
+	definitions, err := c.customFieldRepository.GetAll(ctx)
+	if err != nil {
+		c.logger.Errorf("customFieldUseCase/GetAll :%v", err)
+		return nil, err
+	}
+	return definitions, nil
+}
+
+func (c customFieldUseCase) GetById(id int, ctx context.Context) (domain.CustomFieldDefinition, error) {
+	definition, err := c.customFieldRepository.GetById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("customFieldUseCase/GetById :%v", err)
+		return domain.CustomFieldDefinition{}, err
+	}
+	return definition, nil
+}
+
+func (c customFieldUseCase) Insert(definition *domain.CustomFieldDefinition, ctx context.Context) (domain.Response, error) {
+	now := time.Now()
+	definition.Name = strings.TrimSpace(definition.Name)
+	definition.Type = strings.ToLower(strings.TrimSpace(definition.Type))
+	definition.CreatedAt = types.NullTime{Time: now, Valid: true}
+	definition.UpdatedAt = types.NullTime{Time: now, Valid: true}
+	if err := customfield.CheckDefinition(definition); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+
+	message, err := c.customFieldRepository.Insert(definition, ctx)
+	if err != nil {
+		c.logger.Errorf("customFieldUseCase/Insert :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+// Update changes everything but the name and the type, which existing values
+// depend on. Existing values are not revalidated.
+func (c customFieldUseCase) Update(newDefinition *domain.CustomFieldDefinition, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	currentDefinition, err := c.customFieldRepository.GetById(newDefinition.ID, ctx)
+	if err != nil {
+		message.Message = "this id is not found"
+		message.StatusCode = 500
+		return message, err
+	}
+
+	if newDefinition.Name != "" && newDefinition.Name != currentDefinition.Name {
+		return domain.Response{Message: "the name of a custom field cannot change", StatusCode: 400}, nil
+	}
+	if newDefinition.Type != "" && newDefinition.Type != currentDefinition.Type {
+		return domain.Response{Message: "the type of a custom field cannot change", StatusCode: 400}, nil
+	}
+	newDefinition.Name = currentDefinition.Name
+	newDefinition.Type = currentDefinition.Type
+	if newDefinition.Label == "" {
+		newDefinition.Label = currentDefinition.Label
+	}
+	if newDefinition.Description == "" {
+		newDefinition.Description = currentDefinition.Description
+	}
+	if newDefinition.EnumValues == nil {
+		newDefinition.EnumValues = currentDefinition.EnumValues
+	}
+	if newDefinition.Pattern == "" {
+		newDefinition.Pattern = currentDefinition.Pattern
+	}
+	newDefinition.CreatedAt = currentDefinition.CreatedAt
+	newDefinition.UpdatedAt = types.NullTime{Time: time.Now(), Valid: true}
+	if err := customfield.CheckDefinition(newDefinition); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, nil
+	}
+
+	message, err = c.customFieldRepository.Update(newDefinition, ctx)
+	if err != nil {
+		c.logger.Errorf("customFieldUseCase/Update :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+func (c customFieldUseCase) DeleteById(id int, ctx context.Context) (domain.Response, error) {
+	message, err := c.customFieldRepository.DeleteById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("customFieldUseCase/DeleteById :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+func NewCustomFieldUseCase(c domain.CustomFieldRepository, log *logrus.Logger) domain.CustomFieldUseCase {
+	return &customFieldUseCase{
+		customFieldRepository: c,
+		logger:                log,
+	}
+}
@@ -0,0 +1,67 @@
+package delivery_followup
+
+import (
+	"customer-playground/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type FollowUpHandler struct {
+	followUpUseCase domain.FollowUpUseCase
+	logger          *logrus.Logger
+}
+
+func NewFollowUpHandler(r *gin.Engine, c domain.FollowUpUseCase, l *logrus.Logger) *gin.Engine {
+	handler := &FollowUpHandler{followUpUseCase: c, logger: l}
+
+	r.GET("/user/:user/follow-ups", handler.HandlerGetOpenFollowUps)
+	r.GET("/user/:user/mentions", handler.HandlerGetMentions)
+
+	return r
+}
+
+// HandlerGetOpenFollowUps godoc
+// @Summary Get a user's open follow-ups
+// @Description Retrieves the open follow-up notes assigned to a user across all customers, the earliest due first
+// @Tags follow-up
+// @Produce json
+// @Param user path string true "Assignee"
+// @Param X-User header string false "Agent making the request, needed for private notes"
+// @Success 200 {array} domain.CustomerNote
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /user/{user}/follow-ups [get]
+func (c *FollowUpHandler) HandlerGetOpenFollowUps(ctx *gin.Context) {
+	customerNotes, err := c.followUpUseCase.GetOpenByAssignee(ctx.Param("user"), ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "FollowUpHandler/HandlerGetOpenFollowUps", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, customerNotes)
+	return
+}
+
+// HandlerGetMentions godoc
+// @Summary Get notes mentioning a user
+// @Description Retrieves the notes that mention a user as @user, the newest first
+// @Tags follow-up
+// @Produce json
+// @Param user path string true "Mentioned user"
+// @Param X-User header string false "Agent making the request, needed for private notes"
+// @Success 200 {array} domain.CustomerNote
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /user/{user}/mentions [get]
+func (c *FollowUpHandler) HandlerGetMentions(ctx *gin.Context) {
+	customerNotes, err := c.followUpUseCase.GetMentioning(ctx.Param("user"), ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "FollowUpHandler/HandlerGetMentions", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, customerNotes)
+	return
+}
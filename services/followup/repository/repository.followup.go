@@ -0,0 +1,304 @@
+package repository_followup
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queryGetOpenByAssignee = `
+		SELECT
+			id,
+			customer_number,
+			note,
+			author,
+			category,
+			pinned,
+			visibility,
+			due_at,
+			assignee,
+			status,
+			ARRAY(SELECT username FROM customer_note_mention m WHERE m.note_id = customer_note.id ORDER BY username),
+			revision,
+			updated_by,
+			created_at,
+			updated_at
+		FROM customer_note
+		WHERE tenant_id = app_tenant() AND assignee = $1 AND status = 'open'
+		ORDER BY due_at NULLS LAST, id
+	`
+	queryGetMentioning = `
+		SELECT
+			id,
+			customer_number,
+			note,
+			author,
+			category,
+			pinned,
+			visibility,
+			due_at,
+			assignee,
+			status,
+			ARRAY(SELECT username FROM customer_note_mention m WHERE m.note_id = customer_note.id ORDER BY username),
+			revision,
+			updated_by,
+			created_at,
+			updated_at
+		FROM customer_note
+		WHERE tenant_id = app_tenant() AND id IN (SELECT note_id FROM customer_note_mention WHERE tenant_id = app_tenant() AND username = $1)
+		ORDER BY created_at DESC, id DESC
+	`
+	// queryLockDue locks the notes until the reminders are recorded, so
+	// another instance running the scheduler skips them.
+	queryLockDue = `
+		SELECT
+			id,
+			customer_number,
+			note,
+			author,
+			category,
+			pinned,
+			visibility,
+			due_at,
+			assignee,
+			status,
+			ARRAY(SELECT username FROM customer_note_mention m WHERE m.note_id = customer_note.id ORDER BY username),
+			revision,
+			updated_by,
+			created_at,
+			updated_at
+		FROM customer_note
+		WHERE tenant_id = app_tenant() AND status = 'open' AND due_at <= $1 AND reminded_at IS NULL
+		ORDER BY due_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	querySetReminded = `
+		UPDATE customer_note SET
+			reminded_at = $2
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+	queryLockPendingMentions = `
+		SELECT note_id, username
+		FROM customer_note_mention
+		WHERE tenant_id = app_tenant() AND notified_at IS NULL
+		ORDER BY created_at, note_id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+	queryGetNote = `
+		SELECT
+			id,
+			customer_number,
+			note,
+			author,
+			category,
+			pinned,
+			visibility,
+			due_at,
+			assignee,
+			status,
+			ARRAY(SELECT username FROM customer_note_mention m WHERE m.note_id = customer_note.id ORDER BY username),
+			revision,
+			updated_by,
+			created_at,
+			updated_at
+		FROM customer_note
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+	querySetNotified = `
+		UPDATE customer_note_mention SET
+			notified_at = $3
+		WHERE tenant_id = app_tenant() AND note_id = $1 AND username = $2
+	`
+)
+
+type followUpRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	logger *logrus.Logger
+}
+
+func scanCustomerNote(row interface{ Scan(...interface{}) error }, customerNote *domain.CustomerNote) error {
+	return row.Scan(
+		&customerNote.ID,
+		&customerNote.CustomerNumber,
+		&customerNote.Note,
+		&customerNote.Author,
+		&customerNote.Category,
+		&customerNote.Pinned,
+		&customerNote.Visibility,
+		&customerNote.DueAt,
+		&customerNote.Assignee,
+		&customerNote.Status,
+		pq.Array(&customerNote.Mentions),
+		&customerNote.Revision,
+		&customerNote.UpdatedBy,
+		&customerNote.CreatedAt,
+		&customerNote.UpdatedAt,
+	)
+}
+
+func (c followUpRepository) GetOpenByAssignee(user string, ctx context.Context) ([]domain.CustomerNote, error) {
+	customerNotes, err := c.query(ctx, queryGetOpenByAssignee, user)
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return customerNotes, nil
+}
+
+func (c followUpRepository) GetMentioning(user string, ctx context.Context) ([]domain.CustomerNote, error) {
+	customerNotes, err := c.query(ctx, queryGetMentioning, user)
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return customerNotes, nil
+}
+
+func (c followUpRepository) query(ctx context.Context, query string, args ...interface{}) ([]domain.CustomerNote, error) {
+	var customerNotes []domain.CustomerNote
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, query, func(stmt *sql.Stmt) error {
+				customerNotes = nil
+				rows, err := stmt.QueryContext(ctx, args...)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var customerNote domain.CustomerNote
+					if err := scanCustomerNote(rows, &customerNote); err != nil {
+						return err
+					}
+
+					customerNotes = append(customerNotes, customerNote)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	return customerNotes, err
+}
+
+func (c followUpRepository) DispatchDue(now time.Time, limit int, send func(domain.CustomerNote) error, ctx context.Context) (int, error) {
+	sent := 0
+	err := c.transaction(ctx, func(tx *database.Tx) error {
+		sent = 0
+		rows, err := tx.Query(queryLockDue, now, limit)
+		if err != nil {
+			return err
+		}
+		var due []domain.CustomerNote
+		for rows.Next() {
+			var customerNote domain.CustomerNote
+			if err := scanCustomerNote(rows, &customerNote); err != nil {
+				rows.Close()
+				return err
+			}
+			due = append(due, customerNote)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, customerNote := range due {
+			// A failed reminder stays due and is retried on the next run.
+			if send(customerNote) != nil {
+				continue
+			}
+			if _, err := tx.Exec(querySetReminded, customerNote.ID, now); err != nil {
+				return err
+			}
+			sent++
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Errorf("failed to dispatch reminders: %v", err)
+		return 0, err
+	}
+	return sent, nil
+}
+
+func (c followUpRepository) DispatchMentions(limit int, send func(user string, note domain.CustomerNote) error, ctx context.Context) (int, error) {
+	type mention struct {
+		noteId int
+		user   string
+	}
+	sent := 0
+	err := c.transaction(ctx, func(tx *database.Tx) error {
+		sent = 0
+		rows, err := tx.Query(queryLockPendingMentions, limit)
+		if err != nil {
+			return err
+		}
+		var pending []mention
+		for rows.Next() {
+			var m mention
+			if err := rows.Scan(&m.noteId, &m.user); err != nil {
+				rows.Close()
+				return err
+			}
+			pending = append(pending, m)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, m := range pending {
+			var customerNote domain.CustomerNote
+			if err := scanCustomerNote(tx.QueryRow(queryGetNote, m.noteId), &customerNote); err != nil {
+				return err
+			}
+			if send(m.user, customerNote) != nil {
+				continue
+			}
+			if _, err := tx.Exec(querySetNotified, m.noteId, m.user, time.Now()); err != nil {
+				return err
+			}
+			sent++
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Errorf("failed to dispatch mentions: %v", err)
+		return 0, err
+	}
+	return sent, nil
+}
+
+func (c followUpRepository) transaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	return c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), fn)
+	})
+}
+
+// Close releases the prepared statements held by the repository.
+func (c followUpRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewFollowUpRepository(db *database.DB, retry database.RetryPolicy, log *logrus.Logger) domain.FollowUpRepository {
+	return &followUpRepository{
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
+		logger: log,
+	}
+}
@@ -0,0 +1,103 @@
+package usecase_followup
+
+import (
+	"context"
+	"customer-playground/domain"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBatchSize is how many notifications one transaction sends when no
+// batch size is configured.
+const DefaultBatchSize = 100
+
+type followUpUseCase struct {
+	followUpRepository domain.FollowUpRepository
+	notifier           domain.Notifier
+	batchSize          int
+	logger             *logrus.Logger
+}
+
+// GetOpenByAssignee lists what user has to follow up on; private notes stay
+// hidden from everybody but their author.
+func (c followUpUseCase) GetOpenByAssignee(user string, ctx context.Context) ([]domain.CustomerNote, error) {
+	customerNotes, err := c.followUpRepository.GetOpenByAssignee(user, ctx)
+	if err != nil {
+		c.logger.Errorf("followUpUseCase/GetOpenByAssignee :%v", err)
+		return nil, err
+	}
+	return domain.VisibleNotes(customerNotes, domain.NoteReader(ctx)), nil
+}
+
+func (c followUpUseCase) GetMentioning(user string, ctx context.Context) ([]domain.CustomerNote, error) {
+	customerNotes, err := c.followUpRepository.GetMentioning(user, ctx)
+	if err != nil {
+		c.logger.Errorf("followUpUseCase/GetMentioning :%v", err)
+		return nil, err
+	}
+	return domain.VisibleNotes(customerNotes, domain.NoteReader(ctx)), nil
+}
+
+func (c followUpUseCase) DispatchReminders(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		sent, err := c.followUpRepository.DispatchDue(time.Now(), c.batchSize, func(customerNote domain.CustomerNote) error {
+			return c.notify(domain.Notification{Kind: domain.NotificationReminder, Recipient: customerNote.Assignee, Note: customerNote}, ctx)
+		}, ctx)
+		total += sent
+		if err != nil {
+			c.logger.Errorf("followUpUseCase/DispatchReminders :%v", err)
+			return total, err
+		}
+		// A short batch means nothing is left or a notifier is failing; the
+		// next run picks up the rest.
+		if sent < c.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// DispatchMentions skips mentions of the author and of users who cannot see
+// the note; they count as handled.
+func (c followUpUseCase) DispatchMentions(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		sent, err := c.followUpRepository.DispatchMentions(c.batchSize, func(user string, customerNote domain.CustomerNote) error {
+			if user == customerNote.Author || !customerNote.VisibleTo(user) {
+				return nil
+			}
+			return c.notify(domain.Notification{Kind: domain.NotificationMention, Recipient: user, Note: customerNote}, ctx)
+		}, ctx)
+		total += sent
+		if err != nil {
+			c.logger.Errorf("followUpUseCase/DispatchMentions :%v", err)
+			return total, err
+		}
+		if sent < c.batchSize {
+			return total, nil
+		}
+	}
+}
+
+func (c followUpUseCase) notify(notification domain.Notification, ctx context.Context) error {
+	if err := c.notifier.Notify(notification, ctx); err != nil {
+		c.logger.Errorf("followUpUseCase/Notify %s to %s for note %d :%v", notification.Kind, notification.Recipient, notification.Note.ID, err)
+		return err
+	}
+	return nil
+}
+
+// NewFollowUpUseCase sends up to batchSize notifications per transaction,
+// or DefaultBatchSize when it is not positive.
+func NewFollowUpUseCase(followUpRepository domain.FollowUpRepository, notifier domain.Notifier, batchSize int, log *logrus.Logger) domain.FollowUpUseCase {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &followUpUseCase{
+		followUpRepository: followUpRepository,
+		notifier:           notifier,
+		batchSize:          batchSize,
+		logger:             log,
+	}
+}
@@ -0,0 +1,133 @@
+package usecase_followup
+
+import (
+	"context"
+	"customer-playground/domain"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type recordingNotifier struct {
+	sent []domain.Notification
+	fail map[string]bool
+}
+
+func (n *recordingNotifier) Notify(notification domain.Notification, ctx context.Context) error {
+	if n.fail[notification.Recipient] {
+		return errors.New("unreachable")
+	}
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+type mention struct {
+	user string
+	note domain.CustomerNote
+}
+
+// stubFollowUpRepository hands out due notes and mentions in batches and
+// forgets the ones send succeeded for, like the database does.
+type stubFollowUpRepository struct {
+	domain.FollowUpRepository
+	due      []domain.CustomerNote
+	mentions []mention
+	open     []domain.CustomerNote
+}
+
+func (s *stubFollowUpRepository) GetOpenByAssignee(user string, ctx context.Context) ([]domain.CustomerNote, error) {
+	return s.open, nil
+}
+
+func (s *stubFollowUpRepository) DispatchDue(now time.Time, limit int, send func(domain.CustomerNote) error, ctx context.Context) (int, error) {
+	var left []domain.CustomerNote
+	sent := 0
+	for i, note := range s.due {
+		if i >= limit || send(note) != nil {
+			left = append(left, note)
+			continue
+		}
+		sent++
+	}
+	s.due = left
+	return sent, nil
+}
+
+func (s *stubFollowUpRepository) DispatchMentions(limit int, send func(string, domain.CustomerNote) error, ctx context.Context) (int, error) {
+	var left []mention
+	sent := 0
+	for i, m := range s.mentions {
+		if i >= limit || send(m.user, m.note) != nil {
+			left = append(left, m)
+			continue
+		}
+		sent++
+	}
+	s.mentions = left
+	return sent, nil
+}
+
+func newUseCase(repository domain.FollowUpRepository, notifier domain.Notifier, batchSize int) domain.FollowUpUseCase {
+	logger := logrus.New()
+	logger.Out = io.Discard
+	return NewFollowUpUseCase(repository, notifier, batchSize, logger)
+}
+
+func TestDispatchReminders(t *testing.T) {
+	repository := &stubFollowUpRepository{}
+	for i := 1; i <= 5; i++ {
+		repository.due = append(repository.due, domain.CustomerNote{ID: i, Assignee: "bob"})
+	}
+	repository.due = append(repository.due, domain.CustomerNote{ID: 6, Assignee: "offline"})
+	notifier := &recordingNotifier{fail: map[string]bool{"offline": true}}
+
+	sent, err := newUseCase(repository, notifier, 2).DispatchReminders(context.Background())
+	if err != nil || sent != 5 {
+		t.Fatalf("DispatchReminders() = %d, %v, want 5 in batches of 2", sent, err)
+	}
+	for _, notification := range notifier.sent {
+		if notification.Kind != domain.NotificationReminder || notification.Recipient != "bob" {
+			t.Errorf("sent %+v", notification)
+		}
+	}
+	if len(repository.due) != 1 || repository.due[0].ID != 6 {
+		t.Errorf("failed reminder is not kept for the next run: %+v", repository.due)
+	}
+}
+
+func TestDispatchMentionsSkipsAuthorAndHiddenNotes(t *testing.T) {
+	internal := domain.CustomerNote{ID: 1, Author: "jane", Visibility: domain.NoteVisibilityInternal}
+	private := domain.CustomerNote{ID: 2, Author: "jane", Visibility: domain.NoteVisibilityPrivate}
+	repository := &stubFollowUpRepository{mentions: []mention{
+		{"bob", internal},
+		{"jane", internal},
+		{"bob", private},
+	}}
+	notifier := &recordingNotifier{}
+
+	handled, err := newUseCase(repository, notifier, 0).DispatchMentions(context.Background())
+	if err != nil || handled != 3 {
+		t.Fatalf("DispatchMentions() = %d, %v, want all 3 handled", handled, err)
+	}
+	want := []domain.Notification{{Kind: domain.NotificationMention, Recipient: "bob", Note: internal}}
+	if !reflect.DeepEqual(notifier.sent, want) {
+		t.Errorf("sent %+v, want only bob about the internal note", notifier.sent)
+	}
+}
+
+func TestGetOpenByAssigneeHidesPrivateNotes(t *testing.T) {
+	repository := &stubFollowUpRepository{open: []domain.CustomerNote{
+		{ID: 1, Author: "jane", Assignee: "jane", Visibility: domain.NoteVisibilityPrivate},
+		{ID: 2, Author: "bob", Assignee: "jane", Visibility: domain.NoteVisibilityInternal},
+	}}
+	useCase := newUseCase(repository, &recordingNotifier{}, 0)
+
+	notes, err := useCase.GetOpenByAssignee("jane", domain.WithUser(context.Background(), "bob"))
+	if err != nil || len(notes) != 1 || notes[0].ID != 2 {
+		t.Errorf("GetOpenByAssignee() for bob = %+v, %v, want only note 2", notes, err)
+	}
+}
@@ -0,0 +1,209 @@
+package delivery_noteattachment
+
+import (
+	"customer-playground/domain"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// multipartOverhead leaves room for the multipart boundaries and headers
+// around a file of the maximum size.
+const multipartOverhead = 1 << 20
+
+type NoteAttachmentHandler struct {
+	noteAttachmentUseCase domain.NoteAttachmentUseCase
+	maxSize               int64
+	logger                *logrus.Logger
+}
+
+// NewNoteAttachmentHandler rejects request bodies much larger than maxSize
+// before they are read.
+func NewNoteAttachmentHandler(r *gin.Engine, c domain.NoteAttachmentUseCase, maxSize int64, l *logrus.Logger) *gin.Engine {
+	handler := &NoteAttachmentHandler{noteAttachmentUseCase: c, maxSize: maxSize, logger: l}
+
+	r.GET("/customer-note/:id/attachments", handler.HandlerGetNoteAttachments)
+	r.POST("/customer-note/:id/attachments", handler.HandlerUploadNoteAttachment)
+	r.GET("/customer-note/:id/attachments/:attachment_id", handler.HandlerDownloadNoteAttachment)
+	r.DELETE("/customer-note/:id/attachments/:attachment_id", handler.HandlerDeleteNoteAttachment)
+
+	return r
+}
+
+func pathIds(ctx *gin.Context) (int, int, error) {
+	noteId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return 0, 0, err
+	}
+	id, err := strconv.Atoi(ctx.Param("attachment_id"))
+	if err != nil {
+		return 0, 0, err
+	}
+	return noteId, id, nil
+}
+
+// HandlerGetNoteAttachments godoc
+// @Summary Get note attachments
+// @Description Retrieves the files attached to a customer note
+// @Tags note-attachment
+// @Produce json
+// @Param id path int true "Customer Note ID"
+// @Param X-User header string false "Agent making the request, needed for private notes"
+// @Success 200 {array} domain.NoteAttachment
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-note/{id}/attachments [get]
+func (c *NoteAttachmentHandler) HandlerGetNoteAttachments(ctx *gin.Context) {
+	noteId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerGetNoteAttachments/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	attachments, err := c.noteAttachmentUseCase.GetByNoteId(noteId, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerGetNoteAttachments", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attachments)
+	return
+}
+
+// HandlerUploadNoteAttachment godoc
+// @Summary Upload a note attachment
+// @Description Attaches a PDF, image or plain text file to a customer note. The type is sniffed from the content; identical files are stored once.
+// @Tags note-attachment
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Customer Note ID"
+// @Param file formData file true "File to attach"
+// @Param X-User header string false "Agent making the request"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 413 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-note/{id}/attachments [post]
+func (c *NoteAttachmentHandler) HandlerUploadNoteAttachment(ctx *gin.Context) {
+	noteId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerUploadNoteAttachment/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	maxSize := domain.TenantFromContext(ctx.Request.Context()).Int64("attachment.max_size", c.maxSize)
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize+multipartOverhead)
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerUploadNoteAttachment/ParseFile", err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, domain.ErrorResponse{Message: "the file is too large"})
+			return
+		}
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerUploadNoteAttachment/OpenFile", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+	// One byte more than allowed lets the use case report the limit.
+	content, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerUploadNoteAttachment/ReadFile", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	attachment := domain.NoteAttachment{NoteID: noteId, FileName: fileHeader.Filename}
+	message, err := c.noteAttachmentUseCase.Upload(&attachment, content, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerUploadNoteAttachment/Upload", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerDownloadNoteAttachment godoc
+// @Summary Download a note attachment
+// @Description Downloads the content of a note attachment
+// @Tags note-attachment
+// @Produce octet-stream
+// @Param id path int true "Customer Note ID"
+// @Param attachment_id path int true "Attachment ID"
+// @Param X-User header string false "Agent making the request, needed for private notes"
+// @Success 200 {file} file
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-note/{id}/attachments/{attachment_id} [get]
+func (c *NoteAttachmentHandler) HandlerDownloadNoteAttachment(ctx *gin.Context) {
+	noteId, id, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerDownloadNoteAttachment/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	attachment, content, err := c.noteAttachmentUseCase.Download(noteId, id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerDownloadNoteAttachment", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	defer content.Close()
+
+	ctx.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"ETag":                   `"` + attachment.SHA256 + `"`,
+	})
+	return
+}
+
+// HandlerDeleteNoteAttachment godoc
+// @Summary Delete a note attachment
+// @Description Removes an attachment from a note. The stored file is removed once no note refers to it.
+// @Tags note-attachment
+// @Produce json
+// @Param id path int true "Customer Note ID"
+// @Param attachment_id path int true "Attachment ID"
+// @Param X-User header string false "Agent making the request, needed for private notes"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-note/{id}/attachments/{attachment_id} [delete]
+func (c *NoteAttachmentHandler) HandlerDeleteNoteAttachment(ctx *gin.Context) {
+	noteId, id, err := pathIds(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerDeleteNoteAttachment/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.noteAttachmentUseCase.DeleteById(noteId, id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "NoteAttachmentHandler/HandlerDeleteNoteAttachment/Delete", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
@@ -0,0 +1,346 @@
+package repository_noteattachment
+
+import (
+	"bytes"
+	"context"
+	"customer-playground/blob"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queryGetByNoteId = `
+		SELECT
+			id,
+			note_id,
+			file_name,
+			content_type,
+			size,
+			sha256,
+			uploaded_by,
+			created_at
+		FROM customer_note_attachment
+		WHERE tenant_id = app_tenant() AND note_id = $1
+		ORDER BY id
+	`
+	queryGetById = `
+		SELECT
+			id,
+			note_id,
+			file_name,
+			content_type,
+			size,
+			sha256,
+			uploaded_by,
+			created_at
+		FROM customer_note_attachment
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+	// queryUpsertBlob locks the blob row for the rest of the transaction, so
+	// the cleanup cannot remove the file while it is being attached. It
+	// reports whether the row is new, i.e. the file still has to be stored.
+	queryUpsertBlob = `
+		INSERT INTO note_attachment_blob (sha256, size, content_type)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, sha256) DO UPDATE SET size = EXCLUDED.size
+		RETURNING (xmax = 0)
+	`
+	queryInsert = `
+		INSERT INTO customer_note_attachment(
+			note_id,
+			file_name,
+			content_type,
+			size,
+			sha256,
+			uploaded_by,
+			created_at) VALUES (
+		$1, $2, $3, $4, $5, $6, $7
+	)
+		RETURNING id
+	`
+	queryDeleteById = `
+		DELETE
+		FROM customer_note_attachment
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+	// Attachments of deleted notes and customers go with them through ON
+	// DELETE CASCADE, which leaves their blobs without references.
+	queryLockOrphanedBlobs = `
+		SELECT sha256
+		FROM note_attachment_blob b
+		WHERE b.tenant_id = app_tenant()
+			AND NOT EXISTS (SELECT 1 FROM customer_note_attachment a WHERE a.tenant_id = b.tenant_id AND a.sha256 = b.sha256)
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+	queryDeleteBlob = `
+		DELETE
+		FROM note_attachment_blob
+		WHERE tenant_id = app_tenant() AND sha256 = $1
+	`
+)
+
+// cleanupBatchSize bounds how long one cleanup transaction holds its locks.
+const cleanupBatchSize = 100
+
+type noteAttachmentRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	store  blob.Store
+	logger *logrus.Logger
+}
+
+func scanAttachment(row interface{ Scan(...interface{}) error }, attachment *domain.NoteAttachment) error {
+	return row.Scan(
+		&attachment.ID,
+		&attachment.NoteID,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+		&attachment.UploadedBy,
+		&attachment.CreatedAt,
+	)
+}
+
+// blobKey keeps the files of each tenant apart, so identical uploads of two
+// tenants are stored twice and cleaned up independently.
+func blobKey(sha256 string, ctx context.Context) string {
+	tenantID, _ := database.TenantID(ctx)
+	return fmt.Sprintf("tenant/%d/sha256/%s/%s", tenantID, sha256[:2], sha256)
+}
+
+func (c noteAttachmentRepository) GetByNoteId(noteId int, ctx context.Context) ([]domain.NoteAttachment, error) {
+	var attachments []domain.NoteAttachment
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetByNoteId, func(stmt *sql.Stmt) error {
+				attachments = nil
+				rows, err := stmt.QueryContext(ctx, noteId)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var attachment domain.NoteAttachment
+					if err := scanAttachment(rows, &attachment); err != nil {
+						return err
+					}
+					attachments = append(attachments, attachment)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (c noteAttachmentRepository) GetById(id int, ctx context.Context) (domain.NoteAttachment, error) {
+	var attachment domain.NoteAttachment
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetById, func(stmt *sql.Stmt) error {
+				return scanAttachment(stmt.QueryRowContext(ctx, id), &attachment)
+			})
+		})
+	})
+	if err != nil {
+		return domain.NoteAttachment{}, err
+	}
+
+	return attachment, nil
+}
+
+// Insert records the attachment and stores its content unless a file with
+// the same SHA256 is already stored.
+func (c noteAttachmentRepository) Insert(attachment *domain.NoteAttachment, content []byte, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	stored := false
+	err := c.transaction(ctx, func(tx *database.Tx) error {
+		var isNew bool
+		if err := tx.QueryRow(queryUpsertBlob, attachment.SHA256, attachment.Size, attachment.ContentType).Scan(&isNew); err != nil {
+			return err
+		}
+		err := tx.QueryRow(queryInsert,
+			attachment.NoteID,
+			attachment.FileName,
+			attachment.ContentType,
+			attachment.Size,
+			attachment.SHA256,
+			attachment.UploadedBy,
+			attachment.CreatedAt,
+		).Scan(&attachment.ID)
+		if err != nil {
+			return err
+		}
+		if !isNew {
+			return nil
+		}
+		stored = true
+		return c.store.Put(ctx, blobKey(attachment.SHA256, ctx), bytes.NewReader(content), attachment.Size, attachment.ContentType)
+	})
+	if isUniqueViolation(err) {
+		message.Message = fmt.Sprintf("This file is already attached to note %d", attachment.NoteID)
+		message.StatusCode = 400
+		return message, nil
+	}
+	if isForeignKeyViolation(err) {
+		message.Message = fmt.Sprintf("No customer note found with id %d", attachment.NoteID)
+		message.StatusCode = 400
+		return message, nil
+	}
+	if err != nil {
+		if stored {
+			// The blob row was rolled back, nothing refers to the file.
+			if deleteErr := c.store.Delete(context.WithoutCancel(ctx), blobKey(attachment.SHA256, ctx)); deleteErr != nil {
+				c.logger.Errorf("failed to delete blob: %v", deleteErr)
+			}
+		}
+		message.Message = "Failed to Insert Note Attachment"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	message.Message = fmt.Sprintf("Succes Insert Note Attachment with id %d", attachment.ID)
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c noteAttachmentRepository) Open(sha256 string, ctx context.Context) (io.ReadCloser, error) {
+	content, err := c.store.Get(ctx, blobKey(sha256, ctx))
+	if err != nil {
+		c.logger.Errorf("failed to read blob: %v", err)
+		return nil, err
+	}
+	return content, nil
+}
+
+func (c noteAttachmentRepository) DeleteById(id int, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	var result sql.Result
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryDeleteById, func(stmt *sql.Stmt) error {
+			var err error
+			result, err = stmt.ExecContext(ctx, id)
+			return err
+		})
+	})
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Delete id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		message.Message = fmt.Sprintf("Could not determine rows affected for id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to get rows affected: %v", err)
+		return message, err
+	}
+	if rowsAffected == 0 {
+		message.Message = fmt.Sprintf("No note attachment found with id %d", id)
+		message.StatusCode = 500
+		return message, sql.ErrNoRows
+	}
+	message.StatusCode = 200
+	message.Message = "Succes Delete!!"
+	return message, nil
+}
+
+// DeleteOrphanedBlobs removes stored files no attachment refers to, one
+// batch per transaction, and returns how many were removed.
+func (c noteAttachmentRepository) DeleteOrphanedBlobs(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		deleted := 0
+		err := c.transaction(ctx, func(tx *database.Tx) error {
+			deleted = 0
+			rows, err := tx.Query(queryLockOrphanedBlobs, cleanupBatchSize)
+			if err != nil {
+				return err
+			}
+			var orphans []string
+			for rows.Next() {
+				var sha256 string
+				if err := rows.Scan(&sha256); err != nil {
+					rows.Close()
+					return err
+				}
+				orphans = append(orphans, sha256)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			// The rows stay locked until commit, so no upload can reuse a
+			// file between its removal from the store and from the table.
+			for _, sha256 := range orphans {
+				if err := c.store.Delete(ctx, blobKey(sha256, ctx)); err != nil {
+					return err
+				}
+				if _, err := tx.Exec(queryDeleteBlob, sha256); err != nil {
+					return err
+				}
+				deleted++
+			}
+			return nil
+		})
+		if err != nil {
+			c.logger.Errorf("failed to clean up blobs: %v", err)
+			return total, err
+		}
+		total += deleted
+		if deleted < cleanupBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+func (c noteAttachmentRepository) transaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	return c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), fn)
+	})
+}
+
+// Close releases the prepared statements held by the repository.
+func (c noteAttachmentRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewNoteAttachmentRepository(db *database.DB, retry database.RetryPolicy, store blob.Store, log *logrus.Logger) domain.NoteAttachmentRepository {
+	return &noteAttachmentRepository{
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
+		store:  store,
+		logger: log,
+	}
+}
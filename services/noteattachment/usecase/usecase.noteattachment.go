@@ -0,0 +1,218 @@
+package usecase_noteattachment
+
+import (
+	"context"
+	"crypto/sha256"
+	"customer-playground/domain"
+	"customer-playground/types"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultMaxSize is used when no size limit is configured.
+const DefaultMaxSize = 10 << 20
+
+const maxFileNameLength = 255
+
+// allowedContentTypes are the sniffed types that may be attached. The type
+// a client declares is ignored.
+var allowedContentTypes = map[string]bool{
+	"application/pdf":           true,
+	"image/png":                 true,
+	"image/jpeg":                true,
+	"image/gif":                 true,
+	"image/webp":                true,
+	"text/plain; charset=utf-8": true,
+}
+
+type noteAttachmentUseCase struct {
+	noteAttachmentRepository domain.NoteAttachmentRepository
+	customerNoteUseCase      domain.CustomerNoteUseCase
+	maxSize                  int64
+	logger                   *logrus.Logger
+}
+
+// note checks that the note exists and is visible to the caller.
+func (c noteAttachmentUseCase) note(noteId int, ctx context.Context) (bool, error) {
+	_, err := c.customerNoteUseCase.GetById(noteId, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (c noteAttachmentUseCase) GetByNoteId(noteId int, ctx context.Context) ([]domain.NoteAttachment, error) {
+	found, err := c.note(noteId, ctx)
+	if err != nil {
+		c.logger.Errorf("noteAttachmentUseCase/GetByNoteId/GetNote :%v", err)
+		return nil, err
+	}
+	if !found {
+		return nil, sql.ErrNoRows
+	}
+	attachments, err := c.noteAttachmentRepository.GetByNoteId(noteId, ctx)
+	if err != nil {
+		c.logger.Errorf("noteAttachmentUseCase/GetByNoteId :%v", err)
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (c noteAttachmentUseCase) Upload(attachment *domain.NoteAttachment, content []byte, ctx context.Context) (domain.Response, error) {
+	found, err := c.note(attachment.NoteID, ctx)
+	if err != nil {
+		c.logger.Errorf("noteAttachmentUseCase/Upload/GetNote :%v", err)
+		return domain.Response{Message: "Failed to Insert Note Attachment", StatusCode: 500}, err
+	}
+	if !found {
+		return domain.Response{Message: fmt.Sprintf("No customer note found with id %d", attachment.NoteID), StatusCode: 400}, nil
+	}
+
+	if user := domain.UserFromContext(ctx); user != "" {
+		attachment.UploadedBy = user
+	}
+	if attachment.UploadedBy == "" {
+		return domain.Response{Message: "uploaded_by is required, send it in the " + domain.UserHeader + " header", StatusCode: 400}, nil
+	}
+	if len(content) == 0 {
+		return domain.Response{Message: "the file is empty", StatusCode: 400}, nil
+	}
+	if maxSize := domain.TenantFromContext(ctx).Int64("attachment.max_size", c.maxSize); int64(len(content)) > maxSize {
+		return domain.Response{Message: fmt.Sprintf("the file is larger than %d bytes", maxSize), StatusCode: 400}, nil
+	}
+	contentType, ok := detectContentType(content)
+	if !ok {
+		return domain.Response{Message: fmt.Sprintf("files of type %s cannot be attached", contentType), StatusCode: 400}, nil
+	}
+
+	sum := sha256.Sum256(content)
+	attachment.SHA256 = hex.EncodeToString(sum[:])
+	attachment.ContentType = contentType
+	attachment.Size = int64(len(content))
+	attachment.FileName = sanitizeFileName(attachment.FileName)
+	attachment.CreatedAt = types.NullTime{Time: time.Now(), Valid: true}
+
+	message, err := c.noteAttachmentRepository.Insert(attachment, content, ctx)
+	if err != nil {
+		c.logger.Errorf("noteAttachmentUseCase/Upload :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+// Download returns the attachment with its content, which the caller must
+// close. An attachment of another note is reported as sql.ErrNoRows.
+func (c noteAttachmentUseCase) Download(noteId int, id int, ctx context.Context) (domain.NoteAttachment, io.ReadCloser, error) {
+	attachment, err := c.attachment(noteId, id, ctx)
+	if err != nil {
+		return domain.NoteAttachment{}, nil, err
+	}
+	content, err := c.noteAttachmentRepository.Open(attachment.SHA256, ctx)
+	if err != nil {
+		c.logger.Errorf("noteAttachmentUseCase/Download :%v", err)
+		return domain.NoteAttachment{}, nil, err
+	}
+	return attachment, content, nil
+}
+
+func (c noteAttachmentUseCase) DeleteById(noteId int, id int, ctx context.Context) (domain.Response, error) {
+	if _, err := c.attachment(noteId, id, ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Response{Message: fmt.Sprintf("No note attachment found with id %d", id), StatusCode: 400}, nil
+		}
+		return domain.Response{Message: fmt.Sprintf("Failed Delete id %d", id), StatusCode: 500}, err
+	}
+	message, err := c.noteAttachmentRepository.DeleteById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("noteAttachmentUseCase/DeleteById :%v", err)
+		return message, err
+	}
+	// The file may have been the last reference to its blob; the periodic
+	// cleanup retries when this fails.
+	if _, err := c.Cleanup(ctx); err != nil {
+		c.logger.Errorf("noteAttachmentUseCase/DeleteById/Cleanup :%v", err)
+	}
+	return message, nil
+}
+
+func (c noteAttachmentUseCase) Cleanup(ctx context.Context) (int, error) {
+	deleted, err := c.noteAttachmentRepository.DeleteOrphanedBlobs(ctx)
+	if err != nil {
+		c.logger.Errorf("noteAttachmentUseCase/Cleanup :%v", err)
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+func (c noteAttachmentUseCase) attachment(noteId int, id int, ctx context.Context) (domain.NoteAttachment, error) {
+	found, err := c.note(noteId, ctx)
+	if err != nil {
+		c.logger.Errorf("noteAttachmentUseCase/GetNote :%v", err)
+		return domain.NoteAttachment{}, err
+	}
+	if !found {
+		return domain.NoteAttachment{}, sql.ErrNoRows
+	}
+	attachment, err := c.noteAttachmentRepository.GetById(id, ctx)
+	if err == nil && attachment.NoteID != noteId {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		c.logger.Errorf("noteAttachmentUseCase/GetAttachment :%v", err)
+		return domain.NoteAttachment{}, err
+	}
+	return attachment, nil
+}
+
+// detectContentType sniffs the content and reports whether it may be
+// attached.
+func detectContentType(content []byte) (string, bool) {
+	contentType := http.DetectContentType(content)
+	return contentType, allowedContentTypes[contentType]
+}
+
+// sanitizeFileName keeps the base name of a client supplied file name
+// without control characters, quotes or path separators.
+func sanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' || r == '/' || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	for len(name) > maxFileNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == ".." {
+		return "attachment"
+	}
+	return name
+}
+
+// NewNoteAttachmentUseCase limits uploads to maxSize bytes, or DefaultMaxSize
+// when it is not positive.
+func NewNoteAttachmentUseCase(noteAttachmentRepository domain.NoteAttachmentRepository, customerNoteUseCase domain.CustomerNoteUseCase, maxSize int64, log *logrus.Logger) domain.NoteAttachmentUseCase {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return &noteAttachmentUseCase{
+		noteAttachmentRepository: noteAttachmentRepository,
+		customerNoteUseCase:      customerNoteUseCase,
+		maxSize:                  maxSize,
+		logger:                   log,
+	}
+}
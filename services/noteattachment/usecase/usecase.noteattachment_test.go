@@ -0,0 +1,134 @@
+package usecase_noteattachment
+
+import (
+	"context"
+	"crypto/sha256"
+	"customer-playground/domain"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type stubNoteUseCase struct {
+	domain.CustomerNoteUseCase
+}
+
+func (stubNoteUseCase) GetById(id int, ctx context.Context) (domain.CustomerNote, error) {
+	if id != 1 {
+		return domain.CustomerNote{}, sql.ErrNoRows
+	}
+	return domain.CustomerNote{ID: 1}, nil
+}
+
+type stubAttachmentRepository struct {
+	domain.NoteAttachmentRepository
+	inserted []domain.NoteAttachment
+	stored   map[int]domain.NoteAttachment
+}
+
+func (s *stubAttachmentRepository) Insert(attachment *domain.NoteAttachment, content []byte, ctx context.Context) (domain.Response, error) {
+	s.inserted = append(s.inserted, *attachment)
+	return domain.Response{Message: "Succes", StatusCode: 200}, nil
+}
+
+func (s *stubAttachmentRepository) GetById(id int, ctx context.Context) (domain.NoteAttachment, error) {
+	attachment, ok := s.stored[id]
+	if !ok {
+		return domain.NoteAttachment{}, sql.ErrNoRows
+	}
+	return attachment, nil
+}
+
+func newUseCase(repository *stubAttachmentRepository, maxSize int64) domain.NoteAttachmentUseCase {
+	logger := logrus.New()
+	logger.Out = io.Discard
+	return NewNoteAttachmentUseCase(repository, stubNoteUseCase{}, maxSize, logger)
+}
+
+func TestUpload(t *testing.T) {
+	repository := &stubAttachmentRepository{}
+	useCase := newUseCase(repository, 64)
+	ctx := domain.WithUser(context.Background(), "jane")
+
+	pdf := []byte("%PDF-1.4\n%test document")
+	message, err := useCase.Upload(&domain.NoteAttachment{NoteID: 1, FileName: `C:\Users\jane\"invoice".pdf`, ContentType: "text/html"}, pdf, ctx)
+	if err != nil || message.StatusCode != 200 {
+		t.Fatalf("Upload() = %v, %v", message, err)
+	}
+	got := repository.inserted[0]
+	if got.ContentType != "application/pdf" || got.Size != int64(len(pdf)) || got.UploadedBy != "jane" {
+		t.Errorf("Upload() stored %+v", got)
+	}
+	if got.FileName != "invoice.pdf" {
+		t.Errorf("Upload() file name = %q, want invoice.pdf", got.FileName)
+	}
+	if sum := sha256.Sum256(pdf); got.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Upload() sha256 = %q", got.SHA256)
+	}
+
+	rejected := []struct {
+		noteId  int
+		content []byte
+		msg     string
+	}{
+		{2, pdf, "No customer note"},
+		{1, nil, "empty"},
+		{1, []byte(strings.Repeat("a", 65)), "larger than 64"},
+		{1, []byte("<html><script>alert(1)</script></html>"), "cannot be attached"},
+		{1, []byte("MZ\x90\x00\x03\x00\x00\x00"), "cannot be attached"},
+	}
+	for _, test := range rejected {
+		message, err := useCase.Upload(&domain.NoteAttachment{NoteID: test.noteId, FileName: "x"}, test.content, ctx)
+		if err != nil || message.StatusCode != 400 || !strings.Contains(message.Message, test.msg) {
+			t.Errorf("Upload(%.10q) = %v, %v, want 400 %q", test.content, message, err, test.msg)
+		}
+	}
+	if len(repository.inserted) != 1 {
+		t.Errorf("rejected uploads reached the repository: %d inserts", len(repository.inserted))
+	}
+}
+
+func TestUploadTenantMaxSize(t *testing.T) {
+	repository := &stubAttachmentRepository{}
+	useCase := newUseCase(repository, 64)
+	tenant := domain.Tenant{ID: 2, Slug: "acme", Overrides: map[string]interface{}{"attachment.max_size": int64(16)}}
+	ctx := domain.WithTenant(domain.WithUser(context.Background(), "jane"), tenant)
+
+	message, err := useCase.Upload(&domain.NoteAttachment{NoteID: 1, FileName: "x"}, []byte("%PDF-1.4\n%test document"), ctx)
+	if err != nil || message.StatusCode != 400 || !strings.Contains(message.Message, "larger than 16") {
+		t.Errorf("Upload() = %v, %v, want the tenant's limit", message, err)
+	}
+}
+
+func TestDownloadChecksNote(t *testing.T) {
+	repository := &stubAttachmentRepository{stored: map[int]domain.NoteAttachment{5: {ID: 5, NoteID: 3}}}
+	useCase := newUseCase(repository, 0)
+
+	if _, _, err := useCase.Download(1, 5, context.Background()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Download() of another note's attachment error = %v, want sql.ErrNoRows", err)
+	}
+	if _, _, err := useCase.Download(2, 5, context.Background()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Download() from a hidden note error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := map[string]string{
+		"report.pdf":             "report.pdf",
+		"../../etc/passwd":       "passwd",
+		"a\r\nb.txt":             "ab.txt",
+		"":                       "attachment",
+		"..":                     "attachment",
+		strings.Repeat("é", 200): strings.Repeat("é", 127),
+	}
+	for in, want := range tests {
+		if got := sanitizeFileName(in); got != want {
+			t.Errorf("sanitizeFileName(%.20q) = %.20q, want %.20q", in, got, want)
+		}
+	}
+}
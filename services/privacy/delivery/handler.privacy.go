@@ -0,0 +1,292 @@
+package delivery_privacy
+
+import (
+	"bytes"
+	"customer-playground/domain"
+	"customer-playground/pii"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type PrivacyHandler struct {
+	privacyUseCase domain.PrivacyUseCase
+	logger         *logrus.Logger
+}
+
+func NewPrivacyHandler(r *gin.Engine, c domain.PrivacyUseCase, l *logrus.Logger) *gin.Engine {
+	handler := &PrivacyHandler{privacyUseCase: c, logger: l}
+
+	r.GET("/customer/:customer_number/data-export", handler.HandlerExportCustomerData)
+	r.POST("/customer/:customer_number/erasure-requests", handler.HandlerRequestErasure)
+	r.GET("/customer-erasure", handler.HandlerGetErasureRequests)
+	r.GET("/customer-erasure/signing-key", handler.HandlerGetSigningKey)
+	r.GET("/customer-erasure/:id", handler.HandlerGetErasureRequestById)
+	r.GET("/customer-erasure/:id/certificate", handler.HandlerGetErasureCertificate)
+	r.POST("/customer-erasure/:id/approve", handler.HandlerApproveErasure)
+	r.POST("/customer-erasure/:id/reject", handler.HandlerRejectErasure)
+
+	return r
+}
+
+// HandlerExportCustomerData godoc
+// @Summary Export customer data
+// @Description Assembles everything held about a customer for a data subject access request: profile, addresses, contact points, tags, notes with their revisions and attachments, merges and erasure requests. Private notes are included. format=zip adds the attached files. Email, phone and birth date are masked unless the caller holds pii:read.
+// @Tags privacy
+// @Produce json
+// @Produce application/zip
+// @Param customer_number path int true "Customer Number"
+// @Param format query string false "json (default) or zip"
+// @Param X-User header string false "Agent making the export"
+// @Success 200 {object} domain.DataSubjectExport
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/data-export [get]
+func (c *PrivacyHandler) HandlerExportCustomerData(ctx *gin.Context) {
+	customerNumber, err := strconv.Atoi(ctx.Param("customer_number"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerExportCustomerData/ParseCustomerNumber", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	format := ctx.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		ctx.JSON(http.StatusBadRequest, domain.Response{Message: "format must be json or zip", StatusCode: 400})
+		return
+	}
+
+	export, err := c.privacyUseCase.Export(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerExportCustomerData/Export", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	export = pii.Export(ctx, export)
+	fileName := fmt.Sprintf("customer-%d-%s.%s", customerNumber, export.ExportedAt.Format("20060102T150405Z"), format)
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	if format == "json" {
+		ctx.IndentedJSON(http.StatusOK, export)
+		return
+	}
+
+	// The bundle is built before anything is sent so a failure can still be
+	// reported.
+	var bundle bytes.Buffer
+	if err := c.privacyUseCase.WriteBundle(export, &bundle, ctx); err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerExportCustomerData/WriteBundle", err)
+		ctx.Header("Content-Disposition", "")
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Data(http.StatusOK, "application/zip", bundle.Bytes())
+	return
+}
+
+// HandlerRequestErasure godoc
+// @Summary Request erasure
+// @Description Asks to erase the personal data of a customer. The requester is the subject of the bearer token; someone other than the requester has to approve it.
+// @Tags privacy
+// @Accept json
+// @Produce json
+// @Param customer_number path int true "Customer Number"
+// @Param request body domain.ErasureRequest true "Only reason is used"
+// @Param Authorization header string true "Bearer token of the agent requesting the erasure"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer/{customer_number}/erasure-requests [post]
+func (c *PrivacyHandler) HandlerRequestErasure(ctx *gin.Context) {
+	customerNumber, err := strconv.Atoi(ctx.Param("customer_number"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerRequestErasure/ParseCustomerNumber", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	var request domain.ErasureRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerRequestErasure/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	request.CustomerNumber = customerNumber
+
+	message, err := c.privacyUseCase.RequestErasure(&request, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerRequestErasure/RequestErasure", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(message.StatusCode, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerGetErasureRequests godoc
+// @Summary Get erasure requests
+// @Description Retrieves erasure requests, newest first
+// @Tags privacy
+// @Produce json
+// @Param status query string false "pending, rejected or completed; all when empty"
+// @Success 200 {array} domain.ErasureRequest
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-erasure [get]
+func (c *PrivacyHandler) HandlerGetErasureRequests(ctx *gin.Context) {
+	requests, err := c.privacyUseCase.GetErasureRequests(ctx.Query("status"), ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerGetErasureRequests", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, requests)
+	return
+}
+
+// HandlerGetErasureRequestById godoc
+// @Summary Get erasure request by id
+// @Description Retrieves one erasure request with its certificate once completed
+// @Tags privacy
+// @Produce json
+// @Param id path int true "Erasure Request ID"
+// @Success 200 {object} domain.ErasureRequest
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-erasure/{id} [get]
+func (c *PrivacyHandler) HandlerGetErasureRequestById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerGetErasureRequestById/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	request, err := c.privacyUseCase.GetErasureRequestById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerGetErasureRequestById", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, request)
+	return
+}
+
+// HandlerGetErasureCertificate godoc
+// @Summary Get erasure certificate
+// @Description Retrieves the signed certificate of a completed erasure. The signature covers the payload bytes as returned; check it with the key from /customer-erasure/signing-key.
+// @Tags privacy
+// @Produce json
+// @Param id path int true "Erasure Request ID"
+// @Success 200 {object} domain.ErasureCertificate
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-erasure/{id}/certificate [get]
+func (c *PrivacyHandler) HandlerGetErasureCertificate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerGetErasureCertificate/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	request, err := c.privacyUseCase.GetErasureRequestById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerGetErasureCertificate", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if request.Certificate == nil {
+		ctx.JSON(http.StatusBadRequest, domain.Response{Message: fmt.Sprintf("erasure request %d is %s and has no certificate", id, request.Status), StatusCode: 400})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, request.Certificate)
+	return
+}
+
+// HandlerGetSigningKey godoc
+// @Summary Get certificate signing key
+// @Description Retrieves the public key erasure certificates are signed with
+// @Tags privacy
+// @Produce json
+// @Success 200 {object} domain.SigningKey
+// @Router /customer-erasure/signing-key [get]
+func (c *PrivacyHandler) HandlerGetSigningKey(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.privacyUseCase.SigningKey())
+	return
+}
+
+// HandlerApproveErasure godoc
+// @Summary Approve erasure
+// @Description Carries out a pending erasure request: the customer is anonymised, addresses, contact points and attachments are deleted and notes with their revisions are redacted. The customer number and everything referring to it stay. The approver is the subject of the bearer token, must hold privacy:approve and must not be the requester.
+// @Tags privacy
+// @Produce json
+// @Param id path int true "Erasure Request ID"
+// @Param Authorization header string true "Bearer token of the agent approving the erasure"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-erasure/{id}/approve [post]
+func (c *PrivacyHandler) HandlerApproveErasure(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerApproveErasure/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.privacyUseCase.ApproveErasure(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerApproveErasure", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(message.StatusCode, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerRejectErasure godoc
+// @Summary Reject erasure
+// @Description Rejects a pending erasure request. The reviewer is the subject of the bearer token, must hold privacy:approve and must not be the requester.
+// @Tags privacy
+// @Produce json
+// @Param id path int true "Erasure Request ID"
+// @Param Authorization header string true "Bearer token of the agent rejecting the erasure"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /customer-erasure/{id}/reject [post]
+func (c *PrivacyHandler) HandlerRejectErasure(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerRejectErasure/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.privacyUseCase.RejectErasure(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "PrivacyHandler/HandlerRejectErasure", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(message.StatusCode, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
@@ -0,0 +1,361 @@
+package repository_privacy
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"customer-playground/encryption"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queryInsertErasureRequest = `
+		INSERT INTO customer_erasure_request (customer_number, reason, requested_by, requested_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status
+	`
+	querySelectErasureRequest = `
+		SELECT
+			id,
+			customer_number,
+			reason,
+			status,
+			requested_by,
+			requested_at,
+			reviewed_by,
+			reviewed_at,
+			certificate
+		FROM customer_erasure_request
+		WHERE tenant_id = app_tenant()
+	`
+	queryGetErasureRequests = querySelectErasureRequest + `
+		AND ($1 = 0 OR customer_number = $1)
+		AND ($2 = '' OR status = $2)
+		ORDER BY requested_at DESC, id DESC
+	`
+	queryGetErasureRequestById = querySelectErasureRequest + ` AND id = $1`
+	queryLockErasureRequest    = querySelectErasureRequest + ` AND id = $1 AND status = 'pending' FOR UPDATE`
+	queryRejectErasureRequest  = `
+		UPDATE customer_erasure_request SET
+			status = 'rejected',
+			reviewed_by = $2,
+			reviewed_at = $3
+		WHERE tenant_id = app_tenant() AND id = $1 AND status = 'pending'
+	`
+	queryCompleteErasureRequest = `
+		UPDATE customer_erasure_request SET
+			status = 'completed',
+			reviewed_by = $2,
+			reviewed_at = $3,
+			certificate = $4
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+
+	// The erasure statements below run in one transaction. The customer row
+	// stays, so everything referring to it keeps working; what identifies
+	// the person is removed or replaced by $2. The email, which is required
+	// and unique, becomes a placeholder stored like any other: $4 sealed,
+	// with its blind index $5.
+	queryEraseCustomer = `
+		UPDATE customer SET
+			name = $2,
+			email = $4,
+			email_index = $5,
+			phone = '',
+			birth_date = NULL,
+			custom_fields = '{}',
+			updated_at = $3
+		WHERE tenant_id = app_tenant() AND customer_number = $1
+	`
+	queryDeleteAddresses = `
+		DELETE
+		FROM customer_address
+		WHERE tenant_id = app_tenant() AND customer_number = $1
+	`
+	queryDeleteContactPoints = `
+		DELETE
+		FROM customer_contact_point
+		WHERE tenant_id = app_tenant() AND customer_number = $1
+	`
+	// The files go with the next attachment cleanup.
+	queryDeleteAttachments = `
+		DELETE
+		FROM customer_note_attachment a
+		USING customer_note n
+		WHERE n.id = a.note_id
+			AND n.tenant_id = app_tenant()
+			AND a.tenant_id = app_tenant()
+			AND n.customer_number = $1
+	`
+	queryRedactNotes = `
+		UPDATE customer_note SET
+			note = $2,
+			updated_at = $3
+		WHERE tenant_id = app_tenant() AND customer_number = $1 AND note <> $2
+	`
+	// Mentions follow the note text, which no longer has any.
+	queryDeleteMentions = `
+		DELETE
+		FROM customer_note_mention m
+		USING customer_note n
+		WHERE n.id = m.note_id
+			AND n.tenant_id = app_tenant()
+			AND m.tenant_id = app_tenant()
+			AND n.customer_number = $1
+	`
+	// Revisions cannot be changed otherwise, see migrations/0003_erasure_requests.sql.
+	queryRedactRevisions = `
+		UPDATE customer_note_revision r SET
+			note = $2
+		FROM customer_note n
+		WHERE n.id = r.note_id
+			AND n.tenant_id = app_tenant()
+			AND r.tenant_id = app_tenant()
+			AND n.customer_number = $1
+			AND r.note <> $2
+	`
+	// Snapshots of customers merged into this one describe the same person.
+	queryRedactMergeSnapshots = `
+		UPDATE customer_merge SET
+			merged_customer = merged_customer || jsonb_build_object(
+				'name', $2::text,
+				'email', $2::text,
+				'phone', '',
+				'birth_date', NULL,
+				'custom_fields', '{}'::jsonb
+			)
+		WHERE tenant_id = app_tenant() AND (survivor_customer_number = $1 OR merged_customer_number = $1)
+	`
+	queryDeleteDuplicateCandidates = `
+		DELETE
+		FROM customer_duplicate_candidate
+		WHERE tenant_id = app_tenant() AND $1 IN (customer_a, customer_b)
+	`
+)
+
+type privacyRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	cipher *encryption.Cipher
+	logger *logrus.Logger
+}
+
+func scanErasureRequest(row interface{ Scan(...interface{}) error }, request *domain.ErasureRequest) error {
+	var certificate sql.NullString
+	err := row.Scan(
+		&request.ID,
+		&request.CustomerNumber,
+		&request.Reason,
+		&request.Status,
+		&request.RequestedBy,
+		&request.RequestedAt,
+		&request.ReviewedBy,
+		&request.ReviewedAt,
+		&certificate,
+	)
+	if err != nil || !certificate.Valid {
+		return err
+	}
+	request.Certificate = &domain.ErasureCertificate{}
+	return json.Unmarshal([]byte(certificate.String), request.Certificate)
+}
+
+func (c privacyRepository) InsertErasureRequest(request *domain.ErasureRequest, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryInsertErasureRequest, func(stmt *sql.Stmt) error {
+			return stmt.QueryRowContext(ctx,
+				request.CustomerNumber,
+				request.Reason,
+				request.RequestedBy,
+				request.RequestedAt,
+			).Scan(&request.ID, &request.Status)
+		})
+	})
+	if err != nil {
+		message.Message = "Failed to Insert Erasure Request"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	message.Message = fmt.Sprintf("Succes Insert Erasure Request with id %d", request.ID)
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c privacyRepository) GetErasureRequests(customerNumber int, status string, ctx context.Context) ([]domain.ErasureRequest, error) {
+	var requests []domain.ErasureRequest
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetErasureRequests, func(stmt *sql.Stmt) error {
+				requests = nil
+				rows, err := stmt.QueryContext(ctx, customerNumber, status)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var request domain.ErasureRequest
+					if err := scanErasureRequest(rows, &request); err != nil {
+						return err
+					}
+					requests = append(requests, request)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+func (c privacyRepository) GetErasureRequestById(id int, ctx context.Context) (domain.ErasureRequest, error) {
+	var request domain.ErasureRequest
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetErasureRequestById, func(stmt *sql.Stmt) error {
+				return scanErasureRequest(stmt.QueryRowContext(ctx, id), &request)
+			})
+		})
+	})
+	if err != nil {
+		return domain.ErasureRequest{}, err
+	}
+
+	return request, nil
+}
+
+func (c privacyRepository) RejectErasureRequest(id int, reviewer string, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	var result sql.Result
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryRejectErasureRequest, func(stmt *sql.Stmt) error {
+			var err error
+			result, err = stmt.ExecContext(ctx, id, reviewer, time.Now())
+			return err
+		})
+	})
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Update id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		message.Message = fmt.Sprintf("Could not determine rows affected for id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to get rows affected: %v", err)
+		return message, err
+	}
+	if rowsAffected == 0 {
+		message.Message = fmt.Sprintf("No pending erasure request found with id %d", id)
+		message.StatusCode = 500
+		return message, sql.ErrNoRows
+	}
+	message.Message = "Succes Update"
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c privacyRepository) Erase(id int, reviewer string, sign func(request domain.ErasureRequest, summary domain.ErasureSummary, erasedAt time.Time) (domain.ErasureCertificate, error), ctx context.Context) (domain.ErasureRequest, error) {
+	var request domain.ErasureRequest
+	err := c.transaction(ctx, func(tx *database.Tx) error {
+		request = domain.ErasureRequest{}
+		if err := scanErasureRequest(tx.QueryRow(queryLockErasureRequest, id), &request); err != nil {
+			return err
+		}
+		now := time.Now()
+		customerNumber := request.CustomerNumber
+		placeholder, err := c.cipher.SealCustomer(ctx, domain.Customer{Email: fmt.Sprintf("erased-%d@erased.invalid", customerNumber)})
+		if err != nil {
+			return err
+		}
+
+		var summary domain.ErasureSummary
+		steps := []struct {
+			query string
+			args  []interface{}
+			count *int
+		}{
+			{queryEraseCustomer, []interface{}{customerNumber, domain.ErasedText, now, placeholder.Email, placeholder.EmailIndex}, nil},
+			{queryDeleteAddresses, []interface{}{customerNumber}, &summary.AddressesDeleted},
+			{queryDeleteContactPoints, []interface{}{customerNumber}, &summary.ContactPointsDeleted},
+			{queryDeleteAttachments, []interface{}{customerNumber}, &summary.AttachmentsDeleted},
+			{queryRedactNotes, []interface{}{customerNumber, domain.ErasedText, now}, &summary.NotesRedacted},
+			{queryDeleteMentions, []interface{}{customerNumber}, nil},
+			{queryRedactRevisions, []interface{}{customerNumber, domain.ErasedText}, &summary.RevisionsRedacted},
+			{queryRedactMergeSnapshots, []interface{}{customerNumber, domain.ErasedText}, &summary.MergeSnapshotsRedacted},
+			{queryDeleteDuplicateCandidates, []interface{}{customerNumber}, &summary.DuplicateCandidatesDeleted},
+		}
+		for _, step := range steps {
+			result, err := tx.Exec(step.query, step.args...)
+			if err != nil {
+				return err
+			}
+			if step.count == nil {
+				continue
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			*step.count = int(affected)
+		}
+
+		certificate, err := sign(request, summary, now)
+		if err != nil {
+			return err
+		}
+		stored, err := json.Marshal(certificate)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(queryCompleteErasureRequest, id, reviewer, now, string(stored)); err != nil {
+			return err
+		}
+		request.Status = domain.ErasureStatusCompleted
+		request.ReviewedBy = reviewer
+		request.ReviewedAt.Time, request.ReviewedAt.Valid = now, true
+		request.Certificate = &certificate
+		return nil
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return domain.ErasureRequest{}, err
+	}
+	return request, nil
+}
+
+func (c privacyRepository) transaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	return c.retry.DoTransaction(ctx, func() error {
+		return c.stmts.Transaction(ctx, c.db.Writer(ctx), fn)
+	})
+}
+
+// Close releases the prepared statements held by the repository.
+func (c privacyRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewPrivacyRepository(db *database.DB, retry database.RetryPolicy, cipher *encryption.Cipher, log *logrus.Logger) domain.PrivacyRepository {
+	return &privacyRepository{
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
+		cipher: cipher,
+		logger: log,
+	}
+}
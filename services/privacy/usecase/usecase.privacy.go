@@ -0,0 +1,292 @@
+package usecase_privacy
+
+import (
+	"archive/zip"
+	"context"
+	"customer-playground/domain"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sources are the repositories an export reads. Notes come from the
+// repository rather than the use case so private notes are included.
+type Sources struct {
+	Customers     domain.CustomerRepository
+	Addresses     domain.CustomerAddressRepository
+	ContactPoints domain.CustomerContactPointRepository
+	Tags          domain.TagRepository
+	Notes         domain.CustomerNoteRepository
+	Attachments   domain.NoteAttachmentRepository
+	Merges        domain.CustomerMergeRepository
+}
+
+type privacyUseCase struct {
+	privacyRepository domain.PrivacyRepository
+	sources           Sources
+	signer            domain.CertificateSigner
+	invalidator       domain.CustomerInvalidator
+	logger            *logrus.Logger
+}
+
+func (c privacyUseCase) Export(customerNumber int, ctx context.Context) (domain.DataSubjectExport, error) {
+	export := domain.DataSubjectExport{
+		ExportedAt: time.Now().UTC(),
+		ExportedBy: domain.UserFromContext(ctx),
+	}
+
+	var err error
+	if export.Customer, err = c.sources.Customers.GetByCustomerNumber(customerNumber, ctx); err != nil {
+		c.logger.Errorf("privacyUseCase/Export/GetCustomer :%v", err)
+		return domain.DataSubjectExport{}, err
+	}
+	if export.Addresses, err = c.sources.Addresses.GetByCustomerNumber(customerNumber, ctx); err != nil {
+		c.logger.Errorf("privacyUseCase/Export/GetAddresses :%v", err)
+		return domain.DataSubjectExport{}, err
+	}
+	if export.ContactPoints, err = c.sources.ContactPoints.GetByCustomerNumber(customerNumber, ctx); err != nil {
+		c.logger.Errorf("privacyUseCase/Export/GetContactPoints :%v", err)
+		return domain.DataSubjectExport{}, err
+	}
+	if export.Tags, err = c.sources.Tags.GetByCustomerNumber(customerNumber, ctx); err != nil {
+		c.logger.Errorf("privacyUseCase/Export/GetTags :%v", err)
+		return domain.DataSubjectExport{}, err
+	}
+	if export.Merges, err = c.sources.Merges.GetHistory(customerNumber, ctx); err != nil {
+		c.logger.Errorf("privacyUseCase/Export/GetMerges :%v", err)
+		return domain.DataSubjectExport{}, err
+	}
+	if export.ErasureRequests, err = c.privacyRepository.GetErasureRequests(customerNumber, "", ctx); err != nil {
+		c.logger.Errorf("privacyUseCase/Export/GetErasureRequests :%v", err)
+		return domain.DataSubjectExport{}, err
+	}
+
+	notes, err := c.sources.Notes.GetByCustomerNumber(customerNumber, ctx)
+	if err != nil {
+		c.logger.Errorf("privacyUseCase/Export/GetNotes :%v", err)
+		return domain.DataSubjectExport{}, err
+	}
+	export.Notes = make([]domain.DataSubjectNote, 0, len(notes))
+	for _, note := range notes {
+		subjectNote := domain.DataSubjectNote{CustomerNote: note}
+		if subjectNote.Revisions, err = c.sources.Notes.GetRevisions(note.ID, ctx); err != nil {
+			c.logger.Errorf("privacyUseCase/Export/GetRevisions :%v", err)
+			return domain.DataSubjectExport{}, err
+		}
+		if subjectNote.Attachments, err = c.sources.Attachments.GetByNoteId(note.ID, ctx); err != nil {
+			c.logger.Errorf("privacyUseCase/Export/GetAttachments :%v", err)
+			return domain.DataSubjectExport{}, err
+		}
+		export.Notes = append(export.Notes, subjectNote)
+	}
+	return export, nil
+}
+
+// WriteBundle writes export.json and every attachment under
+// attachments/<note id>/.
+func (c privacyUseCase) WriteBundle(export domain.DataSubjectExport, w io.Writer, ctx context.Context) error {
+	archive := zip.NewWriter(w)
+
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: "export.json", Method: zip.Deflate, Modified: export.ExportedAt})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return err
+	}
+
+	for _, note := range export.Notes {
+		for _, attachment := range note.Attachments {
+			name := fmt.Sprintf("attachments/%d/%d-%s", note.ID, attachment.ID, path.Base(attachment.FileName))
+			if err := c.writeAttachment(archive, name, attachment, ctx); err != nil {
+				c.logger.Errorf("privacyUseCase/WriteBundle/%s :%v", name, err)
+				return err
+			}
+		}
+	}
+	return archive.Close()
+}
+
+func (c privacyUseCase) writeAttachment(archive *zip.Writer, name string, attachment domain.NoteAttachment, ctx context.Context) error {
+	content, err := c.sources.Attachments.Open(attachment.SHA256, ctx)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: attachment.CreatedAt.Time})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, content)
+	return err
+}
+
+func (c privacyUseCase) RequestErasure(request *domain.ErasureRequest, ctx context.Context) (domain.Response, error) {
+	// The requester and reviewer are the subjects of verified tokens, so
+	// neither can be made up with a header.
+	request.RequestedBy = domain.SubjectFromContext(ctx)
+	if request.RequestedBy == "" {
+		return domain.Response{Message: "erasure requests need a bearer token naming the requesting user", StatusCode: 401}, nil
+	}
+
+	_, err := c.sources.Customers.GetByCustomerNumber(request.CustomerNumber, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Response{Message: fmt.Sprintf("No customer found with customer_number %d", request.CustomerNumber), StatusCode: 400}, nil
+	}
+	if err != nil {
+		c.logger.Errorf("privacyUseCase/RequestErasure/GetCustomer :%v", err)
+		return domain.Response{Message: "Failed to Insert Erasure Request", StatusCode: 500}, err
+	}
+
+	existing, err := c.privacyRepository.GetErasureRequests(request.CustomerNumber, "", ctx)
+	if err != nil {
+		c.logger.Errorf("privacyUseCase/RequestErasure/GetErasureRequests :%v", err)
+		return domain.Response{Message: "Failed to Insert Erasure Request", StatusCode: 500}, err
+	}
+	for _, other := range existing {
+		if other.Status != domain.ErasureStatusRejected {
+			return domain.Response{Message: fmt.Sprintf("customer %d already has a %s erasure request, id %d", request.CustomerNumber, other.Status, other.ID), StatusCode: 400}, nil
+		}
+	}
+
+	request.ID = 0
+	request.Status = domain.ErasureStatusPending
+	request.RequestedAt.Time, request.RequestedAt.Valid = time.Now(), true
+	request.ReviewedBy = ""
+	request.ReviewedAt.Valid = false
+	request.Certificate = nil
+	message, err := c.privacyRepository.InsertErasureRequest(request, ctx)
+	if err != nil {
+		c.logger.Errorf("privacyUseCase/RequestErasure :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+func (c privacyUseCase) GetErasureRequests(status string, ctx context.Context) ([]domain.ErasureRequest, error) {
+	requests, err := c.privacyRepository.GetErasureRequests(0, status, ctx)
+	if err != nil {
+		c.logger.Errorf("privacyUseCase/GetErasureRequests :%v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (c privacyUseCase) GetErasureRequestById(id int, ctx context.Context) (domain.ErasureRequest, error) {
+	request, err := c.privacyRepository.GetErasureRequestById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("privacyUseCase/GetErasureRequestById :%v", err)
+		return domain.ErasureRequest{}, err
+	}
+	return request, nil
+}
+
+// review loads a pending request for the reviewer in ctx, who must hold
+// privacy:approve and not be the requester. A non-nil Response means the
+// review cannot go ahead.
+func (c privacyUseCase) review(id int, ctx context.Context) (string, *domain.Response, error) {
+	reviewer := domain.SubjectFromContext(ctx)
+	if reviewer == "" {
+		return "", &domain.Response{Message: "reviews need a bearer token naming the reviewing user", StatusCode: 401}, nil
+	}
+	if !domain.HasPermission(ctx, domain.PermissionApproveErasure) {
+		return "", &domain.Response{Message: "reviewing erasure requests needs the " + domain.PermissionApproveErasure + " permission", StatusCode: 403}, nil
+	}
+
+	request, err := c.privacyRepository.GetErasureRequestById(id, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", &domain.Response{Message: fmt.Sprintf("No erasure request found with id %d", id), StatusCode: 400}, nil
+	}
+	if err != nil {
+		return "", &domain.Response{Message: fmt.Sprintf("Failed Update id %d", id), StatusCode: 500}, err
+	}
+	if request.Status != domain.ErasureStatusPending {
+		return "", &domain.Response{Message: fmt.Sprintf("erasure request %d is %s", id, request.Status), StatusCode: 400}, nil
+	}
+	if request.RequestedBy == reviewer {
+		return "", &domain.Response{Message: "an erasure request must be reviewed by someone other than " + reviewer, StatusCode: 400}, nil
+	}
+	return reviewer, nil, nil
+}
+
+func (c privacyUseCase) ApproveErasure(id int, ctx context.Context) (domain.Response, error) {
+	reviewer, message, err := c.review(id, ctx)
+	if message != nil {
+		if err != nil {
+			c.logger.Errorf("privacyUseCase/ApproveErasure/GetErasureRequest :%v", err)
+		}
+		return *message, err
+	}
+
+	tenant := domain.TenantFromContext(ctx).Slug
+	request, err := c.privacyRepository.Erase(id, reviewer, func(request domain.ErasureRequest, summary domain.ErasureSummary, erasedAt time.Time) (domain.ErasureCertificate, error) {
+		return c.signer.Sign(domain.ErasureCertificatePayload{
+			RequestID:      request.ID,
+			Tenant:         tenant,
+			CustomerNumber: request.CustomerNumber,
+			Reason:         request.Reason,
+			RequestedBy:    request.RequestedBy,
+			RequestedAt:    request.RequestedAt.Time.UTC(),
+			ApprovedBy:     reviewer,
+			ErasedAt:       erasedAt.UTC(),
+			Erased:         summary,
+		})
+	}, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Someone else reviewed it in the meantime.
+		return domain.Response{Message: fmt.Sprintf("erasure request %d is no longer pending", id), StatusCode: 400}, nil
+	}
+	if err != nil {
+		c.logger.Errorf("privacyUseCase/ApproveErasure :%v", err)
+		return domain.Response{Message: fmt.Sprintf("Failed Update id %d", id), StatusCode: 500}, err
+	}
+	if c.invalidator != nil {
+		c.invalidator.Invalidate(request.CustomerNumber, ctx)
+	}
+	return domain.Response{Message: fmt.Sprintf("Succes Erase customer %d", request.CustomerNumber), StatusCode: 200}, nil
+}
+
+func (c privacyUseCase) RejectErasure(id int, ctx context.Context) (domain.Response, error) {
+	reviewer, message, err := c.review(id, ctx)
+	if message != nil {
+		if err != nil {
+			c.logger.Errorf("privacyUseCase/RejectErasure/GetErasureRequest :%v", err)
+		}
+		return *message, err
+	}
+
+	response, err := c.privacyRepository.RejectErasureRequest(id, reviewer, ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Response{Message: fmt.Sprintf("erasure request %d is no longer pending", id), StatusCode: 400}, nil
+	}
+	if err != nil {
+		c.logger.Errorf("privacyUseCase/RejectErasure :%v", err)
+		return response, err
+	}
+	return response, nil
+}
+
+func (c privacyUseCase) SigningKey() domain.SigningKey {
+	return c.signer.SigningKey()
+}
+
+// NewPrivacyUseCase signs erasure certificates with signer. The invalidator
+// may be nil when customers are not cached.
+func NewPrivacyUseCase(privacyRepository domain.PrivacyRepository, sources Sources, signer domain.CertificateSigner, invalidator domain.CustomerInvalidator, log *logrus.Logger) domain.PrivacyUseCase {
+	return &privacyUseCase{
+		privacyRepository: privacyRepository,
+		sources:           sources,
+		signer:            signer,
+		invalidator:       invalidator,
+		logger:            log,
+	}
+}
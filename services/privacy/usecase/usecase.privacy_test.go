@@ -0,0 +1,272 @@
+package usecase_privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"customer-playground/certificate"
+	"customer-playground/domain"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type stubPrivacyRepository struct {
+	domain.PrivacyRepository
+	requests map[int]domain.ErasureRequest
+	inserted *domain.ErasureRequest
+	erased   int
+}
+
+func (s *stubPrivacyRepository) InsertErasureRequest(request *domain.ErasureRequest, ctx context.Context) (domain.Response, error) {
+	inserted := *request
+	s.inserted = &inserted
+	return domain.Response{Message: "Succes Insert", StatusCode: 200}, nil
+}
+
+func (s *stubPrivacyRepository) GetErasureRequests(customerNumber int, status string, ctx context.Context) ([]domain.ErasureRequest, error) {
+	var requests []domain.ErasureRequest
+	for _, request := range s.requests {
+		if request.CustomerNumber == customerNumber {
+			requests = append(requests, request)
+		}
+	}
+	return requests, nil
+}
+
+func (s *stubPrivacyRepository) GetErasureRequestById(id int, ctx context.Context) (domain.ErasureRequest, error) {
+	request, ok := s.requests[id]
+	if !ok {
+		return domain.ErasureRequest{}, sql.ErrNoRows
+	}
+	return request, nil
+}
+
+func (s *stubPrivacyRepository) Erase(id int, reviewer string, sign func(request domain.ErasureRequest, summary domain.ErasureSummary, erasedAt time.Time) (domain.ErasureCertificate, error), ctx context.Context) (domain.ErasureRequest, error) {
+	request := s.requests[id]
+	certificate, err := sign(request, domain.ErasureSummary{NotesRedacted: 2}, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC))
+	if err != nil {
+		return domain.ErasureRequest{}, err
+	}
+	request.Status = domain.ErasureStatusCompleted
+	request.ReviewedBy = reviewer
+	request.Certificate = &certificate
+	s.requests[id] = request
+	s.erased = request.CustomerNumber
+	return request, nil
+}
+
+type stubCustomerRepository struct {
+	domain.CustomerRepository
+}
+
+func (stubCustomerRepository) GetByCustomerNumber(customerNumber int, ctx context.Context) (domain.Customer, error) {
+	if customerNumber != 1 {
+		return domain.Customer{}, sql.ErrNoRows
+	}
+	return domain.Customer{CustomerNumber: 1, Name: "John Doe"}, nil
+}
+
+type stubAttachmentRepository struct {
+	domain.NoteAttachmentRepository
+	content map[string]string
+}
+
+func (s stubAttachmentRepository) Open(sha256 string, ctx context.Context) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(s.content[sha256])), nil
+}
+
+type invalidations []int
+
+func (i *invalidations) Invalidate(customerNumber int, ctx context.Context) {
+	*i = append(*i, customerNumber)
+}
+
+func newUseCase(t *testing.T, repository *stubPrivacyRepository, invalidator domain.CustomerInvalidator) (domain.PrivacyUseCase, *certificate.Signer) {
+	t.Helper()
+	signer, err := certificate.GenerateSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.Out = io.Discard
+	sources := Sources{
+		Customers:   stubCustomerRepository{},
+		Attachments: stubAttachmentRepository{content: map[string]string{"abc": "%PDF-1.4"}},
+	}
+	return NewPrivacyUseCase(repository, sources, signer, invalidator, logger), signer
+}
+
+// reviewer returns the context of a request with a token for user holding
+// privacy:approve.
+func reviewer(user string) context.Context {
+	ctx := domain.WithSubject(domain.WithUser(context.Background(), user), user)
+	return domain.WithPermissions(ctx, []string{domain.PermissionApproveErasure})
+}
+
+func TestApproveErasureSignsCertificate(t *testing.T) {
+	requestedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	repository := &stubPrivacyRepository{requests: map[int]domain.ErasureRequest{
+		3: {ID: 3, CustomerNumber: 1, Reason: "asked by mail", Status: domain.ErasureStatusPending, RequestedBy: "jane"},
+	}}
+	request := repository.requests[3]
+	request.RequestedAt.Time, request.RequestedAt.Valid = requestedAt, true
+	repository.requests[3] = request
+	var invalidated invalidations
+	useCase, signer := newUseCase(t, repository, &invalidated)
+
+	ctx := reviewer("bob")
+	ctx = domain.WithTenant(ctx, domain.Tenant{ID: 1, Slug: "default"})
+	message, err := useCase.ApproveErasure(3, ctx)
+	if err != nil || message.StatusCode != 200 {
+		t.Fatalf("ApproveErasure() = %v, %v", message, err)
+	}
+	if repository.erased != 1 || len(invalidated) != 1 || invalidated[0] != 1 {
+		t.Fatalf("erased %d, invalidated %v, want customer 1", repository.erased, invalidated)
+	}
+
+	cert := repository.requests[3].Certificate
+	publicKey, err := base64.StdEncoding.DecodeString(signer.SigningKey().PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := certificate.Verify(*cert, ed25519.PublicKey(publicKey))
+	if err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+	want := domain.ErasureCertificatePayload{
+		RequestID:      3,
+		Tenant:         "default",
+		CustomerNumber: 1,
+		Reason:         "asked by mail",
+		RequestedBy:    "jane",
+		RequestedAt:    requestedAt,
+		ApprovedBy:     "bob",
+		ErasedAt:       time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		Erased:         domain.ErasureSummary{NotesRedacted: 2},
+	}
+	if payload != want {
+		t.Errorf("payload = %+v, want %+v", payload, want)
+	}
+}
+
+func TestErasureNeedsSecondReviewer(t *testing.T) {
+	repository := &stubPrivacyRepository{requests: map[int]domain.ErasureRequest{
+		3: {ID: 3, CustomerNumber: 1, Status: domain.ErasureStatusPending, RequestedBy: "jane"},
+		4: {ID: 4, CustomerNumber: 1, Status: domain.ErasureStatusRejected, RequestedBy: "jane"},
+	}}
+	useCase, _ := newUseCase(t, repository, nil)
+
+	tests := []struct {
+		name       string
+		id         int
+		ctx        context.Context
+		wantStatus int
+	}{
+		{"requester", 3, reviewer("jane"), 400},
+		{"no token", 3, context.Background(), 401},
+		{"user header only", 3, domain.WithPermissions(domain.WithUser(context.Background(), "bob"), []string{domain.PermissionApproveErasure}), 401},
+		{"no permission", 3, domain.WithSubject(context.Background(), "bob"), 403},
+		{"not pending", 4, reviewer("bob"), 400},
+		{"unknown", 9, reviewer("bob"), 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := useCase.ApproveErasure(tt.id, tt.ctx)
+			if err != nil || message.StatusCode != tt.wantStatus {
+				t.Errorf("ApproveErasure() = %v, %v, want %d", message, err, tt.wantStatus)
+			}
+			message, err = useCase.RejectErasure(tt.id, tt.ctx)
+			if err != nil || message.StatusCode != tt.wantStatus {
+				t.Errorf("RejectErasure() = %v, %v, want %d", message, err, tt.wantStatus)
+			}
+		})
+	}
+	if repository.erased != 0 {
+		t.Errorf("customer %d was erased", repository.erased)
+	}
+}
+
+func TestRequestErasure(t *testing.T) {
+	repository := &stubPrivacyRepository{requests: map[int]domain.ErasureRequest{
+		4: {ID: 4, CustomerNumber: 1, Status: domain.ErasureStatusRejected, RequestedBy: "jane"},
+	}}
+	useCase, _ := newUseCase(t, repository, nil)
+	ctx := domain.WithSubject(context.Background(), "jane")
+
+	message, err := useCase.RequestErasure(&domain.ErasureRequest{CustomerNumber: 1}, domain.WithUser(context.Background(), "jane"))
+	if err != nil || message.StatusCode != 401 {
+		t.Errorf("RequestErasure() without a token = %v, %v, want 401", message, err)
+	}
+
+	message, err = useCase.RequestErasure(&domain.ErasureRequest{CustomerNumber: 2}, ctx)
+	if err != nil || message.StatusCode != 400 {
+		t.Errorf("RequestErasure() of an unknown customer = %v, %v, want 400", message, err)
+	}
+
+	message, err = useCase.RequestErasure(&domain.ErasureRequest{CustomerNumber: 1, Status: domain.ErasureStatusCompleted, ReviewedBy: "jane"}, ctx)
+	if err != nil || message.StatusCode != 200 {
+		t.Fatalf("RequestErasure() = %v, %v", message, err)
+	}
+	got := repository.inserted
+	if got.Status != domain.ErasureStatusPending || got.RequestedBy != "jane" || got.ReviewedBy != "" || !got.RequestedAt.Valid {
+		t.Errorf("RequestErasure() inserted %+v", got)
+	}
+
+	repository.requests[5] = domain.ErasureRequest{ID: 5, CustomerNumber: 1, Status: domain.ErasureStatusPending, RequestedBy: "jane"}
+	message, err = useCase.RequestErasure(&domain.ErasureRequest{CustomerNumber: 1}, ctx)
+	if err != nil || message.StatusCode != 400 {
+		t.Errorf("RequestErasure() with one pending = %v, %v, want 400", message, err)
+	}
+}
+
+func TestWriteBundle(t *testing.T) {
+	useCase, _ := newUseCase(t, &stubPrivacyRepository{}, nil)
+	export := domain.DataSubjectExport{
+		Customer: domain.Customer{CustomerNumber: 1, Name: "John Doe"},
+		Notes: []domain.DataSubjectNote{{
+			CustomerNote: domain.CustomerNote{ID: 7, CustomerNumber: 1},
+			Attachments:  []domain.NoteAttachment{{ID: 2, NoteID: 7, FileName: "../invoice.pdf", SHA256: "abc"}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := useCase.WriteBundle(export, &buf, context.Background()); err != nil {
+		t.Fatalf("WriteBundle() = %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		content, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(content)
+		content.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(data)
+	}
+	if got := files["attachments/7/2-invoice.pdf"]; got != "%PDF-1.4" {
+		t.Errorf("attachment = %q, files %v", got, files)
+	}
+	var decoded domain.DataSubjectExport
+	if err := json.Unmarshal([]byte(files["export.json"]), &decoded); err != nil {
+		t.Fatalf("export.json: %v", err)
+	}
+	if decoded.Customer.Name != "John Doe" || len(decoded.Notes) != 1 {
+		t.Errorf("export.json = %+v", decoded)
+	}
+}
@@ -0,0 +1,320 @@
+package delivery_segment
+
+import (
+	"customer-playground/domain"
+	"customer-playground/pii"
+	"customer-playground/segment"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type SegmentHandler struct {
+	segmentUseCase domain.SegmentUseCase
+	logger         *logrus.Logger
+}
+
+func NewSegmentHandler(r *gin.Engine, c domain.SegmentUseCase, l *logrus.Logger) *gin.Engine {
+	handler := &SegmentHandler{segmentUseCase: c, logger: l}
+
+	r.GET("/segment", handler.HandlerGetAllSegment)
+	r.GET("/segment/:id", handler.HandlerGetByIdSegment)
+	r.POST("/segment", handler.HandlerInsertSegment)
+	r.PUT("/segment/:id", handler.HandlerUpdateSegment)
+	r.DELETE("/segment/:id", handler.HandlerDeleteSegmentById)
+	r.GET("/segment/:id/members", handler.HandlerGetSegmentMembers)
+	r.GET("/segment/:id/count", handler.HandlerGetSegmentCount)
+	r.POST("/segment/evaluate", handler.HandlerEvaluateSegment)
+
+	return r
+}
+
+// HandlerGetAllSegment godoc
+// @Summary Get all segments
+// @Description Retrieves every saved segment
+// @Tags segment
+// @Produce json
+// @Success 200 {array} domain.Segment
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /segment [get]
+func (c *SegmentHandler) HandlerGetAllSegment(ctx *gin.Context) {
+	segments, err := c.segmentUseCase.GetAll(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerGetAllSegment", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, segments)
+	return
+}
+
+// HandlerGetByIdSegment godoc
+// @Summary Get a segment
+// @Description Retrieves a saved segment by its ID
+// @Tags segment
+// @Produce json
+// @Param id path int true "Segment ID"
+// @Success 200 {object} domain.Segment
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /segment/{id} [get]
+func (c *SegmentHandler) HandlerGetByIdSegment(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerGetByIdSegment/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	segment, err := c.segmentUseCase.GetById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerGetByIdSegment", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, segment)
+	return
+}
+
+// HandlerInsertSegment godoc
+// @Summary Create a segment
+// @Description Saves a segment. The definition is checked before it is stored, e.g. "tag = vip AND birth_date IN THIS MONTH".
+// @Tags segment
+// @Accept json
+// @Produce json
+// @Param segment body domain.Segment true "Segment Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /segment [post]
+func (c *SegmentHandler) HandlerInsertSegment(ctx *gin.Context) {
+	var segment domain.Segment
+	err := ctx.Bind(&segment)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerInsertSegment/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.segmentUseCase.Insert(&segment, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerInsertSegment/Insert", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerUpdateSegment godoc
+// @Summary Update a segment
+// @Description Updates a saved segment. Empty fields keep their current value.
+// @Tags segment
+// @Accept json
+// @Produce json
+// @Param id path int true "Segment ID"
+// @Param segment body domain.Segment true "Segment Payload"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /segment/{id} [put]
+func (c *SegmentHandler) HandlerUpdateSegment(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerUpdateSegment/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	var segment domain.Segment
+	err = ctx.Bind(&segment)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerUpdateSegment/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	segment.ID = id
+	message, err := c.segmentUseCase.Update(&segment, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerUpdateSegment/Update", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if message.StatusCode != 200 {
+		ctx.JSON(http.StatusBadRequest, message)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerDeleteSegmentById godoc
+// @Summary Delete a segment
+// @Description Deletes a saved segment by its ID
+// @Tags segment
+// @Produce json
+// @Param id path int true "Segment ID"
+// @Success 200 {object} domain.Response
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /segment/{id} [delete]
+func (c *SegmentHandler) HandlerDeleteSegmentById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerDeleteSegmentById/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	message, err := c.segmentUseCase.DeleteById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerDeleteSegmentById/Delete", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+	return
+}
+
+// HandlerGetSegmentMembers godoc
+// @Summary Get segment members
+// @Description Evaluates a saved segment and returns one page of its customers with the total count
+// @Tags segment
+// @Produce json
+// @Param id path int true "Segment ID"
+// @Param limit query int false "Page size, at most 500" default(50)
+// @Param offset query int false "Customers to skip" default(0)
+// @Success 200 {object} domain.SegmentMembers
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /segment/{id}/members [get]
+func (c *SegmentHandler) HandlerGetSegmentMembers(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerGetSegmentMembers/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	limit, offset, err := page(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerGetSegmentMembers/ParsePage", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	segment, err := c.segmentUseCase.GetById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerGetSegmentMembers/GetById", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	members, err := c.segmentUseCase.Members(segment.Definition, limit, offset, ctx)
+	if err != nil {
+		c.abortEvaluation(ctx, "SegmentHandler/HandlerGetSegmentMembers/Members", err)
+		return
+	}
+
+	members.Customers = pii.Customers(ctx, members.Customers)
+	ctx.JSON(http.StatusOK, members)
+	return
+}
+
+// HandlerGetSegmentCount godoc
+// @Summary Count segment members
+// @Description Evaluates a saved segment and returns the number of its customers
+// @Tags segment
+// @Produce json
+// @Param id path int true "Segment ID"
+// @Success 200 {object} domain.SegmentCount
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /segment/{id}/count [get]
+func (c *SegmentHandler) HandlerGetSegmentCount(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerGetSegmentCount/ParseId", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	segment, err := c.segmentUseCase.GetById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerGetSegmentCount/GetById", err)
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	count, err := c.segmentUseCase.Count(segment.Definition, ctx)
+	if err != nil {
+		c.abortEvaluation(ctx, "SegmentHandler/HandlerGetSegmentCount/Count", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, count)
+	return
+}
+
+// HandlerEvaluateSegment godoc
+// @Summary Evaluate a definition
+// @Description Evaluates an unsaved definition, e.g. to preview a segment before saving it
+// @Tags segment
+// @Accept json
+// @Produce json
+// @Param definition body domain.SegmentDefinition true "Segment Definition"
+// @Param limit query int false "Page size, at most 500" default(50)
+// @Param offset query int false "Customers to skip" default(0)
+// @Success 200 {object} domain.SegmentMembers
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /segment/evaluate [post]
+func (c *SegmentHandler) HandlerEvaluateSegment(ctx *gin.Context) {
+	limit, offset, err := page(ctx)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerEvaluateSegment/ParsePage", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	var definition domain.SegmentDefinition
+	err = ctx.Bind(&definition)
+	if err != nil {
+		c.logger.Errorf("%s : %v", "SegmentHandler/HandlerEvaluateSegment/ParseBodyData", err)
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	members, err := c.segmentUseCase.Members(definition.Definition, limit, offset, ctx)
+	if err != nil {
+		c.abortEvaluation(ctx, "SegmentHandler/HandlerEvaluateSegment/Members", err)
+		return
+	}
+
+	members.Customers = pii.Customers(ctx, members.Customers)
+	ctx.JSON(http.StatusOK, members)
+	return
+}
+
+// abortEvaluation answers invalid definitions with 400 and everything else
+// with 500.
+func (c *SegmentHandler) abortEvaluation(ctx *gin.Context, where string, err error) {
+	c.logger.Errorf("%s : %v", where, err)
+	var syntaxErr *segment.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		ctx.JSON(http.StatusBadRequest, domain.Response{Message: syntaxErr.Error(), StatusCode: 400})
+		ctx.Abort()
+		return
+	}
+	ctx.AbortWithError(http.StatusInternalServerError, err)
+}
+
+func page(ctx *gin.Context) (int, int, error) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "0"))
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil {
+		return 0, 0, err
+	}
+	return limit, offset, nil
+}
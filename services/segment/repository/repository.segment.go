@@ -0,0 +1,328 @@
+package repository_segment
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"customer-playground/encryption"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queryGetAll = `
+		SELECT
+			id,
+			name,
+			definition,
+			created_at,
+			updated_at
+		FROM segment
+		WHERE tenant_id = app_tenant()
+		ORDER BY name
+	`
+	queryGetById = `
+		SELECT
+			id,
+			name,
+			definition,
+			created_at,
+			updated_at
+		FROM segment
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+	queryInsert = `
+		INSERT INTO segment(
+			name,
+			definition,
+			created_at,
+			updated_at) VALUES (
+		$1, $2, $3, $4
+	)
+		RETURNING id
+	`
+	queryUpdate = `
+		UPDATE segment SET
+			name = $2,
+			definition = $3,
+			updated_at = $4
+		WHERE
+			tenant_id = app_tenant() AND id = $1
+	`
+	queryDeleteById = `
+		DELETE
+		FROM segment
+		WHERE tenant_id = app_tenant() AND id = $1
+	`
+	// Segment conditions are compiled per definition, so member queries are
+	// built around them and not kept in the statement cache.
+	queryMembers = `
+		SELECT
+			c.customer_number,
+			c.name,
+			c.email,
+			c.phone,
+			c.birth_date,
+			c.created_at,
+			c.updated_at
+		FROM customer c
+		WHERE c.tenant_id = app_tenant() AND %s
+		ORDER BY c.customer_number
+		LIMIT $%d OFFSET $%d
+	`
+	queryCount = `
+		SELECT COUNT(*)
+		FROM customer c
+		WHERE c.tenant_id = app_tenant() AND %s
+	`
+)
+
+type segmentRepository struct {
+	db     *database.DB
+	stmts  *database.Statements
+	retry  database.RetryPolicy
+	cipher *encryption.Cipher
+	logger *logrus.Logger
+}
+
+func scanSegment(row interface{ Scan(...interface{}) error }, segment *domain.Segment) error {
+	return row.Scan(
+		&segment.ID,
+		&segment.Name,
+		&segment.Definition,
+		&segment.CreatedAt,
+		&segment.UpdatedAt,
+	)
+}
+
+func (c segmentRepository) GetAll(ctx context.Context) ([]domain.Segment, error) {
+	var segments []domain.Segment
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetAll, func(stmt *sql.Stmt) error {
+				segments = nil
+				rows, err := stmt.QueryContext(ctx)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var segment domain.Segment
+					if err := scanSegment(rows, &segment); err != nil {
+						return err
+					}
+
+					segments = append(segments, segment)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+func (c segmentRepository) GetById(id int, ctx context.Context) (domain.Segment, error) {
+	var segment domain.Segment
+	err := c.retry.DoRead(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return c.stmts.Run(ctx, reader, queryGetById, func(stmt *sql.Stmt) error {
+				return scanSegment(stmt.QueryRowContext(ctx, id), &segment)
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return domain.Segment{}, err
+	}
+
+	return segment, nil
+}
+
+func (c segmentRepository) Insert(segment *domain.Segment, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryInsert, func(stmt *sql.Stmt) error {
+			return stmt.QueryRowContext(ctx,
+				segment.Name,
+				segment.Definition,
+				segment.CreatedAt,
+				segment.UpdatedAt,
+			).Scan(&segment.ID)
+		})
+	})
+	if isUniqueViolation(err) {
+		message.Message = fmt.Sprintf("A segment named %q already exists", segment.Name)
+		message.StatusCode = 400
+		return message, nil
+	}
+	if err != nil {
+		message.Message = "Failed to Insert Segment"
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, nil
+	}
+	message.Message = fmt.Sprintf("Succes Insert Segment with id %d", segment.ID)
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c segmentRepository) Update(segment *domain.Segment, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryUpdate, func(stmt *sql.Stmt) error {
+			_, err := stmt.ExecContext(ctx,
+				segment.ID,
+				segment.Name,
+				segment.Definition,
+				segment.UpdatedAt,
+			)
+			return err
+		})
+	})
+	if isUniqueViolation(err) {
+		message.Message = fmt.Sprintf("A segment named %q already exists", segment.Name)
+		message.StatusCode = 400
+		return message, nil
+	}
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Update id %d", segment.ID)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, nil
+	}
+	message.Message = "Succes Update"
+	message.StatusCode = 200
+	return message, nil
+}
+
+func (c segmentRepository) DeleteById(id int, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	var result sql.Result
+	err := c.retry.Do(ctx, func() error {
+		return c.stmts.Run(ctx, c.db.Writer(ctx), queryDeleteById, func(stmt *sql.Stmt) error {
+			var err error
+			result, err = stmt.ExecContext(ctx, id)
+			return err
+		})
+	})
+	if err != nil {
+		message.Message = fmt.Sprintf("Failed Delete id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to execute statement: %v", err)
+		return message, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		message.Message = fmt.Sprintf("Could not determine rows affected for id %d", id)
+		message.StatusCode = 500
+		c.logger.Errorf("failed to get rows affected: %v", err)
+		return message, err
+	}
+	if rowsAffected == 0 {
+		message.Message = fmt.Sprintf("No segment found with id %d", id)
+		message.StatusCode = 500
+		return message, sql.ErrNoRows
+	}
+	message.StatusCode = 200
+	message.Message = "Succes Delete!!"
+	return message, nil
+}
+
+func (c segmentRepository) Members(where string, args []interface{}, limit int, offset int, ctx context.Context) ([]domain.Customer, error) {
+	query := fmt.Sprintf(queryMembers, where, len(args)+1, len(args)+2)
+	args = append(args[:len(args):len(args)], limit, offset)
+
+	var customers []domain.Customer
+	err := c.retry.DoTransaction(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return database.Transaction(ctx, reader, func(tx *sql.Tx) error {
+				customers = nil
+				rows, err := tx.QueryContext(ctx, query, args...)
+				if err != nil {
+					return err
+				}
+
+				defer rows.Close()
+
+				for rows.Next() {
+					var customer domain.Customer
+					var stored encryption.StoredCustomer
+					err := rows.Scan(
+						&customer.CustomerNumber,
+						&customer.Name,
+						&stored.Email,
+						&stored.Phone,
+						&stored.BirthDate,
+						&customer.CreatedAt,
+						&customer.UpdatedAt,
+					)
+					if err != nil {
+						return err
+					}
+					if err := c.cipher.OpenCustomer(ctx, stored, &customer); err != nil {
+						return err
+					}
+
+					customers = append(customers, customer)
+				}
+				return rows.Err()
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to evaluate segment: %v", err)
+		return nil, err
+	}
+
+	return customers, nil
+}
+
+func (c segmentRepository) Count(where string, args []interface{}, ctx context.Context) (int, error) {
+	query := fmt.Sprintf(queryCount, where)
+
+	var count int
+	err := c.retry.DoTransaction(ctx, func() error {
+		return c.db.Read(ctx, func(reader *sql.DB) error {
+			return database.Transaction(ctx, reader, func(tx *sql.Tx) error {
+				return tx.QueryRowContext(ctx, query, args...).Scan(&count)
+			})
+		})
+	})
+	if err != nil {
+		c.logger.Errorf("failed to count segment: %v", err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// Close releases the prepared statements held by the repository.
+func (c segmentRepository) Close() error {
+	return c.stmts.Close()
+}
+
+func NewSegmentRepository(db *database.DB, retry database.RetryPolicy, cipher *encryption.Cipher, log *logrus.Logger) domain.SegmentRepository {
+	return &segmentRepository{
+		db:     db,
+		stmts:  database.NewStatements(),
+		retry:  retry,
+		cipher: cipher,
+		logger: log,
+	}
+}
@@ -0,0 +1,160 @@
+package usecase_segment
+
+import (
+	"context"
+	"customer-playground/domain"
+	"customer-playground/segment"
+	"customer-playground/types"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMembersLimit = 50
+	maxMembersLimit     = 500
+)
+
+type segmentUseCase struct {
+	segmentRepository domain.SegmentRepository
+	encryption        segment.Encryption
+	logger            *logrus.Logger
+}
+
+func (c segmentUseCase) GetAll(ctx context.Context) ([]domain.Segment, error) {
+	segments, err := c.segmentRepository.GetAll(ctx)
+	if err != nil {
+		c.logger.Errorf("segmentUseCase/GetAll :%v", err)
+		return nil, err
+	}
+	return segments, nil
+}
+
+func (c segmentUseCase) GetById(id int, ctx context.Context) (domain.Segment, error) {
+	segment, err := c.segmentRepository.GetById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("segmentUseCase/GetById :%v", err)
+		return domain.Segment{}, err
+	}
+	return segment, nil
+}
+
+func (c segmentUseCase) Insert(newSegment *domain.Segment, ctx context.Context) (domain.Response, error) {
+	now := time.Now()
+	newSegment.CreatedAt = types.NullTime{Time: now, Valid: true}
+	newSegment.UpdatedAt = types.NullTime{Time: now, Valid: true}
+	if message, ok := c.validate(newSegment); !ok {
+		return message, nil
+	}
+
+	message, err := c.segmentRepository.Insert(newSegment, ctx)
+	if err != nil {
+		c.logger.Errorf("segmentUseCase/Insert :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+func (c segmentUseCase) Update(newSegment *domain.Segment, ctx context.Context) (domain.Response, error) {
+	var message domain.Response
+	currentSegment, err := c.segmentRepository.GetById(newSegment.ID, ctx)
+	if err != nil {
+		message.Message = "this id is not found"
+		message.StatusCode = 500
+		return message, err
+	}
+
+	if newSegment.Name == "" {
+		newSegment.Name = currentSegment.Name
+	}
+	if newSegment.Definition == "" {
+		newSegment.Definition = currentSegment.Definition
+	}
+	newSegment.CreatedAt = currentSegment.CreatedAt
+	newSegment.UpdatedAt = types.NullTime{Time: time.Now(), Valid: true}
+	if message, ok := c.validate(newSegment); !ok {
+		return message, nil
+	}
+
+	message, err = c.segmentRepository.Update(newSegment, ctx)
+	if err != nil {
+		c.logger.Errorf("segmentUseCase/Update :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+func (c segmentUseCase) DeleteById(id int, ctx context.Context) (domain.Response, error) {
+	message, err := c.segmentRepository.DeleteById(id, ctx)
+	if err != nil {
+		c.logger.Errorf("segmentUseCase/DeleteById :%v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+func (c segmentUseCase) Members(definition string, limit int, offset int, ctx context.Context) (domain.SegmentMembers, error) {
+	query, err := segment.Compile(definition, c.encryption)
+	if err != nil {
+		return domain.SegmentMembers{}, err
+	}
+	if limit <= 0 {
+		limit = defaultMembersLimit
+	}
+	if limit > maxMembersLimit {
+		limit = maxMembersLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	count, err := c.segmentRepository.Count(query.Where, query.Args, ctx)
+	if err != nil {
+		c.logger.Errorf("segmentUseCase/Members/Count :%v", err)
+		return domain.SegmentMembers{}, err
+	}
+	customers, err := c.segmentRepository.Members(query.Where, query.Args, limit, offset, ctx)
+	if err != nil {
+		c.logger.Errorf("segmentUseCase/Members :%v", err)
+		return domain.SegmentMembers{}, err
+	}
+	if customers == nil {
+		customers = []domain.Customer{}
+	}
+	return domain.SegmentMembers{Count: count, Limit: limit, Offset: offset, Customers: customers}, nil
+}
+
+func (c segmentUseCase) Count(definition string, ctx context.Context) (domain.SegmentCount, error) {
+	query, err := segment.Compile(definition, c.encryption)
+	if err != nil {
+		return domain.SegmentCount{}, err
+	}
+	count, err := c.segmentRepository.Count(query.Where, query.Args, ctx)
+	if err != nil {
+		c.logger.Errorf("segmentUseCase/Count :%v", err)
+		return domain.SegmentCount{}, err
+	}
+	return domain.SegmentCount{Count: count}, nil
+}
+
+// validate checks the name and that the definition compiles, so saved
+// segments can always be evaluated.
+func (c segmentUseCase) validate(newSegment *domain.Segment) (domain.Response, bool) {
+	newSegment.Name = strings.TrimSpace(newSegment.Name)
+	if newSegment.Name == "" || len(newSegment.Name) > 100 {
+		return domain.Response{Message: "name must be between 1 and 100 characters", StatusCode: 400}, false
+	}
+	if _, err := segment.Compile(newSegment.Definition, c.encryption); err != nil {
+		return domain.Response{Message: err.Error(), StatusCode: 400}, false
+	}
+	return domain.Response{}, true
+}
+
+func NewSegmentUseCase(c domain.SegmentRepository, enc segment.Encryption, log *logrus.Logger) domain.SegmentUseCase {
+	return &segmentUseCase{
+		segmentRepository: c,
+		encryption:        enc,
+		logger:            log,
+	}
+}
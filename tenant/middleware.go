@@ -0,0 +1,146 @@
+package tenant
+
+import (
+	"customer-playground/domain"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Resolver is a gin middleware that scopes every request to a tenant. The
+// tenant comes from the bearer token, else from the X-Tenant header when
+// headers are trusted, else it is the default tenant. Headers are trusted
+// only when enabled and sent by one of the trusted proxies, which are
+// expected to overwrite them. Permissions only come from the token scope.
+type Resolver struct {
+	registry      *Registry
+	secret        string
+	trustHeader   bool
+	proxies       []*net.IPNet
+	defaultTenant string
+	exempt        []string
+	logger        *logrus.Logger
+}
+
+// NewResolver verifies tokens with secret. trustedProxies are addresses or
+// CIDR ranges, as validated by the config; entries that are neither are
+// ignored. Requests for routes starting with one of the exempt prefixes are
+// not scoped; they must not touch tenant data.
+func NewResolver(registry *Registry, secret string, trustHeader bool, trustedProxies []string, defaultTenant string, exempt []string, logger *logrus.Logger) *Resolver {
+	var proxies []*net.IPNet
+	for _, proxy := range trustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			proxies = append(proxies, network)
+		} else if ip := net.ParseIP(proxy); ip != nil {
+			bits := 128
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 32
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return &Resolver{
+		registry:      registry,
+		secret:        secret,
+		trustHeader:   trustHeader,
+		proxies:       proxies,
+		defaultTenant: defaultTenant,
+		exempt:        exempt,
+		logger:        logger,
+	}
+}
+
+func (r *Resolver) Handler(ctx *gin.Context) {
+	for _, prefix := range r.exempt {
+		if strings.HasPrefix(ctx.Request.URL.Path, prefix) {
+			ctx.Next()
+			return
+		}
+	}
+
+	claims, status, err := r.resolve(ctx)
+	if err != nil {
+		r.logger.Errorf("%s : %v", "Resolver/Handler", err)
+		ctx.AbortWithStatusJSON(status, domain.ErrorResponse{Message: err.Error()})
+		return
+	}
+	tenant, ok := r.registry.Lookup(claims.Tenant)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, domain.ErrorResponse{Message: "unknown tenant " + claims.Tenant})
+		return
+	}
+
+	requestCtx := WithTenant(ctx.Request.Context(), tenant)
+	if claims.Subject != "" {
+		requestCtx = domain.WithSubject(domain.WithUser(requestCtx, claims.Subject), claims.Subject)
+	}
+	if permissions := claims.Permissions(); len(permissions) > 0 {
+		requestCtx = domain.WithPermissions(requestCtx, permissions)
+	}
+	ctx.Request = ctx.Request.WithContext(requestCtx)
+	ctx.Next()
+}
+
+// resolve returns the tenant slug and, from a token, the user and their
+// permissions.
+func (r *Resolver) resolve(ctx *gin.Context) (Claims, int, error) {
+	header := ctx.GetHeader(domain.TenantHeader)
+
+	if authorization := ctx.GetHeader("Authorization"); authorization != "" {
+		// The token names the user; a second name could only disagree.
+		if ctx.GetHeader(domain.UserHeader) != "" {
+			return Claims{}, http.StatusBadRequest, errors.New("send either a bearer token or the " + domain.UserHeader + " header, not both")
+		}
+		token, ok := strings.CutPrefix(authorization, "Bearer ")
+		if !ok {
+			return Claims{}, http.StatusUnauthorized, errors.New("only bearer tokens are supported")
+		}
+		claims, err := Verify(strings.TrimSpace(token), r.secret, time.Now())
+		if err != nil {
+			return Claims{}, http.StatusUnauthorized, err
+		}
+		if header != "" && header != claims.Tenant {
+			return Claims{}, http.StatusForbidden, errors.New("the token is not valid for tenant " + header)
+		}
+		return claims, 0, nil
+	}
+
+	if header != "" {
+		if !r.trustedPeer(ctx) {
+			return Claims{}, http.StatusUnauthorized, errors.New("a bearer token is required to choose a tenant")
+		}
+		return Claims{Tenant: header}, 0, nil
+	}
+	if r.defaultTenant == "" {
+		return Claims{}, http.StatusBadRequest, errors.New("tenant is required, send a bearer token or the " + domain.TenantHeader + " header")
+	}
+	return Claims{Tenant: r.defaultTenant}, 0, nil
+}
+
+// trustedPeer tells whether headers are trusted and the request came
+// directly from a trusted proxy. It looks at the connection, not at
+// X-Forwarded-For, which the client may have set.
+func (r *Resolver) trustedPeer(ctx *gin.Context) bool {
+	if !r.trustHeader {
+		return false
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(ctx.Request.RemoteAddr))
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range r.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
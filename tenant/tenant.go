@@ -0,0 +1,100 @@
+// Package tenant resolves which tenant a request is for and keeps the
+// tenants configured for the deployment.
+package tenant
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"database/sql"
+	"sort"
+)
+
+// Registry holds the configured tenants by slug. It does not change after
+// start up.
+type Registry struct {
+	bySlug map[string]domain.Tenant
+}
+
+func NewRegistry(tenants []domain.Tenant) *Registry {
+	registry := &Registry{bySlug: make(map[string]domain.Tenant, len(tenants))}
+	for _, tenant := range tenants {
+		registry.bySlug[tenant.Slug] = tenant
+	}
+	return registry
+}
+
+func (r *Registry) Lookup(slug string) (domain.Tenant, bool) {
+	tenant, ok := r.bySlug[slug]
+	return tenant, ok
+}
+
+// All returns every tenant ordered by ID.
+func (r *Registry) All() []domain.Tenant {
+	tenants := make([]domain.Tenant, 0, len(r.bySlug))
+	for _, tenant := range r.bySlug {
+		tenants = append(tenants, tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants
+}
+
+// WithTenant scopes ctx to tenant, both for the use cases and for every
+// database statement run with it.
+func WithTenant(ctx context.Context, tenant domain.Tenant) context.Context {
+	return database.WithTenant(domain.WithTenant(ctx, tenant), tenant.ID)
+}
+
+// ForEach returns a job that runs run once per tenant, scoped to it, and
+// adds up what it handled. A failing tenant does not stop the others.
+func ForEach(registry *Registry, run func(ctx context.Context) (int, error)) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		total := 0
+		var firstErr error
+		for _, tenant := range registry.All() {
+			handled, err := run(WithTenant(ctx, tenant))
+			total += handled
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return total, firstErr
+	}
+}
+
+const querySync = `
+	INSERT INTO tenant (slug, name) VALUES ($1, $2)
+	ON CONFLICT (slug) DO UPDATE SET name = EXCLUDED.name
+	RETURNING id
+`
+
+// Sync creates the tenants missing from the tenant table, updates their
+// names and fills in their IDs.
+func Sync(ctx context.Context, db *sql.DB, tenants []domain.Tenant) ([]domain.Tenant, error) {
+	synced := make([]domain.Tenant, len(tenants))
+	for i, tenant := range tenants {
+		if err := db.QueryRowContext(ctx, querySync, tenant.Slug, tenant.Name).Scan(&tenant.ID); err != nil {
+			return nil, err
+		}
+		synced[i] = tenant
+	}
+	return synced, nil
+}
+
+// Flatten turns nested settings such as {"dedupe": {"threshold": 0.7}} into
+// the dotted keys Tenant overrides use, {"dedupe.threshold": 0.7}.
+func Flatten(settings map[string]interface{}) map[string]interface{} {
+	flat := map[string]interface{}{}
+	var walk func(prefix string, settings map[string]interface{})
+	walk = func(prefix string, settings map[string]interface{}) {
+		for key, value := range settings {
+			if nested, ok := value.(map[string]interface{}); ok {
+				walk(prefix+key+".", nested)
+				continue
+			}
+			flat[prefix+key] = value
+		}
+	}
+	walk("", settings)
+	return flat
+}
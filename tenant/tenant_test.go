@@ -0,0 +1,169 @@
+package tenant
+
+import (
+	"context"
+	"customer-playground/database"
+	"customer-playground/domain"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const secret = "test-secret"
+
+var registry = NewRegistry([]domain.Tenant{
+	{ID: 1, Slug: "default", Name: "Default"},
+	{ID: 2, Slug: "acme", Name: "ACME", Overrides: map[string]interface{}{"dedupe.threshold": 0.7}},
+})
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	valid, err := Sign(Claims{Tenant: "acme", Subject: "jane", ExpiresAt: now.Unix() + 60}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := Verify(valid, secret, now)
+	if err != nil || claims.Tenant != "acme" || claims.Subject != "jane" {
+		t.Fatalf("Verify() = %+v, %v", claims, err)
+	}
+
+	expired, _ := Sign(Claims{Tenant: "acme", ExpiresAt: now.Unix()}, secret)
+	noTenant, _ := Sign(Claims{Subject: "jane", ExpiresAt: now.Unix() + 60}, secret)
+	noExpiry, _ := Sign(Claims{Tenant: "acme", Subject: "jane"}, secret)
+	none := `eyJhbGciOiJub25lIn0.` + valid[len(tokenHeader)+1:]
+	tests := map[string]struct {
+		token, secret string
+		want          error
+	}{
+		"expired":      {expired, secret, ErrExpiredToken},
+		"wrong secret": {valid, "other", ErrInvalidToken},
+		"no secret":    {valid, "", ErrInvalidToken},
+		"no tenant":    {noTenant, secret, ErrInvalidToken},
+		"no expiry":    {noExpiry, secret, ErrInvalidToken},
+		"alg none":     {none, secret, ErrInvalidToken},
+		"tampered":     {valid[:len(valid)-2] + "AA", secret, ErrInvalidToken},
+		"garbage":      {"not-a-token", secret, ErrInvalidToken},
+	}
+	for name, test := range tests {
+		if _, err := Verify(test.token, test.secret, now); !errors.Is(err, test.want) {
+			t.Errorf("%s: Verify() = %v, want %v", name, err, test.want)
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.Out = io.Discard
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	acmeToken, _ := Sign(Claims{Tenant: "acme", Subject: "jane", ExpiresAt: expiresAt}, secret)
+	readerToken, _ := Sign(Claims{Tenant: "acme", Subject: "jane", Scope: "customer:write pii:read", ExpiresAt: expiresAt}, secret)
+
+	// httptest requests come from 192.0.2.1.
+	proxy := []string{"192.0.2.0/24"}
+
+	tests := []struct {
+		name          string
+		trustHeader   bool
+		proxies       []string
+		defaultTenant string
+		path          string
+		headers       map[string]string
+		wantStatus    int
+		wantTenant    int
+		wantUser      string
+		wantPII       bool
+	}{
+		{name: "default", defaultTenant: "default", wantStatus: http.StatusOK, wantTenant: 1},
+		{name: "no default", wantStatus: http.StatusBadRequest},
+		{name: "token", headers: map[string]string{"Authorization": "Bearer " + acmeToken}, wantStatus: http.StatusOK, wantTenant: 2, wantUser: "jane"},
+		{name: "token and header", headers: map[string]string{"Authorization": "Bearer " + acmeToken, "X-Tenant": "acme"}, wantStatus: http.StatusOK, wantTenant: 2, wantUser: "jane"},
+		{name: "token for other tenant", trustHeader: true, proxies: proxy, headers: map[string]string{"Authorization": "Bearer " + acmeToken, "X-Tenant": "default"}, wantStatus: http.StatusForbidden},
+		{name: "token and user header", headers: map[string]string{"Authorization": "Bearer " + acmeToken, "X-User": "bob"}, wantStatus: http.StatusBadRequest},
+		{name: "bad token", defaultTenant: "default", headers: map[string]string{"Authorization": "Bearer x.y.z"}, wantStatus: http.StatusUnauthorized},
+		{name: "basic auth", defaultTenant: "default", headers: map[string]string{"Authorization": "Basic Zm9vOmJhcg=="}, wantStatus: http.StatusUnauthorized},
+		{name: "trusted header", trustHeader: true, proxies: proxy, headers: map[string]string{"X-Tenant": "acme"}, wantStatus: http.StatusOK, wantTenant: 2},
+		{name: "untrusted header", defaultTenant: "default", headers: map[string]string{"X-Tenant": "acme"}, wantStatus: http.StatusUnauthorized},
+		{name: "unknown tenant", trustHeader: true, proxies: proxy, headers: map[string]string{"X-Tenant": "nobody"}, wantStatus: http.StatusBadRequest},
+		{name: "token with scope", headers: map[string]string{"Authorization": "Bearer " + readerToken}, wantStatus: http.StatusOK, wantTenant: 2, wantUser: "jane", wantPII: true},
+		{name: "token ignores permissions header", trustHeader: true, proxies: proxy, headers: map[string]string{"Authorization": "Bearer " + acmeToken, "X-Permissions": "pii:read"}, wantStatus: http.StatusOK, wantTenant: 2, wantUser: "jane"},
+		{name: "permissions header from trusted proxy", trustHeader: true, proxies: proxy, headers: map[string]string{"X-Tenant": "acme", "X-Permissions": "pii:read"}, wantStatus: http.StatusOK, wantTenant: 2},
+		{name: "permissions header", defaultTenant: "default", headers: map[string]string{"X-Permissions": "pii:read"}, wantStatus: http.StatusOK, wantTenant: 1},
+		{name: "header from untrusted peer", trustHeader: true, proxies: []string{"10.0.0.0/8"}, defaultTenant: "default", headers: map[string]string{"X-Tenant": "acme"}, wantStatus: http.StatusUnauthorized},
+		{name: "header from trusted address", trustHeader: true, proxies: []string{"192.0.2.1"}, headers: map[string]string{"X-Tenant": "acme"}, wantStatus: http.StatusOK, wantTenant: 2},
+		{name: "forwarded for a trusted address", trustHeader: true, proxies: []string{"10.0.0.0/8"}, defaultTenant: "default", headers: map[string]string{"X-Tenant": "acme", "X-Forwarded-For": "10.0.0.1"}, wantStatus: http.StatusUnauthorized},
+		{name: "exempt", path: "/debug/vars", wantStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		resolver := NewResolver(registry, secret, test.trustHeader, test.proxies, test.defaultTenant, []string{"/debug/"}, logger)
+		r := gin.New()
+		r.Use(resolver.Handler)
+		var gotTenant, gotDatabaseTenant int
+		var gotUser string
+		var gotPII bool
+		handler := func(ctx *gin.Context) {
+			gotTenant = domain.TenantFromContext(ctx.Request.Context()).ID
+			gotDatabaseTenant, _ = database.TenantID(ctx.Request.Context())
+			gotUser = domain.UserFromContext(ctx.Request.Context())
+			gotPII = domain.HasPermission(ctx.Request.Context(), domain.PermissionReadPII)
+		}
+		r.GET("/customer", handler)
+		r.GET("/debug/vars", handler)
+
+		path := test.path
+		if path == "" {
+			path = "/customer"
+		}
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, value := range test.headers {
+			request.Header.Set(key, value)
+		}
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.wantStatus {
+			t.Errorf("%s: status = %d, want %d", test.name, recorder.Code, test.wantStatus)
+			continue
+		}
+		if gotTenant != test.wantTenant || gotDatabaseTenant != test.wantTenant || gotUser != test.wantUser {
+			t.Errorf("%s: tenant = %d/%d, user = %q, want %d, %q", test.name, gotTenant, gotDatabaseTenant, gotUser, test.wantTenant, test.wantUser)
+		}
+		if gotPII != test.wantPII {
+			t.Errorf("%s: pii:read = %v, want %v", test.name, gotPII, test.wantPII)
+		}
+	}
+}
+
+func TestForEach(t *testing.T) {
+	var seen []int
+	run := ForEach(registry, func(ctx context.Context) (int, error) {
+		id, _ := database.TenantID(ctx)
+		seen = append(seen, id)
+		if id == 1 {
+			return 0, errors.New("boom")
+		}
+		return 3, nil
+	})
+	handled, err := run(context.Background())
+	if handled != 3 || err == nil || !reflect.DeepEqual(seen, []int{1, 2}) {
+		t.Errorf("ForEach() = %d, %v after %v", handled, err, seen)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	got := Flatten(map[string]interface{}{
+		"dedupe":     map[string]interface{}{"threshold": 0.7},
+		"attachment": map[string]interface{}{"max_size": int64(1024)},
+	})
+	want := map[string]interface{}{"dedupe.threshold": 0.7, "attachment.max_size": int64(1024)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Flatten() = %v, want %v", got, want)
+	}
+}
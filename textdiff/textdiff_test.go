@@ -0,0 +1,31 @@
+package textdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	got := Lines("a\nb\nc\n", "a\nc\nd")
+	want := []Line{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}, {Insert, "d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %v, want %v", got, want)
+	}
+
+	if got := Lines("", "x"); !reflect.DeepEqual(got, []Line{{Insert, "x"}}) {
+		t.Errorf("Lines() from empty = %v", got)
+	}
+	if got := Lines("x", ""); !reflect.DeepEqual(got, []Line{{Delete, "x"}}) {
+		t.Errorf("Lines() to empty = %v", got)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	if got := Unified("same", "same"); got != "" {
+		t.Errorf("Unified() of equal texts = %q, want empty", got)
+	}
+	want := " Customer called\n-about billing\n+about shipping\n"
+	if got := Unified("Customer called\nabout billing", "Customer called\nabout shipping"); got != want {
+		t.Errorf("Unified() = %q, want %q", got, want)
+	}
+}
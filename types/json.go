@@ -0,0 +1,215 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ugorji/go/codec"
+)
+
+// JSONMap is a JSON object stored in a JSONB column. A nil map is stored as
+// an empty object.
+type JSONMap map[string]interface{}
+
+func (m *JSONMap) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONMap", value)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return err
+	}
+	if len(decoded) == 0 {
+		decoded = nil
+	}
+	*m = decoded
+	return nil
+}
+
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	// Returned as a string so drivers never mistake it for bytea.
+	b, err := json.Marshal(map[string]interface{}(m))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// MarshalXML writes one child element per key, in key order. Values other
+// than strings carry their JSON type in a type attribute, e.g.
+// <visits type="number">3</visits>, so they decode to what the JSON decodes
+// to. Keys must be XML names, which custom field names are.
+func (m JSONMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		element := xml.StartElement{Name: xml.Name{Local: key}}
+		var text string
+		switch value := m[key].(type) {
+		case nil:
+			element.Attr = []xml.Attr{{Name: xml.Name{Local: "type"}, Value: "null"}}
+		case string:
+			text = value
+		case bool:
+			element.Attr = []xml.Attr{{Name: xml.Name{Local: "type"}, Value: "boolean"}}
+			text = strconv.FormatBool(value)
+		default:
+			number, ok := toFloat(value)
+			if !ok {
+				return fmt.Errorf("cannot marshal %T of %s to XML", value, key)
+			}
+			element.Attr = []xml.Attr{{Name: xml.Name{Local: "type"}, Value: "number"}}
+			text = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		if err := e.EncodeElement(text, element); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (m *JSONMap) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	decoded := JSONMap{}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			var text string
+			if err := d.DecodeElement(&text, &token); err != nil {
+				return err
+			}
+			key := token.Name.Local
+			switch kind := attr(token, "type"); kind {
+			case "", "string":
+				decoded[key] = text
+			case "null":
+				decoded[key] = nil
+			case "boolean":
+				if decoded[key], err = strconv.ParseBool(text); err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+			case "number":
+				if decoded[key], err = strconv.ParseFloat(text, 64); err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+			default:
+				return fmt.Errorf("%s: unknown type %q", key, kind)
+			}
+		case xml.EndElement:
+			if len(decoded) == 0 {
+				decoded = nil
+			}
+			*m = decoded
+			return nil
+		}
+	}
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// CodecEncodeSelf writes the map as a MessagePack map.
+func (m *JSONMap) CodecEncodeSelf(e *codec.Encoder) {
+	e.MustEncode(map[string]interface{}(*m))
+}
+
+// CodecDecodeSelf reads a MessagePack map and turns its integers into
+// float64 and its byte strings into strings, the types encoding/json
+// decodes to and the custom field validation expects.
+func (m *JSONMap) CodecDecodeSelf(d *codec.Decoder) {
+	var decoded map[string]interface{}
+	d.MustDecode(&decoded)
+	for key, value := range decoded {
+		decoded[key] = normalize(value)
+	}
+	if len(decoded) == 0 {
+		decoded = nil
+	}
+	*m = decoded
+}
+
+func normalize(value interface{}) interface{} {
+	switch value := value.(type) {
+	case []byte:
+		return string(value)
+	case map[string]interface{}:
+		for key, v := range value {
+			value[key] = normalize(v)
+		}
+		return value
+	case []interface{}:
+		for i, v := range value {
+			value[i] = normalize(v)
+		}
+		return value
+	}
+	if number, ok := toFloat(value); ok {
+		return number
+	}
+	return value
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case int8:
+		return float64(value), true
+	case int16:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case uint:
+		return float64(value), true
+	case uint8:
+		return float64(value), true
+	case uint16:
+		return float64(value), true
+	case uint32:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case json.Number:
+		number, err := value.Float64()
+		return number, err == nil
+	}
+	return 0, false
+}
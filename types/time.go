@@ -2,9 +2,12 @@ package types
 
 import (
 	"database/sql/driver"
+	"encoding/xml"
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/ugorji/go/codec"
 )
 
 type NullTime struct {
@@ -54,3 +57,37 @@ func (nt NullTime) MarshalJSON() ([]byte, error) {
 	}
 	return []byte(`"` + nt.Time.Format(time.RFC3339) + `"`), nil
 }
+
+// MarshalXML writes the time as RFC 3339 text and leaves out the element when
+// the time is null.
+func (nt NullTime) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if !nt.Valid {
+		return nil
+	}
+	return e.EncodeElement(nt.Time.Format(time.RFC3339), start)
+}
+
+func (nt *NullTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	return nt.UnmarshalJSON([]byte(strings.TrimSpace(s)))
+}
+
+// CodecEncodeSelf writes the time as a MessagePack timestamp, or nil when the
+// time is null.
+func (nt *NullTime) CodecEncodeSelf(e *codec.Encoder) {
+	if !nt.Valid {
+		e.MustEncode(nil)
+		return
+	}
+	e.MustEncode(nt.Time)
+}
+
+func (nt *NullTime) CodecDecodeSelf(d *codec.Decoder) {
+	var t time.Time
+	d.MustDecode(&t)
+	nt.Time = t
+	nt.Valid = !t.IsZero()
+}
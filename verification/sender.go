@@ -0,0 +1,81 @@
+// Package verification delivers one-time verification codes to customer
+// contact points.
+package verification
+
+import (
+	"context"
+	"customer-playground/domain"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogSender writes codes to the application log. It is meant for local
+// development only.
+type LogSender struct {
+	logger *logrus.Logger
+}
+
+func NewLogSender(logger *logrus.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(contactPoint domain.CustomerContactPoint, code string, ctx context.Context) error {
+	s.logger.WithFields(logrus.Fields{
+		"customer_number":  contactPoint.CustomerNumber,
+		"contact_point_id": contactPoint.ID,
+		"type":             contactPoint.Type,
+		"value":            contactPoint.Value,
+		"code":             code,
+	}).Info("verification code issued")
+	return nil
+}
+
+// FileSender appends every code as a JSON line to a file, so tests and local
+// tooling can pick them up.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+type fileMessage struct {
+	SentAt         time.Time `json:"sent_at"`
+	CustomerNumber int       `json:"customer_number"`
+	ContactPointID int       `json:"contact_point_id"`
+	Type           string    `json:"type"`
+	Value          string    `json:"value"`
+	Code           string    `json:"code"`
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(contactPoint domain.CustomerContactPoint, code string, ctx context.Context) error {
+	line, err := json.Marshal(fileMessage{
+		SentAt:         time.Now(),
+		CustomerNumber: contactPoint.CustomerNumber,
+		ContactPointID: contactPoint.ID,
+		Type:           contactPoint.Type,
+		Value:          contactPoint.Value,
+		Code:           code,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}